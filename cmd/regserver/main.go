@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,6 +17,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"winsbygroup.com/regserver/internal/config"
+	"winsbygroup.com/regserver/internal/logging"
 	"winsbygroup.com/regserver/internal/server"
 	"winsbygroup.com/regserver/internal/version"
 )
@@ -39,8 +41,18 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 	cfg.DemoMode = *demoFlag
+
+	//
+	// Logging (the standard log package is routed through slog as well)
+	//
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("failed to configure logging: %v", err)
+	}
+	slog.SetDefault(logger)
+
 	if cfg.DemoMode {
-		log.Println("**DEMO MODE**")
+		slog.Warn("**DEMO MODE**")
 	}
 
 	//
@@ -48,7 +60,8 @@ func main() {
 	//
 	srv, err := server.Build(cfg)
 	if err != nil {
-		log.Fatalf("failed to build server: %v", err)
+		slog.Error("failed to build server", "error", err)
+		os.Exit(1)
 	}
 	defer srv.DB.Close()
 
@@ -72,20 +85,29 @@ func main() {
 	// Normal server startup
 	//
 	go func() {
+		slog.Info("server starting", "addr", cfg.Addr, "version", version.Version)
 		if err := srv.Echo.StartServer(srv.HTTP); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			srv.Echo.Logger.Fatalf("server failed: %v", err)
+			slog.Error("server failed", "error", err)
+			os.Exit(1)
 		}
 	}()
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	srv.StartJobs(jobsCtx)
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
+	slog.Info("shutting down")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := srv.Echo.Shutdown(ctx); err != nil {
-		log.Fatal(err)
+		slog.Error("shutdown failed", "error", err)
+		os.Exit(1)
 	}
 }
@@ -6,6 +6,7 @@
 using System.Net.Http.Json;
 using System.Security.Cryptography;
 using System.Text;
+using System.Text.Json;
 
 public record ActivationRequest(string MachineCode, string UserName);
 
@@ -53,7 +54,7 @@ public class LicenseClient
     /// <param name="expirationDate">Expiration date in yyyy-mm-dd format</param>
     /// <param name="maintExpirationDate">Maintenance expiration date in yyyy-mm-dd format</param>
     /// <param name="maxProductVersion">Maximum allowed product version (empty if no restriction)</param>
-    /// <param name="features">Feature dictionary (values will be converted to strings)</param>
+    /// <param name="features">Feature dictionary (values are converted to their canonical strings)</param>
     /// <param name="secret">The REGISTRATION_SECRET shared with the server</param>
     /// <returns>Base64-encoded SHA1 hash</returns>
     public static string CalculateRegistrationHash(
@@ -83,7 +84,7 @@ public class LicenseClient
                 sb.Append('|');
                 sb.Append(key);
                 sb.Append('=');
-                sb.Append(features[key]?.ToString() ?? "");
+                sb.Append(FeatureString(features[key]));
             }
         }
 
@@ -100,6 +101,21 @@ public class LicenseClient
         return Convert.ToBase64String(hashBytes);
     }
 
+    /// <summary>
+    /// Renders a feature value in its canonical form: JSON numbers as written,
+    /// booleans as "true"/"false" and strings without quotes.
+    /// </summary>
+    private static string FeatureString(object? value) => value switch
+    {
+        null => "",
+        bool b => b ? "true" : "false",
+        JsonElement e when e.ValueKind == JsonValueKind.String => e.GetString() ?? "",
+        JsonElement e when e.ValueKind == JsonValueKind.True => "true",
+        JsonElement e when e.ValueKind == JsonValueKind.False => "false",
+        JsonElement e => e.GetRawText(),
+        _ => value.ToString() ?? ""
+    };
+
     /// <summary>
     /// Validates an activation response by comparing the calculated hash with the server-provided hash.
     /// </summary>
@@ -8,17 +8,28 @@ using System.Text;
 
 public static class MachineCode
 {
+    // Returns a structured machine code: "fp1." followed by one short hash per
+    // hardware component. The server recognises the machine when enough
+    // components still match, so replacing one part does not use up a seat.
+    // Keep the component order fixed; an unreadable component is left empty.
     public static string GetMachineCode()
     {
-        var raw = string.Join("|", new[]
+        var components = new[]
         {
             GetWmi("Win32_BaseBoard", "SerialNumber"),
             GetWmi("Win32_Processor", "ProcessorId"),
             GetWmi("Win32_OperatingSystem", "SerialNumber"),
             GetWmi("Win32_LogicalDisk", "VolumeSerialNumber", "DeviceID='C:'")
-        }.Where(s => !string.IsNullOrWhiteSpace(s)));
+        };
 
-        return Hash(raw);
+        return "fp1." + string.Join(".", components.Select(ComponentHash));
+    }
+
+    private static string ComponentHash(string value)
+    {
+        if (string.IsNullOrWhiteSpace(value))
+            return "";
+        return Hash(value.Trim()).Substring(0, 16);
     }
 
     private static string GetWmi(string className, string propertyName, string whereClause = null)
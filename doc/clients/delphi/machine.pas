@@ -43,24 +43,33 @@ begin
   end;
 end;
 
-function GetMachineCode: string;
+function ComponentHash(const Value: string): string;
 var
-  Raw: string;
-  HashBytes: TBytes;
   HashBase64: string;
 begin
-  Raw := WmiQuery('Win32_BaseBoard', 'SerialNumber') + '|' +
-         WmiQuery('Win32_Processor', 'ProcessorId') + '|' +
-         WmiQuery('Win32_OperatingSystem', 'SerialNumber') + '|' +
-         WmiQuery('Win32_LogicalDisk', 'VolumeSerialNumber', 'DeviceID="C:"');
+  Result := '';
+  if Trim(Value) = '' then
+    Exit;
 
-  HashBytes := THashSHA2.GetHashBytes(Raw);
-  HashBase64 := TNetEncoding.Base64.EncodeBytesToString(HashBytes);
+  HashBase64 := TNetEncoding.Base64.EncodeBytesToString(THashSHA2.GetHashBytes(Trim(Value)));
 
   // sanitize for URLs, DB keys, etc.
   HashBase64 := HashBase64.Replace('=', '').Replace('+', '').Replace('/', '');
 
-  Result := HashBase64;
+  Result := Copy(HashBase64, 1, 16);
+end;
+
+// Returns a structured machine code: 'fp1.' followed by one short hash per
+// hardware component. The server recognises the machine when enough
+// components still match, so replacing one part does not use up a seat.
+// Keep the component order fixed; an unreadable component is left empty.
+function GetMachineCode: string;
+begin
+  Result := 'fp1.' +
+    ComponentHash(WmiQuery('Win32_BaseBoard', 'SerialNumber')) + '.' +
+    ComponentHash(WmiQuery('Win32_Processor', 'ProcessorId')) + '.' +
+    ComponentHash(WmiQuery('Win32_OperatingSystem', 'SerialNumber')) + '.' +
+    ComponentHash(WmiQuery('Win32_LogicalDisk', 'VolumeSerialNumber', 'DeviceID="C:"'));
 end;
 
 end.
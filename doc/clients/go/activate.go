@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 )
 
 type ActivationRequest struct {
@@ -58,6 +59,16 @@ func ActivateProduct(baseURL, licenseKey, machineCode, userName string) (*Activa
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		// Suspended or cancelled licenses carry an error code (license_suspended, license_cancelled)
+		var apiErr struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Code != "" {
+			return nil, fmt.Errorf("activation failed (%s): %s - please contact billing", apiErr.Code, apiErr.Error)
+		}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("activation failed: %s", resp.Status)
 	}
@@ -70,6 +81,19 @@ func ActivateProduct(baseURL, licenseKey, machineCode, userName string) (*Activa
 	return &result, nil
 }
 
+// featureString renders a decoded JSON feature value in its canonical form:
+// numbers without exponent or decimals, booleans as "true"/"false".
+func featureString(v any) string {
+	switch x := v.(type) {
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		return fmt.Sprintf("%v", x)
+	}
+}
+
 // CalculateRegistrationHash computes the registration hash for offline license validation.
 // The algorithm:
 // 1. Build string: {MachineCode}|{ExpirationDate}|{MaintExpirationDate}|{MaxProductVersion}|{Feature1}={Value1}|...
@@ -90,7 +114,7 @@ func CalculateRegistrationHash(machineCode, expirationDate, maintExpirationDate,
 		sort.Strings(keys)
 
 		for _, k := range keys {
-			regString += "|" + k + "=" + fmt.Sprintf("%v", features[k])
+			regString += "|" + k + "=" + featureString(features[k])
 		}
 	}
 
@@ -9,15 +9,27 @@ import (
 	"strings"
 )
 
+// GetMachineCode returns a structured machine code: "fp1." followed by one
+// short hash per hardware component. The server recognises the machine when
+// enough components still match, so replacing one part does not use up a seat.
+// Keep the component order fixed; an unreadable component is left empty.
 func GetMachineCode() string {
-	raw := strings.Join([]string{
+	parts := []string{"fp1"}
+	for _, id := range []string{
 		getMachineID(),
 		getCPUID(),
 		getDiskID(),
-	}, "|")
-
-	sum := sha256.Sum256([]byte(raw))
-	enc := base64.RawURLEncoding.EncodeToString(sum[:])
+	} {
+		parts = append(parts, componentHash(id))
+	}
+	return strings.Join(parts, ".")
+}
 
-	return enc
+func componentHash(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:16]
 }
@@ -8,9 +8,12 @@ CREATE TABLE IF NOT EXISTS customer (
     contact_name VARCHAR(255),
     phone VARCHAR(255),
     email VARCHAR(255),
-    notes TEXT
+    notes TEXT,
+    reseller_id INTEGER REFERENCES reseller (reseller_id) ON DELETE SET NULL
 );
 
+CREATE INDEX IF NOT EXISTS idx_customer_reseller_id ON customer (reseller_id ASC);
+
 CREATE TABLE IF NOT EXISTS machine (
     machine_id INTEGER PRIMARY KEY AUTOINCREMENT,
     customer_id INTEGER NOT NULL,
@@ -27,7 +30,9 @@ CREATE TABLE IF NOT EXISTS product (
     product_name VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE,
     product_guid VARCHAR(36) NOT NULL UNIQUE COLLATE NOCASE,
 	latest_version VARCHAR(10) NOT NULL,
-	download_url VARCHAR(255) NOT NULL DEFAULT ('https://download.example.com/{product}')
+	download_url VARCHAR(255) NOT NULL DEFAULT ('https://download.example.com/{product}'),
+    inactivity_days INTEGER,
+    inactivity_action VARCHAR(10) NOT NULL DEFAULT 'flag' CHECK (inactivity_action IN ('flag','release'))
 );
 
 CREATE TABLE IF NOT EXISTS registration (
@@ -38,6 +43,14 @@ CREATE TABLE IF NOT EXISTS registration (
     first_registration_date VARCHAR(10),
     last_registration_date VARCHAR(10),
     installed_version VARCHAR(20) NOT NULL DEFAULT '',
+    license_id INTEGER REFERENCES license (license_id) ON DELETE CASCADE,
+    last_checkin_date VARCHAR(10),
+    idle_date VARCHAR(10),
+    released_date VARCHAR(10),
+    last_seen_at VARCHAR(19),
+    client_os VARCHAR(255),
+    client_hostname VARCHAR(255),
+    client_ip VARCHAR(45),
     CONSTRAINT pk_registration PRIMARY KEY (machine_id, product_id),
     FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE,
     FOREIGN KEY (machine_id) REFERENCES machine (machine_id) ON DELETE CASCADE
@@ -45,9 +58,11 @@ CREATE TABLE IF NOT EXISTS registration (
 
 CREATE INDEX IF NOT EXISTS idx_registration_machine_id ON registration (machine_id ASC);
 CREATE INDEX IF NOT EXISTS idx_registration_product_id ON registration (product_id ASC);
+CREATE INDEX IF NOT EXISTS idx_registration_license_id ON registration (license_id ASC);
 
 
 CREATE TABLE IF NOT EXISTS license (
+    license_id INTEGER PRIMARY KEY AUTOINCREMENT,
     customer_id INTEGER NOT NULL,
     product_id INTEGER NOT NULL,
     license_key VARCHAR(36) NOT NULL COLLATE NOCASE,
@@ -58,7 +73,14 @@ CREATE TABLE IF NOT EXISTS license (
     expiration_date VARCHAR(10),
     maint_expiration_date VARCHAR(10) NOT NULL DEFAULT '9999-12-31',
     max_product_version VARCHAR(255),
-    CONSTRAINT pk_license PRIMARY KEY (customer_id, product_id),
+    key_status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (key_status IN ('active','suspended','revoked')),
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active','suspended','cancelled')),
+    status_reason VARCHAR(255) NOT NULL DEFAULT '',
+    status_changed_at VARCHAR(19) NOT NULL DEFAULT '',
+    bundle_id INTEGER REFERENCES bundle (bundle_id) ON DELETE SET NULL,
+    plan_id INTEGER REFERENCES plan (plan_id) ON DELETE SET NULL,
+    inactivity_days INTEGER,
+    inactivity_action VARCHAR(10) CHECK (inactivity_action IN ('flag','release')),
     FOREIGN KEY (customer_id) REFERENCES customer (customer_id) ON DELETE CASCADE,
     FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
 );
@@ -66,31 +88,185 @@ CREATE TABLE IF NOT EXISTS license (
 CREATE INDEX IF NOT EXISTS idx_license_customer_id ON license (customer_id ASC);
 CREATE INDEX IF NOT EXISTS idx_license_product_id ON license (product_id ASC);
 CREATE UNIQUE INDEX IF NOT EXISTS idx_license_key ON license (license_key);
+CREATE INDEX IF NOT EXISTS idx_license_bundle_id ON license (customer_id, bundle_id);
+CREATE INDEX IF NOT EXISTS idx_license_plan_id ON license (plan_id ASC);
 
 
 CREATE TABLE IF NOT EXISTS feature (
     feature_id INTEGER PRIMARY KEY AUTOINCREMENT,
     product_id INTEGER NOT NULL,
     feature_name VARCHAR(255) NOT NULL,	
-    feature_type INTEGER NOT NULL CHECK (feature_type in (0,1,2)) DEFAULT 0,		
+    feature_type INTEGER NOT NULL CHECK (feature_type in (0,1,2,3,4)) DEFAULT 0,		
     allowed_values VARCHAR(255),
     default_value VARCHAR(255),
+    min_value INTEGER,
+    max_value INTEGER,
+    max_length INTEGER NOT NULL DEFAULT 0,
+    feature_status TEXT NOT NULL DEFAULT 'active' CHECK (feature_status IN ('active', 'deprecated', 'hidden')),
+    status_date TEXT,
     FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
 );
 
 CREATE INDEX IF NOT EXISTS idx_feature_product_id ON feature (product_id ASC);
 CREATE UNIQUE INDEX IF NOT EXISTS idx_feature_product_name ON feature (product_id, feature_name COLLATE NOCASE);
 
+CREATE TABLE IF NOT EXISTS feature_alias (
+    alias_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feature_id INTEGER NOT NULL,
+    alias_name VARCHAR(255) NOT NULL,
+    cutoff_date TEXT NOT NULL,
+    FOREIGN KEY (feature_id) REFERENCES feature (feature_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_feature_alias_feature_id ON feature_alias (feature_id ASC);
+
 
 CREATE TABLE IF NOT EXISTS license_feature (
-    customer_id INTEGER NOT NULL,
-    product_id INTEGER NOT NULL,
+    license_id INTEGER NOT NULL,
     feature_id INTEGER NOT NULL,
     feature_value VARCHAR(255) NOT NULL,
-    CONSTRAINT pk_license_feature PRIMARY KEY (customer_id, product_id, feature_id),
+    CONSTRAINT pk_license_feature PRIMARY KEY (license_id, feature_id),
     FOREIGN KEY (feature_id) REFERENCES feature (feature_id),
-    FOREIGN KEY (customer_id, product_id) REFERENCES license (customer_id, product_id) ON DELETE CASCADE
+    FOREIGN KEY (license_id) REFERENCES license (license_id) ON DELETE CASCADE
 );
 
 CREATE INDEX IF NOT EXISTS idx_license_feature_feature_id ON license_feature (feature_id ASC);
-CREATE INDEX IF NOT EXISTS idx_license_feature_custid_prodid ON license_feature (customer_id ASC, product_id ASC);
+
+
+CREATE TABLE IF NOT EXISTS activation_event (
+    event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    customer_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    machine_id INTEGER NOT NULL,
+    event_type VARCHAR(20) NOT NULL CHECK (event_type IN ('new','reactivation')),
+    user_name VARCHAR(255) NOT NULL DEFAULT '',
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    client_version VARCHAR(20) NOT NULL DEFAULT '',
+    event_time VARCHAR(19) NOT NULL,
+    FOREIGN KEY (customer_id) REFERENCES customer (customer_id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE,
+    FOREIGN KEY (machine_id) REFERENCES machine (machine_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_actevent_time ON activation_event (event_time ASC);
+CREATE INDEX IF NOT EXISTS idx_actevent_custid_prodid ON activation_event (customer_id ASC, product_id ASC);
+
+
+CREATE TABLE IF NOT EXISTS license_key_history (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    license_id INTEGER NOT NULL,
+    license_key VARCHAR(36) NOT NULL COLLATE NOCASE,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    replaced_at VARCHAR(19) NOT NULL,
+    grace_until VARCHAR(19) NOT NULL,
+    revoked_at VARCHAR(19) NOT NULL DEFAULT '',
+    FOREIGN KEY (license_id) REFERENCES license (license_id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keyhist_key ON license_key_history (license_key);
+CREATE INDEX IF NOT EXISTS idx_keyhist_license_id ON license_key_history (license_id ASC);
+
+CREATE TABLE IF NOT EXISTS machine_transfer (
+    transfer_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    machine_id INTEGER NOT NULL,
+    from_customer_id INTEGER NOT NULL,
+    to_customer_id INTEGER NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    transferred_at VARCHAR(19) NOT NULL,
+    FOREIGN KEY (machine_id) REFERENCES machine (machine_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_machine_id ON machine_transfer (machine_id ASC);
+
+CREATE TABLE IF NOT EXISTS customer_contact (
+    contact_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    customer_id INTEGER NOT NULL,
+    contact_name VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    roles VARCHAR(255) NOT NULL DEFAULT '',
+    is_primary BOOLEAN NOT NULL DEFAULT 0,
+    notes TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (customer_id) REFERENCES customer (customer_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_customer_id ON customer_contact (customer_id ASC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contact_primary ON customer_contact (customer_id) WHERE is_primary = 1;
+
+CREATE TABLE IF NOT EXISTS reseller (
+    reseller_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reseller_name VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    seat_allocation INTEGER NOT NULL DEFAULT 0 CHECK (seat_allocation >= 0),
+    api_key_hash CHAR(64) NOT NULL UNIQUE,
+    api_key_prefix VARCHAR(12) NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at VARCHAR(19) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bundle (
+    bundle_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    bundle_name VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at VARCHAR(19) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bundle_product (
+    bundle_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    CONSTRAINT pk_bundle_product PRIMARY KEY (bundle_id, product_id),
+    FOREIGN KEY (bundle_id) REFERENCES bundle (bundle_id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS customer_bundle (
+    customer_id INTEGER NOT NULL,
+    bundle_id INTEGER NOT NULL,
+    license_key VARCHAR(36) NOT NULL UNIQUE COLLATE NOCASE,
+    created_at VARCHAR(19) NOT NULL,
+    CONSTRAINT pk_customer_bundle PRIMARY KEY (customer_id, bundle_id),
+    FOREIGN KEY (customer_id) REFERENCES customer (customer_id) ON DELETE CASCADE,
+    FOREIGN KEY (bundle_id) REFERENCES bundle (bundle_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS plan (
+    plan_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    plan_name VARCHAR(255) NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_plan_product_name ON plan (product_id, plan_name COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS plan_feature (
+    plan_id INTEGER NOT NULL,
+    feature_id INTEGER NOT NULL,
+    feature_value TEXT NOT NULL,
+    CONSTRAINT pk_plan_feature PRIMARY KEY (plan_id, feature_id),
+    FOREIGN KEY (plan_id) REFERENCES plan (plan_id) ON DELETE CASCADE,
+    FOREIGN KEY (feature_id) REFERENCES feature (feature_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_feature_feature_id ON plan_feature (feature_id ASC);
+
+
+CREATE TABLE IF NOT EXISTS bulk_operation (
+    operation_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    operation_time VARCHAR(19) NOT NULL,
+    selection TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    license_count INTEGER NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_operation_product_id ON bulk_operation (product_id ASC);
+
+CREATE TABLE IF NOT EXISTS bulk_operation_license (
+    operation_id INTEGER NOT NULL,
+    license_id INTEGER NOT NULL,
+    customer_name VARCHAR(255) NOT NULL,
+    changes TEXT NOT NULL,
+    CONSTRAINT pk_bulk_operation_license PRIMARY KEY (operation_id, license_id),
+    FOREIGN KEY (operation_id) REFERENCES bulk_operation (operation_id) ON DELETE CASCADE
+);
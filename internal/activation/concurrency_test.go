@@ -0,0 +1,125 @@
+package activation_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+// TestActivateConcurrent fires parallel activations at licenses on a
+// file-backed database and checks that no license ends up with more machines
+// than its license count
+func TestActivateConcurrent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+
+	activationSvc := activation.NewService(
+		db,
+		"test-secret",
+		custSvc,
+		machineSvc,
+		registration.NewService(db),
+		licenseSvc,
+		prodSvc,
+		feature.NewService(db),
+		featurevalue.NewService(db),
+		analytics.NewService(db),
+	)
+
+	prod, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Test Product",
+		ProductGUID:   "TEST-GUID-CONCURRENT",
+		LatestVersion: "1.0.0",
+	})
+
+	const (
+		rounds   = 5
+		seats    = 3
+		machines = 12 // activating at once, each twice
+	)
+	expires := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+
+	for round := range rounds {
+		cust, err := custSvc.Create(ctx, &customer.Customer{CustomerName: fmt.Sprintf("Concurrent Co %d", round)})
+		if err != nil {
+			t.Fatalf("create customer: %v", err)
+		}
+		lic, err := licenseSvc.Create(ctx, &license.License{
+			CustomerID:          cust.CustomerID,
+			ProductID:           prod.ProductID,
+			LicenseKey:          fmt.Sprintf("CONCURRENT-%d", round),
+			LicenseCount:        seats,
+			StartDate:           time.Now().Format("2006-01-02"),
+			ExpirationDate:      expires,
+			MaintExpirationDate: expires,
+		})
+		if err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			won   = map[string]bool{}
+			start = make(chan struct{})
+		)
+		for i := range machines * 2 {
+			code := fmt.Sprintf("MACHINE-%d-%d", round, i%machines)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: code, UserName: "user"})
+				switch {
+				case err == nil:
+					mu.Lock()
+					won[code] = true
+					mu.Unlock()
+				case !errors.Is(err, activation.ErrLicenseCountExceeded):
+					t.Errorf("activate %s: %v", code, err)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		active, err := machineSvc.GetActiveForLicense(ctx, lic.LicenseID)
+		if err != nil {
+			t.Fatalf("get active machines: %v", err)
+		}
+		if len(active) > seats || len(won) > seats {
+			t.Fatalf("round %d: %d machines registered and %d activated on %d seats", round, len(active), len(won), seats)
+		}
+		if len(active) != seats {
+			t.Errorf("round %d: expected all %d seats taken, got %d", round, seats, len(active))
+		}
+		for _, m := range active {
+			if !won[m.MachineCode] {
+				t.Errorf("round %d: %s holds a seat but its activation failed", round, m.MachineCode)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package activation
+
+import (
+	"errors"
+
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/product"
+)
+
+var (
+	// ErrNoLicense is returned when the customer has no license for the product
+	ErrNoLicense = errors.New("no license")
+
+	// ErrLicenseCountExceeded is returned when all license seats are in use by other machines
+	ErrLicenseCountExceeded = errors.New("license count exceeded")
+
+	// ErrProductRequired is returned when a bundle license key is used without naming the product
+	ErrProductRequired = errors.New("productGuid is required for a bundle license key")
+
+	// ErrProductNotLicensed is returned when the license key does not cover the requested product
+	ErrProductNotLicensed = errors.New("license key is not valid for this product")
+
+	// ErrMachineCodeRequired is returned when a registration is created without a machine code
+	ErrMachineCodeRequired = errors.New("machineCode is required")
+
+	// ErrNotRegistered is returned when a machine asks to refresh a registration it does not have
+	ErrNotRegistered = errors.New("machine is not registered for this license")
+)
+
+// CodeSeatLimit is the error code clients receive with ErrLicenseCountExceeded
+const CodeSeatLimit = "seat_limit"
+
+// FailureReason classifies an activation error for metrics and logging
+func FailureReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrLicenseCountExceeded):
+		return CodeSeatLimit
+	case errors.Is(err, ErrProductRequired), errors.Is(err, ErrMachineCodeRequired):
+		return "bad_request"
+	case errors.Is(err, ErrProductNotLicensed):
+		return "no_license"
+	case errors.Is(err, license.ErrLicenseSuspended), errors.Is(err, license.ErrLicenseCancelled):
+		return "license_inactive"
+	case errors.Is(err, ErrNoLicense), errors.Is(err, license.ErrNotFound),
+		errors.Is(err, product.ErrNotFound), errors.Is(err, customer.ErrNotFound):
+		return "no_license"
+	default:
+		return "internal"
+	}
+}
@@ -0,0 +1,40 @@
+package activation_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestFailureReason(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	_, missingLicense := license.NewService(db).Get(ctx, 9999)
+	_, missingProduct := product.NewService(db).GetByGUID(ctx, "NO-SUCH-GUID")
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("activate: %w", activation.ErrLicenseCountExceeded), "seat_limit"},
+		{activation.ErrMachineCodeRequired, "bad_request"},
+		{license.ErrLicenseSuspended, "license_inactive"},
+		{activation.ErrNoLicense, "no_license"},
+		{missingLicense, "no_license"},
+		{missingProduct, "no_license"},
+		{errors.New("backup file not found"), "internal"}, // only the sentinels count
+	}
+	for _, tt := range tests {
+		if got := activation.FailureReason(tt.err); got != tt.want {
+			t.Errorf("FailureReason(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
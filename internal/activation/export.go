@@ -0,0 +1,80 @@
+package activation
+
+import (
+	"context"
+	"errors"
+
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/registration"
+)
+
+// BuildRegistration returns a machine's current signed registration of a
+// product, as stored. Nothing is written: no seat is checked, the dates and
+// hash are not touched and no activation is recorded, so it can be used to
+// export or resend a registration file. The features are those the
+// registration was last activated with (see Refresh), which its stored hash
+// covers. A registration released by the inactivity policy must be activated
+// again.
+func (s *Service) BuildRegistration(ctx context.Context, machineID, productID int64) (*Response, error) {
+	reg, err := s.regSvc.Get(ctx, machineID, productID)
+	if err != nil {
+		if errors.Is(err, registration.ErrNotFound) {
+			return nil, ErrNotRegistered
+		}
+		return nil, err
+	}
+	if reg.LicenseID == nil || reg.IsReleased() {
+		return nil, ErrNotRegistered
+	}
+
+	lic, err := s.licenseSvc.Get(ctx, *reg.LicenseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := lic.CheckStatus(); err != nil {
+		return nil, err
+	}
+	m, err := s.machineSvc.Get(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrNotRegistered
+	}
+	cust, err := s.customerSvc.Get(ctx, lic.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	prod, err := s.productSvc.Get(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := s.featureSvc.GetAsOf(ctx, productID, reg.LastRegistrationDate)
+	if err != nil {
+		return nil, err
+	}
+	planVals, err := s.featureValueSvc.GetPlanValues(ctx, lic.LicenseID)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := s.featureValueSvc.GetFeatureValues(ctx, lic.LicenseID)
+	if err != nil {
+		return nil, err
+	}
+	merged := feature.MergeWithOverrides(defs, planVals, vals)
+
+	return &Response{
+		UserName:            m.UserName,
+		UserCompany:         cust.CustomerName,
+		MachineCode:         m.MachineCode,
+		ExpirationDate:      reg.ExpirationDate,
+		MaintExpirationDate: lic.MaintExpirationDate,
+		MaxProductVersion:   lic.MaxProductVersion,
+		LatestVersion:       prod.LatestVersion,
+		ProductGUID:         prod.ProductGUID,
+		LicenseKey:          lic.LicenseKey,
+		RegistrationHash:    reg.RegistrationHash,
+		Features:            feature.TypedValues(defs, merged),
+	}, nil
+}
@@ -0,0 +1,144 @@
+package activation_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestBuildRegistration(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+	featureSvc := feature.NewService(db)
+
+	activationSvc := activation.NewService(
+		db,
+		"test-secret",
+		custSvc,
+		machineSvc,
+		regSvc,
+		licenseSvc,
+		prodSvc,
+		featureSvc,
+		featurevalue.NewService(db),
+		analytics.NewService(db),
+	)
+
+	prod, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Test Product",
+		ProductGUID:   "TEST-GUID-EXPORT",
+		LatestVersion: "1.0.0",
+	})
+	if _, err := featureSvc.Create(ctx, &feature.Feature{
+		ProductID:    prod.ProductID,
+		FeatureName:  "MaxUsers",
+		FeatureType:  featurevalue.TypeInteger,
+		DefaultValue: "10",
+	}); err != nil {
+		t.Fatalf("create feature: %v", err)
+	}
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Export Co"})
+	expires := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+	lic, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           prod.ProductID,
+		LicenseKey:          "EXPORT-KEY",
+		LicenseCount:        1,
+		StartDate:           time.Now().Format("2006-01-02"),
+		ExpirationDate:      expires,
+		MaintExpirationDate: expires,
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	activated, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: "MACHINE-A", UserName: "user a"})
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	m, _ := machineSvc.GetByCode(ctx, cust.CustomerID, "MACHINE-A")
+
+	stored := func() registration.Registration {
+		t.Helper()
+		reg, err := regSvc.Get(ctx, m.MachineID, prod.ProductID)
+		if err != nil {
+			t.Fatalf("get registration: %v", err)
+		}
+		return *reg
+	}
+	events := func() int {
+		t.Helper()
+		var n int
+		if err := db.Get(&n, "SELECT COUNT(*) FROM activation_event"); err != nil {
+			t.Fatalf("count events: %v", err)
+		}
+		return n
+	}
+
+	t.Run("matches the activation", func(t *testing.T) {
+		resp, err := activationSvc.BuildRegistration(ctx, m.MachineID, prod.ProductID)
+		if err != nil {
+			t.Fatalf("BuildRegistration: %v", err)
+		}
+		if !reflect.DeepEqual(resp, activated) {
+			t.Errorf("expected the activation's registration\n got %+v\nwant %+v", resp, activated)
+		}
+	})
+
+	t.Run("changes nothing", func(t *testing.T) {
+		// An expired registration whose seat was taken cannot be activated again
+		if _, err := db.Exec("UPDATE registration SET expiration_date = ?, last_registration_date = ? WHERE machine_id = ?",
+			time.Now().AddDate(0, 0, -1).Format("2006-01-02"), time.Now().AddDate(0, 0, -30).Format("2006-01-02"), m.MachineID); err != nil {
+			t.Fatalf("expire registration: %v", err)
+		}
+		if _, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: "MACHINE-B", UserName: "user b"}); err != nil {
+			t.Fatalf("activate second machine: %v", err)
+		}
+
+		before, count := stored(), events()
+		resp, err := activationSvc.BuildRegistration(ctx, m.MachineID, prod.ProductID)
+		if err != nil {
+			t.Fatalf("expected an export with all seats in use to succeed: %v", err)
+		}
+		if after := stored(); !reflect.DeepEqual(after, before) {
+			t.Errorf("expected the registration unchanged\n got %+v\nwant %+v", after, before)
+		}
+		if events() != count {
+			t.Error("expected no activation to be recorded")
+		}
+		if resp.ExpirationDate != before.ExpirationDate || resp.RegistrationHash != before.RegistrationHash {
+			t.Errorf("expected the stored dates and hash, got %+v", resp)
+		}
+	})
+
+	t.Run("rejects released and unknown registrations", func(t *testing.T) {
+		if _, err := activationSvc.BuildRegistration(ctx, m.MachineID, 9999); !errors.Is(err, activation.ErrNotRegistered) {
+			t.Errorf("expected ErrNotRegistered for an unknown registration, got %v", err)
+		}
+		if _, err := db.Exec("UPDATE registration SET released_date = ? WHERE machine_id = ?", time.Now().Format("2006-01-02"), m.MachineID); err != nil {
+			t.Fatalf("release registration: %v", err)
+		}
+		if _, err := activationSvc.BuildRegistration(ctx, m.MachineID, prod.ProductID); !errors.Is(err, activation.ErrNotRegistered) {
+			t.Errorf("expected ErrNotRegistered for a released registration, got %v", err)
+		}
+	})
+}
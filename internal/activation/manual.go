@@ -0,0 +1,80 @@
+package activation
+
+import (
+	"context"
+	"strings"
+)
+
+// ActivateForCustomer activates a product for a machine of a customer without
+// the license key, as done for offline (manual) registrations. A machine
+// already registered for the product keeps its license; otherwise the first
+// active license of the product with a free seat is used.
+func (s *Service) ActivateForCustomer(ctx context.Context, customerID, productID int64, req *Request) (*Response, error) {
+	if strings.TrimSpace(req.MachineCode) == "" {
+		return nil, ErrMachineCodeRequired
+	}
+	if _, err := s.customerSvc.Get(ctx, customerID); err != nil {
+		return nil, err
+	}
+	if _, err := s.productSvc.Get(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	licenseID, err := s.registeredLicense(ctx, customerID, productID, req.MachineCode)
+	if err != nil {
+		return nil, err
+	}
+	if licenseID == 0 {
+		lic, err := s.freeLicense(ctx, customerID, productID)
+		if err != nil {
+			return nil, err
+		}
+		licenseID = lic.LicenseID
+	}
+	return s.Activate(ctx, licenseID, req)
+}
+
+// registeredLicense returns the license a customer's machine is registered
+// under for the product (0 = not registered)
+func (s *Service) registeredLicense(ctx context.Context, customerID, productID int64, machineCode string) (int64, error) {
+	m, err := s.machineSvc.GetByCode(ctx, customerID, machineCode)
+	if err != nil || m == nil {
+		return 0, err
+	}
+	regs, err := s.regSvc.GetForMachine(ctx, m.MachineID)
+	if err != nil {
+		return 0, err
+	}
+	for _, reg := range regs {
+		if reg.ProductID == productID && reg.LicenseID != nil {
+			return *reg.LicenseID, nil
+		}
+	}
+	return 0, nil
+}
+
+// FileName returns the name of a download made of the given parts, e.g.
+// {product}-{company}-{username}.json for a registration file
+func FileName(ext string, parts ...string) string {
+	for i, p := range parts {
+		parts[i] = sanitizeFilename(p)
+	}
+	return strings.Join(parts, "-") + ext
+}
+
+// sanitizeFilename removes or replaces characters that are invalid in filenames
+func sanitizeFilename(s string) string {
+	replacer := strings.NewReplacer(
+		" ", "_",
+		"/", "_",
+		"\\", "_",
+		":", "_",
+		"*", "_",
+		"?", "_",
+		"\"", "_",
+		"<", "_",
+		">", "_",
+		"|", "_",
+	)
+	return replacer.Replace(s)
+}
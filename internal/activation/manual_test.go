@@ -0,0 +1,135 @@
+package activation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestActivateForCustomer(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+
+	activationSvc := activation.NewService(
+		db,
+		"test-secret",
+		custSvc,
+		machineSvc,
+		regSvc,
+		licenseSvc,
+		prodSvc,
+		feature.NewService(db),
+		featurevalue.NewService(db),
+		analytics.NewService(db),
+	)
+
+	prod, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Test Product",
+		ProductGUID:   "TEST-GUID-MANUAL",
+		LatestVersion: "1.0.0",
+	})
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Offline Co"})
+	unlicensed, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Unlicensed Co"})
+
+	newLicense := func(key string, seats int) *license.License {
+		t.Helper()
+		expires := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+		lic, err := licenseSvc.Create(ctx, &license.License{
+			CustomerID:          cust.CustomerID,
+			ProductID:           prod.ProductID,
+			LicenseKey:          key,
+			LicenseCount:        seats,
+			StartDate:           time.Now().Format("2006-01-02"),
+			ExpirationDate:      expires,
+			MaintExpirationDate: expires,
+		})
+		if err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+		return lic
+	}
+	first := newLicense("MANUAL-1", 1)
+	second := newLicense("MANUAL-2", 1)
+
+	register := func(customerID int64, machineCode string) (*activation.Response, error) {
+		return activationSvc.ActivateForCustomer(ctx, customerID, prod.ProductID, &activation.Request{
+			MachineCode: machineCode,
+			UserName:    "offline user",
+		})
+	}
+	licenseOf := func(machineCode string) int64 {
+		t.Helper()
+		m, err := machineSvc.GetByCode(ctx, cust.CustomerID, machineCode)
+		if err != nil || m == nil {
+			t.Fatalf("get machine %s: %v", machineCode, err)
+		}
+		reg, err := regSvc.Get(ctx, m.MachineID, prod.ProductID)
+		if err != nil || reg.LicenseID == nil {
+			t.Fatalf("get registration: %v", err)
+		}
+		return *reg.LicenseID
+	}
+
+	t.Run("requires a machine code", func(t *testing.T) {
+		if _, err := register(cust.CustomerID, " "); !errors.Is(err, activation.ErrMachineCodeRequired) {
+			t.Errorf("expected ErrMachineCodeRequired, got %v", err)
+		}
+	})
+
+	t.Run("rejects a customer without license", func(t *testing.T) {
+		if _, err := register(unlicensed.CustomerID, "MACHINE-X"); !errors.Is(err, activation.ErrNoLicense) {
+			t.Errorf("expected ErrNoLicense, got %v", err)
+		}
+	})
+
+	t.Run("uses the first license with a free seat", func(t *testing.T) {
+		resp, err := register(cust.CustomerID, "MACHINE-A")
+		if err != nil {
+			t.Fatalf("register: %v", err)
+		}
+		if resp.LicenseKey != first.LicenseKey || resp.RegistrationHash == "" {
+			t.Errorf("expected a signed registration of the first license, got %+v", resp)
+		}
+
+		if _, err := register(cust.CustomerID, "MACHINE-B"); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+		if got := licenseOf("MACHINE-B"); got != second.LicenseID {
+			t.Errorf("expected the second license once the first is full, got %d", got)
+		}
+	})
+
+	t.Run("keeps the license of a registered machine", func(t *testing.T) {
+		resp, err := register(cust.CustomerID, "MACHINE-B")
+		if err != nil {
+			t.Fatalf("re-register: %v", err)
+		}
+		if resp.LicenseKey != second.LicenseKey || licenseOf("MACHINE-B") != second.LicenseID {
+			t.Errorf("expected the machine to stay on the second license, got %s", resp.LicenseKey)
+		}
+	})
+
+	t.Run("rejects when all seats are in use", func(t *testing.T) {
+		if _, err := register(cust.CustomerID, "MACHINE-C"); !errors.Is(err, activation.ErrLicenseCountExceeded) {
+			t.Errorf("expected ErrLicenseCountExceeded, got %v", err)
+		}
+	})
+}
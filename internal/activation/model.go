@@ -1,8 +1,14 @@
 package activation
 
 type Request struct {
-	MachineCode string `json:"machineCode"`
-	UserName    string `json:"userName"`
+	MachineCode      string `json:"machineCode"`
+	UserName         string `json:"userName"`
+	InstalledVersion string `json:"installedVersion,omitempty"` // optional, recorded with the activation event
+	ProductGUID      string `json:"productGuid,omitempty"`      // product to activate; required for bundle license keys
+	OS               string `json:"os,omitempty"`               // optional, shown to support with the registration
+	Hostname         string `json:"hostname,omitempty"`         // optional, shown to support with the registration
+
+	ClientIP string `json:"-"` // set by the handler from the request (not part of the body)
 }
 
 type Response struct {
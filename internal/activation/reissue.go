@@ -0,0 +1,203 @@
+package activation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/registration"
+)
+
+// ReissueLicense recomputes the expiration date and hash of every registration
+// of a license from its current terms and feature values, in the caller's
+// transaction. It implements registration.Reissuer.
+func (s *Service) ReissueLicense(ctx context.Context, tx *sqlx.Tx, licenseID int64) error {
+	regs, err := s.regSvc.GetIssuedForLicense(ctx, tx, licenseID)
+	if err != nil {
+		return err
+	}
+	return s.reissue(ctx, tx, regs)
+}
+
+// ReissueProduct recomputes every registration of a product, for changes that
+// affect all its licenses such as feature defaults and plan values. It
+// implements registration.Reissuer.
+func (s *Service) ReissueProduct(ctx context.Context, tx *sqlx.Tx, productID int64) error {
+	regs, err := s.regSvc.GetIssuedForProduct(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	return s.reissue(ctx, tx, regs)
+}
+
+// ReissueMachine recomputes every registration of a machine, for a machine
+// whose stored code changed after fingerprint drift. It implements
+// registration.Reissuer.
+func (s *Service) ReissueMachine(ctx context.Context, tx *sqlx.Tx, machineID int64) error {
+	regs, err := s.regSvc.GetIssuedForMachine(ctx, tx, machineID)
+	if err != nil {
+		return err
+	}
+	return s.reissue(ctx, tx, regs)
+}
+
+// reissue stores the recomputed expiration date and hash of the registrations
+// that changed. Each keeps its last registration date, so its features are
+// named as when it was activated and same-day activations keep their hash.
+func (s *Service) reissue(ctx context.Context, tx *sqlx.Tx, regs []registration.Issued) error {
+	licenses := make(map[int64]*license.License)
+	changed := 0
+	for i := range regs {
+		reg := &regs[i]
+		if reg.LicenseID == nil {
+			continue
+		}
+
+		lic, ok := licenses[*reg.LicenseID]
+		if !ok {
+			l, err := s.licenseSvc.GetTx(ctx, tx, *reg.LicenseID)
+			if err != nil {
+				return err
+			}
+			lic = l
+			licenses[lic.LicenseID] = lic
+		}
+
+		regHash, _, err := s.registrationHash(ctx, tx, reg.MachineCode, lic, reg.LastRegistrationDate)
+		if err != nil {
+			return err
+		}
+		if reg.ExpirationDate == lic.ExpirationDate && reg.RegistrationHash == regHash {
+			continue
+		}
+
+		reg.ExpirationDate = lic.ExpirationDate
+		reg.RegistrationHash = regHash
+		if err := s.regSvc.Reissue(ctx, tx, &reg.Registration); err != nil {
+			return err
+		}
+		changed++
+	}
+
+	if changed > 0 {
+		logging.FromContext(ctx).Info("registrations reissued", "registrations", changed, "licenses", len(licenses))
+	}
+	return nil
+}
+
+// registrationHash computes the hash of a machine's registration of a license
+// activated on asOf, from the license as changed so far in tx. It also returns
+// the features emitted to the registration with their typed values.
+func (s *Service) registrationHash(ctx context.Context, tx *sqlx.Tx, machineCode string, lic *license.License, asOf string) (string, map[string]any, error) {
+	defs, err := s.featureSvc.GetAsOfTx(ctx, tx, lic.ProductID, asOf)
+	if err != nil {
+		return "", nil, err
+	}
+	planVals, err := s.featureValueSvc.GetPlanValuesTx(ctx, tx, lic.LicenseID)
+	if err != nil {
+		return "", nil, err
+	}
+	vals, err := s.featureValueSvc.GetFeatureValuesTx(ctx, tx, lic.LicenseID)
+	if err != nil {
+		return "", nil, err
+	}
+	merged := feature.MergeWithOverrides(defs, planVals, vals)
+
+	regHash, err := s.computeHash(machineCode, lic.ExpirationDate, lic.MaintExpirationDate, lic.MaxProductVersion, merged)
+	if err != nil {
+		return "", nil, fmt.Errorf("compute registration hash: %w", err)
+	}
+	return regHash, feature.TypedValues(defs, merged), nil
+}
+
+// Refresh returns a machine's registration re-issued under the license's
+// current terms, so a client picks up changed dates and features without
+// activating again. No seat is checked and the registration keeps its dates,
+// so its features keep the names they were activated with. A machine whose
+// fingerprint has drifted is recognised as at activation, and its registration
+// is re-issued for the new code. A registration released by the inactivity
+// policy must be activated again.
+func (s *Service) Refresh(ctx context.Context, licenseID int64, machineCode string) (*Response, error) {
+	lic, err := s.licenseSvc.Get(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := lic.CheckStatus(); err != nil {
+		return nil, err
+	}
+
+	cust, err := s.customerSvc.Get(ctx, lic.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	prod, err := s.productSvc.Get(ctx, lic.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	var m *machine.Machine
+	var reg *registration.Registration
+	var features map[string]any
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		// The matcher's update of a drifted code is rolled back unless the
+		// machine holds a registration of this license
+		m, err = s.machineSvc.Find(ctx, tx, lic.CustomerID, machineCode)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return ErrNotRegistered
+		}
+		reg, err = s.regSvc.Get(ctx, m.MachineID, lic.ProductID)
+		if err != nil {
+			if errors.Is(err, registration.ErrNotFound) {
+				return ErrNotRegistered
+			}
+			return err
+		}
+		if reg.LicenseID == nil || *reg.LicenseID != licenseID || reg.IsReleased() {
+			return ErrNotRegistered
+		}
+
+		regHash, typed, err := s.registrationHash(ctx, tx, m.MachineCode, lic, reg.LastRegistrationDate)
+		if err != nil {
+			return err
+		}
+		features = typed
+		if reg.ExpirationDate == lic.ExpirationDate && reg.RegistrationHash == regHash {
+			return nil
+		}
+		reg.ExpirationDate = lic.ExpirationDate
+		reg.RegistrationHash = regHash
+		return s.regSvc.Reissue(ctx, tx, reg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("registration refreshed",
+		"license_id", licenseID,
+		"machine_id", m.MachineID,
+		"product_id", lic.ProductID,
+	)
+
+	return &Response{
+		UserName:            m.UserName,
+		UserCompany:         cust.CustomerName,
+		MachineCode:         m.MachineCode,
+		ExpirationDate:      lic.ExpirationDate,
+		MaintExpirationDate: lic.MaintExpirationDate,
+		MaxProductVersion:   lic.MaxProductVersion,
+		LatestVersion:       prod.LatestVersion,
+		ProductGUID:         prod.ProductGUID,
+		LicenseKey:          lic.LicenseKey,
+		RegistrationHash:    reg.RegistrationHash,
+		Features:            features,
+	}, nil
+}
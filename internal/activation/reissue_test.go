@@ -0,0 +1,171 @@
+package activation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestReissue(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+	featureSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+
+	activationSvc := activation.NewService(
+		db,
+		"test-secret",
+		custSvc,
+		machineSvc,
+		regSvc,
+		licenseSvc,
+		prodSvc,
+		featureSvc,
+		fvSvc,
+		analytics.NewService(db),
+	)
+	licenseSvc.SetReissuer(activationSvc)
+	fvSvc.SetReissuer(activationSvc)
+	featureSvc.SetReissuer(activationSvc)
+
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Reissue Co"})
+	prod, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Reissue App", ProductGUID: "TEST-GUID-REISSUE", LatestVersion: "1.0.0"})
+	seats, _ := featureSvc.Create(ctx, &feature.Feature{ProductID: prod.ProductID, FeatureName: "Seats", FeatureType: featurevalue.TypeInteger, DefaultValue: "1"})
+
+	today := time.Now().Format("2006-01-02")
+	lic, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           prod.ProductID,
+		LicenseKey:          "REISSUE-KEY",
+		LicenseCount:        2,
+		StartDate:           today,
+		ExpirationDate:      time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+		MaintExpirationDate: time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	activate := func(machineCode string) *activation.Response {
+		t.Helper()
+		resp, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: machineCode, UserName: "user"})
+		if err != nil {
+			t.Fatalf("activate %s: %v", machineCode, err)
+		}
+		return resp
+	}
+	stored := func(machineCode string) *registration.Registration {
+		t.Helper()
+		m, err := machineSvc.GetByCode(ctx, cust.CustomerID, machineCode)
+		if err != nil || m == nil {
+			t.Fatalf("get machine %s: %v", machineCode, err)
+		}
+		reg, err := regSvc.Get(ctx, m.MachineID, prod.ProductID)
+		if err != nil {
+			t.Fatalf("get registration: %v", err)
+		}
+		return reg
+	}
+
+	first := activate("MACHINE-A")
+	activate("MACHINE-B")
+
+	t.Run("license terms", func(t *testing.T) {
+		renewed := time.Now().AddDate(2, 0, 0).Format("2006-01-02")
+		cur, _ := licenseSvc.Get(ctx, lic.LicenseID)
+		cur.ExpirationDate = renewed
+		cur.MaxProductVersion = "2.0.0"
+		if err := licenseSvc.Update(ctx, cur); err != nil {
+			t.Fatalf("update license: %v", err)
+		}
+
+		for _, code := range []string{"MACHINE-A", "MACHINE-B"} {
+			reg := stored(code)
+			if reg.ExpirationDate != renewed {
+				t.Errorf("%s: expected expiration %s, got %s", code, renewed, reg.ExpirationDate)
+			}
+			if reg.LastRegistrationDate != today {
+				t.Errorf("%s: expected last registration date kept, got %s", code, reg.LastRegistrationDate)
+			}
+		}
+		if stored("MACHINE-A").RegistrationHash == first.RegistrationHash {
+			t.Error("expected the registration hash to be re-issued")
+		}
+	})
+
+	t.Run("feature values", func(t *testing.T) {
+		before := stored("MACHINE-A").RegistrationHash
+		if err := fvSvc.Update(ctx, &featurevalue.FeatureValue{LicenseID: lic.LicenseID, FeatureID: seats.FeatureID, FeatureValue: "5"}); err != nil {
+			t.Fatalf("set feature value: %v", err)
+		}
+		if stored("MACHINE-A").RegistrationHash == before {
+			t.Error("expected a feature value change to re-issue the hash")
+		}
+	})
+
+	t.Run("feature definitions", func(t *testing.T) {
+		// Registrations issued today keep their feature set: a new feature is
+		// emitted from tomorrow
+		before := stored("MACHINE-A").RegistrationHash
+		reports, err := featureSvc.Create(ctx, &feature.Feature{ProductID: prod.ProductID, FeatureName: "Reports", FeatureType: featurevalue.TypeBoolean, DefaultValue: "true"})
+		if err != nil {
+			t.Fatalf("create feature: %v", err)
+		}
+		if tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02"); reports.EffectiveDate != tomorrow {
+			t.Errorf("expected the feature to take effect %s, got %q", tomorrow, reports.EffectiveDate)
+		}
+		if stored("MACHINE-A").RegistrationHash != before {
+			t.Error("expected a new feature to leave issued registrations unchanged")
+		}
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		// Refreshing needs no free seat
+		cur, _ := licenseSvc.Get(ctx, lic.LicenseID)
+		cur.LicenseCount = 1
+		if err := licenseSvc.Update(ctx, cur); err != nil {
+			t.Fatalf("update license: %v", err)
+		}
+
+		resp, err := activationSvc.Refresh(ctx, lic.LicenseID, "MACHINE-B")
+		if err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		reg := stored("MACHINE-B")
+		if resp.RegistrationHash != reg.RegistrationHash || resp.ExpirationDate != reg.ExpirationDate {
+			t.Errorf("expected the stored registration, got %+v", resp)
+		}
+		if _, ok := resp.Features["Reports"]; resp.MaxProductVersion != "2.0.0" || resp.Features["Seats"] != "5" || ok {
+			t.Errorf("expected the current terms and today's features, got %+v", resp)
+		}
+
+		// A reactivation on the same day issues the same registration
+		if again := activate("MACHINE-A"); again.RegistrationHash != stored("MACHINE-A").RegistrationHash {
+			t.Error("expected a reactivation to match the re-issued hash")
+		}
+	})
+
+	t.Run("refresh unknown machine", func(t *testing.T) {
+		if _, err := activationSvc.Refresh(ctx, lic.LicenseID, "MACHINE-X"); !errors.Is(err, activation.ErrNotRegistered) {
+			t.Errorf("expected ErrNotRegistered, got %v", err)
+		}
+	})
+}
@@ -2,15 +2,19 @@ package activation
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
+	"winsbygroup.com/regserver/internal/analytics"
 	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/feature"
 	"winsbygroup.com/regserver/internal/featurevalue"
 	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
 	"winsbygroup.com/regserver/internal/machine"
 	"winsbygroup.com/regserver/internal/product"
 	"winsbygroup.com/regserver/internal/registration"
@@ -26,6 +30,7 @@ type Service struct {
 	productSvc         *product.Service
 	featureSvc         *feature.Service
 	featureValueSvc    *featurevalue.Service
+	analyticsSvc       *analytics.Service
 }
 
 func NewService(
@@ -38,6 +43,7 @@ func NewService(
 	productSvc *product.Service,
 	featureSvc *feature.Service,
 	featureValueSvc *featurevalue.Service,
+	analyticsSvc *analytics.Service,
 ) *Service {
 	return &Service{
 		db:                 db,
@@ -49,6 +55,7 @@ func NewService(
 		productSvc:         productSvc,
 		featureSvc:         featureSvc,
 		featureValueSvc:    featureValueSvc,
+		analyticsSvc:       analyticsSvc,
 	}
 }
 
@@ -66,7 +73,7 @@ func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
 
 func (s *Service) Activate(
 	ctx context.Context,
-	customerID, productID int64,
+	licenseID int64,
 	req *Request,
 ) (*Response, error) {
 
@@ -74,33 +81,17 @@ func (s *Service) Activate(
 	var machineID int64
 
 	// License - check early for license validity
-	lic, err := s.licenseSvc.Get(ctx, customerID, productID)
+	lic, err := s.licenseSvc.Get(ctx, licenseID)
 	if err != nil {
 		return nil, err
 	}
 	if lic == nil {
-		return nil, fmt.Errorf("no license for customer %d product %d", customerID, productID)
+		return nil, fmt.Errorf("%w (%d)", ErrNoLicense, licenseID)
 	}
-
-	// License count check - get active machines and verify we haven't exceeded the limit
-	activeMachines, err := s.machineSvc.GetActiveForLicense(ctx, customerID, productID)
-	if err != nil {
+	if err := lic.CheckStatus(); err != nil {
 		return nil, err
 	}
-
-	// Check if this machine is already active (allow re-activation)
-	isExistingMachine := false
-	for _, m := range activeMachines {
-		if m.MachineCode == req.MachineCode {
-			isExistingMachine = true
-			break
-		}
-	}
-
-	// If this is a new machine and we're at the license limit, reject
-	if !isExistingMachine && len(activeMachines) >= lic.LicenseCount {
-		return nil, fmt.Errorf("license count exceeded: %d of %d licenses in use", len(activeMachines), lic.LicenseCount)
-	}
+	customerID, productID := lic.CustomerID, lic.ProductID
 
 	// Fetch customer (for CustomerName)
 	cust, err := s.customerSvc.Get(ctx, customerID)
@@ -114,18 +105,24 @@ func (s *Service) Activate(
 		return nil, err
 	}
 
-	// Features - fetch before transaction so we can compute the hash
-	defs, err := s.featureSvc.GetForProduct(ctx, productID)
+	// Features - fetch before transaction so we can compute the hash, as
+	// emitted to a registration activated today
+	defs, err := s.featureSvc.GetAsOf(ctx, productID, now)
 	if err != nil {
 		return nil, err
 	}
 
-	vals, err := s.featureValueSvc.GetFeatureValues(ctx, customerID, productID)
+	planVals, err := s.featureValueSvc.GetPlanValues(ctx, licenseID)
 	if err != nil {
 		return nil, err
 	}
 
-	merged := feature.MergeWithOverrides(defs, vals)
+	vals, err := s.featureValueSvc.GetFeatureValues(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := feature.MergeWithOverrides(defs, planVals, vals)
 
 	// Compute registration hash (includes MaxProductVersion for tamper detection)
 	regHash, err := s.computeHash(req.MachineCode, lic.ExpirationDate, lic.MaintExpirationDate, lic.MaxProductVersion, merged)
@@ -134,32 +131,77 @@ func (s *Service) Activate(
 	}
 
 	// Machine + registration writes in a single transaction
+	eventType := analytics.EventNew
 	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
 
-		// Machine
+		// Machine - resolved first, so a known machine whose fingerprint has
+		// drifted is recognised by the seat check; rolled back if no seat is free
 		mid, err := s.machineSvc.GetOrCreate(ctx, tx, customerID, req.MachineCode, req.UserName)
 		if err != nil {
 			return err
 		}
 		machineID = mid
 
+		// License count check - counted in the transaction, which holds the
+		// write lock (see sqlite.DSN), so concurrent activations cannot both
+		// take the last seat
+		if err := s.checkSeats(ctx, tx, lic, machineID); err != nil {
+			return err
+		}
+
+		// A machine that was ever registered for this product is a reactivation
+		existed, err := s.regSvc.Exists(ctx, tx, machineID, productID)
+		if err != nil {
+			return err
+		}
+
 		// Registration upsert
 		reg := &registration.Registration{
 			MachineID:             machineID,
 			ProductID:             productID,
+			LicenseID:             &lic.LicenseID,
 			ExpirationDate:        lic.ExpirationDate,
 			RegistrationHash:      regHash,
 			FirstRegistrationDate: now,
 			LastRegistrationDate:  now,
+			LastSeenAt:            time.Now().UTC().Format(registration.TimeFormat),
+			ClientOS:              req.OS,
+			ClientHostname:        req.Hostname,
+			ClientIP:              req.ClientIP,
+		}
+
+		if err := s.regSvc.Upsert(ctx, tx, reg); err != nil {
+			return err
 		}
 
-		return s.regSvc.Upsert(ctx, tx, reg)
+		// Activation history (append-only)
+		if existed {
+			eventType = analytics.EventReactivation
+		}
+		return s.analyticsSvc.Record(ctx, tx, &analytics.Event{
+			CustomerID:    customerID,
+			ProductID:     productID,
+			MachineID:     machineID,
+			EventType:     eventType,
+			UserName:      req.UserName,
+			ClientIP:      req.ClientIP,
+			ClientVersion: req.InstalledVersion,
+		})
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	logging.FromContext(ctx).Info("machine activated",
+		"license_id", licenseID,
+		"customer_id", customerID,
+		"product_id", productID,
+		"machine_id", machineID,
+		"event_type", eventType,
+		"installed_version", req.InstalledVersion,
+	)
+
 	// Build response
 	return &Response{
 		UserName:            req.UserName,
@@ -172,19 +214,56 @@ func (s *Service) Activate(
 		ProductGUID:         prod.ProductGUID,
 		LicenseKey:          lic.LicenseKey,
 		RegistrationHash:    regHash,
-		Features:            merged,
+		Features:            feature.TypedValues(defs, merged),
 	}, nil
 }
 
-// computeHash builds the registration string and computes its HMAC hash
-func (s *Service) computeHash(machineCode, expDate, maintExpDate, maxVersion string, features map[string]any) (string, error) {
-	// Convert features to string map
-	featStr := make(map[string]string, len(features))
-	for k, v := range features {
-		featStr[k] = fmt.Sprintf("%v", v)
+// checkSeats returns ErrLicenseCountExceeded if a machine that does not hold
+// a seat of the license yet would exceed its license count (re-activations
+// are always allowed)
+func (s *Service) checkSeats(ctx context.Context, tx *sqlx.Tx, lic *license.License, machineID int64) error {
+	activeMachines, err := s.machineSvc.GetActiveForLicenseTx(ctx, tx, lic.LicenseID)
+	if err != nil {
+		return err
 	}
+	for _, m := range activeMachines {
+		if m.MachineID == machineID {
+			return nil
+		}
+	}
+	if len(activeMachines) >= lic.LicenseCount {
+		return fmt.Errorf("%w: %d of %d licenses in use", ErrLicenseCountExceeded, len(activeMachines), lic.LicenseCount)
+	}
+	return nil
+}
 
-	// Build registration string and compute HMAC hash
-	regStr := buildRegistrationString(machineCode, expDate, maintExpDate, maxVersion, featStr)
+// SelectProduct picks the product a license key activates. A key for a
+// single product needs no product GUID (if one is given it must match); a
+// bundle key covers several products, so the client names one by its GUID.
+func (s *Service) SelectProduct(ctx context.Context, productIDs []int64, productGUID string) (int64, error) {
+	if productGUID == "" {
+		if len(productIDs) == 1 {
+			return productIDs[0], nil
+		}
+		return 0, ErrProductRequired
+	}
+
+	prod, err := s.productSvc.GetByGUID(ctx, productGUID)
+	if err != nil {
+		if errors.Is(err, product.ErrNotFound) {
+			return 0, fmt.Errorf("%w (%s)", ErrProductNotLicensed, productGUID)
+		}
+		return 0, err
+	}
+	if !slices.Contains(productIDs, prod.ProductID) {
+		return 0, fmt.Errorf("%w (%s)", ErrProductNotLicensed, productGUID)
+	}
+	return prod.ProductID, nil
+}
+
+// computeHash builds the registration string and computes its HMAC hash
+// from the stored (canonical) feature strings, not their typed JSON values.
+func (s *Service) computeHash(machineCode, expDate, maintExpDate, maxVersion string, features map[string]string) (string, error) {
+	regStr := buildRegistrationString(machineCode, expDate, maintExpDate, maxVersion, features)
 	return computeRegistrationHash(regStr, s.registrationSecret)
 }
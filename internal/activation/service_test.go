@@ -2,13 +2,22 @@ package activation_test
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf16"
 
 	_ "github.com/mattn/go-sqlite3"
 
 	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
 	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/feature"
 	"winsbygroup.com/regserver/internal/featurevalue"
@@ -42,6 +51,7 @@ func TestActivate_LicenseCountCheck(t *testing.T) {
 		prodSvc,
 		featureSvc,
 		fvSvc,
+		analytics.NewService(db),
 	)
 
 	// Create test customer
@@ -66,7 +76,7 @@ func TestActivate_LicenseCountCheck(t *testing.T) {
 	futureDate := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
 
 	// Create license with license count of 2
-	_, err = licenseSvc.Create(ctx, &license.License{
+	lic, err := licenseSvc.Create(ctx, &license.License{
 		CustomerID:          cust.CustomerID,
 		ProductID:           prod.ProductID,
 		LicenseKey:          "REG-GUID-123",
@@ -87,7 +97,7 @@ func TestActivate_LicenseCountCheck(t *testing.T) {
 			MachineCode: "MACHINE-001",
 			UserName:    "user1",
 		}
-		resp, err := activationSvc.Activate(ctx, cust.CustomerID, prod.ProductID, req)
+		resp, err := activationSvc.Activate(ctx, lic.LicenseID, req)
 		if err != nil {
 			t.Fatalf("first activation should succeed: %v", err)
 		}
@@ -101,7 +111,7 @@ func TestActivate_LicenseCountCheck(t *testing.T) {
 			MachineCode: "MACHINE-002",
 			UserName:    "user2",
 		}
-		_, err := activationSvc.Activate(ctx, cust.CustomerID, prod.ProductID, req)
+		_, err := activationSvc.Activate(ctx, lic.LicenseID, req)
 		if err != nil {
 			t.Fatalf("second activation should succeed: %v", err)
 		}
@@ -112,7 +122,7 @@ func TestActivate_LicenseCountCheck(t *testing.T) {
 			MachineCode: "MACHINE-003",
 			UserName:    "user3",
 		}
-		_, err := activationSvc.Activate(ctx, cust.CustomerID, prod.ProductID, req)
+		_, err := activationSvc.Activate(ctx, lic.LicenseID, req)
 		if err == nil {
 			t.Fatal("third activation should fail due to license count exceeded")
 		}
@@ -130,7 +140,7 @@ func TestActivate_LicenseCountCheck(t *testing.T) {
 			MachineCode: "MACHINE-001",
 			UserName:    "user1-updated",
 		}
-		resp, err := activationSvc.Activate(ctx, cust.CustomerID, prod.ProductID, req)
+		resp, err := activationSvc.Activate(ctx, lic.LicenseID, req)
 		if err != nil {
 			t.Fatalf("re-activation should succeed: %v", err)
 		}
@@ -163,28 +173,16 @@ func TestActivate_NoLicense(t *testing.T) {
 		prodSvc,
 		featureSvc,
 		fvSvc,
+		analytics.NewService(db),
 	)
 
-	// Create test customer
-	cust, _ := custSvc.Create(ctx, &customer.Customer{
-		CustomerName: "Test Company",
-	})
-
-	// Create test product
-	prod, _ := prodSvc.Create(ctx, &product.Product{
-		ProductName:   "Test Product",
-		ProductGUID:   "TEST-GUID-123",
-		LatestVersion: "1.0.0",
-		DownloadURL:   "http://example.com/download",
-	})
-
-	// Don't create customer product - no license
+	// Don't create a license - the ID refers to nothing
 
 	req := &activation.Request{
 		MachineCode: "MACHINE-001",
 		UserName:    "user1",
 	}
-	_, err := activationSvc.Activate(ctx, cust.CustomerID, prod.ProductID, req)
+	_, err := activationSvc.Activate(ctx, 999, req)
 	if err == nil {
 		t.Fatal("activation without license should fail")
 	}
@@ -195,6 +193,71 @@ func TestActivate_NoLicense(t *testing.T) {
 	}
 }
 
+func TestActivate_SuspendedLicense(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+	featureSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+
+	activationSvc := activation.NewService(
+		db,
+		"test-secret",
+		custSvc,
+		machineSvc,
+		regSvc,
+		licenseSvc,
+		prodSvc,
+		featureSvc,
+		fvSvc,
+		analytics.NewService(db),
+	)
+
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Late Payer"})
+	prod, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Test Product",
+		ProductGUID:   "TEST-GUID-SUSP",
+		LatestVersion: "1.0.0",
+		DownloadURL:   "http://example.com/download",
+	})
+	lic, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           prod.ProductID,
+		LicenseCount:        1,
+		StartDate:           "2024-01-01",
+		ExpirationDate:      "2099-12-31",
+		MaintExpirationDate: "2099-12-31",
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	if err := licenseSvc.SetStatus(ctx, lic.LicenseID, license.StatusSuspended, "invoice overdue"); err != nil {
+		t.Fatalf("suspend license: %v", err)
+	}
+
+	req := &activation.Request{MachineCode: "MACHINE-001", UserName: "user1"}
+	_, err = activationSvc.Activate(ctx, lic.LicenseID, req)
+	if !errors.Is(err, license.ErrLicenseSuspended) {
+		t.Fatalf("expected ErrLicenseSuspended, got %v", err)
+	}
+	if reason := activation.FailureReason(err); reason != "license_inactive" {
+		t.Errorf("expected failure reason license_inactive, got %q", reason)
+	}
+
+	if err := licenseSvc.SetStatus(ctx, lic.LicenseID, license.StatusActive, ""); err != nil {
+		t.Fatalf("reactivate license: %v", err)
+	}
+	if _, err := activationSvc.Activate(ctx, lic.LicenseID, req); err != nil {
+		t.Errorf("expected activation after reactivation, got %v", err)
+	}
+}
+
 func TestActivate_ExpiredMachineDoesNotCount(t *testing.T) {
 	ctx := context.Background()
 	db := testutil.NewTestDB(t)
@@ -218,6 +281,7 @@ func TestActivate_ExpiredMachineDoesNotCount(t *testing.T) {
 		prodSvc,
 		featureSvc,
 		fvSvc,
+		analytics.NewService(db),
 	)
 
 	// Create test customer and product
@@ -233,7 +297,7 @@ func TestActivate_ExpiredMachineDoesNotCount(t *testing.T) {
 	pastDate := time.Now().AddDate(0, 0, -1).Format("2006-01-02") // yesterday
 
 	// Create customer product with license count of 1
-	_, _ = licenseSvc.Create(ctx, &license.License{
+	lic, _ := licenseSvc.Create(ctx, &license.License{
 		CustomerID:          cust.CustomerID,
 		ProductID:           prod.ProductID,
 		LicenseKey:          "REG-GUID-123",
@@ -254,6 +318,7 @@ func TestActivate_ExpiredMachineDoesNotCount(t *testing.T) {
 	_ = regSvc.Upsert(ctx, tx, &registration.Registration{
 		MachineID:             machineID,
 		ProductID:             prod.ProductID,
+		LicenseID:             &lic.LicenseID,
 		ExpirationDate:        pastDate,
 		RegistrationHash:      "EXPIRED-MACHINE",
 		FirstRegistrationDate: pastDate,
@@ -266,7 +331,7 @@ func TestActivate_ExpiredMachineDoesNotCount(t *testing.T) {
 		MachineCode: "NEW-MACHINE",
 		UserName:    "new-user",
 	}
-	_, err := activationSvc.Activate(ctx, cust.CustomerID, prod.ProductID, req)
+	_, err := activationSvc.Activate(ctx, lic.LicenseID, req)
 	if err != nil {
 		t.Fatalf("new machine should be able to activate when only existing machine is expired: %v", err)
 	}
@@ -276,7 +341,7 @@ func TestActivate_ExpiredMachineDoesNotCount(t *testing.T) {
 		MachineCode: "ANOTHER-NEW-MACHINE",
 		UserName:    "another-user",
 	}
-	_, err = activationSvc.Activate(ctx, cust.CustomerID, prod.ProductID, req2)
+	_, err = activationSvc.Activate(ctx, lic.LicenseID, req2)
 	if err == nil {
 		t.Fatal("should fail when license count exceeded")
 	}
@@ -284,3 +349,298 @@ func TestActivate_ExpiredMachineDoesNotCount(t *testing.T) {
 		t.Errorf("expected 'license count exceeded' error, got: %v", err)
 	}
 }
+
+func TestActivate_FingerprintDrift(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	machineSvc.SetFingerprintMatch(2)
+	regSvc := registration.NewService(db)
+
+	activationSvc := activation.NewService(
+		db,
+		"test-secret",
+		custSvc,
+		machineSvc,
+		regSvc,
+		licenseSvc,
+		prodSvc,
+		feature.NewService(db),
+		featurevalue.NewService(db),
+		analytics.NewService(db),
+	)
+	machineSvc.SetReissuer(activationSvc)
+
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Test Company"})
+	prod, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Test Product",
+		ProductGUID:   "TEST-GUID-123",
+		LatestVersion: "1.0.0",
+		DownloadURL:   "http://example.com/download",
+	})
+	other, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Other Product",
+		ProductGUID:   "OTHER-GUID-456",
+		LatestVersion: "1.0.0",
+		DownloadURL:   "http://example.com/other",
+	})
+
+	futureDate := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+
+	// The machine also holds a registration of another product
+	otherLic, _ := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           other.ProductID,
+		LicenseKey:          "REG-GUID-456",
+		LicenseCount:        1,
+		LicenseTerm:         365,
+		StartDate:           time.Now().Format("2006-01-02"),
+		ExpirationDate:      futureDate,
+		MaintExpirationDate: futureDate,
+		MaxProductVersion:   "99.0.0",
+	})
+	if _, err := activationSvc.Activate(ctx, otherLic.LicenseID, &activation.Request{MachineCode: "fp1.a.b.c", UserName: "user1"}); err != nil {
+		t.Fatalf("other product activation: %v", err)
+	}
+
+	// A single seat, taken by the first activation
+	lic, _ := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           prod.ProductID,
+		LicenseKey:          "REG-GUID-123",
+		LicenseCount:        1,
+		LicenseTerm:         365,
+		StartDate:           time.Now().Format("2006-01-02"),
+		ExpirationDate:      futureDate,
+		MaintExpirationDate: futureDate,
+		MaxProductVersion:   "99.0.0",
+	})
+
+	if _, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: "fp1.a.b.c", UserName: "user1"}); err != nil {
+		t.Fatalf("first activation: %v", err)
+	}
+
+	t.Run("drifted machine re-activates on a full license", func(t *testing.T) {
+		if _, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: "fp1.a.b.NEWDISK", UserName: "user1"}); err != nil {
+			t.Fatalf("re-activation after a disk change should reuse the seat: %v", err)
+		}
+		machines, err := machineSvc.GetForLicense(ctx, lic.LicenseID)
+		if err != nil {
+			t.Fatalf("get machines: %v", err)
+		}
+		if len(machines) != 1 || machines[0].MachineCode != "fp1.a.b.NEWDISK" {
+			t.Errorf("expected the one machine to take the new code, got %+v", machines)
+		}
+	})
+
+	t.Run("drifted machine refreshes", func(t *testing.T) {
+		resp, err := activationSvc.Refresh(ctx, lic.LicenseID, "fp1.a.NEWBOARD.NEWDISK")
+		if err != nil {
+			t.Fatalf("refresh after a board change should find the machine: %v", err)
+		}
+		if resp.MachineCode != "fp1.a.NEWBOARD.NEWDISK" {
+			t.Errorf("expected the response for the new code, got %q", resp.MachineCode)
+		}
+	})
+
+	t.Run("another machine is still over the limit", func(t *testing.T) {
+		_, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: "fp1.x.y.z", UserName: "user2"})
+		if !errors.Is(err, activation.ErrLicenseCountExceeded) {
+			t.Errorf("expected ErrLicenseCountExceeded, got %v", err)
+		}
+	})
+
+	t.Run("other products are re-issued for the new code", func(t *testing.T) {
+		m, err := machineSvc.GetByCode(ctx, cust.CustomerID, "fp1.a.NEWBOARD.NEWDISK")
+		if err != nil || m == nil {
+			t.Fatalf("get machine: %v", err)
+		}
+		exported, err := activationSvc.BuildRegistration(ctx, m.MachineID, other.ProductID)
+		if err != nil {
+			t.Fatalf("BuildRegistration: %v", err)
+		}
+		refreshed, err := activationSvc.Refresh(ctx, otherLic.LicenseID, "fp1.a.NEWBOARD.NEWDISK")
+		if err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		if exported.MachineCode != refreshed.MachineCode || exported.RegistrationHash != refreshed.RegistrationHash {
+			t.Errorf("expected the exported registration to match a refresh, got %s/%s, want %s/%s",
+				exported.MachineCode, exported.RegistrationHash, refreshed.MachineCode, refreshed.RegistrationHash)
+		}
+	})
+}
+
+func TestActivate_TypedFeatures(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+	featureSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+
+	activationSvc := activation.NewService(
+		db, "test-secret", custSvc, machineSvc, regSvc, licenseSvc, prodSvc, featureSvc, fvSvc, analytics.NewService(db),
+	)
+
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Typed Co"})
+	prod, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Typed", ProductGUID: "TYPED-GUID"})
+
+	futureDate := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+	lic, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           prod.ProductID,
+		LicenseCount:        1,
+		StartDate:           time.Now().Format("2006-01-02"),
+		ExpirationDate:      futureDate,
+		MaintExpirationDate: futureDate,
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	defs := []feature.Feature{
+		{FeatureName: "PartTypes", FeatureType: featurevalue.TypeInteger, DefaultValue: "999999999"},
+		{FeatureName: "Legacy", FeatureType: featurevalue.TypeBoolean, DefaultValue: "false"},
+		{FeatureName: "SupportUntil", FeatureType: featurevalue.TypeDate, DefaultValue: "2030-06-30"},
+		{FeatureName: "Tier", FeatureType: featurevalue.TypeString, DefaultValue: "Gold"},
+	}
+	var legacyID int64
+	for _, d := range defs {
+		d.ProductID = prod.ProductID
+		f, err := featureSvc.Create(ctx, &d)
+		if err != nil {
+			t.Fatalf("create feature %s: %v", d.FeatureName, err)
+		}
+		if d.FeatureName == "Legacy" {
+			legacyID = f.FeatureID
+		}
+	}
+	if err := fvSvc.Update(ctx, &featurevalue.FeatureValue{LicenseID: lic.LicenseID, FeatureID: legacyID, FeatureValue: "true"}); err != nil {
+		t.Fatalf("override Legacy: %v", err)
+	}
+
+	resp, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: "TYPED-MACHINE", UserName: "user"})
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+
+	want := map[string]any{
+		"PartTypes":    "999999999",
+		"Legacy":       true,
+		"SupportUntil": "2030-06-30",
+		"Tier":         "Gold",
+	}
+	for k, v := range want {
+		if resp.Features[k] != v {
+			t.Errorf("Features[%s] = %#v, want %#v", k, resp.Features[k], v)
+		}
+	}
+
+	// Deployed clients built from the original Go sample decode Features into
+	// map[string]any and hash each value formatted with %v
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	var decoded activation.Response
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got := sampleClientHash(t, &decoded, "test-secret"); got != decoded.RegistrationHash {
+		t.Errorf("sample client computed hash %s, server sent %s", got, decoded.RegistrationHash)
+	}
+}
+
+// sampleClientHash computes the registration hash the way the original Go
+// client sample (doc/clients/go) does
+func sampleClientHash(t *testing.T, resp *activation.Response, secret string) string {
+	t.Helper()
+	regString := resp.MachineCode + "|" + resp.ExpirationDate + "|" + resp.MaintExpirationDate + "|" + resp.MaxProductVersion
+	keys := make([]string, 0, len(resp.Features))
+	for k := range resp.Features {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		regString += "|" + k + "=" + fmt.Sprintf("%v", resp.Features[k])
+	}
+
+	u := utf16.Encode([]rune(regString + secret))
+	buf := make([]byte, 2*len(u))
+	for i, r := range u {
+		binary.LittleEndian.PutUint16(buf[2*i:], r)
+	}
+	sum := sha1.Sum(buf)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestActivate_FeatureChangesKeepSameDayHash(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+	featureSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+
+	activationSvc := activation.NewService(
+		db, "test-secret", custSvc, machineSvc, regSvc, licenseSvc, prodSvc, featureSvc, fvSvc, analytics.NewService(db),
+	)
+
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Rename Co"})
+	prod, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Rename", ProductGUID: "RENAME-GUID"})
+
+	futureDate := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+	lic, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           prod.ProductID,
+		LicenseCount:        1,
+		StartDate:           time.Now().Format("2006-01-02"),
+		ExpirationDate:      futureDate,
+		MaintExpirationDate: futureDate,
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	legacy, _ := featureSvc.Create(ctx, &feature.Feature{ProductID: prod.ProductID, FeatureName: "Legacy", FeatureType: featurevalue.TypeBoolean, DefaultValue: "true"})
+	tier, _ := featureSvc.Create(ctx, &feature.Feature{ProductID: prod.ProductID, FeatureName: "Tier", FeatureType: featurevalue.TypeString, DefaultValue: "Gold"})
+
+	req := &activation.Request{MachineCode: "RENAME-MACHINE", UserName: "user"}
+	first, err := activationSvc.Activate(ctx, lic.LicenseID, req)
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+
+	// Rename one feature and hide the other; both take effect tomorrow
+	legacy.FeatureName = "Compat"
+	if err := featureSvc.Update(ctx, legacy); err != nil {
+		t.Fatalf("rename feature: %v", err)
+	}
+	tier.Status = feature.StatusHidden
+	if err := featureSvc.Update(ctx, tier); err != nil {
+		t.Fatalf("hide feature: %v", err)
+	}
+
+	again, err := activationSvc.Activate(ctx, lic.LicenseID, req)
+	if err != nil {
+		t.Fatalf("reactivate: %v", err)
+	}
+	if again.RegistrationHash != first.RegistrationHash {
+		t.Errorf("hash changed on the day of the change: %s != %s", again.RegistrationHash, first.RegistrationHash)
+	}
+	if again.Features["Legacy"] != true || again.Features["Tier"] != "Gold" || len(again.Features) != 2 {
+		t.Errorf("expected the features under their former names, got %v", again.Features)
+	}
+}
@@ -0,0 +1,141 @@
+package activation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/registration"
+)
+
+// TransferMachine moves a machine and its registrations to another customer.
+// Every product the machine is registered for must be licensed to the
+// destination customer by an active license with a free seat. Registrations
+// are re-issued with the destination license's dates, features and hash; first
+// registration and installed version are kept.
+func (s *Service) TransferMachine(ctx context.Context, machineID, toCustomerID int64, reason string) (*machine.Transfer, error) {
+	m, err := s.machineSvc.Get(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("machine not found (%d)", machineID)
+	}
+	if _, err := s.customerSvc.Get(ctx, toCustomerID); err != nil {
+		return nil, err
+	}
+
+	regs, err := s.regSvc.GetForMachine(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate destination licenses and compute the new registrations before writing anything
+	updated := make([]registration.Registration, 0, len(regs))
+	licenses := make([]*license.License, 0, len(regs))
+	for _, reg := range regs {
+		lic, err := s.freeLicense(ctx, toCustomerID, reg.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		// The registration keeps its last activation date, so it keeps the
+		// feature names and set it was activated with
+		defs, err := s.featureSvc.GetAsOf(ctx, reg.ProductID, reg.LastRegistrationDate)
+		if err != nil {
+			return nil, err
+		}
+		planVals, err := s.featureValueSvc.GetPlanValues(ctx, lic.LicenseID)
+		if err != nil {
+			return nil, err
+		}
+		vals, err := s.featureValueSvc.GetFeatureValues(ctx, lic.LicenseID)
+		if err != nil {
+			return nil, err
+		}
+		merged := feature.MergeWithOverrides(defs, planVals, vals)
+
+		regHash, err := s.computeHash(m.MachineCode, lic.ExpirationDate, lic.MaintExpirationDate, lic.MaxProductVersion, merged)
+		if err != nil {
+			return nil, fmt.Errorf("compute registration hash: %w", err)
+		}
+
+		reg.LicenseID = &lic.LicenseID
+		reg.ExpirationDate = lic.ExpirationDate
+		reg.RegistrationHash = regHash
+		updated = append(updated, reg)
+		licenses = append(licenses, lic)
+	}
+
+	var transfer *machine.Transfer
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		t, err := s.machineSvc.Transfer(ctx, tx, machineID, toCustomerID, reason)
+		if err != nil {
+			return err
+		}
+		transfer = t
+
+		for i := range updated {
+			// Seats are checked again now that the transaction holds the write lock
+			if err := s.checkSeats(ctx, tx, licenses[i], machineID); err != nil {
+				return err
+			}
+			if err := s.regSvc.Upsert(ctx, tx, &updated[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("machine transferred",
+		"machine_id", machineID,
+		"from_customer_id", transfer.FromCustomerID,
+		"to_customer_id", toCustomerID,
+		"registrations", len(updated),
+		"reason", reason,
+	)
+	return transfer, nil
+}
+
+// freeLicense picks the customer's license a new or transferred registration
+// goes to: the first active license of the product with a free seat
+func (s *Service) freeLicense(ctx context.Context, customerID, productID int64) (*license.License, error) {
+	lics, err := s.licenseSvc.GetForProduct(ctx, customerID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lics) == 0 {
+		return nil, fmt.Errorf("%w for customer %d product %d", ErrNoLicense, customerID, productID)
+	}
+
+	var statusErr, countErr error
+	for i := range lics {
+		lic := &lics[i]
+		if err := lic.CheckStatus(); err != nil {
+			statusErr = fmt.Errorf("product %d: %w", productID, err)
+			continue
+		}
+
+		active, err := s.machineSvc.GetActiveForLicense(ctx, lic.LicenseID)
+		if err != nil {
+			return nil, err
+		}
+		if len(active) < lic.LicenseCount {
+			return lic, nil
+		}
+		countErr = fmt.Errorf("%w: product %d has %d of %d licenses in use", ErrLicenseCountExceeded, productID, len(active), lic.LicenseCount)
+	}
+
+	if countErr != nil {
+		return nil, countErr
+	}
+	return nil, statusErr
+}
@@ -0,0 +1,164 @@
+package activation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestTransferMachine(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+	featureSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+
+	activationSvc := activation.NewService(
+		db,
+		"test-secret",
+		custSvc,
+		machineSvc,
+		regSvc,
+		licenseSvc,
+		prodSvc,
+		featureSvc,
+		fvSvc,
+		analytics.NewService(db),
+	)
+
+	prod, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Test Product",
+		ProductGUID:   "TEST-GUID-XFER",
+		LatestVersion: "1.0.0",
+		DownloadURL:   "http://example.com/download",
+	})
+
+	// licenseOf maps each licensed customer to its license ID
+	licenseOf := map[int64]int64{}
+	newCustomer := func(name string, seats int, expires time.Time) int64 {
+		c, err := custSvc.Create(ctx, &customer.Customer{CustomerName: name})
+		if err != nil {
+			t.Fatalf("create customer: %v", err)
+		}
+		if seats > 0 {
+			lic, err := licenseSvc.Create(ctx, &license.License{
+				CustomerID:          c.CustomerID,
+				ProductID:           prod.ProductID,
+				LicenseKey:          "XFER-" + name,
+				LicenseCount:        seats,
+				StartDate:           time.Now().Format("2006-01-02"),
+				ExpirationDate:      expires.Format("2006-01-02"),
+				MaintExpirationDate: expires.Format("2006-01-02"),
+			})
+			if err != nil {
+				t.Fatalf("create license: %v", err)
+			}
+			licenseOf[c.CustomerID] = lic.LicenseID
+		}
+		return c.CustomerID
+	}
+
+	parent := newCustomer("Parent Co", 5, time.Now().AddDate(1, 0, 0))
+	subsidiary := newCustomer("Subsidiary", 2, time.Now().AddDate(2, 0, 0))
+	full := newCustomer("Full Co", 1, time.Now().AddDate(1, 0, 0))
+	unlicensed := newCustomer("Unlicensed Co", 0, time.Time{})
+
+	activate := func(customerID int64, machineCode string) {
+		t.Helper()
+		_, err := activationSvc.Activate(ctx, licenseOf[customerID], &activation.Request{
+			MachineCode: machineCode,
+			UserName:    "user",
+		})
+		if err != nil {
+			t.Fatalf("activate %s: %v", machineCode, err)
+		}
+	}
+	activate(parent, "MACHINE-A")
+	activate(subsidiary, "MACHINE-S1")
+	activate(full, "MACHINE-F1")
+
+	m, _ := machineSvc.GetByCode(ctx, parent, "MACHINE-A")
+	before, _ := regSvc.Get(ctx, m.MachineID, prod.ProductID)
+
+	t.Run("rejects destination without license", func(t *testing.T) {
+		_, err := activationSvc.TransferMachine(ctx, m.MachineID, unlicensed, "")
+		if !errors.Is(err, activation.ErrNoLicense) {
+			t.Errorf("expected ErrNoLicense, got %v", err)
+		}
+	})
+
+	t.Run("rejects destination without free seats", func(t *testing.T) {
+		_, err := activationSvc.TransferMachine(ctx, m.MachineID, full, "")
+		if !errors.Is(err, activation.ErrLicenseCountExceeded) {
+			t.Errorf("expected ErrLicenseCountExceeded, got %v", err)
+		}
+		still, _ := machineSvc.Get(ctx, m.MachineID)
+		if still.CustomerID != parent {
+			t.Errorf("expected failed transfer to leave machine with customer %d, got %d", parent, still.CustomerID)
+		}
+	})
+
+	t.Run("moves machine and re-issues registration", func(t *testing.T) {
+		tr, err := activationSvc.TransferMachine(ctx, m.MachineID, subsidiary, "workstation moved")
+		if err != nil {
+			t.Fatalf("TransferMachine: %v", err)
+		}
+		if tr.FromCustomerID != parent || tr.ToCustomerID != subsidiary {
+			t.Errorf("unexpected transfer record: %+v", tr)
+		}
+
+		after, err := regSvc.Get(ctx, m.MachineID, prod.ProductID)
+		if err != nil {
+			t.Fatalf("get registration: %v", err)
+		}
+		subLic, _ := licenseSvc.Get(ctx, licenseOf[subsidiary])
+		if after.LicenseID == nil || *after.LicenseID != subLic.LicenseID {
+			t.Errorf("expected registration to count against license %d, got %v", subLic.LicenseID, after.LicenseID)
+		}
+		if after.ExpirationDate != subLic.ExpirationDate {
+			t.Errorf("expected expiration %s, got %s", subLic.ExpirationDate, after.ExpirationDate)
+		}
+		if after.RegistrationHash == before.RegistrationHash {
+			t.Error("expected registration hash to change for the new license terms")
+		}
+		if after.FirstRegistrationDate != before.FirstRegistrationDate {
+			t.Errorf("expected first registration date kept, got %s", after.FirstRegistrationDate)
+		}
+
+		// The re-issued hash matches what an activation under the new customer produces
+		resp, err := activationSvc.Activate(ctx, licenseOf[subsidiary], &activation.Request{
+			MachineCode: "MACHINE-A",
+			UserName:    "user",
+		})
+		if err != nil {
+			t.Fatalf("activate after transfer: %v", err)
+		}
+		if resp.RegistrationHash != after.RegistrationHash {
+			t.Errorf("expected transferred hash %s to match activation hash %s", after.RegistrationHash, resp.RegistrationHash)
+		}
+
+		// Seats moved with the machine
+		parentActive, _ := machineSvc.GetActiveForLicense(ctx, licenseOf[parent])
+		subActive, _ := machineSvc.GetActiveForLicense(ctx, licenseOf[subsidiary])
+		if len(parentActive) != 0 || len(subActive) != 2 {
+			t.Errorf("expected 0 parent and 2 subsidiary seats in use, got %d and %d", len(parentActive), len(subActive))
+		}
+	})
+}
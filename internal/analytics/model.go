@@ -0,0 +1,61 @@
+package analytics
+
+// Event types recorded for each activation
+const (
+	EventNew          = "new"
+	EventReactivation = "reactivation"
+)
+
+// Interval is the bucket size used when aggregating activation events
+type Interval string
+
+const (
+	IntervalDay   Interval = "day"
+	IntervalWeek  Interval = "week"
+	IntervalMonth Interval = "month"
+)
+
+// Event is a single (append-only) activation record
+type Event struct {
+	EventID       int64  `db:"event_id"`
+	CustomerID    int64  `db:"customer_id"`
+	ProductID     int64  `db:"product_id"`
+	MachineID     int64  `db:"machine_id"`
+	EventType     string `db:"event_type"`
+	UserName      string `db:"user_name"`
+	ClientIP      string `db:"client_ip"`
+	ClientVersion string `db:"client_version"`
+	EventTime     string `db:"event_time"` // yyyy-mm-dd hh:mm:ss (UTC)
+}
+
+// PeriodCount is the number of activations within a single day, week or month.
+// Weekly periods are labeled with the date of the Monday starting the week.
+type PeriodCount struct {
+	Period            string `db:"period"`
+	NewCount          int    `db:"new_count"`
+	ReactivationCount int    `db:"reactivation_count"`
+}
+
+// Total returns the combined number of activations in the period
+func (p PeriodCount) Total() int {
+	return p.NewCount + p.ReactivationCount
+}
+
+// SeatUtilization is the number of seats in use for a license
+type SeatUtilization struct {
+	LicenseID    int64  `db:"license_id"`
+	CustomerID   int64  `db:"customer_id"`
+	CustomerName string `db:"customer_name"`
+	ProductID    int64  `db:"product_id"`
+	ProductName  string `db:"product_name"`
+	LicenseCount int    `db:"license_count"`
+	SeatsInUse   int    `db:"seats_in_use"`
+}
+
+// Percent returns seats in use as a percentage of the license count
+func (s SeatUtilization) Percent() float64 {
+	if s.LicenseCount <= 0 {
+		return 0
+	}
+	return float64(s.SeatsInUse) * 100 / float64(s.LicenseCount)
+}
@@ -0,0 +1,67 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	Create(ctx context.Context, tx *sqlx.Tx, e *Event) (int64, error)
+	GetForCustomerProduct(ctx context.Context, customerID, productID int64) ([]Event, error)
+	GetPeriodCounts(ctx context.Context, interval Interval, since string, productID int64) ([]PeriodCount, error)
+	GetSeatUtilization(ctx context.Context, productID int64) ([]SeatUtilization, error)
+}
+
+type repo struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) Repository {
+	return &repo{db: db}
+}
+
+func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, e *Event) (int64, error) {
+	res, err := tx.ExecContext(ctx, createEventSQL,
+		e.CustomerID,
+		e.ProductID,
+		e.MachineID,
+		e.EventType,
+		e.UserName,
+		e.ClientIP,
+		e.ClientVersion,
+		e.EventTime,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create activation event: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *repo) GetForCustomerProduct(ctx context.Context, customerID, productID int64) ([]Event, error) {
+	var out []Event
+	err := r.db.SelectContext(ctx, &out, getEventsForCustomerProductSQL, customerID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get activation events: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetPeriodCounts(ctx context.Context, interval Interval, since string, productID int64) ([]PeriodCount, error) {
+	var out []PeriodCount
+	err := r.db.SelectContext(ctx, &out, getPeriodCountsSQL, string(interval), since, productID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get activation counts: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetSeatUtilization(ctx context.Context, productID int64) ([]SeatUtilization, error) {
+	var out []SeatUtilization
+	err := r.db.SelectContext(ctx, &out, getSeatUtilizationSQL, productID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get seat utilization: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Service struct {
+	repo Repository
+	db   *sqlx.DB
+}
+
+func NewService(db *sqlx.DB) *Service {
+	return &Service{
+		db:   db,
+		repo: New(db),
+	}
+}
+
+// Record appends an activation event within the caller's transaction.
+// EventTime defaults to the current (UTC) time.
+func (s *Service) Record(ctx context.Context, tx *sqlx.Tx, e *Event) error {
+	if e.EventTime == "" {
+		e.EventTime = time.Now().UTC().Format("2006-01-02 15:04:05")
+	}
+	id, err := s.repo.Create(ctx, tx, e)
+	if err != nil {
+		return err
+	}
+	e.EventID = id
+	return nil
+}
+
+// GetForCustomerProduct returns the activation events of a customer's
+// machines for a product, newest first, across all its licenses of the product
+func (s *Service) GetForCustomerProduct(ctx context.Context, customerID, productID int64) ([]Event, error) {
+	return s.repo.GetForCustomerProduct(ctx, customerID, productID)
+}
+
+// GetActivationCounts returns activation counts for the most recent number of periods
+// (including the current one), oldest first. Periods without activations are included
+// with zero counts so the result can be charted directly. A productID of 0 includes all products.
+func (s *Service) GetActivationCounts(ctx context.Context, interval Interval, periods int, productID int64) ([]PeriodCount, error) {
+	if periods < 1 {
+		return nil, fmt.Errorf("periods must be at least 1")
+	}
+
+	now := time.Now().UTC()
+	start := periodStart(interval, now)
+	for i := 1; i < periods; i++ {
+		start = nextPeriod(interval, start, -1)
+	}
+
+	counts, err := s.repo.GetPeriodCounts(ctx, interval, start.Format("2006-01-02"), productID)
+	if err != nil {
+		return nil, err
+	}
+
+	byPeriod := make(map[string]PeriodCount, len(counts))
+	for _, c := range counts {
+		byPeriod[c.Period] = c
+	}
+
+	out := make([]PeriodCount, 0, periods)
+	for t := start; !t.After(now); t = nextPeriod(interval, t, 1) {
+		label := periodLabel(interval, t)
+		pc, ok := byPeriod[label]
+		if !ok {
+			pc = PeriodCount{Period: label}
+		}
+		out = append(out, pc)
+	}
+	return out, nil
+}
+
+// GetSeatUtilization returns seats in use for every license (or for one product when productID > 0)
+func (s *Service) GetSeatUtilization(ctx context.Context, productID int64) ([]SeatUtilization, error) {
+	return s.repo.GetSeatUtilization(ctx, productID)
+}
+
+// ParseInterval converts a query string value to an Interval (defaults to daily)
+func ParseInterval(s string) (Interval, error) {
+	switch Interval(s) {
+	case "", IntervalDay:
+		return IntervalDay, nil
+	case IntervalWeek:
+		return IntervalWeek, nil
+	case IntervalMonth:
+		return IntervalMonth, nil
+	default:
+		return "", fmt.Errorf("invalid interval %q (must be day, week or month)", s)
+	}
+}
+
+// periodStart returns the start of the period containing t
+func periodStart(interval Interval, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case IntervalWeek:
+		// weeks start on Monday (matches the SQL 'weekday 1' modifier)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextPeriod moves the period start forward (n > 0) or backward (n < 0)
+func nextPeriod(interval Interval, t time.Time, n int) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7*n)
+	case IntervalMonth:
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// periodLabel formats a period start the same way the SQL query labels it
+func periodLabel(interval Interval, t time.Time) string {
+	if interval == IntervalMonth {
+		return t.Format("2006-01")
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,298 @@
+package analytics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+type fixture struct {
+	db            *sqlx.DB
+	svc           *analytics.Service
+	activationSvc *activation.Service
+	customerID    int64
+	productID     int64
+	licenseID     int64
+}
+
+func setup(t *testing.T) fixture {
+	t.Helper()
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	svc := analytics.NewService(db)
+
+	activationSvc := activation.NewService(
+		db,
+		"test-secret",
+		custSvc,
+		machine.NewService(db),
+		registration.NewService(db),
+		licenseSvc,
+		prodSvc,
+		feature.NewService(db),
+		featurevalue.NewService(db),
+		svc,
+	)
+
+	cust, err := custSvc.Create(ctx, &customer.Customer{CustomerName: "Analytics Co"})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	prod, err := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Analytics Product",
+		ProductGUID:   "ANALYTICS-GUID",
+		LatestVersion: "1.0.0",
+		DownloadURL:   "http://example.com/download",
+	})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	futureDate := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+	lic, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           prod.ProductID,
+		LicenseKey:          "ANALYTICS-KEY",
+		LicenseCount:        4,
+		LicenseTerm:         0,
+		StartDate:           time.Now().Format("2006-01-02"),
+		ExpirationDate:      futureDate,
+		MaintExpirationDate: futureDate,
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	return fixture{
+		db:            db,
+		svc:           svc,
+		activationSvc: activationSvc,
+		customerID:    cust.CustomerID,
+		productID:     prod.ProductID,
+		licenseID:     lic.LicenseID,
+	}
+}
+
+func TestActivate_RecordsEvents(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t)
+
+	req := &activation.Request{
+		MachineCode:      "MACHINE-A",
+		UserName:         "alice",
+		InstalledVersion: "1.2.3",
+		ClientIP:         "203.0.113.7",
+	}
+	if _, err := f.activationSvc.Activate(ctx, f.licenseID, req); err != nil {
+		t.Fatalf("first activation: %v", err)
+	}
+	if _, err := f.activationSvc.Activate(ctx, f.licenseID, req); err != nil {
+		t.Fatalf("reactivation: %v", err)
+	}
+
+	events, err := f.svc.GetForCustomerProduct(ctx, f.customerID, f.productID)
+	if err != nil {
+		t.Fatalf("GetForCustomerProduct: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	// newest first
+	if events[0].EventType != analytics.EventReactivation {
+		t.Errorf("expected latest event to be a reactivation, got %q", events[0].EventType)
+	}
+	if events[1].EventType != analytics.EventNew {
+		t.Errorf("expected first event to be new, got %q", events[1].EventType)
+	}
+	if events[1].UserName != "alice" || events[1].ClientIP != "203.0.113.7" || events[1].ClientVersion != "1.2.3" {
+		t.Errorf("unexpected event details: %+v", events[1])
+	}
+}
+
+func TestGetActivationCounts(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t)
+
+	if _, err := f.activationSvc.Activate(ctx, f.licenseID, &activation.Request{MachineCode: "M1", UserName: "u1"}); err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	if _, err := f.activationSvc.Activate(ctx, f.licenseID, &activation.Request{MachineCode: "M1", UserName: "u1"}); err != nil {
+		t.Fatalf("reactivate: %v", err)
+	}
+
+	// Back-dated event three days ago (reuses the machine created above)
+	events, _ := f.svc.GetForCustomerProduct(ctx, f.customerID, f.productID)
+	old := time.Now().UTC().AddDate(0, 0, -3)
+	tx := f.db.MustBegin()
+	err := f.svc.Record(ctx, tx, &analytics.Event{
+		CustomerID: f.customerID,
+		ProductID:  f.productID,
+		MachineID:  events[0].MachineID,
+		EventType:  analytics.EventNew,
+		EventTime:  old.Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	t.Run("daily includes empty periods", func(t *testing.T) {
+		counts, err := f.svc.GetActivationCounts(ctx, analytics.IntervalDay, 7, 0)
+		if err != nil {
+			t.Fatalf("GetActivationCounts: %v", err)
+		}
+		if len(counts) != 7 {
+			t.Fatalf("expected 7 periods, got %d", len(counts))
+		}
+
+		today := counts[6]
+		if today.Period != time.Now().UTC().Format("2006-01-02") {
+			t.Errorf("expected last period to be today, got %s", today.Period)
+		}
+		if today.NewCount != 1 || today.ReactivationCount != 1 {
+			t.Errorf("expected 1 new + 1 reactivation today, got %+v", today)
+		}
+
+		past := counts[3]
+		if past.Period != old.Format("2006-01-02") || past.NewCount != 1 {
+			t.Errorf("expected 1 new activation on %s, got %+v", old.Format("2006-01-02"), past)
+		}
+		if counts[4].Total() != 0 {
+			t.Errorf("expected empty period, got %+v", counts[4])
+		}
+	})
+
+	t.Run("monthly totals", func(t *testing.T) {
+		counts, err := f.svc.GetActivationCounts(ctx, analytics.IntervalMonth, 3, f.productID)
+		if err != nil {
+			t.Fatalf("GetActivationCounts: %v", err)
+		}
+		total := 0
+		for _, c := range counts {
+			total += c.Total()
+		}
+		if total != 3 {
+			t.Errorf("expected 3 activations over 3 months, got %d", total)
+		}
+	})
+
+	t.Run("weekly periods start on Monday", func(t *testing.T) {
+		counts, err := f.svc.GetActivationCounts(ctx, analytics.IntervalWeek, 4, 0)
+		if err != nil {
+			t.Fatalf("GetActivationCounts: %v", err)
+		}
+		for _, c := range counts {
+			d, err := time.Parse("2006-01-02", c.Period)
+			if err != nil {
+				t.Fatalf("parse period %q: %v", c.Period, err)
+			}
+			if d.Weekday() != time.Monday {
+				t.Errorf("expected period %s to be a Monday", c.Period)
+			}
+		}
+		if counts[len(counts)-1].Total() < 2 {
+			t.Errorf("expected this week to include today's activations, got %+v", counts[len(counts)-1])
+		}
+	})
+
+	t.Run("invalid periods", func(t *testing.T) {
+		if _, err := f.svc.GetActivationCounts(ctx, analytics.IntervalDay, 0, 0); err == nil {
+			t.Error("expected error for zero periods")
+		}
+	})
+}
+
+func TestGetSeatUtilization(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t)
+
+	if _, err := f.activationSvc.Activate(ctx, f.licenseID, &activation.Request{MachineCode: "M1", UserName: "u1"}); err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+
+	seats, err := f.svc.GetSeatUtilization(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetSeatUtilization: %v", err)
+	}
+	if len(seats) != 1 {
+		t.Fatalf("expected 1 license, got %d", len(seats))
+	}
+	if seats[0].SeatsInUse != 1 || seats[0].LicenseCount != 4 {
+		t.Errorf("expected 1 of 4 seats in use, got %+v", seats[0])
+	}
+	if seats[0].Percent() != 25 {
+		t.Errorf("expected 25%% utilization, got %.1f", seats[0].Percent())
+	}
+
+	t.Run("counts each license of a product separately", func(t *testing.T) {
+		futureDate := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+		second, err := license.NewService(f.db).Create(ctx, &license.License{
+			CustomerID:          f.customerID,
+			ProductID:           f.productID,
+			LicenseKey:          "ANALYTICS-KEY-2",
+			LicenseCount:        2,
+			StartDate:           time.Now().Format("2006-01-02"),
+			ExpirationDate:      futureDate,
+			MaintExpirationDate: futureDate,
+		})
+		if err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+
+		seats, err := f.svc.GetSeatUtilization(ctx, f.productID)
+		if err != nil {
+			t.Fatalf("GetSeatUtilization: %v", err)
+		}
+		byLicense := map[int64]analytics.SeatUtilization{}
+		for _, s := range seats {
+			byLicense[s.LicenseID] = s
+		}
+		if len(byLicense) != 2 {
+			t.Fatalf("expected a row per license, got %+v", seats)
+		}
+		if s := byLicense[f.licenseID]; s.SeatsInUse != 1 || s.LicenseCount != 4 {
+			t.Errorf("expected 1 of 4 seats in use on the first license, got %+v", s)
+		}
+		if s := byLicense[second.LicenseID]; s.SeatsInUse != 0 || s.LicenseCount != 2 {
+			t.Errorf("expected no seats in use on the second license, got %+v", s)
+		}
+	})
+}
+
+func TestParseInterval(t *testing.T) {
+	for in, want := range map[string]analytics.Interval{
+		"":      analytics.IntervalDay,
+		"day":   analytics.IntervalDay,
+		"week":  analytics.IntervalWeek,
+		"month": analytics.IntervalMonth,
+	} {
+		got, err := analytics.ParseInterval(in)
+		if err != nil || got != want {
+			t.Errorf("ParseInterval(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := analytics.ParseInterval("year"); err == nil {
+		t.Error("expected error for invalid interval")
+	}
+}
@@ -0,0 +1,55 @@
+package analytics
+
+const createEventSQL = `
+INSERT INTO activation_event (
+    customer_id, product_id, machine_id, event_type,
+    user_name, client_ip, client_version, event_time
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// Events carry no license, so they are looked up by customer and product
+const getEventsForCustomerProductSQL = `
+SELECT event_id, customer_id, product_id, machine_id, event_type,
+       user_name, client_ip, client_version, event_time
+FROM activation_event
+WHERE customer_id = ? AND product_id = ?
+ORDER BY event_time DESC, event_id DESC
+`
+
+// periods are bucketed by day, by week (labeled with the Monday starting the week) or by month
+const getPeriodCountsSQL = `
+SELECT
+    CASE ?
+        WHEN 'day' THEN DATE(event_time)
+        WHEN 'week' THEN DATE(event_time, '-6 days', 'weekday 1')
+        ELSE STRFTIME('%Y-%m', event_time)
+    END AS period,
+    SUM(CASE WHEN event_type = 'new' THEN 1 ELSE 0 END) AS new_count,
+    SUM(CASE WHEN event_type = 'reactivation' THEN 1 ELSE 0 END) AS reactivation_count
+FROM activation_event
+WHERE event_time >= ?
+  AND (? = 0 OR product_id = ?)
+GROUP BY period
+ORDER BY period
+`
+
+const getSeatUtilizationSQL = `
+SELECT
+    l.license_id,
+    l.customer_id,
+    c.customer_name,
+    l.product_id,
+    p.product_name,
+    l.license_count,
+    (
+        SELECT COUNT(*)
+        FROM registration r
+        WHERE r.license_id = l.license_id
+          AND r.expiration_date >= DATE('now')
+    ) AS seats_in_use
+FROM license l
+JOIN customer c ON c.customer_id = l.customer_id
+JOIN product p ON p.product_id = l.product_id
+WHERE (? = 0 OR l.product_id = ?)
+ORDER BY c.customer_name, p.product_name, l.license_id
+`
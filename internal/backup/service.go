@@ -33,9 +33,7 @@ type BackupResult struct {
 
 // CreateBackup creates a SQL dump of the database
 func (s *Service) CreateBackup(ctx context.Context) (*BackupResult, error) {
-	// Determine backup directory (relative to DB file)
-	dbDir := filepath.Dir(s.dbPath)
-	backupDir := filepath.Join(dbDir, "backups")
+	backupDir := s.backupDir()
 
 	// Create backup directory if it doesn't exist
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
@@ -97,6 +95,45 @@ func (s *Service) CreateBackup(ctx context.Context) (*BackupResult, error) {
 	}, nil
 }
 
+// LastBackup returns the most recent backup file (nil if no backups exist) and when it was written
+func (s *Service) LastBackup() (*BackupResult, time.Time, error) {
+	entries, err := os.ReadDir(s.backupDir())
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("read backup directory: %w", err)
+	}
+
+	// timestamped filenames sort chronologically
+	var latest string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), "_regdump.sql.gz") && e.Name() > latest {
+			latest = e.Name()
+		}
+	}
+	if latest == "" {
+		return nil, time.Time{}, nil
+	}
+
+	path := filepath.Join(s.backupDir(), latest)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("stat backup file: %w", err)
+	}
+
+	return &BackupResult{
+		Filename: latest,
+		Path:     path,
+		Size:     info.Size(),
+	}, info.ModTime(), nil
+}
+
+// backupDir is the backup directory (relative to DB file)
+func (s *Service) backupDir() string {
+	return filepath.Join(filepath.Dir(s.dbPath), "backups")
+}
+
 // generateDump creates a SQL dump from the database
 func generateDump(ctx context.Context, db *sqlx.DB) (string, error) {
 	var sb strings.Builder
@@ -130,3 +130,50 @@ func TestBackupService(t *testing.T) {
 		t.Error("expected dump to contain COMMIT")
 	}
 }
+
+func TestLastBackup(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db := testutil.NewTestDBAt(t, dbPath)
+	backupSvc := backup.NewService(db, dbPath)
+
+	t.Run("no backups", func(t *testing.T) {
+		last, _, err := backupSvc.LastBackup()
+		if err != nil {
+			t.Fatalf("LastBackup: %v", err)
+		}
+		if last != nil {
+			t.Errorf("expected no backup, got %+v", last)
+		}
+	})
+
+	t.Run("returns newest backup", func(t *testing.T) {
+		// an older backup left over from a previous day
+		backupDir := filepath.Join(filepath.Dir(dbPath), "backups")
+		if err := os.MkdirAll(backupDir, 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(backupDir, "2000-01-01_00.00.00_regdump.sql.gz"), []byte("old"), 0644); err != nil {
+			t.Fatalf("write old backup: %v", err)
+		}
+
+		created, err := backupSvc.CreateBackup(ctx)
+		if err != nil {
+			t.Fatalf("CreateBackup: %v", err)
+		}
+
+		last, at, err := backupSvc.LastBackup()
+		if err != nil {
+			t.Fatalf("LastBackup: %v", err)
+		}
+		if last == nil || last.Filename != created.Filename {
+			t.Fatalf("expected %s, got %+v", created.Filename, last)
+		}
+		if last.Size != created.Size {
+			t.Errorf("expected size %d, got %d", created.Size, last.Size)
+		}
+		if at.IsZero() {
+			t.Error("expected backup time to be set")
+		}
+	})
+}
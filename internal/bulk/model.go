@@ -0,0 +1,164 @@
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrProductRequired = errors.New("product is required")
+	ErrNoChange        = errors.New("no change given")
+	ErrNoLicenses      = errors.New("no licenses match the selection")
+	ErrInvalidChange   = errors.New("change cannot be applied")
+	ErrWrongProduct    = errors.New("feature belongs to another product")
+)
+
+// Selection picks the licenses of a product a bulk operation applies to
+type Selection struct {
+	ProductID   int64   `json:"productId"`
+	ExpiresFrom string  `json:"expiresFrom"` // yyyy-mm-dd; "" = no lower bound
+	ExpiresTo   string  `json:"expiresTo"`   // yyyy-mm-dd, inclusive; "" = no upper bound
+	CustomerIDs []int64 `json:"customerIds"` // empty = all customers
+}
+
+// String describes the selection for the audit trail
+func (s Selection) String() string {
+	parts := []string{fmt.Sprintf("product %d", s.ProductID)}
+	switch {
+	case s.ExpiresFrom != "" && s.ExpiresTo != "":
+		parts = append(parts, fmt.Sprintf("expiring %s to %s", s.ExpiresFrom, s.ExpiresTo))
+	case s.ExpiresFrom != "":
+		parts = append(parts, "expiring from "+s.ExpiresFrom)
+	case s.ExpiresTo != "":
+		parts = append(parts, "expiring until "+s.ExpiresTo)
+	}
+	if len(s.CustomerIDs) > 0 {
+		parts = append(parts, fmt.Sprintf("customers %s", joinIDs(s.CustomerIDs)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Change is what a bulk operation does to each selected license. Zero values
+// leave the license unchanged.
+type Change struct {
+	ExtendMonths      int     `json:"extendMonths"`      // added to the expiration date
+	ExtendMaintMonths int     `json:"extendMaintMonths"` // added to the maintenance expiration date
+	AddSeats          int     `json:"addSeats"`          // added to the seat count (negative removes seats)
+	MaxProductVersion *string `json:"maxProductVersion"` // nil = unchanged; "" removes the restriction
+	FeatureID         int64   `json:"featureId"`         // feature whose license value is set; 0 = none
+	FeatureValue      string  `json:"featureValue"`      // empty removes the license value of a non-string feature
+}
+
+// IsEmpty reports whether the change does nothing
+func (c Change) IsEmpty() bool {
+	return c.ExtendMonths == 0 && c.ExtendMaintMonths == 0 && c.AddSeats == 0 &&
+		c.MaxProductVersion == nil && c.FeatureID == 0
+}
+
+// String describes the change for the audit trail
+func (c Change) String() string {
+	var parts []string
+	if c.ExtendMonths != 0 {
+		parts = append(parts, fmt.Sprintf("expiration %+d months", c.ExtendMonths))
+	}
+	if c.ExtendMaintMonths != 0 {
+		parts = append(parts, fmt.Sprintf("maintenance %+d months", c.ExtendMaintMonths))
+	}
+	if c.AddSeats != 0 {
+		parts = append(parts, fmt.Sprintf("seats %+d", c.AddSeats))
+	}
+	if c.MaxProductVersion != nil {
+		parts = append(parts, fmt.Sprintf("max version %q", *c.MaxProductVersion))
+	}
+	if c.FeatureID != 0 {
+		parts = append(parts, fmt.Sprintf("feature %d = %q", c.FeatureID, c.FeatureValue))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Item is one selected license with its values before and after the change
+type Item struct {
+	LicenseID              int64  `json:"licenseId"`
+	CustomerID             int64  `json:"customerId"`
+	CustomerName           string `json:"customerName"`
+	ProductName            string `json:"productName,omitempty"` // set for bundled licenses
+	LicenseKey             string `json:"licenseKey"`
+	BundleID               *int64 `json:"bundleId,omitempty"` // seats and dates apply to the whole bundle
+	ExpirationDate         string `json:"expirationDate"`
+	NewExpirationDate      string `json:"newExpirationDate"`
+	MaintExpirationDate    string `json:"maintExpirationDate"`
+	NewMaintExpirationDate string `json:"newMaintExpirationDate"`
+	LicenseCount           int    `json:"licenseCount"`
+	NewLicenseCount        int    `json:"newLicenseCount"`
+	MaxProductVersion      string `json:"maxProductVersion"`
+	NewMaxProductVersion   string `json:"newMaxProductVersion"`
+	FeatureValue           string `json:"featureValue"`    // license value of the changed feature; "" = none
+	NewFeatureValue        string `json:"newFeatureValue"` // canonical form
+	Error                  string `json:"error,omitempty"` // why the change cannot be applied to this license
+}
+
+// Changes describes the item's old and new values for the audit trail
+func (it Item) Changes(featureChanged bool) string {
+	var parts []string
+	add := func(name, from, to string) {
+		if from != to {
+			parts = append(parts, fmt.Sprintf("%s %q -> %q", name, from, to))
+		}
+	}
+	add("expiration_date", it.ExpirationDate, it.NewExpirationDate)
+	add("maint_expiration_date", it.MaintExpirationDate, it.NewMaintExpirationDate)
+	add("license_count", fmt.Sprint(it.LicenseCount), fmt.Sprint(it.NewLicenseCount))
+	add("max_product_version", it.MaxProductVersion, it.NewMaxProductVersion)
+	if featureChanged {
+		add("feature_value", it.FeatureValue, it.NewFeatureValue)
+	}
+	if len(parts) == 0 {
+		return "unchanged"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Preview is the outcome of a bulk operation before it is applied
+type Preview struct {
+	Selection Selection `json:"selection"`
+	Change    Change    `json:"change"`
+	Items     []Item    `json:"items"`
+	Bundled   []Item    `json:"bundled"` // other products' licenses whose seats and dates change with a selected bundle license
+	Errors    int       `json:"errors"`  // items the change cannot be applied to
+}
+
+// Operation is an applied bulk operation in the audit trail
+type Operation struct {
+	OperationID   int64              `db:"operation_id" json:"operationId"`
+	ProductID     int64              `db:"product_id" json:"productId"`
+	OperationTime string             `db:"operation_time" json:"operationTime"`
+	Selection     string             `db:"selection" json:"selection"`
+	Changes       string             `db:"changes" json:"changes"`
+	LicenseCount  int                `db:"license_count" json:"licenseCount"`
+	Licenses      []OperationLicense `db:"-" json:"licenses,omitempty"`
+}
+
+// OperationLicense is the change a bulk operation made to one license
+type OperationLicense struct {
+	OperationID  int64  `db:"operation_id" json:"-"`
+	LicenseID    int64  `db:"license_id" json:"licenseId"`
+	CustomerName string `db:"customer_name" json:"customerName"`
+	Changes      string `db:"changes" json:"changes"`
+}
+
+// License is a selectable license of a product with its customer
+type License struct {
+	LicenseID    int64  `db:"license_id"`
+	CustomerID   int64  `db:"customer_id"`
+	CustomerName string `db:"customer_name"`
+	ProductName  string `db:"product_name"` // bundled licenses only
+}
+
+func joinIDs(ids []int64) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = fmt.Sprint(id)
+	}
+	return strings.Join(s, ",")
+}
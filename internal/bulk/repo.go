@@ -0,0 +1,105 @@
+package bulk
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	GetLicenses(ctx context.Context, sel *Selection) ([]License, error)
+	GetBundled(ctx context.Context, customerID, bundleID, licenseID int64) ([]License, error)
+	CreateOperation(ctx context.Context, tx *sqlx.Tx, op *Operation) (int64, error)
+	CreateOperationLicense(ctx context.Context, tx *sqlx.Tx, ol *OperationLicense) error
+	GetOperations(ctx context.Context, productID int64) ([]Operation, error)
+	GetOperation(ctx context.Context, id int64) (*Operation, error)
+	GetOperationLicenses(ctx context.Context, id int64) ([]OperationLicense, error)
+}
+
+type repo struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) Repository {
+	return &repo{db: db}
+}
+
+// GetLicenses returns the product's licenses within the selection's expiration
+// range; the customer list is applied by the service
+func (r *repo) GetLicenses(ctx context.Context, sel *Selection) ([]License, error) {
+	var out []License
+	err := r.db.SelectContext(ctx, &out, getLicensesSQL,
+		sel.ProductID,
+		sel.ExpiresFrom, sel.ExpiresFrom,
+		sel.ExpiresTo, sel.ExpiresTo,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get licenses: %w", err)
+	}
+	return out, nil
+}
+
+// GetBundled returns the licenses of a customer bundle other than licenseID
+func (r *repo) GetBundled(ctx context.Context, customerID, bundleID, licenseID int64) ([]License, error) {
+	var out []License
+	err := r.db.SelectContext(ctx, &out, getBundledSQL, customerID, bundleID, licenseID)
+	if err != nil {
+		return nil, fmt.Errorf("get bundled licenses: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) CreateOperation(ctx context.Context, tx *sqlx.Tx, op *Operation) (int64, error) {
+	res, err := tx.ExecContext(ctx, createOperationSQL,
+		op.ProductID,
+		op.OperationTime,
+		op.Selection,
+		op.Changes,
+		op.LicenseCount,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create bulk operation: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *repo) CreateOperationLicense(ctx context.Context, tx *sqlx.Tx, ol *OperationLicense) error {
+	_, err := tx.ExecContext(ctx, createOperationLicenseSQL, ol.OperationID, ol.LicenseID, ol.CustomerName, ol.Changes)
+	if err != nil {
+		return fmt.Errorf("create bulk operation license: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) GetOperations(ctx context.Context, productID int64) ([]Operation, error) {
+	out := []Operation{}
+	err := r.db.SelectContext(ctx, &out, getOperationsSQL, productID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get bulk operations: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetOperation(ctx context.Context, id int64) (*Operation, error) {
+	var out Operation
+	err := r.db.GetContext(ctx, &out, getOperationSQL, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("bulk operation not found (%d)", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get bulk operation: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *repo) GetOperationLicenses(ctx context.Context, id int64) ([]OperationLicense, error) {
+	out := []OperationLicense{}
+	err := r.db.SelectContext(ctx, &out, getOperationLicensesSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("get bulk operation licenses: %w", err)
+	}
+	return out, nil
+}
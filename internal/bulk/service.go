@@ -0,0 +1,351 @@
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
+)
+
+// timeFormat is the format of operation times (UTC)
+const timeFormat = "2006-01-02 15:04:05"
+
+type Service struct {
+	repo       Repository
+	db         *sqlx.DB
+	licenseSvc *license.Service
+	featureSvc *feature.Service
+	valueSvc   *featurevalue.Service
+}
+
+func NewService(db *sqlx.DB, licenseSvc *license.Service, featureSvc *feature.Service, valueSvc *featurevalue.Service) *Service {
+	return &Service{
+		db:         db,
+		repo:       New(db),
+		licenseSvc: licenseSvc,
+		featureSvc: featureSvc,
+		valueSvc:   valueSvc,
+	}
+}
+
+func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Preview returns the selected licenses with their values before and after
+// the change, without changing anything. Licenses the change cannot be applied
+// to (e.g. fewer than one seat left) carry an error. A change of seats or dates
+// to a bundle license also changes the bundle's licenses of other products;
+// those are listed as bundled.
+func (s *Service) Preview(ctx context.Context, sel Selection, ch Change) (*Preview, error) {
+	p, _, err := s.preview(ctx, sel, ch)
+	return p, err
+}
+
+// Apply applies the change to every selected license in one transaction and
+// records the operation, including the bundled licenses it changed, in the
+// audit trail. Nothing is changed if the change
+// cannot be applied to one of the licenses.
+func (s *Service) Apply(ctx context.Context, sel Selection, ch Change) (*Operation, error) {
+	p, lics, err := s.preview(ctx, sel, ch)
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range p.Items {
+		if it.Error != "" {
+			return nil, fmt.Errorf("%w: license %d (%s): %s", ErrInvalidChange, it.LicenseID, it.CustomerName, it.Error)
+		}
+	}
+
+	op := &Operation{
+		ProductID:     sel.ProductID,
+		OperationTime: time.Now().UTC().Format(timeFormat),
+		Selection:     sel.String(),
+		Changes:       ch.String(),
+		LicenseCount:  len(p.Items) + len(p.Bundled),
+		Licenses:      make([]OperationLicense, 0, len(p.Items)+len(p.Bundled)),
+	}
+	termsChanged := ch.ExtendMonths != 0 || ch.ExtendMaintMonths != 0 || ch.AddSeats != 0 || ch.MaxProductVersion != nil
+
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		id, err := s.repo.CreateOperation(ctx, tx, op)
+		if err != nil {
+			return err
+		}
+		op.OperationID = id
+
+		for i, it := range p.Items {
+			if termsChanged {
+				lic := lics[i]
+				lic.ExpirationDate = it.NewExpirationDate
+				lic.MaintExpirationDate = it.NewMaintExpirationDate
+				lic.LicenseCount = it.NewLicenseCount
+				lic.MaxProductVersion = it.NewMaxProductVersion
+				if err := s.licenseSvc.UpdateTerms(ctx, tx, &lic); err != nil {
+					return fmt.Errorf("license %d: %w", it.LicenseID, err)
+				}
+			}
+			if ch.FeatureID != 0 {
+				fv := &featurevalue.FeatureValue{LicenseID: it.LicenseID, FeatureID: ch.FeatureID, FeatureValue: ch.FeatureValue}
+				if err := s.valueSvc.Set(ctx, tx, fv); err != nil {
+					return fmt.Errorf("license %d: %w", it.LicenseID, err)
+				}
+			}
+
+			op.Licenses = append(op.Licenses, OperationLicense{
+				OperationID:  id,
+				LicenseID:    it.LicenseID,
+				CustomerName: it.CustomerName,
+				Changes:      it.Changes(ch.FeatureID != 0),
+			})
+		}
+		// The bundled licenses were changed by UpdateTerms along with their
+		// bundle's selected license
+		for _, it := range p.Bundled {
+			op.Licenses = append(op.Licenses, OperationLicense{
+				OperationID:  id,
+				LicenseID:    it.LicenseID,
+				CustomerName: it.CustomerName,
+				Changes:      it.Changes(false),
+			})
+		}
+		for i := range op.Licenses {
+			if err := s.repo.CreateOperationLicense(ctx, tx, &op.Licenses[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("bulk operation applied",
+		"operation_id", op.OperationID,
+		"product_id", op.ProductID,
+		"licenses", op.LicenseCount,
+		"selection", op.Selection,
+		"changes", op.Changes,
+	)
+	return op, nil
+}
+
+// GetOperations returns the audit trail of bulk operations, newest first
+// (productID 0 = all products)
+func (s *Service) GetOperations(ctx context.Context, productID int64) ([]Operation, error) {
+	return s.repo.GetOperations(ctx, productID)
+}
+
+// GetOperation returns a bulk operation with the change it made to each license
+func (s *Service) GetOperation(ctx context.Context, id int64) (*Operation, error) {
+	op, err := s.repo.GetOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if op.Licenses, err = s.repo.GetOperationLicenses(ctx, id); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// preview computes the preview along with the selected licenses (in item order)
+func (s *Service) preview(ctx context.Context, sel Selection, ch Change) (*Preview, []license.License, error) {
+	if sel.ProductID == 0 {
+		return nil, nil, ErrProductRequired
+	}
+	if ch.IsEmpty() {
+		return nil, nil, ErrNoChange
+	}
+	for _, d := range []string{sel.ExpiresFrom, sel.ExpiresTo} {
+		if _, err := parseDate(d); d != "" && err != nil {
+			return nil, nil, fmt.Errorf("%w: expiration range %q is not a yyyy-mm-dd date", ErrInvalidChange, d)
+		}
+	}
+
+	var feat *feature.Feature
+	if ch.FeatureID != 0 {
+		f, err := s.featureSvc.Get(ctx, ch.FeatureID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if f.ProductID != sel.ProductID {
+			return nil, nil, fmt.Errorf("%w (feature %d)", ErrWrongProduct, ch.FeatureID)
+		}
+		feat = f
+	}
+
+	selected, err := s.repo.GetLicenses(ctx, &sel)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sel.CustomerIDs) > 0 {
+		selected = slices.DeleteFunc(selected, func(l License) bool {
+			return !slices.Contains(sel.CustomerIDs, l.CustomerID)
+		})
+	}
+	if len(selected) == 0 {
+		return nil, nil, ErrNoLicenses
+	}
+
+	p := &Preview{Selection: sel, Change: ch, Items: make([]Item, len(selected))}
+	lics := make([]license.License, len(selected))
+	for i, sl := range selected {
+		lic, err := s.licenseSvc.Get(ctx, sl.LicenseID)
+		if err != nil {
+			return nil, nil, err
+		}
+		lics[i] = *lic
+
+		it, err := s.previewItem(ctx, lic, feat, ch)
+		if err != nil {
+			return nil, nil, err
+		}
+		it.CustomerName = sl.CustomerName
+		if it.Error != "" {
+			p.Errors++
+		}
+		p.Items[i] = *it
+	}
+
+	p.Bundled = []Item{}
+	if ch.ExtendMonths != 0 || ch.ExtendMaintMonths != 0 || ch.AddSeats != 0 {
+		for _, it := range p.Items {
+			if it.BundleID == nil {
+				continue
+			}
+			bundled, err := s.bundledItems(ctx, &it)
+			if err != nil {
+				return nil, nil, err
+			}
+			p.Bundled = append(p.Bundled, bundled...)
+		}
+	}
+	return p, lics, nil
+}
+
+// bundledItems returns the other licenses of the bundle of a selected license
+// with the seats and dates they take from it
+func (s *Service) bundledItems(ctx context.Context, it *Item) ([]Item, error) {
+	bundled, err := s.repo.GetBundled(ctx, it.CustomerID, *it.BundleID, it.LicenseID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, len(bundled))
+	for i, bl := range bundled {
+		lic, err := s.licenseSvc.Get(ctx, bl.LicenseID)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = Item{
+			LicenseID:              lic.LicenseID,
+			CustomerID:             lic.CustomerID,
+			CustomerName:           bl.CustomerName,
+			ProductName:            bl.ProductName,
+			LicenseKey:             lic.LicenseKey,
+			BundleID:               lic.BundleID,
+			ExpirationDate:         lic.ExpirationDate,
+			NewExpirationDate:      it.NewExpirationDate,
+			MaintExpirationDate:    lic.MaintExpirationDate,
+			NewMaintExpirationDate: it.NewMaintExpirationDate,
+			LicenseCount:           lic.LicenseCount,
+			NewLicenseCount:        it.NewLicenseCount,
+			MaxProductVersion:      lic.MaxProductVersion,
+			NewMaxProductVersion:   lic.MaxProductVersion,
+		}
+	}
+	return items, nil
+}
+
+// previewItem computes the change to one license
+func (s *Service) previewItem(ctx context.Context, lic *license.License, feat *feature.Feature, ch Change) (*Item, error) {
+	it := &Item{
+		LicenseID:           lic.LicenseID,
+		CustomerID:          lic.CustomerID,
+		LicenseKey:          lic.LicenseKey,
+		BundleID:            lic.BundleID,
+		ExpirationDate:      lic.ExpirationDate,
+		MaintExpirationDate: lic.MaintExpirationDate,
+		LicenseCount:        lic.LicenseCount,
+		MaxProductVersion:   lic.MaxProductVersion,
+	}
+
+	next := *lic
+	var err error
+	if next.ExpirationDate, err = addMonths(lic.ExpirationDate, ch.ExtendMonths); err != nil {
+		it.Error = err.Error()
+	}
+	if next.MaintExpirationDate, err = addMonths(lic.MaintExpirationDate, ch.ExtendMaintMonths); err != nil {
+		it.Error = err.Error()
+	}
+	next.LicenseCount += ch.AddSeats
+	if ch.MaxProductVersion != nil {
+		next.MaxProductVersion = *ch.MaxProductVersion
+	}
+	if err := next.Validate(); err != nil && it.Error == "" {
+		it.Error = err.Error()
+	}
+	it.NewExpirationDate = next.ExpirationDate
+	it.NewMaintExpirationDate = next.MaintExpirationDate
+	it.NewLicenseCount = next.LicenseCount
+	it.NewMaxProductVersion = next.MaxProductVersion
+
+	if feat != nil {
+		vals, err := s.valueSvc.GetFeatureValues(ctx, lic.LicenseID)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			if v.FeatureID == feat.FeatureID {
+				it.FeatureValue = v.FeatureValue
+			}
+		}
+		it.NewFeatureValue = ch.FeatureValue
+		if ch.FeatureValue != "" || feat.FeatureType == featurevalue.TypeString {
+			v, err := feat.Rules().Validate("feature_value", ch.FeatureValue)
+			if err != nil && it.Error == "" {
+				it.Error = err.Error()
+			} else if err == nil {
+				it.NewFeatureValue = v
+			}
+		}
+	}
+	return it, nil
+}
+
+// addMonths adds months to a yyyy-mm-dd date, keeping the day of the month
+// or, when the target month is shorter, using its last day (2026-01-31 plus
+// one month is 2026-02-28). Dates in 9999 mean "never" and are kept.
+func addMonths(date string, months int) (string, error) {
+	if months == 0 {
+		return date, nil
+	}
+	t, err := parseDate(date)
+	if err != nil {
+		return date, fmt.Errorf("date %q is not yyyy-mm-dd", date)
+	}
+	if t.Year() >= 9999 {
+		return date, nil
+	}
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	day := min(t.Day(), first.AddDate(0, 1, -1).Day())
+	return first.AddDate(0, 0, day-1).Format("2006-01-02"), nil
+}
+
+func parseDate(date string) (time.Time, error) {
+	return time.Parse("2006-01-02", date)
+}
@@ -0,0 +1,232 @@
+package bulk_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+
+	"winsbygroup.com/regserver/internal/bulk"
+	"winsbygroup.com/regserver/internal/bundle"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestBulkService(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	licSvc := license.NewService(db)
+	featSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+	svc := bulk.NewService(db, licSvc, featSvc, fvSvc)
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+
+	prod, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Bulk", ProductGUID: "GUID-BULK"})
+	other, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Other", ProductGUID: "GUID-OTHER"})
+	maxSeats := int64(50)
+	seats, _ := featSvc.Create(ctx, &feature.Feature{ProductID: prod.ProductID, FeatureName: "Seats", FeatureType: featurevalue.TypeInteger, DefaultValue: "1", MaxValue: &maxSeats})
+	otherFeat, _ := featSvc.Create(ctx, &feature.Feature{ProductID: other.ProductID, FeatureName: "Seats", FeatureType: featurevalue.TypeInteger, DefaultValue: "1"})
+
+	newLicense := func(name string, productID int64, count int, expires string) *license.License {
+		cust, err := custSvc.Create(ctx, &customer.Customer{CustomerName: name})
+		if err != nil {
+			t.Fatalf("create customer: %v", err)
+		}
+		lic, err := licSvc.Create(ctx, &license.License{
+			CustomerID:          cust.CustomerID,
+			ProductID:           productID,
+			LicenseKey:          uuid.New().String(),
+			LicenseCount:        count,
+			StartDate:           "2024-01-01",
+			ExpirationDate:      expires,
+			MaintExpirationDate: expires,
+			MaxProductVersion:   "4.0.0",
+		})
+		if err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+		return lic
+	}
+	a := newLicense("Alpha", prod.ProductID, 5, "2025-06-30")
+	b := newLicense("Beta", prod.ProductID, 1, "2025-12-31")
+	c := newLicense("Gamma", prod.ProductID, 3, "2026-12-31")
+	newLicense("Delta", other.ProductID, 3, "2025-06-30")
+
+	t.Run("validation", func(t *testing.T) {
+		if _, err := svc.Preview(ctx, bulk.Selection{}, bulk.Change{AddSeats: 1}); !errors.Is(err, bulk.ErrProductRequired) {
+			t.Errorf("expected ErrProductRequired, got %v", err)
+		}
+		if _, err := svc.Preview(ctx, bulk.Selection{ProductID: prod.ProductID}, bulk.Change{}); !errors.Is(err, bulk.ErrNoChange) {
+			t.Errorf("expected ErrNoChange, got %v", err)
+		}
+		sel := bulk.Selection{ProductID: prod.ProductID, ExpiresFrom: "2030-01-01"}
+		if _, err := svc.Preview(ctx, sel, bulk.Change{AddSeats: 1}); !errors.Is(err, bulk.ErrNoLicenses) {
+			t.Errorf("expected ErrNoLicenses, got %v", err)
+		}
+		ch := bulk.Change{FeatureID: otherFeat.FeatureID, FeatureValue: "2"}
+		if _, err := svc.Preview(ctx, bulk.Selection{ProductID: prod.ProductID}, ch); !errors.Is(err, bulk.ErrWrongProduct) {
+			t.Errorf("expected ErrWrongProduct, got %v", err)
+		}
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		sel := bulk.Selection{ProductID: prod.ProductID, ExpiresTo: "2025-12-31"}
+		p, err := svc.Preview(ctx, sel, bulk.Change{ExtendMonths: 12, AddSeats: -2})
+		if err != nil {
+			t.Fatalf("preview: %v", err)
+		}
+		if len(p.Items) != 2 || p.Items[0].LicenseID != a.LicenseID || p.Items[1].LicenseID != b.LicenseID {
+			t.Fatalf("expected Alpha and Beta, got %+v", p.Items)
+		}
+		if p.Items[0].NewExpirationDate != "2026-06-30" || p.Items[0].NewLicenseCount != 3 {
+			t.Errorf("unexpected Alpha change: %+v", p.Items[0])
+		}
+		// Beta would be left without seats
+		if p.Errors != 1 || p.Items[1].Error == "" {
+			t.Errorf("expected an error for Beta, got %+v", p.Items[1])
+		}
+		if _, err := svc.Apply(ctx, sel, bulk.Change{ExtendMonths: 12, AddSeats: -2}); !errors.Is(err, bulk.ErrInvalidChange) {
+			t.Errorf("expected ErrInvalidChange, got %v", err)
+		}
+		got, _ := licSvc.Get(ctx, a.LicenseID)
+		if got.ExpirationDate != "2025-06-30" || got.LicenseCount != 5 {
+			t.Errorf("expected nothing applied, got %+v", got)
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		version := "5.0.0"
+		sel := bulk.Selection{ProductID: prod.ProductID, CustomerIDs: []int64{a.CustomerID, c.CustomerID}}
+		ch := bulk.Change{ExtendMaintMonths: 1, AddSeats: 1, MaxProductVersion: &version, FeatureID: seats.FeatureID, FeatureValue: " 20 "}
+		op, err := svc.Apply(ctx, sel, ch)
+		if err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+		if op.LicenseCount != 2 {
+			t.Errorf("expected 2 licenses, got %d", op.LicenseCount)
+		}
+
+		got, _ := licSvc.Get(ctx, c.LicenseID)
+		if got.MaintExpirationDate != "2027-01-31" || got.LicenseCount != 4 || got.MaxProductVersion != "5.0.0" || got.ExpirationDate != "2026-12-31" {
+			t.Errorf("unexpected Gamma license: %+v", got)
+		}
+		vals, _ := fvSvc.GetFeatureValues(ctx, a.LicenseID)
+		if len(vals) != 1 || vals[0].FeatureValue != "20" {
+			t.Errorf("expected Seats = 20 for Alpha, got %+v", vals)
+		}
+		untouched, _ := licSvc.Get(ctx, b.LicenseID)
+		if untouched.LicenseCount != 1 || untouched.MaxProductVersion != "4.0.0" {
+			t.Errorf("expected Beta unchanged, got %+v", untouched)
+		}
+
+		saved, err := svc.GetOperation(ctx, op.OperationID)
+		if err != nil {
+			t.Fatalf("get operation: %v", err)
+		}
+		if len(saved.Licenses) != 2 || saved.Licenses[0].CustomerName != "Alpha" {
+			t.Fatalf("unexpected audit licenses: %+v", saved.Licenses)
+		}
+		for _, want := range []string{`license_count "5" -> "6"`, `max_product_version "4.0.0" -> "5.0.0"`, `feature_value "" -> "20"`} {
+			if !strings.Contains(saved.Licenses[0].Changes, want) {
+				t.Errorf("audit %q is missing %q", saved.Licenses[0].Changes, want)
+			}
+		}
+		ops, _ := svc.GetOperations(ctx, prod.ProductID)
+		if len(ops) != 1 || !strings.Contains(ops[0].Changes, "seats +1") {
+			t.Errorf("unexpected operations: %+v", ops)
+		}
+		if ops, _ := svc.GetOperations(ctx, other.ProductID); len(ops) != 0 {
+			t.Errorf("expected no operations for the other product, got %d", len(ops))
+		}
+	})
+
+	t.Run("month end", func(t *testing.T) {
+		jan31 := newLicense("Jan 31", prod.ProductID, 1, "2026-01-31")
+		leap := newLicense("Leap Day", prod.ProductID, 1, "2024-02-29")
+		for _, tt := range []struct {
+			lic    *license.License
+			months int
+			want   string
+		}{
+			{jan31, 1, "2026-02-28"},
+			{jan31, 3, "2026-04-30"},
+			{jan31, -2, "2025-11-30"},
+			{leap, 12, "2025-02-28"},
+			{leap, 48, "2028-02-29"},
+		} {
+			sel := bulk.Selection{ProductID: prod.ProductID, CustomerIDs: []int64{tt.lic.CustomerID}}
+			p, err := svc.Preview(ctx, sel, bulk.Change{ExtendMonths: tt.months, ExtendMaintMonths: tt.months})
+			if err != nil {
+				t.Fatalf("preview: %v", err)
+			}
+			if it := p.Items[0]; it.NewExpirationDate != tt.want || it.NewMaintExpirationDate != tt.want {
+				t.Errorf("%s %+d months: expected %s, got %s / %s", it.ExpirationDate, tt.months, tt.want, it.NewExpirationDate, it.NewMaintExpirationDate)
+			}
+		}
+	})
+
+	t.Run("bundled licenses", func(t *testing.T) {
+		bundleSvc := bundle.NewService(db, licSvc)
+		b, err := bundleSvc.Create(ctx, &bundle.Bundle{BundleName: "Suite"}, []int64{prod.ProductID, other.ProductID})
+		if err != nil {
+			t.Fatalf("create bundle: %v", err)
+		}
+		cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Epsilon"})
+		if _, err := bundleSvc.License(ctx, cust.CustomerID, b.BundleID, &license.License{
+			LicenseCount:        2,
+			StartDate:           "2024-01-01",
+			ExpirationDate:      "2026-03-31",
+			MaintExpirationDate: "2026-03-31",
+		}); err != nil {
+			t.Fatalf("license bundle: %v", err)
+		}
+
+		sel := bulk.Selection{ProductID: prod.ProductID, CustomerIDs: []int64{cust.CustomerID}}
+		ch := bulk.Change{ExtendMonths: 2, AddSeats: 1}
+		p, err := svc.Preview(ctx, sel, ch)
+		if err != nil {
+			t.Fatalf("preview: %v", err)
+		}
+		if len(p.Items) != 1 || len(p.Bundled) != 1 {
+			t.Fatalf("expected one selected and one bundled license, got %+v / %+v", p.Items, p.Bundled)
+		}
+		sibling := p.Bundled[0]
+		if sibling.ProductName != "Other" || sibling.NewExpirationDate != "2026-05-31" || sibling.NewLicenseCount != 3 {
+			t.Errorf("unexpected bundled license: %+v", sibling)
+		}
+
+		op, err := svc.Apply(ctx, sel, ch)
+		if err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+		if op.LicenseCount != 2 {
+			t.Errorf("expected the bundled license to be counted, got %d", op.LicenseCount)
+		}
+		got, _ := licSvc.Get(ctx, sibling.LicenseID)
+		if got.ExpirationDate != "2026-05-31" || got.LicenseCount != 3 {
+			t.Errorf("expected the bundled license to change as previewed, got %+v", got)
+		}
+		saved, _ := svc.GetOperation(ctx, op.OperationID)
+		if len(saved.Licenses) != 2 || saved.Licenses[1].LicenseID != sibling.LicenseID {
+			t.Errorf("expected the bundled license in the audit trail, got %+v", saved.Licenses)
+		}
+
+		// Feature changes stay with the selected licenses
+		p, err = svc.Preview(ctx, sel, bulk.Change{FeatureID: seats.FeatureID, FeatureValue: "3"})
+		if err != nil {
+			t.Fatalf("preview: %v", err)
+		}
+		if len(p.Bundled) != 0 {
+			t.Errorf("expected no bundled licenses for a feature change, got %+v", p.Bundled)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package bulk
+
+const getLicensesSQL = `
+SELECT
+    l.license_id,
+    l.customer_id,
+    c.customer_name
+FROM license l
+JOIN customer c ON c.customer_id = l.customer_id
+WHERE l.product_id = ?
+  AND (? = '' OR l.expiration_date >= ?)
+  AND (? = '' OR l.expiration_date <= ?)
+ORDER BY c.customer_name, l.license_id
+`
+
+// getBundledSQL returns the other licenses of a customer bundle, whose seats
+// and dates follow those of the bundle's licenses
+const getBundledSQL = `
+SELECT
+    l.license_id,
+    l.customer_id,
+    c.customer_name,
+    p.product_name
+FROM license l
+JOIN customer c ON c.customer_id = l.customer_id
+JOIN product p ON p.product_id = l.product_id
+WHERE l.customer_id = ? AND l.bundle_id = ? AND l.license_id <> ?
+ORDER BY p.product_name, l.license_id
+`
+
+const createOperationSQL = `
+INSERT INTO bulk_operation (
+    product_id,
+    operation_time,
+    selection,
+    changes,
+    license_count
+) VALUES (?, ?, ?, ?, ?)
+`
+
+const createOperationLicenseSQL = `
+INSERT INTO bulk_operation_license (
+    operation_id,
+    license_id,
+    customer_name,
+    changes
+) VALUES (?, ?, ?, ?)
+`
+
+const getOperationsSQL = `
+SELECT
+    operation_id,
+    product_id,
+    operation_time,
+    selection,
+    changes,
+    license_count
+FROM bulk_operation
+WHERE ? = 0 OR product_id = ?
+ORDER BY operation_id DESC
+`
+
+const getOperationSQL = `
+SELECT
+    operation_id,
+    product_id,
+    operation_time,
+    selection,
+    changes,
+    license_count
+FROM bulk_operation
+WHERE operation_id = ?
+`
+
+const getOperationLicensesSQL = `
+SELECT
+    operation_id,
+    license_id,
+    customer_name,
+    changes
+FROM bulk_operation_license
+WHERE operation_id = ?
+ORDER BY customer_name, license_id
+`
@@ -0,0 +1,106 @@
+package bundle
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"winsbygroup.com/regserver/internal/license"
+)
+
+// Validation errors
+var (
+	ErrNameRequired    = errors.New("bundle name is required")
+	ErrNoProducts      = errors.New("a bundle needs at least one product")
+	ErrBundleLicensed  = errors.New("bundle products cannot be changed while the bundle is licensed to customers")
+	ErrAlreadyLicensed = errors.New("bundle is already licensed to the customer")
+)
+
+// Bundle groups products that are sold and licensed as a single unit
+type Bundle struct {
+	BundleID    int64     `db:"bundle_id" json:"bundleId"`
+	BundleName  string    `db:"bundle_name" json:"bundleName"`
+	Description string    `db:"description" json:"description"`
+	CreatedAt   string    `db:"created_at" json:"createdAt"`
+	Products    []Product `db:"-" json:"products"`
+}
+
+// Validate checks business rules for a bundle
+func (b *Bundle) Validate() error {
+	if strings.TrimSpace(b.BundleName) == "" {
+		return ErrNameRequired
+	}
+	return nil
+}
+
+// ProductIDs returns the IDs of the bundle's products
+func (b *Bundle) ProductIDs() []int64 {
+	ids := make([]int64, len(b.Products))
+	for i, p := range b.Products {
+		ids[i] = p.ProductID
+	}
+	return ids
+}
+
+// Product is a product in a bundle
+type Product struct {
+	ProductID   int64  `db:"product_id" json:"productId"`
+	ProductName string `db:"product_name" json:"productName"`
+	ProductGUID string `db:"product_guid" json:"productGuid"`
+}
+
+// CustomerBundle is a bundle licensed to a customer. The customer gets one
+// license per product of the bundle, all with the same seats and dates, and a
+// bundle license key that activates any of them.
+type CustomerBundle struct {
+	CustomerID int64             `db:"customer_id" json:"customerId"`
+	BundleID   int64             `db:"bundle_id" json:"bundleId"`
+	BundleName string            `db:"bundle_name" json:"bundleName"`
+	LicenseKey string            `db:"license_key" json:"licenseKey"`
+	KeyStatus  string            `db:"key_status" json:"keyStatus"`
+	CreatedAt  string            `db:"created_at" json:"createdAt"`
+	Licenses   []license.License `db:"-" json:"licenses"`
+}
+
+// KeyRef identifies the customer bundle a bundle license key belongs to and
+// the products it activates, with the bundle license of each product
+// (LicenseIDs[i] licenses ProductIDs[i])
+type KeyRef struct {
+	CustomerID int64
+	BundleID   int64
+	ProductIDs []int64
+	LicenseIDs []int64
+}
+
+// LicenseFor returns the ID of the bundle license for a product (0 if the
+// bundle does not cover the product)
+func (r KeyRef) LicenseFor(productID int64) int64 {
+	for i, id := range r.ProductIDs {
+		if id == productID {
+			return r.LicenseIDs[i]
+		}
+	}
+	return 0
+}
+
+// KeyHistory is a change to a bundle license key, as license.KeyHistory is
+// for a license key: a rotation (Event "replaced") or a status change
+type KeyHistory struct {
+	HistoryID  int64  `db:"history_id" json:"historyId"`
+	CustomerID int64  `db:"customer_id" json:"customerId"`
+	BundleID   int64  `db:"bundle_id" json:"bundleId"`
+	LicenseKey string `db:"license_key" json:"licenseKey"`
+	Event      string `db:"event" json:"event"`
+	Reason     string `db:"reason" json:"reason"`
+	ReplacedAt string `db:"replaced_at" json:"replacedAt"`
+	GraceUntil string `db:"grace_until" json:"graceUntil"`
+	RevokedAt  string `db:"revoked_at" json:"revokedAt"`
+}
+
+// InGrace reports whether the replaced key is still accepted at the given time
+func (h KeyHistory) InGrace(now time.Time) bool {
+	return h.Event == license.KeyEventReplaced && h.RevokedAt == "" && h.GraceUntil > now.UTC().Format(timeFormat)
+}
+
+// timeFormat is the format of created_at and key history timestamps (UTC)
+const timeFormat = "2006-01-02 15:04:05"
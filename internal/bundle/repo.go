@@ -0,0 +1,237 @@
+package bundle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]Bundle, error)
+	Get(ctx context.Context, id int64) (*Bundle, error)
+	GetProducts(ctx context.Context, id int64) ([]Product, error)
+	Create(ctx context.Context, tx *sqlx.Tx, b *Bundle) (int64, error)
+	Update(ctx context.Context, tx *sqlx.Tx, b *Bundle) error
+	Delete(ctx context.Context, tx *sqlx.Tx, id int64) error
+	SetProducts(ctx context.Context, tx *sqlx.Tx, id int64, productIDs []int64) error
+	CountCustomers(ctx context.Context, id int64) (int, error)
+
+	GetForCustomer(ctx context.Context, customerID int64) ([]CustomerBundle, error)
+	GetCustomerBundle(ctx context.Context, customerID, bundleID int64) (*CustomerBundle, error)
+	GetByLicenseKey(ctx context.Context, licenseKey string) (*CustomerBundle, error)
+	CreateCustomerBundle(ctx context.Context, tx *sqlx.Tx, cb *CustomerBundle) error
+	DeleteCustomerBundle(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64) error
+
+	UpdateKey(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64, licenseKey string) error
+	UpdateKeyStatus(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64, status string) error
+	GetKeyHistory(ctx context.Context, customerID, bundleID int64) ([]KeyHistory, error)
+	GetKeyHistoryByKey(ctx context.Context, licenseKey string) (*KeyHistory, error)
+	CreateKeyHistory(ctx context.Context, tx *sqlx.Tx, h *KeyHistory) error
+	RevokeKeyHistory(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64, revokedAt string) error
+}
+
+type repo struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) Repository {
+	return &repo{db: db}
+}
+
+func (r *repo) GetAll(ctx context.Context) ([]Bundle, error) {
+	var out []Bundle
+	err := r.db.SelectContext(ctx, &out, getAllBundlesSQL)
+	if err != nil {
+		return nil, fmt.Errorf("get all bundles: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) Get(ctx context.Context, id int64) (*Bundle, error) {
+	var out Bundle
+	err := r.db.GetContext(ctx, &out, getBundleSQL, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("bundle not found (%d)", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get bundle: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *repo) GetProducts(ctx context.Context, id int64) ([]Product, error) {
+	out := []Product{}
+	err := r.db.SelectContext(ctx, &out, getBundleProductsSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("get bundle products: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, b *Bundle) (int64, error) {
+	res, err := tx.ExecContext(ctx, createBundleSQL, b.BundleName, b.Description, b.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("create bundle: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, b *Bundle) error {
+	_, err := tx.ExecContext(ctx, updateBundleSQL, b.BundleName, b.Description, b.BundleID)
+	if err != nil {
+		return fmt.Errorf("update bundle: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, deleteBundleSQL, id)
+	if err != nil {
+		return fmt.Errorf("delete bundle: %w", err)
+	}
+	return nil
+}
+
+// SetProducts replaces the products of a bundle
+func (r *repo) SetProducts(ctx context.Context, tx *sqlx.Tx, id int64, productIDs []int64) error {
+	if _, err := tx.ExecContext(ctx, clearBundleProductsSQL, id); err != nil {
+		return fmt.Errorf("clear bundle products: %w", err)
+	}
+	for _, pid := range productIDs {
+		if _, err := tx.ExecContext(ctx, addBundleProductSQL, id, pid); err != nil {
+			return fmt.Errorf("add bundle product %d: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// CountCustomers returns the number of customers the bundle is licensed to
+func (r *repo) CountCustomers(ctx context.Context, id int64) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, countCustomerBundlesSQL, id)
+	if err != nil {
+		return 0, fmt.Errorf("count bundle customers: %w", err)
+	}
+	return n, nil
+}
+
+func (r *repo) GetForCustomer(ctx context.Context, customerID int64) ([]CustomerBundle, error) {
+	out := []CustomerBundle{}
+	err := r.db.SelectContext(ctx, &out, getCustomerBundlesSQL, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("get customer bundles: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetCustomerBundle(ctx context.Context, customerID, bundleID int64) (*CustomerBundle, error) {
+	var out CustomerBundle
+	err := r.db.GetContext(ctx, &out, getCustomerBundleSQL, customerID, bundleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("bundle license not found (%d/%d)", customerID, bundleID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get customer bundle: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *repo) GetByLicenseKey(ctx context.Context, licenseKey string) (*CustomerBundle, error) {
+	var out CustomerBundle
+	err := r.db.GetContext(ctx, &out, getCustomerBundleByKeySQL, strings.ToLower(licenseKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("bundle license not found: %s", licenseKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get customer bundle by key: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *repo) CreateCustomerBundle(ctx context.Context, tx *sqlx.Tx, cb *CustomerBundle) error {
+	_, err := tx.ExecContext(ctx, createCustomerBundleSQL,
+		cb.CustomerID,
+		cb.BundleID,
+		strings.ToLower(cb.LicenseKey),
+		cb.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("create customer bundle: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) DeleteCustomerBundle(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64) error {
+	_, err := tx.ExecContext(ctx, deleteCustomerBundleSQL, customerID, bundleID)
+	if err != nil {
+		return fmt.Errorf("delete customer bundle: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) UpdateKey(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64, licenseKey string) error {
+	_, err := tx.ExecContext(ctx, updateCustomerBundleKeySQL, strings.ToLower(licenseKey), customerID, bundleID)
+	if err != nil {
+		return fmt.Errorf("update bundle license key: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) UpdateKeyStatus(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64, status string) error {
+	_, err := tx.ExecContext(ctx, updateCustomerBundleKeyStatusSQL, status, customerID, bundleID)
+	if err != nil {
+		return fmt.Errorf("update bundle key status: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) GetKeyHistory(ctx context.Context, customerID, bundleID int64) ([]KeyHistory, error) {
+	out := []KeyHistory{}
+	err := r.db.SelectContext(ctx, &out, getKeyHistorySQL, customerID, bundleID)
+	if err != nil {
+		return nil, fmt.Errorf("get bundle key history: %w", err)
+	}
+	return out, nil
+}
+
+// GetKeyHistoryByKey returns the history entry for a replaced key (nil if the key was never replaced)
+func (r *repo) GetKeyHistoryByKey(ctx context.Context, licenseKey string) (*KeyHistory, error) {
+	var h KeyHistory
+	err := r.db.GetContext(ctx, &h, getKeyHistoryByKeySQL, strings.ToLower(licenseKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get bundle key history by key: %w", err)
+	}
+	return &h, nil
+}
+
+func (r *repo) CreateKeyHistory(ctx context.Context, tx *sqlx.Tx, h *KeyHistory) error {
+	_, err := tx.ExecContext(ctx, createKeyHistorySQL,
+		h.CustomerID,
+		h.BundleID,
+		strings.ToLower(h.LicenseKey),
+		h.Event,
+		h.Reason,
+		h.ReplacedAt,
+		h.GraceUntil,
+	)
+	if err != nil {
+		return fmt.Errorf("create bundle key history: %w", err)
+	}
+	return nil
+}
+
+// RevokeKeyHistory ends the grace period of every replaced key still in grace
+func (r *repo) RevokeKeyHistory(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64, revokedAt string) error {
+	_, err := tx.ExecContext(ctx, revokeKeyHistorySQL, revokedAt, customerID, bundleID, revokedAt)
+	if err != nil {
+		return fmt.Errorf("revoke bundle key history: %w", err)
+	}
+	return nil
+}
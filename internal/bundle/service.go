@@ -0,0 +1,383 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/license"
+)
+
+type Service struct {
+	repo       Repository
+	db         *sqlx.DB
+	licenseSvc *license.Service
+}
+
+func NewService(db *sqlx.DB, licenseSvc *license.Service) *Service {
+	return &Service{
+		db:         db,
+		repo:       New(db),
+		licenseSvc: licenseSvc,
+	}
+}
+
+func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// -------------------------
+// Bundles
+// -------------------------
+
+func (s *Service) GetAll(ctx context.Context) ([]Bundle, error) {
+	out, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		if out[i].Products, err = s.repo.GetProducts(ctx, out[i].BundleID); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (s *Service) Get(ctx context.Context, id int64) (*Bundle, error) {
+	b, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if b.Products, err = s.repo.GetProducts(ctx, id); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Create creates a bundle of the given products
+func (s *Service) Create(ctx context.Context, b *Bundle, productIDs []int64) (*Bundle, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	if len(productIDs) == 0 {
+		return nil, ErrNoProducts
+	}
+	b.CreatedAt = time.Now().UTC().Format(timeFormat)
+
+	var id int64
+	err := s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		if id, err = s.repo.Create(ctx, tx, b); err != nil {
+			return err
+		}
+		return s.repo.SetProducts(ctx, tx, id, uniqueIDs(productIDs))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, id)
+}
+
+// Update renames a bundle and sets its products. The products cannot change
+// while the bundle is licensed to any customer, since their licenses and
+// bundle keys were issued for the old set.
+func (s *Service) Update(ctx context.Context, b *Bundle, productIDs []int64) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	if len(productIDs) == 0 {
+		return ErrNoProducts
+	}
+
+	cur, err := s.Get(ctx, b.BundleID)
+	if err != nil {
+		return err
+	}
+	productIDs = uniqueIDs(productIDs)
+	changed := !sameIDs(cur.ProductIDs(), productIDs)
+	if changed {
+		n, err := s.repo.CountCustomers(ctx, b.BundleID)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("%w (%d customers)", ErrBundleLicensed, n)
+		}
+	}
+
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.repo.Update(ctx, tx, b); err != nil {
+			return err
+		}
+		if changed {
+			return s.repo.SetProducts(ctx, tx, b.BundleID, productIDs)
+		}
+		return nil
+	})
+}
+
+// Delete deletes a bundle. Customers keep the licenses issued for it as
+// separate licenses, but its bundle keys stop working.
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.repo.Delete(ctx, tx, id)
+	})
+}
+
+// -------------------------
+// Customer bundles
+// -------------------------
+
+// GetForCustomer returns the bundles licensed to a customer with their licenses
+func (s *Service) GetForCustomer(ctx context.Context, customerID int64) ([]CustomerBundle, error) {
+	out, err := s.repo.GetForCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		if out[i].Licenses, err = s.licenseSvc.GetForBundle(ctx, customerID, out[i].BundleID); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// GetCustomerBundle returns a bundle licensed to a customer with its licenses
+func (s *Service) GetCustomerBundle(ctx context.Context, customerID, bundleID int64) (*CustomerBundle, error) {
+	cb, err := s.repo.GetCustomerBundle(ctx, customerID, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	if cb.Licenses, err = s.licenseSvc.GetForBundle(ctx, customerID, bundleID); err != nil {
+		return nil, err
+	}
+	return cb, nil
+}
+
+// License licenses a bundle to a customer: it creates a license for each of
+// the bundle's products with the seats and dates of terms, and a bundle
+// license key. Each license also gets its own key for that product alone.
+// Returns ErrAlreadyLicensed if the customer already holds the bundle; licenses
+// of the same products bought separately are unaffected.
+func (s *Service) License(ctx context.Context, customerID, bundleID int64, terms *license.License) (*CustomerBundle, error) {
+	if err := terms.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, err := s.Get(ctx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.Products) == 0 {
+		return nil, ErrNoProducts
+	}
+	if _, err := s.repo.GetCustomerBundle(ctx, customerID, bundleID); err == nil {
+		return nil, fmt.Errorf("%w (%s)", ErrAlreadyLicensed, b.BundleName)
+	} else if !strings.Contains(err.Error(), "not found") {
+		return nil, err
+	}
+
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		for _, p := range b.Products {
+			lic := *terms
+			lic.CustomerID = customerID
+			lic.ProductID = p.ProductID
+			lic.LicenseKey = uuid.New().String()
+			lic.BundleID = &bundleID
+			if err := s.licenseSvc.CreateForBundle(ctx, tx, &lic); err != nil {
+				return err
+			}
+		}
+
+		return s.repo.CreateCustomerBundle(ctx, tx, &CustomerBundle{
+			CustomerID: customerID,
+			BundleID:   bundleID,
+			LicenseKey: uuid.New().String(),
+			CreatedAt:  time.Now().UTC().Format(timeFormat),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCustomerBundle(ctx, customerID, bundleID)
+}
+
+// UpdateLicense renews a customer's bundle: the seats and dates of terms
+// apply to every license of the bundle
+func (s *Service) UpdateLicense(ctx context.Context, customerID, bundleID int64, terms *license.License) error {
+	if _, err := s.repo.GetCustomerBundle(ctx, customerID, bundleID); err != nil {
+		return err
+	}
+	lic := *terms
+	lic.CustomerID = customerID
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.licenseSvc.UpdateBundleTerms(ctx, tx, bundleID, &lic)
+	})
+}
+
+// Unlicense removes a bundle from a customer along with its licenses
+func (s *Service) Unlicense(ctx context.Context, customerID, bundleID int64) error {
+	if _, err := s.repo.GetCustomerBundle(ctx, customerID, bundleID); err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.licenseSvc.DeleteForBundle(ctx, tx, customerID, bundleID); err != nil {
+			return err
+		}
+		return s.repo.DeleteCustomerBundle(ctx, tx, customerID, bundleID)
+	})
+}
+
+// ResolveKey returns the customer bundle for a bundle license key and the
+// products it activates: those the customer still holds a bundle license for.
+// Replaced keys are accepted until their grace period ends. Returns
+// license.ErrKeySuspended or license.ErrKeyRevoked for bundles whose key is not
+// active, and license.ErrKeyReplaced for replaced keys past their grace
+// period. The status of each product's license is checked when it is activated.
+func (s *Service) ResolveKey(ctx context.Context, licenseKey string) (*KeyRef, error) {
+	var cb *CustomerBundle
+
+	hist, err := s.repo.GetKeyHistoryByKey(ctx, licenseKey)
+	if err != nil {
+		return nil, err
+	}
+	if hist != nil {
+		if !hist.InGrace(time.Now()) {
+			return nil, fmt.Errorf("%w (%s)", license.ErrKeyReplaced, hist.ReplacedAt)
+		}
+		cb, err = s.repo.GetCustomerBundle(ctx, hist.CustomerID, hist.BundleID)
+	} else {
+		cb, err = s.repo.GetByLicenseKey(ctx, licenseKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch cb.KeyStatus {
+	case license.KeySuspended:
+		return nil, license.ErrKeySuspended
+	case license.KeyRevoked:
+		return nil, license.ErrKeyRevoked
+	}
+
+	lics, err := s.licenseSvc.GetForBundle(ctx, cb.CustomerID, cb.BundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := &KeyRef{CustomerID: cb.CustomerID, BundleID: cb.BundleID}
+	for _, l := range lics {
+		ref.ProductIDs = append(ref.ProductIDs, l.ProductID)
+		ref.LicenseIDs = append(ref.LicenseIDs, l.LicenseID)
+	}
+	return ref, nil
+}
+
+// RotateKey issues a new bundle license key. The old key keeps working for the
+// grace period (0 ends it immediately). The keys of the bundle's licenses are
+// unchanged.
+func (s *Service) RotateKey(ctx context.Context, customerID, bundleID int64, grace time.Duration, reason string) (*CustomerBundle, error) {
+	if grace < 0 {
+		return nil, license.ErrNegativeGracePeriod
+	}
+	cb, err := s.repo.GetCustomerBundle(ctx, customerID, bundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		hist := &KeyHistory{
+			CustomerID: customerID,
+			BundleID:   bundleID,
+			LicenseKey: cb.LicenseKey,
+			Event:      license.KeyEventReplaced,
+			Reason:     reason,
+			ReplacedAt: now.Format(timeFormat),
+			GraceUntil: now.Add(grace).Format(timeFormat),
+		}
+		if err := s.repo.CreateKeyHistory(ctx, tx, hist); err != nil {
+			return err
+		}
+		return s.repo.UpdateKey(ctx, tx, customerID, bundleID, uuid.New().String())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCustomerBundle(ctx, customerID, bundleID)
+}
+
+// SetKeyStatus suspends, revokes or reactivates a bundle license key,
+// recording the change and its reason in the key history. Revoking also ends
+// the grace period of any replaced keys. The keys of the bundle's licenses are
+// unchanged.
+func (s *Service) SetKeyStatus(ctx context.Context, customerID, bundleID int64, status, reason string) error {
+	if !license.IsValidKeyStatus(status) {
+		return license.ErrInvalidKeyStatus
+	}
+	cb, err := s.repo.GetCustomerBundle(ctx, customerID, bundleID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(timeFormat)
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.repo.UpdateKeyStatus(ctx, tx, customerID, bundleID, status); err != nil {
+			return err
+		}
+		hist := &KeyHistory{
+			CustomerID: customerID,
+			BundleID:   bundleID,
+			LicenseKey: cb.LicenseKey,
+			Event:      status,
+			Reason:     reason,
+			ReplacedAt: now,
+		}
+		if err := s.repo.CreateKeyHistory(ctx, tx, hist); err != nil {
+			return err
+		}
+		if status == license.KeyRevoked {
+			return s.repo.RevokeKeyHistory(ctx, tx, customerID, bundleID, now)
+		}
+		return nil
+	})
+}
+
+// GetKeyHistory lists the changes to a customer's bundle license key, newest first
+func (s *Service) GetKeyHistory(ctx context.Context, customerID, bundleID int64) ([]KeyHistory, error) {
+	if _, err := s.repo.GetCustomerBundle(ctx, customerID, bundleID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetKeyHistory(ctx, customerID, bundleID)
+}
+
+// uniqueIDs returns ids sorted with duplicates removed
+func uniqueIDs(ids []int64) []int64 {
+	out := slices.Clone(ids)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// sameIDs reports whether two sets of IDs are equal
+func sameIDs(a, b []int64) bool {
+	return slices.Equal(uniqueIDs(a), uniqueIDs(b))
+}
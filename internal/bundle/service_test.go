@@ -0,0 +1,264 @@
+package bundle_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"winsbygroup.com/regserver/internal/bundle"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestBundleService(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	licSvc := license.NewService(db)
+	svc := bundle.NewService(db, licSvc)
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Suite Customer"})
+	editor, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Editor", ProductGUID: "GUID-EDITOR"})
+	viewer, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Viewer", ProductGUID: "GUID-VIEWER"})
+	other, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Other", ProductGUID: "GUID-OTHER"})
+
+	terms := func(count int, expires string) *license.License {
+		return &license.License{
+			LicenseCount:        count,
+			StartDate:           "2025-01-01",
+			ExpirationDate:      expires,
+			MaintExpirationDate: expires,
+		}
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		if _, err := svc.Create(ctx, &bundle.Bundle{}, []int64{editor.ProductID}); !errors.Is(err, bundle.ErrNameRequired) {
+			t.Errorf("expected ErrNameRequired, got %v", err)
+		}
+		if _, err := svc.Create(ctx, &bundle.Bundle{BundleName: "Empty"}, nil); !errors.Is(err, bundle.ErrNoProducts) {
+			t.Errorf("expected ErrNoProducts, got %v", err)
+		}
+	})
+
+	b, err := svc.Create(ctx, &bundle.Bundle{BundleName: "Suite"}, []int64{viewer.ProductID, editor.ProductID, editor.ProductID})
+	if err != nil {
+		t.Fatalf("create bundle: %v", err)
+	}
+
+	t.Run("create", func(t *testing.T) {
+		if len(b.Products) != 2 || b.Products[0].ProductName != "Editor" || b.Products[1].ProductName != "Viewer" {
+			t.Errorf("unexpected bundle products: %+v", b.Products)
+		}
+	})
+
+	var cb *bundle.CustomerBundle
+
+	t.Run("license a bundle", func(t *testing.T) {
+		cb, err = svc.License(ctx, cust.CustomerID, b.BundleID, terms(5, "2025-12-31"))
+		if err != nil {
+			t.Fatalf("license bundle: %v", err)
+		}
+		if cb.LicenseKey == "" {
+			t.Error("expected a bundle license key")
+		}
+		if len(cb.Licenses) != 2 {
+			t.Fatalf("expected 2 licenses, got %d", len(cb.Licenses))
+		}
+		for _, l := range cb.Licenses {
+			if l.BundleID == nil || *l.BundleID != b.BundleID {
+				t.Errorf("expected license for product %d to belong to the bundle", l.ProductID)
+			}
+			if l.LicenseKey == cb.LicenseKey {
+				t.Error("expected each license to keep its own key")
+			}
+			if l.LicenseCount != 5 || l.ExpirationDate != "2025-12-31" {
+				t.Errorf("unexpected license terms: %+v", l)
+			}
+		}
+	})
+
+	t.Run("cannot license a bundle twice", func(t *testing.T) {
+		if _, err := svc.License(ctx, cust.CustomerID, b.BundleID, terms(5, "2025-12-31")); !errors.Is(err, bundle.ErrAlreadyLicensed) {
+			t.Errorf("expected ErrAlreadyLicensed, got %v", err)
+		}
+	})
+
+	t.Run("resolve key", func(t *testing.T) {
+		ref, err := svc.ResolveKey(ctx, strings.ToUpper(cb.LicenseKey))
+		if err != nil {
+			t.Fatalf("resolve key: %v", err)
+		}
+		if ref.CustomerID != cust.CustomerID || ref.BundleID != b.BundleID || len(ref.ProductIDs) != 2 || len(ref.LicenseIDs) != 2 {
+			t.Errorf("unexpected key ref: %+v", ref)
+		}
+		if _, err := svc.ResolveKey(ctx, "unknown-key"); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("expected not found, got %v", err)
+		}
+	})
+
+	t.Run("rotate, suspend and revoke the key", func(t *testing.T) {
+		oldKey := cb.LicenseKey
+		rotated, err := svc.RotateKey(ctx, cust.CustomerID, b.BundleID, time.Hour, "key leaked")
+		if err != nil {
+			t.Fatalf("RotateKey: %v", err)
+		}
+		if rotated.LicenseKey == oldKey || rotated.KeyStatus != license.KeyActive {
+			t.Fatalf("expected a new active key, got %+v", rotated)
+		}
+		if ref, err := svc.ResolveKey(ctx, oldKey); err != nil || ref.BundleID != b.BundleID {
+			t.Errorf("expected the old key to work during its grace period, got %+v, %v", ref, err)
+		}
+		for _, l := range rotated.Licenses {
+			if _, err := licSvc.GetByActiveKey(ctx, l.LicenseKey); err != nil {
+				t.Errorf("expected the product license keys to be unchanged, got %v", err)
+			}
+		}
+
+		if err := svc.SetKeyStatus(ctx, cust.CustomerID, b.BundleID, license.KeySuspended, "chargeback"); err != nil {
+			t.Fatalf("SetKeyStatus: %v", err)
+		}
+		for _, key := range []string{rotated.LicenseKey, oldKey} {
+			if _, err := svc.ResolveKey(ctx, key); !errors.Is(err, license.ErrKeySuspended) {
+				t.Errorf("expected ErrKeySuspended, got %v", err)
+			}
+		}
+
+		if err := svc.SetKeyStatus(ctx, cust.CustomerID, b.BundleID, license.KeyRevoked, "fraud"); err != nil {
+			t.Fatalf("SetKeyStatus: %v", err)
+		}
+		if _, err := svc.ResolveKey(ctx, rotated.LicenseKey); !errors.Is(err, license.ErrKeyRevoked) {
+			t.Errorf("expected ErrKeyRevoked, got %v", err)
+		}
+
+		// Reactivating restores the current key but not the revoked grace key
+		if err := svc.SetKeyStatus(ctx, cust.CustomerID, b.BundleID, license.KeyActive, "resolved"); err != nil {
+			t.Fatalf("SetKeyStatus: %v", err)
+		}
+		if _, err := svc.ResolveKey(ctx, rotated.LicenseKey); err != nil {
+			t.Errorf("expected the reactivated key to work, got %v", err)
+		}
+		if _, err := svc.ResolveKey(ctx, oldKey); !errors.Is(err, license.ErrKeyReplaced) {
+			t.Errorf("expected ErrKeyReplaced for the revoked grace key, got %v", err)
+		}
+
+		hist, err := svc.GetKeyHistory(ctx, cust.CustomerID, b.BundleID)
+		if err != nil {
+			t.Fatalf("GetKeyHistory: %v", err)
+		}
+		var events []string
+		for _, h := range hist {
+			events = append(events, h.Event+":"+h.Reason)
+		}
+		if got := strings.Join(events, ","); got != "active:resolved,revoked:fraud,suspended:chargeback,replaced:key leaked" {
+			t.Errorf("unexpected key history %s", got)
+		}
+
+		if err := svc.SetKeyStatus(ctx, cust.CustomerID, b.BundleID, "lost", ""); !errors.Is(err, license.ErrInvalidKeyStatus) {
+			t.Errorf("expected ErrInvalidKeyStatus, got %v", err)
+		}
+		if _, err := svc.RotateKey(ctx, cust.CustomerID, b.BundleID, -time.Hour, ""); !errors.Is(err, license.ErrNegativeGracePeriod) {
+			t.Errorf("expected ErrNegativeGracePeriod, got %v", err)
+		}
+		cb = rotated
+	})
+
+	t.Run("renewing one license renews the bundle", func(t *testing.T) {
+		licenseFor := func(productID int64) int64 {
+			for _, l := range cb.Licenses {
+				if l.ProductID == productID {
+					return l.LicenseID
+				}
+			}
+			return 0
+		}
+
+		lic := terms(8, "2026-12-31")
+		lic.LicenseID = licenseFor(editor.ProductID)
+		lic.MaxProductVersion = "2.0.0"
+		if err := licSvc.Update(ctx, lic); err != nil {
+			t.Fatalf("update license: %v", err)
+		}
+
+		v, err := licSvc.Get(ctx, licenseFor(viewer.ProductID))
+		if err != nil {
+			t.Fatalf("get license: %v", err)
+		}
+		if v.LicenseCount != 8 || v.ExpirationDate != "2026-12-31" || v.MaintExpirationDate != "2026-12-31" {
+			t.Errorf("expected the viewer license to be renewed, got %+v", v)
+		}
+		if v.MaxProductVersion != "" {
+			t.Errorf("expected the max version to stay per product, got %q", v.MaxProductVersion)
+		}
+	})
+
+	t.Run("update bundle license", func(t *testing.T) {
+		if err := svc.UpdateLicense(ctx, cust.CustomerID, b.BundleID, terms(10, "2027-06-30")); err != nil {
+			t.Fatalf("update bundle license: %v", err)
+		}
+		got, err := svc.GetCustomerBundle(ctx, cust.CustomerID, b.BundleID)
+		if err != nil {
+			t.Fatalf("get customer bundle: %v", err)
+		}
+		for _, l := range got.Licenses {
+			if l.LicenseCount != 10 || l.ExpirationDate != "2027-06-30" {
+				t.Errorf("unexpected license terms: %+v", l)
+			}
+		}
+	})
+
+	t.Run("products are fixed while licensed", func(t *testing.T) {
+		b.BundleName = "Suite Pro"
+		err := svc.Update(ctx, b, []int64{editor.ProductID, viewer.ProductID, other.ProductID})
+		if !errors.Is(err, bundle.ErrBundleLicensed) {
+			t.Errorf("expected ErrBundleLicensed, got %v", err)
+		}
+		if err := svc.Update(ctx, b, []int64{viewer.ProductID, editor.ProductID}); err != nil {
+			t.Errorf("expected rename with the same products to work: %v", err)
+		}
+	})
+
+	t.Run("unlicense removes the bundle licenses", func(t *testing.T) {
+		if err := svc.Unlicense(ctx, cust.CustomerID, b.BundleID); err != nil {
+			t.Fatalf("unlicense: %v", err)
+		}
+		lics, err := licSvc.GetForCustomer(ctx, cust.CustomerID)
+		if err != nil {
+			t.Fatalf("get licenses: %v", err)
+		}
+		if len(lics) != 0 {
+			t.Errorf("expected no licenses, got %d", len(lics))
+		}
+		if _, err := svc.ResolveKey(ctx, cb.LicenseKey); err == nil {
+			t.Error("expected the bundle key to stop working")
+		}
+	})
+
+	t.Run("deleting a bundle keeps its licenses", func(t *testing.T) {
+		if _, err := svc.License(ctx, cust.CustomerID, b.BundleID, terms(5, "2025-12-31")); err != nil {
+			t.Fatalf("license bundle: %v", err)
+		}
+		if err := svc.Delete(ctx, b.BundleID); err != nil {
+			t.Fatalf("delete bundle: %v", err)
+		}
+		lics, err := licSvc.GetForCustomer(ctx, cust.CustomerID)
+		if err != nil {
+			t.Fatalf("get licenses: %v", err)
+		}
+		if len(lics) != 2 {
+			t.Fatalf("expected 2 licenses, got %d", len(lics))
+		}
+		for _, l := range lics {
+			if l.BundleID != nil {
+				t.Errorf("expected a separate license, got bundle %d", *l.BundleID)
+			}
+		}
+	})
+}
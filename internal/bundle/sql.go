@@ -0,0 +1,149 @@
+package bundle
+
+const getAllBundlesSQL = `
+SELECT bundle_id, bundle_name, description, created_at
+FROM bundle
+ORDER BY bundle_name
+`
+
+const getBundleSQL = `
+SELECT bundle_id, bundle_name, description, created_at
+FROM bundle
+WHERE bundle_id = ?
+`
+
+const createBundleSQL = `
+INSERT INTO bundle (bundle_name, description, created_at)
+VALUES (?, ?, ?)
+`
+
+const updateBundleSQL = `
+UPDATE bundle
+SET bundle_name = ?, description = ?
+WHERE bundle_id = ?
+`
+
+const deleteBundleSQL = `
+DELETE FROM bundle
+WHERE bundle_id = ?
+`
+
+const getBundleProductsSQL = `
+SELECT p.product_id, p.product_name, p.product_guid
+FROM bundle_product bp
+JOIN product p ON p.product_id = bp.product_id
+WHERE bp.bundle_id = ?
+ORDER BY p.product_name
+`
+
+const addBundleProductSQL = `
+INSERT INTO bundle_product (bundle_id, product_id)
+VALUES (?, ?)
+`
+
+const clearBundleProductsSQL = `
+DELETE FROM bundle_product
+WHERE bundle_id = ?
+`
+
+const countCustomerBundlesSQL = `
+SELECT COUNT(*)
+FROM customer_bundle
+WHERE bundle_id = ?
+`
+
+const customerBundleColumns = `
+    cb.customer_id,
+    cb.bundle_id,
+    b.bundle_name,
+    cb.license_key,
+    cb.key_status,
+    cb.created_at
+`
+
+const getCustomerBundlesSQL = `
+SELECT` + customerBundleColumns + `
+FROM customer_bundle cb
+JOIN bundle b ON b.bundle_id = cb.bundle_id
+WHERE cb.customer_id = ?
+ORDER BY b.bundle_name
+`
+
+const getCustomerBundleSQL = `
+SELECT` + customerBundleColumns + `
+FROM customer_bundle cb
+JOIN bundle b ON b.bundle_id = cb.bundle_id
+WHERE cb.customer_id = ? AND cb.bundle_id = ?
+`
+
+const getCustomerBundleByKeySQL = `
+SELECT` + customerBundleColumns + `
+FROM customer_bundle cb
+JOIN bundle b ON b.bundle_id = cb.bundle_id
+WHERE cb.license_key = ?
+`
+
+const createCustomerBundleSQL = `
+INSERT INTO customer_bundle (customer_id, bundle_id, license_key, created_at)
+VALUES (?, ?, ?, ?)
+`
+
+const updateCustomerBundleKeySQL = `
+UPDATE customer_bundle
+SET license_key = ?
+WHERE customer_id = ? AND bundle_id = ?
+`
+
+const updateCustomerBundleKeyStatusSQL = `
+UPDATE customer_bundle
+SET key_status = ?
+WHERE customer_id = ? AND bundle_id = ?
+`
+
+const createKeyHistorySQL = `
+INSERT INTO bundle_key_history (
+    customer_id,
+    bundle_id,
+    license_key,
+    event,
+    reason,
+    replaced_at,
+    grace_until
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+const revokeKeyHistorySQL = `
+UPDATE bundle_key_history
+SET revoked_at = ?
+WHERE customer_id = ? AND bundle_id = ? AND event = 'replaced' AND revoked_at = '' AND grace_until > ?
+`
+
+const keyHistoryColumns = `
+    history_id,
+    customer_id,
+    bundle_id,
+    license_key,
+    event,
+    reason,
+    replaced_at,
+    grace_until,
+    revoked_at
+`
+
+const getKeyHistorySQL = `
+SELECT` + keyHistoryColumns + `
+FROM bundle_key_history
+WHERE customer_id = ? AND bundle_id = ?
+ORDER BY history_id DESC
+`
+
+const getKeyHistoryByKeySQL = `
+SELECT` + keyHistoryColumns + `
+FROM bundle_key_history
+WHERE license_key = ? AND event = 'replaced'
+`
+
+const deleteCustomerBundleSQL = `
+DELETE FROM customer_bundle
+WHERE customer_id = ? AND bundle_id = ?
+`
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -16,21 +18,84 @@ type Config struct {
 	ReadTimeout        time.Duration `yaml:"read_timeout"`
 	WriteTimeout       time.Duration `yaml:"write_timeout"`
 	IdleTimeout        time.Duration `yaml:"idle_timeout"`
+	MetricsToken       string        `yaml:"metrics_token"` // bearer token for /metrics (empty = unprotected)
+	LogFormat          string        `yaml:"log_format"`    // "json" or "text"
+	LogLevel           string        `yaml:"log_level"`     // "debug", "info", "warn" or "error"
+	RateLimit          RateLimit     `yaml:"rate_limit"`
+	TrustedProxies     []string      `yaml:"trusted_proxies"`   // proxy IPs/CIDRs whose X-Forwarded-For is trusted (empty = use the connection address)
+	FingerprintMatch   int           `yaml:"fingerprint_match"` // matching machine code components that identify a known machine (0 = exact only)
+	Portal             bool          `yaml:"portal"`            // serve the customer portal at /portal (requires PublicURL)
+	PublicURL          string        `yaml:"public_url"`        // base URL for links in emails, e.g. https://license.example.com
+	SwaggerUI          bool          `yaml:"swagger_ui"`        // serve Swagger UI for the OpenAPI spec at /api/docs
+	SMTP               SMTP          `yaml:"smtp"`
+	Inactivity         Inactivity    `yaml:"inactivity"`
 
 	DBPathSource string // where DBPath was set from: "default", "yaml file", or "env var"
 	DemoMode     bool   // load sample data on new database (set via -demo flag)
 }
 
+// RateLimit configures throttling of the client API and web login. Requests are
+// limited by token buckets per client IP and per license key, and repeated
+// authentication failures lock the client out for progressively longer.
+type RateLimit struct {
+	Enabled          bool          `yaml:"enabled"`
+	IPRate           float64       `yaml:"ip_rate"`           // sustained requests per second per client IP
+	IPBurst          int           `yaml:"ip_burst"`          // requests allowed at once per client IP
+	KeyRate          float64       `yaml:"key_rate"`          // sustained requests per second per license key
+	KeyBurst         int           `yaml:"key_burst"`         // requests allowed at once per license key
+	LockoutThreshold int           `yaml:"lockout_threshold"` // failures (per IP and key, or distinct keys per IP) before the first lockout
+	LockoutDuration  time.Duration `yaml:"lockout_duration"`  // first lockout; doubles with each further failure
+	LockoutMax       time.Duration `yaml:"lockout_max"`       // longest lockout
+}
+
+// SMTP configures outgoing email (customer portal sign-in links). When no host
+// is set, messages are written to the log instead.
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// Inactivity schedules the inactivity policies that flag registrations idle
+// and release their seats. The policies themselves are set per product and
+// license.
+type Inactivity struct {
+	CheckInterval time.Duration `yaml:"check_interval"` // how often the policies run (0 = never)
+	NoticeDays    int           `yaml:"notice_days"`    // days a registration stays flagged idle before its seat is released
+}
+
 // Load loads configuration from YAML file and overrides with env vars if present
 func Load(path string) (*Config, error) {
 	// Defaults
 	cfg := &Config{
-		Addr:         ":8080",
-		DBPath:       "./registrations.db",
-		DBPathSource: "default",
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		Addr:             ":8080",
+		DBPath:           "./registrations.db",
+		DBPathSource:     "default",
+		ReadTimeout:      5 * time.Second,
+		WriteTimeout:     10 * time.Second,
+		IdleTimeout:      120 * time.Second,
+		LogFormat:        "text",
+		LogLevel:         "info",
+		FingerprintMatch: 2,
+		RateLimit: RateLimit{
+			Enabled:          true,
+			IPRate:           2,
+			IPBurst:          20,
+			KeyRate:          0.5,
+			KeyBurst:         10,
+			LockoutThreshold: 5,
+			LockoutDuration:  time.Minute,
+			LockoutMax:       time.Hour,
+		},
+		SMTP: SMTP{
+			Port: 587,
+		},
+		Inactivity: Inactivity{
+			CheckInterval: 24 * time.Hour,
+			NoticeDays:    7,
+		},
 	}
 
 	// Load from YAML if file exists
@@ -60,6 +125,48 @@ func Load(path string) (*Config, error) {
 	if v := os.Getenv("REGISTRATION_SECRET"); v != "" {
 		cfg.RegistrationSecret = v
 	}
+	if v := os.Getenv("METRICS_TOKEN"); v != "" {
+		cfg.MetricsToken = v
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		cfg.LogFormat = v
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		cfg.LogLevel = v
+	}
+	if v := os.Getenv("RATE_LIMIT_ENABLED"); v != "" {
+		cfg.RateLimit.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		cfg.TrustedProxies = strings.Split(v, ",")
+	}
+	if v := os.Getenv("PORTAL"); v != "" {
+		cfg.Portal = v == "true" || v == "1"
+	}
+	if v := os.Getenv("PUBLIC_URL"); v != "" {
+		cfg.PublicURL = v
+	}
+	if v := os.Getenv("SWAGGER_UI"); v != "" {
+		cfg.SwaggerUI = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SMTP_PASSWORD"); v != "" {
+		cfg.SMTP.Password = v
+	}
+	if v := os.Getenv("FINGERPRINT_MATCH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.FingerprintMatch = n
+		}
+	}
+	if v := os.Getenv("INACTIVITY_CHECK_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Inactivity.CheckInterval = d
+		}
+	}
+	if v := os.Getenv("INACTIVITY_NOTICE_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Inactivity.NoticeDays = n
+		}
+	}
 
 	return cfg, nil
 }
@@ -15,6 +15,18 @@ func TestLoad(t *testing.T) {
 		os.Unsetenv("DB_PATH")
 		os.Unsetenv("API_KEY")
 		os.Unsetenv("REGISTRATION_SECRET")
+		os.Unsetenv("METRICS_TOKEN")
+		os.Unsetenv("LOG_FORMAT")
+		os.Unsetenv("LOG_LEVEL")
+		os.Unsetenv("RATE_LIMIT_ENABLED")
+		os.Unsetenv("TRUSTED_PROXIES")
+		os.Unsetenv("FINGERPRINT_MATCH")
+		os.Unsetenv("PORTAL")
+		os.Unsetenv("PUBLIC_URL")
+		os.Unsetenv("SMTP_PASSWORD")
+		os.Unsetenv("SWAGGER_UI")
+		os.Unsetenv("INACTIVITY_CHECK_INTERVAL")
+		os.Unsetenv("INACTIVITY_NOTICE_DAYS")
 	}
 
 	t.Run("returns defaults when config file does not exist", func(t *testing.T) {
@@ -183,6 +195,290 @@ registration_secret: "yaml-secret"
 		}
 	})
 
+	t.Run("metrics token from YAML and env", func(t *testing.T) {
+		clearEnvVars()
+
+		tmpDir := t.TempDir()
+		cfgPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(cfgPath, []byte(`metrics_token: "yaml-token"`), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.MetricsToken != "yaml-token" {
+			t.Errorf("expected MetricsToken 'yaml-token', got %q", cfg.MetricsToken)
+		}
+
+		os.Setenv("METRICS_TOKEN", "env-token")
+		defer clearEnvVars()
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.MetricsToken != "env-token" {
+			t.Errorf("expected MetricsToken 'env-token', got %q", cfg.MetricsToken)
+		}
+	})
+
+	t.Run("log options default, from YAML and env", func(t *testing.T) {
+		clearEnvVars()
+
+		cfg, err := config.Load("nonexistent.yaml")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.LogFormat != "text" || cfg.LogLevel != "info" {
+			t.Errorf("expected text/info defaults, got %q/%q", cfg.LogFormat, cfg.LogLevel)
+		}
+
+		tmpDir := t.TempDir()
+		cfgPath := filepath.Join(tmpDir, "config.yaml")
+		yamlContent := "log_format: json\nlog_level: debug\n"
+		if err := os.WriteFile(cfgPath, []byte(yamlContent), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.LogFormat != "json" || cfg.LogLevel != "debug" {
+			t.Errorf("expected json/debug from YAML, got %q/%q", cfg.LogFormat, cfg.LogLevel)
+		}
+
+		os.Setenv("LOG_LEVEL", "warn")
+		defer clearEnvVars()
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.LogFormat != "json" || cfg.LogLevel != "warn" {
+			t.Errorf("expected json/warn with env override, got %q/%q", cfg.LogFormat, cfg.LogLevel)
+		}
+	})
+
+	t.Run("rate limit defaults and YAML overrides", func(t *testing.T) {
+		clearEnvVars()
+
+		cfg, err := config.Load("nonexistent.yaml")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !cfg.RateLimit.Enabled || cfg.RateLimit.LockoutThreshold != 5 || cfg.RateLimit.LockoutDuration != time.Minute {
+			t.Errorf("unexpected rate limit defaults: %+v", cfg.RateLimit)
+		}
+
+		tmpDir := t.TempDir()
+		cfgPath := filepath.Join(tmpDir, "config.yaml")
+		yamlContent := "rate_limit:\n  ip_burst: 50\n  lockout_max: 30m\n"
+		if err := os.WriteFile(cfgPath, []byte(yamlContent), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.RateLimit.IPBurst != 50 || cfg.RateLimit.LockoutMax != 30*time.Minute {
+			t.Errorf("expected YAML rate limit values, got %+v", cfg.RateLimit)
+		}
+		if !cfg.RateLimit.Enabled || cfg.RateLimit.KeyBurst != 10 {
+			t.Errorf("expected unset rate limit values to keep defaults, got %+v", cfg.RateLimit)
+		}
+
+		os.Setenv("RATE_LIMIT_ENABLED", "false")
+		defer clearEnvVars()
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.RateLimit.Enabled {
+			t.Error("expected RATE_LIMIT_ENABLED=false to disable rate limiting")
+		}
+	})
+
+	t.Run("swagger UI off by default, from YAML and env", func(t *testing.T) {
+		clearEnvVars()
+
+		cfg, err := config.Load("nonexistent.yaml")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.SwaggerUI {
+			t.Error("expected SwaggerUI off by default")
+		}
+
+		tmpDir := t.TempDir()
+		cfgPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(cfgPath, []byte("swagger_ui: true\n"), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !cfg.SwaggerUI {
+			t.Error("expected SwaggerUI on from YAML")
+		}
+
+		os.Setenv("SWAGGER_UI", "false")
+		defer clearEnvVars()
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.SwaggerUI {
+			t.Error("expected SWAGGER_UI=false to turn SwaggerUI off")
+		}
+	})
+
+	t.Run("fingerprint match default, from YAML and env", func(t *testing.T) {
+		clearEnvVars()
+
+		cfg, err := config.Load("nonexistent.yaml")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.FingerprintMatch != 2 {
+			t.Errorf("expected FingerprintMatch 2, got %d", cfg.FingerprintMatch)
+		}
+
+		tmpDir := t.TempDir()
+		cfgPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(cfgPath, []byte("fingerprint_match: 3\n"), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.FingerprintMatch != 3 {
+			t.Errorf("expected FingerprintMatch 3 from YAML, got %d", cfg.FingerprintMatch)
+		}
+
+		os.Setenv("FINGERPRINT_MATCH", "0")
+		defer clearEnvVars()
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.FingerprintMatch != 0 {
+			t.Errorf("expected FINGERPRINT_MATCH=0 to override YAML, got %d", cfg.FingerprintMatch)
+		}
+	})
+
+	t.Run("trusted proxies from YAML and env", func(t *testing.T) {
+		clearEnvVars()
+
+		tmpDir := t.TempDir()
+		cfgPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(cfgPath, []byte("trusted_proxies: [\"127.0.0.1\", \"::1\"]\n"), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(cfg.TrustedProxies) != 2 || cfg.TrustedProxies[1] != "::1" {
+			t.Errorf("expected TrustedProxies from YAML, got %q", cfg.TrustedProxies)
+		}
+
+		os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+		defer clearEnvVars()
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(cfg.TrustedProxies) != 1 || cfg.TrustedProxies[0] != "10.0.0.0/8" {
+			t.Errorf("expected TRUSTED_PROXIES to override YAML, got %q", cfg.TrustedProxies)
+		}
+	})
+
+	t.Run("portal, smtp and public url from YAML and env", func(t *testing.T) {
+		clearEnvVars()
+
+		tmpDir := t.TempDir()
+		cfgPath := filepath.Join(tmpDir, "config.yaml")
+		yamlContent := "portal: true\npublic_url: https://license.example.com\nsmtp:\n  host: mail.example.com\n  from: licenses@example.com\n  password: yaml-pass\n"
+		if err := os.WriteFile(cfgPath, []byte(yamlContent), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !cfg.Portal || cfg.PublicURL != "https://license.example.com" {
+			t.Errorf("expected Portal and PublicURL from YAML, got %v, %q", cfg.Portal, cfg.PublicURL)
+		}
+		if cfg.SMTP.Host != "mail.example.com" || cfg.SMTP.Port != 587 || cfg.SMTP.From != "licenses@example.com" {
+			t.Errorf("unexpected SMTP config: %+v", cfg.SMTP)
+		}
+
+		os.Setenv("SMTP_PASSWORD", "env-pass")
+		os.Setenv("PUBLIC_URL", "https://portal.example.com")
+		os.Setenv("PORTAL", "false")
+		defer clearEnvVars()
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.SMTP.Password != "env-pass" || cfg.PublicURL != "https://portal.example.com" || cfg.Portal {
+			t.Errorf("expected env overrides, got password %q, url %q, portal %v", cfg.SMTP.Password, cfg.PublicURL, cfg.Portal)
+		}
+	})
+
+	t.Run("inactivity default, from YAML and env", func(t *testing.T) {
+		clearEnvVars()
+
+		cfg, err := config.Load("nonexistent.yaml")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.Inactivity.CheckInterval != 24*time.Hour || cfg.Inactivity.NoticeDays != 7 {
+			t.Errorf("expected daily checks with 7 notice days, got %+v", cfg.Inactivity)
+		}
+
+		tmpDir := t.TempDir()
+		cfgPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(cfgPath, []byte("inactivity:\n  check_interval: 6h\n  notice_days: 14\n"), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.Inactivity.CheckInterval != 6*time.Hour || cfg.Inactivity.NoticeDays != 14 {
+			t.Errorf("expected inactivity settings from YAML, got %+v", cfg.Inactivity)
+		}
+
+		os.Setenv("INACTIVITY_CHECK_INTERVAL", "0s")
+		os.Setenv("INACTIVITY_NOTICE_DAYS", "3")
+		defer clearEnvVars()
+
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.Inactivity.CheckInterval != 0 || cfg.Inactivity.NoticeDays != 3 {
+			t.Errorf("expected env vars to override YAML, got %+v", cfg.Inactivity)
+		}
+	})
+
 	t.Run("returns error for invalid YAML", func(t *testing.T) {
 		clearEnvVars()
 
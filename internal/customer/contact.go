@@ -0,0 +1,128 @@
+package customer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Contact roles
+const (
+	RoleBilling    = "billing"
+	RoleTechnical  = "technical"
+	RolePurchasing = "purchasing"
+)
+
+// Roles lists the valid contact roles in display order
+var Roles = []string{RoleBilling, RoleTechnical, RolePurchasing}
+
+var (
+	// ErrInvalidRole is returned for a contact role that is not one of Roles
+	ErrInvalidRole = errors.New("invalid contact role")
+
+	// ErrContactRequired is returned for a contact without a name or email
+	ErrContactRequired = errors.New("contact name or email is required")
+)
+
+// Contact is a person at a customer. Roles decide which contact receives
+// which kind of notice; the primary contact is the fallback and is copied to
+// the customer's contact_name, phone and email columns.
+type Contact struct {
+	ContactID   int64  `db:"contact_id"`
+	CustomerID  int64  `db:"customer_id"`
+	ContactName string `db:"contact_name"`
+	Phone       string `db:"phone"`
+	Email       string `db:"email"`
+	Roles       string `db:"roles"` // comma-separated
+	IsPrimary   bool   `db:"is_primary"`
+	Notes       string `db:"notes"`
+}
+
+// RoleList returns the contact's roles
+func (c Contact) RoleList() []string {
+	if c.Roles == "" {
+		return nil
+	}
+	return strings.Split(c.Roles, ",")
+}
+
+// HasRole reports whether the contact has the role
+func (c Contact) HasRole(role string) bool {
+	for _, r := range c.RoleList() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the contact and normalizes its roles
+func (c *Contact) Validate() error {
+	if strings.TrimSpace(c.ContactName) == "" && strings.TrimSpace(c.Email) == "" {
+		return ErrContactRequired
+	}
+	roles, err := JoinRoles(strings.Split(c.Roles, ","))
+	if err != nil {
+		return err
+	}
+	c.Roles = roles
+	return nil
+}
+
+// JoinRoles validates roles and returns them comma-separated in Roles order,
+// without duplicates. Blank entries are ignored.
+func JoinRoles(roles []string) (string, error) {
+	seen := make(map[string]bool)
+	for _, r := range roles {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if !isValidRole(r) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidRole, r)
+		}
+		seen[r] = true
+	}
+
+	var out []string
+	for _, r := range Roles {
+		if seen[r] {
+			out = append(out, r)
+		}
+	}
+	return strings.Join(out, ","), nil
+}
+
+func isValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectContact picks the contact for a role: a contact with the role
+// (the primary one first), otherwise the primary contact, otherwise the first
+// contact. Returns nil when there are no contacts.
+func SelectContact(contacts []Contact, role string) *Contact {
+	var primary, withRole *Contact
+	for i := range contacts {
+		c := &contacts[i]
+		if c.HasRole(role) && (withRole == nil || c.IsPrimary) {
+			withRole = c
+		}
+		if c.IsPrimary {
+			primary = c
+		}
+	}
+	switch {
+	case withRole != nil:
+		return withRole
+	case primary != nil:
+		return primary
+	case len(contacts) > 0:
+		return &contacts[0]
+	}
+	return nil
+}
@@ -1,5 +1,10 @@
 package customer
 
+import "errors"
+
+// ErrNotFound is returned when a customer does not exist
+var ErrNotFound = errors.New("customer not found")
+
 type Customer struct {
 	CustomerID   int64  `db:"customer_id"`
 	CustomerName string `db:"customer_name"`
@@ -7,4 +12,5 @@ type Customer struct {
 	Phone        string `db:"phone"`
 	Email        string `db:"email"`
 	Notes        string `db:"notes"`
+	ResellerID   *int64 `db:"reseller_id"` // nil for direct customers
 }
@@ -11,11 +11,22 @@ import (
 
 type Repository interface {
 	GetAll(ctx context.Context) ([]Customer, error)
+	GetForReseller(ctx context.Context, resellerID int64) ([]Customer, error)
 	Get(ctx context.Context, id int64) (*Customer, error)
 	Create(ctx context.Context, tx *sqlx.Tx, c *Customer) (int64, error)
 	Update(ctx context.Context, tx *sqlx.Tx, c *Customer) error
 	Delete(ctx context.Context, tx *sqlx.Tx, id int64) error
 	Exists(ctx context.Context, id int64) (bool, error)
+	SetReseller(ctx context.Context, tx *sqlx.Tx, id int64, resellerID *int64) error
+
+	GetContacts(ctx context.Context, customerID int64) ([]Contact, error)
+	GetContact(ctx context.Context, contactID int64) (*Contact, error)
+	CreateContact(ctx context.Context, tx *sqlx.Tx, c *Contact) (int64, error)
+	UpdateContact(ctx context.Context, tx *sqlx.Tx, c *Contact) error
+	DeleteContact(ctx context.Context, tx *sqlx.Tx, contactID int64) error
+	ClearPrimary(ctx context.Context, tx *sqlx.Tx, customerID, keepContactID int64) error
+	UpdatePrimary(ctx context.Context, tx *sqlx.Tx, c *Customer) (bool, error)
+	SyncPrimary(ctx context.Context, tx *sqlx.Tx, customerID int64) error
 }
 
 type repo struct {
@@ -35,11 +46,20 @@ func (r *repo) GetAll(ctx context.Context) ([]Customer, error) {
 	return out, nil
 }
 
+func (r *repo) GetForReseller(ctx context.Context, resellerID int64) ([]Customer, error) {
+	var out []Customer
+	err := r.db.SelectContext(ctx, &out, getCustomersForResellerSQL, resellerID)
+	if err != nil {
+		return nil, fmt.Errorf("get reseller customers: %w", err)
+	}
+	return out, nil
+}
+
 func (r *repo) Get(ctx context.Context, id int64) (*Customer, error) {
 	var c Customer
 	err := r.db.GetContext(ctx, &c, getCustomerSQL, id)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("customer not found (%d)", id)
+		return nil, fmt.Errorf("%w (%d)", ErrNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get customer: %w", err)
@@ -54,6 +74,7 @@ func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, c *Customer) (int64, err
 		c.Phone,
 		c.Email,
 		c.Notes,
+		c.ResellerID,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("create customer: %w", err)
@@ -84,6 +105,14 @@ func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, id int64) error {
 	return nil
 }
 
+func (r *repo) SetReseller(ctx context.Context, tx *sqlx.Tx, id int64, resellerID *int64) error {
+	_, err := tx.ExecContext(ctx, setResellerSQL, resellerID, id)
+	if err != nil {
+		return fmt.Errorf("set customer reseller: %w", err)
+	}
+	return nil
+}
+
 func (r *repo) Exists(ctx context.Context, id int64) (bool, error) {
 	var exists bool
 	err := r.db.GetContext(ctx, &exists, customerExistsSQL, id)
@@ -92,3 +121,96 @@ func (r *repo) Exists(ctx context.Context, id int64) (bool, error) {
 	}
 	return exists, nil
 }
+
+func (r *repo) GetContacts(ctx context.Context, customerID int64) ([]Contact, error) {
+	var out []Contact
+	err := r.db.SelectContext(ctx, &out, getContactsSQL, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("get contacts: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetContact(ctx context.Context, contactID int64) (*Contact, error) {
+	var c Contact
+	err := r.db.GetContext(ctx, &c, getContactSQL, contactID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("contact not found (%d)", contactID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get contact: %w", err)
+	}
+	return &c, nil
+}
+
+func (r *repo) CreateContact(ctx context.Context, tx *sqlx.Tx, c *Contact) (int64, error) {
+	res, err := tx.ExecContext(ctx, createContactSQL,
+		c.CustomerID,
+		c.ContactName,
+		c.Phone,
+		c.Email,
+		c.Roles,
+		c.IsPrimary,
+		c.Notes,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create contact: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *repo) UpdateContact(ctx context.Context, tx *sqlx.Tx, c *Contact) error {
+	_, err := tx.ExecContext(ctx, updateContactSQL,
+		c.ContactName,
+		c.Phone,
+		c.Email,
+		c.Roles,
+		c.IsPrimary,
+		c.Notes,
+		c.ContactID,
+	)
+	if err != nil {
+		return fmt.Errorf("update contact: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) DeleteContact(ctx context.Context, tx *sqlx.Tx, contactID int64) error {
+	_, err := tx.ExecContext(ctx, deleteContactSQL, contactID)
+	if err != nil {
+		return fmt.Errorf("delete contact: %w", err)
+	}
+	return nil
+}
+
+// ClearPrimary unsets the primary flag on the customer's contacts other than keepContactID
+func (r *repo) ClearPrimary(ctx context.Context, tx *sqlx.Tx, customerID, keepContactID int64) error {
+	_, err := tx.ExecContext(ctx, clearPrimaryContactSQL, customerID, keepContactID)
+	if err != nil {
+		return fmt.Errorf("clear primary contact: %w", err)
+	}
+	return nil
+}
+
+// UpdatePrimary copies the customer's contact columns to its primary contact.
+// Returns false if the customer has no primary contact.
+func (r *repo) UpdatePrimary(ctx context.Context, tx *sqlx.Tx, c *Customer) (bool, error) {
+	res, err := tx.ExecContext(ctx, updatePrimaryContactSQL, c.ContactName, c.Phone, c.Email, c.CustomerID)
+	if err != nil {
+		return false, fmt.Errorf("update primary contact: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update primary contact: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SyncPrimary copies the primary contact to the customer's contact columns
+func (r *repo) SyncPrimary(ctx context.Context, tx *sqlx.Tx, customerID int64) error {
+	_, err := tx.ExecContext(ctx, syncPrimaryContactSQL, customerID)
+	if err != nil {
+		return fmt.Errorf("sync primary contact: %w", err)
+	}
+	return nil
+}
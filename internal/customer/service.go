@@ -2,6 +2,8 @@ package customer
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -34,6 +36,11 @@ func (s *Service) GetAll(ctx context.Context) ([]Customer, error) {
 	return s.repo.GetAll(ctx)
 }
 
+// GetForReseller returns the customers managed by a reseller
+func (s *Service) GetForReseller(ctx context.Context, resellerID int64) ([]Customer, error) {
+	return s.repo.GetForReseller(ctx, resellerID)
+}
+
 func (s *Service) Get(ctx context.Context, id int64) (*Customer, error) {
 	return s.repo.Get(ctx, id)
 }
@@ -42,8 +49,11 @@ func (s *Service) Create(ctx context.Context, c *Customer) (*Customer, error) {
 	var id int64
 	err := s.WithTx(ctx, func(tx *sqlx.Tx) error {
 		var err error
-		id, err = s.repo.Create(ctx, tx, c)
-		return err
+		if id, err = s.repo.Create(ctx, tx, c); err != nil {
+			return err
+		}
+		c.CustomerID = id
+		return s.savePrimaryContact(ctx, tx, c)
 	})
 	if err != nil {
 		return nil, err
@@ -59,7 +69,10 @@ func (s *Service) Create(ctx context.Context, c *Customer) (*Customer, error) {
 
 func (s *Service) Update(ctx context.Context, c *Customer) error {
 	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
-		return s.repo.Update(ctx, tx, c)
+		if err := s.repo.Update(ctx, tx, c); err != nil {
+			return err
+		}
+		return s.savePrimaryContact(ctx, tx, c)
 	})
 }
 
@@ -69,6 +82,137 @@ func (s *Service) Delete(ctx context.Context, id int64) error {
 	})
 }
 
+// SetReseller assigns a customer to a reseller, or makes it a direct customer when resellerID is nil
+func (s *Service) SetReseller(ctx context.Context, id int64, resellerID *int64) error {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.repo.SetReseller(ctx, tx, id, resellerID)
+	})
+}
+
 func (s *Service) Exists(ctx context.Context, id int64) (bool, error) {
 	return s.repo.Exists(ctx, id)
 }
+
+// --------------------------
+// Contacts
+// --------------------------
+
+func (s *Service) GetContacts(ctx context.Context, customerID int64) ([]Contact, error) {
+	return s.repo.GetContacts(ctx, customerID)
+}
+
+func (s *Service) GetContact(ctx context.Context, contactID int64) (*Contact, error) {
+	return s.repo.GetContact(ctx, contactID)
+}
+
+// ContactFor returns the contact that should receive notices for a role.
+// See SelectContact. Returns nil if the customer has no contacts.
+func (s *Service) ContactFor(ctx context.Context, customerID int64, role string) (*Contact, error) {
+	contacts, err := s.repo.GetContacts(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return SelectContact(contacts, role), nil
+}
+
+// CreateContact adds a contact to a customer. The customer's first contact
+// becomes its primary contact.
+func (s *Service) CreateContact(ctx context.Context, c *Contact) (*Contact, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	exists, err := s.repo.Exists(ctx, c.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w (%d)", ErrNotFound, c.CustomerID)
+	}
+	existing, err := s.repo.GetContacts(ctx, c.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		c.IsPrimary = true
+	}
+
+	var id int64
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if c.IsPrimary {
+			if err := s.repo.ClearPrimary(ctx, tx, c.CustomerID, 0); err != nil {
+				return err
+			}
+		}
+		var err error
+		if id, err = s.repo.CreateContact(ctx, tx, c); err != nil {
+			return err
+		}
+		return s.repo.SyncPrimary(ctx, tx, c.CustomerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetContact(ctx, id)
+}
+
+// UpdateContact updates a contact. The contact's customer cannot be changed.
+func (s *Service) UpdateContact(ctx context.Context, c *Contact) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetContact(ctx, c.ContactID)
+	if err != nil {
+		return err
+	}
+	c.CustomerID = existing.CustomerID
+
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if c.IsPrimary {
+			if err := s.repo.ClearPrimary(ctx, tx, c.CustomerID, c.ContactID); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.UpdateContact(ctx, tx, c); err != nil {
+			return err
+		}
+		return s.repo.SyncPrimary(ctx, tx, c.CustomerID)
+	})
+}
+
+func (s *Service) DeleteContact(ctx context.Context, contactID int64) error {
+	existing, err := s.repo.GetContact(ctx, contactID)
+	if err != nil {
+		return err
+	}
+
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.repo.DeleteContact(ctx, tx, contactID); err != nil {
+			return err
+		}
+		return s.repo.SyncPrimary(ctx, tx, existing.CustomerID)
+	})
+}
+
+// savePrimaryContact copies the customer's contact columns to its primary
+// contact, creating one with every role if there is none yet
+func (s *Service) savePrimaryContact(ctx context.Context, tx *sqlx.Tx, c *Customer) error {
+	found, err := s.repo.UpdatePrimary(ctx, tx, c)
+	if err != nil || found {
+		return err
+	}
+	if c.ContactName == "" && c.Phone == "" && c.Email == "" {
+		return nil
+	}
+	_, err = s.repo.CreateContact(ctx, tx, &Contact{
+		CustomerID:  c.CustomerID,
+		ContactName: c.ContactName,
+		Phone:       c.Phone,
+		Email:       c.Email,
+		Roles:       strings.Join(Roles, ","),
+		IsPrimary:   true,
+	})
+	return err
+}
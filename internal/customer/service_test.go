@@ -2,6 +2,8 @@ package customer_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -97,3 +99,176 @@ func TestCustomerDuplicateNameCaseInsensitive(t *testing.T) {
 		}
 	}
 }
+
+func TestCustomerContacts(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	svc := customer.NewService(db)
+
+	cust, err := svc.Create(ctx, &customer.Customer{
+		CustomerName: "Acme Corp",
+		ContactName:  "John Doe",
+		Email:        "john@acme.com",
+	})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+
+	t.Run("customer contact becomes primary contact", func(t *testing.T) {
+		contacts, err := svc.GetContacts(ctx, cust.CustomerID)
+		if err != nil {
+			t.Fatalf("get contacts: %v", err)
+		}
+		if len(contacts) != 1 {
+			t.Fatalf("expected 1 contact, got %d", len(contacts))
+		}
+		c := contacts[0]
+		if !c.IsPrimary || c.ContactName != "John Doe" || c.Email != "john@acme.com" {
+			t.Errorf("unexpected primary contact: %+v", c)
+		}
+		for _, role := range customer.Roles {
+			if !c.HasRole(role) {
+				t.Errorf("expected primary contact to have role %q", role)
+			}
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := svc.CreateContact(ctx, &customer.Contact{CustomerID: cust.CustomerID, Phone: "555"})
+		if !errors.Is(err, customer.ErrContactRequired) {
+			t.Errorf("expected ErrContactRequired, got %v", err)
+		}
+		_, err = svc.CreateContact(ctx, &customer.Contact{CustomerID: cust.CustomerID, ContactName: "X", Roles: "sales"})
+		if !errors.Is(err, customer.ErrInvalidRole) {
+			t.Errorf("expected ErrInvalidRole, got %v", err)
+		}
+		_, err = svc.CreateContact(ctx, &customer.Contact{CustomerID: 9999, ContactName: "X"})
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("expected customer not found, got %v", err)
+		}
+	})
+
+	var tech *customer.Contact
+	t.Run("roles are normalized", func(t *testing.T) {
+		tech, err = svc.CreateContact(ctx, &customer.Contact{
+			CustomerID:  cust.CustomerID,
+			ContactName: "Tina Tech",
+			Email:       "it@acme.com",
+			Roles:       " Technical,purchasing,technical",
+		})
+		if err != nil {
+			t.Fatalf("create contact: %v", err)
+		}
+		if tech.Roles != "technical,purchasing" {
+			t.Errorf("expected roles %q, got %q", "technical,purchasing", tech.Roles)
+		}
+		if tech.IsPrimary {
+			t.Error("expected second contact not to be primary")
+		}
+	})
+
+	t.Run("role selection", func(t *testing.T) {
+		got, err := svc.ContactFor(ctx, cust.CustomerID, customer.RoleTechnical)
+		if err != nil {
+			t.Fatalf("contact for: %v", err)
+		}
+		// The primary contact has every role, so it wins over the technical contact
+		if got.ContactName != "John Doe" {
+			t.Errorf("expected primary contact, got %q", got.ContactName)
+		}
+
+		contacts, _ := svc.GetContacts(ctx, cust.CustomerID)
+		primary := contacts[0]
+		primary.Roles = customer.RoleBilling
+		if err := svc.UpdateContact(ctx, &primary); err != nil {
+			t.Fatalf("update contact: %v", err)
+		}
+
+		got, _ = svc.ContactFor(ctx, cust.CustomerID, customer.RoleTechnical)
+		if got.ContactName != "Tina Tech" {
+			t.Errorf("expected technical contact, got %q", got.ContactName)
+		}
+	})
+
+	t.Run("single primary synced to customer", func(t *testing.T) {
+		tech.IsPrimary = true
+		if err := svc.UpdateContact(ctx, tech); err != nil {
+			t.Fatalf("update contact: %v", err)
+		}
+
+		contacts, _ := svc.GetContacts(ctx, cust.CustomerID)
+		primaries := 0
+		for _, c := range contacts {
+			if c.IsPrimary {
+				primaries++
+			}
+		}
+		if primaries != 1 {
+			t.Errorf("expected 1 primary contact, got %d", primaries)
+		}
+
+		got, _ := svc.Get(ctx, cust.CustomerID)
+		if got.ContactName != "Tina Tech" || got.Email != "it@acme.com" {
+			t.Errorf("expected customer contact columns from primary, got %q <%s>", got.ContactName, got.Email)
+		}
+	})
+
+	t.Run("customer update writes through to primary", func(t *testing.T) {
+		got, _ := svc.Get(ctx, cust.CustomerID)
+		got.Phone = "555-0100"
+		if err := svc.Update(ctx, got); err != nil {
+			t.Fatalf("update customer: %v", err)
+		}
+
+		c, _ := svc.GetContact(ctx, tech.ContactID)
+		if c.Phone != "555-0100" {
+			t.Errorf("expected primary contact phone to be updated, got %q", c.Phone)
+		}
+	})
+
+	t.Run("delete primary", func(t *testing.T) {
+		if err := svc.DeleteContact(ctx, tech.ContactID); err != nil {
+			t.Fatalf("delete contact: %v", err)
+		}
+		if _, err := svc.GetContact(ctx, tech.ContactID); err == nil {
+			t.Error("expected error getting deleted contact")
+		}
+
+		// The remaining contact is copied to the customer
+		got, _ := svc.Get(ctx, cust.CustomerID)
+		if got.ContactName != "John Doe" {
+			t.Errorf("expected remaining contact on customer, got %q", got.ContactName)
+		}
+	})
+}
+
+func TestSelectContact(t *testing.T) {
+	if customer.SelectContact(nil, customer.RoleBilling) != nil {
+		t.Error("expected nil for no contacts")
+	}
+
+	contacts := []customer.Contact{
+		{ContactID: 1, ContactName: "first", Roles: "purchasing"},
+		{ContactID: 2, ContactName: "primary", Roles: "technical", IsPrimary: true},
+		{ContactID: 3, ContactName: "billing", Roles: "billing"},
+	}
+	tests := []struct {
+		role string
+		want string
+	}{
+		{customer.RoleBilling, "billing"},
+		{customer.RoleTechnical, "primary"},
+		{customer.RolePurchasing, "first"},
+		{"other", "primary"},
+	}
+	for _, tt := range tests {
+		if got := customer.SelectContact(contacts, tt.role); got.ContactName != tt.want {
+			t.Errorf("SelectContact(%q) = %q, want %q", tt.role, got.ContactName, tt.want)
+		}
+	}
+
+	if got := customer.SelectContact(contacts[:1], customer.RoleBilling); got.ContactName != "first" {
+		t.Errorf("expected first contact as fallback, got %q", got.ContactName)
+	}
+}
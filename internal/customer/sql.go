@@ -1,21 +1,34 @@
 package customer
 
 const getAllCustomersSQL = `
-SELECT customer_id, customer_name, contact_name, phone, email, notes
+SELECT customer_id, customer_name, contact_name, phone, email, notes, reseller_id
 FROM customer
 ORDER BY customer_name
 `
 
+const getCustomersForResellerSQL = `
+SELECT customer_id, customer_name, contact_name, phone, email, notes, reseller_id
+FROM customer
+WHERE reseller_id = ?
+ORDER BY customer_name
+`
+
 const getCustomerSQL = `
-SELECT customer_id, customer_name, contact_name, phone, email, notes
+SELECT customer_id, customer_name, contact_name, phone, email, notes, reseller_id
 FROM customer
 WHERE customer_id = ?
 `
 
 const createCustomerSQL = `
 INSERT INTO customer (
-    customer_name, contact_name, phone, email, notes
-) VALUES (?, ?, ?, ?, ?)
+    customer_name, contact_name, phone, email, notes, reseller_id
+) VALUES (?, ?, ?, ?, ?, ?)
+`
+
+const setResellerSQL = `
+UPDATE customer
+SET reseller_id = ?
+WHERE customer_id = ?
 `
 
 const updateCustomerSQL = `
@@ -34,3 +47,62 @@ SELECT EXISTS(
     SELECT 1 FROM customer WHERE customer_id = ?
 )
 `
+
+const getContactsSQL = `
+SELECT contact_id, customer_id, contact_name, phone, email, roles, is_primary, notes
+FROM customer_contact
+WHERE customer_id = ?
+ORDER BY is_primary DESC, contact_name, contact_id
+`
+
+const getContactSQL = `
+SELECT contact_id, customer_id, contact_name, phone, email, roles, is_primary, notes
+FROM customer_contact
+WHERE contact_id = ?
+`
+
+const createContactSQL = `
+INSERT INTO customer_contact (
+    customer_id, contact_name, phone, email, roles, is_primary, notes
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+const updateContactSQL = `
+UPDATE customer_contact
+SET contact_name = ?, phone = ?, email = ?, roles = ?, is_primary = ?, notes = ?
+WHERE contact_id = ?
+`
+
+const deleteContactSQL = `
+DELETE FROM customer_contact
+WHERE contact_id = ?
+`
+
+const clearPrimaryContactSQL = `
+UPDATE customer_contact
+SET is_primary = 0
+WHERE customer_id = ? AND contact_id <> ?
+`
+
+const updatePrimaryContactSQL = `
+UPDATE customer_contact
+SET contact_name = ?, phone = ?, email = ?
+WHERE customer_id = ? AND is_primary = 1
+`
+
+// syncPrimaryContactSQL copies the primary contact (or the first contact when
+// there is no primary) to the customer's contact columns
+const syncPrimaryContactSQL = `
+UPDATE customer
+SET (contact_name, phone, email) = (
+    SELECT COALESCE(MAX(contact_name), ''), COALESCE(MAX(phone), ''), COALESCE(MAX(email), '')
+    FROM (
+        SELECT contact_name, phone, email
+        FROM customer_contact
+        WHERE customer_id = customer.customer_id
+        ORDER BY is_primary DESC, contact_id
+        LIMIT 1
+    )
+)
+WHERE customer_id = ?
+`
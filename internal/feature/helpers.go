@@ -9,22 +9,32 @@ import (
 func ToInt(s string) int {
 	switch strings.ToLower(s) {
 	case "integer":
-		return 0
+		return featurevalue.TypeInteger
 	case "string":
-		return 1
+		return featurevalue.TypeString
 	case "values":
-		return 2
+		return featurevalue.TypeValues
+	case "boolean":
+		return featurevalue.TypeBoolean
+	case "date":
+		return featurevalue.TypeDate
 	default:
 		return -1
 	}
 }
 
-// MergeWithOverrides returns a map of feature names to values, applying customer
-// overrides where they exist and using defaults otherwise.
-func MergeWithOverrides(defs []Feature, vals []featurevalue.FeatureValue) map[string]any {
-	out := make(map[string]any)
+// MergeWithOverrides returns a map of feature names to values. Each feature gets
+// the license's override if there is one, else the value of the license's plan,
+// else the feature default. Values are the stored strings, which the
+// registration hash is computed from.
+func MergeWithOverrides(defs []Feature, planVals, vals []featurevalue.FeatureValue) map[string]string {
+	out := make(map[string]string)
 
-	// Build lookup for customer overrides
+	// Build lookups for plan values and customer overrides
+	plan := make(map[int64]string)
+	for _, v := range planVals {
+		plan[v.FeatureID] = v.FeatureValue
+	}
 	overrides := make(map[int64]string)
 	for _, v := range vals {
 		overrides[v.FeatureID] = v.FeatureValue
@@ -33,6 +43,8 @@ func MergeWithOverrides(defs []Feature, vals []featurevalue.FeatureValue) map[st
 	for _, d := range defs {
 		if v, ok := overrides[d.FeatureID]; ok {
 			out[d.FeatureName] = v
+		} else if v, ok := plan[d.FeatureID]; ok {
+			out[d.FeatureName] = v
 		} else {
 			out[d.FeatureName] = d.DefaultValue
 		}
@@ -40,3 +52,15 @@ func MergeWithOverrides(defs []Feature, vals []featurevalue.FeatureValue) map[st
 
 	return out
 }
+
+// TypedValues converts merged feature values to the typed values returned to
+// clients as JSON (true/false for boolean features, strings otherwise).
+func TypedValues(defs []Feature, merged map[string]string) map[string]any {
+	out := make(map[string]any, len(merged))
+	for _, d := range defs {
+		if v, ok := merged[d.FeatureName]; ok {
+			out[d.FeatureName] = featurevalue.Typed(d.FeatureType, v)
+		}
+	}
+	return out
+}
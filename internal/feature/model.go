@@ -1,10 +1,84 @@
 package feature
 
+import (
+	"errors"
+
+	"winsbygroup.com/regserver/internal/featurevalue"
+)
+
+// Feature statuses. Deprecated features are still emitted to clients but
+// flagged for removal; hidden features are no longer emitted to registrations
+// activated on or after the status date.
+const (
+	StatusActive     = "active"
+	StatusDeprecated = "deprecated"
+	StatusHidden     = "hidden"
+)
+
+var (
+	ErrInvalidStatus = errors.New("invalid feature status")
+	ErrFeatureInUse  = errors.New("feature is still emitted to registrations activated before it was hidden")
+)
+
 type Feature struct {
-	FeatureID     int64  `db:"feature_id"`
-	ProductID     int64  `db:"product_id"`
-	FeatureName   string `db:"feature_name"`
-	FeatureType   int    `db:"feature_type"`
-	AllowedValues string `db:"allowed_values"`
-	DefaultValue  string `db:"default_value"`
+	FeatureID     int64   `db:"feature_id"`
+	ProductID     int64   `db:"product_id"`
+	FeatureName   string  `db:"feature_name"`
+	FeatureType   int     `db:"feature_type"`
+	AllowedValues string  `db:"allowed_values"`
+	DefaultValue  string  `db:"default_value"`
+	MinValue      *int64  `db:"min_value"`  // integer features; nil = no minimum
+	MaxValue      *int64  `db:"max_value"`  // integer features; nil = no maximum
+	MaxLength     int     `db:"max_length"` // string features; 0 = no limit
+	Status        string  `db:"feature_status"`
+	StatusDate    string  `db:"status_date"`    // date the status takes effect for activations; "" = always
+	EffectiveDate string  `db:"effective_date"` // first activation date the feature is emitted to; "" = always
+	Aliases       []Alias `db:"-"`              // former names, oldest first
+}
+
+// Alias is a former name of a renamed feature. Registrations activated before
+// the cutoff date were issued (and hashed) with the feature under this name.
+type Alias struct {
+	AliasID    int64  `db:"alias_id"`
+	FeatureID  int64  `db:"feature_id"`
+	AliasName  string `db:"alias_name"`
+	CutoffDate string `db:"cutoff_date"`
+}
+
+// Rules returns the constraints the feature puts on its default and license values.
+func (f *Feature) Rules() featurevalue.Rules {
+	return featurevalue.Rules{
+		FeatureType:   f.FeatureType,
+		AllowedValues: f.AllowedValues,
+		MinValue:      f.MinValue,
+		MaxValue:      f.MaxValue,
+		MaxLength:     f.MaxLength,
+	}
+}
+
+// NameAsOf returns the name the feature was emitted under to a registration
+// activated on date (yyyy-mm-dd): the first alias whose cutoff is after date,
+// else the current name.
+func (f *Feature) NameAsOf(date string) string {
+	for _, a := range f.Aliases {
+		if date < a.CutoffDate {
+			return a.AliasName
+		}
+	}
+	return f.FeatureName
+}
+
+// EmittedAsOf reports whether the feature is part of the registration of a
+// client activated on date: from its effective date on and, for hidden
+// features, until their status date.
+func (f *Feature) EmittedAsOf(date string) bool {
+	if f.EffectiveDate != "" && date < f.EffectiveDate {
+		return false
+	}
+	return f.Status != StatusHidden || (f.StatusDate != "" && date < f.StatusDate)
+}
+
+// ValidStatus reports whether s is a known feature status.
+func ValidStatus(s string) bool {
+	return s == StatusActive || s == StatusDeprecated || s == StatusHidden
 }
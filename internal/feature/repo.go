@@ -11,10 +11,15 @@ import (
 
 type Repository interface {
 	GetForProduct(ctx context.Context, productID int64) ([]Feature, error)
+	GetForProductTx(ctx context.Context, tx *sqlx.Tx, productID int64) ([]Feature, error)
 	Get(ctx context.Context, id int64) (*Feature, error)
 	Create(ctx context.Context, tx *sqlx.Tx, f *Feature) (int64, error)
 	Update(ctx context.Context, tx *sqlx.Tx, f *Feature) error
 	Delete(ctx context.Context, tx *sqlx.Tx, id int64) error
+	GetIssuedDates(ctx context.Context, tx *sqlx.Tx, productID int64) ([]string, error)
+	GetAliasesForProduct(ctx context.Context, productID int64) ([]Alias, error)
+	GetAliasesForProductTx(ctx context.Context, tx *sqlx.Tx, productID int64) ([]Alias, error)
+	CreateAlias(ctx context.Context, tx *sqlx.Tx, a *Alias) error
 }
 
 type repo struct {
@@ -34,6 +39,15 @@ func (r *repo) GetForProduct(ctx context.Context, productID int64) ([]Feature, e
 	return out, nil
 }
 
+func (r *repo) GetForProductTx(ctx context.Context, tx *sqlx.Tx, productID int64) ([]Feature, error) {
+	var out []Feature
+	err := tx.SelectContext(ctx, &out, getFeaturesForProductSQL, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get features for product: %w", err)
+	}
+	return out, nil
+}
+
 func (r *repo) Get(ctx context.Context, id int64) (*Feature, error) {
 	var f Feature
 	err := r.db.GetContext(ctx, &f, getFeatureSQL, id)
@@ -53,6 +67,12 @@ func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, f *Feature) (int64, erro
 		f.FeatureType,
 		f.AllowedValues,
 		f.DefaultValue,
+		f.MinValue,
+		f.MaxValue,
+		f.MaxLength,
+		f.Status,
+		f.StatusDate,
+		f.EffectiveDate,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("create feature: %w", err)
@@ -66,6 +86,11 @@ func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, f *Feature) error {
 		f.FeatureType,
 		f.AllowedValues,
 		f.DefaultValue,
+		f.MinValue,
+		f.MaxValue,
+		f.MaxLength,
+		f.Status,
+		f.StatusDate,
 		f.FeatureID,
 	)
 	if err != nil {
@@ -81,3 +106,38 @@ func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, id int64) error {
 	}
 	return nil
 }
+
+func (r *repo) GetIssuedDates(ctx context.Context, tx *sqlx.Tx, productID int64) ([]string, error) {
+	var out []string
+	err := tx.SelectContext(ctx, &out, getIssuedDatesSQL, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get issued registration dates: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetAliasesForProduct(ctx context.Context, productID int64) ([]Alias, error) {
+	var out []Alias
+	err := r.db.SelectContext(ctx, &out, getAliasesForProductSQL, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get feature aliases: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetAliasesForProductTx(ctx context.Context, tx *sqlx.Tx, productID int64) ([]Alias, error) {
+	var out []Alias
+	err := tx.SelectContext(ctx, &out, getAliasesForProductSQL, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get feature aliases: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) CreateAlias(ctx context.Context, tx *sqlx.Tx, a *Alias) error {
+	_, err := tx.ExecContext(ctx, createAliasSQL, a.FeatureID, a.AliasName, a.CutoffDate)
+	if err != nil {
+		return fmt.Errorf("create feature alias: %w", err)
+	}
+	return nil
+}
@@ -2,13 +2,20 @@ package feature
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/registration"
 )
 
 type Service struct {
-	repo Repository
-	db   *sqlx.DB
+	repo     Repository
+	db       *sqlx.DB
+	reissuer registration.Reissuer
 }
 
 func NewService(db *sqlx.DB) *Service {
@@ -18,6 +25,12 @@ func NewService(db *sqlx.DB) *Service {
 	}
 }
 
+// SetReissuer sets what recomputes the registrations of a product when its
+// features change (nil, the default, leaves registrations as they are)
+func (s *Service) SetReissuer(r registration.Reissuer) {
+	s.reissuer = r
+}
+
 func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -30,21 +43,113 @@ func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
 	return tx.Commit()
 }
 
+// GetForProduct returns the product's features with their former names
 func (s *Service) GetForProduct(ctx context.Context, productID int64) ([]Feature, error) {
-	return s.repo.GetForProduct(ctx, productID)
+	defs, err := s.repo.GetForProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := s.repo.GetAliasesForProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range defs {
+		defs[i].Aliases = aliasesOf(aliases, defs[i].FeatureID)
+	}
+	return defs, nil
+}
+
+// getForProductTx returns the product's features with their former names as
+// changed so far in the caller's transaction
+func (s *Service) getForProductTx(ctx context.Context, tx *sqlx.Tx, productID int64) ([]Feature, error) {
+	defs, err := s.repo.GetForProductTx(ctx, tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := s.repo.GetAliasesForProductTx(ctx, tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range defs {
+		defs[i].Aliases = aliasesOf(aliases, defs[i].FeatureID)
+	}
+	return defs, nil
+}
+
+// GetAsOf returns the product's features as they are emitted to (and hashed
+// for) a registration activated on date (yyyy-mm-dd): renamed features carry
+// the name they had then and hidden features are left out. Deployed clients
+// keep validating until they reactivate.
+func (s *Service) GetAsOf(ctx context.Context, productID int64, date string) ([]Feature, error) {
+	defs, err := s.GetForProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	return asOf(defs, date), nil
+}
+
+// GetAsOfTx returns the product's features like GetAsOf, as changed so far in
+// the caller's transaction
+func (s *Service) GetAsOfTx(ctx context.Context, tx *sqlx.Tx, productID int64, date string) ([]Feature, error) {
+	defs, err := s.getForProductTx(ctx, tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	return asOf(defs, date), nil
+}
+
+// asOf returns the features emitted to a registration activated on date, under
+// the names they had then
+func asOf(defs []Feature, date string) []Feature {
+	out := make([]Feature, 0, len(defs))
+	for _, f := range defs {
+		if !f.EmittedAsOf(date) {
+			continue
+		}
+		f.FeatureName = f.NameAsOf(date)
+		out = append(out, f)
+	}
+	return out
 }
 
 func (s *Service) Get(ctx context.Context, id int64) (*Feature, error) {
-	return s.repo.Get(ctx, id)
+	f, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := s.repo.GetAliasesForProduct(ctx, f.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	f.Aliases = aliasesOf(aliases, f.FeatureID)
+	return f, nil
 }
 
 func (s *Service) Create(ctx context.Context, f *Feature) (*Feature, error) {
+	if f.Status == "" {
+		f.Status = StatusActive
+	}
+	if err := validate(f); err != nil {
+		return nil, err
+	}
+	if err := s.checkAliasName(ctx, f); err != nil {
+		return nil, err
+	}
+	// A feature created hidden was never emitted
+	f.StatusDate = ""
+
 	var id int64
 
 	err := s.WithTx(ctx, func(tx *sqlx.Tx) error {
-		var err error
-		id, err = s.repo.Create(ctx, tx, f)
-		return err
+		dates, err := s.repo.GetIssuedDates(ctx, tx, f.ProductID)
+		if err != nil {
+			return err
+		}
+		f.EffectiveDate = effectiveDate(dates)
+		if id, err = s.repo.Create(ctx, tx, f); err != nil {
+			return err
+		}
+		return s.reissue(ctx, tx, f.ProductID)
 	})
 	if err != nil {
 		return nil, err
@@ -58,14 +163,158 @@ func (s *Service) Create(ctx context.Context, f *Feature) (*Feature, error) {
 	return created, nil
 }
 
+// Update saves the feature. A rename records the former name as an alias and a
+// status change gets a new status date, both effective from tomorrow: registration
+// dates are whole days, so clients activated on the day of the change keep the
+// feature set they were issued. An empty status keeps the current one.
 func (s *Service) Update(ctx context.Context, f *Feature) error {
+	cur, err := s.repo.Get(ctx, f.FeatureID)
+	if err != nil {
+		return err
+	}
+	f.ProductID = cur.ProductID
+	if f.Status == "" {
+		f.Status = cur.Status
+	}
+	if err := validate(f); err != nil {
+		return err
+	}
+	if err := s.checkAliasName(ctx, f); err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	f.StatusDate = cur.StatusDate
+	if f.Status != cur.Status {
+		f.StatusDate = cutoff
+	}
+
 	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
-		return s.repo.Update(ctx, tx, f)
+		if f.FeatureName != cur.FeatureName {
+			alias := &Alias{FeatureID: f.FeatureID, AliasName: cur.FeatureName, CutoffDate: cutoff}
+			if err := s.repo.CreateAlias(ctx, tx, alias); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.Update(ctx, tx, f); err != nil {
+			return err
+		}
+		return s.reissue(ctx, tx, f.ProductID)
 	})
 }
 
+// Delete removes the feature. A feature still emitted to an issued
+// registration is hidden from tomorrow instead, so deployed clients keep
+// validating until they reactivate. A hidden feature can be removed once no
+// issued registration includes it; until then Delete returns ErrFeatureInUse.
 func (s *Service) Delete(ctx context.Context, id int64) error {
+	cur, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
 	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
-		return s.repo.Delete(ctx, tx, id)
+		dates, err := s.repo.GetIssuedDates(ctx, tx, cur.ProductID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !emittedToAny(cur, dates):
+			if err := s.repo.Delete(ctx, tx, id); err != nil {
+				return err
+			}
+		case cur.Status != StatusHidden:
+			cur.Status = StatusHidden
+			cur.StatusDate = time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+			if err := s.repo.Update(ctx, tx, cur); err != nil {
+				return err
+			}
+		default:
+			return ErrFeatureInUse
+		}
+		return s.reissue(ctx, tx, cur.ProductID)
 	})
 }
+
+// effectiveDate returns the date a new feature is emitted from, given the
+// activation dates of the product's issued registrations: today, or tomorrow
+// when a registration was already issued today, since registration dates are
+// whole days and those clients keep the feature set they were issued
+func effectiveDate(issued []string) string {
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	for _, d := range issued {
+		if d >= today {
+			return now.AddDate(0, 0, 1).Format("2006-01-02")
+		}
+	}
+	return today
+}
+
+// emittedToAny reports whether the feature is part of any registration
+// activated on one of dates
+func emittedToAny(f *Feature, dates []string) bool {
+	for _, d := range dates {
+		if f.EmittedAsOf(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// reissue recomputes the registrations of a product whose features changed in tx
+func (s *Service) reissue(ctx context.Context, tx *sqlx.Tx, productID int64) error {
+	if s.reissuer == nil {
+		return nil
+	}
+	return s.reissuer.ReissueProduct(ctx, tx, productID)
+}
+
+// checkAliasName rejects a name that another feature of the product is still
+// emitted under to older registrations
+func (s *Service) checkAliasName(ctx context.Context, f *Feature) error {
+	aliases, err := s.repo.GetAliasesForProduct(ctx, f.ProductID)
+	if err != nil {
+		return err
+	}
+	for _, a := range aliases {
+		if a.FeatureID != f.FeatureID && strings.EqualFold(a.AliasName, f.FeatureName) {
+			return &featurevalue.FieldError{
+				Field: "feature_name",
+				Err:   fmt.Errorf("%w: %q is a former name of feature %d, still emitted to older registrations", featurevalue.ErrInvalidValue, a.AliasName, a.FeatureID),
+			}
+		}
+	}
+	return nil
+}
+
+// aliasesOf returns the aliases of one feature, keeping their order
+func aliasesOf(aliases []Alias, featureID int64) []Alias {
+	var out []Alias
+	for _, a := range aliases {
+		if a.FeatureID == featureID {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// validate checks the feature's rules and that its default value satisfies them;
+// the default is stored in its canonical form.
+func validate(f *Feature) error {
+	if !ValidStatus(f.Status) {
+		return &featurevalue.FieldError{
+			Field: "feature_status",
+			Err:   fmt.Errorf("%w: %w %q", featurevalue.ErrInvalidValue, ErrInvalidStatus, f.Status),
+		}
+	}
+	rules := f.Rules()
+	if err := rules.Check(); err != nil {
+		return err
+	}
+	def, err := rules.Validate("default_value", f.DefaultValue)
+	if err != nil {
+		return err
+	}
+	f.DefaultValue = def
+	return nil
+}
@@ -2,11 +2,14 @@ package feature_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 
 	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
 	"winsbygroup.com/regserver/internal/product"
 	"winsbygroup.com/regserver/internal/sqlite"
 	"winsbygroup.com/regserver/internal/testutil"
@@ -35,7 +38,7 @@ func TestFeatureLifecycle(t *testing.T) {
 		ProductID:     p.ProductID,
 		FeatureName:   "MaxUsers",
 		FeatureType:   feature.ToInt("values"),
-		AllowedValues: "1,5,10",
+		AllowedValues: "1|5|10",
 		DefaultValue:  "1",
 	}
 
@@ -108,3 +111,293 @@ func TestFeatureDuplicateName(t *testing.T) {
 		t.Errorf("expected unique constraint error, got: %v", err)
 	}
 }
+
+func TestFeatureDefaultValueValidation(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	prodSvc := product.NewService(db)
+	featSvc := feature.NewService(db)
+
+	p, err := prodSvc.Create(ctx, &product.Product{
+		ProductName: "Widget",
+		ProductGUID: "GUID-123",
+	})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	// Boolean defaults are stored in canonical form
+	f, err := featSvc.Create(ctx, &feature.Feature{
+		ProductID:    p.ProductID,
+		FeatureName:  "Legacy",
+		FeatureType:  feature.ToInt("boolean"),
+		DefaultValue: "TRUE",
+	})
+	if err != nil {
+		t.Fatalf("create boolean feature: %v", err)
+	}
+	if f.DefaultValue != "true" {
+		t.Errorf("expected default 'true', got %q", f.DefaultValue)
+	}
+
+	invalid := []*feature.Feature{
+		{ProductID: p.ProductID, FeatureName: "Seats", FeatureType: feature.ToInt("integer"), DefaultValue: "ten"},
+		{ProductID: p.ProductID, FeatureName: "Until", FeatureType: feature.ToInt("date"), DefaultValue: "2030-13-01"},
+		{ProductID: p.ProductID, FeatureName: "Mystery", FeatureType: feature.ToInt("bogus"), DefaultValue: "x"},
+	}
+	for _, inv := range invalid {
+		if _, err := featSvc.Create(ctx, inv); !errors.Is(err, featurevalue.ErrInvalidValue) {
+			t.Errorf("%s: expected ErrInvalidValue, got %v", inv.FeatureName, err)
+		}
+	}
+
+	// Updates are validated too
+	f.DefaultValue = "maybe"
+	if err := featSvc.Update(ctx, f); !errors.Is(err, featurevalue.ErrInvalidValue) {
+		t.Errorf("update: expected ErrInvalidValue, got %v", err)
+	}
+}
+
+func TestFeatureRulesValidation(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	prodSvc := product.NewService(db)
+	featSvc := feature.NewService(db)
+
+	p, err := prodSvc.Create(ctx, &product.Product{
+		ProductName: "Widget",
+		ProductGUID: "GUID-123",
+	})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	one, ten := int64(1), int64(10)
+
+	// A valid integer feature with bounds round-trips them
+	f, err := featSvc.Create(ctx, &feature.Feature{
+		ProductID:    p.ProductID,
+		FeatureName:  "Seats",
+		FeatureType:  feature.ToInt("integer"),
+		DefaultValue: "5",
+		MinValue:     &one,
+		MaxValue:     &ten,
+	})
+	if err != nil {
+		t.Fatalf("create bounded feature: %v", err)
+	}
+	if f.MinValue == nil || *f.MinValue != 1 || f.MaxValue == nil || *f.MaxValue != 10 {
+		t.Errorf("expected bounds 1..10, got %v..%v", f.MinValue, f.MaxValue)
+	}
+
+	tests := []struct {
+		name  string
+		f     feature.Feature
+		field string
+	}{
+		{"default below minimum", feature.Feature{FeatureType: feature.ToInt("integer"), DefaultValue: "0", MinValue: &one}, "default_value"},
+		{"minimum above maximum", feature.Feature{FeatureType: feature.ToInt("integer"), DefaultValue: "5", MinValue: &ten, MaxValue: &one}, "max_value"},
+		{"bounds on a string", feature.Feature{FeatureType: feature.ToInt("string"), DefaultValue: "x", MinValue: &one}, "min_value"},
+		{"default too long", feature.Feature{FeatureType: feature.ToInt("string"), DefaultValue: "Enterprise", MaxLength: 5}, "default_value"},
+		{"max length on an integer", feature.Feature{FeatureType: feature.ToInt("integer"), DefaultValue: "5", MaxLength: 5}, "max_length"},
+		{"values without options", feature.Feature{FeatureType: feature.ToInt("values"), DefaultValue: "A"}, "allowed_values"},
+		{"default not an option", feature.Feature{FeatureType: feature.ToInt("values"), AllowedValues: "A|B", DefaultValue: "C"}, "default_value"},
+		{"unknown type", feature.Feature{FeatureType: feature.ToInt("bogus"), DefaultValue: "x"}, "feature_type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.f.ProductID = p.ProductID
+			tt.f.FeatureName = tt.name
+			_, err := featSvc.Create(ctx, &tt.f)
+			var fe *featurevalue.FieldError
+			if !errors.As(err, &fe) {
+				t.Fatalf("expected a field error, got %v", err)
+			}
+			if fe.Field != tt.field {
+				t.Errorf("expected field %q, got %q (%v)", tt.field, fe.Field, err)
+			}
+		})
+	}
+}
+
+func TestFeatureRenameAndStatus(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	prodSvc := product.NewService(db)
+	featSvc := feature.NewService(db)
+
+	p, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Widget", ProductGUID: "GUID-123"})
+	f, err := featSvc.Create(ctx, &feature.Feature{ProductID: p.ProductID, FeatureName: "Legacy", FeatureType: feature.ToInt("boolean"), DefaultValue: "true"})
+	if err != nil {
+		t.Fatalf("create feature: %v", err)
+	}
+	if f.Status != feature.StatusActive {
+		t.Errorf("expected a new feature to be active, got %q", f.Status)
+	}
+	other, _ := featSvc.Create(ctx, &feature.Feature{ProductID: p.ProductID, FeatureName: "Tier", FeatureType: feature.ToInt("string"), DefaultValue: "Gold"})
+
+	// Rename twice and hide the other feature; all effective tomorrow
+	today := time.Now().Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	for _, name := range []string{"Compat", "Compatibility"} {
+		f.FeatureName = name
+		if err := featSvc.Update(ctx, f); err != nil {
+			t.Fatalf("rename to %s: %v", name, err)
+		}
+	}
+	other.Status = feature.StatusHidden
+	if err := featSvc.Update(ctx, other); err != nil {
+		t.Fatalf("hide: %v", err)
+	}
+
+	got, _ := featSvc.Get(ctx, f.FeatureID)
+	if len(got.Aliases) != 2 || got.Aliases[0].AliasName != "Legacy" || got.Aliases[0].CutoffDate != tomorrow {
+		t.Errorf("unexpected aliases: %+v", got.Aliases)
+	}
+
+	names := func(date string) []string {
+		defs, err := featSvc.GetAsOf(ctx, p.ProductID, date)
+		if err != nil {
+			t.Fatalf("get as of %s: %v", date, err)
+		}
+		var out []string
+		for _, d := range defs {
+			out = append(out, d.FeatureName)
+		}
+		return out
+	}
+	if got := names(today); len(got) != 2 || got[0] != "Legacy" || got[1] != "Tier" {
+		t.Errorf("expected the former feature set before the cutoff, got %v", got)
+	}
+	if got := names(tomorrow); len(got) != 1 || got[0] != "Compatibility" {
+		t.Errorf("expected only the renamed feature from the cutoff, got %v", got)
+	}
+
+	t.Run("former name is reserved", func(t *testing.T) {
+		_, err := featSvc.Create(ctx, &feature.Feature{ProductID: p.ProductID, FeatureName: "legacy", FeatureType: feature.ToInt("string")})
+		var fe *featurevalue.FieldError
+		if !errors.As(err, &fe) || fe.Field != "feature_name" {
+			t.Errorf("expected a feature_name error, got %v", err)
+		}
+		// The feature itself may take its former name back
+		f.FeatureName = "Legacy"
+		if err := featSvc.Update(ctx, f); err != nil {
+			t.Errorf("rename back: %v", err)
+		}
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		f.Status = "retired"
+		err := featSvc.Update(ctx, f)
+		if !errors.Is(err, feature.ErrInvalidStatus) || !errors.Is(err, featurevalue.ErrInvalidValue) {
+			t.Errorf("expected ErrInvalidStatus, got %v", err)
+		}
+	})
+}
+
+func TestFeatureEffectiveDateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	prodSvc := product.NewService(db)
+	featSvc := feature.NewService(db)
+
+	today := time.Now().Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	p, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Widget", ProductGUID: "GUID-123"})
+	create := func(name string) *feature.Feature {
+		t.Helper()
+		f, err := featSvc.Create(ctx, &feature.Feature{ProductID: p.ProductID, FeatureName: name, FeatureType: feature.ToInt("string")})
+		if err != nil {
+			t.Fatalf("create feature %s: %v", name, err)
+		}
+		return f
+	}
+	emitted := func(date string) map[string]bool {
+		t.Helper()
+		defs, err := featSvc.GetAsOf(ctx, p.ProductID, date)
+		if err != nil {
+			t.Fatalf("get as of %s: %v", date, err)
+		}
+		out := make(map[string]bool)
+		for _, d := range defs {
+			out[d.FeatureName] = true
+		}
+		return out
+	}
+
+	// Without issued registrations a feature is emitted from today
+	tier := create("Tier")
+	if tier.EffectiveDate != today {
+		t.Errorf("expected Tier to take effect today, got %q", tier.EffectiveDate)
+	}
+
+	// A registration issued last year
+	if _, err := db.Exec(`
+		INSERT INTO customer (customer_id, customer_name) VALUES (1, 'Acme');
+		INSERT INTO machine (machine_id, customer_id, machine_code) VALUES (1, 1, 'M-1');
+		INSERT INTO license (license_id, customer_id, product_id, license_key, license_count, is_subscription, license_term)
+			VALUES (1, 1, ?, 'KEY-1', 1, 0, 0);
+		INSERT INTO registration (machine_id, product_id, license_id, expiration_date, registration_hash,
+			first_registration_date, last_registration_date)
+			VALUES (1, ?, 1, '2099-12-31', 'HASH', '2020-01-01', '2020-01-01');`, p.ProductID, p.ProductID); err != nil {
+		t.Fatalf("insert registration: %v", err)
+	}
+
+	t.Run("new features stay out of older registrations", func(t *testing.T) {
+		if got := emitted("2020-01-01"); got["Tier"] {
+			t.Errorf("expected Tier left out of a registration activated before it existed, got %v", got)
+		}
+		if got := emitted(today); !got["Tier"] {
+			t.Errorf("expected Tier emitted to activations from today, got %v", got)
+		}
+	})
+
+	t.Run("delete removes a feature no registration includes", func(t *testing.T) {
+		if err := featSvc.Delete(ctx, tier.FeatureID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := featSvc.Get(ctx, tier.FeatureID); err == nil {
+			t.Error("expected the feature to be removed")
+		}
+	})
+
+	t.Run("delete hides an emitted feature", func(t *testing.T) {
+		// A feature from before effective dates, emitted to the registration of 2020-01-01
+		legacy := create("Legacy")
+		if _, err := db.Exec(`UPDATE feature SET effective_date = NULL WHERE feature_id = ?`, legacy.FeatureID); err != nil {
+			t.Fatalf("clear effective date: %v", err)
+		}
+
+		if err := featSvc.Delete(ctx, legacy.FeatureID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		got, err := featSvc.Get(ctx, legacy.FeatureID)
+		if err != nil {
+			t.Fatalf("expected the feature to be kept: %v", err)
+		}
+		if got.Status != feature.StatusHidden || got.StatusDate != tomorrow {
+			t.Errorf("expected the feature hidden from %s, got %q from %q", tomorrow, got.Status, got.StatusDate)
+		}
+		if !emitted("2020-01-01")["Legacy"] || emitted(tomorrow)["Legacy"] {
+			t.Error("expected Legacy emitted to the older registration only")
+		}
+
+		// Still hashed for the older registration
+		if err := featSvc.Delete(ctx, legacy.FeatureID); !errors.Is(err, feature.ErrFeatureInUse) {
+			t.Errorf("expected ErrFeatureInUse, got %v", err)
+		}
+
+		// Once that registration is reactivated the feature can go
+		if _, err := db.Exec(`UPDATE registration SET last_registration_date = ?`, tomorrow); err != nil {
+			t.Fatalf("reactivate: %v", err)
+		}
+		if err := featSvc.Delete(ctx, legacy.FeatureID); err != nil {
+			t.Errorf("delete after reactivation: %v", err)
+		}
+	})
+}
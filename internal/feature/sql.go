@@ -6,8 +6,14 @@ SELECT
     product_id,
     feature_name,
     feature_type,
-    allowed_values,
-    default_value
+    COALESCE(allowed_values, '') AS allowed_values,
+    default_value,
+    min_value,
+    max_value,
+    max_length,
+    feature_status,
+    COALESCE(status_date, '') AS status_date,
+    COALESCE(effective_date, '') AS effective_date
 FROM feature
 WHERE product_id = ?
 ORDER BY feature_name
@@ -19,8 +25,14 @@ SELECT
     product_id,
     feature_name,
     feature_type,
-    allowed_values,
-    default_value
+    COALESCE(allowed_values, '') AS allowed_values,
+    default_value,
+    min_value,
+    max_value,
+    max_length,
+    feature_status,
+    COALESCE(status_date, '') AS status_date,
+    COALESCE(effective_date, '') AS effective_date
 FROM feature
 WHERE feature_id = ?
 `
@@ -31,8 +43,14 @@ INSERT INTO feature (
     feature_name,
     feature_type,
     allowed_values,
-    default_value
-) VALUES (?, ?, ?, ?, ?)
+    default_value,
+    min_value,
+    max_value,
+    max_length,
+    feature_status,
+    status_date,
+    effective_date
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
 `
 
 const updateFeatureSQL = `
@@ -41,11 +59,44 @@ SET
     feature_name = ?,
     feature_type = ?,
     allowed_values = ?,
-    default_value = ?
+    default_value = ?,
+    min_value = ?,
+    max_value = ?,
+    max_length = ?,
+    feature_status = ?,
+    status_date = NULLIF(?, '')
 WHERE feature_id = ?
 `
 
+// getIssuedDatesSQL returns the activation dates of a product's issued
+// registrations (those recomputed when its features change)
+const getIssuedDatesSQL = `
+SELECT DISTINCT last_registration_date
+FROM registration
+WHERE product_id = ? AND license_id IS NOT NULL AND released_date IS NULL
+`
+
 const deleteFeatureSQL = `
 DELETE FROM feature
 WHERE feature_id = ?
 `
+
+const getAliasesForProductSQL = `
+SELECT
+    a.alias_id,
+    a.feature_id,
+    a.alias_name,
+    a.cutoff_date
+FROM feature_alias a
+JOIN feature f ON f.feature_id = a.feature_id
+WHERE f.product_id = ?
+ORDER BY a.cutoff_date, a.alias_id
+`
+
+const createAliasSQL = `
+INSERT INTO feature_alias (
+    feature_id,
+    alias_name,
+    cutoff_date
+) VALUES (?, ?, ?)
+`
@@ -1,8 +1,7 @@
 package featurevalue
 
 type FeatureValue struct {
-	CustomerID   int64  `db:"customer_id"`
-	ProductID    int64  `db:"product_id"`
+	LicenseID    int64  `db:"license_id"`
 	FeatureID    int64  `db:"feature_id"`
 	FeatureValue string `db:"feature_value"`
 }
@@ -2,14 +2,21 @@ package featurevalue
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Repository interface {
-	GetFeatureValues(ctx context.Context, customerID, productID int64) ([]FeatureValue, error)
+	GetFeatureValues(ctx context.Context, licenseID int64) ([]FeatureValue, error)
+	GetPlanValues(ctx context.Context, licenseID int64) ([]FeatureValue, error)
+	GetFeatureValuesTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]FeatureValue, error)
+	GetPlanValuesTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]FeatureValue, error)
+	GetRules(ctx context.Context, featureID int64) (*Rules, error)
 	Update(ctx context.Context, tx *sqlx.Tx, fv *FeatureValue) error
+	Delete(ctx context.Context, tx *sqlx.Tx, licenseID, featureID int64) error
 }
 
 type repo struct {
@@ -20,10 +27,10 @@ func New(db *sqlx.DB) Repository {
 	return &repo{db: db}
 }
 
-func (r *repo) GetFeatureValues(ctx context.Context, customerID, productID int64) ([]FeatureValue, error) {
+func (r *repo) GetFeatureValues(ctx context.Context, licenseID int64) ([]FeatureValue, error) {
 	var out []FeatureValue
 	err := r.db.SelectContext(ctx, &out, getFeatureValuesSQL,
-		customerID, productID,
+		licenseID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("get feature values: %w", err)
@@ -31,10 +38,48 @@ func (r *repo) GetFeatureValues(ctx context.Context, customerID, productID int64
 	return out, nil
 }
 
+func (r *repo) GetPlanValues(ctx context.Context, licenseID int64) ([]FeatureValue, error) {
+	var out []FeatureValue
+	err := r.db.SelectContext(ctx, &out, getPlanValuesSQL, licenseID)
+	if err != nil {
+		return nil, fmt.Errorf("get plan values: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetFeatureValuesTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]FeatureValue, error) {
+	var out []FeatureValue
+	err := tx.SelectContext(ctx, &out, getFeatureValuesSQL, licenseID)
+	if err != nil {
+		return nil, fmt.Errorf("get feature values: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetPlanValuesTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]FeatureValue, error) {
+	var out []FeatureValue
+	err := tx.SelectContext(ctx, &out, getPlanValuesSQL, licenseID)
+	if err != nil {
+		return nil, fmt.Errorf("get plan values: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetRules(ctx context.Context, featureID int64) (*Rules, error) {
+	var rules Rules
+	err := r.db.GetContext(ctx, &rules, getFeatureRulesSQL, featureID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("feature not found (%d)", featureID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get feature rules: %w", err)
+	}
+	return &rules, nil
+}
+
 func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, fv *FeatureValue) error {
 	_, err := tx.ExecContext(ctx, updateFeatureValueSQL,
-		fv.CustomerID,
-		fv.ProductID,
+		fv.LicenseID,
 		fv.FeatureID,
 		fv.FeatureValue,
 	)
@@ -43,3 +88,11 @@ func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, fv *FeatureValue) error
 	}
 	return nil
 }
+
+func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, licenseID, featureID int64) error {
+	_, err := tx.ExecContext(ctx, deleteFeatureValueSQL, licenseID, featureID)
+	if err != nil {
+		return fmt.Errorf("delete feature value: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package featurevalue
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rules are the constraints a feature definition puts on its values. The same
+// rules check a feature's default value and every license override of it.
+type Rules struct {
+	FeatureType   int    `db:"feature_type"`
+	AllowedValues string `db:"allowed_values"`
+	MinValue      *int64 `db:"min_value"`
+	MaxValue      *int64 `db:"max_value"`
+	MaxLength     int    `db:"max_length"`
+}
+
+// FieldError is a validation failure of one form/request field, named like the
+// database column (e.g. "default_value"). It wraps ErrInvalidValue.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string { return e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+func fieldError(field, format string, args ...any) *FieldError {
+	return &FieldError{Field: field, Err: fmt.Errorf("%w: "+format, append([]any{ErrInvalidValue}, args...)...)}
+}
+
+// AllowedList returns the pipe-separated allowed values of a values feature.
+func (r Rules) AllowedList() []string {
+	var out []string
+	for _, v := range strings.Split(r.AllowedValues, "|") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Check validates the rules themselves, as entered on a feature definition.
+func (r Rules) Check() error {
+	if !ValidType(r.FeatureType) {
+		return fieldError("feature_type", "unknown feature type %d", r.FeatureType)
+	}
+	if r.FeatureType == TypeValues && len(r.AllowedList()) == 0 {
+		return fieldError("allowed_values", "values features need at least one allowed value")
+	}
+	if r.FeatureType != TypeInteger {
+		if r.MinValue != nil {
+			return fieldError("min_value", "only integer features have a minimum")
+		}
+		if r.MaxValue != nil {
+			return fieldError("max_value", "only integer features have a maximum")
+		}
+	}
+	if r.MinValue != nil && r.MaxValue != nil && *r.MinValue > *r.MaxValue {
+		return fieldError("max_value", "maximum %d is below the minimum %d", *r.MaxValue, *r.MinValue)
+	}
+	if r.MaxLength < 0 {
+		return fieldError("max_length", "maximum length cannot be negative")
+	}
+	if r.MaxLength > 0 && r.FeatureType != TypeString {
+		return fieldError("max_length", "only string features have a maximum length")
+	}
+	return nil
+}
+
+// Validate checks value against the rules and returns its canonical form (see
+// Normalize). Integers must lie within MinValue..MaxValue, strings must fit
+// MaxLength and each pipe-separated part of a values feature must be one of the
+// allowed values. Errors are returned for the given field.
+func (r Rules) Validate(field, value string) (string, error) {
+	v, err := Normalize(r.FeatureType, value)
+	if err != nil {
+		return "", &FieldError{Field: field, Err: err}
+	}
+
+	switch r.FeatureType {
+	case TypeInteger:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		if r.MinValue != nil && n < *r.MinValue {
+			return "", fieldError(field, "%d is below the minimum of %d", n, *r.MinValue)
+		}
+		if r.MaxValue != nil && n > *r.MaxValue {
+			return "", fieldError(field, "%d is above the maximum of %d", n, *r.MaxValue)
+		}
+	case TypeString:
+		if r.MaxLength > 0 && utf8.RuneCountInString(v) > r.MaxLength {
+			return "", fieldError(field, "longer than %d characters", r.MaxLength)
+		}
+	case TypeValues:
+		allowed := r.AllowedList()
+		for _, part := range strings.Split(v, "|") {
+			if !slices.Contains(allowed, strings.TrimSpace(part)) {
+				return "", fieldError(field, "%q is not one of the allowed values (%s)", part, strings.Join(allowed, ", "))
+			}
+		}
+	}
+	return v, nil
+}
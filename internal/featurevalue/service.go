@@ -4,11 +4,14 @@ import (
 	"context"
 
 	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/registration"
 )
 
 type Service struct {
-	repo Repository
-	db   *sqlx.DB
+	repo     Repository
+	db       *sqlx.DB
+	reissuer registration.Reissuer
 }
 
 func NewService(db *sqlx.DB) *Service {
@@ -18,6 +21,12 @@ func NewService(db *sqlx.DB) *Service {
 	}
 }
 
+// SetReissuer sets what recomputes the registrations of a license when its
+// feature values change (nil, the default, leaves registrations as they are)
+func (s *Service) SetReissuer(r registration.Reissuer) {
+	s.reissuer = r
+}
+
 func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -30,12 +39,53 @@ func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
 	return tx.Commit()
 }
 
-func (s *Service) GetFeatureValues(ctx context.Context, customerID, productID int64) ([]FeatureValue, error) {
-	return s.repo.GetFeatureValues(ctx, customerID, productID)
+func (s *Service) GetFeatureValues(ctx context.Context, licenseID int64) ([]FeatureValue, error) {
+	return s.repo.GetFeatureValues(ctx, licenseID)
+}
+
+// GetPlanValues returns the feature values of the license's plan (none if the
+// license is not on a plan). They apply before the license's own values.
+func (s *Service) GetPlanValues(ctx context.Context, licenseID int64) ([]FeatureValue, error) {
+	return s.repo.GetPlanValues(ctx, licenseID)
+}
+
+// GetFeatureValuesTx returns the license's own values as changed so far in the caller's transaction
+func (s *Service) GetFeatureValuesTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]FeatureValue, error) {
+	return s.repo.GetFeatureValuesTx(ctx, tx, licenseID)
+}
+
+// GetPlanValuesTx returns the license's plan values as changed so far in the caller's transaction
+func (s *Service) GetPlanValuesTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]FeatureValue, error) {
+	return s.repo.GetPlanValuesTx(ctx, tx, licenseID)
 }
 
+// Update sets a license's value for a feature after validating it against the
+// feature's rules; the value is stored in its canonical form (see Normalize).
+// An empty value for a feature that is not a string feature removes the
+// override, so the license falls back to the feature's default.
 func (s *Service) Update(ctx context.Context, fv *FeatureValue) error {
 	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
-		return s.repo.Update(ctx, tx, fv)
+		return s.Set(ctx, tx, fv)
 	})
 }
+
+// Set sets a license's value for a feature like Update, in the caller's transaction
+func (s *Service) Set(ctx context.Context, tx *sqlx.Tx, fv *FeatureValue) error {
+	rules, err := s.repo.GetRules(ctx, fv.FeatureID)
+	if err != nil {
+		return err
+	}
+
+	if fv.FeatureValue == "" && rules.FeatureType != TypeString {
+		err = s.repo.Delete(ctx, tx, fv.LicenseID, fv.FeatureID)
+	} else {
+		if fv.FeatureValue, err = rules.Validate("feature_value", fv.FeatureValue); err != nil {
+			return err
+		}
+		err = s.repo.Update(ctx, tx, fv)
+	}
+	if err != nil || s.reissuer == nil {
+		return err
+	}
+	return s.reissuer.ReissueLicense(ctx, tx, fv.LicenseID)
+}
@@ -2,6 +2,7 @@ package featurevalue_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -42,7 +43,7 @@ func TestFeatureValueLifecycle(t *testing.T) {
 	}
 
 	// Create license (required for foreign key)
-	_, err = licenseSvc.Create(ctx, &license.License{
+	lic, err := licenseSvc.Create(ctx, &license.License{
 		CustomerID:          c.CustomerID,
 		ProductID:           p.ProductID,
 		LicenseCount:        1,
@@ -77,17 +78,17 @@ func TestFeatureValueLifecycle(t *testing.T) {
 
 	// Insert initial feature value overrides
 	initial := []featurevalue.FeatureValue{
-		{CustomerID: c.CustomerID, ProductID: p.ProductID, FeatureID: feat1.FeatureID, FeatureValue: "A"},
-		{CustomerID: c.CustomerID, ProductID: p.ProductID, FeatureID: feat2.FeatureID, FeatureValue: "B"},
+		{LicenseID: lic.LicenseID, FeatureID: feat1.FeatureID, FeatureValue: "A"},
+		{LicenseID: lic.LicenseID, FeatureID: feat2.FeatureID, FeatureValue: "B"},
 	}
 
 	tx := db.MustBeginTx(ctx, nil)
 	for _, fv := range initial {
 		_, err := tx.Exec(`
             INSERT INTO license_feature
-                (customer_id, product_id, feature_id, feature_value)
-            VALUES (?, ?, ?, ?)
-        `, fv.CustomerID, fv.ProductID, fv.FeatureID, fv.FeatureValue)
+                (license_id, feature_id, feature_value)
+            VALUES (?, ?, ?)
+        `, fv.LicenseID, fv.FeatureID, fv.FeatureValue)
 		if err != nil {
 			tx.Rollback()
 			t.Fatalf("insert feature value: %v", err)
@@ -98,7 +99,7 @@ func TestFeatureValueLifecycle(t *testing.T) {
 	}
 
 	// Verify GetFeatureValues returns both rows
-	values, err := fvSvc.GetFeatureValues(ctx, c.CustomerID, p.ProductID)
+	values, err := fvSvc.GetFeatureValues(ctx, lic.LicenseID)
 	if err != nil {
 		t.Fatalf("GetFeatureValues: %v", err)
 	}
@@ -109,8 +110,7 @@ func TestFeatureValueLifecycle(t *testing.T) {
 
 	// Update one feature value
 	updated := &featurevalue.FeatureValue{
-		CustomerID:   c.CustomerID,
-		ProductID:    p.ProductID,
+		LicenseID:    lic.LicenseID,
 		FeatureID:    feat1.FeatureID,
 		FeatureValue: "Updated",
 	}
@@ -120,7 +120,7 @@ func TestFeatureValueLifecycle(t *testing.T) {
 	}
 
 	// Verify update persisted
-	values, err = fvSvc.GetFeatureValues(ctx, c.CustomerID, p.ProductID)
+	values, err = fvSvc.GetFeatureValues(ctx, lic.LicenseID)
 	if err != nil {
 		t.Fatalf("GetFeatureValues: %v", err)
 	}
@@ -171,7 +171,7 @@ func TestFeatureValueUpsert(t *testing.T) {
 	}
 
 	// Create license (required for foreign key)
-	_, err = licenseSvc.Create(ctx, &license.License{
+	lic, err := licenseSvc.Create(ctx, &license.License{
 		CustomerID:          c.CustomerID,
 		ProductID:           p.ProductID,
 		LicenseCount:        1,
@@ -195,7 +195,7 @@ func TestFeatureValueUpsert(t *testing.T) {
 	}
 
 	// Verify no override exists yet
-	values, err := fvSvc.GetFeatureValues(ctx, c.CustomerID, p.ProductID)
+	values, err := fvSvc.GetFeatureValues(ctx, lic.LicenseID)
 	if err != nil {
 		t.Fatalf("GetFeatureValues: %v", err)
 	}
@@ -205,8 +205,7 @@ func TestFeatureValueUpsert(t *testing.T) {
 
 	// Test INSERT path: Update when no row exists should create one
 	override := &featurevalue.FeatureValue{
-		CustomerID:   c.CustomerID,
-		ProductID:    p.ProductID,
+		LicenseID:    lic.LicenseID,
 		FeatureID:    feat.FeatureID,
 		FeatureValue: "50",
 	}
@@ -215,7 +214,7 @@ func TestFeatureValueUpsert(t *testing.T) {
 	}
 
 	// Verify the override was created
-	values, err = fvSvc.GetFeatureValues(ctx, c.CustomerID, p.ProductID)
+	values, err = fvSvc.GetFeatureValues(ctx, lic.LicenseID)
 	if err != nil {
 		t.Fatalf("GetFeatureValues after insert: %v", err)
 	}
@@ -233,7 +232,7 @@ func TestFeatureValueUpsert(t *testing.T) {
 	}
 
 	// Verify the override was updated
-	values, err = fvSvc.GetFeatureValues(ctx, c.CustomerID, p.ProductID)
+	values, err = fvSvc.GetFeatureValues(ctx, lic.LicenseID)
 	if err != nil {
 		t.Fatalf("GetFeatureValues after update: %v", err)
 	}
@@ -244,3 +243,187 @@ func TestFeatureValueUpsert(t *testing.T) {
 		t.Fatalf("expected value '100', got '%s'", values[0].FeatureValue)
 	}
 }
+
+func TestFeatureValueTypedValidation(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	featSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+
+	c, err := custSvc.Create(ctx, &customer.Customer{CustomerName: "Acme"})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	p, err := prodSvc.Create(ctx, &product.Product{ProductName: "Widget", ProductGUID: "typed-guid"})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	lic, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          c.CustomerID,
+		ProductID:           p.ProductID,
+		LicenseCount:        1,
+		StartDate:           "2024-01-01",
+		ExpirationDate:      "2099-12-31",
+		MaintExpirationDate: "2099-12-31",
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		featureType int
+		def         string
+		value       string
+		want        string // canonical stored value; empty means the update must fail
+	}{
+		{"integer", featurevalue.TypeInteger, "10", " 042 ", "42"},
+		{"integer rejects text", featurevalue.TypeInteger, "10", "lots", ""},
+		{"boolean", featurevalue.TypeBoolean, "false", "True", "true"},
+		{"boolean rejects text", featurevalue.TypeBoolean, "false", "yes", ""},
+		{"date", featurevalue.TypeDate, "2030-01-01", "2031-06-30", "2031-06-30"},
+		{"date rejects other formats", featurevalue.TypeDate, "2030-01-01", "06/30/2031", ""},
+		{"string is stored as given", featurevalue.TypeString, "", " Gold ", " Gold "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := featSvc.Create(ctx, &feature.Feature{
+				ProductID:    p.ProductID,
+				FeatureName:  tt.name,
+				FeatureType:  tt.featureType,
+				DefaultValue: tt.def,
+			})
+			if err != nil {
+				t.Fatalf("create feature: %v", err)
+			}
+
+			fv := &featurevalue.FeatureValue{LicenseID: lic.LicenseID, FeatureID: f.FeatureID, FeatureValue: tt.value}
+			err = fvSvc.Update(ctx, fv)
+			if tt.want == "" {
+				if !errors.Is(err, featurevalue.ErrInvalidValue) {
+					t.Fatalf("expected ErrInvalidValue, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			values, err := fvSvc.GetFeatureValues(ctx, lic.LicenseID)
+			if err != nil {
+				t.Fatalf("GetFeatureValues: %v", err)
+			}
+			for _, v := range values {
+				if v.FeatureID == f.FeatureID && v.FeatureValue != tt.want {
+					t.Errorf("stored %q, want %q", v.FeatureValue, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFeatureValueRules(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licenseSvc := license.NewService(db)
+	featSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+
+	c, err := custSvc.Create(ctx, &customer.Customer{CustomerName: "Acme"})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	p, err := prodSvc.Create(ctx, &product.Product{ProductName: "Widget", ProductGUID: "rules-guid"})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	lic, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID:          c.CustomerID,
+		ProductID:           p.ProductID,
+		LicenseCount:        1,
+		StartDate:           "2024-01-01",
+		ExpirationDate:      "2099-12-31",
+		MaintExpirationDate: "2099-12-31",
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	one, hundred := int64(1), int64(100)
+	seats, err := featSvc.Create(ctx, &feature.Feature{
+		ProductID: p.ProductID, FeatureName: "Seats", FeatureType: featurevalue.TypeInteger,
+		DefaultValue: "10", MinValue: &one, MaxValue: &hundred,
+	})
+	if err != nil {
+		t.Fatalf("create Seats: %v", err)
+	}
+	tier, err := featSvc.Create(ctx, &feature.Feature{
+		ProductID: p.ProductID, FeatureName: "Tier", FeatureType: featurevalue.TypeString,
+		DefaultValue: "Gold", MaxLength: 8,
+	})
+	if err != nil {
+		t.Fatalf("create Tier: %v", err)
+	}
+	formats, err := featSvc.Create(ctx, &feature.Feature{
+		ProductID: p.ProductID, FeatureName: "Formats", FeatureType: featurevalue.TypeValues,
+		AllowedValues: "CSV|JSON|XML", DefaultValue: "CSV",
+	})
+	if err != nil {
+		t.Fatalf("create Formats: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		feature int64
+		value   string
+		ok      bool
+	}{
+		{"integer within range", seats.FeatureID, "100", true},
+		{"integer below minimum", seats.FeatureID, "0", false},
+		{"integer above maximum", seats.FeatureID, "101", false},
+		{"integer not a number", seats.FeatureID, "abc", false},
+		{"string within length", tier.FeatureID, "Platinum", true},
+		{"string too long", tier.FeatureID, "Enterprise", false},
+		{"allowed value", formats.FeatureID, "JSON", true},
+		{"several allowed values", formats.FeatureID, "CSV|XML", true},
+		{"value not allowed", formats.FeatureID, "PDF", false},
+		{"one of several not allowed", formats.FeatureID, "CSV|PDF", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fvSvc.Update(ctx, &featurevalue.FeatureValue{LicenseID: lic.LicenseID, FeatureID: tt.feature, FeatureValue: tt.value})
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("expected %q to be accepted: %v", tt.value, err)
+				}
+				return
+			}
+			var fe *featurevalue.FieldError
+			if !errors.As(err, &fe) || fe.Field != "feature_value" {
+				t.Fatalf("expected a feature_value field error for %q, got %v", tt.value, err)
+			}
+		})
+	}
+
+	// An empty value removes the override of a non-string feature
+	if err := fvSvc.Update(ctx, &featurevalue.FeatureValue{LicenseID: lic.LicenseID, FeatureID: seats.FeatureID}); err != nil {
+		t.Fatalf("clear Seats: %v", err)
+	}
+	values, err := fvSvc.GetFeatureValues(ctx, lic.LicenseID)
+	if err != nil {
+		t.Fatalf("GetFeatureValues: %v", err)
+	}
+	for _, v := range values {
+		if v.FeatureID == seats.FeatureID {
+			t.Errorf("expected Seats override to be removed, got %q", v.FeatureValue)
+		}
+	}
+}
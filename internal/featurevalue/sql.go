@@ -2,17 +2,44 @@ package featurevalue
 
 const getFeatureValuesSQL = `
 SELECT
-    customer_id,
-    product_id,
+    license_id,
     feature_id,
     feature_value
 FROM license_feature
-WHERE customer_id = ? AND product_id = ?
+WHERE license_id = ?
 ORDER BY feature_id
 `
 
+// getPlanValuesSQL returns the feature values of the plan a license is on
+const getPlanValuesSQL = `
+SELECT
+    l.license_id,
+    pf.feature_id,
+    pf.feature_value
+FROM license l
+JOIN plan_feature pf ON pf.plan_id = l.plan_id
+WHERE l.license_id = ?
+ORDER BY pf.feature_id
+`
+
+const getFeatureRulesSQL = `
+SELECT
+    feature_type,
+    COALESCE(allowed_values, '') AS allowed_values,
+    min_value,
+    max_value,
+    max_length
+FROM feature
+WHERE feature_id = ?
+`
+
 const updateFeatureValueSQL = `
-INSERT INTO license_feature (customer_id, product_id, feature_id, feature_value)
-VALUES (?, ?, ?, ?)
-ON CONFLICT (customer_id, product_id, feature_id) DO UPDATE SET feature_value = excluded.feature_value
+INSERT INTO license_feature (license_id, feature_id, feature_value)
+VALUES (?, ?, ?)
+ON CONFLICT (license_id, feature_id) DO UPDATE SET feature_value = excluded.feature_value
+`
+
+const deleteFeatureValueSQL = `
+DELETE FROM license_feature
+WHERE license_id = ? AND feature_id = ?
 `
@@ -0,0 +1,74 @@
+package featurevalue
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Feature types (feature.feature_type). They live here rather than in the feature
+// package so both feature defaults and license values are validated the same way.
+const (
+	TypeInteger = 0
+	TypeString  = 1
+	TypeValues  = 2
+	TypeBoolean = 3
+	TypeDate    = 4
+)
+
+var ErrInvalidValue = errors.New("invalid feature value")
+
+// ValidType reports whether featureType is a known feature type.
+func ValidType(featureType int) bool {
+	return featureType >= TypeInteger && featureType <= TypeDate
+}
+
+// Normalize validates value against featureType and returns its canonical string
+// form, which is what gets stored and hashed: integers in decimal, booleans as
+// "true"/"false" and dates as YYYY-MM-DD. String and values features are stored
+// as given.
+func Normalize(featureType int, value string) (string, error) {
+	v := strings.TrimSpace(value)
+
+	switch featureType {
+	case TypeString, TypeValues:
+		return value, nil
+	case TypeInteger:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not an integer", ErrInvalidValue, value)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case TypeBoolean:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not a boolean", ErrInvalidValue, value)
+		}
+		return strconv.FormatBool(b), nil
+	case TypeDate:
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not a date (YYYY-MM-DD)", ErrInvalidValue, value)
+		}
+		return d.Format("2006-01-02"), nil
+	default:
+		return "", fmt.Errorf("%w: unknown feature type %d", ErrInvalidValue, featureType)
+	}
+}
+
+// Typed converts a stored value to the JSON value clients receive: booleans
+// become true/false and every other type stays a string, as integer, string
+// and values features always have been (clients format the value with %v to
+// hash it, and a decoded JSON number can print in exponent form). Booleans
+// that don't parse (rows written before validation existed) are returned
+// unchanged.
+func Typed(featureType int, value string) any {
+	if featureType == TypeBoolean {
+		if b, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+			return b
+		}
+	}
+	return value
+}
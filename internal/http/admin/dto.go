@@ -1,5 +1,12 @@
 package admin
 
+import (
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/bulk"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/reseller"
+)
+
 // -------------------------
 // Customer DTOs
 // -------------------------
@@ -10,6 +17,7 @@ type CreateCustomerRequest struct {
 	Phone        string `json:"phone"`
 	Email        string `json:"email"`
 	Notes        string `json:"notes"`
+	ResellerID   *int64 `json:"resellerId"` // ignored for reseller API keys (always their own)
 }
 
 type UpdateCustomerRequest struct {
@@ -20,6 +28,31 @@ type UpdateCustomerRequest struct {
 	Notes        string `json:"notes"`
 }
 
+// SetCustomerResellerRequest assigns a customer to a reseller (null for a direct customer)
+type SetCustomerResellerRequest struct {
+	ResellerID *int64 `json:"resellerId"`
+}
+
+// CreateContactRequest adds a contact to a customer. Roles are any of
+// "billing", "technical" and "purchasing".
+type CreateContactRequest struct {
+	ContactName string   `json:"contactName"`
+	Phone       string   `json:"phone"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+	IsPrimary   bool     `json:"isPrimary"`
+	Notes       string   `json:"notes"`
+}
+
+type UpdateContactRequest struct {
+	ContactName string   `json:"contactName"`
+	Phone       string   `json:"phone"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+	IsPrimary   bool     `json:"isPrimary"`
+	Notes       string   `json:"notes"`
+}
+
 // -------------------------
 // Product DTOs
 // -------------------------
@@ -64,6 +97,66 @@ type UpdateLicenseRequest struct {
 	MaxProductVersion   string `json:"maxProductVersion"`
 }
 
+// SetLicenseStatusRequest sets a license to active, suspended or cancelled
+type SetLicenseStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// RotateKeyRequest issues a new license key; the old key stays valid for GraceHours
+type RotateKeyRequest struct {
+	GraceHours int    `json:"graceHours"`
+	Reason     string `json:"reason"`
+}
+
+// SetKeyStatusRequest sets a license key to active, suspended or revoked
+type SetKeyStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// -------------------------
+// Bundle DTOs
+// -------------------------
+
+type CreateBundleRequest struct {
+	BundleName  string  `json:"bundleName"`
+	Description string  `json:"description"`
+	ProductIDs  []int64 `json:"productIds"`
+}
+
+// UpdateBundleRequest renames a bundle and sets its products. The products
+// cannot change while the bundle is licensed to customers.
+type UpdateBundleRequest struct {
+	BundleName  string  `json:"bundleName"`
+	Description string  `json:"description"`
+	ProductIDs  []int64 `json:"productIds"`
+}
+
+// CreateCustomerBundleRequest licenses a bundle to a customer. Every product
+// of the bundle gets a license with these seats and dates.
+type CreateCustomerBundleRequest struct {
+	BundleID            int64  `json:"bundleId"`
+	LicenseCount        int    `json:"licenseCount"`
+	IsSubscription      bool   `json:"isSubscription"`
+	LicenseTerm         int    `json:"licenseTerm"`
+	StartDate           string `json:"startDate"`
+	ExpirationDate      string `json:"expirationDate"`
+	MaintExpirationDate string `json:"maintExpirationDate"`
+	MaxProductVersion   string `json:"maxProductVersion"`
+}
+
+// UpdateCustomerBundleRequest renews a customer's bundle: the seats and dates
+// apply to every license of the bundle
+type UpdateCustomerBundleRequest struct {
+	LicenseCount        int    `json:"licenseCount"`
+	IsSubscription      bool   `json:"isSubscription"`
+	LicenseTerm         int    `json:"licenseTerm"`
+	StartDate           string `json:"startDate"`
+	ExpirationDate      string `json:"expirationDate"`
+	MaintExpirationDate string `json:"maintExpirationDate"`
+}
+
 // -------------------------
 // Feature Definition DTOs
 // -------------------------
@@ -73,19 +166,154 @@ type CreateFeatureRequest struct {
 	FeatureType   string `json:"featureType"`
 	AllowedValues string `json:"allowedValues"`
 	DefaultValue  string `json:"defaultValue"`
+	MinValue      *int64 `json:"minValue"`
+	MaxValue      *int64 `json:"maxValue"`
+	MaxLength     int    `json:"maxLength"`
+	Status        string `json:"status"` // active (default), deprecated or hidden
 }
 
+// UpdateFeatureRequest updates a feature definition. A new name keeps the old
+// one as an alias for registrations activated before the change; an empty
+// status keeps the current one.
 type UpdateFeatureRequest struct {
 	FeatureName   string `json:"featureName"`
 	FeatureType   string `json:"featureType"`
 	AllowedValues string `json:"allowedValues"`
 	DefaultValue  string `json:"defaultValue"`
+	MinValue      *int64 `json:"minValue"`
+	MaxValue      *int64 `json:"maxValue"`
+	MaxLength     int    `json:"maxLength"`
+	Status        string `json:"status"`
+}
+
+// -------------------------
+// Plan DTOs
+// -------------------------
+
+// PlanValueRequest is a plan's value for one feature
+type PlanValueRequest struct {
+	FeatureID int64  `json:"featureId"`
+	Value     string `json:"value"`
+}
+
+type CreatePlanRequest struct {
+	PlanName string             `json:"planName"`
+	Values   []PlanValueRequest `json:"values"`
+}
+
+// UpdatePlanRequest renames a plan and sets the given feature values; features
+// not listed keep their plan value
+type UpdatePlanRequest struct {
+	PlanName string             `json:"planName"`
+	Values   []PlanValueRequest `json:"values"`
+}
+
+// SetPlanValueRequest sets a plan's value for a feature. An empty value
+// removes the feature from the plan.
+type SetPlanValueRequest struct {
+	Value string `json:"value"`
+}
+
+// AssignPlanRequest puts licenses on a plan; a null PlanID takes them off
+// their plan
+type AssignPlanRequest struct {
+	PlanID     *int64  `json:"planId"`
+	LicenseIDs []int64 `json:"licenseIds"`
+}
+
+// -------------------------
+// Bulk Operation DTOs
+// -------------------------
+
+// BulkRequest selects licenses of a product and the change to preview or
+// apply to all of them
+type BulkRequest struct {
+	Selection bulk.Selection `json:"selection"`
+	Change    bulk.Change    `json:"change"`
 }
 
 // -------------------------
-// Feature Value DTOs (customer-specific)
+// Feature Value DTOs (license-specific)
 // -------------------------
 
-type UpdateProductFeatureRequest struct {
+type UpdateLicenseFeatureRequest struct {
 	Value string `json:"value"`
 }
+
+// -------------------------
+// Machine DTOs
+// -------------------------
+
+// MachineRegistration is a machine with its registration of a license's
+// product. The fields are untagged so they read like the machine's.
+type MachineRegistration struct {
+	machine.Machine
+	RegistrationHash      string
+	ExpirationDate        string
+	FirstRegistrationDate string
+	LastRegistrationDate  string
+	InstalledVersion      string
+	LastSeenAt            string // last activation or check-in (UTC); "" = not seen since tracking began
+	ClientOS              string
+	ClientHostname        string
+	ClientIP              string
+}
+
+// TransferMachineRequest moves a machine and its registrations to another customer
+type TransferMachineRequest struct {
+	ToCustomerID int64  `json:"toCustomerId"`
+	Reason       string `json:"reason"`
+}
+
+// ManualRegistrationRequest registers a customer's machine for a product
+// without the license key (offline registration)
+type ManualRegistrationRequest struct {
+	CustomerID  int64  `json:"customerId"`
+	ProductID   int64  `json:"productId"`
+	MachineCode string `json:"machineCode"`
+	UserName    string `json:"userName"`
+}
+
+// RegistrationFile is a signed registration (the activation response) with
+// the name it is downloaded as
+type RegistrationFile struct {
+	Name         string
+	Registration *activation.Response
+}
+
+// RegistrationExport is the registration files of a license with the name of
+// the archive they are downloaded in
+type RegistrationExport struct {
+	Name  string
+	Files []RegistrationFile
+}
+
+// -------------------------
+// Reseller DTOs
+// -------------------------
+
+type CreateResellerRequest struct {
+	ResellerName   string `json:"resellerName"`
+	Email          string `json:"email"`
+	SeatAllocation int    `json:"seatAllocation"`
+	Notes          string `json:"notes"`
+}
+
+type UpdateResellerRequest struct {
+	ResellerName   string `json:"resellerName"`
+	Email          string `json:"email"`
+	SeatAllocation int    `json:"seatAllocation"`
+	Notes          string `json:"notes"`
+}
+
+// CreateResellerResponse returns a new reseller with its API key. The key is
+// only stored as a hash and cannot be retrieved later.
+type CreateResellerResponse struct {
+	*reseller.Reseller
+	APIKey string `json:"apiKey"`
+}
+
+// RotateResellerKeyResponse returns a reseller's new API key
+type RotateResellerKeyResponse struct {
+	APIKey string `json:"apiKey"`
+}
@@ -1,13 +1,31 @@
 package admin
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"winsbygroup.com/regserver/internal/activation"
 	"winsbygroup.com/regserver/internal/backup"
+	"winsbygroup.com/regserver/internal/bulk"
+	"winsbygroup.com/regserver/internal/bundle"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/inactivity"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/plan"
+	"winsbygroup.com/regserver/internal/reseller"
+	"winsbygroup.com/regserver/internal/sqlite"
 )
 
 type Handler struct {
@@ -24,7 +42,7 @@ func NewHandler(svc *Service, backupSvc *backup.Service) *Handler {
 func (h *Handler) GetCustomers(c echo.Context) error {
 	out, err := h.svc.GetCustomers(c.Request().Context())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
@@ -33,7 +51,7 @@ func (h *Handler) GetCustomer(c echo.Context) error {
 	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 	out, err := h.svc.GetCustomer(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
@@ -45,7 +63,7 @@ func (h *Handler) CreateCustomer(c echo.Context) error {
 	}
 	out, err := h.svc.CreateCustomer(c.Request().Context(), &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusCreated, out)
 }
@@ -60,7 +78,7 @@ func (h *Handler) UpdateCustomer(c echo.Context) error {
 
 	err := h.svc.UpdateCustomer(c.Request().Context(), id, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 
 	return c.NoContent(http.StatusNoContent)
@@ -70,7 +88,23 @@ func (h *Handler) DeleteCustomer(c echo.Context) error {
 	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 	err := h.svc.DeleteCustomer(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) SetCustomerReseller(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req SetCustomerResellerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	err := h.svc.SetCustomerReseller(c.Request().Context(), id, &req)
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return errorJSON(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
@@ -79,17 +113,105 @@ func (h *Handler) CustomerExists(c echo.Context) error {
 	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 	exists, err := h.svc.CustomerExists(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, map[string]bool{"exists": exists})
 }
 
+// Customer Contacts
+
+func (h *Handler) GetContacts(c echo.Context) error {
+	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+	out, err := h.svc.GetContacts(c.Request().Context(), custID)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) CreateContact(c echo.Context) error {
+	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+	var req CreateContactRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.CreateContact(c.Request().Context(), custID, &req)
+	if err != nil {
+		return contactError(c, err)
+	}
+	return c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) UpdateContact(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req UpdateContactRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.UpdateContact(c.Request().Context(), id, &req); err != nil {
+		return contactError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteContact(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err := h.svc.DeleteContact(c.Request().Context(), id); err != nil {
+		return contactError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func contactError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, customer.ErrInvalidRole), errors.Is(err, customer.ErrContactRequired):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return errorJSON(c, err)
+}
+
+// requestFields maps validation error fields (column names) to the JSON request
+// fields they came from.
+var requestFields = map[string]string{
+	"feature_name":   "featureName",
+	"feature_type":   "featureType",
+	"allowed_values": "allowedValues",
+	"default_value":  "defaultValue",
+	"min_value":      "minValue",
+	"max_value":      "maxValue",
+	"max_length":     "maxLength",
+	"feature_value":  "value",
+	"feature_status": "status",
+}
+
+// errorJSON writes a service error. Reseller scope errors are 403, seat
+// allocation errors 409, invalid feature values 400 (naming the offending
+// request field) and anything else 500.
+func errorJSON(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, reseller.ErrOutOfScope), errors.Is(err, reseller.ErrAdminOnly):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, reseller.ErrAllocationExceeded), errors.Is(err, feature.ErrFeatureInUse):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, featurevalue.ErrInvalidValue):
+		body := map[string]string{"error": err.Error()}
+		var fe *featurevalue.FieldError
+		if errors.As(err, &fe) {
+			body["field"] = requestFields[fe.Field]
+		}
+		return c.JSON(http.StatusBadRequest, body)
+	}
+	return c.JSON(http.StatusInternalServerError, err)
+}
+
 // Products
 
 func (h *Handler) GetProducts(c echo.Context) error {
 	out, err := h.svc.GetProducts(c.Request().Context())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
@@ -98,7 +220,7 @@ func (h *Handler) GetProduct(c echo.Context) error {
 	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 	out, err := h.svc.GetProduct(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
@@ -110,7 +232,7 @@ func (h *Handler) CreateProduct(c echo.Context) error {
 	}
 	out, err := h.svc.CreateProduct(c.Request().Context(), &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusCreated, out)
 }
@@ -123,7 +245,7 @@ func (h *Handler) UpdateProduct(c echo.Context) error {
 	}
 	err := h.svc.UpdateProduct(c.Request().Context(), id, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
@@ -132,27 +254,30 @@ func (h *Handler) DeleteProduct(c echo.Context) error {
 	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 	err := h.svc.DeleteProduct(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
-// Customer Products (Licenses)
+// Licenses
 
 func (h *Handler) GetLicenses(c echo.Context) error {
 	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
 	out, err := h.svc.GetLicenses(c.Request().Context(), custID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
 
-func (h *Handler) GetUnlicensedProducts(c echo.Context) error {
-	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
-	out, err := h.svc.GetUnlicensedProducts(c.Request().Context(), custID)
+func (h *Handler) GetLicense(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetLicense(c.Request().Context(), id)
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
@@ -165,42 +290,251 @@ func (h *Handler) CreateLicense(c echo.Context) error {
 	}
 	out, err := h.svc.CreateLicense(c.Request().Context(), custID, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusCreated, out)
 }
 
 func (h *Handler) UpdateLicense(c echo.Context) error {
-	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
-	prodID, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 	var req UpdateLicenseRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, err)
 	}
-	err := h.svc.UpdateLicense(c.Request().Context(), custID, prodID, &req)
+	err := h.svc.UpdateLicense(c.Request().Context(), id, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
 func (h *Handler) DeleteLicense(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	err := h.svc.DeleteLicense(c.Request().Context(), id)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) SetLicenseStatus(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req SetLicenseStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	err := h.svc.SetLicenseStatus(c.Request().Context(), id, &req)
+	if errors.Is(err, license.ErrInvalidStatus) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) RotateLicenseKey(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req RotateKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.RotateLicenseKey(c.Request().Context(), id, &req)
+	if errors.Is(err, license.ErrNegativeGracePeriod) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) SetLicenseKeyStatus(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req SetKeyStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	err := h.svc.SetLicenseKeyStatus(c.Request().Context(), id, &req)
+	if errors.Is(err, license.ErrInvalidKeyStatus) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) GetLicenseKeyHistory(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetLicenseKeyHistory(c.Request().Context(), id)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// Bundles
+
+func (h *Handler) GetBundles(c echo.Context) error {
+	out, err := h.svc.GetBundles(c.Request().Context())
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) GetBundle(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetBundle(c.Request().Context(), id)
+	if err != nil {
+		return bundleError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) CreateBundle(c echo.Context) error {
+	var req CreateBundleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.CreateBundle(c.Request().Context(), &req)
+	if err != nil {
+		return bundleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) UpdateBundle(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req UpdateBundleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.UpdateBundle(c.Request().Context(), id, &req); err != nil {
+		return bundleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteBundle(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err := h.svc.DeleteBundle(c.Request().Context(), id); err != nil {
+		return bundleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Customer Bundles
+
+func (h *Handler) GetCustomerBundles(c echo.Context) error {
 	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
-	prodID, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
-	err := h.svc.DeleteLicense(c.Request().Context(), custID, prodID)
+	out, err := h.svc.GetCustomerBundles(c.Request().Context(), custID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) CreateCustomerBundle(c echo.Context) error {
+	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+	var req CreateCustomerBundleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.CreateCustomerBundle(c.Request().Context(), custID, &req)
+	if err != nil {
+		return bundleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) UpdateCustomerBundle(c echo.Context) error {
+	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+	bundleID, _ := strconv.ParseInt(c.Param("bundleId"), 10, 64)
+	var req UpdateCustomerBundleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.UpdateCustomerBundle(c.Request().Context(), custID, bundleID, &req); err != nil {
+		return bundleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteCustomerBundle(c echo.Context) error {
+	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+	bundleID, _ := strconv.ParseInt(c.Param("bundleId"), 10, 64)
+	if err := h.svc.DeleteCustomerBundle(c.Request().Context(), custID, bundleID); err != nil {
+		return bundleError(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
+func (h *Handler) RotateCustomerBundleKey(c echo.Context) error {
+	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+	bundleID, _ := strconv.ParseInt(c.Param("bundleId"), 10, 64)
+	var req RotateKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.RotateCustomerBundleKey(c.Request().Context(), custID, bundleID, &req)
+	if err != nil {
+		return bundleError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) SetCustomerBundleKeyStatus(c echo.Context) error {
+	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+	bundleID, _ := strconv.ParseInt(c.Param("bundleId"), 10, 64)
+	var req SetKeyStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.SetCustomerBundleKeyStatus(c.Request().Context(), custID, bundleID, &req); err != nil {
+		return bundleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) GetCustomerBundleKeyHistory(c echo.Context) error {
+	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+	bundleID, _ := strconv.ParseInt(c.Param("bundleId"), 10, 64)
+	out, err := h.svc.GetCustomerBundleKeyHistory(c.Request().Context(), custID, bundleID)
+	if err != nil {
+		return bundleError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func bundleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, bundle.ErrNameRequired), errors.Is(err, bundle.ErrNoProducts),
+		errors.Is(err, license.ErrNegativeGracePeriod), errors.Is(err, license.ErrInvalidKeyStatus),
+		errors.Is(err, license.ErrLicenseCountRequired), errors.Is(err, license.ErrStartDateRequired),
+		errors.Is(err, license.ErrExpirationDateRequired), errors.Is(err, license.ErrMaintExpirationRequired),
+		errors.Is(err, license.ErrSubscriptionRequiresTerm), errors.Is(err, license.ErrInvalidMaxVersion):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case strings.Contains(err.Error(), "FOREIGN KEY constraint failed"):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "unknown product"})
+	case errors.Is(err, bundle.ErrBundleLicensed), errors.Is(err, bundle.ErrAlreadyLicensed):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case sqlite.IsUniqueConstraintError(err):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "a bundle with this name already exists"})
+	case strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return errorJSON(c, err)
+}
+
 // Feature Definitions
 
 func (h *Handler) GetFeatures(c echo.Context) error {
 	prodID, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
 	out, err := h.svc.GetFeatures(c.Request().Context(), prodID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
@@ -213,7 +547,7 @@ func (h *Handler) CreateFeature(c echo.Context) error {
 	}
 	out, err := h.svc.CreateFeature(c.Request().Context(), prodID, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusCreated, out)
 }
@@ -226,7 +560,7 @@ func (h *Handler) UpdateFeature(c echo.Context) error {
 	}
 	err := h.svc.UpdateFeature(c.Request().Context(), id, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
@@ -235,36 +569,260 @@ func (h *Handler) DeleteFeature(c echo.Context) error {
 	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 	err := h.svc.DeleteFeature(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
-// Product Feature Values
+// Plans
 
-func (h *Handler) GetProductFeatures(c echo.Context) error {
-	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+func (h *Handler) GetPlans(c echo.Context) error {
 	prodID, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
-	out, err := h.svc.GetProductFeatures(c.Request().Context(), custID, prodID)
+	out, err := h.svc.GetPlans(c.Request().Context(), prodID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return planError(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
 
-func (h *Handler) UpdateProductFeature(c echo.Context) error {
-	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
+func (h *Handler) GetPlan(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetPlan(c.Request().Context(), id)
+	if err != nil {
+		return planError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) CreatePlan(c echo.Context) error {
 	prodID, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
-	featID, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req CreatePlanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.CreatePlan(c.Request().Context(), prodID, &req)
+	if err != nil {
+		return planError(c, err)
+	}
+	return c.JSON(http.StatusCreated, out)
+}
 
-	var req UpdateProductFeatureRequest
+func (h *Handler) UpdatePlan(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req UpdatePlanRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, err)
 	}
+	if err := h.svc.UpdatePlan(c.Request().Context(), id, &req); err != nil {
+		return planError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) DeletePlan(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err := h.svc.DeletePlan(c.Request().Context(), id); err != nil {
+		return planError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
 
-	err := h.svc.UpdateProductFeature(c.Request().Context(), custID, prodID, featID, &req)
+func (h *Handler) SetPlanValue(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	featID, _ := strconv.ParseInt(c.Param("featureId"), 10, 64)
+	var req SetPlanValueRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.SetPlanValue(c.Request().Context(), id, featID, &req); err != nil {
+		return planError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) GetPlanLicenses(c echo.Context) error {
+	prodID, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
+	out, err := h.svc.GetPlanLicenses(c.Request().Context(), prodID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return planError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) AssignPlan(c echo.Context) error {
+	var req AssignPlanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.AssignPlan(c.Request().Context(), &req); err != nil {
+		return planError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func planError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, plan.ErrNameRequired), errors.Is(err, plan.ErrWrongProduct):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case strings.Contains(err.Error(), "FOREIGN KEY constraint failed"):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "unknown product"})
+	case sqlite.IsUniqueConstraintError(err):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "the product already has a plan with this name"})
+	case strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return errorJSON(c, err)
+}
+
+// Bulk Operations
+
+func (h *Handler) PreviewBulk(c echo.Context) error {
+	var req BulkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.PreviewBulk(c.Request().Context(), &req)
+	if err != nil {
+		return bulkError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) ApplyBulk(c echo.Context) error {
+	var req BulkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.ApplyBulk(c.Request().Context(), &req)
+	if err != nil {
+		return bulkError(c, err)
+	}
+	return c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) GetBulkOperations(c echo.Context) error {
+	prodID, _ := strconv.ParseInt(c.QueryParam("productId"), 10, 64)
+	out, err := h.svc.GetBulkOperations(c.Request().Context(), prodID)
+	if err != nil {
+		return bulkError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) GetBulkOperation(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetBulkOperation(c.Request().Context(), id)
+	if err != nil {
+		return bulkError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func bulkError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, bulk.ErrProductRequired), errors.Is(err, bulk.ErrNoChange),
+		errors.Is(err, bulk.ErrNoLicenses), errors.Is(err, bulk.ErrInvalidChange), errors.Is(err, bulk.ErrWrongProduct):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return errorJSON(c, err)
+}
+
+// Inactivity Policies
+
+func (h *Handler) GetProductInactivityPolicy(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
+	out, err := h.svc.GetProductInactivityPolicy(c.Request().Context(), id)
+	if err != nil {
+		return inactivityError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) SetProductInactivityPolicy(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
+	var req inactivity.Policy
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.SetProductInactivityPolicy(c.Request().Context(), id, &req); err != nil {
+		return inactivityError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) GetLicenseInactivityPolicy(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetLicenseInactivityPolicy(c.Request().Context(), id)
+	if err != nil {
+		return inactivityError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) SetLicenseInactivityPolicy(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req inactivity.Policy
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.SetLicenseInactivityPolicy(c.Request().Context(), id, &req); err != nil {
+		return inactivityError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) PreviewInactivity(c echo.Context) error {
+	prodID, _ := strconv.ParseInt(c.QueryParam("productId"), 10, 64)
+	out, err := h.svc.PreviewInactivity(c.Request().Context(), prodID)
+	if err != nil {
+		return inactivityError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) RunInactivity(c echo.Context) error {
+	out, err := h.svc.RunInactivity(c.Request().Context())
+	if err != nil {
+		return inactivityError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func inactivityError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, inactivity.ErrInvalidDays), errors.Is(err, inactivity.ErrInvalidAction):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return errorJSON(c, err)
+}
+
+// License Feature Values
+
+func (h *Handler) GetLicenseFeatures(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetLicenseFeatures(c.Request().Context(), id)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) UpdateLicenseFeature(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	featID, _ := strconv.ParseInt(c.Param("featureId"), 10, 64)
+
+	var req UpdateLicenseFeatureRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	err := h.svc.UpdateLicenseFeature(c.Request().Context(), id, featID, &req)
+	if err != nil {
+		return errorJSON(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
@@ -272,14 +830,16 @@ func (h *Handler) UpdateProductFeature(c echo.Context) error {
 // Machine Registrations
 
 func (h *Handler) GetMachineRegistrations(c echo.Context) error {
-	custID, _ := strconv.ParseInt(c.Param("customerId"), 10, 64)
-	prodID, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
 
 	active := c.QueryParam("active") == "true"
 
-	out, err := h.svc.GetMachineRegistrations(c.Request().Context(), custID, prodID, active)
+	out, err := h.svc.GetMachineRegistrations(c.Request().Context(), id, active)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return errorJSON(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
@@ -290,11 +850,118 @@ func (h *Handler) DeleteMachineRegistration(c echo.Context) error {
 
 	err := h.svc.DeleteMachineRegistration(c.Request().Context(), machineID, prodID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
+func (h *Handler) TransferMachine(c echo.Context) error {
+	machineID, _ := strconv.ParseInt(c.Param("machineId"), 10, 64)
+	var req TransferMachineRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.TransferMachine(c.Request().Context(), machineID, &req)
+	switch {
+	case errors.Is(err, machine.ErrSameCustomer),
+		errors.Is(err, machine.ErrMachineCodeExists),
+		errors.Is(err, activation.ErrNoLicense),
+		errors.Is(err, activation.ErrLicenseCountExceeded),
+		license.ErrorCode(err) != "":
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, reseller.ErrOutOfScope):
+		return errorJSON(c, err)
+	case err != nil && strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) GetMachineTransfers(c echo.Context) error {
+	machineID, _ := strconv.ParseInt(c.Param("machineId"), 10, 64)
+	out, err := h.svc.GetMachineTransfers(c.Request().Context(), machineID)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) CreateManualRegistration(c echo.Context) error {
+	var req ManualRegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.CreateManualRegistration(c.Request().Context(), &req)
+	if err != nil {
+		return registrationError(c, err)
+	}
+	return registrationFileJSON(c, http.StatusCreated, out)
+}
+
+func (h *Handler) ExportMachineRegistration(c echo.Context) error {
+	machineID, _ := strconv.ParseInt(c.Param("machineId"), 10, 64)
+	prodID, _ := strconv.ParseInt(c.Param("productId"), 10, 64)
+	out, err := h.svc.ExportRegistration(c.Request().Context(), machineID, prodID)
+	if err != nil {
+		return registrationError(c, err)
+	}
+	return registrationFileJSON(c, http.StatusOK, out)
+}
+
+func (h *Handler) ExportLicenseRegistrations(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.ExportLicenseRegistrations(c.Request().Context(), id)
+	if err != nil {
+		return registrationError(c, err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range out.Files {
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			return errorJSON(c, err)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.Registration); err != nil {
+			return errorJSON(c, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return errorJSON(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", out.Name))
+	return c.Blob(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// registrationFileJSON sends a registration file as a download
+func registrationFileJSON(c echo.Context, code int, f *RegistrationFile) error {
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", f.Name))
+	return c.JSONPretty(code, f.Registration, "  ")
+}
+
+func registrationError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, activation.ErrMachineCodeRequired):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, activation.ErrNotRegistered):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, activation.ErrNoLicense),
+		errors.Is(err, activation.ErrLicenseCountExceeded),
+		license.ErrorCode(err) != "":
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, reseller.ErrOutOfScope):
+		return errorJSON(c, err)
+	case strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return errorJSON(c, err)
+}
+
 // Expirations
 
 func (h *Handler) GetExpirations(c echo.Context) error {
@@ -305,14 +972,98 @@ func (h *Handler) GetExpirations(c echo.Context) error {
 
 	out, err := h.svc.GetExpiredLicenses(c.Request().Context(), before)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// Resellers
+
+func (h *Handler) GetResellers(c echo.Context) error {
+	out, err := h.svc.GetResellers(c.Request().Context())
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) GetReseller(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetReseller(c.Request().Context(), id)
+	if err != nil {
+		return resellerError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) CreateReseller(c echo.Context) error {
+	var req CreateResellerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	out, err := h.svc.CreateReseller(c.Request().Context(), &req)
+	if err != nil {
+		return resellerError(c, err)
+	}
+	return c.JSON(http.StatusCreated, out)
+}
+
+func (h *Handler) UpdateReseller(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var req UpdateResellerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+	if err := h.svc.UpdateReseller(c.Request().Context(), id, &req); err != nil {
+		return resellerError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteReseller(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err := h.svc.DeleteReseller(c.Request().Context(), id); err != nil {
+		return resellerError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) RotateResellerKey(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.RotateResellerKey(c.Request().Context(), id)
+	if err != nil {
+		return resellerError(c, err)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) GetResellerUsage(c echo.Context) error {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	out, err := h.svc.GetResellerUsage(c.Request().Context(), id)
+	if err != nil {
+		return resellerError(c, err)
 	}
 	return c.JSON(http.StatusOK, out)
 }
 
+func resellerError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, reseller.ErrNameRequired), errors.Is(err, reseller.ErrNegativeAllocation):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case sqlite.IsUniqueConstraintError(err):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "a reseller with this name already exists"})
+	case strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return errorJSON(c, err)
+}
+
 // Backup
 
 func (h *Handler) BackupDatabase(c echo.Context) error {
+	if _, ok := reseller.ScopeFromContext(c.Request().Context()); ok {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": reseller.ErrAdminOnly.Error()})
+	}
 	result, err := h.backupSvc.CreateBackup(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
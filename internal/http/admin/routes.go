@@ -11,6 +11,13 @@ func RegisterRoutes(g *echo.Group, h *Handler) {
 	g.PUT("/customers/:id", h.UpdateCustomer)
 	g.DELETE("/customers/:id", h.DeleteCustomer)
 	g.GET("/customers/:id/exists", h.CustomerExists)
+	g.PUT("/customers/:id/reseller", h.SetCustomerReseller)
+
+	// Customer contacts
+	g.GET("/customers/:customerId/contacts", h.GetContacts)
+	g.POST("/customers/:customerId/contacts", h.CreateContact)
+	g.PUT("/contacts/:id", h.UpdateContact)
+	g.DELETE("/contacts/:id", h.DeleteContact)
 
 	// Products
 	g.GET("/products", h.GetProducts)
@@ -19,12 +26,32 @@ func RegisterRoutes(g *echo.Group, h *Handler) {
 	g.PUT("/products/:id", h.UpdateProduct)
 	g.DELETE("/products/:id", h.DeleteProduct)
 
-	// Licenses (customer products)
-	g.GET("/customers/:customerId/products", h.GetLicenses)
-	g.GET("/customers/:customerId/unlicensed-products", h.GetUnlicensedProducts)
-	g.POST("/customers/:customerId/products", h.CreateLicense)
-	g.PUT("/customers/:customerId/products/:productId", h.UpdateLicense)
-	g.DELETE("/customers/:customerId/products/:productId", h.DeleteLicense)
+	// Licenses (a customer may hold several licenses of a product)
+	g.GET("/customers/:customerId/licenses", h.GetLicenses)
+	g.POST("/customers/:customerId/licenses", h.CreateLicense)
+	g.GET("/licenses/:id", h.GetLicense)
+	g.PUT("/licenses/:id", h.UpdateLicense)
+	g.DELETE("/licenses/:id", h.DeleteLicense)
+	g.PUT("/licenses/:id/status", h.SetLicenseStatus)
+	g.POST("/licenses/:id/rotate-key", h.RotateLicenseKey)
+	g.PUT("/licenses/:id/key-status", h.SetLicenseKeyStatus)
+	g.GET("/licenses/:id/key-history", h.GetLicenseKeyHistory)
+
+	// Bundles (products licensed as a single unit)
+	g.GET("/bundles", h.GetBundles)
+	g.GET("/bundles/:id", h.GetBundle)
+	g.POST("/bundles", h.CreateBundle)
+	g.PUT("/bundles/:id", h.UpdateBundle)
+	g.DELETE("/bundles/:id", h.DeleteBundle)
+
+	// Customer bundles
+	g.GET("/customers/:customerId/bundles", h.GetCustomerBundles)
+	g.POST("/customers/:customerId/bundles", h.CreateCustomerBundle)
+	g.PUT("/customers/:customerId/bundles/:bundleId", h.UpdateCustomerBundle)
+	g.DELETE("/customers/:customerId/bundles/:bundleId", h.DeleteCustomerBundle)
+	g.POST("/customers/:customerId/bundles/:bundleId/rotate-key", h.RotateCustomerBundleKey)
+	g.PUT("/customers/:customerId/bundles/:bundleId/key-status", h.SetCustomerBundleKeyStatus)
+	g.GET("/customers/:customerId/bundles/:bundleId/key-history", h.GetCustomerBundleKeyHistory)
 
 	// Feature definitions (per product)
 	g.GET("/products/:productId/features", h.GetFeatures)
@@ -32,17 +59,55 @@ func RegisterRoutes(g *echo.Group, h *Handler) {
 	g.PUT("/features/:id", h.UpdateFeature)
 	g.DELETE("/features/:id", h.DeleteFeature)
 
-	// Product features (customer-specific feature values)
-	g.GET("/customers/:customerId/products/:productId/features", h.GetProductFeatures)
-	g.PUT("/customers/:customerId/products/:productId/features/:id", h.UpdateProductFeature)
+	// Plans (per product sets of feature values that licenses are put on)
+	g.GET("/products/:productId/plans", h.GetPlans)
+	g.POST("/products/:productId/plans", h.CreatePlan)
+	g.GET("/plans/:id", h.GetPlan)
+	g.PUT("/plans/:id", h.UpdatePlan)
+	g.DELETE("/plans/:id", h.DeletePlan)
+	g.PUT("/plans/:id/features/:featureId", h.SetPlanValue)
+	g.GET("/products/:productId/licenses", h.GetPlanLicenses)
+	g.PUT("/licenses/plan", h.AssignPlan)
+
+	// Bulk operations (change many licenses of a product at once, with an audit trail)
+	g.POST("/bulk/preview", h.PreviewBulk)
+	g.POST("/bulk/apply", h.ApplyBulk)
+	g.GET("/bulk/operations", h.GetBulkOperations)
+	g.GET("/bulk/operations/:id", h.GetBulkOperation)
+
+	// Inactivity policies (flag idle registrations and release their seats)
+	g.GET("/products/:productId/inactivity-policy", h.GetProductInactivityPolicy)
+	g.PUT("/products/:productId/inactivity-policy", h.SetProductInactivityPolicy)
+	g.GET("/licenses/:id/inactivity-policy", h.GetLicenseInactivityPolicy)
+	g.PUT("/licenses/:id/inactivity-policy", h.SetLicenseInactivityPolicy)
+	g.GET("/inactivity/preview", h.PreviewInactivity)
+	g.POST("/inactivity/run", h.RunInactivity)
+
+	// License features (license-specific feature values)
+	g.GET("/licenses/:id/features", h.GetLicenseFeatures)
+	g.PUT("/licenses/:id/features/:featureId", h.UpdateLicenseFeature)
 
 	// Machine registrations
-	g.GET("/customers/:customerId/products/:productId/registrations", h.GetMachineRegistrations)
+	g.GET("/licenses/:id/registrations", h.GetMachineRegistrations)
+	g.GET("/licenses/:id/registrations/export", h.ExportLicenseRegistrations)
+	g.POST("/registrations", h.CreateManualRegistration)
+	g.GET("/registrations/:machineId/:productId/export", h.ExportMachineRegistration)
 	g.DELETE("/registrations/:machineId/:productId", h.DeleteMachineRegistration)
+	g.POST("/machines/:machineId/transfer", h.TransferMachine)
+	g.GET("/machines/:machineId/transfers", h.GetMachineTransfers)
 
 	// Expirations
 	g.GET("/expirations", h.GetExpirations)
 
+	// Resellers
+	g.GET("/resellers", h.GetResellers)
+	g.GET("/resellers/:id", h.GetReseller)
+	g.POST("/resellers", h.CreateReseller)
+	g.PUT("/resellers/:id", h.UpdateReseller)
+	g.DELETE("/resellers/:id", h.DeleteReseller)
+	g.POST("/resellers/:id/rotate-key", h.RotateResellerKey)
+	g.GET("/resellers/:id/usage", h.GetResellerUsage)
+
 	// Backup
 	g.POST("/backup", h.BackupDatabase)
 }
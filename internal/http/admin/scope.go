@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"winsbygroup.com/regserver/internal/reseller"
+)
+
+// Requests authenticated with a reseller API key carry a reseller scope (see
+// middleware.AdminAPIKeyAuth). The checks below restrict them to the
+// reseller's own customers; requests with the admin key are not restricted.
+
+// requireAdmin rejects reseller-scoped requests
+func requireAdmin(ctx context.Context) error {
+	if _, ok := reseller.ScopeFromContext(ctx); ok {
+		return reseller.ErrAdminOnly
+	}
+	return nil
+}
+
+// checkReseller returns reseller.ErrOutOfScope if a reseller-scoped request is for another reseller
+func checkReseller(ctx context.Context, resellerID int64) error {
+	if id, ok := reseller.ScopeFromContext(ctx); ok && id != resellerID {
+		return reseller.ErrOutOfScope
+	}
+	return nil
+}
+
+// checkCustomer returns reseller.ErrOutOfScope if the request may not manage the customer
+func (s *Service) checkCustomer(ctx context.Context, customerID int64) error {
+	resellerID, ok := reseller.ScopeFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	c, err := s.customers.Get(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	if c.ResellerID == nil || *c.ResellerID != resellerID {
+		return reseller.ErrOutOfScope
+	}
+	return nil
+}
+
+// checkMachine returns reseller.ErrOutOfScope if the request may not manage the machine's customer
+func (s *Service) checkMachine(ctx context.Context, machineID int64) error {
+	if _, ok := reseller.ScopeFromContext(ctx); !ok {
+		return nil
+	}
+	m, err := s.machines.Get(ctx, machineID)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("machine not found (%d)", machineID)
+	}
+	return s.checkCustomer(ctx, m.CustomerID)
+}
+
+// checkLicense returns reseller.ErrOutOfScope if the request may not manage the license's customer
+func (s *Service) checkLicense(ctx context.Context, licenseID int64) error {
+	if _, ok := reseller.ScopeFromContext(ctx); !ok {
+		return nil
+	}
+	lic, err := s.licenses.Get(ctx, licenseID)
+	if err != nil {
+		return err
+	}
+	return s.checkCustomer(ctx, lic.CustomerID)
+}
+
+// checkContact returns reseller.ErrOutOfScope if the request may not manage the contact's customer
+func (s *Service) checkContact(ctx context.Context, contactID int64) error {
+	if _, ok := reseller.ScopeFromContext(ctx); !ok {
+		return nil
+	}
+	c, err := s.customers.GetContact(ctx, contactID)
+	if err != nil {
+		return err
+	}
+	return s.checkCustomer(ctx, c.CustomerID)
+}
+
+// checkAllocation returns reseller.ErrAllocationExceeded if a reseller-scoped
+// request would issue seats beyond the reseller's seat allocation. licenseIDs
+// are the licenses whose seats the request replaces (none for new licenses).
+func (s *Service) checkAllocation(ctx context.Context, licenseIDs []int64, requested int) error {
+	resellerID, ok := reseller.ScopeFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return s.resellers.CheckAllocation(ctx, resellerID, licenseIDs, requested)
+}
@@ -2,16 +2,27 @@ package admin
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/bulk"
+	"winsbygroup.com/regserver/internal/bundle"
 	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/feature"
 	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/inactivity"
 	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
 	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/plan"
 	"winsbygroup.com/regserver/internal/product"
 	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/reseller"
 )
 
 type Service struct {
@@ -22,6 +33,12 @@ type Service struct {
 	featureValues *featurevalue.Service
 	machines      *machine.Service
 	registrations *registration.Service
+	activations   *activation.Service
+	resellers     *reseller.Service
+	bundles       *bundle.Service
+	plans         *plan.Service
+	bulk          *bulk.Service
+	inactivity    *inactivity.Service
 }
 
 func NewService(
@@ -32,6 +49,12 @@ func NewService(
 	fv *featurevalue.Service,
 	m *machine.Service,
 	r *registration.Service,
+	a *activation.Service,
+	rs *reseller.Service,
+	b *bundle.Service,
+	pl *plan.Service,
+	bk *bulk.Service,
+	in *inactivity.Service,
 ) *Service {
 	return &Service{
 		customers:     c,
@@ -41,6 +64,12 @@ func NewService(
 		featureValues: fv,
 		machines:      m,
 		registrations: r,
+		activations:   a,
+		resellers:     rs,
+		bundles:       b,
+		plans:         pl,
+		bulk:          bk,
+		inactivity:    in,
 	}
 }
 
@@ -49,13 +78,21 @@ func NewService(
 // -------------------------
 
 func (s *Service) GetCustomers(ctx context.Context) ([]customer.Customer, error) {
+	if resellerID, ok := reseller.ScopeFromContext(ctx); ok {
+		return s.customers.GetForReseller(ctx, resellerID)
+	}
 	return s.customers.GetAll(ctx)
 }
 
 func (s *Service) GetCustomer(ctx context.Context, id int64) (*customer.Customer, error) {
+	if err := s.checkCustomer(ctx, id); err != nil {
+		return nil, err
+	}
 	return s.customers.Get(ctx, id)
 }
 
+// CreateCustomer creates a customer. Customers created with a reseller API key
+// always belong to that reseller.
 func (s *Service) CreateCustomer(ctx context.Context, req *CreateCustomerRequest) (*customer.Customer, error) {
 	c := &customer.Customer{
 		CustomerName: req.CustomerName,
@@ -63,11 +100,23 @@ func (s *Service) CreateCustomer(ctx context.Context, req *CreateCustomerRequest
 		Phone:        req.Phone,
 		Email:        req.Email,
 		Notes:        req.Notes,
+		ResellerID:   req.ResellerID,
+	}
+	if resellerID, ok := reseller.ScopeFromContext(ctx); ok {
+		c.ResellerID = &resellerID
 	}
-	return s.customers.Create(ctx, c)
+	out, err := s.customers.Create(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("customer created", "customer_id", out.CustomerID)
+	return out, nil
 }
 
 func (s *Service) UpdateCustomer(ctx context.Context, id int64, req *UpdateCustomerRequest) error {
+	if err := s.checkCustomer(ctx, id); err != nil {
+		return err
+	}
 	c := &customer.Customer{
 		CustomerID:   id,
 		CustomerName: req.CustomerName,
@@ -80,11 +129,119 @@ func (s *Service) UpdateCustomer(ctx context.Context, id int64, req *UpdateCusto
 }
 
 func (s *Service) DeleteCustomer(ctx context.Context, id int64) error {
-	return s.customers.Delete(ctx, id)
+	if err := s.checkCustomer(ctx, id); err != nil {
+		return err
+	}
+	if err := s.customers.Delete(ctx, id); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("customer deleted", "customer_id", id)
+	return nil
 }
 
+// CustomerExists reports whether a customer exists. With a reseller API key,
+// other resellers' and direct customers are reported as not existing.
 func (s *Service) CustomerExists(ctx context.Context, id int64) (bool, error) {
-	return s.customers.Exists(ctx, id)
+	exists, err := s.customers.Exists(ctx, id)
+	if err != nil || !exists {
+		return false, err
+	}
+	if err := s.checkCustomer(ctx, id); errors.Is(err, reseller.ErrOutOfScope) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetCustomerReseller assigns a customer to a reseller, or makes it a direct
+// customer when the request's reseller ID is null
+func (s *Service) SetCustomerReseller(ctx context.Context, id int64, req *SetCustomerResellerRequest) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if req.ResellerID != nil {
+		if _, err := s.resellers.Get(ctx, *req.ResellerID); err != nil {
+			return err
+		}
+	}
+	if err := s.customers.SetReseller(ctx, id, req.ResellerID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("customer reseller changed", "customer_id", id, "reseller_id", req.ResellerID)
+	return nil
+}
+
+// -------------------------
+// Customer Contacts
+// -------------------------
+
+func (s *Service) GetContacts(ctx context.Context, customerID int64) ([]customer.Contact, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+	return s.customers.GetContacts(ctx, customerID)
+}
+
+func (s *Service) GetContact(ctx context.Context, contactID int64) (*customer.Contact, error) {
+	if err := s.checkContact(ctx, contactID); err != nil {
+		return nil, err
+	}
+	return s.customers.GetContact(ctx, contactID)
+}
+
+func (s *Service) CreateContact(ctx context.Context, customerID int64, req *CreateContactRequest) (*customer.Contact, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+	roles, err := customer.JoinRoles(req.Roles)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.customers.CreateContact(ctx, &customer.Contact{
+		CustomerID:  customerID,
+		ContactName: req.ContactName,
+		Phone:       req.Phone,
+		Email:       req.Email,
+		Roles:       roles,
+		IsPrimary:   req.IsPrimary,
+		Notes:       req.Notes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("contact created", "customer_id", customerID, "contact_id", out.ContactID)
+	return out, nil
+}
+
+func (s *Service) UpdateContact(ctx context.Context, contactID int64, req *UpdateContactRequest) error {
+	if err := s.checkContact(ctx, contactID); err != nil {
+		return err
+	}
+	roles, err := customer.JoinRoles(req.Roles)
+	if err != nil {
+		return err
+	}
+	return s.customers.UpdateContact(ctx, &customer.Contact{
+		ContactID:   contactID,
+		ContactName: req.ContactName,
+		Phone:       req.Phone,
+		Email:       req.Email,
+		Roles:       roles,
+		IsPrimary:   req.IsPrimary,
+		Notes:       req.Notes,
+	})
+}
+
+func (s *Service) DeleteContact(ctx context.Context, contactID int64) error {
+	if err := s.checkContact(ctx, contactID); err != nil {
+		return err
+	}
+	if err := s.customers.DeleteContact(ctx, contactID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("contact deleted", "contact_id", contactID)
+	return nil
 }
 
 // -------------------------
@@ -100,6 +257,9 @@ func (s *Service) GetProduct(ctx context.Context, id int64) (*product.Product, e
 }
 
 func (s *Service) CreateProduct(ctx context.Context, req *CreateProductRequest) (*product.Product, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
 	p := &product.Product{
 		ProductName:   req.Name,
 		ProductGUID:   req.Guid,
@@ -110,6 +270,9 @@ func (s *Service) CreateProduct(ctx context.Context, req *CreateProductRequest)
 }
 
 func (s *Service) UpdateProduct(ctx context.Context, id int64, req *UpdateProductRequest) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
 	p := &product.Product{
 		ProductID:     id,
 		ProductName:   req.Name,
@@ -121,6 +284,9 @@ func (s *Service) UpdateProduct(ctx context.Context, id int64, req *UpdateProduc
 }
 
 func (s *Service) DeleteProduct(ctx context.Context, id int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
 	return s.products.Delete(ctx, id)
 }
 
@@ -129,14 +295,28 @@ func (s *Service) DeleteProduct(ctx context.Context, id int64) error {
 // -------------------------
 
 func (s *Service) GetLicenses(ctx context.Context, customerID int64) ([]license.License, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
 	return s.licenses.GetForCustomer(ctx, customerID)
 }
 
-func (s *Service) GetUnlicensedProducts(ctx context.Context, customerID int64) ([]product.Product, error) {
-	return s.licenses.GetUnlicensed(ctx, customerID)
+func (s *Service) GetLicense(ctx context.Context, licenseID int64) (*license.License, error) {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return nil, err
+	}
+	return s.licenses.Get(ctx, licenseID)
 }
 
+// CreateLicense adds a license for a product. A customer may hold several
+// licenses of the same product; each has its own key, seats and dates.
 func (s *Service) CreateLicense(ctx context.Context, customerID int64, req *CreateLicenseRequest) (*license.License, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+	if err := s.checkAllocation(ctx, nil, req.LicenseCount); err != nil {
+		return nil, err
+	}
 	lic := &license.License{
 		CustomerID:          customerID,
 		ProductID:           req.ProductID,
@@ -149,13 +329,216 @@ func (s *Service) CreateLicense(ctx context.Context, customerID int64, req *Crea
 		MaintExpirationDate: req.MaintExpirationDate,
 		MaxProductVersion:   req.MaxProductVersion,
 	}
-	return s.licenses.Create(ctx, lic)
+	out, err := s.licenses.Create(ctx, lic)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("license created",
+		"license_id", out.LicenseID,
+		"customer_id", customerID,
+		"product_id", req.ProductID,
+		"license_key", logging.KeyPrefix(lic.LicenseKey),
+		"license_count", req.LicenseCount,
+	)
+	return out, nil
 }
 
-func (s *Service) UpdateLicense(ctx context.Context, customerID, productID int64, req *UpdateLicenseRequest) error {
+func (s *Service) UpdateLicense(ctx context.Context, licenseID int64, req *UpdateLicenseRequest) error {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return err
+	}
+	// Seats and dates of a bundle license apply to the whole bundle
+	licenseIDs, err := s.licenseGroup(ctx, licenseID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkAllocation(ctx, licenseIDs, req.LicenseCount*len(licenseIDs)); err != nil {
+		return err
+	}
 	lic := &license.License{
-		CustomerID:          customerID,
-		ProductID:           productID,
+		LicenseID:           licenseID,
+		LicenseCount:        req.LicenseCount,
+		IsSubscription:      req.IsSubscription,
+		LicenseTerm:         req.LicenseTerm,
+		StartDate:           req.StartDate,
+		ExpirationDate:      req.ExpirationDate,
+		MaintExpirationDate: req.MaintExpirationDate,
+		MaxProductVersion:   req.MaxProductVersion,
+	}
+	if err := s.licenses.Update(ctx, lic); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("license updated",
+		"license_id", licenseID,
+		"license_count", req.LicenseCount,
+		"expiration_date", req.ExpirationDate,
+	)
+	return nil
+}
+
+func (s *Service) DeleteLicense(ctx context.Context, licenseID int64) error {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return err
+	}
+	if err := s.licenses.Delete(ctx, licenseID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("license deleted", "license_id", licenseID)
+	return nil
+}
+
+func (s *Service) SetLicenseStatus(ctx context.Context, licenseID int64, req *SetLicenseStatusRequest) error {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return err
+	}
+	if err := s.licenses.SetStatus(ctx, licenseID, req.Status, req.Reason); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("license status changed",
+		"license_id", licenseID,
+		"status", req.Status,
+		"reason", req.Reason,
+	)
+	return nil
+}
+
+func (s *Service) RotateLicenseKey(ctx context.Context, licenseID int64, req *RotateKeyRequest) (*license.License, error) {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return nil, err
+	}
+	grace := time.Duration(req.GraceHours) * time.Hour
+	out, err := s.licenses.RotateKey(ctx, licenseID, grace, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("license key rotated",
+		"license_id", licenseID,
+		"grace_hours", req.GraceHours,
+		"reason", req.Reason,
+	)
+	return out, nil
+}
+
+func (s *Service) SetLicenseKeyStatus(ctx context.Context, licenseID int64, req *SetKeyStatusRequest) error {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return err
+	}
+	if err := s.licenses.SetKeyStatus(ctx, licenseID, req.Status, req.Reason); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("license key status changed",
+		"license_id", licenseID,
+		"key_status", req.Status,
+		"reason", req.Reason,
+	)
+	return nil
+}
+
+func (s *Service) GetLicenseKeyHistory(ctx context.Context, licenseID int64) ([]license.KeyHistory, error) {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return nil, err
+	}
+	return s.licenses.GetKeyHistory(ctx, licenseID)
+}
+
+// licenseGroup returns the licenses that change with a license: the license
+// itself, or all licenses of its customer bundle
+func (s *Service) licenseGroup(ctx context.Context, licenseID int64) ([]int64, error) {
+	lic, err := s.licenses.Get(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+	if lic.BundleID == nil {
+		return []int64{licenseID}, nil
+	}
+	lics, err := s.licenses.GetForBundle(ctx, lic.CustomerID, *lic.BundleID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(lics))
+	for i, l := range lics {
+		ids[i] = l.LicenseID
+	}
+	return ids, nil
+}
+
+// -------------------------
+// Bundles
+// -------------------------
+
+func (s *Service) GetBundles(ctx context.Context) ([]bundle.Bundle, error) {
+	return s.bundles.GetAll(ctx)
+}
+
+func (s *Service) GetBundle(ctx context.Context, id int64) (*bundle.Bundle, error) {
+	return s.bundles.Get(ctx, id)
+}
+
+func (s *Service) CreateBundle(ctx context.Context, req *CreateBundleRequest) (*bundle.Bundle, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	b, err := s.bundles.Create(ctx, &bundle.Bundle{
+		BundleName:  req.BundleName,
+		Description: req.Description,
+	}, req.ProductIDs)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("bundle created", "bundle_id", b.BundleID, "products", len(b.Products))
+	return b, nil
+}
+
+func (s *Service) UpdateBundle(ctx context.Context, id int64, req *UpdateBundleRequest) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	err := s.bundles.Update(ctx, &bundle.Bundle{
+		BundleID:    id,
+		BundleName:  req.BundleName,
+		Description: req.Description,
+	}, req.ProductIDs)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("bundle updated", "bundle_id", id)
+	return nil
+}
+
+func (s *Service) DeleteBundle(ctx context.Context, id int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := s.bundles.Delete(ctx, id); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("bundle deleted", "bundle_id", id)
+	return nil
+}
+
+// -------------------------
+// Customer Bundles
+// -------------------------
+
+func (s *Service) GetCustomerBundles(ctx context.Context, customerID int64) ([]bundle.CustomerBundle, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+	return s.bundles.GetForCustomer(ctx, customerID)
+}
+
+func (s *Service) CreateCustomerBundle(ctx context.Context, customerID int64, req *CreateCustomerBundleRequest) (*bundle.CustomerBundle, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+	b, err := s.bundles.Get(ctx, req.BundleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAllocation(ctx, nil, req.LicenseCount*len(b.Products)); err != nil {
+		return nil, err
+	}
+	out, err := s.bundles.License(ctx, customerID, req.BundleID, &license.License{
 		LicenseCount:        req.LicenseCount,
 		IsSubscription:      req.IsSubscription,
 		LicenseTerm:         req.LicenseTerm,
@@ -163,12 +546,104 @@ func (s *Service) UpdateLicense(ctx context.Context, customerID, productID int64
 		ExpirationDate:      req.ExpirationDate,
 		MaintExpirationDate: req.MaintExpirationDate,
 		MaxProductVersion:   req.MaxProductVersion,
+	})
+	if err != nil {
+		return nil, err
 	}
-	return s.licenses.Update(ctx, lic)
+	logging.FromContext(ctx).Info("bundle licensed",
+		"customer_id", customerID,
+		"bundle_id", req.BundleID,
+		"license_key", logging.KeyPrefix(out.LicenseKey),
+		"license_count", req.LicenseCount,
+	)
+	return out, nil
 }
 
-func (s *Service) DeleteLicense(ctx context.Context, customerID, productID int64) error {
-	return s.licenses.Delete(ctx, customerID, productID)
+func (s *Service) UpdateCustomerBundle(ctx context.Context, customerID, bundleID int64, req *UpdateCustomerBundleRequest) error {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return err
+	}
+	cb, err := s.bundles.GetCustomerBundle(ctx, customerID, bundleID)
+	if err != nil {
+		return err
+	}
+	licenseIDs := make([]int64, len(cb.Licenses))
+	for i, l := range cb.Licenses {
+		licenseIDs[i] = l.LicenseID
+	}
+	if err := s.checkAllocation(ctx, licenseIDs, req.LicenseCount*len(licenseIDs)); err != nil {
+		return err
+	}
+	err = s.bundles.UpdateLicense(ctx, customerID, bundleID, &license.License{
+		LicenseCount:        req.LicenseCount,
+		IsSubscription:      req.IsSubscription,
+		LicenseTerm:         req.LicenseTerm,
+		StartDate:           req.StartDate,
+		ExpirationDate:      req.ExpirationDate,
+		MaintExpirationDate: req.MaintExpirationDate,
+	})
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("bundle license updated",
+		"customer_id", customerID,
+		"bundle_id", bundleID,
+		"license_count", req.LicenseCount,
+		"expiration_date", req.ExpirationDate,
+	)
+	return nil
+}
+
+func (s *Service) DeleteCustomerBundle(ctx context.Context, customerID, bundleID int64) error {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return err
+	}
+	if err := s.bundles.Unlicense(ctx, customerID, bundleID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("bundle license deleted", "customer_id", customerID, "bundle_id", bundleID)
+	return nil
+}
+
+func (s *Service) RotateCustomerBundleKey(ctx context.Context, customerID, bundleID int64, req *RotateKeyRequest) (*bundle.CustomerBundle, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+	grace := time.Duration(req.GraceHours) * time.Hour
+	out, err := s.bundles.RotateKey(ctx, customerID, bundleID, grace, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("bundle license key rotated",
+		"customer_id", customerID,
+		"bundle_id", bundleID,
+		"grace_hours", req.GraceHours,
+		"reason", req.Reason,
+	)
+	return out, nil
+}
+
+func (s *Service) SetCustomerBundleKeyStatus(ctx context.Context, customerID, bundleID int64, req *SetKeyStatusRequest) error {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return err
+	}
+	if err := s.bundles.SetKeyStatus(ctx, customerID, bundleID, req.Status, req.Reason); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("bundle license key status changed",
+		"customer_id", customerID,
+		"bundle_id", bundleID,
+		"key_status", req.Status,
+		"reason", req.Reason,
+	)
+	return nil
+}
+
+func (s *Service) GetCustomerBundleKeyHistory(ctx context.Context, customerID, bundleID int64) ([]bundle.KeyHistory, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+	return s.bundles.GetKeyHistory(ctx, customerID, bundleID)
 }
 
 // -------------------------
@@ -180,62 +655,406 @@ func (s *Service) GetFeatures(ctx context.Context, productID int64) ([]feature.F
 }
 
 func (s *Service) CreateFeature(ctx context.Context, productID int64, req *CreateFeatureRequest) (*feature.Feature, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
 	f := &feature.Feature{
 		ProductID:     productID,
 		FeatureName:   req.FeatureName,
 		FeatureType:   feature.ToInt(req.FeatureType),
 		AllowedValues: req.AllowedValues,
 		DefaultValue:  req.DefaultValue,
+		MinValue:      req.MinValue,
+		MaxValue:      req.MaxValue,
+		MaxLength:     req.MaxLength,
+		Status:        req.Status,
 	}
 	return s.features.Create(ctx, f)
 }
 
 func (s *Service) UpdateFeature(ctx context.Context, featureID int64, req *UpdateFeatureRequest) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
 	f := &feature.Feature{
 		FeatureID:     featureID,
 		FeatureName:   req.FeatureName,
 		FeatureType:   feature.ToInt(req.FeatureType),
 		AllowedValues: req.AllowedValues,
 		DefaultValue:  req.DefaultValue,
+		MinValue:      req.MinValue,
+		MaxValue:      req.MaxValue,
+		MaxLength:     req.MaxLength,
+		Status:        req.Status,
 	}
 	return s.features.Update(ctx, f)
 }
 
 func (s *Service) DeleteFeature(ctx context.Context, featureID int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
 	return s.features.Delete(ctx, featureID)
 }
 
 // -------------------------
-// Product Feature Values (customer-specific overrides)
+// Plans (per product)
+// -------------------------
+
+func (s *Service) GetPlans(ctx context.Context, productID int64) ([]plan.Plan, error) {
+	return s.plans.GetForProduct(ctx, productID)
+}
+
+func (s *Service) GetPlan(ctx context.Context, id int64) (*plan.Plan, error) {
+	return s.plans.Get(ctx, id)
+}
+
+func (s *Service) CreatePlan(ctx context.Context, productID int64, req *CreatePlanRequest) (*plan.Plan, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	p := &plan.Plan{ProductID: productID, PlanName: req.PlanName, Values: planValues(req.Values)}
+	out, err := s.plans.Create(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("plan created", "plan_id", out.PlanID, "product_id", productID)
+	return out, nil
+}
+
+func (s *Service) UpdatePlan(ctx context.Context, id int64, req *UpdatePlanRequest) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	return s.plans.Update(ctx, &plan.Plan{PlanID: id, PlanName: req.PlanName, Values: planValues(req.Values)})
+}
+
+func planValues(values []PlanValueRequest) []plan.Value {
+	out := make([]plan.Value, len(values))
+	for i, v := range values {
+		out[i] = plan.Value{FeatureID: v.FeatureID, FeatureValue: v.Value}
+	}
+	return out
+}
+
+func (s *Service) DeletePlan(ctx context.Context, id int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := s.plans.Delete(ctx, id); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("plan deleted", "plan_id", id)
+	return nil
+}
+
+func (s *Service) SetPlanValue(ctx context.Context, id, featureID int64, req *SetPlanValueRequest) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := s.plans.SetValue(ctx, id, featureID, req.Value); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("plan value updated", "plan_id", id, "feature_id", featureID)
+	return nil
+}
+
+// GetPlanLicenses returns every license of a product with its customer and
+// plan. The list spans all customers, so it is not available to resellers.
+func (s *Service) GetPlanLicenses(ctx context.Context, productID int64) ([]plan.License, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.plans.GetLicenses(ctx, productID)
+}
+
+// AssignPlan changes the plan of several licenses at once (a nil plan takes
+// them off their plan). Resellers may only change their own customers' licenses.
+func (s *Service) AssignPlan(ctx context.Context, req *AssignPlanRequest) error {
+	for _, id := range req.LicenseIDs {
+		if err := s.checkLicense(ctx, id); err != nil {
+			return err
+		}
+	}
+	if err := s.plans.Assign(ctx, req.PlanID, req.LicenseIDs); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("license plans changed", "plan_id", req.PlanID, "licenses", len(req.LicenseIDs))
+	return nil
+}
+
+// -------------------------
+// Bulk Operations (admin only, they span customers)
 // -------------------------
 
-func (s *Service) GetProductFeatures(ctx context.Context, customerID, productID int64) ([]featurevalue.FeatureValue, error) {
-	return s.featureValues.GetFeatureValues(ctx, customerID, productID)
+func (s *Service) PreviewBulk(ctx context.Context, req *BulkRequest) (*bulk.Preview, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.bulk.Preview(ctx, req.Selection, req.Change)
 }
 
-func (s *Service) UpdateProductFeature(ctx context.Context, customerID, productID, featureID int64, req *UpdateProductFeatureRequest) error {
+func (s *Service) ApplyBulk(ctx context.Context, req *BulkRequest) (*bulk.Operation, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.bulk.Apply(ctx, req.Selection, req.Change)
+}
+
+// GetBulkOperations returns the audit trail of bulk operations (productID 0 = all products)
+func (s *Service) GetBulkOperations(ctx context.Context, productID int64) ([]bulk.Operation, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.bulk.GetOperations(ctx, productID)
+}
+
+func (s *Service) GetBulkOperation(ctx context.Context, id int64) (*bulk.Operation, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.bulk.GetOperation(ctx, id)
+}
+
+// -------------------------
+// Inactivity Policies (admin only, they release seats across customers)
+// -------------------------
+
+func (s *Service) GetProductInactivityPolicy(ctx context.Context, productID int64) (*inactivity.Policy, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.inactivity.GetProductPolicy(ctx, productID)
+}
+
+func (s *Service) SetProductInactivityPolicy(ctx context.Context, productID int64, p *inactivity.Policy) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := s.inactivity.SetProductPolicy(ctx, productID, p); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("product inactivity policy changed", "product_id", productID, "policy", p.String())
+	return nil
+}
+
+func (s *Service) GetLicenseInactivityPolicy(ctx context.Context, licenseID int64) (*inactivity.Policy, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.inactivity.GetLicensePolicy(ctx, licenseID)
+}
+
+func (s *Service) SetLicenseInactivityPolicy(ctx context.Context, licenseID int64, p *inactivity.Policy) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := s.inactivity.SetLicensePolicy(ctx, licenseID, p); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("license inactivity policy changed", "license_id", licenseID, "policy", p.String())
+	return nil
+}
+
+// PreviewInactivity returns what the next inactivity run does (productID 0 = all products)
+func (s *Service) PreviewInactivity(ctx context.Context, productID int64) (*inactivity.Report, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.inactivity.Preview(ctx, productID)
+}
+
+// RunInactivity applies the inactivity policies now instead of waiting for
+// the scheduled run
+func (s *Service) RunInactivity(ctx context.Context) (*inactivity.Result, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.inactivity.Run(ctx)
+}
+
+// -------------------------
+// License Feature Values (license-specific overrides)
+// -------------------------
+
+func (s *Service) GetLicenseFeatures(ctx context.Context, licenseID int64) ([]featurevalue.FeatureValue, error) {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return nil, err
+	}
+	return s.featureValues.GetFeatureValues(ctx, licenseID)
+}
+
+func (s *Service) UpdateLicenseFeature(ctx context.Context, licenseID, featureID int64, req *UpdateLicenseFeatureRequest) error {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return err
+	}
 	fv := &featurevalue.FeatureValue{
-		CustomerID:   customerID,
-		ProductID:    productID,
+		LicenseID:    licenseID,
 		FeatureID:    featureID,
 		FeatureValue: req.Value,
 	}
-	return s.featureValues.Update(ctx, fv)
+	if err := s.featureValues.Update(ctx, fv); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("feature value updated",
+		"license_id", licenseID,
+		"feature_id", featureID,
+	)
+	return nil
 }
 
 // -------------------------
 // Machine Registrations
 // -------------------------
 
-func (s *Service) GetMachineRegistrations(ctx context.Context, customerID, productID int64, activeOnly bool) ([]machine.Machine, error) {
+// GetMachineRegistrations returns the machines registered against a license
+// with their registration of its product, including when each was last seen
+func (s *Service) GetMachineRegistrations(ctx context.Context, licenseID int64, activeOnly bool) ([]MachineRegistration, error) {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return nil, err
+	}
+	lic, err := s.licenses.Get(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var machines []machine.Machine
 	if activeOnly {
-		return s.machines.GetActiveForLicense(ctx, customerID, productID)
+		machines, err = s.machines.GetActiveForLicense(ctx, licenseID)
+	} else {
+		machines, err = s.machines.GetForLicense(ctx, licenseID)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return s.machines.GetForLicense(ctx, customerID, productID)
+
+	out := make([]MachineRegistration, len(machines))
+	for i, m := range machines {
+		out[i].Machine = m
+		reg, err := s.registrations.Get(ctx, m.MachineID, lic.ProductID)
+		if err != nil {
+			continue
+		}
+		out[i].RegistrationHash = reg.RegistrationHash
+		out[i].ExpirationDate = reg.ExpirationDate
+		out[i].FirstRegistrationDate = reg.FirstRegistrationDate
+		out[i].LastRegistrationDate = reg.LastRegistrationDate
+		out[i].InstalledVersion = reg.InstalledVersion
+		out[i].LastSeenAt = reg.LastSeenAt
+		out[i].ClientOS = reg.ClientOS
+		out[i].ClientHostname = reg.ClientHostname
+		out[i].ClientIP = reg.ClientIP
+	}
+	return out, nil
 }
 
 func (s *Service) DeleteMachineRegistration(ctx context.Context, machineID, productID int64) error {
-	return s.registrations.Delete(ctx, machineID, productID)
+	if err := s.checkMachine(ctx, machineID); err != nil {
+		return err
+	}
+	if err := s.registrations.Delete(ctx, machineID, productID); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("machine registration deleted", "machine_id", machineID, "product_id", productID)
+	return nil
+}
+
+func (s *Service) TransferMachine(ctx context.Context, machineID int64, req *TransferMachineRequest) (*machine.Transfer, error) {
+	if err := s.checkMachine(ctx, machineID); err != nil {
+		return nil, err
+	}
+	if err := s.checkCustomer(ctx, req.ToCustomerID); err != nil {
+		return nil, err
+	}
+	return s.activations.TransferMachine(ctx, machineID, req.ToCustomerID, req.Reason)
+}
+
+func (s *Service) GetMachineTransfers(ctx context.Context, machineID int64) ([]machine.Transfer, error) {
+	if err := s.checkMachine(ctx, machineID); err != nil {
+		return nil, err
+	}
+	return s.machines.GetTransfers(ctx, machineID)
+}
+
+// CreateManualRegistration registers a customer's machine for a product
+// without the license key and returns its registration file
+func (s *Service) CreateManualRegistration(ctx context.Context, req *ManualRegistrationRequest) (*RegistrationFile, error) {
+	if err := s.checkCustomer(ctx, req.CustomerID); err != nil {
+		return nil, err
+	}
+	resp, err := s.activations.ActivateForCustomer(ctx, req.CustomerID, req.ProductID, &activation.Request{
+		MachineCode: req.MachineCode,
+		UserName:    req.UserName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.registrationFile(ctx, req.ProductID, resp)
+}
+
+// ExportRegistration returns the registration file of a machine's registration
+// as stored; nothing is changed
+func (s *Service) ExportRegistration(ctx context.Context, machineID, productID int64) (*RegistrationFile, error) {
+	if err := s.checkMachine(ctx, machineID); err != nil {
+		return nil, err
+	}
+	resp, err := s.activations.BuildRegistration(ctx, machineID, productID)
+	if err != nil {
+		return nil, err
+	}
+	return s.registrationFile(ctx, productID, resp)
+}
+
+// ExportLicenseRegistrations returns the registration files of a license's
+// active registrations
+func (s *Service) ExportLicenseRegistrations(ctx context.Context, licenseID int64) (*RegistrationExport, error) {
+	if err := s.checkLicense(ctx, licenseID); err != nil {
+		return nil, err
+	}
+	lic, err := s.licenses.Get(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+	machines, err := s.machines.GetActiveForLicense(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &RegistrationExport{Files: make([]RegistrationFile, 0, len(machines))}
+	names := map[string]int{}
+	for _, m := range machines {
+		f, err := s.ExportRegistration(ctx, m.MachineID, lic.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		// Machines of the same user get numbered files
+		if n := names[f.Name]; n > 0 {
+			names[f.Name]++
+			f.Name = fmt.Sprintf("%s-%d.json", strings.TrimSuffix(f.Name, ".json"), n+1)
+		} else {
+			names[f.Name] = 1
+		}
+		out.Files = append(out.Files, *f)
+	}
+
+	prod, err := s.products.Get(ctx, lic.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	cust, err := s.customers.Get(ctx, lic.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	out.Name = activation.FileName(".zip", prod.ProductName, cust.CustomerName, "registrations")
+	return out, nil
+}
+
+func (s *Service) registrationFile(ctx context.Context, productID int64, resp *activation.Response) (*RegistrationFile, error) {
+	prod, err := s.products.Get(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistrationFile{Name: activation.FileName(".json", prod.ProductName, resp.UserCompany, resp.UserName), Registration: resp}, nil
 }
 
 // -------------------------
@@ -243,5 +1062,116 @@ func (s *Service) DeleteMachineRegistration(ctx context.Context, machineID, prod
 // -------------------------
 
 func (s *Service) GetExpiredLicenses(ctx context.Context, before string) ([]license.ExpiredLicense, error) {
-	return s.licenses.GetExpiredLicenses(ctx, before)
+	expired, err := s.licenses.GetExpiredLicenses(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	resellerID, ok := reseller.ScopeFromContext(ctx)
+	if !ok {
+		return expired, nil
+	}
+	customers, err := s.customers.GetForReseller(ctx, resellerID)
+	if err != nil {
+		return nil, err
+	}
+	own := make(map[int64]bool, len(customers))
+	for _, c := range customers {
+		own[c.CustomerID] = true
+	}
+	out := []license.ExpiredLicense{}
+	for _, el := range expired {
+		if own[el.CustomerID] {
+			out = append(out, el)
+		}
+	}
+	return out, nil
+}
+
+// -------------------------
+// Resellers
+// -------------------------
+
+func (s *Service) GetResellers(ctx context.Context) ([]reseller.Reseller, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.resellers.GetAll(ctx)
+}
+
+// GetReseller returns a reseller. A reseller API key can only read its own reseller.
+func (s *Service) GetReseller(ctx context.Context, id int64) (*reseller.Reseller, error) {
+	if err := checkReseller(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.resellers.Get(ctx, id)
+}
+
+func (s *Service) CreateReseller(ctx context.Context, req *CreateResellerRequest) (*CreateResellerResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	r, key, err := s.resellers.Create(ctx, &reseller.Reseller{
+		ResellerName:   req.ResellerName,
+		Email:          req.Email,
+		SeatAllocation: req.SeatAllocation,
+		Notes:          req.Notes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("reseller created",
+		"reseller_id", r.ResellerID,
+		"seat_allocation", r.SeatAllocation,
+	)
+	return &CreateResellerResponse{Reseller: r, APIKey: key}, nil
+}
+
+func (s *Service) UpdateReseller(ctx context.Context, id int64, req *UpdateResellerRequest) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	err := s.resellers.Update(ctx, &reseller.Reseller{
+		ResellerID:     id,
+		ResellerName:   req.ResellerName,
+		Email:          req.Email,
+		SeatAllocation: req.SeatAllocation,
+		Notes:          req.Notes,
+	})
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("reseller updated", "reseller_id", id, "seat_allocation", req.SeatAllocation)
+	return nil
+}
+
+func (s *Service) DeleteReseller(ctx context.Context, id int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := s.resellers.Delete(ctx, id); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("reseller deleted", "reseller_id", id)
+	return nil
+}
+
+func (s *Service) RotateResellerKey(ctx context.Context, id int64) (*RotateResellerKeyResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	key, err := s.resellers.RotateKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("reseller key rotated", "reseller_id", id)
+	return &RotateResellerKeyResponse{APIKey: key}, nil
+}
+
+// GetResellerUsage reports a reseller's seats. A reseller API key can only read its own usage.
+func (s *Service) GetResellerUsage(ctx context.Context, id int64) (*reseller.Usage, error) {
+	if err := checkReseller(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.resellers.Usage(ctx, id)
 }
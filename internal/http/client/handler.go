@@ -2,17 +2,24 @@ package client
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 
 	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/bundle"
 	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/feature"
 	"winsbygroup.com/regserver/internal/featurevalue"
 	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
 	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/metrics"
 	"winsbygroup.com/regserver/internal/middleware"
 	"winsbygroup.com/regserver/internal/product"
 	"winsbygroup.com/regserver/internal/registration"
@@ -27,6 +34,7 @@ type Handler struct {
 	FeatureService      *feature.Service
 	FeatureValueService *featurevalue.Service
 	CustomerService     *customer.Service
+	BundleService       *bundle.Service
 }
 
 func NewHandler(
@@ -38,6 +46,7 @@ func NewHandler(
 	f *feature.Service,
 	fv *featurevalue.Service,
 	c *customer.Service,
+	b *bundle.Service,
 ) *Handler {
 	return &Handler{
 		ActivationService:   a,
@@ -48,6 +57,7 @@ func NewHandler(
 		FeatureService:      f,
 		FeatureValueService: fv,
 		CustomerService:     c,
+		BundleService:       b,
 	}
 }
 
@@ -55,12 +65,16 @@ func NewHandler(
 func (h *Handler) Activate(c echo.Context) error {
 	var req activation.Request
 	if err := c.Bind(&req); err != nil {
+		metrics.Activations.WithLabelValues("failure", "bad_request").Inc()
+		logging.FromContext(c.Request().Context()).Warn("activation failed", "reason", "bad_request", "error", err)
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "invalid request body",
 		})
 	}
 
-	// Get customerID and productID from context (set by LicenseKeyAuth middleware)
+	req.ClientIP = c.RealIP()
+
+	// Get the license from context (set by LicenseKeyAuth middleware)
 	lic, ok := c.Get("license").(middleware.LicenseContext)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -68,12 +82,86 @@ func (h *Handler) Activate(c echo.Context) error {
 		})
 	}
 
+	// A bundle license key activates the product the client names
+	productID, err := h.ActivationService.SelectProduct(c.Request().Context(), lic.Products(), req.ProductGUID)
+	if err != nil {
+		h.recordActivation(c.Request().Context(), lic.CustomerID, 0, err)
+		return productError(c, err)
+	}
+
 	resp, err := h.ActivationService.Activate(
 		c.Request().Context(),
-		lic.CustomerID,
-		lic.ProductID,
+		lic.LicenseFor(productID),
 		&req,
 	)
+	h.recordActivation(c.Request().Context(), lic.CustomerID, productID, err)
+	if code := license.ErrorCode(err); code != "" {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+			"code":  code,
+		})
+	}
+	if errors.Is(err, activation.ErrLicenseCountExceeded) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+			"code":  activation.CodeSeatLimit,
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// RefreshRequest is the request body for refreshing a machine's registration
+type RefreshRequest struct {
+	MachineCode string `json:"machineCode"`
+	ProductGUID string `json:"productGuid,omitempty"` // required for bundle license keys
+}
+
+// POST /refresh
+func (h *Handler) Refresh(c echo.Context) error {
+	var req RefreshRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request body",
+		})
+	}
+	if req.MachineCode == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "machineCode is required",
+		})
+	}
+
+	// Get the license from context (set by LicenseKeyAuth middleware)
+	lic, ok := c.Get("license").(middleware.LicenseContext)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "invalid license context",
+		})
+	}
+
+	ctx := c.Request().Context()
+	productID, err := h.ActivationService.SelectProduct(ctx, lic.Products(), req.ProductGUID)
+	if err != nil {
+		return productError(c, err)
+	}
+
+	resp, err := h.ActivationService.Refresh(ctx, lic.LicenseFor(productID), req.MachineCode)
+	if code := license.ErrorCode(err); code != "" {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+			"code":  code,
+		})
+	}
+	if errors.Is(err, activation.ErrNotRegistered) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -83,6 +171,30 @@ func (h *Handler) Activate(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+// recordActivation updates the activation metrics (and logs failures) with the result of an activation
+func (h *Handler) recordActivation(ctx context.Context, customerID, productID int64, err error) {
+	if err == nil {
+		metrics.Activations.WithLabelValues("success", "").Inc()
+		return
+	}
+
+	reason := activation.FailureReason(err)
+	metrics.Activations.WithLabelValues("failure", reason).Inc()
+	logging.FromContext(ctx).Warn("activation failed",
+		"customer_id", customerID,
+		"product_id", productID,
+		"reason", reason,
+		"error", err,
+	)
+	if reason == activation.CodeSeatLimit {
+		product := strconv.FormatInt(productID, 10)
+		if p, perr := h.ProductService.Get(ctx, productID); perr == nil {
+			product = p.ProductName
+		}
+		metrics.SeatLimitRejections.WithLabelValues(product).Inc()
+	}
+}
+
 // GET /productver/:guid
 func (h *Handler) GetProductVersion(c echo.Context) error {
 	guid := c.Param("guid")
@@ -111,8 +223,11 @@ func (h *Handler) GetProductVersion(c echo.Context) error {
 	})
 }
 
-// LicenseInfoResponse is the response for the license info endpoint
+// LicenseInfoResponse is the response for the license info endpoint.
+// LicenseKey is the license's current key, which differs from the requested
+// key when an old key is used during its rotation grace period.
 type LicenseInfoResponse struct {
+	LicenseKey          string         `json:"LicenseKey"`
 	CustomerName        string         `json:"CustomerName"`
 	ProductGUID         string         `json:"ProductGUID"`
 	ProductName         string         `json:"ProductName"`
@@ -137,16 +252,9 @@ func (h *Handler) GetLicenseInfo(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get the license by key
-	lic, err := h.LicenseService.GetByLicenseKey(ctx, licenseKey)
+	lic, err := h.licenseForKey(ctx, licenseKey, c.QueryParam("productGuid"))
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return c.JSON(http.StatusNotFound, map[string]string{
-				"error": "license not found",
-			})
-		}
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return licenseKeyError(c, err)
 	}
 
 	// Get product info
@@ -166,7 +274,7 @@ func (h *Handler) GetLicenseInfo(c echo.Context) error {
 	}
 
 	// Get active (non-expired) machine registrations count
-	activeMachines, err := h.MachineService.GetActiveForLicense(ctx, lic.CustomerID, lic.ProductID)
+	activeMachines, err := h.MachineService.GetActiveForLicense(ctx, lic.LicenseID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -178,8 +286,8 @@ func (h *Handler) GetLicenseInfo(c echo.Context) error {
 		licensesAvailable = 0
 	}
 
-	// Get feature values (merged with defaults)
-	features, err := h.mergeFeatures(ctx, lic.CustomerID, lic.ProductID)
+	// Get feature values (merged with defaults) as a client activating today gets them
+	features, err := h.mergeFeatures(ctx, lic, time.Now().Format("2006-01-02"))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -187,6 +295,7 @@ func (h *Handler) GetLicenseInfo(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, LicenseInfoResponse{
+		LicenseKey:          lic.LicenseKey,
 		CustomerName:        cust.CustomerName,
 		ProductGUID:         prod.ProductGUID,
 		ProductName:         prod.ProductName,
@@ -200,10 +309,84 @@ func (h *Handler) GetLicenseInfo(c echo.Context) error {
 	})
 }
 
+// licenseForKey returns the license a key gives access to. For a bundle
+// license key that is the bundle license of the product named by productGUID.
+func (h *Handler) licenseForKey(ctx context.Context, licenseKey, productGUID string) (*license.License, error) {
+	lic, err := h.LicenseService.GetByActiveKey(ctx, licenseKey)
+	if err == nil || !errors.Is(err, license.ErrNotFound) {
+		return lic, err
+	}
+
+	ref, berr := h.BundleService.ResolveKey(ctx, licenseKey)
+	if license.ErrorCode(berr) != "" {
+		return nil, berr // a bundle key that is not active
+	}
+	if berr != nil {
+		return nil, err // report the license key lookup error
+	}
+	productID, err := h.ActivationService.SelectProduct(ctx, ref.ProductIDs, productGUID)
+	if err != nil {
+		return nil, err
+	}
+	lic, err = h.LicenseService.Get(ctx, ref.LicenseFor(productID))
+	if err != nil {
+		return nil, err
+	}
+	if err := lic.CheckStatus(); err != nil {
+		return nil, err
+	}
+	return lic, nil
+}
+
+// productError answers a license key that does not select a product: 400 when
+// a bundle key is used without a product GUID, 403 when the key does not
+// cover the product
+func productError(c echo.Context, err error) error {
+	if errors.Is(err, activation.ErrProductRequired) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, activation.ErrProductNotLicensed) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": err.Error(),
+	})
+}
+
+// licenseKeyError answers a failed license key lookup: 403 with an error code
+// for a suspended or cancelled license, or a key that is suspended, revoked or
+// past its rotation grace period; 400 or 403 when the key does not select a
+// product; 404 for an unknown key, which counts toward the client's lockout
+func licenseKeyError(c echo.Context, err error) error {
+	switch code := license.ErrorCode(err); {
+	case errors.Is(err, activation.ErrProductRequired), errors.Is(err, activation.ErrProductNotLicensed):
+		return productError(c, err)
+	case code != "":
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+			"code":  code,
+		})
+	case errors.Is(err, license.ErrNotFound):
+		middleware.AuthFailed(c)
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "license not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": err.Error(),
+	})
+}
+
 // UpdateLicenseRequest is the request body for updating license/machine info
 type UpdateLicenseRequest struct {
 	MachineCode      string `json:"machineCode"`
 	InstalledVersion string `json:"installedVersion"`
+	OS               string `json:"os,omitempty"`       // optional, shown to support with the registration
+	Hostname         string `json:"hostname,omitempty"` // optional, shown to support with the registration
 }
 
 // PUT /license/:license_key
@@ -231,26 +414,43 @@ func (h *Handler) UpdateLicenseInfo(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get the license by key
-	lic, err := h.LicenseService.GetByLicenseKey(ctx, licenseKey)
+	lic, err := h.licenseForKey(ctx, licenseKey, c.QueryParam("productGuid"))
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return c.JSON(http.StatusNotFound, map[string]string{
-				"error": "license not found",
-			})
+		return licenseKeyError(c, err)
+	}
+
+	// Find the machine by code, including one whose fingerprint has drifted.
+	// Only a machine holding an unreleased registration of this license may
+	// check in with its key; otherwise the matcher's update is rolled back.
+	var m *machine.Machine
+	err = h.RegistrationService.WithTx(ctx, func(tx *sqlx.Tx) error {
+		m, err = h.MachineService.Find(ctx, tx, lic.CustomerID, req.MachineCode)
+		if err != nil || m == nil {
+			return err
 		}
-		return c.JSON(http.StatusInternalServerError, map[string]string{
+		reg, err := h.RegistrationService.Get(ctx, m.MachineID, lic.ProductID)
+		if err != nil {
+			if errors.Is(err, registration.ErrNotFound) {
+				return activation.ErrNotRegistered
+			}
+			return err
+		}
+		if reg.LicenseID == nil || *reg.LicenseID != lic.LicenseID || reg.IsReleased() {
+			return activation.ErrNotRegistered
+		}
+		return nil
+	})
+	if errors.Is(err, activation.ErrNotRegistered) {
+		return c.JSON(http.StatusNotFound, map[string]string{
 			"error": err.Error(),
 		})
 	}
-
-	// Find the machine by code
-	machine, err := h.MachineService.GetByCode(ctx, lic.CustomerID, req.MachineCode)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
 		})
 	}
-	if machine == nil {
+	if m == nil {
 		return c.JSON(http.StatusNotFound, map[string]string{
 			"error": "machine not found for this license",
 		})
@@ -258,7 +458,7 @@ func (h *Handler) UpdateLicenseInfo(c echo.Context) error {
 
 	// Update installed version if provided
 	if req.InstalledVersion != "" {
-		err = h.RegistrationService.UpdateInstalledVersion(ctx, machine.MachineID, lic.ProductID, req.InstalledVersion)
+		err = h.RegistrationService.UpdateInstalledVersion(ctx, m.MachineID, lic.ProductID, req.InstalledVersion)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") {
 				return c.JSON(http.StatusNotFound, map[string]string{
@@ -271,6 +471,14 @@ func (h *Handler) UpdateLicenseInfo(c echo.Context) error {
 		}
 	}
 
+	// Record the check-in: last seen, client details and the inactivity policy
+	info := registration.ClientInfo{OS: req.OS, Hostname: req.Hostname, IP: c.RealIP()}
+	if err := h.RegistrationService.CheckIn(ctx, m.MachineID, lic.ProductID, info); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
 	// Return the same response as GET /license/:license_key
 	prod, err := h.ProductService.Get(ctx, lic.ProductID)
 	if err != nil {
@@ -286,7 +494,7 @@ func (h *Handler) UpdateLicenseInfo(c echo.Context) error {
 		})
 	}
 
-	activeMachines, err := h.MachineService.GetActiveForLicense(ctx, lic.CustomerID, lic.ProductID)
+	activeMachines, err := h.MachineService.GetActiveForLicense(ctx, lic.LicenseID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -298,7 +506,13 @@ func (h *Handler) UpdateLicenseInfo(c echo.Context) error {
 		licensesAvailable = 0
 	}
 
-	features, err := h.mergeFeatures(ctx, lic.CustomerID, lic.ProductID)
+	// The machine's features as of its last activation, matching its registration file
+	asOf := time.Now().Format("2006-01-02")
+	if reg, err := h.RegistrationService.Get(ctx, m.MachineID, lic.ProductID); err == nil {
+		asOf = reg.LastRegistrationDate
+	}
+
+	features, err := h.mergeFeatures(ctx, lic, asOf)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -306,6 +520,7 @@ func (h *Handler) UpdateLicenseInfo(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, LicenseInfoResponse{
+		LicenseKey:          lic.LicenseKey,
 		CustomerName:        cust.CustomerName,
 		ProductGUID:         prod.ProductGUID,
 		ProductName:         prod.ProductName,
@@ -319,17 +534,23 @@ func (h *Handler) UpdateLicenseInfo(c echo.Context) error {
 	})
 }
 
-// mergeFeatures returns feature values with customer overrides applied to defaults
-func (h *Handler) mergeFeatures(ctx context.Context, customerID, productID int64) (map[string]any, error) {
-	defs, err := h.FeatureService.GetForProduct(ctx, productID)
+// mergeFeatures returns typed feature values with the license's plan values and
+// overrides applied to defaults, named as emitted to a registration activated on asOf
+func (h *Handler) mergeFeatures(ctx context.Context, lic *license.License, asOf string) (map[string]any, error) {
+	defs, err := h.FeatureService.GetAsOf(ctx, lic.ProductID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	planVals, err := h.FeatureValueService.GetPlanValues(ctx, lic.LicenseID)
 	if err != nil {
 		return nil, err
 	}
 
-	vals, err := h.FeatureValueService.GetFeatureValues(ctx, customerID, productID)
+	vals, err := h.FeatureValueService.GetFeatureValues(ctx, lic.LicenseID)
 	if err != nil {
 		return nil, err
 	}
 
-	return feature.MergeWithOverrides(defs, vals), nil
+	return feature.TypedValues(defs, feature.MergeWithOverrides(defs, planVals, vals)), nil
 }
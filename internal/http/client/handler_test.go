@@ -7,17 +7,23 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	_ "github.com/mattn/go-sqlite3"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 
 	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/bundle"
+	"winsbygroup.com/regserver/internal/config"
 	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/feature"
 	"winsbygroup.com/regserver/internal/featurevalue"
 	"winsbygroup.com/regserver/internal/http/client"
 	"winsbygroup.com/regserver/internal/license"
 	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/metrics"
 	"winsbygroup.com/regserver/internal/middleware"
 	"winsbygroup.com/regserver/internal/product"
 	"winsbygroup.com/regserver/internal/registration"
@@ -46,9 +52,10 @@ func TestGetProductVersion(t *testing.T) {
 		productSvc,
 		featureSvc,
 		featureValueSvc,
+		analytics.NewService(db),
 	)
 
-	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc)
+	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc, bundle.NewService(db, licenseSvc))
 
 	// Create a test product
 	testProduct := &product.Product{
@@ -171,9 +178,10 @@ func TestActivate(t *testing.T) {
 		productSvc,
 		featureSvc,
 		featureValueSvc,
+		analytics.NewService(db),
 	)
 
-	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc)
+	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc, bundle.NewService(db, licenseSvc))
 
 	// Setup test data
 	testCustomer := &customer.Customer{
@@ -230,6 +238,7 @@ func TestActivate(t *testing.T) {
 
 		// Set the license context (normally set by middleware)
 		c.Set("license", middleware.LicenseContext{
+			LicenseID:  lic.LicenseID,
 			CustomerID: createdCustomer.CustomerID,
 			ProductID:  createdProduct.ProductID,
 		})
@@ -323,6 +332,121 @@ func TestActivate(t *testing.T) {
 			t.Errorf("expected error %q, got %q", "invalid request body", resp["error"])
 		}
 	})
+
+	t.Run("records seat limit rejections", func(t *testing.T) {
+		single, err := productSvc.Create(ctx, &product.Product{
+			ProductName:   "Single Seat App",
+			ProductGUID:   "PROD-GUID-SINGLE",
+			LatestVersion: "1.0.0",
+			DownloadURL:   "https://example.com/download",
+		})
+		if err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+		singleLic, err := licenseSvc.Create(ctx, &license.License{
+			CustomerID:          createdCustomer.CustomerID,
+			ProductID:           single.ProductID,
+			LicenseKey:          "REG-GUID-SINGLE",
+			LicenseCount:        1,
+			StartDate:           "2024-01-01",
+			ExpirationDate:      "2099-12-31",
+			MaintExpirationDate: "2099-12-31",
+		})
+		if err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+
+		before := promtest.ToFloat64(metrics.Activations.WithLabelValues("failure", "seat_limit"))
+		var rec *httptest.ResponseRecorder
+		for _, code := range []string{"SEAT-MACHINE-1", "SEAT-MACHINE-2"} {
+			body, _ := json.Marshal(activation.Request{MachineCode: code, UserName: "testuser"})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/activate", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec = httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.Set("license", middleware.LicenseContext{
+				LicenseID:  singleLic.LicenseID,
+				CustomerID: createdCustomer.CustomerID,
+				ProductID:  single.ProductID,
+			})
+			if err := handler.Activate(c); err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+		}
+
+		// The second machine finds every seat taken
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp map[string]string
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp["code"] != "seat_limit" {
+			t.Errorf("expected code seat_limit, got %q", resp["code"])
+		}
+
+		if got := promtest.ToFloat64(metrics.SeatLimitRejections.WithLabelValues("Single Seat App")); got != 1 {
+			t.Errorf("expected 1 seat limit rejection, got %v", got)
+		}
+		if got := promtest.ToFloat64(metrics.Activations.WithLabelValues("failure", "seat_limit")) - before; got != 1 {
+			t.Errorf("expected 1 seat limit failure, got %v", got)
+		}
+	})
+
+	t.Run("bundle key activates the product named by GUID", func(t *testing.T) {
+		bundleSvc := bundle.NewService(db, licenseSvc)
+		p1, _ := productSvc.Create(ctx, &product.Product{ProductName: "Suite Editor", ProductGUID: "PROD-GUID-SUITE-1"})
+		p2, _ := productSvc.Create(ctx, &product.Product{ProductName: "Suite Viewer", ProductGUID: "PROD-GUID-SUITE-2"})
+		b, err := bundleSvc.Create(ctx, &bundle.Bundle{BundleName: "Suite"}, []int64{p1.ProductID, p2.ProductID})
+		if err != nil {
+			t.Fatalf("create bundle: %v", err)
+		}
+		cb, err := bundleSvc.License(ctx, createdCustomer.CustomerID, b.BundleID, &license.License{
+			LicenseCount:        2,
+			StartDate:           "2024-01-01",
+			ExpirationDate:      "2099-12-31",
+			MaintExpirationDate: "2099-12-31",
+		})
+		if err != nil {
+			t.Fatalf("license bundle: %v", err)
+		}
+
+		activate := func(productGUID string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(activation.Request{MachineCode: "SUITE-MACHINE", UserName: "testuser", ProductGUID: productGUID})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/activate", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.Set("license", middleware.LicenseContext{
+				CustomerID: cb.CustomerID,
+				BundleID:   cb.BundleID,
+				ProductIDs: []int64{cb.Licenses[0].ProductID, cb.Licenses[1].ProductID},
+				LicenseIDs: []int64{cb.Licenses[0].LicenseID, cb.Licenses[1].LicenseID},
+			})
+			if err := handler.Activate(c); err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+			return rec
+		}
+
+		rec := activate("prod-guid-suite-2")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp activation.Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		if resp.ProductGUID != p2.ProductGUID {
+			t.Errorf("expected ProductGUID %q, got %q", p2.ProductGUID, resp.ProductGUID)
+		}
+
+		if rec := activate(""); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 without a product GUID, got %d", rec.Code)
+		}
+		if rec := activate(createdProduct.ProductGUID); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403 for a product outside the bundle, got %d", rec.Code)
+		}
+	})
 }
 
 func TestRegisterRoutes(t *testing.T) {
@@ -345,9 +469,10 @@ func TestRegisterRoutes(t *testing.T) {
 		productSvc,
 		featureSvc,
 		featureValueSvc,
+		analytics.NewService(db),
 	)
 
-	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc)
+	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc, bundle.NewService(db, licenseSvc))
 
 	e := echo.New()
 	g := e.Group("/api/v1")
@@ -355,12 +480,13 @@ func TestRegisterRoutes(t *testing.T) {
 	noopMiddleware := func(next echo.HandlerFunc) echo.HandlerFunc {
 		return next
 	}
-	client.RegisterRoutes(g, handler, noopMiddleware)
+	client.RegisterRoutes(g, handler, noopMiddleware, noopMiddleware)
 
 	// Verify routes are registered
 	routes := e.Routes()
 	expectedRoutes := map[string]string{
 		"POST:/api/v1/activate":            "activate",
+		"POST:/api/v1/refresh":             "refresh",
 		"GET:/api/v1/productver/:guid":     "productver",
 		"GET:/api/v1/license/:license_key": "license",
 		"PUT:/api/v1/license/:license_key": "license update",
@@ -404,9 +530,10 @@ func TestGetLicenseInfo(t *testing.T) {
 		productSvc,
 		featureSvc,
 		featureValueSvc,
+		analytics.NewService(db),
 	)
 
-	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc)
+	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc, bundle.NewService(db, licenseSvc))
 
 	// Setup test data
 	testCustomer := &customer.Customer{
@@ -516,7 +643,7 @@ func TestGetLicenseInfo(t *testing.T) {
 
 	t.Run("returns correct available count with active registrations", func(t *testing.T) {
 		// Activate a machine to reduce available count
-		activationSvc.Activate(ctx, createdCustomer.CustomerID, createdProduct.ProductID, &activation.Request{
+		activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{
 			MachineCode: "MACHINE-INFO-001",
 			UserName:    "testuser",
 		})
@@ -543,6 +670,77 @@ func TestGetLicenseInfo(t *testing.T) {
 			t.Errorf("expected LicensesAvailable %d after 1 activation, got %d", 4, resp.LicensesAvailable)
 		}
 	})
+
+	t.Run("old key returns new key during grace and 403 when suspended", func(t *testing.T) {
+		rotated, err := licenseSvc.RotateKey(ctx, lic.LicenseID, time.Hour, "leaked")
+		if err != nil {
+			t.Fatalf("rotate key: %v", err)
+		}
+
+		get := func() *httptest.ResponseRecorder {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/license/LICENSE-KEY-123", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("license_key")
+			c.SetParamValues("LICENSE-KEY-123")
+			if err := handler.GetLicenseInfo(c); err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+			return rec
+		}
+
+		rec := get()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d during grace, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp client.LicenseInfoResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		if resp.LicenseKey != rotated.LicenseKey {
+			t.Errorf("expected LicenseKey %q, got %q", rotated.LicenseKey, resp.LicenseKey)
+		}
+
+		if err := licenseSvc.SetKeyStatus(ctx, lic.LicenseID, license.KeySuspended, ""); err != nil {
+			t.Fatalf("suspend key: %v", err)
+		}
+		if rec := get(); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d for suspended key, got %d", http.StatusForbidden, rec.Code)
+		}
+		if err := licenseSvc.SetKeyStatus(ctx, lic.LicenseID, license.KeyActive, ""); err != nil {
+			t.Fatalf("reactivate key: %v", err)
+		}
+	})
+
+	t.Run("returns 403 with code for suspended license", func(t *testing.T) {
+		if err := licenseSvc.SetStatus(ctx, lic.LicenseID, license.StatusSuspended, "invoice overdue"); err != nil {
+			t.Fatalf("suspend license: %v", err)
+		}
+		defer licenseSvc.SetStatus(ctx, lic.LicenseID, license.StatusActive, "")
+
+		cur, _ := licenseSvc.Get(ctx, lic.LicenseID)
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/license/"+cur.LicenseKey, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("license_key")
+		c.SetParamValues(cur.LicenseKey)
+
+		if err := handler.GetLicenseInfo(c); err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		if body["code"] != license.CodeLicenseSuspended {
+			t.Errorf("expected code %q, got %q", license.CodeLicenseSuspended, body["code"])
+		}
+	})
 }
 
 func TestUpdateLicenseInfo(t *testing.T) {
@@ -568,9 +766,10 @@ func TestUpdateLicenseInfo(t *testing.T) {
 		productSvc,
 		featureSvc,
 		featureValueSvc,
+		analytics.NewService(db),
 	)
 
-	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc)
+	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc, bundle.NewService(db, licenseSvc))
 
 	// Setup test data
 	testCustomer := &customer.Customer{
@@ -612,7 +811,7 @@ func TestUpdateLicenseInfo(t *testing.T) {
 	}
 
 	// Activate a machine first
-	_, err = activationSvc.Activate(ctx, createdCustomer.CustomerID, createdProduct.ProductID, &activation.Request{
+	_, err = activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{
 		MachineCode: "UPDATE-MACHINE-001",
 		UserName:    "updateuser",
 	})
@@ -660,6 +859,130 @@ func TestUpdateLicenseInfo(t *testing.T) {
 		}
 	})
 
+	t.Run("records last seen and client details", func(t *testing.T) {
+		put := func(reqBody client.UpdateLicenseRequest) {
+			t.Helper()
+			body, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/license/UPDATE-LICENSE-KEY", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderXRealIP, "203.0.113.7")
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.SetParamNames("license_key")
+			c.SetParamValues("UPDATE-LICENSE-KEY")
+			if err := handler.UpdateLicenseInfo(c); err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+		}
+		stored := func() *registration.Registration {
+			t.Helper()
+			m, err := machineSvc.GetByCode(ctx, createdCustomer.CustomerID, "UPDATE-MACHINE-001")
+			if err != nil || m == nil {
+				t.Fatalf("get machine: %v", err)
+			}
+			reg, err := regSvc.Get(ctx, m.MachineID, createdProduct.ProductID)
+			if err != nil {
+				t.Fatalf("get registration: %v", err)
+			}
+			return reg
+		}
+
+		put(client.UpdateLicenseRequest{MachineCode: "UPDATE-MACHINE-001", InstalledVersion: "3.5.0", OS: "Windows 11", Hostname: "WS-042"})
+		reg := stored()
+		if reg.LastSeenAt == "" || reg.ClientOS != "Windows 11" || reg.ClientHostname != "WS-042" || reg.ClientIP != "203.0.113.7" {
+			t.Errorf("expected the check-in to record the client, got %+v", reg)
+		}
+
+		// Clients that do not report their details keep the last ones
+		put(client.UpdateLicenseRequest{MachineCode: "UPDATE-MACHINE-001", InstalledVersion: "3.5.0"})
+		if reg := stored(); reg.ClientOS != "Windows 11" || reg.ClientHostname != "WS-042" {
+			t.Errorf("expected the client details to be kept, got %+v", reg)
+		}
+	})
+
+	t.Run("checks in a machine whose fingerprint drifted", func(t *testing.T) {
+		machineSvc.SetFingerprintMatch(2)
+		defer machineSvc.SetFingerprintMatch(0)
+		if _, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: "fp1.cpu.board.disk", UserName: "updateuser"}); err != nil {
+			t.Fatalf("activate machine: %v", err)
+		}
+
+		body, _ := json.Marshal(client.UpdateLicenseRequest{MachineCode: "fp1.cpu.board.NEWDISK"})
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/license/UPDATE-LICENSE-KEY", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("license_key")
+		c.SetParamValues("UPDATE-LICENSE-KEY")
+		if err := handler.UpdateLicenseInfo(c); err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+	})
+
+	checkIn := func(key, machineCode string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(client.UpdateLicenseRequest{MachineCode: machineCode})
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/license/"+key, bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("license_key")
+		c.SetParamValues(key)
+		if err := handler.UpdateLicenseInfo(c); err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		return rec
+	}
+
+	t.Run("returns 404 for a key of another license", func(t *testing.T) {
+		other := *lic
+		other.LicenseKey = "UPDATE-OTHER-KEY"
+		if _, err := licenseSvc.Create(ctx, &other); err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+		m, err := machineSvc.GetByCode(ctx, createdCustomer.CustomerID, "UPDATE-MACHINE-001")
+		if err != nil || m == nil {
+			t.Fatalf("get machine: %v", err)
+		}
+		if _, err := db.Exec("UPDATE registration SET idle_date = '2024-06-01' WHERE machine_id = ?", m.MachineID); err != nil {
+			t.Fatalf("flag idle: %v", err)
+		}
+
+		if rec := checkIn("UPDATE-OTHER-KEY", "UPDATE-MACHINE-001"); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+		}
+		if reg, err := regSvc.Get(ctx, m.MachineID, createdProduct.ProductID); err != nil || reg.IdleDate == "" {
+			t.Errorf("expected the registration to stay flagged idle, got %+v (%v)", reg, err)
+		}
+
+		if rec := checkIn("UPDATE-LICENSE-KEY", "UPDATE-MACHINE-001"); rec.Code != http.StatusOK {
+			t.Errorf("expected status %d with the registration's own key, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("returns 404 for a released registration", func(t *testing.T) {
+		if _, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: "UPDATE-MACHINE-REL", UserName: "updateuser"}); err != nil {
+			t.Fatalf("activate machine: %v", err)
+		}
+		m, err := machineSvc.GetByCode(ctx, createdCustomer.CustomerID, "UPDATE-MACHINE-REL")
+		if err != nil || m == nil {
+			t.Fatalf("get machine: %v", err)
+		}
+		if _, err := db.Exec("UPDATE registration SET released_date = '2024-06-01' WHERE machine_id = ?", m.MachineID); err != nil {
+			t.Fatalf("release: %v", err)
+		}
+
+		if rec := checkIn("UPDATE-LICENSE-KEY", "UPDATE-MACHINE-REL"); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+		}
+	})
+
 	t.Run("returns 404 for unknown license key", func(t *testing.T) {
 		e := echo.New()
 		reqBody := client.UpdateLicenseRequest{
@@ -777,3 +1100,76 @@ func TestUpdateLicenseInfo(t *testing.T) {
 		}
 	})
 }
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	customerSvc := customer.NewService(db)
+	productSvc := product.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+	licenseSvc := license.NewService(db)
+	featureSvc := feature.NewService(db)
+	featureValueSvc := featurevalue.NewService(db)
+	activationSvc := activation.NewService(db, "test-secret", customerSvc, machineSvc, regSvc, licenseSvc,
+		productSvc, featureSvc, featureValueSvc, analytics.NewService(db))
+	handler := client.NewHandler(activationSvc, regSvc, productSvc, licenseSvc, machineSvc, featureSvc, featureValueSvc, customerSvc, bundle.NewService(db, licenseSvc))
+
+	cust, err := customerSvc.Create(ctx, &customer.Customer{CustomerName: "Lockout Co"})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	prod, err := productSvc.Create(ctx, &product.Product{ProductName: "Lockout App", ProductGUID: "PROD-GUID-LOCKOUT", LatestVersion: "1.0.0"})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if _, err := licenseSvc.Create(ctx, &license.License{
+		CustomerID: cust.CustomerID, ProductID: prod.ProductID, LicenseKey: "LOCKOUT-LICENSE-KEY", LicenseCount: 1,
+		StartDate: "2024-01-01", ExpirationDate: "2099-12-31", MaintExpirationDate: "2099-12-31",
+	}); err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	rl := middleware.NewRateLimiter(config.RateLimit{
+		Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 100, KeyBurst: 100,
+		LockoutThreshold: 3, LockoutDuration: time.Minute, LockoutMax: time.Hour,
+	})
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	client.RegisterRoutes(e.Group("/api/v1"), handler, middleware.LicenseKeyAuth(db), rl.Guard())
+
+	send := func(method, path, licenseKey, body string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.1.0.1:5000"
+		if licenseKey != "" {
+			req.Header.Set("X-License-Key", licenseKey)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A valid key used on machines that are not registered is not an
+	// authentication failure
+	for i := 0; i < 5; i++ {
+		if code := send(http.MethodPut, "/api/v1/license/LOCKOUT-LICENSE-KEY", "", `{"machineCode":"unregistered"}`); code != http.StatusNotFound {
+			t.Fatalf("update %d: expected 404, got %d", i+1, code)
+		}
+		if code := send(http.MethodPost, "/api/v1/refresh", "LOCKOUT-LICENSE-KEY", `{"machineCode":"unregistered"}`); code != http.StatusNotFound {
+			t.Fatalf("refresh %d: expected 404, got %d", i+1, code)
+		}
+	}
+	if code := send(http.MethodGet, "/api/v1/license/LOCKOUT-LICENSE-KEY", "", ""); code != http.StatusOK {
+		t.Fatalf("expected no lockout for a valid key, got %d", code)
+	}
+
+	// Unknown keys are
+	send(http.MethodPost, "/api/v1/activate", "GUESS-1-KEY", `{"machineCode":"m1"}`)
+	send(http.MethodGet, "/api/v1/license/GUESS-2-KEY", "", "")
+	send(http.MethodPut, "/api/v1/license/GUESS-3-KEY", "", `{"machineCode":"m1"}`)
+	if code := send(http.MethodGet, "/api/v1/license/LOCKOUT-LICENSE-KEY", "", ""); code != http.StatusTooManyRequests {
+		t.Errorf("expected lockout after guessing keys, got %d", code)
+	}
+}
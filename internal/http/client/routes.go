@@ -6,17 +6,21 @@ import (
 
 // RegisterRoutes wires all client-facing endpoints under the given Echo group.
 // The licKeyAuth middleware is applied only to endpoints that require license key validation.
-func RegisterRoutes(g *echo.Group, h *Handler, licKeyAuth echo.MiddlewareFunc) {
+// The keyGuard middleware (rate limiting and lockout) is applied to endpoints that accept a license key.
+func RegisterRoutes(g *echo.Group, h *Handler, licKeyAuth, keyGuard echo.MiddlewareFunc) {
 
 	// Activation endpoint (requires license key)
-	g.POST("/activate", h.Activate, licKeyAuth)
+	g.POST("/activate", h.Activate, keyGuard, licKeyAuth)
+
+	// Re-issued registration of an activated machine (requires license key, no seat check)
+	g.POST("/refresh", h.Refresh, keyGuard, licKeyAuth)
 
 	// Product version lookup (public, no auth required)
 	g.GET("/productver/:guid", h.GetProductVersion)
 
 	// License info lookup (public, no auth required - license key is in URL)
-	g.GET("/license/:license_key", h.GetLicenseInfo)
+	g.GET("/license/:license_key", h.GetLicenseInfo, keyGuard)
 
 	// Update machine's installed version (public, no auth required - license key is in URL)
-	g.PUT("/license/:license_key", h.UpdateLicenseInfo)
+	g.PUT("/license/:license_key", h.UpdateLicenseInfo, keyGuard)
 }
@@ -14,12 +14,17 @@ import (
 	"winsbygroup.com/regserver/internal/middleware"
 
 	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
 	"winsbygroup.com/regserver/internal/backup"
+	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/feature"
 	"winsbygroup.com/regserver/internal/featurevalue"
 	"winsbygroup.com/regserver/internal/http/admin"
+	"winsbygroup.com/regserver/internal/inactivity"
 	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
 	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/plan"
 	"winsbygroup.com/regserver/internal/product"
 	"winsbygroup.com/regserver/internal/registration"
 	"winsbygroup.com/regserver/internal/sqlite"
@@ -38,6 +43,7 @@ type Handler struct {
 	regSvc        *registration.Service
 	activationSvc *activation.Service
 	backupSvc     *backup.Service
+	analyticsSvc  *analytics.Service
 }
 
 // NewHandler creates a new web handler
@@ -50,6 +56,7 @@ func NewHandler(
 	regSvc *registration.Service,
 	activationSvc *activation.Service,
 	backupSvc *backup.Service,
+	analyticsSvc *analytics.Service,
 ) *Handler {
 	return &Handler{
 		svc:           svc,
@@ -60,6 +67,7 @@ func NewHandler(
 		regSvc:        regSvc,
 		activationSvc: activationSvc,
 		backupSvc:     backupSvc,
+		analyticsSvc:  analyticsSvc,
 	}
 }
 
@@ -72,6 +80,15 @@ func (h *Handler) getCustomerName(ctx context.Context, customerID int64) string
 	return cust.CustomerName
 }
 
+// getProductName returns the product name for the given ID, or empty string if not found
+func (h *Handler) getProductName(ctx context.Context, productID int64) string {
+	prod, err := h.productSvc.Get(ctx, productID)
+	if err != nil || prod == nil {
+		return ""
+	}
+	return prod.ProductName
+}
+
 // --------------------------
 // Authentication
 // --------------------------
@@ -86,6 +103,9 @@ func (h *Handler) Login(c echo.Context) error {
 	apiKey := c.FormValue("api_key")
 
 	if !middleware.ValidateAdminKey(apiKey) {
+		middleware.AuthFailed(c) // counts toward the login lockout
+		logging.FromContext(c.Request().Context()).Warn("failed login", "remote_ip", c.RealIP())
+		c.Response().WriteHeader(http.StatusUnauthorized)
 		return pages.Login("Invalid API key").Render(c.Request().Context(), c.Response())
 	}
 
@@ -274,6 +294,186 @@ func (h *Handler) DeleteCustomer(c echo.Context) error {
 	return components.CustomersTable(FromDomainCustomers(customers)).Render(ctx, c.Response())
 }
 
+// --------------------------
+// Customer Contacts
+// --------------------------
+
+func (h *Handler) CustomerContactsManager(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+	return h.renderContactsManager(c, ctx, customerID)
+}
+
+func (h *Handler) NewContactForm(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+
+	return components.ContactForm(nil, customerID).Render(ctx, c.Response())
+}
+
+func (h *Handler) EditContactForm(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, contactID, err := contactParams(c)
+	if err != nil {
+		return err
+	}
+
+	contact, err := h.svc.GetContact(ctx, contactID)
+	if err != nil || contact.CustomerID != customerID {
+		return echo.NewHTTPError(http.StatusNotFound, "Contact not found")
+	}
+
+	viewContact := FromDomainContact(*contact)
+	return components.ContactForm(&viewContact, customerID).Render(ctx, c.Response())
+}
+
+func (h *Handler) CreateContact(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+
+	form, _ := c.FormParams()
+	req := &admin.CreateContactRequest{
+		ContactName: c.FormValue("contact_name"),
+		Phone:       c.FormValue("phone"),
+		Email:       c.FormValue("email"),
+		Roles:       form["roles"],
+		IsPrimary:   c.FormValue("is_primary") == "on",
+		Notes:       c.FormValue("notes"),
+	}
+
+	if _, err := h.svc.CreateContact(ctx, customerID, req); err != nil {
+		contact := &vm.Contact{
+			CustomerID:  customerID,
+			ContactName: req.ContactName,
+			Phone:       req.Phone,
+			Email:       req.Email,
+			Roles:       req.Roles,
+			IsPrimary:   req.IsPrimary,
+			Notes:       req.Notes,
+		}
+		return h.renderContactFormWithError(c, ctx, contact, customerID, err)
+	}
+
+	setTriggerWithData(c, `{"customersChanged": true, "showToast": {"message": "Contact created successfully", "type": "success"}}`)
+	return h.renderContactsManager(c, ctx, customerID)
+}
+
+func (h *Handler) UpdateContact(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, contactID, err := contactParams(c)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := h.svc.GetContact(ctx, contactID); err != nil || existing.CustomerID != customerID {
+		return echo.NewHTTPError(http.StatusNotFound, "Contact not found")
+	}
+
+	form, _ := c.FormParams()
+	req := &admin.UpdateContactRequest{
+		ContactName: c.FormValue("contact_name"),
+		Phone:       c.FormValue("phone"),
+		Email:       c.FormValue("email"),
+		Roles:       form["roles"],
+		IsPrimary:   c.FormValue("is_primary") == "on",
+		Notes:       c.FormValue("notes"),
+	}
+
+	if err := h.svc.UpdateContact(ctx, contactID, req); err != nil {
+		contact := &vm.Contact{
+			ContactID:   contactID,
+			CustomerID:  customerID,
+			ContactName: req.ContactName,
+			Phone:       req.Phone,
+			Email:       req.Email,
+			Roles:       req.Roles,
+			IsPrimary:   req.IsPrimary,
+			Notes:       req.Notes,
+		}
+		return h.renderContactFormWithError(c, ctx, contact, customerID, err)
+	}
+
+	setTriggerWithData(c, `{"customersChanged": true, "showToast": {"message": "Contact updated successfully", "type": "success"}}`)
+	return h.renderContactsManager(c, ctx, customerID)
+}
+
+func (h *Handler) DeleteContact(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, contactID, err := contactParams(c)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := h.svc.GetContact(ctx, contactID); err != nil || existing.CustomerID != customerID {
+		return echo.NewHTTPError(http.StatusNotFound, "Contact not found")
+	}
+	if err := h.svc.DeleteContact(ctx, contactID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	setTriggerWithData(c, `{"customersChanged": true, "showToast": {"message": "Contact deleted successfully", "type": "success"}}`)
+	return h.renderContactsManager(c, ctx, customerID)
+}
+
+// contactParams parses the :id (customer) and :contactId path parameters
+func contactParams(c echo.Context) (int64, int64, error) {
+	customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+	contactID, err := strconv.ParseInt(c.Param("contactId"), 10, 64)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid contact ID")
+	}
+	return customerID, contactID, nil
+}
+
+func (h *Handler) renderContactsManager(c echo.Context, ctx context.Context, customerID int64) error {
+	cust, err := h.svc.GetCustomer(ctx, customerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	}
+
+	contacts, err := h.svc.GetContacts(ctx, customerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	viewCustomer := FromDomainCustomer(*cust)
+	return components.CustomerContactsManager(&viewCustomer, FromDomainContacts(contacts)).Render(ctx, c.Response())
+}
+
+// renderContactFormWithError re-renders the contact form with appropriate field errors
+func (h *Handler) renderContactFormWithError(c echo.Context, ctx context.Context, contact *vm.Contact, customerID int64, err error) error {
+	fieldErrors := make(map[string]string)
+	switch {
+	case errors.Is(err, customer.ErrContactRequired):
+		fieldErrors["contact_name"] = "Enter a contact name or email"
+	case errors.Is(err, customer.ErrInvalidRole):
+		fieldErrors["roles"] = err.Error()
+	default:
+		// Unknown error - show toast instead
+		setTriggerWithData(c, fmt.Sprintf(`{"showToast": {"message": %q, "type": "error"}}`, err.Error()))
+		return c.String(http.StatusUnprocessableEntity, "")
+	}
+
+	formData := components.ContactFormData{
+		Contact:    contact,
+		CustomerID: customerID,
+		Errors:     fieldErrors,
+	}
+	return components.ContactFormWithErrors(formData).Render(ctx, c.Response())
+}
+
 // --------------------------
 // Products
 // --------------------------
@@ -487,35 +687,13 @@ func (h *Handler) CreateFeature(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
 
-	req := &admin.CreateFeatureRequest{
-		FeatureName:   c.FormValue("feature_name"),
-		FeatureType:   c.FormValue("feature_type"),
-		AllowedValues: c.FormValue("allowed_values"),
-		DefaultValue:  c.FormValue("default_value"),
-	}
-
-	// Validate allowed_values for Values type
-	if req.FeatureType == "values" && !strings.Contains(req.AllowedValues, "|") {
-		feature := &vm.Feature{
-			FeatureName:   req.FeatureName,
-			FeatureType:   featureTypeFromString(req.FeatureType),
-			AllowedValues: req.AllowedValues,
-			DefaultValue:  req.DefaultValue,
-		}
-		return h.renderFeatureFormWithError(c, ctx, feature, productID, "allowed_values", "Values type requires at least two pipe-separated options (e.g., Yes|No)")
+	req, feature, field, message := readFeatureForm(c, 0)
+	if field != "" {
+		return h.renderFeatureFormWithError(c, ctx, feature, productID, field, message)
 	}
 
 	if _, err := h.svc.CreateFeature(ctx, productID, req); err != nil {
-		feature := &vm.Feature{
-			FeatureName:   req.FeatureName,
-			FeatureType:   featureTypeFromString(req.FeatureType),
-			AllowedValues: req.AllowedValues,
-			DefaultValue:  req.DefaultValue,
-		}
-		if sqlite.IsUniqueConstraintError(err) {
-			return h.renderFeatureFormWithError(c, ctx, feature, productID, "feature_name", "A feature with this name already exists for this product")
-		}
-		return h.renderFeatureFormWithError(c, ctx, feature, productID, "", err.Error())
+		return h.renderFeatureSaveError(c, ctx, feature, productID, err)
 	}
 
 	// Return updated features manager
@@ -533,37 +711,14 @@ func (h *Handler) UpdateFeature(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid feature ID")
 	}
 
-	req := &admin.UpdateFeatureRequest{
-		FeatureName:   c.FormValue("feature_name"),
-		FeatureType:   c.FormValue("feature_type"),
-		AllowedValues: c.FormValue("allowed_values"),
-		DefaultValue:  c.FormValue("default_value"),
-	}
-
-	// Validate allowed_values for Values type
-	if req.FeatureType == "values" && !strings.Contains(req.AllowedValues, "|") {
-		feature := &vm.Feature{
-			FeatureID:     featureID,
-			FeatureName:   req.FeatureName,
-			FeatureType:   featureTypeFromString(req.FeatureType),
-			AllowedValues: req.AllowedValues,
-			DefaultValue:  req.DefaultValue,
-		}
-		return h.renderFeatureFormWithError(c, ctx, feature, productID, "allowed_values", "Values type requires at least two pipe-separated options (e.g., Yes|No)")
+	req, feature, field, message := readFeatureForm(c, featureID)
+	if field != "" {
+		return h.renderFeatureFormWithError(c, ctx, feature, productID, field, message)
 	}
 
-	if err := h.svc.UpdateFeature(ctx, featureID, req); err != nil {
-		feature := &vm.Feature{
-			FeatureID:     featureID,
-			FeatureName:   req.FeatureName,
-			FeatureType:   featureTypeFromString(req.FeatureType),
-			AllowedValues: req.AllowedValues,
-			DefaultValue:  req.DefaultValue,
-		}
-		if sqlite.IsUniqueConstraintError(err) {
-			return h.renderFeatureFormWithError(c, ctx, feature, productID, "feature_name", "A feature with this name already exists for this product")
-		}
-		return h.renderFeatureFormWithError(c, ctx, feature, productID, "", err.Error())
+	updateReq := admin.UpdateFeatureRequest(*req)
+	if err := h.svc.UpdateFeature(ctx, featureID, &updateReq); err != nil {
+		return h.renderFeatureSaveError(c, ctx, feature, productID, err)
 	}
 
 	// Return updated features manager
@@ -582,6 +737,74 @@ func (h *Handler) UpdateFeature(c echo.Context) error {
 	return components.ProductFeaturesManager(&viewProduct, viewFeatures).Render(ctx, c.Response())
 }
 
+// readFeatureForm reads the feature form into a request and the view model used
+// to re-render the form. Form-level problems (bounds that are not whole numbers,
+// a values feature with fewer than two options) come back as field and message.
+func readFeatureForm(c echo.Context, featureID int64) (*admin.CreateFeatureRequest, *vm.Feature, string, string) {
+	feature := &vm.Feature{
+		FeatureID:     featureID,
+		FeatureName:   c.FormValue("feature_name"),
+		FeatureType:   featureTypeFromString(c.FormValue("feature_type")),
+		AllowedValues: c.FormValue("allowed_values"),
+		DefaultValue:  c.FormValue("default_value"),
+		MinValue:      strings.TrimSpace(c.FormValue("min_value")),
+		MaxValue:      strings.TrimSpace(c.FormValue("max_value")),
+		MaxLength:     strings.TrimSpace(c.FormValue("max_length")),
+		Status:        c.FormValue("feature_status"),
+	}
+	req := &admin.CreateFeatureRequest{
+		FeatureName:   feature.FeatureName,
+		FeatureType:   c.FormValue("feature_type"),
+		AllowedValues: feature.AllowedValues,
+		DefaultValue:  feature.DefaultValue,
+		Status:        feature.Status,
+	}
+
+	// Validate allowed_values for Values type
+	if req.FeatureType == "values" && !strings.Contains(req.AllowedValues, "|") {
+		return req, feature, "allowed_values", "Values type requires at least two pipe-separated options (e.g., Yes|No)"
+	}
+
+	var err error
+	if req.MinValue, err = parseOptionalInt(feature.MinValue); err != nil {
+		return req, feature, "min_value", "Must be empty or a whole number"
+	}
+	if req.MaxValue, err = parseOptionalInt(feature.MaxValue); err != nil {
+		return req, feature, "max_value", "Must be empty or a whole number"
+	}
+	if feature.MaxLength != "" {
+		if req.MaxLength, err = strconv.Atoi(feature.MaxLength); err != nil {
+			return req, feature, "max_length", "Must be empty or a whole number"
+		}
+	}
+	return req, feature, "", ""
+}
+
+// parseOptionalInt parses an optional integer form value (nil when empty)
+func parseOptionalInt(s string) (*int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// renderFeatureSaveError re-renders the feature form for a failed save, showing
+// validation errors on the field they belong to
+func (h *Handler) renderFeatureSaveError(c echo.Context, ctx context.Context, feature *vm.Feature, productID int64, err error) error {
+	var fe *featurevalue.FieldError
+	switch {
+	case sqlite.IsUniqueConstraintError(err):
+		return h.renderFeatureFormWithError(c, ctx, feature, productID, "feature_name", "A feature with this name already exists for this product")
+	case errors.As(err, &fe):
+		return h.renderFeatureFormWithError(c, ctx, feature, productID, fe.Field, fe.Error())
+	}
+	return h.renderFeatureFormWithError(c, ctx, feature, productID, "", err.Error())
+}
+
 // featureTypeFromString converts string to vm.FeatureType
 func featureTypeFromString(s string) vm.FeatureType {
 	switch s {
@@ -591,6 +814,10 @@ func featureTypeFromString(s string) vm.FeatureType {
 		return vm.FeatureTypeString
 	case "values":
 		return vm.FeatureTypeValues
+	case "boolean":
+		return vm.FeatureTypeBoolean
+	case "date":
+		return vm.FeatureTypeDate
 	default:
 		return vm.FeatureTypeInteger
 	}
@@ -627,6 +854,9 @@ func (h *Handler) DeleteFeature(c echo.Context) error {
 	}
 
 	if err := h.svc.DeleteFeature(ctx, featureID); err != nil {
+		if errors.Is(err, feature.ErrFeatureInUse) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -647,141 +877,662 @@ func (h *Handler) DeleteFeature(c echo.Context) error {
 }
 
 // --------------------------
-// Licenses
+// Product Plans
 // --------------------------
 
-func (h *Handler) GetLicenses(c echo.Context) error {
+func (h *Handler) ProductPlansManager(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
+	return h.renderPlansManager(c, ctx, productID)
+}
 
-	lics, err := h.svc.GetLicenses(ctx, customerID)
+func (h *Handler) renderPlansManager(c echo.Context, ctx context.Context, productID int64) error {
+	prod, err := h.svc.GetProduct(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+
+	plans, err := h.svc.GetPlans(ctx, productID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	viewLics := h.convertLicenses(ctx, lics)
-	return components.LicensesTable(customerID, h.getCustomerName(ctx, customerID), viewLics).Render(ctx, c.Response())
+	viewProduct := FromDomainProduct(*prod)
+	return components.ProductPlansManager(&viewProduct, FromDomainPlans(plans)).Render(ctx, c.Response())
 }
 
-func (h *Handler) NewLicenseForm(c echo.Context) error {
+func (h *Handler) NewPlanForm(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
 
-	products, err := h.svc.GetUnlicensedProducts(ctx, customerID)
+	features, err := h.svc.GetFeatures(ctx, productID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	return components.LicenseForm(nil, customerID, FromDomainProducts(products)).Render(ctx, c.Response())
+	formData := components.PlanFormData{
+		ProductID: productID,
+		Features:  FromDomainFeatures(features),
+	}
+	return components.PlanFormWithErrors(formData).Render(ctx, c.Response())
 }
 
-func (h *Handler) EditLicenseForm(c echo.Context) error {
+func (h *Handler) EditPlanForm(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	productID, planID, err := planParams(c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+		return err
 	}
-	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+
+	p, err := h.svc.GetPlan(ctx, planID)
+	if err != nil || p.ProductID != productID {
+		return echo.NewHTTPError(http.StatusNotFound, "Plan not found")
 	}
 
-	lics, err := h.svc.GetLicenses(ctx, customerID)
+	features, err := h.svc.GetFeatures(ctx, productID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	var lic *license.License
-	for i, item := range lics {
-		if item.ProductID == productID {
-			lic = &lics[i]
-			break
-		}
+	viewPlan := FromDomainPlan(*p)
+	formData := components.PlanFormData{
+		Plan:      &viewPlan,
+		ProductID: productID,
+		PlanName:  viewPlan.PlanName,
+		Features:  FromDomainFeatures(features),
+		Values:    viewPlan.Values,
 	}
+	return components.PlanFormWithErrors(formData).Render(ctx, c.Response())
+}
 
-	if lic == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "License not found")
+func (h *Handler) CreatePlan(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
 
-	prod, _ := h.svc.GetProduct(ctx, productID)
-	products := []product.Product{}
-	if prod != nil {
-		products = append(products, *prod)
+	features, err := h.svc.GetFeatures(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	viewLic := FromDomainLicense(*lic, prod.ProductName)
-	return components.LicenseForm(&viewLic, customerID, FromDomainProducts(products)).Render(ctx, c.Response())
+	formData := readPlanForm(c, productID, features)
+	req := &admin.CreatePlanRequest{
+		PlanName: formData.PlanName,
+		Values:   planValueRequests(formData.Values),
+	}
+	if _, err := h.svc.CreatePlan(ctx, productID, req); err != nil {
+		return h.renderPlanSaveError(c, ctx, formData, err)
+	}
+
+	setTriggerWithData(c, `{"showToast": {"message": "Plan created successfully", "type": "success"}}`)
+	return h.renderPlansManager(c, ctx, productID)
 }
 
-func (h *Handler) CreateLicense(c echo.Context) error {
+func (h *Handler) UpdatePlan(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	productID, planID, err := planParams(c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+		return err
 	}
 
-	productID, _ := strconv.ParseInt(c.FormValue("product_id"), 10, 64)
-	licenseCount, _ := strconv.Atoi(c.FormValue("license_count"))
-	licenseTerm, _ := strconv.Atoi(c.FormValue("license_term"))
-	isSubscription := c.FormValue("license_type") == "subscription"
+	p, err := h.svc.GetPlan(ctx, planID)
+	if err != nil || p.ProductID != productID {
+		return echo.NewHTTPError(http.StatusNotFound, "Plan not found")
+	}
 
-	req := &admin.CreateLicenseRequest{
-		ProductID:           productID,
-		LicenseCount:        licenseCount,
-		IsSubscription:      isSubscription,
-		LicenseTerm:         licenseTerm,
-		StartDate:           c.FormValue("start_date"),
-		ExpirationDate:      c.FormValue("expiration_date"),
-		MaintExpirationDate: c.FormValue("maint_expiration_date"),
-		MaxProductVersion:   strings.TrimSpace(c.FormValue("max_product_version")),
+	features, err := h.svc.GetFeatures(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	if _, err := h.svc.CreateLicense(ctx, customerID, req); err != nil {
-		license := &vm.License{
-			ProductID:           productID,
-			LicenseCount:        licenseCount,
-			IsSubscription:      isSubscription,
-			LicenseTerm:         licenseTerm,
-			StartDate:           req.StartDate,
-			ExpirationDate:      req.ExpirationDate,
-			MaintExpirationDate: req.MaintExpirationDate,
-			MaxProductVersion:   req.MaxProductVersion,
-		}
-		return h.renderLicenseFormWithError(c, ctx, license, customerID, true, err)
+	formData := readPlanForm(c, productID, features)
+	viewPlan := FromDomainPlan(*p)
+	formData.Plan = &viewPlan
+	req := &admin.UpdatePlanRequest{
+		PlanName: formData.PlanName,
+		Values:   planValueRequests(formData.Values),
+	}
+	if err := h.svc.UpdatePlan(ctx, planID, req); err != nil {
+		return h.renderPlanSaveError(c, ctx, formData, err)
 	}
 
-	lics, err := h.svc.GetLicenses(ctx, customerID)
+	setTriggerWithData(c, `{"showToast": {"message": "Plan updated successfully", "type": "success"}}`)
+	return h.renderPlansManager(c, ctx, productID)
+}
+
+func (h *Handler) DeletePlan(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, planID, err := planParams(c)
 	if err != nil {
+		return err
+	}
+
+	if p, err := h.svc.GetPlan(ctx, planID); err != nil || p.ProductID != productID {
+		return echo.NewHTTPError(http.StatusNotFound, "Plan not found")
+	}
+
+	if err := h.svc.DeletePlan(ctx, planID); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	setTriggerWithData(c, `{"closeModal": true, "showToast": {"message": "License created successfully", "type": "success"}}`)
-	viewLics := h.convertLicenses(ctx, lics)
-	return components.LicensesTable(customerID, h.getCustomerName(ctx, customerID), viewLics).Render(ctx, c.Response())
+	setTriggerWithData(c, `{"showToast": {"message": "Plan deleted successfully", "type": "success"}}`)
+	return h.renderPlansManager(c, ctx, productID)
 }
 
-func (h *Handler) UpdateLicense(c echo.Context) error {
+// PlanAssignForm lists the product's licenses for changing their plan in bulk
+func (h *Handler) PlanAssignForm(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
-	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+	return h.renderPlanAssignForm(c, ctx, productID, "")
+}
+
+func (h *Handler) AssignPlan(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
 
-	licenseCount, _ := strconv.Atoi(c.FormValue("license_count"))
-	licenseTerm, _ := strconv.Atoi(c.FormValue("license_term"))
-	isSubscription := c.FormValue("license_type") == "subscription"
-
-	req := &admin.UpdateLicenseRequest{
+	form, _ := c.FormParams()
+	req := &admin.AssignPlanRequest{}
+	if s := c.FormValue("plan_id"); s != "" {
+		planID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid plan ID")
+		}
+		req.PlanID = &planID
+	}
+	for _, s := range form["license_id"] {
+		licenseID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
+		}
+		req.LicenseIDs = append(req.LicenseIDs, licenseID)
+	}
+	if len(req.LicenseIDs) == 0 {
+		return h.renderPlanAssignForm(c, ctx, productID, "Select at least one license")
+	}
+
+	if err := h.svc.AssignPlan(ctx, req); err != nil {
+		return h.renderPlanAssignForm(c, ctx, productID, err.Error())
+	}
+
+	setTriggerWithData(c, fmt.Sprintf(`{"showToast": {"message": "Plan changed for %d licenses", "type": "success"}}`, len(req.LicenseIDs)))
+	return h.renderPlansManager(c, ctx, productID)
+}
+
+func (h *Handler) renderPlanAssignForm(c echo.Context, ctx context.Context, productID int64, errorMsg string) error {
+	prod, err := h.svc.GetProduct(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+	plans, err := h.svc.GetPlans(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	licenses, err := h.svc.GetPlanLicenses(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	viewProduct := FromDomainProduct(*prod)
+	formData := components.PlanAssignFormData{
+		Product:  &viewProduct,
+		Plans:    FromDomainPlans(plans),
+		Licenses: FromDomainPlanLicenses(licenses),
+		Error:    errorMsg,
+	}
+	return components.PlanAssignForm(formData).Render(ctx, c.Response())
+}
+
+// planParams parses the product and plan IDs of a plan route
+func planParams(c echo.Context) (int64, int64, error) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	planID, err := strconv.ParseInt(c.Param("planId"), 10, 64)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid plan ID")
+	}
+	return productID, planID, nil
+}
+
+// readPlanForm reads the plan form: the plan name and one value per feature of
+// the product (empty = feature default)
+func readPlanForm(c echo.Context, productID int64, features []feature.Feature) components.PlanFormData {
+	formData := components.PlanFormData{
+		ProductID: productID,
+		PlanName:  c.FormValue("plan_name"),
+		Features:  FromDomainFeatures(features),
+		Values:    make(map[int64]string, len(features)),
+	}
+	for _, f := range features {
+		formData.Values[f.FeatureID] = c.FormValue(fmt.Sprintf("feature_%d", f.FeatureID))
+	}
+	return formData
+}
+
+// planValueRequests converts plan form values to request values
+func planValueRequests(values map[int64]string) []admin.PlanValueRequest {
+	out := make([]admin.PlanValueRequest, 0, len(values))
+	for featureID, v := range values {
+		out = append(out, admin.PlanValueRequest{FeatureID: featureID, Value: v})
+	}
+	return out
+}
+
+// renderPlanSaveError re-renders the plan form for a failed save, showing
+// validation errors on the field they belong to
+func (h *Handler) renderPlanSaveError(c echo.Context, ctx context.Context, formData components.PlanFormData, err error) error {
+	var ve *plan.ValueError
+	formData.Errors = make(map[string]string)
+	switch {
+	case sqlite.IsUniqueConstraintError(err):
+		formData.Errors["plan_name"] = "A plan with this name already exists for this product"
+	case errors.Is(err, plan.ErrNameRequired):
+		formData.Errors["plan_name"] = "Plan name is required"
+	case errors.As(err, &ve):
+		formData.Errors[fmt.Sprintf("feature_%d", ve.FeatureID)] = ve.Err.Error()
+	default:
+		setTriggerWithData(c, fmt.Sprintf(`{"showToast": {"message": %q, "type": "error"}}`, err.Error()))
+		return c.String(http.StatusUnprocessableEntity, "")
+	}
+	return components.PlanFormWithErrors(formData).Render(ctx, c.Response())
+}
+
+// --------------------------
+// Bulk License Updates
+// --------------------------
+
+// BulkForm shows the bulk update form of a product's licenses
+func (h *Handler) BulkForm(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	return h.renderBulkForm(c, ctx, productID, components.BulkFormValues{}, nil, "")
+}
+
+// PreviewBulk shows the licenses a bulk update selects with their new values
+func (h *Handler) PreviewBulk(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	values, req, errorMsg := readBulkForm(c, productID)
+	if errorMsg != "" {
+		return h.renderBulkForm(c, ctx, productID, values, nil, errorMsg)
+	}
+
+	preview, err := h.svc.PreviewBulk(ctx, req)
+	if err != nil {
+		return h.renderBulkForm(c, ctx, productID, values, nil, err.Error())
+	}
+	return h.renderBulkForm(c, ctx, productID, values, FromDomainBulkPreview(preview), "")
+}
+
+// ApplyBulk applies a previewed bulk update and shows the product's bulk history
+func (h *Handler) ApplyBulk(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	values, req, errorMsg := readBulkForm(c, productID)
+	if errorMsg != "" {
+		return h.renderBulkForm(c, ctx, productID, values, nil, errorMsg)
+	}
+
+	op, err := h.svc.ApplyBulk(ctx, req)
+	if err != nil {
+		// Show the preview again so the failing licenses can be seen
+		preview, perr := h.svc.PreviewBulk(ctx, req)
+		if perr != nil {
+			return h.renderBulkForm(c, ctx, productID, values, nil, err.Error())
+		}
+		return h.renderBulkForm(c, ctx, productID, values, FromDomainBulkPreview(preview), err.Error())
+	}
+
+	setTriggerWithData(c, fmt.Sprintf(`{"showToast": {"message": "Updated %d licenses", "type": "success"}}`, op.LicenseCount))
+	return h.renderBulkHistory(c, ctx, productID)
+}
+
+// BulkHistory lists the bulk updates applied to a product's licenses
+func (h *Handler) BulkHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	return h.renderBulkHistory(c, ctx, productID)
+}
+
+func (h *Handler) renderBulkHistory(c echo.Context, ctx context.Context, productID int64) error {
+	prod, err := h.svc.GetProduct(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+	ops, err := h.svc.GetBulkOperations(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	viewProduct := FromDomainProduct(*prod)
+	return components.BulkHistory(&viewProduct, FromDomainBulkOperations(ops)).Render(ctx, c.Response())
+}
+
+func (h *Handler) renderBulkForm(c echo.Context, ctx context.Context, productID int64, values components.BulkFormValues, preview *BulkPreview, errorMsg string) error {
+	prod, err := h.svc.GetProduct(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+	licenses, err := h.svc.GetPlanLicenses(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	features, err := h.svc.GetFeatures(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// Offer each customer holding a license for the product once
+	var customers []Customer
+	seen := make(map[int64]bool)
+	for _, lic := range licenses {
+		if !seen[lic.CustomerID] {
+			seen[lic.CustomerID] = true
+			customers = append(customers, Customer{CustomerID: lic.CustomerID, CustomerName: lic.CustomerName})
+		}
+	}
+
+	viewProduct := FromDomainProduct(*prod)
+	formData := components.BulkFormData{
+		Product:   &viewProduct,
+		Customers: customers,
+		Features:  FromDomainFeatures(features),
+		Values:    values,
+		Preview:   preview,
+		Error:     errorMsg,
+	}
+	return components.BulkForm(formData).Render(ctx, c.Response())
+}
+
+// readBulkForm reads the bulk update form into its values and the request to
+// send; a non-empty message reports a field that could not be parsed
+func readBulkForm(c echo.Context, productID int64) (components.BulkFormValues, *admin.BulkRequest, string) {
+	form, _ := c.FormParams()
+	values := components.BulkFormValues{
+		ExpiresFrom:       c.FormValue("expires_from"),
+		ExpiresTo:         c.FormValue("expires_to"),
+		ExtendMonths:      strings.TrimSpace(c.FormValue("extend_months")),
+		ExtendMaintMonths: strings.TrimSpace(c.FormValue("extend_maint_months")),
+		AddSeats:          strings.TrimSpace(c.FormValue("add_seats")),
+		ChangeMaxVersion:  c.FormValue("change_max_version") != "",
+		MaxProductVersion: strings.TrimSpace(c.FormValue("max_product_version")),
+		FeatureValue:      c.FormValue("feature_value"),
+	}
+	req := &admin.BulkRequest{}
+	req.Selection.ProductID = productID
+	req.Selection.ExpiresFrom = values.ExpiresFrom
+	req.Selection.ExpiresTo = values.ExpiresTo
+
+	for _, s := range form["customer_id"] {
+		customerID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return values, nil, "Invalid customer ID"
+		}
+		values.CustomerIDs = append(values.CustomerIDs, customerID)
+	}
+	req.Selection.CustomerIDs = values.CustomerIDs
+
+	for _, field := range []struct {
+		label string
+		value string
+		dest  *int
+	}{
+		{"Extend expiration", values.ExtendMonths, &req.Change.ExtendMonths},
+		{"Extend maintenance", values.ExtendMaintMonths, &req.Change.ExtendMaintMonths},
+		{"Add seats", values.AddSeats, &req.Change.AddSeats},
+	} {
+		if field.value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field.value)
+		if err != nil {
+			return values, nil, field.label + " must be a whole number"
+		}
+		*field.dest = n
+	}
+
+	if values.ChangeMaxVersion {
+		req.Change.MaxProductVersion = &values.MaxProductVersion
+	}
+	if s := c.FormValue("feature_id"); s != "" {
+		featureID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return values, nil, "Invalid feature ID"
+		}
+		values.FeatureID = featureID
+		req.Change.FeatureID = featureID
+		req.Change.FeatureValue = values.FeatureValue
+	}
+	return values, req, ""
+}
+
+// --------------------------
+// Inactivity Policies
+// --------------------------
+
+// InactivityForm shows a product's inactivity policy with the preview of the next run
+func (h *Handler) InactivityForm(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	policy, err := h.svc.GetProductInactivityPolicy(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+	days := ""
+	if policy.Days != nil && *policy.Days > 0 {
+		days = strconv.Itoa(*policy.Days)
+	}
+	return h.renderInactivityForm(c, ctx, productID, days, policy.Action, "")
+}
+
+// SaveInactivity sets a product's inactivity policy and shows the new preview
+func (h *Handler) SaveInactivity(c echo.Context) error {
+	ctx := c.Request().Context()
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	days := strings.TrimSpace(c.FormValue("days"))
+	action := c.FormValue("action")
+	policy := &inactivity.Policy{Action: action}
+	if days != "" && days != "0" {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return h.renderInactivityForm(c, ctx, productID, days, action, "Days must be a whole number")
+		}
+		policy.Days = &n
+	}
+
+	if err := h.svc.SetProductInactivityPolicy(ctx, productID, policy); err != nil {
+		return h.renderInactivityForm(c, ctx, productID, days, action, err.Error())
+	}
+
+	setTriggerWithData(c, `{"showToast": {"message": "Inactivity policy saved", "type": "success"}}`)
+	return h.renderInactivityForm(c, ctx, productID, days, action, "")
+}
+
+func (h *Handler) renderInactivityForm(c echo.Context, ctx context.Context, productID int64, days, action, errorMsg string) error {
+	prod, err := h.svc.GetProduct(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+	report, err := h.svc.PreviewInactivity(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	viewProduct := FromDomainProduct(*prod)
+	formData := components.InactivityFormData{
+		Product: &viewProduct,
+		Days:    days,
+		Action:  action,
+		Report:  FromDomainInactivityReport(report),
+		Error:   errorMsg,
+	}
+	return components.InactivityForm(formData).Render(ctx, c.Response())
+}
+
+// --------------------------
+// Licenses
+// --------------------------
+
+func (h *Handler) GetLicenses(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+
+	lics, err := h.svc.GetLicenses(ctx, customerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	viewLics := h.convertLicenses(ctx, lics)
+	return components.LicensesTable(customerID, h.getCustomerName(ctx, customerID), viewLics).Render(ctx, c.Response())
+}
+
+func (h *Handler) NewLicenseForm(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+
+	// A customer may hold several licenses of the same product, so every product is offered
+	products, err := h.svc.GetProducts(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return components.LicenseForm(nil, customerID, FromDomainProducts(products)).Render(ctx, c.Response())
+}
+
+func (h *Handler) EditLicenseForm(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
+	}
+
+	lic, err := h.getLicense(ctx, licenseID)
+	if err != nil {
+		return err
+	}
+
+	prod, _ := h.svc.GetProduct(ctx, lic.ProductID)
+	products := []product.Product{}
+	productName := ""
+	if prod != nil {
+		products = append(products, *prod)
+		productName = prod.ProductName
+	}
+
+	viewLic := FromDomainLicense(*lic, productName)
+	return components.LicenseForm(&viewLic, customerID, FromDomainProducts(products)).Render(ctx, c.Response())
+}
+
+func (h *Handler) CreateLicense(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+
+	productID, _ := strconv.ParseInt(c.FormValue("product_id"), 10, 64)
+	licenseCount, _ := strconv.Atoi(c.FormValue("license_count"))
+	licenseTerm, _ := strconv.Atoi(c.FormValue("license_term"))
+	isSubscription := c.FormValue("license_type") == "subscription"
+
+	req := &admin.CreateLicenseRequest{
+		ProductID:           productID,
+		LicenseCount:        licenseCount,
+		IsSubscription:      isSubscription,
+		LicenseTerm:         licenseTerm,
+		StartDate:           c.FormValue("start_date"),
+		ExpirationDate:      c.FormValue("expiration_date"),
+		MaintExpirationDate: c.FormValue("maint_expiration_date"),
+		MaxProductVersion:   strings.TrimSpace(c.FormValue("max_product_version")),
+	}
+
+	if _, err := h.svc.CreateLicense(ctx, customerID, req); err != nil {
+		license := &vm.License{
+			ProductID:           productID,
+			LicenseCount:        licenseCount,
+			IsSubscription:      isSubscription,
+			LicenseTerm:         licenseTerm,
+			StartDate:           req.StartDate,
+			ExpirationDate:      req.ExpirationDate,
+			MaintExpirationDate: req.MaintExpirationDate,
+			MaxProductVersion:   req.MaxProductVersion,
+		}
+		return h.renderLicenseFormWithError(c, ctx, license, customerID, true, err)
+	}
+
+	lics, err := h.svc.GetLicenses(ctx, customerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	setTriggerWithData(c, `{"closeModal": true, "showToast": {"message": "License created successfully", "type": "success"}}`)
+	viewLics := h.convertLicenses(ctx, lics)
+	return components.LicensesTable(customerID, h.getCustomerName(ctx, customerID), viewLics).Render(ctx, c.Response())
+}
+
+func (h *Handler) UpdateLicense(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+	}
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
+	}
+
+	productID, _ := strconv.ParseInt(c.FormValue("product_id"), 10, 64)
+	licenseCount, _ := strconv.Atoi(c.FormValue("license_count"))
+	licenseTerm, _ := strconv.Atoi(c.FormValue("license_term"))
+	isSubscription := c.FormValue("license_type") == "subscription"
+
+	req := &admin.UpdateLicenseRequest{
 		LicenseCount:        licenseCount,
 		IsSubscription:      isSubscription,
 		LicenseTerm:         licenseTerm,
@@ -791,8 +1542,18 @@ func (h *Handler) UpdateLicense(c echo.Context) error {
 		MaxProductVersion:   strings.TrimSpace(c.FormValue("max_product_version")),
 	}
 
-	if err := h.svc.UpdateLicense(ctx, customerID, productID, req); err != nil {
+	statusReq := &admin.SetLicenseStatusRequest{
+		Status: c.FormValue("status"),
+		Reason: strings.TrimSpace(c.FormValue("status_reason")),
+	}
+
+	err = h.svc.UpdateLicense(ctx, licenseID, req)
+	if err == nil && statusReq.Status != "" {
+		err = h.svc.SetLicenseStatus(ctx, licenseID, statusReq)
+	}
+	if err != nil {
 		license := &vm.License{
+			LicenseID:           licenseID,
 			ProductID:           productID,
 			LicenseCount:        licenseCount,
 			IsSubscription:      isSubscription,
@@ -801,6 +1562,8 @@ func (h *Handler) UpdateLicense(c echo.Context) error {
 			ExpirationDate:      req.ExpirationDate,
 			MaintExpirationDate: req.MaintExpirationDate,
 			MaxProductVersion:   req.MaxProductVersion,
+			Status:              statusReq.Status,
+			StatusReason:        statusReq.Reason,
 		}
 		return h.renderLicenseFormWithError(c, ctx, license, customerID, false, err)
 	}
@@ -824,25 +1587,22 @@ func (h *Handler) renderLicenseFormWithError(c echo.Context, ctx context.Context
 		fieldErrors["max_product_version"] = "Must be empty or in #.#.# format (e.g., 1.0.0)"
 	case errors.Is(err, license.ErrSubscriptionRequiresTerm):
 		fieldErrors["license_term"] = "Subscription licenses require a term greater than 0"
-	case sqlite.IsUniqueConstraintError(err):
-		fieldErrors["product_id"] = "This customer already has a license for this product"
+	case errors.Is(err, license.ErrInvalidStatus):
+		fieldErrors["status"] = "Status must be active, suspended or cancelled"
 	default:
 		// Unknown error - show toast instead
 		setTriggerWithData(c, fmt.Sprintf(`{"showToast": {"message": %q, "type": "error"}}`, "Failed to save license"))
 		return c.String(http.StatusUnprocessableEntity, "")
 	}
 
-	// Get products for form dropdown
-	unlicensedProducts, _ := h.svc.GetUnlicensedProducts(ctx, customerID)
-	viewProducts := FromDomainProducts(unlicensedProducts)
-
-	// For edit, include current product in list
-	if lic.ProductID != 0 {
-		prod, _ := h.productSvc.Get(ctx, lic.ProductID)
-		if prod != nil {
-			lic.ProductName = prod.ProductName
-			viewProducts = append([]vm.Product{FromDomainProduct(*prod)}, viewProducts...)
-		}
+	// Get products for form dropdown: every product when creating, the licensed one when editing
+	var viewProducts []vm.Product
+	if isNew {
+		products, _ := h.svc.GetProducts(ctx)
+		viewProducts = FromDomainProducts(products)
+	} else if prod, _ := h.productSvc.Get(ctx, lic.ProductID); prod != nil {
+		lic.ProductName = prod.ProductName
+		viewProducts = []vm.Product{FromDomainProduct(*prod)}
 	}
 
 	formData := components.LicenseFormData{
@@ -863,12 +1623,12 @@ func (h *Handler) DeleteLicense(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
 	}
-	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
 	}
 
-	if err := h.svc.DeleteLicense(ctx, customerID, productID); err != nil {
+	if err := h.svc.DeleteLicense(ctx, licenseID); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -882,44 +1642,132 @@ func (h *Handler) DeleteLicense(c echo.Context) error {
 	return components.LicensesTable(customerID, h.getCustomerName(ctx, customerID), viewLics).Render(ctx, c.Response())
 }
 
+func (h *Handler) LicenseKeyModal(c echo.Context) error {
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
+	}
+
+	return h.renderLicenseKeyModal(c, licenseID)
+}
+
+func (h *Handler) RotateLicenseKey(c echo.Context) error {
+	ctx := c.Request().Context()
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
+	}
+
+	graceHours, _ := strconv.Atoi(c.FormValue("grace_hours"))
+	req := &admin.RotateKeyRequest{
+		GraceHours: graceHours,
+		Reason:     strings.TrimSpace(c.FormValue("reason")),
+	}
+	if _, err := h.svc.RotateLicenseKey(ctx, licenseID, req); err != nil {
+		setTriggerWithData(c, fmt.Sprintf(`{"showToast": {"message": %q, "type": "error"}}`, "Failed to rotate license key"))
+		return c.String(http.StatusUnprocessableEntity, "")
+	}
+
+	setTriggerWithData(c, `{"licensesChanged": true, "showToast": {"message": "License key rotated", "type": "success"}}`)
+	return h.renderLicenseKeyModal(c, licenseID)
+}
+
+func (h *Handler) SetLicenseKeyStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
+	}
+
+	req := &admin.SetKeyStatusRequest{
+		Status: c.FormValue("status"),
+		Reason: strings.TrimSpace(c.FormValue("reason")),
+	}
+	if err := h.svc.SetLicenseKeyStatus(ctx, licenseID, req); err != nil {
+		if errors.Is(err, license.ErrInvalidKeyStatus) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	setTriggerWithData(c, fmt.Sprintf(`{"licensesChanged": true, "showToast": {"message": %q, "type": "success"}}`, "License key "+req.Status))
+	return h.renderLicenseKeyModal(c, licenseID)
+}
+
+// renderLicenseKeyModal renders the current key, its status and the replaced keys
+func (h *Handler) renderLicenseKeyModal(c echo.Context, licenseID int64) error {
+	ctx := c.Request().Context()
+
+	lic, err := h.getLicense(ctx, licenseID)
+	if err != nil {
+		return err
+	}
+
+	hist, err := h.svc.GetLicenseKeyHistory(ctx, licenseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	productName := ""
+	if prod, _ := h.productSvc.Get(ctx, lic.ProductID); prod != nil {
+		productName = prod.ProductName
+	}
+
+	return components.LicenseKeyModal(FromDomainLicense(*lic, productName), FromDomainKeyHistory(hist)).Render(ctx, c.Response())
+}
+
+// getLicense loads a license, reporting a missing one as 404
+func (h *Handler) getLicense(ctx context.Context, licenseID int64) (*license.License, error) {
+	lic, err := h.svc.GetLicense(ctx, licenseID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "License not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return lic, nil
+}
+
 // --------------------------
-// Product Features (customer values)
+// License Features (license values)
 // --------------------------
 
-func (h *Handler) GetProductFeatures(c echo.Context) error {
+func (h *Handler) GetLicenseFeatures(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
 	}
-	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+
+	lic, err := h.getLicense(ctx, licenseID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+		return err
 	}
 
-	features := h.getEnrichedFeatureValues(ctx, customerID, productID)
-	return components.FeaturesTable(customerID, productID, features).Render(ctx, c.Response())
+	features := h.getEnrichedFeatureValues(ctx, lic)
+	return components.FeaturesTable(licenseID, features).Render(ctx, c.Response())
 }
 
 func (h *Handler) EditFeatureValueForm(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
-	}
-	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
 	}
 	featureID, err := strconv.ParseInt(c.Param("featureID"), 10, 64)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid feature ID")
 	}
 
-	features := h.getEnrichedFeatureValues(ctx, customerID, productID)
+	lic, err := h.getLicense(ctx, licenseID)
+	if err != nil {
+		return err
+	}
+
+	features := h.getEnrichedFeatureValues(ctx, lic)
 	for _, f := range features {
 		if f.FeatureID == featureID {
-			return components.FeatureValueForm(customerID, productID, f).Render(ctx, c.Response())
+			return components.FeatureValueForm(licenseID, f, "").Render(ctx, c.Response())
 		}
 	}
 
@@ -928,30 +1776,44 @@ func (h *Handler) EditFeatureValueForm(c echo.Context) error {
 
 func (h *Handler) UpdateFeatureValue(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
-	}
-	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
 	}
 	featureID, err := strconv.ParseInt(c.Param("featureID"), 10, 64)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid feature ID")
 	}
 
-	req := &admin.UpdateProductFeatureRequest{
+	req := &admin.UpdateLicenseFeatureRequest{
 		Value: c.FormValue("feature_value"),
 	}
 
-	if err := h.svc.UpdateProductFeature(ctx, customerID, productID, featureID, req); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	lic, err := h.getLicense(ctx, licenseID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.svc.UpdateLicenseFeature(ctx, licenseID, featureID, req); err != nil {
+		var fe *featurevalue.FieldError
+		if !errors.As(err, &fe) {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		// Re-render the value form with the submitted value and the error
+		for _, f := range h.getEnrichedFeatureValues(ctx, lic) {
+			if f.FeatureID == featureID {
+				f.FeatureValue = req.Value
+				c.Response().Header().Set("HX-Retarget", "#modal-content")
+				c.Response().Header().Set("HX-Reswap", "innerHTML")
+				return components.FeatureValueForm(licenseID, f, fe.Error()).Render(ctx, c.Response())
+			}
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	features := h.getEnrichedFeatureValues(ctx, customerID, productID)
+	features := h.getEnrichedFeatureValues(ctx, lic)
 	setTriggerWithData(c, `{"closeModal": true, "showToast": {"message": "Feature value updated successfully", "type": "success"}}`)
-	return components.FeaturesTable(customerID, productID, features).Render(ctx, c.Response())
+	return components.FeaturesTable(licenseID, features).Render(ctx, c.Response())
 }
 
 // --------------------------
@@ -959,30 +1821,13 @@ func (h *Handler) UpdateFeatureValue(c echo.Context) error {
 // --------------------------
 
 func (h *Handler) GetMachineRegistrations(c echo.Context) error {
-	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
-	}
-	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
 	}
 
 	activeOnly := c.QueryParam("active") == "true"
-	machines, err := h.svc.GetMachineRegistrations(ctx, customerID, productID, activeOnly)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-	}
-
-	prod, err := h.svc.GetProduct(ctx, productID)
-	productName := ""
-	if err == nil && prod != nil {
-		productName = prod.ProductName
-	}
-
-	viewMachines := h.convertMachines(ctx, machines, productID)
-	return components.MachinesModal(customerID, productID, productName, viewMachines).Render(ctx, c.Response())
+	return h.renderMachinesModal(c, licenseID, activeOnly)
 }
 
 func (h *Handler) DeleteMachineRegistration(c echo.Context) error {
@@ -996,93 +1841,101 @@ func (h *Handler) DeleteMachineRegistration(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
 
-	// Get machine to find customerID before deleting
-	machine, err := h.machineSvc.Get(ctx, machineID)
-	if err != nil || machine == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Machine not found")
+	// Find the license before the registration that points at it is gone
+	licenseID, err := h.registrationLicenseID(ctx, machineID, productID)
+	if err != nil {
+		return err
 	}
-	customerID := machine.CustomerID
 
 	if err := h.svc.DeleteMachineRegistration(ctx, machineID, productID); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	// Return updated modal content
-	prod, err := h.productSvc.Get(ctx, productID)
+	setTriggerWithData(c, `{"showToast": {"message": "Machine registration deleted successfully", "type": "success"}}`)
+	return h.renderMachinesModal(c, licenseID, false)
+}
+
+func (h *Handler) ManualRegistrationForm(c echo.Context) error {
+	ctx := c.Request().Context()
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
 	}
 
-	machines, err := h.machineSvc.GetForLicense(ctx, customerID, productID)
+	lic, err := h.getLicense(ctx, licenseID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	viewMachines := h.convertMachines(ctx, machines, productID)
-	setTriggerWithData(c, `{"showToast": {"message": "Machine registration deleted successfully", "type": "success"}}`)
-	return components.MachinesModal(customerID, productID, prod.ProductName, viewMachines).Render(ctx, c.Response())
+	return components.ManualRegistrationForm(licenseID, h.getProductName(ctx, lic.ProductID), "").
+		Render(ctx, c.Response())
 }
 
-func (h *Handler) ManualRegistrationForm(c echo.Context) error {
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+func (h *Handler) CreateManualRegistration(c echo.Context) error {
+	ctx := c.Request().Context()
+	licenseID, err := strconv.ParseInt(c.Param("licenseID"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID")
 	}
-	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+
+	lic, err := h.getLicense(ctx, licenseID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+		return err
 	}
 
-	prod, _ := h.productSvc.Get(c.Request().Context(), productID)
-	productName := ""
-	if prod != nil {
-		productName = prod.ProductName
+	req := &activation.Request{
+		MachineCode: c.FormValue("machine_code"),
+		UserName:    c.FormValue("user_name"),
 	}
 
-	return components.ManualRegistrationForm(customerID, productID, productName, "").
-		Render(c.Request().Context(), c.Response())
+	if _, err := h.activationSvc.Activate(ctx, licenseID, req); err != nil {
+		// Return to form with error displayed inline
+		return components.ManualRegistrationForm(licenseID, h.getProductName(ctx, lic.ProductID), err.Error()).
+			Render(ctx, c.Response())
+	}
+
+	// Success: return updated machines modal
+	setTriggerWithData(c, `{"showToast": {"message": "Registration created successfully", "type": "success"}}`)
+	return h.renderMachinesModal(c, licenseID, false)
 }
 
-func (h *Handler) CreateManualRegistration(c echo.Context) error {
+func (h *Handler) ExportMachineRegistration(c echo.Context) error {
 	ctx := c.Request().Context()
-	customerID, err := strconv.ParseInt(c.Param("customerID"), 10, 64)
+	machineID, err := strconv.ParseInt(c.Param("machineID"), 10, 64)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid machine ID")
 	}
 	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
 
-	req := &activation.Request{
-		MachineCode: c.FormValue("machine_code"),
-		UserName:    c.FormValue("user_name"),
+	// The stored registration is exported as is, without activating again
+	f, err := h.svc.ExportRegistration(ctx, machineID, productID)
+	if errors.Is(err, activation.ErrNotRegistered) {
+		return echo.NewHTTPError(http.StatusNotFound, "Registration not found")
 	}
-
-	_, err = h.activationSvc.Activate(ctx, customerID, productID, req)
 	if err != nil {
-		// Return to form with error displayed inline
-		prod, _ := h.productSvc.Get(ctx, productID)
-		productName := ""
-		if prod != nil {
-			productName = prod.ProductName
-		}
-		return components.ManualRegistrationForm(customerID, productID, productName, err.Error()).Render(ctx, c.Response())
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	// Success: return updated machines modal
-	machines, _ := h.svc.GetMachineRegistrations(ctx, customerID, productID, false)
-	prod, _ := h.productSvc.Get(ctx, productID)
-	productName := ""
-	if prod != nil {
-		productName = prod.ProductName
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.Name))
+	return c.JSONPretty(http.StatusOK, f.Registration, "  ")
+}
+
+func (h *Handler) TransferMachineForm(c echo.Context) error {
+	machineID, err := strconv.ParseInt(c.Param("machineID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid machine ID")
 	}
-	viewMachines := h.convertMachines(ctx, machines, productID)
-	setTriggerWithData(c, `{"showToast": {"message": "Registration created successfully", "type": "success"}}`)
-	return components.MachinesModal(customerID, productID, productName, viewMachines).Render(ctx, c.Response())
+	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	return h.renderTransferMachineForm(c, machineID, productID, "")
 }
 
-func (h *Handler) ExportMachineRegistration(c echo.Context) error {
+func (h *Handler) TransferMachine(c echo.Context) error {
 	ctx := c.Request().Context()
 	machineID, err := strconv.ParseInt(c.Param("machineID"), 10, 64)
 	if err != nil {
@@ -1092,55 +1945,87 @@ func (h *Handler) ExportMachineRegistration(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
+	toCustomerID, err := strconv.ParseInt(c.FormValue("to_customer_id"), 10, 64)
+	if err != nil {
+		return h.renderTransferMachineForm(c, machineID, productID, "Select a customer")
+	}
 
-	// Get machine to find customerID and machineCode
-	machine, err := h.machineSvc.Get(ctx, machineID)
+	// The transfer moves the registration to a license of the new customer
+	fromLicenseID, err := h.registrationLicenseID(ctx, machineID, productID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Machine not found")
+		return err
+	}
+
+	req := &admin.TransferMachineRequest{
+		ToCustomerID: toCustomerID,
+		Reason:       strings.TrimSpace(c.FormValue("reason")),
+	}
+	if _, err := h.svc.TransferMachine(ctx, machineID, req); err != nil {
+		// Return to form with error displayed inline
+		return h.renderTransferMachineForm(c, machineID, productID, err.Error())
 	}
 
-	// Get product for filename
+	// Success: return the machines modal of the license the machine came from
+	setTriggerWithData(c, `{"licensesChanged": true, "showToast": {"message": "Machine transferred successfully", "type": "success"}}`)
+	return h.renderMachinesModal(c, fromLicenseID, false)
+}
+
+func (h *Handler) renderTransferMachineForm(c echo.Context, machineID, productID int64, errorMsg string) error {
+	ctx := c.Request().Context()
+	m, err := h.machineSvc.Get(ctx, machineID)
+	if err != nil || m == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Machine not found")
+	}
+	licenseID, err := h.registrationLicenseID(ctx, machineID, productID)
+	if err != nil {
+		return err
+	}
 	prod, err := h.productSvc.Get(ctx, productID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
 	}
+	customers, err := h.svc.GetCustomers(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
 
-	// Build activation response using existing data
-	req := &activation.Request{
-		MachineCode: machine.MachineCode,
-		UserName:    machine.UserName,
+	mr := vm.MachineRegistration{
+		MachineID:   m.MachineID,
+		LicenseID:   licenseID,
+		CustomerID:  m.CustomerID,
+		ProductID:   productID,
+		MachineCode: m.MachineCode,
+		UserName:    m.UserName,
+	}
+	return components.TransferMachineForm(mr, prod.ProductName, FromDomainCustomers(customers), errorMsg).
+		Render(ctx, c.Response())
+}
+
+// renderMachinesModal renders the machines registered against a license
+func (h *Handler) renderMachinesModal(c echo.Context, licenseID int64, activeOnly bool) error {
+	ctx := c.Request().Context()
+
+	lic, err := h.getLicense(ctx, licenseID)
+	if err != nil {
+		return err
 	}
-	resp, err := h.activationSvc.Activate(ctx, machine.CustomerID, productID, req)
+
+	machines, err := h.svc.GetMachineRegistrations(ctx, licenseID, activeOnly)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	// Set headers for file download: {product}-{company}-{username}.json
-	filename := fmt.Sprintf("%s-%s-%s.json",
-		sanitizeFilename(prod.ProductName),
-		sanitizeFilename(resp.UserCompany),
-		sanitizeFilename(resp.UserName),
-	)
-	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
-	return c.JSONPretty(http.StatusOK, resp, "  ")
-}
-
-// sanitizeFilename removes or replaces characters that are invalid in filenames
-func sanitizeFilename(s string) string {
-	// Replace spaces and common problematic characters
-	replacer := strings.NewReplacer(
-		" ", "_",
-		"/", "_",
-		"\\", "_",
-		":", "_",
-		"*", "_",
-		"?", "_",
-		"\"", "_",
-		"<", "_",
-		">", "_",
-		"|", "_",
-	)
-	return replacer.Replace(s)
+	viewMachines := FromMachineRegistrations(machines, lic.LicenseID, lic.ProductID)
+	return components.MachinesModal(licenseID, h.getProductName(ctx, lic.ProductID), viewMachines).Render(ctx, c.Response())
+}
+
+// registrationLicenseID returns the license a machine's registration for a product counts against
+func (h *Handler) registrationLicenseID(ctx context.Context, machineID, productID int64) (int64, error) {
+	reg, err := h.regSvc.Get(ctx, machineID, productID)
+	if err != nil || reg.LicenseID == nil {
+		return 0, echo.NewHTTPError(http.StatusNotFound, "Registration not found")
+	}
+	return *reg.LicenseID, nil
 }
 
 // --------------------------
@@ -1208,6 +2093,51 @@ func csvEscape(s string) string {
 	return s
 }
 
+// --------------------------
+// Reports
+// --------------------------
+
+// reportPeriods is the number of periods charted for each interval
+var reportPeriods = map[analytics.Interval]int{
+	analytics.IntervalDay:   30,
+	analytics.IntervalWeek:  12,
+	analytics.IntervalMonth: 12,
+}
+
+func (h *Handler) Reports(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	interval, err := analytics.ParseInterval(c.QueryParam("interval"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// product=0 (or missing) reports on all products
+	productID, _ := strconv.ParseInt(c.QueryParam("product"), 10, 64)
+
+	counts, err := h.analyticsSvc.GetActivationCounts(ctx, interval, reportPeriods[interval], productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	seats, err := h.analyticsSvc.GetSeatUtilization(ctx, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	report := FromDomainActivationReport(interval, productID, counts)
+	viewSeats := FromDomainSeatUtilizations(seats)
+	if isHTMX(c) {
+		return components.ReportsContent(report, viewSeats).Render(ctx, c.Response())
+	}
+
+	products, err := h.productSvc.GetAll(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return pages.Reports(FromDomainProducts(products), report, viewSeats).Render(ctx, c.Response())
+}
+
 // --------------------------
 // Helper methods
 // --------------------------
@@ -1245,51 +2175,35 @@ func (h *Handler) convertLicenses(ctx context.Context, lics []license.License) [
 	return result
 }
 
-func (h *Handler) getEnrichedFeatureValues(ctx context.Context, customerID, productID int64) []ProductFeature {
+func (h *Handler) getEnrichedFeatureValues(ctx context.Context, lic *license.License) []ProductFeature {
 	// Get feature definitions
-	features, _ := h.featureSvc.GetForProduct(ctx, productID)
-	// Get feature values
-	values, _ := h.featureValSvc.GetFeatureValues(ctx, customerID, productID)
+	features, _ := h.featureSvc.GetForProduct(ctx, lic.ProductID)
+	// Get feature values and the values of the license's plan
+	values, _ := h.featureValSvc.GetFeatureValues(ctx, lic.LicenseID)
+	planValues, _ := h.featureValSvc.GetPlanValues(ctx, lic.LicenseID)
 
-	// Create a map of feature values by feature ID
+	// Create maps of feature values by feature ID
 	valueMap := make(map[int64]featurevalue.FeatureValue)
 	for _, v := range values {
 		valueMap[v.FeatureID] = v
 	}
+	planMap := make(map[int64]string)
+	for _, v := range planValues {
+		planMap[v.FeatureID] = v.FeatureValue
+	}
 
 	// Combine features with values
 	result := make([]ProductFeature, len(features))
 	for i, f := range features {
 		fv := featurevalue.FeatureValue{
-			CustomerID: customerID,
-			ProductID:  productID,
-			FeatureID:  f.FeatureID,
+			LicenseID: lic.LicenseID,
+			FeatureID: f.FeatureID,
 		}
 		if v, ok := valueMap[f.FeatureID]; ok {
 			fv.FeatureValue = v.FeatureValue
 		}
 		result[i] = FromDomainFeatureValue(fv, f)
-	}
-	return result
-}
-
-func (h *Handler) convertMachines(ctx context.Context, machines []machine.Machine, productID int64) []MachineRegistration {
-	result := make([]MachineRegistration, len(machines))
-	for i, m := range machines {
-		// Get registration details for this machine/product
-		regs, _ := h.regSvc.GetForMachine(ctx, m.MachineID)
-		var regHash, expDate, firstRegDate, lastRegDate, installedVersion string
-		for _, r := range regs {
-			if r.ProductID == productID {
-				regHash = r.RegistrationHash
-				expDate = r.ExpirationDate
-				firstRegDate = r.FirstRegistrationDate
-				lastRegDate = r.LastRegistrationDate
-				installedVersion = r.InstalledVersion
-				break
-			}
-		}
-		result[i] = FromDomainMachine(m, productID, regHash, expDate, firstRegDate, lastRegDate, installedVersion)
+		result[i].PlanValue = planMap[f.FeatureID]
 	}
 	return result
 }
@@ -1,11 +1,19 @@
 package web
 
 import (
+	"strconv"
+	"time"
+
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/bulk"
 	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/feature"
 	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/http/admin"
+	"winsbygroup.com/regserver/internal/inactivity"
 	"winsbygroup.com/regserver/internal/license"
 	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/plan"
 	"winsbygroup.com/regserver/internal/product"
 	vm "winsbygroup.com/regserver/internal/viewmodels"
 )
@@ -13,12 +21,22 @@ import (
 // Re-export types for convenience
 type (
 	Customer            = vm.Customer
+	Contact             = vm.Contact
 	Product             = vm.Product
 	License             = vm.License
+	KeyHistory          = vm.KeyHistory
 	Feature             = vm.Feature
 	ProductFeature      = vm.ProductFeature
+	Plan                = vm.Plan
+	PlanLicense         = vm.PlanLicense
+	BulkPreview         = vm.BulkPreview
+	BulkOperation       = vm.BulkOperation
+	InactivityReport    = vm.InactivityReport
 	MachineRegistration = vm.MachineRegistration
+	PortalLicense       = vm.PortalLicense
 	ExpiredLicense      = vm.ExpiredLicense
+	ActivationReport    = vm.ActivationReport
+	SeatUtilization     = vm.SeatUtilization
 	FeatureType         = vm.FeatureType
 )
 
@@ -27,6 +45,8 @@ const (
 	FeatureTypeInteger = vm.FeatureTypeInteger
 	FeatureTypeString  = vm.FeatureTypeString
 	FeatureTypeValues  = vm.FeatureTypeValues
+	FeatureTypeBoolean = vm.FeatureTypeBoolean
+	FeatureTypeDate    = vm.FeatureTypeDate
 )
 
 // FromDomainCustomer converts a domain customer to view model
@@ -50,6 +70,29 @@ func FromDomainCustomers(customers []customer.Customer) []vm.Customer {
 	return result
 }
 
+// FromDomainContact converts a domain customer contact to view model
+func FromDomainContact(c customer.Contact) vm.Contact {
+	return vm.Contact{
+		ContactID:   c.ContactID,
+		CustomerID:  c.CustomerID,
+		ContactName: c.ContactName,
+		Phone:       c.Phone,
+		Email:       c.Email,
+		Roles:       c.RoleList(),
+		IsPrimary:   c.IsPrimary,
+		Notes:       c.Notes,
+	}
+}
+
+// FromDomainContacts converts a slice of domain customer contacts to view models
+func FromDomainContacts(contacts []customer.Contact) []vm.Contact {
+	result := make([]vm.Contact, len(contacts))
+	for i, c := range contacts {
+		result[i] = FromDomainContact(c)
+	}
+	return result
+}
+
 // FromDomainProduct converts a domain product to view model
 func FromDomainProduct(p product.Product) vm.Product {
 	return vm.Product{
@@ -73,6 +116,7 @@ func FromDomainProducts(products []product.Product) []vm.Product {
 // FromDomainLicense converts a domain license to view model
 func FromDomainLicense(lic license.License, productName string) vm.License {
 	return vm.License{
+		LicenseID:           lic.LicenseID,
 		CustomerID:          lic.CustomerID,
 		ProductID:           lic.ProductID,
 		ProductName:         productName,
@@ -84,9 +128,31 @@ func FromDomainLicense(lic license.License, productName string) vm.License {
 		ExpirationDate:      lic.ExpirationDate,
 		MaintExpirationDate: lic.MaintExpirationDate,
 		MaxProductVersion:   lic.MaxProductVersion,
+		KeyStatus:           lic.KeyStatus,
+		Status:              lic.Status,
+		StatusReason:        lic.StatusReason,
+		StatusChangedAt:     lic.StatusChangedAt,
 	}
 }
 
+// FromDomainKeyHistory converts license key changes to view models
+func FromDomainKeyHistory(hist []license.KeyHistory) []vm.KeyHistory {
+	now := time.Now()
+	result := make([]vm.KeyHistory, len(hist))
+	for i, h := range hist {
+		result[i] = vm.KeyHistory{
+			LicenseKey: h.LicenseKey,
+			Event:      h.Event,
+			Reason:     h.Reason,
+			ReplacedAt: h.ReplacedAt,
+			GraceUntil: h.GraceUntil,
+			RevokedAt:  h.RevokedAt,
+			InGrace:    h.InGrace(now),
+		}
+	}
+	return result
+}
+
 // FromDomainFeature converts a domain feature to view model
 func FromDomainFeature(f feature.Feature) vm.Feature {
 	return vm.Feature{
@@ -96,9 +162,39 @@ func FromDomainFeature(f feature.Feature) vm.Feature {
 		FeatureType:   vm.FeatureType(f.FeatureType),
 		AllowedValues: f.AllowedValues,
 		DefaultValue:  f.DefaultValue,
+		MinValue:      formatLimit(f.MinValue),
+		MaxValue:      formatLimit(f.MaxValue),
+		MaxLength:     formatMaxLength(f.MaxLength),
+		Status:        f.Status,
+		Aliases:       aliasNames(f.Aliases),
 	}
 }
 
+// aliasNames returns the former names of a feature
+func aliasNames(aliases []feature.Alias) []string {
+	out := make([]string, len(aliases))
+	for i, a := range aliases {
+		out[i] = a.AliasName
+	}
+	return out
+}
+
+// formatLimit formats an optional integer bound (empty when unset)
+func formatLimit(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+// formatMaxLength formats a maximum length (empty when unlimited)
+func formatMaxLength(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
 // FromDomainFeatures converts a slice of domain features to view models
 func FromDomainFeatures(features []feature.Feature) []vm.Feature {
 	result := make([]vm.Feature, len(features))
@@ -111,21 +207,146 @@ func FromDomainFeatures(features []feature.Feature) []vm.Feature {
 // FromDomainFeatureValue converts domain feature value + feature to view model
 func FromDomainFeatureValue(fv featurevalue.FeatureValue, f feature.Feature) vm.ProductFeature {
 	return vm.ProductFeature{
-		CustomerID:    fv.CustomerID,
-		ProductID:     fv.ProductID,
+		LicenseID:     fv.LicenseID,
+		ProductID:     f.ProductID,
 		FeatureID:     fv.FeatureID,
 		FeatureName:   f.FeatureName,
 		FeatureType:   vm.FeatureType(f.FeatureType),
 		FeatureValue:  fv.FeatureValue,
 		AllowedValues: f.AllowedValues,
 		DefaultValue:  f.DefaultValue,
+		MinValue:      formatLimit(f.MinValue),
+		MaxValue:      formatLimit(f.MaxValue),
+		MaxLength:     formatMaxLength(f.MaxLength),
 	}
 }
 
+// FromDomainPlan converts a domain plan to view model
+func FromDomainPlan(p plan.Plan) vm.Plan {
+	values := make(map[int64]string, len(p.Values))
+	for _, v := range p.Values {
+		values[v.FeatureID] = v.FeatureValue
+	}
+	return vm.Plan{
+		PlanID:    p.PlanID,
+		ProductID: p.ProductID,
+		PlanName:  p.PlanName,
+		Licenses:  p.Licenses,
+		Values:    values,
+	}
+}
+
+// FromDomainPlans converts a slice of domain plans to view models
+func FromDomainPlans(plans []plan.Plan) []vm.Plan {
+	result := make([]vm.Plan, len(plans))
+	for i, p := range plans {
+		result[i] = FromDomainPlan(p)
+	}
+	return result
+}
+
+// FromDomainPlanLicenses converts the licenses listed for bulk plan changes to view models
+func FromDomainPlanLicenses(licenses []plan.License) []vm.PlanLicense {
+	result := make([]vm.PlanLicense, len(licenses))
+	for i, l := range licenses {
+		result[i] = vm.PlanLicense{
+			LicenseID:    l.LicenseID,
+			CustomerName: l.CustomerName,
+			LicenseKey:   l.LicenseKey,
+			LicenseCount: l.LicenseCount,
+			Status:       l.Status,
+			PlanName:     l.PlanName,
+		}
+		if l.PlanID != nil {
+			result[i].PlanID = *l.PlanID
+		}
+	}
+	return result
+}
+
+// FromDomainBulkPreview converts a bulk operation preview to view model
+func FromDomainBulkPreview(p *bulk.Preview) *vm.BulkPreview {
+	return &vm.BulkPreview{
+		Items:          fromDomainBulkItems(p.Items),
+		Bundled:        fromDomainBulkItems(p.Bundled),
+		Errors:         p.Errors,
+		FeatureChanged: p.Change.FeatureID != 0,
+	}
+}
+
+// fromDomainBulkItems converts the licenses of a bulk operation preview to view models
+func fromDomainBulkItems(items []bulk.Item) []vm.BulkItem {
+	out := make([]vm.BulkItem, len(items))
+	for i, it := range items {
+		out[i] = vm.BulkItem{
+			LicenseID:              it.LicenseID,
+			CustomerName:           it.CustomerName,
+			ProductName:            it.ProductName,
+			LicenseKey:             it.LicenseKey,
+			InBundle:               it.BundleID != nil,
+			ExpirationDate:         it.ExpirationDate,
+			NewExpirationDate:      it.NewExpirationDate,
+			MaintExpirationDate:    it.MaintExpirationDate,
+			NewMaintExpirationDate: it.NewMaintExpirationDate,
+			LicenseCount:           it.LicenseCount,
+			NewLicenseCount:        it.NewLicenseCount,
+			MaxProductVersion:      it.MaxProductVersion,
+			NewMaxProductVersion:   it.NewMaxProductVersion,
+			FeatureValue:           it.FeatureValue,
+			NewFeatureValue:        it.NewFeatureValue,
+			Error:                  it.Error,
+		}
+	}
+	return out
+}
+
+// FromDomainBulkOperations converts the bulk operation audit trail to view models
+func FromDomainBulkOperations(ops []bulk.Operation) []vm.BulkOperation {
+	result := make([]vm.BulkOperation, len(ops))
+	for i, op := range ops {
+		result[i] = vm.BulkOperation{
+			OperationID:   op.OperationID,
+			OperationTime: op.OperationTime,
+			Selection:     op.Selection,
+			Changes:       op.Changes,
+			LicenseCount:  op.LicenseCount,
+		}
+	}
+	return result
+}
+
+// FromDomainInactivityReport converts an inactivity preview to view model
+func FromDomainInactivityReport(r *inactivity.Report) *vm.InactivityReport {
+	out := &vm.InactivityReport{
+		Items:      make([]vm.InactivityItem, len(r.Items)),
+		Flag:       r.Flag,
+		Release:    r.Release,
+		Wait:       r.Wait,
+		Clear:      r.Clear,
+		NoticeDays: r.NoticeDays,
+	}
+	for i, it := range r.Items {
+		out.Items[i] = vm.InactivityItem{
+			CustomerName:     it.CustomerName,
+			LicenseKey:       it.LicenseKey,
+			MachineCode:      it.MachineCode,
+			UserName:         it.UserName,
+			LastActivityDate: it.LastActivityDate,
+			IdleDate:         it.IdleDate,
+			ReleaseDate:      it.ReleaseDate,
+			Days:             it.Days,
+			Action:           it.Action,
+			Next:             it.Next,
+		}
+	}
+	return out
+}
+
 // FromDomainMachine converts a domain machine to view model
-func FromDomainMachine(m machine.Machine, productID int64, regHash, expDate, firstRegDate, lastRegDate, installedVersion string) vm.MachineRegistration {
+func FromDomainMachine(m machine.Machine, licenseID, productID int64, regHash, expDate, firstRegDate, lastRegDate, installedVersion string) vm.MachineRegistration {
 	return vm.MachineRegistration{
 		MachineID:        m.MachineID,
+		LicenseID:        licenseID,
 		CustomerID:       m.CustomerID,
 		ProductID:        productID,
 		MachineCode:      m.MachineCode,
@@ -138,6 +359,20 @@ func FromDomainMachine(m machine.Machine, productID int64, regHash, expDate, fir
 	}
 }
 
+// FromMachineRegistrations converts the machines registered against a license to view models
+func FromMachineRegistrations(mrs []admin.MachineRegistration, licenseID, productID int64) []vm.MachineRegistration {
+	result := make([]vm.MachineRegistration, len(mrs))
+	for i, mr := range mrs {
+		result[i] = FromDomainMachine(mr.Machine, licenseID, productID, mr.RegistrationHash, mr.ExpirationDate,
+			mr.FirstRegistrationDate, mr.LastRegistrationDate, mr.InstalledVersion)
+		result[i].LastSeenAt = mr.LastSeenAt
+		result[i].ClientOS = mr.ClientOS
+		result[i].ClientHostname = mr.ClientHostname
+		result[i].ClientIP = mr.ClientIP
+	}
+	return result
+}
+
 // FromDomainExpiredLicense converts a domain expired license to view model
 func FromDomainExpiredLicense(el license.ExpiredLicense) vm.ExpiredLicense {
 	return vm.ExpiredLicense{
@@ -158,3 +393,38 @@ func FromDomainExpiredLicenses(licenses []license.ExpiredLicense) []vm.ExpiredLi
 	}
 	return result
 }
+
+// FromDomainActivationReport converts domain period counts to the activations chart view model
+func FromDomainActivationReport(interval analytics.Interval, productID int64, counts []analytics.PeriodCount) vm.ActivationReport {
+	periods := make([]vm.ActivationPeriod, len(counts))
+	for i, c := range counts {
+		periods[i] = vm.ActivationPeriod{
+			Period:        c.Period,
+			New:           c.NewCount,
+			Reactivations: c.ReactivationCount,
+		}
+	}
+	return vm.ActivationReport{
+		Interval:  string(interval),
+		ProductID: productID,
+		Periods:   periods,
+	}
+}
+
+// FromDomainSeatUtilizations converts domain seat utilization rows to view models
+func FromDomainSeatUtilizations(seats []analytics.SeatUtilization) []vm.SeatUtilization {
+	result := make([]vm.SeatUtilization, len(seats))
+	for i, s := range seats {
+		result[i] = vm.SeatUtilization{
+			LicenseID:    s.LicenseID,
+			CustomerID:   s.CustomerID,
+			CustomerName: s.CustomerName,
+			ProductID:    s.ProductID,
+			ProductName:  s.ProductName,
+			LicenseCount: s.LicenseCount,
+			SeatsInUse:   s.SeatsInUse,
+			Percent:      s.Percent(),
+		}
+	}
+	return result
+}
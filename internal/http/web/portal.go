@@ -0,0 +1,316 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/mailer"
+	"winsbygroup.com/regserver/internal/middleware"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
+	vm "winsbygroup.com/regserver/internal/viewmodels"
+	"winsbygroup.com/regserver/templates/components"
+	"winsbygroup.com/regserver/templates/pages"
+)
+
+// PortalHandler handles the customer self-service portal. Customers sign in
+// with one of their license keys and a link emailed to their technical contact,
+// and only ever see their own licenses and machines.
+type PortalHandler struct {
+	customerSvc   *customer.Service
+	licenseSvc    *license.Service
+	productSvc    *product.Service
+	machineSvc    *machine.Service
+	regSvc        *registration.Service
+	activationSvc *activation.Service
+	mail          mailer.Mailer
+	store         *middleware.PortalStore
+	publicURL     string
+}
+
+// NewPortalHandler creates a new customer portal handler. publicURL is the base
+// for sign-in links and must be set; links are never built from request headers.
+func NewPortalHandler(
+	customerSvc *customer.Service,
+	licenseSvc *license.Service,
+	productSvc *product.Service,
+	machineSvc *machine.Service,
+	regSvc *registration.Service,
+	activationSvc *activation.Service,
+	mail mailer.Mailer,
+	store *middleware.PortalStore,
+	publicURL string,
+) *PortalHandler {
+	return &PortalHandler{
+		customerSvc:   customerSvc,
+		licenseSvc:    licenseSvc,
+		productSvc:    productSvc,
+		machineSvc:    machineSvc,
+		regSvc:        regSvc,
+		activationSvc: activationSvc,
+		mail:          mail,
+		store:         store,
+		publicURL:     strings.TrimRight(publicURL, "/"),
+	}
+}
+
+// --------------------------
+// Sign in
+// --------------------------
+
+func (h *PortalHandler) LoginPage(c echo.Context) error {
+	return pages.PortalLogin("").Render(c.Request().Context(), c.Response())
+}
+
+// Login emails a sign-in link to the customer owning the license key
+func (h *PortalHandler) Login(c echo.Context) error {
+	ctx := c.Request().Context()
+	licKey := strings.TrimSpace(c.FormValue("license_key"))
+
+	ref, err := h.licenseSvc.ResolveKey(ctx, licKey)
+	if err != nil {
+		if errors.Is(err, license.ErrNotFound) {
+			middleware.AuthFailed(c) // unknown key: counts toward the login lockout
+		}
+		logging.FromContext(ctx).Warn("failed portal login",
+			"license_key", logging.KeyPrefix(licKey), "remote_ip", c.RealIP(), "error", err)
+		c.Response().WriteHeader(http.StatusUnauthorized)
+		return pages.PortalLogin("Invalid or inactive license key").Render(ctx, c.Response())
+	}
+
+	cust, err := h.customerSvc.Get(ctx, ref.CustomerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	// Machines are managed by the technical contact
+	email, name := cust.Email, cust.CustomerName
+	contact, err := h.customerSvc.ContactFor(ctx, cust.CustomerID, customer.RoleTechnical)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if contact != nil && contact.Email != "" {
+		email = contact.Email
+		if contact.ContactName != "" {
+			name = contact.ContactName
+		}
+	}
+	if email == "" {
+		return pages.PortalLogin("No email address is on file for your account. Please contact us to add one.").
+			Render(ctx, c.Response())
+	}
+
+	token := h.store.CreateLink(cust.CustomerID)
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Your license portal sign-in link",
+		Body: fmt.Sprintf("Hello %s,\n\nUse this link to sign in to the license portal:\n\n%s/portal/auth/%s\n\n"+
+			"The link works once and expires in 15 minutes. If you did not request it, you can ignore this email.\n",
+			name, h.publicURL, token),
+	}
+	if err := h.mail.Send(ctx, msg); err != nil {
+		logging.FromContext(ctx).Error("portal sign-in email failed", "customer_id", cust.CustomerID, "error", err)
+		return pages.PortalLogin("The sign-in email could not be sent. Please try again later.").Render(ctx, c.Response())
+	}
+
+	logging.FromContext(ctx).Info("portal sign-in link sent", "customer_id", cust.CustomerID)
+	return pages.PortalLinkSent(maskEmail(email)).Render(ctx, c.Response())
+}
+
+// Auth exchanges an emailed sign-in link for a session
+func (h *PortalHandler) Auth(c echo.Context) error {
+	sessionID, ok := h.store.ConsumeLink(c.Param("token"))
+	if !ok {
+		middleware.AuthFailed(c)
+		c.Response().WriteHeader(http.StatusUnauthorized)
+		return pages.PortalLogin("This sign-in link is invalid or has expired. Please request a new one.").
+			Render(c.Request().Context(), c.Response())
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     middleware.PortalCookieName,
+		Value:    sessionID,
+		Path:     "/portal",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode, // the link is opened from an email client
+	})
+	return c.Redirect(http.StatusFound, "/portal/")
+}
+
+func (h *PortalHandler) Logout(c echo.Context) error {
+	if cookie, err := c.Cookie(middleware.PortalCookieName); err == nil && cookie.Value != "" {
+		h.store.DeleteSession(cookie.Value)
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     middleware.PortalCookieName,
+		Value:    "",
+		Path:     "/portal",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+	return c.Redirect(http.StatusFound, "/portal/login")
+}
+
+// --------------------------
+// Licenses and machines
+// --------------------------
+
+func (h *PortalHandler) Index(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID := middleware.PortalCustomerID(c)
+
+	cust, err := h.customerSvc.Get(ctx, customerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	lics, err := h.portalLicenses(ctx, customerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return pages.Portal(cust.CustomerName, lics).Render(ctx, c.Response())
+}
+
+// DeactivateMachine removes the customer's machine registration for a product, freeing its seat
+func (h *PortalHandler) DeactivateMachine(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID := middleware.PortalCustomerID(c)
+
+	m, reg, err := h.ownedRegistration(c, customerID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.regSvc.Delete(ctx, m.MachineID, reg.ProductID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	logging.FromContext(ctx).Info("machine deactivated in portal",
+		"customer_id", customerID, "machine_id", m.MachineID, "product_id", reg.ProductID)
+
+	lics, err := h.portalLicenses(ctx, customerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	setTriggerWithData(c, `{"showToast": {"message": "Machine deactivated", "type": "success"}}`)
+	return components.PortalLicenses(lics).Render(ctx, c.Response())
+}
+
+// ExportRegistration downloads the offline registration file for one of the customer's machines
+func (h *PortalHandler) ExportRegistration(c echo.Context) error {
+	ctx := c.Request().Context()
+	customerID := middleware.PortalCustomerID(c)
+
+	m, reg, err := h.ownedRegistration(c, customerID)
+	if err != nil {
+		return err
+	}
+	if reg.LicenseID == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Machine not found")
+	}
+	prod, err := h.productSvc.Get(ctx, reg.ProductID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+
+	resp, err := h.activationSvc.BuildRegistration(ctx, m.MachineID, reg.ProductID)
+	if errors.Is(err, activation.ErrNotRegistered) {
+		return echo.NewHTTPError(http.StatusNotFound, "Machine not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", activation.FileName(".json", prod.ProductName, resp.UserCompany, resp.UserName)))
+	return c.JSONPretty(http.StatusOK, resp, "  ")
+}
+
+// ownedRegistration resolves the :machineID/:productID path parameters to a
+// registered machine of the signed-in customer and its registration.
+// Anything else is a 404 so other customers' machines cannot be probed.
+func (h *PortalHandler) ownedRegistration(c echo.Context, customerID int64) (*machine.Machine, *registration.Registration, error) {
+	ctx := c.Request().Context()
+	notFound := echo.NewHTTPError(http.StatusNotFound, "Machine not found")
+
+	machineID, err := strconv.ParseInt(c.Param("machineID"), 10, 64)
+	if err != nil {
+		return nil, nil, notFound
+	}
+	productID, err := strconv.ParseInt(c.Param("productID"), 10, 64)
+	if err != nil {
+		return nil, nil, notFound
+	}
+
+	m, err := h.machineSvc.Get(ctx, machineID)
+	if err != nil || m == nil || m.CustomerID != customerID {
+		return nil, nil, notFound
+	}
+	reg, err := h.regSvc.Get(ctx, machineID, productID)
+	if err != nil {
+		return nil, nil, notFound
+	}
+	return m, reg, nil
+}
+
+// portalLicenses builds the licenses, seat usage and machines shown to a customer
+func (h *PortalHandler) portalLicenses(ctx context.Context, customerID int64) ([]vm.PortalLicense, error) {
+	lics, err := h.licenseSvc.GetForCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]vm.PortalLicense, len(lics))
+	for i, lic := range lics {
+		productName := ""
+		if prod, _ := h.productSvc.Get(ctx, lic.ProductID); prod != nil {
+			productName = prod.ProductName
+		}
+
+		active, err := h.machineSvc.GetActiveForLicense(ctx, lic.LicenseID)
+		if err != nil {
+			return nil, err
+		}
+		machines, err := h.machineSvc.GetForLicense(ctx, lic.LicenseID)
+		if err != nil {
+			return nil, err
+		}
+
+		viewMachines := make([]vm.MachineRegistration, len(machines))
+		for j, m := range machines {
+			reg, _ := h.regSvc.Get(ctx, m.MachineID, lic.ProductID)
+			if reg == nil {
+				reg = &registration.Registration{}
+			}
+			viewMachines[j] = FromDomainMachine(m, lic.LicenseID, lic.ProductID, "", reg.ExpirationDate,
+				reg.FirstRegistrationDate, reg.LastRegistrationDate, reg.InstalledVersion)
+		}
+
+		result[i] = vm.PortalLicense{
+			License:    FromDomainLicense(lic, productName),
+			SeatsInUse: len(active),
+			Machines:   viewMachines,
+		}
+	}
+	return result, nil
+}
+
+// maskEmail hides most of the local part of an address ("j***@acme.com")
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
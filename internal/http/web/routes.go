@@ -4,11 +4,12 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// RegisterRoutes registers all web UI routes
-func RegisterRoutes(e *echo.Group, h *Handler) {
+// RegisterRoutes registers all web UI routes.
+// The loginGuard middleware (rate limiting and lockout) is applied to login attempts.
+func RegisterRoutes(e *echo.Group, h *Handler, loginGuard echo.MiddlewareFunc) {
 	// Authentication
 	e.GET("/login", h.LoginPage)
-	e.POST("/login", h.Login)
+	e.POST("/login", h.Login, loginGuard)
 	e.POST("/logout", h.Logout)
 
 	// Dashboard
@@ -23,6 +24,14 @@ func RegisterRoutes(e *echo.Group, h *Handler) {
 	e.PUT("/customers/:id", h.UpdateCustomer)
 	e.DELETE("/customers/:id", h.DeleteCustomer)
 
+	// Customer Contacts
+	e.GET("/customers/:id/contacts", h.CustomerContactsManager)
+	e.GET("/customers/:id/contacts/new", h.NewContactForm)
+	e.POST("/customers/:id/contacts", h.CreateContact)
+	e.GET("/customers/:id/contacts/:contactId/edit", h.EditContactForm)
+	e.PUT("/customers/:id/contacts/:contactId", h.UpdateContact)
+	e.DELETE("/customers/:id/contacts/:contactId", h.DeleteContact)
+
 	// Products
 	e.GET("/products", h.ListProducts)
 	e.GET("/products/new", h.NewProductForm)
@@ -39,30 +48,74 @@ func RegisterRoutes(e *echo.Group, h *Handler) {
 	e.PUT("/products/:id/features/:featureId", h.UpdateFeature)
 	e.DELETE("/products/:id/features/:featureId", h.DeleteFeature)
 
+	// Product Plans (per product sets of feature values)
+	e.GET("/products/:id/plans", h.ProductPlansManager)
+	e.GET("/products/:id/plans/new", h.NewPlanForm)
+	e.POST("/products/:id/plans", h.CreatePlan)
+	e.GET("/products/:id/plans/assign", h.PlanAssignForm)
+	e.PUT("/products/:id/plans/assign", h.AssignPlan)
+	e.GET("/products/:id/plans/:planId/edit", h.EditPlanForm)
+	e.PUT("/products/:id/plans/:planId", h.UpdatePlan)
+	e.DELETE("/products/:id/plans/:planId", h.DeletePlan)
+
+	// Bulk License Updates (per product)
+	e.GET("/products/:id/bulk", h.BulkForm)
+	e.POST("/products/:id/bulk/preview", h.PreviewBulk)
+	e.POST("/products/:id/bulk/apply", h.ApplyBulk)
+	e.GET("/products/:id/bulk/history", h.BulkHistory)
+
+	// Inactivity Policies (per product)
+	e.GET("/products/:id/inactivity", h.InactivityForm)
+	e.PUT("/products/:id/inactivity", h.SaveInactivity)
+
 	// Licenses
 	e.GET("/licenses/:customerID", h.GetLicenses)
 	e.GET("/licenses/:customerID/new", h.NewLicenseForm)
 	e.POST("/licenses/:customerID", h.CreateLicense)
-	e.GET("/licenses/:customerID/:productID/edit", h.EditLicenseForm)
-	e.PUT("/licenses/:customerID/:productID", h.UpdateLicense)
-	e.DELETE("/licenses/:customerID/:productID", h.DeleteLicense)
+	e.GET("/licenses/:customerID/:licenseID/edit", h.EditLicenseForm)
+	e.PUT("/licenses/:customerID/:licenseID", h.UpdateLicense)
+	e.DELETE("/licenses/:customerID/:licenseID", h.DeleteLicense)
+	e.GET("/licenses/:customerID/:licenseID/key", h.LicenseKeyModal)
+	e.POST("/licenses/:customerID/:licenseID/key/rotate", h.RotateLicenseKey)
+	e.PUT("/licenses/:customerID/:licenseID/key/status", h.SetLicenseKeyStatus)
 
-	// Product Features (customer values)
-	e.GET("/features/:customerID/:productID", h.GetProductFeatures)
-	e.GET("/features/:customerID/:productID/:featureID/edit", h.EditFeatureValueForm)
-	e.PUT("/features/:customerID/:productID/:featureID", h.UpdateFeatureValue)
+	// License Features (license values)
+	e.GET("/features/:licenseID", h.GetLicenseFeatures)
+	e.GET("/features/:licenseID/:featureID/edit", h.EditFeatureValueForm)
+	e.PUT("/features/:licenseID/:featureID", h.UpdateFeatureValue)
 
 	// Machine Registrations
-	e.GET("/machines/:customerID/:productID", h.GetMachineRegistrations)
-	e.GET("/machines/:customerID/:productID/add", h.ManualRegistrationForm)
-	e.POST("/machines/:customerID/:productID", h.CreateManualRegistration)
+	e.GET("/machines/:licenseID", h.GetMachineRegistrations)
+	e.GET("/machines/:licenseID/add", h.ManualRegistrationForm)
+	e.POST("/machines/:licenseID", h.CreateManualRegistration)
 	e.GET("/machines/:machineID/:productID/export", h.ExportMachineRegistration)
+	e.GET("/machines/:machineID/:productID/transfer", h.TransferMachineForm)
+	e.POST("/machines/:machineID/:productID/transfer", h.TransferMachine)
 	e.DELETE("/machines/:machineID/:productID", h.DeleteMachineRegistration)
 
 	// Expirations
 	e.GET("/expirations", h.ListExpirations)
 	e.GET("/expirations/csv", h.ExportExpirationsCSV)
 
+	// Reports
+	e.GET("/reports", h.Reports)
+
 	// Backup
 	e.POST("/backup", h.Backup)
 }
+
+// RegisterPortalRoutes registers the customer portal routes.
+// The loginGuard middleware (rate limiting and lockout) is applied to sign-in attempts.
+func RegisterPortalRoutes(e *echo.Group, h *PortalHandler, loginGuard echo.MiddlewareFunc) {
+	// Authentication
+	e.GET("/login", h.LoginPage)
+	e.POST("/login", h.Login, loginGuard)
+	e.GET("/auth/:token", h.Auth, loginGuard)
+	e.POST("/logout", h.Logout)
+
+	// Licenses and machines
+	e.GET("/", h.Index)
+	e.GET("", h.Index)
+	e.GET("/machines/:machineID/:productID/export", h.ExportRegistration)
+	e.DELETE("/machines/:machineID/:productID", h.DeactivateMachine)
+}
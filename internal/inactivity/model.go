@@ -0,0 +1,104 @@
+package inactivity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Actions taken on registrations that stay idle
+const (
+	ActionFlag    = "flag"    // mark the registration idle; its seat stays taken
+	ActionRelease = "release" // mark it idle, then release its seat after the notice period
+)
+
+// What the next run does to a registration in the report
+const (
+	NextFlag    = "flag"    // becomes idle
+	NextRelease = "release" // its seat is released
+	NextWait    = "wait"    // stays idle, waiting for the notice period or for activity
+	NextClear   = "clear"   // active again under the current policy; the idle flag is cleared
+)
+
+var (
+	ErrInvalidDays   = errors.New("inactivity days cannot be negative")
+	ErrInvalidAction = errors.New("inactivity action must be flag or release")
+)
+
+// Policy is the inactivity policy of a product or license. A license policy
+// overrides its product's: nil days inherit the product's and 0 disables the
+// policy for the license; an empty action inherits the product's.
+type Policy struct {
+	Days   *int   `db:"inactivity_days" json:"days"`     // nil = no policy (product) or inherited (license)
+	Action string `db:"inactivity_action" json:"action"` // flag or release; "" = inherited (license)
+}
+
+// Validate checks the policy; required reports whether the action must be set
+// (products always have one)
+func (p Policy) Validate(required bool) error {
+	if p.Days != nil && *p.Days < 0 {
+		return ErrInvalidDays
+	}
+	switch p.Action {
+	case ActionFlag, ActionRelease:
+	case "":
+		if required {
+			return ErrInvalidAction
+		}
+	default:
+		return fmt.Errorf("%w (%q)", ErrInvalidAction, p.Action)
+	}
+	return nil
+}
+
+// String describes the policy for the log
+func (p Policy) String() string {
+	days, action := "inherited", p.Action
+	if p.Days != nil {
+		days = strconv.Itoa(*p.Days)
+	}
+	if action == "" {
+		action = "inherited"
+	}
+	return fmt.Sprintf("%s days, %s", days, action)
+}
+
+// Item is a registration the inactivity policy applies to, with what the next
+// run does to it
+type Item struct {
+	MachineID            int64  `db:"machine_id" json:"machineId"`
+	ProductID            int64  `db:"product_id" json:"productId"`
+	ProductName          string `db:"product_name" json:"productName"`
+	LicenseID            int64  `db:"license_id" json:"licenseId"`
+	LicenseKey           string `db:"license_key" json:"licenseKey"`
+	CustomerName         string `db:"customer_name" json:"customerName"`
+	MachineCode          string `db:"machine_code" json:"machineCode"`
+	UserName             string `db:"user_name" json:"userName"`
+	LastRegistrationDate string `db:"last_registration_date" json:"lastRegistrationDate"`
+	LastCheckinDate      string `db:"last_checkin_date" json:"lastCheckinDate"` // "" = never checked in
+	IdleDate             string `db:"idle_date" json:"idleDate"`                // "" = not flagged yet
+	Days                 int    `db:"days" json:"days"`                         // effective policy; 0 = none
+	Action               string `db:"action" json:"action"`                     // effective policy
+	LastActivityDate     string `db:"-" json:"lastActivityDate"`
+	ReleaseDate          string `db:"-" json:"releaseDate,omitempty"` // earliest date the seat is released
+	Next                 string `db:"-" json:"next"`
+}
+
+// Report lists the registrations the next run changes or keeps flagged idle
+type Report struct {
+	Date       string `json:"date"`
+	Items      []Item `json:"items"`
+	Flag       int    `json:"flag"`
+	Release    int    `json:"release"`
+	Wait       int    `json:"wait"`
+	Clear      int    `json:"clear"`
+	NoticeDays int    `json:"noticeDays"`
+}
+
+// Result is the outcome of a run
+type Result struct {
+	Date     string `json:"date"`
+	Flagged  int    `json:"flagged"`
+	Released int    `json:"released"`
+	Cleared  int    `json:"cleared"`
+}
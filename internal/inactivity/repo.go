@@ -0,0 +1,114 @@
+package inactivity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	GetProductPolicy(ctx context.Context, productID int64) (*Policy, error)
+	SetProductPolicy(ctx context.Context, tx *sqlx.Tx, productID int64, p *Policy) error
+	GetLicensePolicy(ctx context.Context, licenseID int64) (*Policy, error)
+	SetLicensePolicy(ctx context.Context, tx *sqlx.Tx, licenseID int64, p *Policy) error
+	GetItems(ctx context.Context, productID int64) ([]Item, error)
+	Flag(ctx context.Context, tx *sqlx.Tx, it *Item, date string) (bool, error)
+	Release(ctx context.Context, tx *sqlx.Tx, it *Item, date string) (bool, error)
+	Clear(ctx context.Context, tx *sqlx.Tx, it *Item) error
+}
+
+type repo struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) Repository {
+	return &repo{db: db}
+}
+
+func (r *repo) GetProductPolicy(ctx context.Context, productID int64) (*Policy, error) {
+	var p Policy
+	err := r.db.GetContext(ctx, &p, getProductPolicySQL, productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("product not found (%d)", productID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get product inactivity policy: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *repo) SetProductPolicy(ctx context.Context, tx *sqlx.Tx, productID int64, p *Policy) error {
+	res, err := tx.ExecContext(ctx, setProductPolicySQL, p.Days, p.Action, productID)
+	if err != nil {
+		return fmt.Errorf("set product inactivity policy: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("product not found (%d)", productID)
+	}
+	return nil
+}
+
+func (r *repo) GetLicensePolicy(ctx context.Context, licenseID int64) (*Policy, error) {
+	var p Policy
+	err := r.db.GetContext(ctx, &p, getLicensePolicySQL, licenseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("license not found (%d)", licenseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get license inactivity policy: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *repo) SetLicensePolicy(ctx context.Context, tx *sqlx.Tx, licenseID int64, p *Policy) error {
+	res, err := tx.ExecContext(ctx, setLicensePolicySQL, p.Days, p.Action, licenseID)
+	if err != nil {
+		return fmt.Errorf("set license inactivity policy: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("license not found (%d)", licenseID)
+	}
+	return nil
+}
+
+func (r *repo) GetItems(ctx context.Context, productID int64) ([]Item, error) {
+	var out []Item
+	err := r.db.SelectContext(ctx, &out, getItemsSQL, productID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get inactive registrations: %w", err)
+	}
+	return out, nil
+}
+
+// Flag flags the registration idle, reporting false if it was active again
+// since it.LastActivityDate
+func (r *repo) Flag(ctx context.Context, tx *sqlx.Tx, it *Item, date string) (bool, error) {
+	res, err := tx.ExecContext(ctx, flagSQL, date, it.MachineID, it.ProductID, it.LastActivityDate)
+	if err != nil {
+		return false, fmt.Errorf("flag idle registration: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Release releases the registration's seat, reporting false if it was active
+// again since it.LastActivityDate
+func (r *repo) Release(ctx context.Context, tx *sqlx.Tx, it *Item, date string) (bool, error) {
+	res, err := tx.ExecContext(ctx, releaseSQL, date, it.MachineID, it.ProductID, it.LastActivityDate)
+	if err != nil {
+		return false, fmt.Errorf("release idle registration: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *repo) Clear(ctx context.Context, tx *sqlx.Tx, it *Item) error {
+	_, err := tx.ExecContext(ctx, clearSQL, it.MachineID, it.ProductID)
+	if err != nil {
+		return fmt.Errorf("clear idle registration: %w", err)
+	}
+	return nil
+}
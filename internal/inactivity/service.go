@@ -0,0 +1,201 @@
+package inactivity
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/logging"
+)
+
+// DefaultNoticeDays is how long a registration stays flagged idle before a
+// release policy frees its seat
+const DefaultNoticeDays = 7
+
+const dateFormat = "2006-01-02"
+
+type Service struct {
+	repo       Repository
+	db         *sqlx.DB
+	noticeDays int
+}
+
+func NewService(db *sqlx.DB) *Service {
+	return &Service{
+		db:         db,
+		repo:       New(db),
+		noticeDays: DefaultNoticeDays,
+	}
+}
+
+// SetNoticeDays sets how many days a registration stays flagged idle before a
+// release policy frees its seat
+func (s *Service) SetNoticeDays(days int) {
+	s.noticeDays = max(days, 0)
+}
+
+func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Service) GetProductPolicy(ctx context.Context, productID int64) (*Policy, error) {
+	return s.repo.GetProductPolicy(ctx, productID)
+}
+
+// SetProductPolicy sets the policy of the product's licenses; nil days
+// remove it
+func (s *Service) SetProductPolicy(ctx context.Context, productID int64, p *Policy) error {
+	if err := p.Validate(true); err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.repo.SetProductPolicy(ctx, tx, productID, p)
+	})
+}
+
+func (s *Service) GetLicensePolicy(ctx context.Context, licenseID int64) (*Policy, error) {
+	return s.repo.GetLicensePolicy(ctx, licenseID)
+}
+
+// SetLicensePolicy overrides the product policy for one license; nil days and
+// an empty action inherit the product's
+func (s *Service) SetLicensePolicy(ctx context.Context, licenseID int64, p *Policy) error {
+	if err := p.Validate(false); err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.repo.SetLicensePolicy(ctx, tx, licenseID, p)
+	})
+}
+
+// Preview returns what the next run does, without changing anything
+// (productID 0 = all products)
+func (s *Service) Preview(ctx context.Context, productID int64) (*Report, error) {
+	return s.report(ctx, productID, time.Now())
+}
+
+// Run flags the registrations idle under their policy, releases the seats of
+// those flagged for the notice period under a release policy, and clears the
+// flag of those active again. Registrations activated or checked in while the
+// run is under way are left as they are.
+func (s *Service) Run(ctx context.Context) (*Result, error) {
+	rep, err := s.report(ctx, 0, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Date: rep.Date}
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		for i := range rep.Items {
+			it := &rep.Items[i]
+			var err error
+			var done bool
+			switch it.Next {
+			case NextFlag:
+				if done, err = s.repo.Flag(ctx, tx, it, rep.Date); done {
+					res.Flagged++
+				}
+			case NextRelease:
+				if done, err = s.repo.Release(ctx, tx, it, rep.Date); done {
+					res.Released++
+				}
+			case NextClear:
+				err = s.repo.Clear(ctx, tx, it)
+				res.Cleared++
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Flagged > 0 || res.Released > 0 || res.Cleared > 0 {
+		logging.FromContext(ctx).Info("inactivity policy applied",
+			"flagged", res.Flagged,
+			"released", res.Released,
+			"cleared", res.Cleared,
+		)
+	}
+	return res, nil
+}
+
+// Schedule runs the policy every interval until ctx is done
+func (s *Service) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Run(ctx); err != nil {
+			logging.FromContext(ctx).Error("inactivity policy failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// report works out what a run on the given day does
+func (s *Service) report(ctx context.Context, productID int64, now time.Time) (*Report, error) {
+	items, err := s.repo.GetItems(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := now.Format(dateFormat)
+	rep := &Report{Date: today, NoticeDays: s.noticeDays, Items: []Item{}}
+	for _, it := range items {
+		it.LastActivityDate = max(it.LastRegistrationDate, it.LastCheckinDate)
+		idle := it.Days > 0 && it.LastActivityDate < now.AddDate(0, 0, -it.Days).Format(dateFormat)
+
+		switch {
+		case !idle && it.IdleDate != "":
+			it.Next = NextClear
+			rep.Clear++
+		case !idle:
+			continue
+		case it.IdleDate == "":
+			it.Next = NextFlag
+			if it.Action == ActionRelease {
+				it.ReleaseDate = now.AddDate(0, 0, s.noticeDays).Format(dateFormat)
+			}
+			rep.Flag++
+		case it.Action == ActionRelease:
+			it.ReleaseDate = s.releaseDate(it.IdleDate)
+			if it.ReleaseDate <= today {
+				it.Next = NextRelease
+				rep.Release++
+			} else {
+				it.Next = NextWait
+				rep.Wait++
+			}
+		default:
+			it.Next = NextWait
+			rep.Wait++
+		}
+		rep.Items = append(rep.Items, it)
+	}
+	return rep, nil
+}
+
+// releaseDate returns when a registration flagged idle on idleDate is released
+func (s *Service) releaseDate(idleDate string) string {
+	d, err := time.Parse(dateFormat, idleDate)
+	if err != nil {
+		return idleDate
+	}
+	return d.AddDate(0, 0, s.noticeDays).Format(dateFormat)
+}
@@ -0,0 +1,285 @@
+package inactivity_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/inactivity"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestInactivityService(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+	activationSvc := activation.NewService(db, "test-secret", custSvc, machineSvc, regSvc, licSvc, prodSvc,
+		feature.NewService(db), featurevalue.NewService(db), analytics.NewService(db))
+	licSvc.SetReissuer(activationSvc)
+	svc := inactivity.NewService(db)
+	svc.SetNoticeDays(2)
+
+	daysAgo := func(n int) string { return time.Now().AddDate(0, 0, -n).Format("2006-01-02") }
+	today := daysAgo(0)
+
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Idle Co"})
+	prod, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Idle App", ProductGUID: "GUID-IDLE", LatestVersion: "1.0.0"})
+	lic, err := licSvc.Create(ctx, &license.License{
+		CustomerID:          cust.CustomerID,
+		ProductID:           prod.ProductID,
+		LicenseKey:          "IDLE-KEY",
+		LicenseCount:        1,
+		StartDate:           today,
+		ExpirationDate:      time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+		MaintExpirationDate: time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	activate := func(machineCode string) error {
+		_, err := activationSvc.Activate(ctx, lic.LicenseID, &activation.Request{MachineCode: machineCode, UserName: "user"})
+		return err
+	}
+	stored := func(machineCode string) *registration.Registration {
+		t.Helper()
+		m, err := machineSvc.GetByCode(ctx, cust.CustomerID, machineCode)
+		if err != nil || m == nil {
+			t.Fatalf("get machine %s: %v", machineCode, err)
+		}
+		reg, err := regSvc.Get(ctx, m.MachineID, prod.ProductID)
+		if err != nil {
+			t.Fatalf("get registration: %v", err)
+		}
+		return reg
+	}
+	backdate := func(machineCode, column string, days int) {
+		t.Helper()
+		reg := stored(machineCode)
+		if _, err := db.Exec("UPDATE registration SET "+column+" = ? WHERE machine_id = ? AND product_id = ?", daysAgo(days), reg.MachineID, reg.ProductID); err != nil {
+			t.Fatalf("backdate %s: %v", column, err)
+		}
+	}
+	preview := func() *inactivity.Report {
+		t.Helper()
+		rep, err := svc.Preview(ctx, prod.ProductID)
+		if err != nil {
+			t.Fatalf("preview: %v", err)
+		}
+		return rep
+	}
+	run := func() *inactivity.Result {
+		t.Helper()
+		res, err := svc.Run(ctx)
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		return res
+	}
+	days := func(n int) *int { return &n }
+
+	if err := activate("MACHINE-A"); err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	backdate("MACHINE-A", "last_registration_date", 60)
+
+	t.Run("validation", func(t *testing.T) {
+		if err := svc.SetProductPolicy(ctx, prod.ProductID, &inactivity.Policy{Days: days(-1), Action: inactivity.ActionFlag}); !errors.Is(err, inactivity.ErrInvalidDays) {
+			t.Errorf("expected ErrInvalidDays, got %v", err)
+		}
+		if err := svc.SetProductPolicy(ctx, prod.ProductID, &inactivity.Policy{Days: days(30)}); !errors.Is(err, inactivity.ErrInvalidAction) {
+			t.Errorf("expected ErrInvalidAction for a product without an action, got %v", err)
+		}
+		if err := svc.SetLicensePolicy(ctx, lic.LicenseID, &inactivity.Policy{Action: "delete"}); !errors.Is(err, inactivity.ErrInvalidAction) {
+			t.Errorf("expected ErrInvalidAction, got %v", err)
+		}
+		if err := svc.SetProductPolicy(ctx, 9999, &inactivity.Policy{Action: inactivity.ActionFlag}); err == nil {
+			t.Error("expected an error for an unknown product")
+		}
+	})
+
+	t.Run("no policy", func(t *testing.T) {
+		if rep := preview(); len(rep.Items) != 0 {
+			t.Errorf("expected no registrations without a policy, got %+v", rep.Items)
+		}
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		if err := svc.SetProductPolicy(ctx, prod.ProductID, &inactivity.Policy{Days: days(30), Action: inactivity.ActionRelease}); err != nil {
+			t.Fatalf("set product policy: %v", err)
+		}
+		rep := preview()
+		if len(rep.Items) != 1 || rep.Flag != 1 {
+			t.Fatalf("expected one registration to flag, got %+v", rep)
+		}
+		it := rep.Items[0]
+		if it.Next != inactivity.NextFlag || it.LastActivityDate != daysAgo(60) || it.ReleaseDate != daysAgo(-2) {
+			t.Errorf("unexpected preview item %+v", it)
+		}
+		if stored("MACHINE-A").IdleDate != "" {
+			t.Error("expected the preview to change nothing")
+		}
+
+		if res := run(); res.Flagged != 1 || res.Released != 0 {
+			t.Errorf("expected one flagged registration, got %+v", res)
+		}
+		if stored("MACHINE-A").IdleDate != today {
+			t.Error("expected the registration to be flagged idle")
+		}
+		if rep := preview(); rep.Wait != 1 || rep.Items[0].ReleaseDate != daysAgo(-2) {
+			t.Errorf("expected the registration to wait for the notice period, got %+v", rep)
+		}
+		if err := activate("MACHINE-B"); err == nil {
+			t.Error("expected an idle registration to keep its seat")
+		}
+	})
+
+	t.Run("license override", func(t *testing.T) {
+		if err := svc.SetLicensePolicy(ctx, lic.LicenseID, &inactivity.Policy{Days: days(0)}); err != nil {
+			t.Fatalf("set license policy: %v", err)
+		}
+		if rep := preview(); rep.Clear != 1 {
+			t.Errorf("expected a disabled license policy to clear the flag, got %+v", rep)
+		}
+		if err := svc.SetLicensePolicy(ctx, lic.LicenseID, &inactivity.Policy{}); err != nil {
+			t.Fatalf("reset license policy: %v", err)
+		}
+		p, err := svc.GetLicensePolicy(ctx, lic.LicenseID)
+		if err != nil || p.Days != nil || p.Action != "" {
+			t.Errorf("expected the license to inherit the product policy, got %+v (%v)", p, err)
+		}
+	})
+
+	t.Run("release", func(t *testing.T) {
+		backdate("MACHINE-A", "idle_date", 2)
+		if rep := preview(); rep.Release != 1 {
+			t.Fatalf("expected the registration to be released, got %+v", rep)
+		}
+		if res := run(); res.Released != 1 {
+			t.Errorf("expected one released registration, got %+v", res)
+		}
+		reg := stored("MACHINE-A")
+		if !reg.IsReleased() || reg.ExpirationDate >= today {
+			t.Errorf("expected the registration to be released and expired, got %+v", reg)
+		}
+		if _, err := activationSvc.Refresh(ctx, lic.LicenseID, "MACHINE-A"); !errors.Is(err, activation.ErrNotRegistered) {
+			t.Errorf("expected a released registration not to refresh, got %v", err)
+		}
+		if rep := preview(); len(rep.Items) != 0 {
+			t.Errorf("expected released registrations to leave the report, got %+v", rep.Items)
+		}
+
+		// Re-issuing does not bring a released seat back
+		cur, _ := licSvc.Get(ctx, lic.LicenseID)
+		cur.MaxProductVersion = "2.0.0"
+		if err := licSvc.Update(ctx, cur); err != nil {
+			t.Fatalf("update license: %v", err)
+		}
+		if !stored("MACHINE-A").IsReleased() || stored("MACHINE-A").ExpirationDate >= today {
+			t.Error("expected the registration to stay released")
+		}
+
+		if err := activate("MACHINE-B"); err != nil {
+			t.Errorf("expected the released seat to be free: %v", err)
+		}
+	})
+
+	t.Run("check-in", func(t *testing.T) {
+		backdate("MACHINE-B", "last_registration_date", 45)
+		if res := run(); res.Flagged != 1 {
+			t.Fatalf("expected one flagged registration, got %+v", res)
+		}
+
+		reg := stored("MACHINE-B")
+		if err := regSvc.CheckIn(ctx, reg.MachineID, reg.ProductID, registration.ClientInfo{}); err != nil {
+			t.Fatalf("check in: %v", err)
+		}
+		reg = stored("MACHINE-B")
+		if reg.IdleDate != "" || reg.LastCheckinDate != today {
+			t.Errorf("expected the check-in to clear the idle flag, got %+v", reg)
+		}
+		if rep := preview(); len(rep.Items) != 0 {
+			t.Errorf("expected a recent check-in to keep the registration active, got %+v", rep.Items)
+		}
+	})
+
+	t.Run("reactivation", func(t *testing.T) {
+		cur, _ := licSvc.Get(ctx, lic.LicenseID)
+		cur.LicenseCount = 2
+		if err := licSvc.Update(ctx, cur); err != nil {
+			t.Fatalf("update license: %v", err)
+		}
+		if err := activate("MACHINE-A"); err != nil {
+			t.Fatalf("reactivate: %v", err)
+		}
+		if reg := stored("MACHINE-A"); reg.IsReleased() || reg.ExpirationDate < today {
+			t.Errorf("expected a reactivation to restore the registration, got %+v", reg)
+		}
+	})
+
+	t.Run("check-in during a run", func(t *testing.T) {
+		backdate("MACHINE-A", "last_registration_date", 60)
+		backdate("MACHINE-A", "idle_date", 2)
+		backdate("MACHINE-B", "last_registration_date", 60)
+		backdate("MACHINE-B", "last_checkin_date", 60)
+		rep := preview()
+		if rep.Release != 1 || rep.Flag != 1 {
+			t.Fatalf("expected one release and one flag, got %+v", rep)
+		}
+
+		// Both machines call in after the report is built
+		for _, code := range []string{"MACHINE-A", "MACHINE-B"} {
+			reg := stored(code)
+			if err := regSvc.CheckIn(ctx, reg.MachineID, reg.ProductID, registration.ClientInfo{}); err != nil {
+				t.Fatalf("check in: %v", err)
+			}
+		}
+
+		repo := inactivity.New(db)
+		tx, err := db.Beginx()
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		defer tx.Rollback()
+		for i := range rep.Items {
+			it := &rep.Items[i]
+			var done bool
+			switch it.Next {
+			case inactivity.NextFlag:
+				done, err = repo.Flag(ctx, tx, it, rep.Date)
+			case inactivity.NextRelease:
+				done, err = repo.Release(ctx, tx, it, rep.Date)
+			}
+			if err != nil || done {
+				t.Errorf("expected %s of %s to be skipped, got %v (%v)", it.Next, it.MachineCode, done, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+
+		if reg := stored("MACHINE-A"); reg.IsReleased() || reg.ExpirationDate < today {
+			t.Errorf("expected the checked-in registration to keep its seat, got %+v", reg)
+		}
+		if reg := stored("MACHINE-B"); reg.IdleDate != "" {
+			t.Errorf("expected the checked-in registration not to be flagged, got %+v", reg)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package inactivity
+
+const getProductPolicySQL = `
+SELECT
+    inactivity_days,
+    inactivity_action
+FROM product
+WHERE product_id = ?
+`
+
+const setProductPolicySQL = `
+UPDATE product
+SET
+    inactivity_days = ?,
+    inactivity_action = ?
+WHERE product_id = ?
+`
+
+const getLicensePolicySQL = `
+SELECT
+    inactivity_days,
+    COALESCE(inactivity_action, '') AS inactivity_action
+FROM license
+WHERE license_id = ?
+`
+
+const setLicensePolicySQL = `
+UPDATE license
+SET
+    inactivity_days = ?,
+    inactivity_action = NULLIF(?, '')
+WHERE license_id = ?
+`
+
+// getItemsSQL returns the seat-holding registrations under an inactivity
+// policy, and those still flagged idle whose policy was since removed
+const getItemsSQL = `
+SELECT
+    r.machine_id,
+    r.product_id,
+    p.product_name,
+    r.license_id,
+    l.license_key,
+    c.customer_name,
+    m.machine_code,
+    COALESCE(m.user_name, '') AS user_name,
+    COALESCE(r.last_registration_date, '') AS last_registration_date,
+    COALESCE(r.last_checkin_date, '') AS last_checkin_date,
+    COALESCE(r.idle_date, '') AS idle_date,
+    COALESCE(l.inactivity_days, p.inactivity_days, 0) AS days,
+    COALESCE(l.inactivity_action, p.inactivity_action) AS action
+FROM registration r
+JOIN license l ON l.license_id = r.license_id
+JOIN product p ON p.product_id = r.product_id
+JOIN customer c ON c.customer_id = l.customer_id
+JOIN machine m ON m.machine_id = r.machine_id
+WHERE r.released_date IS NULL
+  AND r.expiration_date >= DATE('now')
+  AND (? = 0 OR r.product_id = ?)
+  AND (r.idle_date IS NOT NULL OR COALESCE(l.inactivity_days, p.inactivity_days, 0) > 0)
+ORDER BY p.product_name, c.customer_name, m.machine_code
+`
+
+// flagSQL and releaseSQL skip a registration that was activated or checked
+// in after the report was built (its last activity is later than the
+// report's), so a run never flags or releases a machine that just called in
+const flagSQL = `
+UPDATE registration
+SET idle_date = ?
+WHERE machine_id = ? AND product_id = ? AND idle_date IS NULL
+  AND MAX(COALESCE(last_registration_date, ''), COALESCE(last_checkin_date, '')) <= ?
+`
+
+// releaseSQL expires the registration so its seat no longer counts
+const releaseSQL = `
+UPDATE registration
+SET
+    expiration_date = DATE('now', '-1 day'),
+    released_date = ?
+WHERE machine_id = ? AND product_id = ? AND released_date IS NULL AND idle_date IS NOT NULL
+  AND MAX(COALESCE(last_registration_date, ''), COALESCE(last_checkin_date, '')) <= ?
+`
+
+const clearSQL = `
+UPDATE registration
+SET idle_date = NULL
+WHERE machine_id = ? AND product_id = ?
+`
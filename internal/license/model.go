@@ -2,6 +2,7 @@ package license
 
 import (
 	"errors"
+	"time"
 
 	"winsbygroup.com/regserver/internal/product"
 )
@@ -14,9 +15,98 @@ var (
 	ErrExpirationDateRequired   = errors.New("expiration date is required")
 	ErrMaintExpirationRequired  = errors.New("maintenance expiration date is required")
 	ErrLicenseCountRequired     = errors.New("license count must be greater than 0")
+	ErrInvalidKeyStatus         = errors.New("key status must be active, suspended or revoked")
+	ErrNegativeGracePeriod      = errors.New("grace period cannot be negative")
+	ErrInvalidStatus            = errors.New("license status must be active, suspended or cancelled")
 )
 
+// ErrNotFound is returned when a license does not exist
+var ErrNotFound = errors.New("license not found")
+
+// Key status errors returned when authenticating with a license key
+var (
+	ErrKeySuspended = errors.New("license key suspended")
+	ErrKeyRevoked   = errors.New("license key revoked")
+	ErrKeyReplaced  = errors.New("license key has been replaced")
+)
+
+// License status errors returned when a license is not active
+var (
+	ErrLicenseSuspended = errors.New("license suspended")
+	ErrLicenseCancelled = errors.New("license cancelled")
+)
+
+// Client error codes for license and key status errors
+const (
+	CodeLicenseSuspended = "license_suspended"
+	CodeLicenseCancelled = "license_cancelled"
+	CodeKeySuspended     = "key_suspended"
+	CodeKeyRevoked       = "key_revoked"
+	CodeKeyReplaced      = "key_replaced"
+)
+
+// ErrorCode returns the client error code for a license or key status error,
+// or "" for any other error
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrLicenseSuspended):
+		return CodeLicenseSuspended
+	case errors.Is(err, ErrLicenseCancelled):
+		return CodeLicenseCancelled
+	case errors.Is(err, ErrKeySuspended):
+		return CodeKeySuspended
+	case errors.Is(err, ErrKeyRevoked):
+		return CodeKeyRevoked
+	case errors.Is(err, ErrKeyReplaced):
+		return CodeKeyReplaced
+	}
+	return ""
+}
+
+// License statuses
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusCancelled = "cancelled"
+)
+
+// IsValidStatus reports whether s is a known license status
+func IsValidStatus(s string) bool {
+	return s == StatusActive || s == StatusSuspended || s == StatusCancelled
+}
+
+// statusError returns the error for a license that is not active (nil if active)
+func statusError(status string) error {
+	switch status {
+	case StatusSuspended:
+		return ErrLicenseSuspended
+	case StatusCancelled:
+		return ErrLicenseCancelled
+	}
+	return nil
+}
+
+// License key statuses
+const (
+	KeyActive    = "active"
+	KeySuspended = "suspended"
+	KeyRevoked   = "revoked"
+)
+
+// KeyEventReplaced is the key history event of a rotation. Status changes are
+// recorded with the new key status as their event.
+const KeyEventReplaced = "replaced"
+
+// IsValidKeyStatus reports whether s is a known key status
+func IsValidKeyStatus(s string) bool {
+	return s == KeyActive || s == KeySuspended || s == KeyRevoked
+}
+
+// timeFormat is the format of key history timestamps (UTC)
+const timeFormat = "2006-01-02 15:04:05"
+
 type License struct {
+	LicenseID           int64  `db:"license_id"`
 	CustomerID          int64  `db:"customer_id"`
 	ProductID           int64  `db:"product_id"`
 	LicenseKey          string `db:"license_key"`
@@ -27,6 +117,18 @@ type License struct {
 	ExpirationDate      string `db:"expiration_date"`
 	MaintExpirationDate string `db:"maint_expiration_date"`
 	MaxProductVersion   string `db:"max_product_version"`
+	KeyStatus           string `db:"key_status"`
+	Status              string `db:"status"`
+	StatusReason        string `db:"status_reason"`
+	StatusChangedAt     string `db:"status_changed_at"`
+	BundleID            *int64 `db:"bundle_id"` // set for licenses issued as part of a customer bundle
+	PlanID              *int64 `db:"plan_id"`   // plan whose feature values apply before the license's own overrides
+}
+
+// CheckStatus returns ErrLicenseSuspended or ErrLicenseCancelled for a license
+// that is not active
+func (l *License) CheckStatus() error {
+	return statusError(l.Status)
 }
 
 // Validate checks business rules for a license
@@ -54,6 +156,8 @@ func (l *License) Validate() error {
 
 // ExpiredLicense represents an expired license with customer/product details
 type ExpiredLicense struct {
+	LicenseID           int64  `db:"license_id" json:"licenseId"`
+	CustomerID          int64  `db:"customer_id" json:"customerId"`
 	CustomerName        string `db:"customer_name" json:"customerName"`
 	ContactName         string `db:"contact_name" json:"contactName"`
 	Email               string `db:"email" json:"email"`
@@ -61,3 +165,32 @@ type ExpiredLicense struct {
 	ExpirationDate      string `db:"expiration_date" json:"expirationDate"`
 	MaintExpirationDate string `db:"maint_expiration_date" json:"maintExpirationDate"`
 }
+
+// KeyRef identifies the license a key belongs to, with the key and license status
+type KeyRef struct {
+	LicenseID  int64  `db:"license_id"`
+	CustomerID int64  `db:"customer_id"`
+	ProductID  int64  `db:"product_id"`
+	KeyStatus  string `db:"key_status"`
+	Status     string `db:"status"`
+}
+
+// KeyHistory is a change to a license key made at ReplacedAt: a rotation that
+// replaced the key (Event "replaced"), or a status change (Event is the new
+// key status). A replaced key is still accepted until GraceUntil, unless the
+// grace period was ended early (RevokedAt).
+type KeyHistory struct {
+	HistoryID  int64  `db:"history_id" json:"historyId"`
+	LicenseID  int64  `db:"license_id" json:"licenseId"`
+	LicenseKey string `db:"license_key" json:"licenseKey"`
+	Event      string `db:"event" json:"event"`
+	Reason     string `db:"reason" json:"reason"`
+	ReplacedAt string `db:"replaced_at" json:"replacedAt"`
+	GraceUntil string `db:"grace_until" json:"graceUntil"`
+	RevokedAt  string `db:"revoked_at" json:"revokedAt"`
+}
+
+// InGrace reports whether the replaced key is still accepted at the given time
+func (h KeyHistory) InGrace(now time.Time) bool {
+	return h.Event == KeyEventReplaced && h.RevokedAt == "" && h.GraceUntil > now.UTC().Format(timeFormat)
+}
@@ -8,19 +8,33 @@ import (
 	"strings"
 
 	"github.com/jmoiron/sqlx"
-	"winsbygroup.com/regserver/internal/product"
 )
 
 type Repository interface {
-	Get(ctx context.Context, customerID, productID int64) (*License, error)
+	Get(ctx context.Context, licenseID int64) (*License, error)
+	GetTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) (*License, error)
 	GetByLicenseKey(ctx context.Context, licenseKey string) (*License, error)
 	GetForCustomer(ctx context.Context, customerID int64) ([]License, error)
-	GetUnlicensed(ctx context.Context, customerID int64) ([]product.Product, error)
+	GetForProduct(ctx context.Context, customerID, productID int64) ([]License, error)
+	GetForBundle(ctx context.Context, customerID, bundleID int64) ([]License, error)
 	GetExpiredLicenses(ctx context.Context, before string) ([]ExpiredLicense, error)
 
 	Create(ctx context.Context, tx *sqlx.Tx, lic *License) error
 	Update(ctx context.Context, tx *sqlx.Tx, lic *License) error
-	Delete(ctx context.Context, tx *sqlx.Tx, customerID, productID int64) error
+	UpdateBundleTerms(ctx context.Context, tx *sqlx.Tx, bundleID int64, lic *License) error
+	Delete(ctx context.Context, tx *sqlx.Tx, licenseID int64) error
+	DeleteForBundle(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64) error
+	UpdatePlan(ctx context.Context, tx *sqlx.Tx, licenseID int64, planID *int64) error
+
+	GetKeyRef(ctx context.Context, licenseID int64) (*KeyRef, error)
+	GetKeyRefByKey(ctx context.Context, licenseKey string) (*KeyRef, error)
+	GetKeyHistory(ctx context.Context, licenseID int64) ([]KeyHistory, error)
+	GetKeyHistoryByKey(ctx context.Context, licenseKey string) (*KeyHistory, error)
+	UpdateKey(ctx context.Context, tx *sqlx.Tx, licenseID int64, licenseKey string) error
+	UpdateKeyStatus(ctx context.Context, tx *sqlx.Tx, licenseID int64, status string) error
+	UpdateStatus(ctx context.Context, tx *sqlx.Tx, licenseID int64, status, reason, changedAt string) error
+	CreateKeyHistory(ctx context.Context, tx *sqlx.Tx, h *KeyHistory) error
+	RevokeKeyHistory(ctx context.Context, tx *sqlx.Tx, licenseID int64, revokedAt string) error
 }
 
 type repo struct {
@@ -31,11 +45,23 @@ func New(db *sqlx.DB) Repository {
 	return &repo{db: db}
 }
 
-func (r *repo) Get(ctx context.Context, customerID, productID int64) (*License, error) {
+func (r *repo) Get(ctx context.Context, licenseID int64) (*License, error) {
+	var lic License
+	err := r.db.GetContext(ctx, &lic, getLicenseSQL, licenseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w (%d)", ErrNotFound, licenseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get license: %w", err)
+	}
+	return &lic, nil
+}
+
+func (r *repo) GetTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) (*License, error) {
 	var lic License
-	err := r.db.GetContext(ctx, &lic, getLicenseSQL, customerID, productID)
+	err := tx.GetContext(ctx, &lic, getLicenseSQL, licenseID)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("license not found (%d/%d)", customerID, productID)
+		return nil, fmt.Errorf("%w (%d)", ErrNotFound, licenseID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get license: %w", err)
@@ -47,7 +73,7 @@ func (r *repo) GetByLicenseKey(ctx context.Context, licenseKey string) (*License
 	var lic License
 	err := r.db.GetContext(ctx, &lic, getLicenseByKeySQL, strings.ToLower(licenseKey))
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("license not found: %s", licenseKey)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, licenseKey)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get license by key: %w", err)
@@ -64,17 +90,29 @@ func (r *repo) GetForCustomer(ctx context.Context, customerID int64) ([]License,
 	return out, nil
 }
 
-func (r *repo) GetUnlicensed(ctx context.Context, customerID int64) ([]product.Product, error) {
-	var out []product.Product
-	err := r.db.SelectContext(ctx, &out, getUnlicensedProductsSQL, customerID)
+// GetForProduct returns a customer's licenses of one product, oldest first
+func (r *repo) GetForProduct(ctx context.Context, customerID, productID int64) ([]License, error) {
+	out := []License{}
+	err := r.db.SelectContext(ctx, &out, getProductLicensesSQL, customerID, productID)
 	if err != nil {
-		return nil, fmt.Errorf("get unlicensed products: %w", err)
+		return nil, fmt.Errorf("get product licenses: %w", err)
 	}
 	return out, nil
 }
 
+// GetForBundle returns the licenses issued for a customer's bundle
+func (r *repo) GetForBundle(ctx context.Context, customerID, bundleID int64) ([]License, error) {
+	out := []License{}
+	err := r.db.SelectContext(ctx, &out, getBundleLicensesSQL, customerID, bundleID)
+	if err != nil {
+		return nil, fmt.Errorf("get bundle licenses: %w", err)
+	}
+	return out, nil
+}
+
+// Create inserts a license and sets its LicenseID
 func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, lic *License) error {
-	_, err := tx.ExecContext(ctx, createLicenseSQL,
+	res, err := tx.ExecContext(ctx, createLicenseSQL,
 		lic.CustomerID,
 		lic.ProductID,
 		strings.ToLower(lic.LicenseKey),
@@ -85,10 +123,17 @@ func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, lic *License) error {
 		lic.ExpirationDate,
 		lic.MaintExpirationDate,
 		lic.MaxProductVersion,
+		lic.BundleID,
+		lic.PlanID,
 	)
 	if err != nil {
 		return fmt.Errorf("create license: %w", err)
 	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("create license: %w", err)
+	}
+	lic.LicenseID = id
 	return nil
 }
 
@@ -101,8 +146,7 @@ func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, lic *License) error {
 		lic.ExpirationDate,
 		lic.MaintExpirationDate,
 		lic.MaxProductVersion,
-		lic.CustomerID,
-		lic.ProductID,
+		lic.LicenseID,
 	)
 	if err != nil {
 		return fmt.Errorf("update license: %w", err)
@@ -110,14 +154,48 @@ func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, lic *License) error {
 	return nil
 }
 
-func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, customerID, productID int64) error {
-	_, err := tx.ExecContext(ctx, deleteLicenseSQL, customerID, productID)
+// UpdateBundleTerms sets the seats and dates of every license of a customer bundle from lic
+func (r *repo) UpdateBundleTerms(ctx context.Context, tx *sqlx.Tx, bundleID int64, lic *License) error {
+	_, err := tx.ExecContext(ctx, updateBundleTermsSQL,
+		lic.LicenseCount,
+		lic.IsSubscription,
+		lic.LicenseTerm,
+		lic.StartDate,
+		lic.ExpirationDate,
+		lic.MaintExpirationDate,
+		lic.CustomerID,
+		bundleID,
+	)
+	if err != nil {
+		return fmt.Errorf("update bundle terms: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, licenseID int64) error {
+	_, err := tx.ExecContext(ctx, deleteLicenseSQL, licenseID)
 	if err != nil {
 		return fmt.Errorf("delete license: %w", err)
 	}
 	return nil
 }
 
+func (r *repo) UpdatePlan(ctx context.Context, tx *sqlx.Tx, licenseID int64, planID *int64) error {
+	_, err := tx.ExecContext(ctx, updatePlanSQL, planID, licenseID)
+	if err != nil {
+		return fmt.Errorf("update license plan: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) DeleteForBundle(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64) error {
+	_, err := tx.ExecContext(ctx, deleteBundleLicensesSQL, customerID, bundleID)
+	if err != nil {
+		return fmt.Errorf("delete bundle licenses: %w", err)
+	}
+	return nil
+}
+
 func (r *repo) GetExpiredLicenses(ctx context.Context, before string) ([]ExpiredLicense, error) {
 	var out []ExpiredLicense
 	err := r.db.SelectContext(ctx, &out, getExpiredLicensesSQL, before, before)
@@ -126,3 +204,97 @@ func (r *repo) GetExpiredLicenses(ctx context.Context, before string) ([]Expired
 	}
 	return out, nil
 }
+
+func (r *repo) GetKeyRef(ctx context.Context, licenseID int64) (*KeyRef, error) {
+	var ref KeyRef
+	err := r.db.GetContext(ctx, &ref, getKeyRefSQL, licenseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w (%d)", ErrNotFound, licenseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get key ref: %w", err)
+	}
+	return &ref, nil
+}
+
+func (r *repo) GetKeyRefByKey(ctx context.Context, licenseKey string) (*KeyRef, error) {
+	var ref KeyRef
+	err := r.db.GetContext(ctx, &ref, getKeyRefByKeySQL, strings.ToLower(licenseKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, licenseKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get key ref by key: %w", err)
+	}
+	return &ref, nil
+}
+
+func (r *repo) GetKeyHistory(ctx context.Context, licenseID int64) ([]KeyHistory, error) {
+	var out []KeyHistory
+	err := r.db.SelectContext(ctx, &out, getKeyHistorySQL, licenseID)
+	if err != nil {
+		return nil, fmt.Errorf("get key history: %w", err)
+	}
+	return out, nil
+}
+
+// GetKeyHistoryByKey returns the history entry for a replaced key (nil if the key was never replaced)
+func (r *repo) GetKeyHistoryByKey(ctx context.Context, licenseKey string) (*KeyHistory, error) {
+	var h KeyHistory
+	err := r.db.GetContext(ctx, &h, getKeyHistoryByKeySQL, strings.ToLower(licenseKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get key history by key: %w", err)
+	}
+	return &h, nil
+}
+
+func (r *repo) UpdateKey(ctx context.Context, tx *sqlx.Tx, licenseID int64, licenseKey string) error {
+	_, err := tx.ExecContext(ctx, updateLicenseKeySQL, strings.ToLower(licenseKey), licenseID)
+	if err != nil {
+		return fmt.Errorf("update license key: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) UpdateKeyStatus(ctx context.Context, tx *sqlx.Tx, licenseID int64, status string) error {
+	_, err := tx.ExecContext(ctx, updateKeyStatusSQL, status, licenseID)
+	if err != nil {
+		return fmt.Errorf("update key status: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) UpdateStatus(ctx context.Context, tx *sqlx.Tx, licenseID int64, status, reason, changedAt string) error {
+	_, err := tx.ExecContext(ctx, updateStatusSQL, status, changedAt, status, reason, licenseID)
+	if err != nil {
+		return fmt.Errorf("update license status: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) CreateKeyHistory(ctx context.Context, tx *sqlx.Tx, h *KeyHistory) error {
+	_, err := tx.ExecContext(ctx, createKeyHistorySQL,
+		h.LicenseID,
+		strings.ToLower(h.LicenseKey),
+		h.Event,
+		h.Reason,
+		h.ReplacedAt,
+		h.GraceUntil,
+	)
+	if err != nil {
+		return fmt.Errorf("create key history: %w", err)
+	}
+	return nil
+}
+
+// RevokeKeyHistory ends the grace period of every replaced key still in grace
+func (r *repo) RevokeKeyHistory(ctx context.Context, tx *sqlx.Tx, licenseID int64, revokedAt string) error {
+	_, err := tx.ExecContext(ctx, revokeKeyHistorySQL, revokedAt, licenseID, revokedAt)
+	if err != nil {
+		return fmt.Errorf("revoke key history: %w", err)
+	}
+	return nil
+}
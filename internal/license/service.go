@@ -2,15 +2,19 @@ package license
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
-	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
 )
 
 type Service struct {
-	repo Repository
-	db   *sqlx.DB
+	repo     Repository
+	db       *sqlx.DB
+	reissuer registration.Reissuer
 }
 
 func NewService(db *sqlx.DB) *Service {
@@ -20,6 +24,12 @@ func NewService(db *sqlx.DB) *Service {
 	}
 }
 
+// SetReissuer sets what recomputes the registrations of a license when its
+// terms or plan change (nil, the default, leaves registrations as they are)
+func (s *Service) SetReissuer(r registration.Reissuer) {
+	s.reissuer = r
+}
+
 func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -32,8 +42,13 @@ func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
 	return tx.Commit()
 }
 
-func (s *Service) Get(ctx context.Context, customerID, productID int64) (*License, error) {
-	return s.repo.Get(ctx, customerID, productID)
+func (s *Service) Get(ctx context.Context, licenseID int64) (*License, error) {
+	return s.repo.Get(ctx, licenseID)
+}
+
+// GetTx returns a license as changed so far in the caller's transaction
+func (s *Service) GetTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) (*License, error) {
+	return s.repo.GetTx(ctx, tx, licenseID)
 }
 
 func (s *Service) GetByLicenseKey(ctx context.Context, licenseKey string) (*License, error) {
@@ -44,8 +59,14 @@ func (s *Service) GetForCustomer(ctx context.Context, customerID int64) ([]Licen
 	return s.repo.GetForCustomer(ctx, customerID)
 }
 
-func (s *Service) GetUnlicensed(ctx context.Context, customerID int64) ([]product.Product, error) {
-	return s.repo.GetUnlicensed(ctx, customerID)
+// GetForProduct returns a customer's licenses of one product, oldest first
+func (s *Service) GetForProduct(ctx context.Context, customerID, productID int64) ([]License, error) {
+	return s.repo.GetForProduct(ctx, customerID, productID)
+}
+
+// GetForBundle returns the licenses issued for a customer's bundle
+func (s *Service) GetForBundle(ctx context.Context, customerID, bundleID int64) ([]License, error) {
+	return s.repo.GetForBundle(ctx, customerID, bundleID)
 }
 
 func (s *Service) Create(ctx context.Context, lic *License) (*License, error) {
@@ -60,7 +81,7 @@ func (s *Service) Create(ctx context.Context, lic *License) (*License, error) {
 		return nil, err
 	}
 
-	created, err := s.repo.Get(ctx, lic.CustomerID, lic.ProductID)
+	created, err := s.repo.Get(ctx, lic.LicenseID)
 	if err != nil {
 		return nil, err
 	}
@@ -68,22 +89,241 @@ func (s *Service) Create(ctx context.Context, lic *License) (*License, error) {
 	return created, nil
 }
 
+// Update changes a license. For a license issued as part of a customer bundle,
+// the seats and dates (a renewal) apply to every license of the bundle.
 func (s *Service) Update(ctx context.Context, lic *License) error {
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.UpdateTerms(ctx, tx, lic)
+	})
+}
+
+// UpdateTerms changes a license like Update, in the caller's transaction
+func (s *Service) UpdateTerms(ctx context.Context, tx *sqlx.Tx, lic *License) error {
 	if err := lic.Validate(); err != nil {
 		return err
 	}
 
-	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
-		return s.repo.Update(ctx, tx, lic)
-	})
+	cur, err := s.repo.Get(ctx, lic.LicenseID)
+	if err != nil {
+		return err
+	}
+	lic.CustomerID = cur.CustomerID
+	lic.ProductID = cur.ProductID
+
+	if err := s.repo.Update(ctx, tx, lic); err != nil {
+		return err
+	}
+	if cur.BundleID != nil {
+		if err := s.repo.UpdateBundleTerms(ctx, tx, *cur.BundleID, lic); err != nil {
+			return err
+		}
+		return s.reissueBundle(ctx, tx, cur.CustomerID, *cur.BundleID)
+	}
+	return s.reissue(ctx, tx, lic.LicenseID)
 }
 
-func (s *Service) Delete(ctx context.Context, customerID, productID int64) error {
+// Delete removes a license along with its feature values and registrations
+func (s *Service) Delete(ctx context.Context, licenseID int64) error {
 	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
-		return s.repo.Delete(ctx, tx, customerID, productID)
+		return s.repo.Delete(ctx, tx, licenseID)
 	})
 }
 
+// CreateForBundle creates one license of a customer bundle in the caller's transaction
+func (s *Service) CreateForBundle(ctx context.Context, tx *sqlx.Tx, lic *License) error {
+	if lic.BundleID == nil {
+		return fmt.Errorf("create bundle license: no bundle for product %d", lic.ProductID)
+	}
+	if err := lic.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, tx, lic)
+}
+
+// UpdateBundleTerms sets the seats and dates of every license of a customer
+// bundle in the caller's transaction
+func (s *Service) UpdateBundleTerms(ctx context.Context, tx *sqlx.Tx, bundleID int64, lic *License) error {
+	if err := lic.Validate(); err != nil {
+		return err
+	}
+	if err := s.repo.UpdateBundleTerms(ctx, tx, bundleID, lic); err != nil {
+		return err
+	}
+	return s.reissueBundle(ctx, tx, lic.CustomerID, bundleID)
+}
+
+// DeleteForBundle deletes the licenses of a customer bundle in the caller's transaction
+func (s *Service) DeleteForBundle(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64) error {
+	return s.repo.DeleteForBundle(ctx, tx, customerID, bundleID)
+}
+
+// SetPlan assigns a plan to a license (nil removes it) in the caller's transaction.
+// The caller checks that the plan belongs to the license's product.
+func (s *Service) SetPlan(ctx context.Context, tx *sqlx.Tx, licenseID int64, planID *int64) error {
+	if err := s.repo.UpdatePlan(ctx, tx, licenseID, planID); err != nil {
+		return err
+	}
+	return s.reissue(ctx, tx, licenseID)
+}
+
+// reissue recomputes the registrations of a changed license in tx
+func (s *Service) reissue(ctx context.Context, tx *sqlx.Tx, licenseID int64) error {
+	if s.reissuer == nil {
+		return nil
+	}
+	return s.reissuer.ReissueLicense(ctx, tx, licenseID)
+}
+
+// reissueBundle recomputes the registrations of every license of a customer bundle in tx
+func (s *Service) reissueBundle(ctx context.Context, tx *sqlx.Tx, customerID, bundleID int64) error {
+	if s.reissuer == nil {
+		return nil
+	}
+	lics, err := s.repo.GetForBundle(ctx, customerID, bundleID)
+	if err != nil {
+		return err
+	}
+	for _, lic := range lics {
+		if err := s.reissuer.ReissueLicense(ctx, tx, lic.LicenseID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Service) GetExpiredLicenses(ctx context.Context, before string) ([]ExpiredLicense, error) {
 	return s.repo.GetExpiredLicenses(ctx, before)
 }
+
+// GetByActiveKey returns the license for a key a client is allowed to use: the
+// license's current key, or a replaced key still within its grace period.
+// Returns ErrKeySuspended or ErrKeyRevoked for licenses whose key is not active,
+// ErrKeyReplaced for replaced keys past their grace period, and
+// ErrLicenseSuspended or ErrLicenseCancelled for licenses that are not active.
+func (s *Service) GetByActiveKey(ctx context.Context, licenseKey string) (*License, error) {
+	ref, err := s.ResolveKey(ctx, licenseKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, ref.LicenseID)
+}
+
+// ResolveKey is GetByActiveKey without loading the full license record
+func (s *Service) ResolveKey(ctx context.Context, licenseKey string) (*KeyRef, error) {
+	var ref *KeyRef
+
+	hist, err := s.repo.GetKeyHistoryByKey(ctx, licenseKey)
+	if err != nil {
+		return nil, err
+	}
+	if hist != nil {
+		if !hist.InGrace(time.Now()) {
+			return nil, fmt.Errorf("%w (%s)", ErrKeyReplaced, hist.ReplacedAt)
+		}
+		ref, err = s.repo.GetKeyRef(ctx, hist.LicenseID)
+	} else {
+		ref, err = s.repo.GetKeyRefByKey(ctx, licenseKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch ref.KeyStatus {
+	case KeySuspended:
+		return nil, ErrKeySuspended
+	case KeyRevoked:
+		return nil, ErrKeyRevoked
+	}
+	if err := statusError(ref.Status); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// RotateKey issues a new license key. The old key keeps working for the grace
+// period (0 ends it immediately). Registrations and feature values are unchanged.
+func (s *Service) RotateKey(ctx context.Context, licenseID int64, grace time.Duration, reason string) (*License, error) {
+	if grace < 0 {
+		return nil, ErrNegativeGracePeriod
+	}
+
+	lic, err := s.repo.Get(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		hist := &KeyHistory{
+			LicenseID:  licenseID,
+			LicenseKey: lic.LicenseKey,
+			Event:      KeyEventReplaced,
+			Reason:     reason,
+			ReplacedAt: now.Format(timeFormat),
+			GraceUntil: now.Add(grace).Format(timeFormat),
+		}
+		if err := s.repo.CreateKeyHistory(ctx, tx, hist); err != nil {
+			return err
+		}
+		return s.repo.UpdateKey(ctx, tx, licenseID, uuid.New().String())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.Get(ctx, licenseID)
+}
+
+// SetKeyStatus suspends, revokes or reactivates a license's key, recording the
+// change and its reason in the key history. Revoking also ends the grace
+// period of any replaced keys, so they stay invalid even if the license is
+// later reactivated.
+func (s *Service) SetKeyStatus(ctx context.Context, licenseID int64, status, reason string) error {
+	if !IsValidKeyStatus(status) {
+		return ErrInvalidKeyStatus
+	}
+	lic, err := s.repo.Get(ctx, licenseID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(timeFormat)
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.repo.UpdateKeyStatus(ctx, tx, licenseID, status); err != nil {
+			return err
+		}
+		hist := &KeyHistory{
+			LicenseID:  licenseID,
+			LicenseKey: lic.LicenseKey,
+			Event:      status,
+			Reason:     reason,
+			ReplacedAt: now,
+		}
+		if err := s.repo.CreateKeyHistory(ctx, tx, hist); err != nil {
+			return err
+		}
+		if status == KeyRevoked {
+			return s.repo.RevokeKeyHistory(ctx, tx, licenseID, now)
+		}
+		return nil
+	})
+}
+
+// SetStatus suspends, cancels or reactivates a license. Non-active licenses are
+// rejected by client endpoints but keep their registrations and dates.
+func (s *Service) SetStatus(ctx context.Context, licenseID int64, status, reason string) error {
+	if !IsValidStatus(status) {
+		return ErrInvalidStatus
+	}
+	if _, err := s.repo.Get(ctx, licenseID); err != nil {
+		return err
+	}
+
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.repo.UpdateStatus(ctx, tx, licenseID, status, reason, time.Now().UTC().Format(timeFormat))
+	})
+}
+
+func (s *Service) GetKeyHistory(ctx context.Context, licenseID int64) ([]KeyHistory, error) {
+	return s.repo.GetKeyHistory(ctx, licenseID)
+}
@@ -3,13 +3,17 @@ package license_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 
 	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
 	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/registration"
 	"winsbygroup.com/regserver/internal/testutil"
 )
 
@@ -43,44 +47,55 @@ func TestLicenseLifecycle(t *testing.T) {
 		DownloadURL:   "url2",
 	})
 
-	// Initially both are unlicensed
-	unlicensed, _ := licSvc.GetUnlicensed(ctx, c.CustomerID)
-	if len(unlicensed) != 2 {
-		t.Fatalf("expected 2 unlicensed, got %d", len(unlicensed))
+	newLicense := func(productID int64, key string) *license.License {
+		t.Helper()
+		lic, err := licSvc.Create(ctx, &license.License{
+			CustomerID:          c.CustomerID,
+			ProductID:           productID,
+			LicenseCount:        5,
+			IsSubscription:      false,
+			LicenseTerm:         0,
+			LicenseKey:          key,
+			StartDate:           "2024-01-01",
+			ExpirationDate:      "9999-12-31",
+			MaintExpirationDate: "9999-12-31",
+		})
+		if err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+		return lic
 	}
 
-	// License product 1
-	lic := &license.License{
-		CustomerID:          c.CustomerID,
-		ProductID:           p1.ProductID,
-		LicenseCount:        5,
-		IsSubscription:      false,
-		LicenseTerm:         0,
-		LicenseKey:          "LIC-123",
-		StartDate:           "2024-01-01",
-		ExpirationDate:      "9999-12-31",
-		MaintExpirationDate: "9999-12-31",
+	// A customer may hold several licenses of the same product
+	first := newLicense(p1.ProductID, "LIC-123")
+	second := newLicense(p1.ProductID, "LIC-456")
+	newLicense(p2.ProductID, "LIC-789")
+
+	if first.LicenseID == 0 || first.LicenseID == second.LicenseID {
+		t.Fatalf("expected distinct license IDs, got %d and %d", first.LicenseID, second.LicenseID)
 	}
 
-	if _, err := licSvc.Create(ctx, lic); err != nil {
-		t.Fatalf("create license: %v", err)
+	all, _ := licSvc.GetForCustomer(ctx, c.CustomerID)
+	if len(all) != 3 {
+		t.Fatalf("expected 3 licenses, got %d", len(all))
 	}
 
-	// Now only product 2 is unlicensed
-	unlicensed, _ = licSvc.GetUnlicensed(ctx, c.CustomerID)
-	if len(unlicensed) != 1 || unlicensed[0].ProductID != p2.ProductID {
-		t.Fatalf("expected only product 2 unlicensed")
+	widgets, _ := licSvc.GetForProduct(ctx, c.CustomerID, p1.ProductID)
+	if len(widgets) != 2 || widgets[0].LicenseID != first.LicenseID {
+		t.Fatalf("expected both product 1 licenses in creation order, got %+v", widgets)
 	}
 
-	// Delete license
-	if err := licSvc.Delete(ctx, c.CustomerID, p1.ProductID); err != nil {
+	// Delete one license; the other license of the product stays
+	if err := licSvc.Delete(ctx, first.LicenseID); err != nil {
 		t.Fatalf("delete: %v", err)
 	}
 
-	// Both unlicensed again
-	unlicensed, _ = licSvc.GetUnlicensed(ctx, c.CustomerID)
-	if len(unlicensed) != 2 {
-		t.Fatalf("expected 2 unlicensed after delete")
+	widgets, _ = licSvc.GetForProduct(ctx, c.CustomerID, p1.ProductID)
+	if len(widgets) != 1 || widgets[0].LicenseID != second.LicenseID {
+		t.Fatalf("expected only the second license after delete, got %+v", widgets)
+	}
+	if _, err := licSvc.Get(ctx, first.LicenseID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected deleted license not found, got %v", err)
 	}
 }
 
@@ -191,6 +206,62 @@ func TestGetExpiredLicenses(t *testing.T) {
 	if expired[0].CustomerName != "Acme Corp" {
 		t.Errorf("expected CustomerName 'Acme Corp', got %s", expired[0].CustomerName)
 	}
+
+	// A billing contact receives expiration notices instead of the primary contact
+	t.Run("billing contact", func(t *testing.T) {
+		if _, err := custSvc.CreateContact(ctx, &customer.Contact{
+			CustomerID:  c1.CustomerID,
+			ContactName: "Accounts Payable",
+			Email:       "ap@acme.com",
+			Roles:       customer.RoleBilling,
+		}); err != nil {
+			t.Fatalf("CreateContact: %v", err)
+		}
+		// The customer's initial contact has every role; take billing away from it
+		contacts, _ := custSvc.GetContacts(ctx, c1.CustomerID)
+		for _, cc := range contacts {
+			if cc.IsPrimary {
+				cc.Roles = customer.RoleTechnical
+				if err := custSvc.UpdateContact(ctx, &cc); err != nil {
+					t.Fatalf("UpdateContact: %v", err)
+				}
+			}
+		}
+
+		expired, err := licSvc.GetExpiredLicenses(ctx, "2021-01-01")
+		if err != nil {
+			t.Fatalf("GetExpiredLicenses: %v", err)
+		}
+		if len(expired) != 1 {
+			t.Fatalf("expected 1 expired license, got %d", len(expired))
+		}
+		if expired[0].ContactName != "Accounts Payable" || expired[0].Email != "ap@acme.com" {
+			t.Errorf("expected billing contact, got %s <%s>", expired[0].ContactName, expired[0].Email)
+		}
+	})
+
+	// Without a usable billing or primary contact, the customer's own contact
+	// columns are reported
+	t.Run("falls back to the customer", func(t *testing.T) {
+		for _, tc := range []struct {
+			name, update string
+		}{
+			{"blank contact email", `UPDATE customer_contact SET email = '' WHERE customer_id = ?`},
+			{"no billing or primary contact", `UPDATE customer_contact SET email = 'tech@beta.com', roles = 'technical', is_primary = 0 WHERE customer_id = ?`},
+		} {
+			// Bypass the service so the customer's columns keep their values
+			if _, err := db.Exec(tc.update, c2.CustomerID); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			expired, err := licSvc.GetExpiredLicenses(ctx, "2025-01-01")
+			if err != nil {
+				t.Fatalf("GetExpiredLicenses: %v", err)
+			}
+			if expired[0].ContactName != "Jane Smith" || expired[0].Email != "jane@beta.com" {
+				t.Errorf("%s: expected the customer's contact, got %s <%s>", tc.name, expired[0].ContactName, expired[0].Email)
+			}
+		}
+	})
 }
 
 func TestLicenseValidation(t *testing.T) {
@@ -340,7 +411,7 @@ func TestLicenseValidation(t *testing.T) {
 		}
 
 		// Clean up
-		licSvc.Delete(ctx, created.CustomerID, created.ProductID)
+		licSvc.Delete(ctx, created.LicenseID)
 	})
 
 	// Max product version validation tests
@@ -384,7 +455,7 @@ func TestLicenseValidation(t *testing.T) {
 		}
 
 		// Clean up
-		licSvc.Delete(ctx, created.CustomerID, created.ProductID)
+		licSvc.Delete(ctx, created.LicenseID)
 	})
 
 	t.Run("empty max product version succeeds", func(t *testing.T) {
@@ -407,7 +478,7 @@ func TestLicenseValidation(t *testing.T) {
 		}
 
 		// Clean up
-		licSvc.Delete(ctx, created.CustomerID, created.ProductID)
+		licSvc.Delete(ctx, created.LicenseID)
 	})
 
 	t.Run("subscription with valid term succeeds", func(t *testing.T) {
@@ -429,7 +500,7 @@ func TestLicenseValidation(t *testing.T) {
 		}
 
 		// Clean up
-		licSvc.Delete(ctx, created.CustomerID, created.ProductID)
+		licSvc.Delete(ctx, created.LicenseID)
 	})
 }
 
@@ -473,8 +544,7 @@ func TestLicenseValidationOnUpdate(t *testing.T) {
 
 	t.Run("update to invalid subscription fails", func(t *testing.T) {
 		updated := &license.License{
-			CustomerID:          created.CustomerID,
-			ProductID:           created.ProductID,
+			LicenseID:           created.LicenseID,
 			LicenseKey:          created.LicenseKey,
 			LicenseCount:        1,
 			IsSubscription:      true,
@@ -492,8 +562,7 @@ func TestLicenseValidationOnUpdate(t *testing.T) {
 
 	t.Run("update to invalid version fails", func(t *testing.T) {
 		updated := &license.License{
-			CustomerID:          created.CustomerID,
-			ProductID:           created.ProductID,
+			LicenseID:           created.LicenseID,
 			LicenseKey:          created.LicenseKey,
 			LicenseCount:        1,
 			IsSubscription:      false,
@@ -510,3 +579,287 @@ func TestLicenseValidationOnUpdate(t *testing.T) {
 		}
 	})
 }
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licSvc := license.NewService(db)
+	machineSvc := machine.NewService(db)
+	regSvc := registration.NewService(db)
+
+	c, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Leaky Co"})
+	p, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Widget",
+		ProductGUID:   "GUID-ROTATE",
+		LatestVersion: "1.0.0",
+		DownloadURL:   "url",
+	})
+	created, err := licSvc.Create(ctx, &license.License{
+		CustomerID:          c.CustomerID,
+		ProductID:           p.ProductID,
+		LicenseCount:        2,
+		LicenseKey:          "old-key-1",
+		StartDate:           "2024-01-01",
+		ExpirationDate:      "9999-12-31",
+		MaintExpirationDate: "9999-12-31",
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	// An existing registration that must survive rotation
+	tx := db.MustBegin()
+	machineID, err := machineSvc.GetOrCreate(ctx, tx, c.CustomerID, "MACHINE-1", "alice")
+	if err != nil {
+		t.Fatalf("create machine: %v", err)
+	}
+	err = regSvc.Upsert(ctx, tx, &registration.Registration{
+		MachineID:        machineID,
+		ProductID:        p.ProductID,
+		LicenseID:        &created.LicenseID,
+		ExpirationDate:   "9999-12-31",
+		RegistrationHash: "hash",
+	})
+	if err != nil {
+		t.Fatalf("create registration: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	t.Run("new license key is active", func(t *testing.T) {
+		lic, err := licSvc.GetByActiveKey(ctx, "OLD-KEY-1")
+		if err != nil {
+			t.Fatalf("GetByActiveKey: %v", err)
+		}
+		if lic.KeyStatus != license.KeyActive {
+			t.Errorf("expected status active, got %q", lic.KeyStatus)
+		}
+	})
+
+	t.Run("rotate with grace keeps old key valid", func(t *testing.T) {
+		rotated, err := licSvc.RotateKey(ctx, created.LicenseID, time.Hour, "posted on forum")
+		if err != nil {
+			t.Fatalf("RotateKey: %v", err)
+		}
+		if rotated.LicenseKey == "old-key-1" || rotated.LicenseKey == "" {
+			t.Fatalf("expected a new key, got %q", rotated.LicenseKey)
+		}
+
+		lic, err := licSvc.GetByActiveKey(ctx, "old-key-1")
+		if err != nil {
+			t.Fatalf("expected old key valid during grace, got %v", err)
+		}
+		if lic.LicenseKey != rotated.LicenseKey {
+			t.Errorf("expected old key to resolve to the current license, got key %q", lic.LicenseKey)
+		}
+		if _, err := licSvc.GetByActiveKey(ctx, rotated.LicenseKey); err != nil {
+			t.Errorf("expected new key valid, got %v", err)
+		}
+
+		reg, err := regSvc.Get(ctx, machineID, p.ProductID)
+		if err != nil || reg == nil {
+			t.Errorf("expected registration to survive rotation, got %v", err)
+		}
+
+		hist, err := licSvc.GetKeyHistory(ctx, created.LicenseID)
+		if err != nil {
+			t.Fatalf("GetKeyHistory: %v", err)
+		}
+		if len(hist) != 1 || hist[0].LicenseKey != "old-key-1" || hist[0].Reason != "posted on forum" {
+			t.Errorf("unexpected key history: %+v", hist)
+		}
+	})
+
+	t.Run("rotate without grace invalidates old key", func(t *testing.T) {
+		before, _ := licSvc.Get(ctx, created.LicenseID)
+		if _, err := licSvc.RotateKey(ctx, created.LicenseID, 0, ""); err != nil {
+			t.Fatalf("RotateKey: %v", err)
+		}
+
+		_, err := licSvc.GetByActiveKey(ctx, before.LicenseKey)
+		if !errors.Is(err, license.ErrKeyReplaced) {
+			t.Errorf("expected ErrKeyReplaced, got %v", err)
+		}
+	})
+
+	t.Run("negative grace fails", func(t *testing.T) {
+		_, err := licSvc.RotateKey(ctx, created.LicenseID, -time.Minute, "")
+		if !errors.Is(err, license.ErrNegativeGracePeriod) {
+			t.Errorf("expected ErrNegativeGracePeriod, got %v", err)
+		}
+	})
+
+	t.Run("suspend and reactivate", func(t *testing.T) {
+		lic, _ := licSvc.Get(ctx, created.LicenseID)
+
+		if err := licSvc.SetKeyStatus(ctx, created.LicenseID, license.KeySuspended, "chargeback"); err != nil {
+			t.Fatalf("SetKeyStatus: %v", err)
+		}
+		if _, err := licSvc.GetByActiveKey(ctx, lic.LicenseKey); !errors.Is(err, license.ErrKeySuspended) {
+			t.Errorf("expected ErrKeySuspended, got %v", err)
+		}
+
+		if err := licSvc.SetKeyStatus(ctx, created.LicenseID, license.KeyActive, "payment received"); err != nil {
+			t.Fatalf("SetKeyStatus: %v", err)
+		}
+		if _, err := licSvc.GetByActiveKey(ctx, lic.LicenseKey); err != nil {
+			t.Errorf("expected reactivated key valid, got %v", err)
+		}
+
+		// Both changes are recorded with their reason, newest first
+		hist, err := licSvc.GetKeyHistory(ctx, created.LicenseID)
+		if err != nil {
+			t.Fatalf("GetKeyHistory: %v", err)
+		}
+		if len(hist) < 2 {
+			t.Fatalf("expected status changes in key history, got %+v", hist)
+		}
+		for i, want := range []struct{ event, reason string }{{license.KeyActive, "payment received"}, {license.KeySuspended, "chargeback"}} {
+			if h := hist[i]; h.Event != want.event || h.Reason != want.reason || h.LicenseKey != lic.LicenseKey || h.InGrace(time.Now()) {
+				t.Errorf("history[%d]: expected %s (%s) of the current key, got %+v", i, want.event, want.reason, h)
+			}
+		}
+	})
+
+	t.Run("revoke ends grace periods", func(t *testing.T) {
+		before, _ := licSvc.Get(ctx, created.LicenseID)
+		if _, err := licSvc.RotateKey(ctx, created.LicenseID, 24*time.Hour, ""); err != nil {
+			t.Fatalf("RotateKey: %v", err)
+		}
+
+		if err := licSvc.SetKeyStatus(ctx, created.LicenseID, license.KeyRevoked, ""); err != nil {
+			t.Fatalf("SetKeyStatus: %v", err)
+		}
+		if _, err := licSvc.GetByActiveKey(ctx, before.LicenseKey); !errors.Is(err, license.ErrKeyReplaced) {
+			t.Errorf("expected grace key ended by revoke, got %v", err)
+		}
+
+		// Reactivating the license does not bring back the revoked grace key
+		if err := licSvc.SetKeyStatus(ctx, created.LicenseID, license.KeyActive, ""); err != nil {
+			t.Fatalf("SetKeyStatus: %v", err)
+		}
+		if _, err := licSvc.GetByActiveKey(ctx, before.LicenseKey); !errors.Is(err, license.ErrKeyReplaced) {
+			t.Errorf("expected grace key to stay revoked, got %v", err)
+		}
+	})
+
+	t.Run("invalid status fails", func(t *testing.T) {
+		err := licSvc.SetKeyStatus(ctx, created.LicenseID, "lost", "")
+		if !errors.Is(err, license.ErrInvalidKeyStatus) {
+			t.Errorf("expected ErrInvalidKeyStatus, got %v", err)
+		}
+	})
+
+	t.Run("unknown key fails", func(t *testing.T) {
+		if _, err := licSvc.GetByActiveKey(ctx, "no-such-key"); err == nil {
+			t.Error("expected error for unknown key")
+		}
+	})
+}
+
+func TestLicenseStatus(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licSvc := license.NewService(db)
+
+	c, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Billing Co"})
+	p, _ := prodSvc.Create(ctx, &product.Product{
+		ProductName:   "Widget",
+		ProductGUID:   "GUID-STATUS",
+		LatestVersion: "1.0.0",
+		DownloadURL:   "url",
+	})
+	created, err := licSvc.Create(ctx, &license.License{
+		CustomerID:          c.CustomerID,
+		ProductID:           p.ProductID,
+		LicenseCount:        1,
+		LicenseKey:          "status-key-1",
+		StartDate:           "2024-01-01",
+		ExpirationDate:      "9999-12-31",
+		MaintExpirationDate: "9999-12-31",
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
+	t.Run("new license is active", func(t *testing.T) {
+		lic, _ := licSvc.Get(ctx, created.LicenseID)
+		if lic.Status != license.StatusActive || lic.StatusChangedAt != "" {
+			t.Errorf("expected active status with no change time, got %q %q", lic.Status, lic.StatusChangedAt)
+		}
+		if err := lic.CheckStatus(); err != nil {
+			t.Errorf("expected no status error, got %v", err)
+		}
+	})
+
+	t.Run("suspend records reason and time", func(t *testing.T) {
+		if err := licSvc.SetStatus(ctx, created.LicenseID, license.StatusSuspended, "invoice overdue"); err != nil {
+			t.Fatalf("SetStatus: %v", err)
+		}
+		lic, _ := licSvc.Get(ctx, created.LicenseID)
+		if lic.Status != license.StatusSuspended || lic.StatusReason != "invoice overdue" || lic.StatusChangedAt == "" {
+			t.Errorf("unexpected status fields: %q %q %q", lic.Status, lic.StatusReason, lic.StatusChangedAt)
+		}
+		if lic.ExpirationDate != "9999-12-31" {
+			t.Errorf("expected expiration date unchanged, got %q", lic.ExpirationDate)
+		}
+
+		if _, err := licSvc.GetByActiveKey(ctx, "status-key-1"); !errors.Is(err, license.ErrLicenseSuspended) {
+			t.Errorf("expected ErrLicenseSuspended, got %v", err)
+		}
+		if code := license.ErrorCode(lic.CheckStatus()); code != license.CodeLicenseSuspended {
+			t.Errorf("expected code %q, got %q", license.CodeLicenseSuspended, code)
+		}
+	})
+
+	t.Run("same status keeps change time", func(t *testing.T) {
+		if _, err := db.Exec(`UPDATE license SET status_changed_at = '2020-01-01 00:00:00'`); err != nil {
+			t.Fatalf("set change time: %v", err)
+		}
+		if err := licSvc.SetStatus(ctx, created.LicenseID, license.StatusSuspended, "still overdue"); err != nil {
+			t.Fatalf("SetStatus: %v", err)
+		}
+		lic, _ := licSvc.Get(ctx, created.LicenseID)
+		if lic.StatusChangedAt != "2020-01-01 00:00:00" || lic.StatusReason != "still overdue" {
+			t.Errorf("expected reason updated and change time kept, got %q %q", lic.StatusReason, lic.StatusChangedAt)
+		}
+	})
+
+	t.Run("cancelled license is rejected", func(t *testing.T) {
+		if err := licSvc.SetStatus(ctx, created.LicenseID, license.StatusCancelled, ""); err != nil {
+			t.Fatalf("SetStatus: %v", err)
+		}
+		if _, err := licSvc.GetByActiveKey(ctx, "status-key-1"); !errors.Is(err, license.ErrLicenseCancelled) {
+			t.Errorf("expected ErrLicenseCancelled, got %v", err)
+		}
+	})
+
+	t.Run("reactivate", func(t *testing.T) {
+		if err := licSvc.SetStatus(ctx, created.LicenseID, license.StatusActive, ""); err != nil {
+			t.Fatalf("SetStatus: %v", err)
+		}
+		if _, err := licSvc.GetByActiveKey(ctx, "status-key-1"); err != nil {
+			t.Errorf("expected active license, got %v", err)
+		}
+	})
+
+	t.Run("invalid status fails", func(t *testing.T) {
+		err := licSvc.SetStatus(ctx, created.LicenseID, "frozen", "")
+		if !errors.Is(err, license.ErrInvalidStatus) {
+			t.Errorf("expected ErrInvalidStatus, got %v", err)
+		}
+	})
+
+	t.Run("unknown license fails", func(t *testing.T) {
+		if err := licSvc.SetStatus(ctx, 9999, license.StatusSuspended, ""); err == nil {
+			t.Error("expected error for unknown license")
+		}
+	})
+}
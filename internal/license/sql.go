@@ -2,6 +2,7 @@ package license
 
 const getLicenseSQL = `
 SELECT
+    license_id,
     customer_id,
     product_id,
     license_key,
@@ -11,13 +12,20 @@ SELECT
     start_date,
     expiration_date,
     maint_expiration_date,
-    max_product_version
+    max_product_version,
+    key_status,
+    status,
+    status_reason,
+    status_changed_at,
+    bundle_id,
+    plan_id
 FROM license
-WHERE customer_id = ? AND product_id = ?
+WHERE license_id = ?
 `
 
 const getLicensesSQL = `
 SELECT
+    license_id,
     customer_id,
     product_id,
     license_key,
@@ -27,21 +35,40 @@ SELECT
     start_date,
     expiration_date,
     maint_expiration_date,
-    max_product_version
+    max_product_version,
+    key_status,
+    status,
+    status_reason,
+    status_changed_at,
+    bundle_id,
+    plan_id
 FROM license
 WHERE customer_id = ?
-ORDER BY product_id
+ORDER BY product_id, license_id
 `
 
-const getUnlicensedProductsSQL = `
-SELECT p.product_id, p.product_name
-FROM product p
-WHERE p.product_id NOT IN (
-    SELECT product_id
-    FROM license
-    WHERE customer_id = ?
-)
-ORDER BY p.product_name
+const getProductLicensesSQL = `
+SELECT
+    license_id,
+    customer_id,
+    product_id,
+    license_key,
+    license_count,
+    is_subscription,
+    license_term,
+    start_date,
+    expiration_date,
+    maint_expiration_date,
+    max_product_version,
+    key_status,
+    status,
+    status_reason,
+    status_changed_at,
+    bundle_id,
+    plan_id
+FROM license
+WHERE customer_id = ? AND product_id = ?
+ORDER BY license_id
 `
 
 const createLicenseSQL = `
@@ -55,8 +82,10 @@ INSERT INTO license (
     start_date,
     expiration_date,
     maint_expiration_date,
-    max_product_version
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    max_product_version,
+    bundle_id,
+    plan_id
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 const updateLicenseSQL = `
@@ -69,31 +98,95 @@ SET
     expiration_date = ?,
     maint_expiration_date = ?,
     max_product_version = ?
-WHERE customer_id = ? AND product_id = ?
+WHERE license_id = ?
+`
+
+const getBundleLicensesSQL = `
+SELECT
+    license_id,
+    customer_id,
+    product_id,
+    license_key,
+    license_count,
+    is_subscription,
+    license_term,
+    start_date,
+    expiration_date,
+    maint_expiration_date,
+    max_product_version,
+    key_status,
+    status,
+    status_reason,
+    status_changed_at,
+    bundle_id,
+    plan_id
+FROM license
+WHERE customer_id = ? AND bundle_id = ?
+ORDER BY product_id, license_id
+`
+
+// updateBundleTermsSQL copies a license's seats and dates to the other
+// licenses of the same customer bundle. The max product version is per product.
+const updateBundleTermsSQL = `
+UPDATE license
+SET
+    license_count = ?,
+    is_subscription = ?,
+    license_term = ?,
+    start_date = ?,
+    expiration_date = ?,
+    maint_expiration_date = ?
+WHERE customer_id = ? AND bundle_id = ?
+`
+
+const updatePlanSQL = `
+UPDATE license
+SET plan_id = ?
+WHERE license_id = ?
 `
 
 const deleteLicenseSQL = `
 DELETE FROM license
-WHERE customer_id = ? AND product_id = ?
+WHERE license_id = ?
+`
+
+const deleteBundleLicensesSQL = `
+DELETE FROM license
+WHERE customer_id = ? AND bundle_id = ?
 `
 
+// getExpiredLicensesSQL reports the customer's billing contact: the primary
+// contact with the billing role, then any billing contact, then the primary
+// contact. Blank names and emails, and customers without such a contact, fall
+// back to the customer's own contact columns.
 const getExpiredLicensesSQL = `
 SELECT
+    l.license_id,
+    c.customer_id,
     c.customer_name,
-    c.contact_name,
-    c.email,
+    COALESCE(NULLIF(cc.contact_name, ''), c.contact_name, '') AS contact_name,
+    COALESCE(NULLIF(cc.email, ''), c.email, '') AS email,
     p.product_name,
     l.expiration_date,
     l.maint_expiration_date
 FROM license l
 JOIN customer c ON c.customer_id = l.customer_id
 JOIN product p ON p.product_id = l.product_id
+LEFT JOIN customer_contact cc ON cc.contact_id = (
+    SELECT contact_id
+    FROM customer_contact
+    WHERE customer_id = c.customer_id
+      AND (instr(',' || roles || ',', ',billing,') > 0 OR is_primary = 1)
+    ORDER BY instr(',' || roles || ',', ',billing,') > 0 DESC, is_primary DESC, contact_id
+    LIMIT 1
+)
 WHERE l.expiration_date < ? OR l.maint_expiration_date < ?
 ORDER BY l.expiration_date DESC
 `
 
 const getLicenseByKeySQL = `
 SELECT
+    license_id,
     customer_id,
     product_id,
     license_key,
@@ -103,7 +196,93 @@ SELECT
     start_date,
     expiration_date,
     maint_expiration_date,
-    max_product_version
+    max_product_version,
+    key_status,
+    status,
+    status_reason,
+    status_changed_at,
+    bundle_id,
+    plan_id
 FROM license
 WHERE license_key = ?
 `
+
+const updateLicenseKeySQL = `
+UPDATE license
+SET license_key = ?
+WHERE license_id = ?
+`
+
+const updateKeyStatusSQL = `
+UPDATE license
+SET key_status = ?
+WHERE license_id = ?
+`
+
+// updateStatusSQL only moves status_changed_at when the status actually changes
+const updateStatusSQL = `
+UPDATE license
+SET
+    status_changed_at = CASE WHEN status = ? THEN status_changed_at ELSE ? END,
+    status = ?,
+    status_reason = ?
+WHERE license_id = ?
+`
+
+const createKeyHistorySQL = `
+INSERT INTO license_key_history (
+    license_id,
+    license_key,
+    event,
+    reason,
+    replaced_at,
+    grace_until
+) VALUES (?, ?, ?, ?, ?, ?)
+`
+
+const revokeKeyHistorySQL = `
+UPDATE license_key_history
+SET revoked_at = ?
+WHERE license_id = ? AND event = 'replaced' AND revoked_at = '' AND grace_until > ?
+`
+
+const getKeyHistorySQL = `
+SELECT
+    history_id,
+    license_id,
+    license_key,
+    event,
+    reason,
+    replaced_at,
+    grace_until,
+    revoked_at
+FROM license_key_history
+WHERE license_id = ?
+ORDER BY history_id DESC
+`
+
+const getKeyRefSQL = `
+SELECT license_id, customer_id, product_id, key_status, status
+FROM license
+WHERE license_id = ?
+`
+
+const getKeyRefByKeySQL = `
+SELECT license_id, customer_id, product_id, key_status, status
+FROM license
+WHERE license_key = ?
+`
+
+const getKeyHistoryByKeySQL = `
+SELECT
+    history_id,
+    license_id,
+    license_key,
+    event,
+    reason,
+    replaced_at,
+    grace_until,
+    revoked_at
+FROM license_key_history
+WHERE license_key = ? AND event = 'replaced'
+`
@@ -0,0 +1,89 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"winsbygroup.com/regserver/internal/logging"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("json output", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := logging.New(&buf, "json", "info")
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		l.Info("hello", "customer_id", 7)
+
+		var line map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("expected JSON output, got %q: %v", buf.String(), err)
+		}
+		if line["msg"] != "hello" || line["customer_id"] != float64(7) {
+			t.Errorf("unexpected log line: %v", line)
+		}
+	})
+
+	t.Run("text output", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := logging.New(&buf, "text", "")
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		l.Info("hello", "product_id", 3)
+		if !strings.Contains(buf.String(), "msg=hello product_id=3") {
+			t.Errorf("unexpected log line: %q", buf.String())
+		}
+	})
+
+	t.Run("filters below level", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := logging.New(&buf, "text", "warn")
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		l.Info("quiet")
+		l.Warn("loud")
+		if strings.Contains(buf.String(), "quiet") || !strings.Contains(buf.String(), "loud") {
+			t.Errorf("expected only warn output, got %q", buf.String())
+		}
+	})
+
+	t.Run("rejects invalid options", func(t *testing.T) {
+		if _, err := logging.New(&bytes.Buffer{}, "xml", "info"); err == nil {
+			t.Error("expected error for invalid format")
+		}
+		if _, err := logging.New(&bytes.Buffer{}, "json", "verbose"); err == nil {
+			t.Error("expected error for invalid level")
+		}
+	})
+}
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l, _ := logging.New(&buf, "text", "info")
+
+	ctx := logging.WithLogger(context.Background(), l)
+	ctx = logging.With(ctx, "request_id", "abc")
+	logging.FromContext(ctx).Info("activated")
+
+	if !strings.Contains(buf.String(), "request_id=abc") {
+		t.Errorf("expected request attributes in output, got %q", buf.String())
+	}
+	if logging.FromContext(context.Background()) == nil {
+		t.Error("expected default logger when none is set")
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	if got := logging.KeyPrefix("0123456789abcdef"); got != "01234567..." {
+		t.Errorf("KeyPrefix = %q", got)
+	}
+	if got := logging.KeyPrefix("short"); got != "short" {
+		t.Errorf("KeyPrefix = %q", got)
+	}
+}
@@ -0,0 +1,56 @@
+package machine
+
+import "strings"
+
+// FingerprintPrefix marks a structured machine code. Structured codes carry one
+// hash per hardware component ("fp1.<board>.<cpu>.<disk>") instead of a single
+// opaque hash, so a machine can still be recognised after a component changes.
+// An empty component means the client could not read that value.
+const FingerprintPrefix = "fp1"
+
+const fingerprintSep = "."
+
+// Fingerprint holds the component hashes of a structured machine code
+type Fingerprint []string
+
+// ParseFingerprint splits a structured machine code into its components.
+// It returns false for opaque (legacy) codes.
+func ParseFingerprint(code string) (Fingerprint, bool) {
+	parts := strings.Split(code, fingerprintSep)
+	if len(parts) < 3 || parts[0] != FingerprintPrefix {
+		return nil, false
+	}
+	return Fingerprint(parts[1:]), true
+}
+
+// String returns the machine code for the fingerprint
+func (f Fingerprint) String() string {
+	return FingerprintPrefix + fingerprintSep + strings.Join(f, fingerprintSep)
+}
+
+// Matches counts the components that are equal in both fingerprints. Components
+// are compared by position and empty components never match. Fingerprints with
+// a different number of components do not match at all.
+func (f Fingerprint) Matches(other Fingerprint) int {
+	if len(f) != len(other) {
+		return 0
+	}
+	n := 0
+	for i := range f {
+		if f[i] != "" && f[i] == other[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// Changed returns the positions of components that differ between the fingerprints
+func (f Fingerprint) Changed(other Fingerprint) []int {
+	var out []int
+	for i := range f {
+		if i >= len(other) || f[i] != other[i] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
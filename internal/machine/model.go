@@ -1,8 +1,28 @@
 package machine
 
+import "errors"
+
+var (
+	// ErrSameCustomer is returned when a machine is transferred to the customer that already owns it
+	ErrSameCustomer = errors.New("machine already belongs to this customer")
+
+	// ErrMachineCodeExists is returned when the destination customer already has a machine with the same code
+	ErrMachineCodeExists = errors.New("destination customer already has a machine with this code")
+)
+
 type Machine struct {
 	MachineID   int64  `db:"machine_id"`
 	CustomerID  int64  `db:"customer_id"`
 	MachineCode string `db:"machine_code"`
 	UserName    string `db:"user_name"`
 }
+
+// Transfer records a machine moving from one customer to another
+type Transfer struct {
+	TransferID     int64  `db:"transfer_id" json:"transferId"`
+	MachineID      int64  `db:"machine_id" json:"machineId"`
+	FromCustomerID int64  `db:"from_customer_id" json:"fromCustomerId"`
+	ToCustomerID   int64  `db:"to_customer_id" json:"toCustomerId"`
+	Reason         string `db:"reason" json:"reason"`
+	TransferredAt  string `db:"transferred_at" json:"transferredAt"`
+}
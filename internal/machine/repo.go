@@ -14,8 +14,14 @@ type Repository interface {
 	GetByCode(ctx context.Context, customerID int64, machineCode string) (*Machine, error)
 	Create(ctx context.Context, tx *sqlx.Tx, m *Machine) (int64, error)
 	UpdateUserName(ctx context.Context, tx *sqlx.Tx, machineID int64, userName string) error
-	GetForLicense(ctx context.Context, customerID, productID int64) ([]Machine, error)
-	GetActiveForLicense(ctx context.Context, customerID, productID int64) ([]Machine, error)
+	GetForLicense(ctx context.Context, licenseID int64) ([]Machine, error)
+	GetActiveForLicense(ctx context.Context, licenseID int64) ([]Machine, error)
+	GetActiveForLicenseTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]Machine, error)
+	UpdateCustomer(ctx context.Context, tx *sqlx.Tx, machineID, customerID int64) error
+	UpdateCode(ctx context.Context, tx *sqlx.Tx, machineID int64, machineCode string) error
+	GetFingerprinted(ctx context.Context, customerID int64) ([]Machine, error)
+	CreateTransfer(ctx context.Context, tx *sqlx.Tx, t *Transfer) error
+	GetTransfers(ctx context.Context, machineID int64) ([]Transfer, error)
 }
 
 type repo struct {
@@ -70,14 +76,69 @@ func (r *repo) UpdateUserName(ctx context.Context, tx *sqlx.Tx, machineID int64,
 	return nil
 }
 
-func (r *repo) GetForLicense(ctx context.Context, customerID, productID int64) ([]Machine, error) {
+func (r *repo) GetForLicense(ctx context.Context, licenseID int64) ([]Machine, error) {
 	var machines []Machine
-	err := r.db.SelectContext(ctx, &machines, getForLicenseSQL, customerID, productID)
+	err := r.db.SelectContext(ctx, &machines, getForLicenseSQL, licenseID)
 	return machines, err
 }
 
-func (r *repo) GetActiveForLicense(ctx context.Context, customerID, productID int64) ([]Machine, error) {
+func (r *repo) GetActiveForLicense(ctx context.Context, licenseID int64) ([]Machine, error) {
 	var machines []Machine
-	err := r.db.SelectContext(ctx, &machines, getActiveForLicenseSQL, customerID, productID)
+	err := r.db.SelectContext(ctx, &machines, getActiveForLicenseSQL, licenseID)
 	return machines, err
 }
+
+func (r *repo) GetActiveForLicenseTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]Machine, error) {
+	var machines []Machine
+	err := tx.SelectContext(ctx, &machines, getActiveForLicenseSQL, licenseID)
+	return machines, err
+}
+
+func (r *repo) UpdateCustomer(ctx context.Context, tx *sqlx.Tx, machineID, customerID int64) error {
+	_, err := tx.ExecContext(ctx, updateCustomerSQL, customerID, machineID)
+	if err != nil {
+		return fmt.Errorf("update machine customer: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) UpdateCode(ctx context.Context, tx *sqlx.Tx, machineID int64, machineCode string) error {
+	_, err := tx.ExecContext(ctx, updateCodeSQL, machineCode, machineID)
+	if err != nil {
+		return fmt.Errorf("update machine code: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) GetFingerprinted(ctx context.Context, customerID int64) ([]Machine, error) {
+	var machines []Machine
+	err := r.db.SelectContext(ctx, &machines, getFingerprintedSQL, customerID, FingerprintPrefix+fingerprintSep+"%")
+	if err != nil {
+		return nil, fmt.Errorf("get fingerprinted machines: %w", err)
+	}
+	return machines, nil
+}
+
+func (r *repo) CreateTransfer(ctx context.Context, tx *sqlx.Tx, t *Transfer) error {
+	res, err := tx.ExecContext(ctx, createTransferSQL,
+		t.MachineID,
+		t.FromCustomerID,
+		t.ToCustomerID,
+		t.Reason,
+		t.TransferredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("create machine transfer: %w", err)
+	}
+	t.TransferID, err = res.LastInsertId()
+	return err
+}
+
+func (r *repo) GetTransfers(ctx context.Context, machineID int64) ([]Transfer, error) {
+	var out []Transfer
+	err := r.db.SelectContext(ctx, &out, getTransfersSQL, machineID)
+	if err != nil {
+		return nil, fmt.Errorf("get machine transfers: %w", err)
+	}
+	return out, nil
+}
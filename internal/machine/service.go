@@ -2,13 +2,23 @@ package machine
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/logging"
+	"winsbygroup.com/regserver/internal/registration"
 )
 
 type Service struct {
-	repo Repository
-	db   *sqlx.DB
+	repo     Repository
+	db       *sqlx.DB
+	reissuer registration.Reissuer
+
+	// fingerprintMatch is the number of equal components at which a structured
+	// machine code is treated as a known machine (0 = exact match only)
+	fingerprintMatch int
 }
 
 func NewService(db *sqlx.DB) *Service {
@@ -18,6 +28,20 @@ func NewService(db *sqlx.DB) *Service {
 	}
 }
 
+// SetFingerprintMatch sets how many components of a structured machine code
+// must match a stored fingerprint for the machine to be recognised after a
+// hardware change. 0 disables fuzzy matching.
+func (s *Service) SetFingerprintMatch(n int) {
+	s.fingerprintMatch = n
+}
+
+// SetReissuer sets what recomputes a machine's registrations when its stored
+// code changes after fingerprint drift (nil, the default, leaves them as they
+// are)
+func (s *Service) SetReissuer(r registration.Reissuer) {
+	s.reissuer = r
+}
+
 func (s *Service) Get(ctx context.Context, machineID int64) (*Machine, error) {
 	return s.repo.GetByID(ctx, machineID)
 }
@@ -34,8 +58,8 @@ func (s *Service) GetOrCreate(
 	userName string,
 ) (int64, error) {
 
-	// Lookup
-	m, err := s.repo.GetByCode(ctx, customerID, machineCode)
+	// Lookup, including machines whose fingerprint has drifted
+	m, err := s.Find(ctx, tx, customerID, machineCode)
 	if err != nil {
 		return 0, err
 	}
@@ -60,10 +84,123 @@ func (s *Service) GetOrCreate(
 	return m.MachineID, nil
 }
 
-func (s *Service) GetForLicense(ctx context.Context, customerID, productID int64) ([]Machine, error) {
-	return s.repo.GetForLicense(ctx, customerID, productID)
+// Find returns the customer's machine with machineCode, or the machine whose
+// fingerprint has drifted to it (its stored code is updated in tx). Returns
+// nil if there is none.
+func (s *Service) Find(ctx context.Context, tx *sqlx.Tx, customerID int64, machineCode string) (*Machine, error) {
+	m, err := s.repo.GetByCode(ctx, customerID, machineCode)
+	if err != nil || m != nil {
+		return m, err
+	}
+	return s.matchFingerprint(ctx, tx, customerID, machineCode)
+}
+
+// matchFingerprint finds the customer's machine whose structured code shares
+// the most components with machineCode, provided at least fingerprintMatch of
+// them are equal. The stored code is replaced with the new fingerprint and the
+// machine's registrations are re-issued for it, so those of other products
+// keep validating.
+func (s *Service) matchFingerprint(ctx context.Context, tx *sqlx.Tx, customerID int64, machineCode string) (*Machine, error) {
+	if s.fingerprintMatch <= 0 {
+		return nil, nil
+	}
+	fp, ok := ParseFingerprint(machineCode)
+	if !ok {
+		return nil, nil
+	}
+
+	candidates, err := s.repo.GetFingerprinted(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *Machine
+	var bestFP Fingerprint
+	bestMatches := 0
+	for i := range candidates {
+		cfp, _ := ParseFingerprint(candidates[i].MachineCode)
+		if n := fp.Matches(cfp); n >= s.fingerprintMatch && n > bestMatches {
+			best, bestFP, bestMatches = &candidates[i], cfp, n
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	if err := s.repo.UpdateCode(ctx, tx, best.MachineID, machineCode); err != nil {
+		return nil, err
+	}
+	if s.reissuer != nil {
+		if err := s.reissuer.ReissueMachine(ctx, tx, best.MachineID); err != nil {
+			return nil, err
+		}
+	}
+	logging.FromContext(ctx).Info("machine fingerprint drift",
+		"customer_id", customerID,
+		"machine_id", best.MachineID,
+		"matched", bestMatches,
+		"components", len(fp),
+		"changed", fp.Changed(bestFP),
+	)
+	best.MachineCode = machineCode
+	return best, nil
+}
+
+func (s *Service) GetForLicense(ctx context.Context, licenseID int64) ([]Machine, error) {
+	return s.repo.GetForLicense(ctx, licenseID)
+}
+
+func (s *Service) GetActiveForLicense(ctx context.Context, licenseID int64) ([]Machine, error) {
+	return s.repo.GetActiveForLicense(ctx, licenseID)
+}
+
+// GetActiveForLicenseTx returns the machines holding a seat of the license as
+// seen by the caller's transaction, for a seat check that must hold until the
+// transaction commits
+func (s *Service) GetActiveForLicenseTx(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]Machine, error) {
+	return s.repo.GetActiveForLicenseTx(ctx, tx, licenseID)
+}
+
+// Transfer moves a machine to another customer within tx and records the move.
+// Registrations stay attached to the machine; callers are responsible for
+// re-validating them against the destination customer's licenses.
+func (s *Service) Transfer(ctx context.Context, tx *sqlx.Tx, machineID, toCustomerID int64, reason string) (*Transfer, error) {
+	m, err := s.repo.GetByID(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("machine not found (%d)", machineID)
+	}
+	if m.CustomerID == toCustomerID {
+		return nil, ErrSameCustomer
+	}
+
+	existing, err := s.repo.GetByCode(ctx, toCustomerID, m.MachineCode)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrMachineCodeExists
+	}
+
+	if err := s.repo.UpdateCustomer(ctx, tx, machineID, toCustomerID); err != nil {
+		return nil, err
+	}
+
+	t := &Transfer{
+		MachineID:      machineID,
+		FromCustomerID: m.CustomerID,
+		ToCustomerID:   toCustomerID,
+		Reason:         reason,
+		TransferredAt:  time.Now().UTC().Format("2006-01-02 15:04:05"),
+	}
+	if err := s.repo.CreateTransfer(ctx, tx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
 }
 
-func (s *Service) GetActiveForLicense(ctx context.Context, customerID, productID int64) ([]Machine, error) {
-	return s.repo.GetActiveForLicense(ctx, customerID, productID)
+func (s *Service) GetTransfers(ctx context.Context, machineID int64) ([]Transfer, error) {
+	return s.repo.GetTransfers(ctx, machineID)
 }
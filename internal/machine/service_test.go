@@ -2,11 +2,13 @@ package machine_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
 
 	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/license"
 	"winsbygroup.com/regserver/internal/machine"
 	"winsbygroup.com/regserver/internal/product"
 	"winsbygroup.com/regserver/internal/registration"
@@ -133,6 +135,7 @@ func TestMachine_GetForLicense(t *testing.T) {
 
 	custSvc := customer.NewService(db)
 	prodSvc := product.NewService(db)
+	licSvc := license.NewService(db)
 	machSvc := machine.NewService(db)
 	regSvc := registration.NewService(db)
 
@@ -152,6 +155,19 @@ func TestMachine_GetForLicense(t *testing.T) {
 		t.Fatalf("create product: %v", err)
 	}
 
+	// Create license the registration counts against
+	lic, err := licSvc.Create(ctx, &license.License{
+		CustomerID:          c.CustomerID,
+		ProductID:           p.ProductID,
+		LicenseCount:        1,
+		StartDate:           "2024-01-01",
+		ExpirationDate:      "2030-01-01",
+		MaintExpirationDate: "2030-01-01",
+	})
+	if err != nil {
+		t.Fatalf("create license: %v", err)
+	}
+
 	// Create machine (requires transaction)
 	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -172,6 +188,7 @@ func TestMachine_GetForLicense(t *testing.T) {
 	reg := &registration.Registration{
 		MachineID:             machineID,
 		ProductID:             p.ProductID,
+		LicenseID:             &lic.LicenseID,
 		ExpirationDate:        "2030-01-01", // string, not time.Time
 		RegistrationHash:      "dummy-hash",
 		FirstRegistrationDate: "2024-01-01",
@@ -184,7 +201,7 @@ func TestMachine_GetForLicense(t *testing.T) {
 	}
 
 	// Now exercise the machine relationship query
-	machines, err := machSvc.GetForLicense(ctx, c.CustomerID, p.ProductID)
+	machines, err := machSvc.GetForLicense(ctx, lic.LicenseID)
 	if err != nil {
 		t.Fatalf("GetForLicense: %v", err)
 	}
@@ -201,3 +218,173 @@ func TestMachine_GetForLicense(t *testing.T) {
 		t.Errorf("expected customerID %d, got %d", c.CustomerID, machines[0].CustomerID)
 	}
 }
+
+func TestMachine_Transfer(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	machSvc := machine.NewService(db)
+
+	from, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Parent Co"})
+	to, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Subsidiary"})
+
+	tx := db.MustBeginTx(ctx, nil)
+	machineID, _ := machSvc.GetOrCreate(ctx, tx, from.CustomerID, "MACHINE-MOVE", "John")
+	dupID, _ := machSvc.GetOrCreate(ctx, tx, from.CustomerID, "MACHINE-DUP", "Jane")
+	_, _ = machSvc.GetOrCreate(ctx, tx, to.CustomerID, "MACHINE-DUP", "Jane")
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	transfer := func(machineID, toCustomerID int64) (*machine.Transfer, error) {
+		tx := db.MustBeginTx(ctx, nil)
+		out, err := machSvc.Transfer(ctx, tx, machineID, toCustomerID, "acquired")
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		return out, tx.Commit()
+	}
+
+	t.Run("moves machine and records transfer", func(t *testing.T) {
+		tr, err := transfer(machineID, to.CustomerID)
+		if err != nil {
+			t.Fatalf("Transfer: %v", err)
+		}
+		if tr.FromCustomerID != from.CustomerID || tr.ToCustomerID != to.CustomerID || tr.TransferredAt == "" {
+			t.Errorf("unexpected transfer record: %+v", tr)
+		}
+
+		m, _ := machSvc.Get(ctx, machineID)
+		if m.CustomerID != to.CustomerID {
+			t.Errorf("expected machine to belong to customer %d, got %d", to.CustomerID, m.CustomerID)
+		}
+
+		hist, err := machSvc.GetTransfers(ctx, machineID)
+		if err != nil {
+			t.Fatalf("GetTransfers: %v", err)
+		}
+		if len(hist) != 1 || hist[0].Reason != "acquired" {
+			t.Errorf("unexpected transfer history: %+v", hist)
+		}
+	})
+
+	t.Run("rejects transfer to current customer", func(t *testing.T) {
+		if _, err := transfer(machineID, to.CustomerID); !errors.Is(err, machine.ErrSameCustomer) {
+			t.Errorf("expected ErrSameCustomer, got %v", err)
+		}
+	})
+
+	t.Run("rejects duplicate machine code at destination", func(t *testing.T) {
+		if _, err := transfer(dupID, to.CustomerID); !errors.Is(err, machine.ErrMachineCodeExists) {
+			t.Errorf("expected ErrMachineCodeExists, got %v", err)
+		}
+	})
+
+	t.Run("rejects unknown machine", func(t *testing.T) {
+		if _, err := transfer(99999, to.CustomerID); err == nil {
+			t.Error("expected error for unknown machine")
+		}
+	})
+}
+
+func TestMachine_GetOrCreate_FingerprintDrift(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	custSvc := customer.NewService(db)
+	machSvc := machine.NewService(db)
+	machSvc.SetFingerprintMatch(2)
+
+	c, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Acme"})
+	other, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Other"})
+
+	getOrCreate := func(customerID int64, code string) int64 {
+		t.Helper()
+		tx := db.MustBeginTx(ctx, nil)
+		id, err := machSvc.GetOrCreate(ctx, tx, customerID, code, "John")
+		if err != nil {
+			tx.Rollback()
+			t.Fatalf("GetOrCreate %s: %v", code, err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+		return id
+	}
+
+	original := getOrCreate(c.CustomerID, "fp1.board.cpu.disk")
+
+	t.Run("replaced disk matches existing machine", func(t *testing.T) {
+		id := getOrCreate(c.CustomerID, "fp1.board.cpu.newdisk")
+		if id != original {
+			t.Fatalf("expected machine %d to be reused, got %d", original, id)
+		}
+		m, _ := machSvc.Get(ctx, id)
+		if m.MachineCode != "fp1.board.cpu.newdisk" {
+			t.Errorf("expected stored fingerprint to be updated, got %s", m.MachineCode)
+		}
+	})
+
+	t.Run("too many changes create a new machine", func(t *testing.T) {
+		if id := getOrCreate(c.CustomerID, "fp1.board.newcpu.otherdisk"); id == original {
+			t.Error("expected a new machine when only one component matches")
+		}
+	})
+
+	t.Run("empty components never match", func(t *testing.T) {
+		a := getOrCreate(c.CustomerID, "fp1.x1..d1")
+		if b := getOrCreate(c.CustomerID, "fp1.x2..d1"); a == b {
+			t.Error("expected empty components not to count as a match")
+		}
+	})
+
+	t.Run("matching is scoped to the customer", func(t *testing.T) {
+		if id := getOrCreate(other.CustomerID, "fp1.board.cpu.disk"); id == original {
+			t.Error("expected another customer's machine not to be matched")
+		}
+	})
+
+	t.Run("legacy codes require an exact match", func(t *testing.T) {
+		a := getOrCreate(c.CustomerID, "MACHINE-123")
+		if b := getOrCreate(c.CustomerID, "MACHINE-124"); a == b {
+			t.Error("expected opaque codes not to be fuzzy matched")
+		}
+	})
+
+	t.Run("disabled matching requires an exact match", func(t *testing.T) {
+		machSvc.SetFingerprintMatch(0)
+		defer machSvc.SetFingerprintMatch(2)
+		if id := getOrCreate(c.CustomerID, "fp1.board.cpu.disk3"); id == original {
+			t.Error("expected no fuzzy match with matching disabled")
+		}
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	fp, ok := machine.ParseFingerprint("fp1.a.b.c")
+	if !ok || len(fp) != 3 {
+		t.Fatalf("expected 3 components, got %v (%v)", fp, ok)
+	}
+	if fp.String() != "fp1.a.b.c" {
+		t.Errorf("expected round trip, got %s", fp.String())
+	}
+	for _, code := range []string{"5mToXAaMQRRXOG58VT2oRKBgD8c=", "fp1.a", "fp2.a.b.c"} {
+		if _, ok := machine.ParseFingerprint(code); ok {
+			t.Errorf("expected %q not to parse as a fingerprint", code)
+		}
+	}
+
+	other, _ := machine.ParseFingerprint("fp1.a.x.c")
+	if n := fp.Matches(other); n != 2 {
+		t.Errorf("expected 2 matching components, got %d", n)
+	}
+	if changed := fp.Changed(other); len(changed) != 1 || changed[0] != 1 {
+		t.Errorf("expected component 1 changed, got %v", changed)
+	}
+	short, _ := machine.ParseFingerprint("fp1.a.b")
+	if n := fp.Matches(short); n != 0 {
+		t.Errorf("expected different lengths not to match, got %d", n)
+	}
+}
@@ -16,8 +16,7 @@ const getActiveForLicenseSQL = `
 SELECT m.machine_id, m.customer_id, m.machine_code, m.user_name
 FROM machine m
 JOIN registration r ON r.machine_id = m.machine_id
-WHERE m.customer_id = ?
-  AND r.product_id = ?
+WHERE r.license_id = ?
   AND r.expiration_date >= DATE('now')
 ORDER BY m.machine_code
 `
@@ -26,7 +25,7 @@ const getForLicenseSQL = `
 SELECT m.machine_id, m.customer_id, m.machine_code, m.user_name
 FROM machine m
 JOIN registration r ON r.machine_id = m.machine_id
-WHERE m.customer_id = ? AND r.product_id = ?
+WHERE r.license_id = ?
 ORDER BY m.machine_code
 `
 
@@ -40,3 +39,34 @@ UPDATE machine
 SET user_name = ?
 WHERE machine_id = ?
 `
+
+const updateCustomerSQL = `
+UPDATE machine
+SET customer_id = ?
+WHERE machine_id = ?
+`
+
+const updateCodeSQL = `
+UPDATE machine
+SET machine_code = ?
+WHERE machine_id = ?
+`
+
+const getFingerprintedSQL = `
+SELECT machine_id, customer_id, machine_code, user_name
+FROM machine
+WHERE customer_id = ? AND machine_code LIKE ?
+ORDER BY machine_id
+`
+
+const createTransferSQL = `
+INSERT INTO machine_transfer (machine_id, from_customer_id, to_customer_id, reason, transferred_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+const getTransfersSQL = `
+SELECT transfer_id, machine_id, from_customer_id, to_customer_id, reason, transferred_at
+FROM machine_transfer
+WHERE machine_id = ?
+ORDER BY transfer_id DESC
+`
@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"winsbygroup.com/regserver/internal/config"
+	"winsbygroup.com/regserver/internal/logging"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns an SMTP mailer, or a LogMailer when no SMTP host is configured
+func New(cfg config.SMTP) Mailer {
+	if cfg.Host == "" {
+		return LogMailer{}
+	}
+	return &SMTPMailer{cfg: cfg, send: smtp.SendMail}
+}
+
+// SMTPMailer sends email through an SMTP server. STARTTLS is used when the
+// server supports it; credentials are only sent when a username is set.
+type SMTPMailer struct {
+	cfg  config.SMTP
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	if err := m.send(addr, auth, m.cfg.From, []string{msg.To}, buildMessage(m.cfg.From, msg, time.Now())); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+	logging.FromContext(ctx).Info("email sent", "subject", msg.Subject)
+	return nil
+}
+
+// LogMailer writes messages to the log instead of sending them. Used when SMTP
+// is not configured (development and demo mode).
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	logging.FromContext(ctx).Warn("email not sent (smtp not configured)",
+		"to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// buildMessage renders an RFC 5322 message with a UTF-8 plain text body
+func buildMessage(from string, msg Message, date time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
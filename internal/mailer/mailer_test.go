@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"context"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"winsbygroup.com/regserver/internal/config"
+)
+
+func TestSMTPMailer(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	var gotAuth smtp.Auth
+
+	m := &SMTPMailer{
+		cfg: config.SMTP{Host: "mail.example.com", Port: 587, Username: "u", Password: "p", From: "licenses@example.com"},
+		send: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			gotAddr, gotAuth, gotFrom, gotTo, gotMsg = addr, a, from, to, msg
+			return nil
+		},
+	}
+
+	err := m.Send(context.Background(), Message{To: "jane@acme.com", Subject: "Sign in", Body: "line 1\nline 2"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if gotAddr != "mail.example.com:587" || gotFrom != "licenses@example.com" || len(gotTo) != 1 || gotTo[0] != "jane@acme.com" {
+		t.Errorf("unexpected envelope: %s %s %v", gotAddr, gotFrom, gotTo)
+	}
+	if gotAuth == nil {
+		t.Error("expected auth when a username is configured")
+	}
+	msg := string(gotMsg)
+	for _, want := range []string{"From: licenses@example.com\r\n", "To: jane@acme.com\r\n", "Subject: Sign in\r\n", "\r\n\r\nline 1\r\nline 2"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected message to contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestBuildMessage_EncodesSubject(t *testing.T) {
+	msg := string(buildMessage("a@example.com", Message{To: "b@example.com", Subject: "Zugang für Müller"}, time.Now()))
+	if !strings.Contains(msg, "Subject: =?utf-8?q?") {
+		t.Errorf("expected encoded subject, got:\n%s", msg)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, ok := New(config.SMTP{}).(LogMailer); !ok {
+		t.Error("expected LogMailer without an SMTP host")
+	}
+	if _, ok := New(config.SMTP{Host: "mail.example.com", Port: 25}).(*SMTPMailer); !ok {
+		t.Error("expected SMTPMailer with an SMTP host")
+	}
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Application metrics (registered with the Default registry)
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "regserver_http_requests_total",
+		Help: "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "regserver_http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	Activations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "regserver_activations_total",
+		Help: "Activation attempts by result (success or failure) and failure reason.",
+	}, []string{"result", "reason"})
+
+	SeatLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "regserver_seat_limit_rejections_total",
+		Help: "Activations rejected because all license seats were in use, by product.",
+	}, []string{"product"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "regserver_rate_limited_total",
+		Help: "Requests rejected with 429 by scope (ip, license_key or lockout).",
+	}, []string{"scope"})
+
+	Lockouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "regserver_lockouts_total",
+		Help: "Clients locked out after repeated authentication failures.",
+	})
+)
+
+func init() {
+	Default.MustRegister(HTTPRequests, HTTPRequestDuration, Activations, SeatLimitRejections, RateLimited, Lockouts)
+}
+
+var (
+	dbOpenDesc         = prometheus.NewDesc("regserver_db_open_connections", "Established database connections (in use and idle).", nil, nil)
+	dbInUseDesc        = prometheus.NewDesc("regserver_db_in_use_connections", "Database connections currently in use.", nil, nil)
+	dbIdleDesc         = prometheus.NewDesc("regserver_db_idle_connections", "Idle database connections.", nil, nil)
+	dbWaitCountDesc    = prometheus.NewDesc("regserver_db_wait_count_total", "Total number of connections waited for.", nil, nil)
+	dbWaitDurationDesc = prometheus.NewDesc("regserver_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", nil, nil)
+)
+
+// DBStats exposes connection pool statistics for the database
+func DBStats(stats func() sql.DBStats) prometheus.Collector {
+	return &funcCollector{
+		descs: []*prometheus.Desc{dbOpenDesc, dbInUseDesc, dbIdleDesc, dbWaitCountDesc, dbWaitDurationDesc},
+		collect: func(ch chan<- prometheus.Metric) {
+			s := stats()
+			ch <- prometheus.MustNewConstMetric(dbOpenDesc, prometheus.GaugeValue, float64(s.OpenConnections))
+			ch <- prometheus.MustNewConstMetric(dbInUseDesc, prometheus.GaugeValue, float64(s.InUse))
+			ch <- prometheus.MustNewConstMetric(dbIdleDesc, prometheus.GaugeValue, float64(s.Idle))
+			ch <- prometheus.MustNewConstMetric(dbWaitCountDesc, prometheus.CounterValue, float64(s.WaitCount))
+			ch <- prometheus.MustNewConstMetric(dbWaitDurationDesc, prometheus.CounterValue, s.WaitDuration.Seconds())
+		},
+	}
+}
+
+var (
+	backupTimeDesc = prometheus.NewDesc("regserver_backup_last_timestamp_seconds", "Unix time of the most recent database backup (0 if none).", nil, nil)
+	backupSizeDesc = prometheus.NewDesc("regserver_backup_last_size_bytes", "Size of the most recent database backup (0 if none).", nil, nil)
+)
+
+// BackupStats exposes the time and size of the most recent backup.
+// last returns ok=false when no backup exists (both gauges report 0).
+func BackupStats(last func() (at time.Time, size int64, ok bool, err error)) prometheus.Collector {
+	return &funcCollector{
+		descs: []*prometheus.Desc{backupTimeDesc, backupSizeDesc},
+		collect: func(ch chan<- prometheus.Metric) {
+			at, size, ok, err := last()
+			if err != nil {
+				slog.Error("metrics: last backup", "error", err)
+			}
+			var ts float64
+			if !ok {
+				size = 0
+			} else {
+				ts = float64(at.Unix())
+			}
+			ch <- prometheus.MustNewConstMetric(backupTimeDesc, prometheus.GaugeValue, ts)
+			ch <- prometheus.MustNewConstMetric(backupSizeDesc, prometheus.GaugeValue, float64(size))
+		},
+	}
+}
+
+// ProductRegistrations is the number of registrations for a product
+type ProductRegistrations struct {
+	Product string
+	Total   int
+	Active  int
+}
+
+var (
+	registrationsTotalDesc  = prometheus.NewDesc("regserver_registrations_total", "Machine registrations per product (including expired).", []string{"product"}, nil)
+	registrationsActiveDesc = prometheus.NewDesc("regserver_registrations_active", "Active (non-expired) machine registrations per product.", []string{"product"}, nil)
+)
+
+// RegistrationCounts exposes total and active (non-expired) registrations per product
+func RegistrationCounts(counts func() ([]ProductRegistrations, error)) prometheus.Collector {
+	return &funcCollector{
+		descs: []*prometheus.Desc{registrationsTotalDesc, registrationsActiveDesc},
+		collect: func(ch chan<- prometheus.Metric) {
+			rows, err := counts()
+			if err != nil {
+				slog.Error("metrics: registration counts", "error", err)
+				return
+			}
+			for _, r := range rows {
+				ch <- prometheus.MustNewConstMetric(registrationsTotalDesc, prometheus.GaugeValue, float64(r.Total), r.Product)
+				ch <- prometheus.MustNewConstMetric(registrationsActiveDesc, prometheus.GaugeValue, float64(r.Active), r.Product)
+			}
+		},
+	}
+}
@@ -0,0 +1,47 @@
+// Package metrics exposes Prometheus metrics using client_golang.
+//
+// Application metrics (requests, activations, rate limiting) are package
+// globals registered with Default. Metrics read from a server's own resources
+// (database pool, backups, registration counts) are collectors passed to
+// Handler, so each server serves its own and building another server never
+// registers a metric twice.
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default holds the application metrics
+var Default = prometheus.NewRegistry()
+
+// Handler serves the application metrics together with the given server
+// collectors in the Prometheus exposition format
+func Handler(server ...prometheus.Collector) http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(server...)
+	return promhttp.HandlerFor(prometheus.Gatherers{Default, reg}, promhttp.HandlerOpts{
+		ErrorLog:      slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// funcCollector computes its metrics on every scrape. Use it for values owned
+// elsewhere (database pool statistics, row counts, etc.).
+type funcCollector struct {
+	descs   []*prometheus.Desc
+	collect func(ch chan<- prometheus.Metric)
+}
+
+func (c *funcCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *funcCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch)
+}
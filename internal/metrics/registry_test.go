@@ -0,0 +1,96 @@
+package metrics_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"winsbygroup.com/regserver/internal/metrics"
+)
+
+func scrape(t *testing.T, h http.Handler) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func serverCollectors(open int, products ...string) []prometheus.Collector {
+	return []prometheus.Collector{
+		metrics.DBStats(func() sql.DBStats {
+			return sql.DBStats{OpenConnections: open, InUse: 1, Idle: open - 1, WaitCount: 3, WaitDuration: 1500 * time.Millisecond}
+		}),
+		metrics.BackupStats(func() (time.Time, int64, bool, error) {
+			return time.Unix(1700000000, 0), 4096, true, nil
+		}),
+		metrics.RegistrationCounts(func() ([]metrics.ProductRegistrations, error) {
+			var out []metrics.ProductRegistrations
+			for i, p := range products {
+				out = append(out, metrics.ProductRegistrations{Product: p, Total: i + 2, Active: i + 1})
+			}
+			return out, nil
+		}),
+	}
+}
+
+func TestHandler(t *testing.T) {
+	metrics.Lockouts.Inc()
+	out := scrape(t, metrics.Handler(serverCollectors(4, "Alpha", `Say "Hi"`)...))
+
+	for _, want := range []string{
+		"# TYPE regserver_lockouts_total counter\n",
+		"# TYPE regserver_db_open_connections gauge\nregserver_db_open_connections 4\n",
+		"regserver_db_idle_connections 3\n",
+		"# TYPE regserver_db_wait_count_total counter\nregserver_db_wait_count_total 3\n",
+		"regserver_db_wait_duration_seconds_total 1.5\n",
+		"regserver_backup_last_timestamp_seconds 1.7e+09\n",
+		"regserver_backup_last_size_bytes 4096\n",
+		`regserver_registrations_total{product="Alpha"} 2` + "\n",
+		`regserver_registrations_active{product="Alpha"} 1` + "\n",
+		`regserver_registrations_total{product="Say \"Hi\""} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestHandler_PerServer(t *testing.T) {
+	// Each server build gets a handler with its own collectors; an earlier
+	// server's collectors must neither fail nor leak into a later one
+	first := metrics.Handler(serverCollectors(4, "Alpha")...)
+	second := metrics.Handler(serverCollectors(9, "Beta")...)
+
+	out := scrape(t, second)
+	if strings.Count(out, "# TYPE regserver_db_open_connections ") != 1 {
+		t.Errorf("expected a single db_open_connections family, got:\n%s", out)
+	}
+	if !strings.Contains(out, "regserver_db_open_connections 9\n") || strings.Contains(out, `product="Alpha"`) {
+		t.Errorf("expected only the second server's values, got:\n%s", out)
+	}
+	if out := scrape(t, first); !strings.Contains(out, "regserver_db_open_connections 4\n") {
+		t.Errorf("expected the first server's values, got:\n%s", out)
+	}
+}
+
+func TestBackupStats_NoBackup(t *testing.T) {
+	out := scrape(t, metrics.Handler(metrics.BackupStats(func() (time.Time, int64, bool, error) {
+		return time.Time{}, 0, false, nil
+	})))
+	for _, want := range []string{"regserver_backup_last_timestamp_seconds 0\n", "regserver_backup_last_size_bytes 0\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -2,25 +2,71 @@ package middleware
 
 import (
 	"crypto/subtle"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
+
+	"winsbygroup.com/regserver/internal/bundle"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/logging"
+	"winsbygroup.com/regserver/internal/reseller"
 )
 
 const SessionCookieName = "regadmin_session"
 
-// LicenseContext holds customer and product IDs extracted from license key
+// LicenseContext holds the license, customer and product IDs extracted from
+// the license key. A bundle license key covers several products: BundleID,
+// ProductIDs and LicenseIDs are set instead of LicenseID and ProductID, and
+// the client names the product to use.
 type LicenseContext struct {
+	LicenseID  int64 `db:"license_id"`
 	CustomerID int64 `db:"customer_id"`
 	ProductID  int64 `db:"product_id"`
+	BundleID   int64
+	ProductIDs []int64
+	LicenseIDs []int64
+}
+
+// Products returns the IDs of the products the license key activates
+func (l LicenseContext) Products() []int64 {
+	if l.BundleID != 0 {
+		return l.ProductIDs
+	}
+	return []int64{l.ProductID}
+}
+
+// LicenseFor returns the ID of the license the key uses for a product
+// (0 if the key does not cover the product)
+func (l LicenseContext) LicenseFor(productID int64) int64 {
+	if l.BundleID == 0 {
+		if productID == l.ProductID {
+			return l.LicenseID
+		}
+		return 0
+	}
+	for i, id := range l.ProductIDs {
+		if id == productID {
+			return l.LicenseIDs[i]
+		}
+	}
+	return 0
 }
 
 // LicenseKeyAuth validates the X-License-Key header against
 // license records. Used for CLIENT endpoints.
+// Replaced keys are accepted until their grace period ends. Keys that are
+// suspended, revoked or past their grace period, and licenses that are
+// suspended or cancelled, are rejected with 403 and an error code (as the
+// client handlers do); unknown keys with 401. Bundle license keys resolve to
+// all products of the customer's bundle.
 func LicenseKeyAuth(db *sqlx.DB) echo.MiddlewareFunc {
+	licenseSvc := license.NewService(db)
+	bundleSvc := bundle.NewService(db, licenseSvc)
+
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			licKey := c.Request().Header.Get("X-License-Key")
@@ -29,26 +75,59 @@ func LicenseKeyAuth(db *sqlx.DB) echo.MiddlewareFunc {
 			}
 
 			var lic LicenseContext
-			err := db.Get(&lic, `
-                SELECT customer_id, product_id
-                FROM license
-                WHERE license_key = ?
-            `, licKey)
+			ref, err := licenseSvc.ResolveKey(c.Request().Context(), licKey)
+			if err == nil {
+				lic = LicenseContext{LicenseID: ref.LicenseID, CustomerID: ref.CustomerID, ProductID: ref.ProductID}
+			} else if errors.Is(err, license.ErrNotFound) {
+				// Not a product license key; try bundle license keys
+				bref, berr := bundleSvc.ResolveKey(c.Request().Context(), licKey)
+				switch {
+				case berr == nil:
+					lic = LicenseContext{CustomerID: bref.CustomerID, BundleID: bref.BundleID, ProductIDs: bref.ProductIDs, LicenseIDs: bref.LicenseIDs}
+					err = nil
+				case license.ErrorCode(berr) != "":
+					err = berr // a bundle key that is not active
+				}
+			}
+			if code := license.ErrorCode(err); code != "" {
+				logging.FromContext(c.Request().Context()).Warn("license not active",
+					"license_key", logging.KeyPrefix(licKey), "remote_ip", c.RealIP(), "error", err)
+				return echo.NewHTTPError(http.StatusForbidden, map[string]string{
+					"error": err.Error(),
+					"code":  code,
+				})
+			}
+			if errors.Is(err, license.ErrNotFound) {
+				AuthFailed(c) // unknown key: counts toward the lockout
+			}
 			if err != nil {
+				logging.FromContext(c.Request().Context()).Warn("invalid license key",
+					"license_key", logging.KeyPrefix(licKey), "remote_ip", c.RealIP(), "error", err)
 				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid license key")
 			}
 
-			// Attach to context
+			// Attach to context, and the key prefix and IDs to every log line for the rest of the request
 			c.Set("license", lic)
+			attrs := []any{"license_key", logging.KeyPrefix(licKey), "customer_id", lic.CustomerID}
+			if lic.BundleID != 0 {
+				attrs = append(attrs, "bundle_id", lic.BundleID)
+			} else {
+				attrs = append(attrs, "license_id", lic.LicenseID, "product_id", lic.ProductID)
+			}
+			ctx := logging.With(c.Request().Context(), attrs...)
+			c.SetRequest(c.Request().WithContext(ctx))
 			return next(c)
 		}
 	}
 }
 
-// AdminAPIKeyAuth validates the X-API-Key header against ADMIN_API_KEY.
-// Used for ADMIN API endpoints. Returns 401 if authentication fails.
-func AdminAPIKeyAuth() echo.MiddlewareFunc {
+// AdminAPIKeyAuth validates the X-API-Key header against ADMIN_API_KEY or a
+// reseller API key. Used for ADMIN API endpoints. Returns 401 if authentication fails.
+// Reseller keys restrict the request to the reseller's customers: the reseller
+// scope is added to the request context and enforced by the admin services.
+func AdminAPIKeyAuth(db *sqlx.DB) echo.MiddlewareFunc {
 	adminKey := os.Getenv("ADMIN_API_KEY")
+	resellerSvc := reseller.NewService(db)
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -61,7 +140,22 @@ func AdminAPIKeyAuth() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, "Missing admin API key")
 			}
 
+			if strings.HasPrefix(key, reseller.KeyPrefix) {
+				r, err := resellerSvc.Authenticate(c.Request().Context(), key)
+				if err != nil {
+					logging.FromContext(c.Request().Context()).Warn("invalid reseller API key",
+						"key_prefix", logging.KeyPrefix(key), "remote_ip", c.RealIP(), "error", err)
+					return echo.NewHTTPError(http.StatusUnauthorized, "Invalid admin API key")
+				}
+
+				ctx := reseller.WithScope(c.Request().Context(), r.ResellerID)
+				ctx = logging.With(ctx, "reseller_id", r.ResellerID)
+				c.SetRequest(c.Request().WithContext(ctx))
+				return next(c)
+			}
+
 			if key != adminKey {
+				logging.FromContext(c.Request().Context()).Warn("invalid admin API key", "remote_ip", c.RealIP())
 				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid admin API key")
 			}
 
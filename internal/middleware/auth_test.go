@@ -1,7 +1,9 @@
 package middleware_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -9,7 +11,9 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"winsbygroup.com/regserver/internal/logging"
 	"winsbygroup.com/regserver/internal/middleware"
+	"winsbygroup.com/regserver/internal/reseller"
 	"winsbygroup.com/regserver/internal/testutil"
 )
 
@@ -32,6 +36,7 @@ func okHandler(c echo.Context) error {
 
 func TestAdminAPIKeyAuth(t *testing.T) {
 	const testAPIKey = "test-admin-key-12345"
+	db := testutil.NewTestDB(t)
 
 	t.Run("allows request with valid API key", func(t *testing.T) {
 		os.Setenv("ADMIN_API_KEY", testAPIKey)
@@ -40,7 +45,7 @@ func TestAdminAPIKeyAuth(t *testing.T) {
 		c, rec := newContext(http.MethodGet, "/api/admin/test")
 		c.Request().Header.Set("X-API-Key", testAPIKey)
 
-		mw := middleware.AdminAPIKeyAuth()
+		mw := middleware.AdminAPIKeyAuth(db)
 		handler := mw(okHandler)
 
 		err := handler(c)
@@ -59,7 +64,7 @@ func TestAdminAPIKeyAuth(t *testing.T) {
 		c, _ := newContext(http.MethodGet, "/api/admin/test")
 		c.Request().Header.Set("X-API-Key", "wrong-key")
 
-		mw := middleware.AdminAPIKeyAuth()
+		mw := middleware.AdminAPIKeyAuth(db)
 		handler := mw(okHandler)
 
 		err := handler(c)
@@ -82,7 +87,7 @@ func TestAdminAPIKeyAuth(t *testing.T) {
 		c, _ := newContext(http.MethodGet, "/api/admin/test")
 		// No X-API-Key header
 
-		mw := middleware.AdminAPIKeyAuth()
+		mw := middleware.AdminAPIKeyAuth(db)
 		handler := mw(okHandler)
 
 		err := handler(c)
@@ -104,7 +109,7 @@ func TestAdminAPIKeyAuth(t *testing.T) {
 		c, _ := newContext(http.MethodGet, "/api/admin/test")
 		c.Request().Header.Set("X-API-Key", "any-key")
 
-		mw := middleware.AdminAPIKeyAuth()
+		mw := middleware.AdminAPIKeyAuth(db)
 		handler := mw(okHandler)
 
 		err := handler(c)
@@ -119,6 +124,76 @@ func TestAdminAPIKeyAuth(t *testing.T) {
 			t.Errorf("expected status 401, got %d", httpErr.Code)
 		}
 	})
+
+	t.Run("reseller key adds reseller scope", func(t *testing.T) {
+		os.Setenv("ADMIN_API_KEY", testAPIKey)
+		defer os.Unsetenv("ADMIN_API_KEY")
+
+		r, key, err := reseller.NewService(db).Create(context.Background(), &reseller.Reseller{
+			ResellerName:   "Partner Ltd",
+			SeatAllocation: 10,
+		})
+		if err != nil {
+			t.Fatalf("create reseller: %v", err)
+		}
+
+		c, rec := newContext(http.MethodGet, "/api/admin/test")
+		c.Request().Header.Set("X-API-Key", key)
+
+		mw := middleware.AdminAPIKeyAuth(db)
+		handler := mw(func(c echo.Context) error {
+			id, ok := reseller.ScopeFromContext(c.Request().Context())
+			if !ok || id != r.ResellerID {
+				t.Errorf("expected reseller scope %d, got %d (%v)", r.ResellerID, id, ok)
+			}
+			return c.String(http.StatusOK, "OK")
+		})
+
+		if err := handler(c); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("admin key has no reseller scope", func(t *testing.T) {
+		os.Setenv("ADMIN_API_KEY", testAPIKey)
+		defer os.Unsetenv("ADMIN_API_KEY")
+
+		c, _ := newContext(http.MethodGet, "/api/admin/test")
+		c.Request().Header.Set("X-API-Key", testAPIKey)
+
+		mw := middleware.AdminAPIKeyAuth(db)
+		handler := mw(func(c echo.Context) error {
+			if _, ok := reseller.ScopeFromContext(c.Request().Context()); ok {
+				t.Error("expected no reseller scope for the admin key")
+			}
+			return c.String(http.StatusOK, "OK")
+		})
+
+		if err := handler(c); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("rejects unknown reseller key", func(t *testing.T) {
+		os.Setenv("ADMIN_API_KEY", testAPIKey)
+		defer os.Unsetenv("ADMIN_API_KEY")
+
+		c, _ := newContext(http.MethodGet, "/api/admin/test")
+		c.Request().Header.Set("X-API-Key", reseller.KeyPrefix+"0000000000000000000000000000000000000000")
+
+		mw := middleware.AdminAPIKeyAuth(db)
+		err := mw(okHandler)(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		if !ok {
+			t.Fatalf("expected echo.HTTPError, got %T", err)
+		}
+		if httpErr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", httpErr.Code)
+		}
+	})
 }
 
 // ============================================================================
@@ -348,7 +423,7 @@ func TestLicenseKeyAuth(t *testing.T) {
 			if !ok {
 				t.Error("license context not set")
 			}
-			if lic.CustomerID != 1 || lic.ProductID != 1 {
+			if lic.LicenseID != 1 || lic.CustomerID != 1 || lic.ProductID != 1 {
 				t.Errorf("unexpected license context: %+v", lic)
 			}
 			return c.String(http.StatusOK, "OK")
@@ -363,6 +438,31 @@ func TestLicenseKeyAuth(t *testing.T) {
 		}
 	})
 
+	t.Run("adds the key prefix and IDs to the request logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, _ := logging.New(&buf, "json", "info")
+		c, _ := newContext(http.MethodPost, "/api/v1/activate")
+		c.SetRequest(c.Request().WithContext(logging.WithLogger(c.Request().Context(), logger)))
+		c.Request().Header.Set("X-License-Key", "valid-reg-guid-123")
+
+		handler := middleware.LicenseKeyAuth(db)(func(c echo.Context) error {
+			logging.FromContext(c.Request().Context()).Info("request")
+			return nil
+		})
+		if err := handler(c); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		var line map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("expected a JSON log line, got %q: %v", buf.String(), err)
+		}
+		if line["license_key"] != logging.KeyPrefix("valid-reg-guid-123") || line["customer_id"] != float64(1) ||
+			line["product_id"] != float64(1) || line["license_id"] != float64(1) {
+			t.Errorf("expected key prefix, customer, license and product IDs, got %v", line)
+		}
+	})
+
 	t.Run("rejects request with invalid license key", func(t *testing.T) {
 		c, _ := newContext(http.MethodPost, "/api/v1/activate")
 		c.Request().Header.Set("X-License-Key", "invalid-key")
@@ -383,6 +483,143 @@ func TestLicenseKeyAuth(t *testing.T) {
 		}
 	})
 
+	t.Run("rejects suspended and revoked license keys with a code", func(t *testing.T) {
+		for _, status := range []string{"suspended", "revoked"} {
+			if _, err := db.Exec(`UPDATE license SET key_status = ? WHERE customer_id = 1 AND product_id = 1`, status); err != nil {
+				t.Fatalf("failed to update key status: %v", err)
+			}
+
+			c, _ := newContext(http.MethodPost, "/api/v1/activate")
+			c.Request().Header.Set("X-License-Key", "valid-reg-guid-123")
+
+			err := middleware.LicenseKeyAuth(db)(okHandler)(c)
+			httpErr, ok := err.(*echo.HTTPError)
+			if !ok {
+				t.Fatalf("%s: expected echo.HTTPError, got %T", status, err)
+			}
+			if httpErr.Code != http.StatusForbidden {
+				t.Errorf("%s: expected status 403, got %d", status, httpErr.Code)
+			}
+			msg, _ := httpErr.Message.(map[string]string)
+			if msg["code"] != "key_"+status {
+				t.Errorf("%s: unexpected code in %v", status, httpErr.Message)
+			}
+		}
+
+		if _, err := db.Exec(`UPDATE license SET key_status = 'active' WHERE customer_id = 1 AND product_id = 1`); err != nil {
+			t.Fatalf("failed to reset key status: %v", err)
+		}
+	})
+
+	t.Run("rejects replaced keys past their grace period with a code", func(t *testing.T) {
+		_, err := db.Exec(`INSERT INTO license_key_history (license_id, license_key, reason, replaced_at, grace_until)
+			VALUES (1, 'replaced-key-123', '', '2025-01-01 00:00:00', '2025-01-02 00:00:00')`)
+		if err != nil {
+			t.Fatalf("failed to insert key history: %v", err)
+		}
+
+		c, _ := newContext(http.MethodPost, "/api/v1/activate")
+		c.Request().Header.Set("X-License-Key", "replaced-key-123")
+
+		err = middleware.LicenseKeyAuth(db)(okHandler)(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		if !ok {
+			t.Fatalf("expected echo.HTTPError, got %T", err)
+		}
+		msg, _ := httpErr.Message.(map[string]string)
+		if httpErr.Code != http.StatusForbidden || msg["code"] != "key_replaced" {
+			t.Errorf("expected 403 key_replaced, got %d %v", httpErr.Code, httpErr.Message)
+		}
+	})
+
+	t.Run("rejects suspended and cancelled licenses with a code", func(t *testing.T) {
+		for _, status := range []string{"suspended", "cancelled"} {
+			if _, err := db.Exec(`UPDATE license SET status = ? WHERE customer_id = 1 AND product_id = 1`, status); err != nil {
+				t.Fatalf("failed to update license status: %v", err)
+			}
+
+			c, _ := newContext(http.MethodPost, "/api/v1/activate")
+			c.Request().Header.Set("X-License-Key", "valid-reg-guid-123")
+
+			err := middleware.LicenseKeyAuth(db)(okHandler)(c)
+			httpErr, ok := err.(*echo.HTTPError)
+			if !ok {
+				t.Fatalf("%s: expected echo.HTTPError, got %T", status, err)
+			}
+			if httpErr.Code != http.StatusForbidden {
+				t.Errorf("%s: expected status 403, got %d", status, httpErr.Code)
+			}
+			msg, _ := httpErr.Message.(map[string]string)
+			if msg["code"] != "license_"+status {
+				t.Errorf("%s: unexpected code in %v", status, httpErr.Message)
+			}
+		}
+
+		if _, err := db.Exec(`UPDATE license SET status = 'active' WHERE customer_id = 1 AND product_id = 1`); err != nil {
+			t.Fatalf("failed to reset license status: %v", err)
+		}
+	})
+
+	t.Run("resolves a bundle license key to its products", func(t *testing.T) {
+		_, err := db.Exec(`
+			INSERT INTO product (product_id, product_name, product_guid, latest_version, download_url)
+				VALUES (2, 'Second Product', 'second-guid', '1.0.0', 'http://example.com');
+			INSERT INTO bundle (bundle_id, bundle_name, created_at) VALUES (1, 'Suite', '2025-01-01 00:00:00');
+			INSERT INTO customer_bundle (customer_id, bundle_id, license_key, created_at)
+				VALUES (1, 1, 'bundle-key-123', '2025-01-01 00:00:00');
+			INSERT INTO license (customer_id, product_id, license_key, license_count, is_subscription, license_term,
+				start_date, expiration_date, max_product_version, bundle_id)
+				VALUES (1, 2, 'bundle-member-key', 5, 0, 12, '2025-01-01', '2099-12-31', '', 1);`)
+		if err != nil {
+			t.Fatalf("failed to insert bundle: %v", err)
+		}
+
+		c, rec := newContext(http.MethodPost, "/api/v1/activate")
+		c.Request().Header.Set("X-License-Key", "bundle-key-123")
+
+		err = middleware.LicenseKeyAuth(db)(func(c echo.Context) error {
+			lic, ok := c.Get("license").(middleware.LicenseContext)
+			if !ok {
+				t.Error("license context not set")
+			}
+			if lic.CustomerID != 1 || lic.BundleID != 1 || lic.ProductID != 0 {
+				t.Errorf("unexpected license context: %+v", lic)
+			}
+			if got := lic.Products(); len(got) != 1 || got[0] != 2 {
+				t.Errorf("expected bundle products [2], got %v", got)
+			}
+			if got := lic.LicenseFor(2); got != 2 {
+				t.Errorf("expected license 2 for product 2, got %d", got)
+			}
+			return c.String(http.StatusOK, "OK")
+		})(c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects a suspended bundle license key with a code", func(t *testing.T) {
+		if _, err := db.Exec(`UPDATE customer_bundle SET key_status = 'suspended' WHERE license_key = 'bundle-key-123'`); err != nil {
+			t.Fatalf("failed to update bundle key status: %v", err)
+		}
+
+		c, _ := newContext(http.MethodPost, "/api/v1/activate")
+		c.Request().Header.Set("X-License-Key", "bundle-key-123")
+
+		err := middleware.LicenseKeyAuth(db)(okHandler)(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		if !ok {
+			t.Fatalf("expected echo.HTTPError, got %T", err)
+		}
+		msg, _ := httpErr.Message.(map[string]string)
+		if httpErr.Code != http.StatusForbidden || msg["code"] != "key_suspended" {
+			t.Errorf("expected 403 key_suspended, got %d %v", httpErr.Code, httpErr.Message)
+		}
+	})
+
 	t.Run("rejects request with missing license key", func(t *testing.T) {
 		c, _ := newContext(http.MethodPost, "/api/v1/activate")
 		// No X-License-Key header
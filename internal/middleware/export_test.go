@@ -0,0 +1,8 @@
+package middleware
+
+// Len returns the number of links and sessions held by the store
+func (s *PortalStore) Len() (links, sessions int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.links), len(s.sessions)
+}
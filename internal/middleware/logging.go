@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"winsbygroup.com/regserver/internal/logging"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds client-supplied request IDs so they can't bloat log lines
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestID echoes the client's X-Request-ID header (or generates one) on the
+// response, and adds it to the request context and the request-scoped logger.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Request().Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.New().String()
+			}
+			c.Response().Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(c.Request().Context(), requestIDKey{}, id)
+			ctx = logging.With(ctx, "request_id", id)
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// GetRequestID retrieves the request ID from context. Returns "" if not set.
+func GetRequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// validRequestID accepts non-empty, printable ASCII IDs of reasonable length
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestLogger writes one structured log line per request using the
+// request-scoped logger, so attributes added by later middleware (license
+// key prefix, customer and product IDs) appear on the line. The path is
+// rebuilt from the matched route with secrets masked (see loggedPath), so
+// license keys and sign-in tokens in URLs never reach the log.
+// Server errors are logged at error level and client errors at warn.
+func RequestLogger() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := responseStatus(c, err)
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+
+			req := c.Request()
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("path", loggedPath(c)),
+				slog.String("route", c.Path()),
+				slog.Int("status", status),
+				slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+				slog.String("remote_ip", c.RealIP()),
+				slog.Int64("bytes_out", c.Response().Size),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			logging.FromContext(req.Context()).LogAttrs(req.Context(), level, "request", attrs...)
+
+			return err
+		}
+	}
+}
+
+// secretParams are route parameters whose values are masked in logged paths
+var secretParams = map[string]func(string) string{
+	"license_key": logging.KeyPrefix,
+	"token":       func(string) string { return "***" },
+}
+
+// loggedPath returns the request path rebuilt from the matched route, with
+// secret parameters masked and the query string dropped. Requests that
+// matched no route log no path, since it may hold a mistyped secret.
+func loggedPath(c echo.Context) string {
+	segs := strings.Split(c.Path(), "/")
+	for i, seg := range segs {
+		name, ok := strings.CutPrefix(seg, ":")
+		if !ok {
+			continue
+		}
+		value := c.Param(name)
+		if mask, ok := secretParams[name]; ok {
+			value = mask(value)
+		}
+		segs[i] = value
+	}
+	return strings.Join(segs, "/")
+}
+
+// responseStatus returns the status code a request was (or will be) answered with.
+// A returned error hasn't been written yet, so use the status it will be written with.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
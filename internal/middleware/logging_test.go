@@ -0,0 +1,141 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"winsbygroup.com/regserver/internal/logging"
+	"winsbygroup.com/regserver/internal/middleware"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Run("echoes client request ID", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "/test")
+		c.Request().Header.Set(middleware.RequestIDHeader, "client-id-123")
+
+		var got string
+		handler := middleware.RequestID()(func(c echo.Context) error {
+			got = middleware.GetRequestID(c.Request().Context())
+			return c.NoContent(http.StatusOK)
+		})
+		if err := handler(c); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if got != "client-id-123" {
+			t.Errorf("expected request ID in context, got %q", got)
+		}
+		if rec.Header().Get(middleware.RequestIDHeader) != "client-id-123" {
+			t.Errorf("expected request ID echoed, got %q", rec.Header().Get(middleware.RequestIDHeader))
+		}
+	})
+
+	t.Run("generates ID when missing or invalid", func(t *testing.T) {
+		for _, provided := range []string{"", "has spaces in it", strings.Repeat("x", 200)} {
+			c, rec := newContext(http.MethodGet, "/test")
+			if provided != "" {
+				c.Request().Header.Set(middleware.RequestIDHeader, provided)
+			}
+
+			if err := middleware.RequestID()(okHandler)(c); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			id := rec.Header().Get(middleware.RequestIDHeader)
+			if id == "" || id == provided {
+				t.Errorf("expected generated request ID for %q, got %q", provided, id)
+			}
+		}
+	})
+
+	t.Run("returns empty ID without middleware", func(t *testing.T) {
+		if id := middleware.GetRequestID(context.Background()); id != "" {
+			t.Errorf("expected empty request ID, got %q", id)
+		}
+	})
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := logging.New(&buf, "json", "info")
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := logging.WithLogger(c.Request().Context(), logger)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	})
+	e.Use(middleware.RequestID())
+	e.Use(middleware.RequestLogger())
+	e.GET("/test/log/:id", okHandler)
+	e.GET("/test/license/:license_key", okHandler)
+	e.GET("/test/auth/:token", okHandler)
+	e.GET("/test/log-fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "not here")
+	})
+
+	readLine := func(t *testing.T) map[string]any {
+		t.Helper()
+		var line map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("expected one JSON log line, got %q: %v", buf.String(), err)
+		}
+		buf.Reset()
+		return line
+	}
+
+	t.Run("logs request with request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test/log/7", nil)
+		req.Header.Set(middleware.RequestIDHeader, "req-42")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		line := readLine(t)
+		if line["level"] != "INFO" || line["request_id"] != "req-42" {
+			t.Errorf("unexpected log line: %v", line)
+		}
+		if line["route"] != "/test/log/:id" || line["status"] != float64(http.StatusOK) {
+			t.Errorf("expected route and status in log line, got %v", line)
+		}
+		if line["path"] != "/test/log/7" {
+			t.Errorf("expected path /test/log/7, got %v", line["path"])
+		}
+	})
+
+	t.Run("masks license keys and tokens in the path", func(t *testing.T) {
+		const key = "0123456789abcdef-SECRET"
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/license/"+key+"?productGuid="+key, nil))
+		raw := buf.String()
+		line := readLine(t)
+		if strings.Contains(raw, "SECRET") {
+			t.Errorf("expected the license key masked, got %s", raw)
+		}
+		if line["path"] != "/test/license/"+logging.KeyPrefix(key) {
+			t.Errorf("expected the key prefix in the path, got %v", line["path"])
+		}
+
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/auth/sign-in-token-SECRET", nil))
+		raw = buf.String()
+		line = readLine(t)
+		if strings.Contains(raw, "SECRET") || line["path"] != "/test/auth/***" {
+			t.Errorf("expected the token masked, got %s", raw)
+		}
+	})
+
+	t.Run("logs client errors at warn", func(t *testing.T) {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/log-fail", nil))
+
+		line := readLine(t)
+		if line["level"] != "WARN" || line["status"] != float64(http.StatusNotFound) {
+			t.Errorf("unexpected log line: %v", line)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"winsbygroup.com/regserver/internal/metrics"
+)
+
+// Metrics records request counts and latencies per route (the registered path, not the raw URL)
+// and status code for the /metrics endpoint.
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := responseStatus(c, err)
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			labels := []string{c.Request().Method, route, strconv.Itoa(status)}
+			metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// MetricsAuth protects the /metrics endpoint with a bearer token
+// (Authorization: Bearer <token>). An empty token leaves the endpoint open.
+func MetricsAuth(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if token == "" {
+				return next(c)
+			}
+
+			auth := c.Request().Header.Get("Authorization")
+			provided, ok := strings.CutPrefix(auth, "Bearer ")
+			if !ok || !constantEqual(provided, token) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid metrics token")
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"winsbygroup.com/regserver/internal/metrics"
+	"winsbygroup.com/regserver/internal/middleware"
+)
+
+func TestMetrics(t *testing.T) {
+	e := echo.New()
+	e.Use(middleware.Metrics())
+	e.GET("/test/metrics/:id", okHandler)
+	e.GET("/test/metrics-fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "not here")
+	})
+	e.GET("/test/metrics-error", func(c echo.Context) error {
+		return errors.New("boom")
+	})
+
+	for _, path := range []string{"/test/metrics/1", "/test/metrics/2", "/test/metrics-fail", "/test/metrics-error"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	t.Run("counts by route template", func(t *testing.T) {
+		if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/test/metrics/:id", "200")); got != 2 {
+			t.Errorf("expected 2 requests, got %v", got)
+		}
+		var m dto.Metric
+		if err := metrics.HTTPRequestDuration.WithLabelValues("GET", "/test/metrics/:id", "200").(prometheus.Metric).Write(&m); err != nil {
+			t.Fatalf("read histogram: %v", err)
+		}
+		if got := m.GetHistogram().GetSampleCount(); got != 2 {
+			t.Errorf("expected 2 latency observations, got %d", got)
+		}
+	})
+
+	t.Run("uses error status codes", func(t *testing.T) {
+		if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/test/metrics-fail", "404")); got != 1 {
+			t.Errorf("expected 1 not found request, got %v", got)
+		}
+		if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/test/metrics-error", "500")); got != 1 {
+			t.Errorf("expected 1 failed request, got %v", got)
+		}
+	})
+}
+
+func TestMetricsAuth(t *testing.T) {
+	t.Run("open when no token configured", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "/metrics")
+		if err := middleware.MetricsAuth("")(okHandler)(c); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("allows valid bearer token", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "/metrics")
+		c.Request().Header.Set("Authorization", "Bearer scrape-secret")
+		if err := middleware.MetricsAuth("scrape-secret")(okHandler)(c); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects missing or wrong token", func(t *testing.T) {
+		for _, header := range []string{"", "Bearer wrong", "scrape-secret"} {
+			c, _ := newContext(http.MethodGet, "/metrics")
+			if header != "" {
+				c.Request().Header.Set("Authorization", header)
+			}
+			err := middleware.MetricsAuth("scrape-secret")(okHandler)(c)
+			he, ok := err.(*echo.HTTPError)
+			if !ok || he.Code != http.StatusUnauthorized {
+				t.Errorf("header %q: expected 401, got %v", header, err)
+			}
+		}
+	})
+}
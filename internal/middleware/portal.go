@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PortalCookieName = "regportal_session"
+
+	portalLinkTTL    = 15 * time.Minute
+	portalSessionTTL = 12 * time.Hour
+
+	// portalMaxLinks is how many unused sign-in links a customer can have;
+	// a new link replaces the oldest
+	portalMaxLinks = 3
+)
+
+// PortalSession is a signed-in customer portal session
+type PortalSession struct {
+	CustomerID int64
+	ExpiresAt  time.Time
+}
+
+// PortalStore holds customer portal sign-in links and sessions in memory.
+// Links are single use; both are lost on server restart. Expired links and
+// sessions are swept as new ones are created.
+type PortalStore struct {
+	mu        sync.Mutex
+	links     map[string]PortalSession
+	sessions  map[string]PortalSession
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewPortalStore creates an empty portal store
+func NewPortalStore() *PortalStore {
+	return &PortalStore{
+		links:    make(map[string]PortalSession),
+		sessions: make(map[string]PortalSession),
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the time source (for tests)
+func (s *PortalStore) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// CreateLink creates a sign-in token for a customer, valid for 15 minutes.
+// Once the customer has portalMaxLinks unused links, the oldest is revoked.
+func (s *PortalStore) CreateLink(customerID int64) string {
+	token := uuid.NewString()
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	var oldest string
+	count := 0
+	for t, link := range s.links {
+		if link.CustomerID != customerID {
+			continue
+		}
+		count++
+		if oldest == "" || link.ExpiresAt.Before(s.links[oldest].ExpiresAt) {
+			oldest = t
+		}
+	}
+	if count >= portalMaxLinks {
+		delete(s.links, oldest)
+	}
+
+	s.links[token] = PortalSession{CustomerID: customerID, ExpiresAt: now.Add(portalLinkTTL)}
+	return token
+}
+
+// ConsumeLink exchanges a sign-in token for a new session ID. The token is
+// removed whether or not it is still valid.
+func (s *PortalStore) ConsumeLink(token string) (string, bool) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	link, ok := s.links[token]
+	delete(s.links, token)
+	if !ok || now.After(link.ExpiresAt) {
+		return "", false
+	}
+
+	id := uuid.NewString()
+	s.sessions[id] = PortalSession{CustomerID: link.CustomerID, ExpiresAt: now.Add(portalSessionTTL)}
+	return id, true
+}
+
+// GetSession retrieves a session by ID. Returns false if not found or expired.
+func (s *PortalStore) GetSession(id string) (PortalSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return PortalSession{}, false
+	}
+	if s.now().After(sess.ExpiresAt) {
+		delete(s.sessions, id)
+		return PortalSession{}, false
+	}
+	return sess, true
+}
+
+// DeleteSession removes a session by ID
+func (s *PortalStore) DeleteSession(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
+
+// sweep drops expired links and sessions. Called with s.mu held; runs at most
+// once a minute.
+func (s *PortalStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for _, m := range []map[string]PortalSession{s.links, s.sessions} {
+		for id, v := range m {
+			if now.After(v.ExpiresAt) {
+				delete(m, id)
+			}
+		}
+	}
+}
+
+// PortalAuth requires a customer portal session cookie and stores the
+// customer ID in the context. Redirects to the portal login otherwise.
+func PortalAuth(store *PortalStore) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Path()
+
+			// Public routes: login and sign-in links
+			if path == "/portal/login" || strings.HasPrefix(path, "/portal/auth/") {
+				return next(c)
+			}
+
+			if cookie, err := c.Cookie(PortalCookieName); err == nil && cookie.Value != "" {
+				if sess, ok := store.GetSession(cookie.Value); ok {
+					c.Set("portalCustomerID", sess.CustomerID)
+					return next(c)
+				}
+			}
+
+			return c.Redirect(http.StatusFound, "/portal/login")
+		}
+	}
+}
+
+// PortalCustomerID returns the customer ID set by PortalAuth (0 if none)
+func PortalCustomerID(c echo.Context) int64 {
+	id, _ := c.Get("portalCustomerID").(int64)
+	return id
+}
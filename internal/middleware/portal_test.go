@@ -0,0 +1,149 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"winsbygroup.com/regserver/internal/middleware"
+)
+
+func TestPortalStore(t *testing.T) {
+	now := time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC)
+	store := middleware.NewPortalStore()
+	store.SetClock(func() time.Time { return now })
+
+	t.Run("link exchanges once for a session", func(t *testing.T) {
+		token := store.CreateLink(42)
+
+		sessionID, ok := store.ConsumeLink(token)
+		if !ok {
+			t.Fatal("expected link to be accepted")
+		}
+		sess, ok := store.GetSession(sessionID)
+		if !ok || sess.CustomerID != 42 {
+			t.Errorf("expected session for customer 42, got %+v (%v)", sess, ok)
+		}
+
+		if _, ok := store.ConsumeLink(token); ok {
+			t.Error("expected link to be single use")
+		}
+	})
+
+	t.Run("expired link is rejected", func(t *testing.T) {
+		token := store.CreateLink(42)
+		now = now.Add(16 * time.Minute)
+
+		if _, ok := store.ConsumeLink(token); ok {
+			t.Error("expected expired link to be rejected")
+		}
+	})
+
+	t.Run("session expires and can be deleted", func(t *testing.T) {
+		sessionID, _ := store.ConsumeLink(store.CreateLink(7))
+
+		store.DeleteSession(sessionID)
+		if _, ok := store.GetSession(sessionID); ok {
+			t.Error("expected deleted session to be gone")
+		}
+
+		sessionID, _ = store.ConsumeLink(store.CreateLink(7))
+		now = now.Add(13 * time.Hour)
+		if _, ok := store.GetSession(sessionID); ok {
+			t.Error("expected session to expire")
+		}
+	})
+
+	t.Run("unknown link is rejected", func(t *testing.T) {
+		if _, ok := store.ConsumeLink("not-a-token"); ok {
+			t.Error("expected unknown link to be rejected")
+		}
+	})
+
+	t.Run("new links revoke the oldest of a customer", func(t *testing.T) {
+		var tokens []string
+		for i := 0; i < 5; i++ {
+			tokens = append(tokens, store.CreateLink(11))
+			now = now.Add(time.Second)
+		}
+		other := store.CreateLink(12)
+
+		for i, token := range tokens {
+			_, ok := store.ConsumeLink(token)
+			if want := i >= 2; ok != want {
+				t.Errorf("link %d: expected accepted = %v, got %v", i, want, ok)
+			}
+		}
+		if _, ok := store.ConsumeLink(other); !ok {
+			t.Error("expected another customer's link to be kept")
+		}
+	})
+
+	t.Run("expired links and sessions are swept", func(t *testing.T) {
+		store.CreateLink(21)
+		store.ConsumeLink(store.CreateLink(22))
+		now = now.Add(13 * time.Hour)
+
+		store.CreateLink(23)
+		if links, sessions := store.Len(); links != 1 || sessions != 0 {
+			t.Errorf("expected only the new link to remain, got %d links and %d sessions", links, sessions)
+		}
+	})
+}
+
+func TestPortalAuth(t *testing.T) {
+	store := middleware.NewPortalStore()
+
+	run := func(path, cookie string) (*httptest.ResponseRecorder, int64) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: middleware.PortalCookieName, Value: cookie})
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath(path)
+
+		var customerID int64
+		handler := middleware.PortalAuth(store)(func(c echo.Context) error {
+			customerID = middleware.PortalCustomerID(c)
+			return c.String(http.StatusOK, "OK")
+		})
+		if err := handler(c); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return rec, customerID
+	}
+
+	t.Run("allows login and sign-in links without a session", func(t *testing.T) {
+		for _, path := range []string{"/portal/login", "/portal/auth/:token"} {
+			if rec, _ := run(path, ""); rec.Code != http.StatusOK {
+				t.Errorf("%s: expected status 200, got %d", path, rec.Code)
+			}
+		}
+	})
+
+	t.Run("redirects to login without a valid session", func(t *testing.T) {
+		for _, cookie := range []string{"", "bogus"} {
+			rec, _ := run("/portal/", cookie)
+			if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/portal/login" {
+				t.Errorf("cookie %q: expected redirect to /portal/login, got %d %s", cookie, rec.Code, rec.Header().Get("Location"))
+			}
+		}
+	})
+
+	t.Run("sets customer ID from session", func(t *testing.T) {
+		sessionID, _ := store.ConsumeLink(store.CreateLink(99))
+
+		rec, customerID := run("/portal/", sessionID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		if customerID != 99 {
+			t.Errorf("expected customer 99, got %d", customerID)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+
+	"winsbygroup.com/regserver/internal/config"
+	"winsbygroup.com/regserver/internal/logging"
+	"winsbygroup.com/regserver/internal/metrics"
+)
+
+// idleTTL is how long an unused bucket or failure record is kept
+const idleTTL = time.Hour
+
+// RateLimiter throttles requests with token buckets per client IP and per
+// license key, and locks out clients after repeated authentication failures.
+// State is held in memory and is lost on server restart.
+type RateLimiter struct {
+	cfg config.RateLimit
+	now func() time.Time
+
+	mu         sync.Mutex
+	ips        map[string]*bucket
+	keys       map[string]*bucket
+	failures   map[string]*failureRecord // by client IP and key prefix
+	ipFailures map[string]*failureRecord // by client IP, counting distinct failed key prefixes
+	lastSweep  time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type failureRecord struct {
+	count       int
+	lockedUntil time.Time
+	lastFailure time.Time
+	keys        map[string]bool // ipFailures: key prefixes that failed
+}
+
+type authFailedKey struct{}
+
+// AuthFailed marks the request as a failed authentication attempt: an unknown
+// license key, a wrong admin key or an invalid sign-in token. Only marked
+// requests count toward a lockout. Valid keys that are rejected for another
+// reason (suspended, replaced, machine not registered) are not marked, so
+// clients that use them cannot lock out others behind the same IP.
+func AuthFailed(c echo.Context) {
+	c.Set("auth_failed", authFailedKey{})
+}
+
+func authFailed(c echo.Context) bool {
+	_, ok := c.Get("auth_failed").(authFailedKey)
+	return ok
+}
+
+// NewRateLimiter creates a rate limiter with the given configuration
+func NewRateLimiter(cfg config.RateLimit) *RateLimiter {
+	return &RateLimiter{
+		cfg:        cfg,
+		now:        time.Now,
+		ips:        make(map[string]*bucket),
+		keys:       make(map[string]*bucket),
+		failures:   make(map[string]*failureRecord),
+		ipFailures: make(map[string]*failureRecord),
+	}
+}
+
+// SetClock replaces the limiter's time source (for tests)
+func (rl *RateLimiter) SetClock(now func() time.Time) {
+	rl.now = now
+}
+
+// Guard applies, in order: progressive lockout of the client, the per-IP
+// bucket and the per-license-key bucket (when the request carries a key in the
+// X-License-Key header or :license_key path parameter).
+//
+// Requests marked with AuthFailed count as failures of the client IP and key
+// prefix, which is locked out after LockoutThreshold of them; a successful
+// request with the same key clears its count. Failures with
+// LockoutThreshold different keys lock out the whole IP, which successes do
+// not clear, so guessing keys cannot be hidden among valid requests.
+// Limited requests get 429 with a Retry-After header (in seconds).
+func (rl *RateLimiter) Guard() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !rl.cfg.Enabled {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			ip := c.RealIP()
+			key := c.Request().Header.Get("X-License-Key")
+			if key == "" {
+				key = c.Param("license_key")
+			}
+
+			if wait := rl.lockedFor(ip, key); wait > 0 {
+				return rl.reject(c, "lockout", key, wait)
+			}
+			if wait := rl.take(rl.ips, ip, rl.cfg.IPRate, rl.cfg.IPBurst); wait > 0 {
+				return rl.reject(c, "ip", key, wait)
+			}
+			if key != "" {
+				if wait := rl.take(rl.keys, key, rl.cfg.KeyRate, rl.cfg.KeyBurst); wait > 0 {
+					return rl.reject(c, "license_key", key, wait)
+				}
+			}
+
+			err := next(c)
+
+			switch {
+			case authFailed(c):
+				rl.recordFailure(c, ip, key)
+			case responseStatus(c, err) < http.StatusBadRequest:
+				rl.clearFailures(ip, key)
+			}
+			return err
+		}
+	}
+}
+
+// take removes a token from the bucket for id, returning how long the caller
+// must wait when none is available (0 if the request is allowed)
+func (rl *RateLimiter) take(buckets map[string]*bucket, id string, r float64, burst int) time.Duration {
+	now := rl.now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.sweep(now)
+
+	b, ok := buckets[id]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(r), burst)}
+		buckets[id] = b
+	}
+	b.lastSeen = now
+
+	res := b.limiter.ReserveN(now, 1)
+	if !res.OK() {
+		return rl.cfg.LockoutMax
+	}
+	if wait := res.DelayFrom(now); wait > 0 {
+		res.CancelAt(now) // don't consume a token for a rejected request
+		return wait
+	}
+	return 0
+}
+
+// failureID identifies the failures of a client IP with a license key (by
+// its prefix, so a guessed key's variations count together)
+func failureID(ip, key string) string {
+	return ip + " " + logging.KeyPrefix(strings.ToLower(key))
+}
+
+// lockedFor returns the remaining lockout for ip and key (0 if not locked out)
+func (rl *RateLimiter) lockedFor(ip, key string) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	var wait time.Duration
+	for _, f := range []*failureRecord{rl.failures[failureID(ip, key)], rl.ipFailures[ip]} {
+		if f != nil && f.lockedUntil.Sub(now) > wait {
+			wait = f.lockedUntil.Sub(now)
+		}
+	}
+	return wait
+}
+
+// recordFailure counts an authentication failure for ip and key, and for ip
+// when the key is new to it, and starts a lockout once either count reaches
+// the threshold. Each failure past the threshold doubles the lockout, up to
+// LockoutMax.
+func (rl *RateLimiter) recordFailure(c echo.Context, ip, key string) {
+	now := rl.now()
+
+	rl.mu.Lock()
+	f, ok := rl.failures[failureID(ip, key)]
+	if !ok {
+		f = &failureRecord{}
+		rl.failures[failureID(ip, key)] = f
+	}
+	f.count++
+	f.lastFailure = now
+	lockout, count, scope := rl.lock(f, now), f.count, "license_key"
+
+	if key != "" {
+		g, ok := rl.ipFailures[ip]
+		if !ok {
+			g = &failureRecord{keys: make(map[string]bool)}
+			rl.ipFailures[ip] = g
+		}
+		g.lastFailure = now
+		if prefix := logging.KeyPrefix(strings.ToLower(key)); !g.keys[prefix] {
+			g.keys[prefix] = true
+			g.count++
+			if d := rl.lock(g, now); d > lockout {
+				lockout, count, scope = d, g.count, "ip"
+			}
+		}
+	}
+	rl.mu.Unlock()
+
+	if lockout > 0 {
+		metrics.Lockouts.Inc()
+		logging.FromContext(c.Request().Context()).Warn("client locked out",
+			"scope", scope,
+			"remote_ip", ip,
+			"license_key", logging.KeyPrefix(key),
+			"failures", count,
+			"lockout", lockout.String(),
+		)
+	}
+}
+
+// lock starts a lockout for f once its count reaches the threshold, returning
+// its length (0 below the threshold). Called with rl.mu held.
+func (rl *RateLimiter) lock(f *failureRecord, now time.Time) time.Duration {
+	if rl.cfg.LockoutThreshold <= 0 || f.count < rl.cfg.LockoutThreshold {
+		return 0
+	}
+	lockout := lockoutDuration(rl.cfg.LockoutDuration, rl.cfg.LockoutMax, f.count-rl.cfg.LockoutThreshold)
+	f.lockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// clearFailures forgets the failures of ip with key after a successful
+// request. Failures counted against the IP as a whole are kept until they
+// expire.
+func (rl *RateLimiter) clearFailures(ip, key string) {
+	rl.mu.Lock()
+	delete(rl.failures, failureID(ip, key))
+	rl.mu.Unlock()
+}
+
+// lockoutDuration doubles base for each step past the threshold, capped at max
+func lockoutDuration(base, max time.Duration, step int) time.Duration {
+	d := time.Duration(float64(base) * math.Pow(2, float64(step)))
+	if d > max || d <= 0 {
+		return max
+	}
+	return d
+}
+
+// sweep drops buckets and failure records that have been idle for idleTTL.
+// Called with rl.mu held; runs at most once a minute.
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < time.Minute {
+		return
+	}
+	rl.lastSweep = now
+
+	for _, m := range []map[string]*bucket{rl.ips, rl.keys} {
+		for id, b := range m {
+			if now.Sub(b.lastSeen) > idleTTL {
+				delete(m, id)
+			}
+		}
+	}
+	for _, m := range []map[string]*failureRecord{rl.failures, rl.ipFailures} {
+		for id, f := range m {
+			if now.After(f.lockedUntil) && now.Sub(f.lastFailure) > idleTTL {
+				delete(m, id)
+			}
+		}
+	}
+}
+
+// reject answers a limited request with 429 and a Retry-After header
+func (rl *RateLimiter) reject(c echo.Context, scope, key string, wait time.Duration) error {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	metrics.RateLimited.WithLabelValues(scope).Inc()
+	logging.FromContext(c.Request().Context()).Warn("request rate limited",
+		"scope", scope,
+		"remote_ip", c.RealIP(),
+		"license_key", logging.KeyPrefix(key),
+		"retry_after", retryAfter,
+	)
+
+	c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	return echo.NewHTTPError(http.StatusTooManyRequests, "Too many requests, retry later")
+}
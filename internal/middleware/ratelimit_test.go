@@ -0,0 +1,290 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"winsbygroup.com/regserver/internal/config"
+	"winsbygroup.com/regserver/internal/metrics"
+	"winsbygroup.com/regserver/internal/middleware"
+)
+
+// fakeClock is a settable time source for the rate limiter
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newLimiter(cfg config.RateLimit) (*middleware.RateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	rl := middleware.NewRateLimiter(cfg)
+	rl.SetClock(clock.now)
+	return rl, clock
+}
+
+// newLimitedServer routes /ok (200), /denied (401, unknown key),
+// /missing/:license_key (404, unknown key) and /unregistered (404 for a valid
+// key, not an authentication failure) through the guard
+func newLimitedServer(rl *middleware.RateLimiter) *echo.Echo {
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	guard := rl.Guard()
+	e.GET("/ok", okHandler, guard)
+	e.GET("/denied", func(c echo.Context) error {
+		middleware.AuthFailed(c)
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid license key")
+	}, guard)
+	e.GET("/missing/:license_key", func(c echo.Context) error {
+		middleware.AuthFailed(c)
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "license not found"})
+	}, guard)
+	e.GET("/unregistered", func(c echo.Context) error {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "machine not registered"})
+	}, guard)
+	return e
+}
+
+func send(e *echo.Echo, path, ip, licenseKey string) *httptest.ResponseRecorder {
+	return sendVia(e, path, ip, "", licenseKey)
+}
+
+// sendVia sends a request from ip with the given X-Forwarded-For header
+func sendVia(e *echo.Echo, path, ip, forwardedFor, licenseKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ip + ":5000"
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	if licenseKey != "" {
+		req.Header.Set("X-License-Key", licenseKey)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("limits requests per IP", func(t *testing.T) {
+		rl, clock := newLimiter(config.RateLimit{Enabled: true, IPRate: 1, IPBurst: 3, KeyRate: 100, KeyBurst: 100})
+		e := newLimitedServer(rl)
+		before := testutil.ToFloat64(metrics.RateLimited.WithLabelValues("ip"))
+
+		for i := 0; i < 3; i++ {
+			if rec := send(e, "/ok", "10.0.0.1", ""); rec.Code != http.StatusOK {
+				t.Fatalf("request %d: expected 200, got %d", i+1, rec.Code)
+			}
+		}
+
+		rec := send(e, "/ok", "10.0.0.1", "")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429 after burst, got %d", rec.Code)
+		}
+		if rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+		}
+		if testutil.ToFloat64(metrics.RateLimited.WithLabelValues("ip")) != before+1 {
+			t.Errorf("expected rate limited metric to increase")
+		}
+
+		// Other IPs have their own bucket
+		if rec := send(e, "/ok", "10.0.0.2", ""); rec.Code != http.StatusOK {
+			t.Errorf("expected other IP to be allowed, got %d", rec.Code)
+		}
+
+		// Tokens refill over time
+		clock.advance(time.Second)
+		if rec := send(e, "/ok", "10.0.0.1", ""); rec.Code != http.StatusOK {
+			t.Errorf("expected request after refill to be allowed, got %d", rec.Code)
+		}
+	})
+
+	t.Run("limits requests per license key", func(t *testing.T) {
+		rl, _ := newLimiter(config.RateLimit{Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 0.1, KeyBurst: 2})
+		e := newLimitedServer(rl)
+
+		// Same key from different IPs shares a bucket
+		send(e, "/ok", "10.0.1.1", "KEY-1")
+		send(e, "/ok", "10.0.1.2", "KEY-1")
+		rec := send(e, "/ok", "10.0.1.3", "KEY-1")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429 for key over limit, got %d", rec.Code)
+		}
+		if rec.Header().Get("Retry-After") != "10" {
+			t.Errorf("expected Retry-After 10, got %q", rec.Header().Get("Retry-After"))
+		}
+
+		if rec := send(e, "/ok", "10.0.1.3", "KEY-2"); rec.Code != http.StatusOK {
+			t.Errorf("expected other key to be allowed, got %d", rec.Code)
+		}
+	})
+
+	t.Run("locks out after repeated failures", func(t *testing.T) {
+		rl, clock := newLimiter(config.RateLimit{
+			Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 100, KeyBurst: 100,
+			LockoutThreshold: 3, LockoutDuration: time.Minute, LockoutMax: 3 * time.Minute,
+		})
+		e := newLimitedServer(rl)
+
+		send(e, "/denied", "10.0.2.1", "GUESS-1")
+		send(e, "/missing/GUESS-2", "10.0.2.1", "")
+		if rec := send(e, "/denied", "10.0.2.1", "GUESS-3"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected the failing request itself to get 401, got %d", rec.Code)
+		}
+
+		rec := send(e, "/ok", "10.0.2.1", "")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected lockout after 3 failures, got %d", rec.Code)
+		}
+		if rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("expected Retry-After 60, got %q", rec.Header().Get("Retry-After"))
+		}
+
+		// Lockout expires, and the next failure doubles it
+		clock.advance(time.Minute)
+		send(e, "/denied", "10.0.2.1", "GUESS-4")
+		if rec := send(e, "/ok", "10.0.2.1", ""); rec.Header().Get("Retry-After") != "120" {
+			t.Errorf("expected doubled lockout (Retry-After 120), got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+		}
+
+		// ... up to the maximum
+		clock.advance(2 * time.Minute)
+		send(e, "/denied", "10.0.2.1", "GUESS-5")
+		if rec := send(e, "/ok", "10.0.2.1", ""); rec.Header().Get("Retry-After") != "180" {
+			t.Errorf("expected capped lockout (Retry-After 180), got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("success clears failures", func(t *testing.T) {
+		rl, _ := newLimiter(config.RateLimit{
+			Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 100, KeyBurst: 100,
+			LockoutThreshold: 2, LockoutDuration: time.Minute, LockoutMax: time.Hour,
+		})
+		e := newLimitedServer(rl)
+
+		send(e, "/denied", "10.0.3.1", "")
+		send(e, "/ok", "10.0.3.1", "")
+		send(e, "/denied", "10.0.3.1", "")
+
+		if rec := send(e, "/ok", "10.0.3.1", ""); rec.Code != http.StatusOK {
+			t.Errorf("expected no lockout after intervening success, got %d", rec.Code)
+		}
+	})
+
+	t.Run("valid key with an unregistered machine never locks out", func(t *testing.T) {
+		rl, _ := newLimiter(config.RateLimit{
+			Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 100, KeyBurst: 100,
+			LockoutThreshold: 2, LockoutDuration: time.Minute, LockoutMax: time.Hour,
+		})
+		e := newLimitedServer(rl)
+
+		for i := 0; i < 5; i++ {
+			if rec := send(e, "/unregistered", "10.0.5.1", "VALID-KEY"); rec.Code != http.StatusNotFound {
+				t.Fatalf("request %d: expected 404, got %d", i+1, rec.Code)
+			}
+		}
+		if rec := send(e, "/ok", "10.0.5.1", "VALID-KEY"); rec.Code != http.StatusOK {
+			t.Errorf("expected no lockout, got %d", rec.Code)
+		}
+	})
+
+	t.Run("failures with one key lock out only that key", func(t *testing.T) {
+		rl, _ := newLimiter(config.RateLimit{
+			Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 100, KeyBurst: 100,
+			LockoutThreshold: 3, LockoutDuration: time.Minute, LockoutMax: time.Hour,
+		})
+		e := newLimitedServer(rl)
+
+		for i := 0; i < 3; i++ {
+			send(e, "/denied", "10.0.6.1", "MISTYPED-KEY")
+		}
+		if rec := send(e, "/ok", "10.0.6.1", "MISTYPED-KEY"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected the failing key to be locked out, got %d", rec.Code)
+		}
+		if rec := send(e, "/ok", "10.0.6.1", "OTHER-KEY"); rec.Code != http.StatusOK {
+			t.Errorf("expected another key from the same IP to be allowed, got %d", rec.Code)
+		}
+	})
+
+	t.Run("successes do not reset guessing keys", func(t *testing.T) {
+		rl, _ := newLimiter(config.RateLimit{
+			Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 100, KeyBurst: 100,
+			LockoutThreshold: 3, LockoutDuration: time.Minute, LockoutMax: time.Hour,
+		})
+		e := newLimitedServer(rl)
+
+		for _, guess := range []string{"GUESS-A", "GUESS-B", "GUESS-C"} {
+			send(e, "/ok", "10.0.7.1", "VALID-KEY")
+			send(e, "/denied", "10.0.7.1", guess)
+		}
+		if rec := send(e, "/ok", "10.0.7.1", "VALID-KEY"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected the IP to be locked out despite successes, got %d", rec.Code)
+		}
+	})
+
+	t.Run("spoofed X-Forwarded-For is ignored", func(t *testing.T) {
+		rl, _ := newLimiter(config.RateLimit{
+			Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 100, KeyBurst: 100,
+			LockoutThreshold: 3, LockoutDuration: time.Minute, LockoutMax: time.Hour,
+		})
+		e := newLimitedServer(rl)
+
+		// The attacker claims to be the victim while guessing keys
+		for _, guess := range []string{"GUESS-A", "GUESS-B", "GUESS-C"} {
+			sendVia(e, "/denied", "10.0.8.1", "10.0.8.2", guess)
+		}
+		if rec := send(e, "/ok", "10.0.8.2", "VALID-KEY"); rec.Code != http.StatusOK {
+			t.Errorf("expected the spoofed IP not to be locked out, got %d", rec.Code)
+		}
+		// ... and cannot escape the lockout by claiming another address
+		if rec := sendVia(e, "/ok", "10.0.8.1", "10.0.8.9", "VALID-KEY"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected the attacker to stay locked out, got %d", rec.Code)
+		}
+	})
+
+	t.Run("X-Forwarded-For from a trusted proxy gives the client IP", func(t *testing.T) {
+		rl, _ := newLimiter(config.RateLimit{
+			Enabled: true, IPRate: 100, IPBurst: 100, KeyRate: 100, KeyBurst: 100,
+			LockoutThreshold: 3, LockoutDuration: time.Minute, LockoutMax: time.Hour,
+		})
+		e := newLimitedServer(rl)
+		extractor, err := middleware.IPExtractor([]string{"127.0.0.1", "::1"})
+		if err != nil {
+			t.Fatalf("IPExtractor: %v", err)
+		}
+		e.IPExtractor = extractor
+
+		// A client-supplied entry left of the one the proxy added is not trusted
+		for _, guess := range []string{"GUESS-A", "GUESS-B", "GUESS-C"} {
+			sendVia(e, "/denied", "127.0.0.1", "10.0.9.2, 10.0.9.1", guess)
+		}
+		if rec := sendVia(e, "/ok", "127.0.0.1", "10.0.9.1", "VALID-KEY"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected the client behind the proxy to be locked out, got %d", rec.Code)
+		}
+		for _, xff := range []string{"10.0.9.2", "10.0.9.3"} {
+			if rec := sendVia(e, "/ok", "127.0.0.1", xff, "VALID-KEY"); rec.Code != http.StatusOK {
+				t.Errorf("expected other clients behind the proxy (%s) to be allowed, got %d", xff, rec.Code)
+			}
+		}
+
+		if _, err := middleware.IPExtractor([]string{"not-an-ip"}); err == nil {
+			t.Error("expected an error for an invalid trusted proxy")
+		}
+	})
+
+	t.Run("disabled passes everything through", func(t *testing.T) {
+		rl, _ := newLimiter(config.RateLimit{Enabled: false, IPRate: 1, IPBurst: 1, LockoutThreshold: 1})
+		e := newLimitedServer(rl)
+
+		for i := 0; i < 5; i++ {
+			send(e, "/denied", "10.0.4.1", "")
+		}
+		if rec := send(e, "/ok", "10.0.4.1", ""); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 with rate limiting disabled, got %d", rec.Code)
+		}
+	})
+}
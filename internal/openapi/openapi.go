@@ -0,0 +1,261 @@
+// Package openapi describes the client and admin APIs as an OpenAPI 3
+// document, served at /api/openapi.json with an optional Swagger UI.
+//
+// The document is assembled from the route tables in routes.go and the
+// request/response types the handlers bind and return, so the schemas follow
+// the Go types. A test checks the route tables against the routes the
+// packages register.
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+
+	"winsbygroup.com/regserver/internal/version"
+)
+
+// Path prefixes of the API groups
+const (
+	ClientPrefix = "/api/v1"
+	AdminPrefix  = "/api/admin"
+)
+
+//go:embed swagger.html
+var swaggerHTML []byte
+
+// swaggerFiles holds the Swagger UI bundle, served with the page so it works
+// without access to a CDN
+//
+//go:embed swagger-ui/*.js swagger-ui/*.css
+var swaggerFiles embed.FS
+
+var (
+	specOnce sync.Once
+	spec     *Document
+)
+
+// Spec returns the OpenAPI document of the client and admin APIs
+func Spec() *Document {
+	specOnce.Do(func() { spec = build() })
+	return spec
+}
+
+// Handler serves the OpenAPI document as JSON
+func Handler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, Spec())
+	}
+}
+
+// SwaggerUI serves a Swagger UI page for the document at /api/openapi.json
+func SwaggerUI() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.HTMLBlob(http.StatusOK, swaggerHTML)
+	}
+}
+
+// SwaggerAssets serves the Swagger UI script and stylesheet the page loads,
+// registered at /api/docs/*
+func SwaggerAssets() echo.HandlerFunc {
+	files, _ := fs.Sub(swaggerFiles, "swagger-ui")
+	return echo.WrapHandler(http.StripPrefix("/api/docs/", http.FileServer(http.FS(files))))
+}
+
+// group is an API group: its routes share a prefix, an authentication scheme
+// and error responses
+type group struct {
+	tag, prefix string
+	security    string         // security scheme of routes with auth
+	auth        bool           // every route requires auth
+	errors      map[int]string // error responses of every route
+	authErrors  map[int]string // error responses of routes with auth
+}
+
+var (
+	clientGroup = group{
+		tag:        "client",
+		prefix:     ClientPrefix,
+		security:   "licenseKey",
+		errors:     map[int]string{500: "Internal error"},
+		authErrors: map[int]string{401: "Missing or invalid license key"},
+	}
+	adminGroup = group{
+		tag:      "admin",
+		prefix:   AdminPrefix,
+		security: "adminKey",
+		auth:     true,
+		errors: map[int]string{
+			400: "Invalid request",
+			403: "Outside the reseller's scope",
+			404: "Not found",
+			500: "Internal error",
+		},
+		authErrors: map[int]string{401: "Missing or invalid API key"},
+	}
+)
+
+func build() *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "Registration Server API",
+			Description: "Client API for activating and checking licenses, and admin API for managing customers, products and licenses.",
+			Version:     version.Version,
+		},
+		Tags: []Tag{
+			{Name: clientGroup.tag, Description: "Client API, called by licensed applications"},
+			{Name: adminGroup.tag, Description: "Admin API, for administrators and resellers"},
+		},
+		Paths: map[string]map[string]Operation{},
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				"licenseKey": {Type: "apiKey", In: "header", Name: "X-License-Key", Description: "License key"},
+				"adminKey":   {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "Admin or reseller API key"},
+			},
+		},
+	}
+	s := schemas{}
+	errRef := s.of(ErrorResponse{})
+
+	add := func(g group, routes []route) {
+		for _, r := range routes {
+			p := g.prefix + openAPIPath(r.path)
+			op := Operation{
+				Tags:        []string{g.tag},
+				Summary:     r.summary,
+				OperationID: operationID(r.method, r.path),
+				Parameters:  append(pathParams(r.path), r.query...),
+				Responses:   map[string]Response{},
+				Security:    []map[string][]string{},
+			}
+			if r.request != nil {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content:  map[string]MediaType{echo.MIMEApplicationJSON: {Schema: s.of(r.request)}},
+				}
+			}
+
+			status := r.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			ok := Response{Description: http.StatusText(status)}
+			switch {
+			case r.media != "":
+				ok.Content = map[string]MediaType{r.media: {Schema: &Schema{Type: "string", Format: "binary"}}}
+			case r.response != nil:
+				ok.Content = map[string]MediaType{echo.MIMEApplicationJSON: {Schema: s.of(r.response)}}
+			case status == http.StatusOK:
+				status = http.StatusNoContent
+				ok.Description = http.StatusText(status)
+			}
+			op.Responses[strconv.Itoa(status)] = ok
+
+			errs := map[int]string{}
+			for code, desc := range g.errors {
+				errs[code] = desc
+			}
+			if r.auth || g.auth {
+				op.Security = []map[string][]string{{g.security: {}}}
+				for code, desc := range g.authErrors {
+					errs[code] = desc
+				}
+			}
+			if r.limited {
+				errs[http.StatusTooManyRequests] = "Too many requests or failed attempts, retry later"
+			}
+			for code, desc := range r.errors {
+				errs[code] = desc
+			}
+			for code, desc := range errs {
+				op.Responses[strconv.Itoa(code)] = Response{
+					Description: desc,
+					Content:     map[string]MediaType{echo.MIMEApplicationJSON: {Schema: errRef}},
+				}
+			}
+
+			if doc.Paths[p] == nil {
+				doc.Paths[p] = map[string]Operation{}
+			}
+			doc.Paths[p][strings.ToLower(r.method)] = op
+		}
+	}
+	add(clientGroup, clientRoutes)
+	add(adminGroup, adminRoutes)
+
+	doc.Components.Schemas = s
+	return doc
+}
+
+// openAPIPath converts Echo's :param path segments to OpenAPI's {param}
+func openAPIPath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") {
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// stringParams are the path parameters that are not integer IDs
+var stringParams = map[string]bool{"guid": true, "license_key": true}
+
+func pathParams(p string) []Parameter {
+	var params []Parameter
+	for _, seg := range strings.Split(p, "/") {
+		name, ok := strings.CutPrefix(seg, ":")
+		if !ok {
+			continue
+		}
+		schema := &Schema{Type: "integer", Format: "int64"}
+		if stringParams[name] {
+			schema = &Schema{Type: "string"}
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return params
+}
+
+// operationID names an operation after its method and path, e.g.
+// GET /customers/:id/exists -> getCustomersByIdExists
+func operationID(method, p string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.Split(p, "/") {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			b.WriteString("By")
+			seg = name
+		}
+		for _, word := range strings.FieldsFunc(seg, func(r rune) bool { return r == '-' || r == '_' }) {
+			r := []rune(word)
+			r[0] = unicode.ToUpper(r[0])
+			b.WriteString(string(r))
+		}
+	}
+	return b.String()
+}
+
+// Routes lists the documented method and path pairs, e.g.
+// "GET /api/v1/productver/:guid", sorted
+func Routes() []string {
+	var out []string
+	for _, g := range []struct {
+		prefix string
+		routes []route
+	}{{ClientPrefix, clientRoutes}, {AdminPrefix, adminRoutes}} {
+		for _, r := range g.routes {
+			out = append(out, r.method+" "+g.prefix+r.path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,187 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"winsbygroup.com/regserver/internal/http/admin"
+	"winsbygroup.com/regserver/internal/http/client"
+	"winsbygroup.com/regserver/internal/openapi"
+)
+
+// registeredRoutes returns the routes client.RegisterRoutes and
+// admin.RegisterRoutes register, as "METHOD /path"
+func registeredRoutes() []string {
+	e := echo.New()
+	noop := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	client.RegisterRoutes(e.Group(openapi.ClientPrefix), &client.Handler{}, noop, noop)
+	admin.RegisterRoutes(e.Group(openapi.AdminPrefix), &admin.Handler{})
+
+	var out []string
+	for _, r := range e.Routes() {
+		if strings.HasPrefix(r.Path, "/api/") {
+			out = append(out, r.Method+" "+r.Path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	registered := registeredRoutes()
+	documented := map[string]bool{}
+	for _, r := range openapi.Routes() {
+		documented[r] = true
+	}
+
+	for _, r := range registered {
+		if !documented[r] {
+			t.Errorf("route %s is missing from the OpenAPI spec (add it to routes.go)", r)
+		}
+		delete(documented, r)
+	}
+	for r := range documented {
+		t.Errorf("route %s is in the OpenAPI spec but not registered", r)
+	}
+}
+
+func TestSpec(t *testing.T) {
+	doc := openapi.Spec()
+
+	t.Run("has an operation per route", func(t *testing.T) {
+		var n int
+		ids := map[string]string{}
+		for p, ops := range doc.Paths {
+			if strings.Contains(p, ":") {
+				t.Errorf("path %s uses :param instead of {param}", p)
+			}
+			for method, op := range ops {
+				n++
+				if prev, ok := ids[op.OperationID]; ok {
+					t.Errorf("operationId %s used by %s and %s %s", op.OperationID, prev, method, p)
+				}
+				ids[op.OperationID] = method + " " + p
+				for _, param := range op.Parameters {
+					if param.In == "path" && !strings.Contains(p, "{"+param.Name+"}") {
+						t.Errorf("%s %s: path parameter %s not in path", method, p, param.Name)
+					}
+				}
+			}
+		}
+		if want := len(openapi.Routes()); n != want {
+			t.Errorf("expected %d operations, got %d", want, n)
+		}
+	})
+
+	t.Run("describes request and response types", func(t *testing.T) {
+		for _, name := range []string{
+			"activation.Request",
+			"activation.Response",
+			"client.LicenseInfoResponse",
+			"admin.CreateCustomerRequest",
+			"admin.ManualRegistrationRequest",
+			"customer.Customer",
+			"openapi.ErrorResponse",
+		} {
+			if doc.Components.Schemas[name] == nil {
+				t.Errorf("expected a %s schema", name)
+			}
+		}
+
+		resp := doc.Components.Schemas["activation.Response"]
+		if resp.Properties["RegistrationHash"] == nil {
+			t.Errorf("expected activation.Response to have its JSON fields, got %+v", resp.Properties)
+		}
+
+		op := doc.Paths["/api/v1/activate"]["post"]
+		if op.RequestBody == nil || op.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/activation.Request" {
+			t.Errorf("expected POST /activate to take an activation.Request, got %+v", op.RequestBody)
+		}
+		if len(op.Security) != 1 || op.Security[0]["licenseKey"] == nil {
+			t.Errorf("expected POST /activate to require a license key, got %v", op.Security)
+		}
+		if op := doc.Paths["/api/v1/productver/{guid}"]["get"]; len(op.Security) != 0 {
+			t.Errorf("expected GET /productver to be public, got %v", op.Security)
+		}
+		if _, ok := doc.Paths["/api/admin/customers"]["post"].Responses["201"]; !ok {
+			t.Error("expected POST /customers to respond 201")
+		}
+		if _, ok := doc.Paths["/api/admin/customers/{id}"]["delete"].Responses["204"]; !ok {
+			t.Error("expected DELETE /customers/{id} to respond 204")
+		}
+	})
+
+	t.Run("references only defined schemas", func(t *testing.T) {
+		b, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		for _, part := range strings.Split(string(b), `"$ref":"#/components/schemas/`)[1:] {
+			name, _, _ := strings.Cut(part, `"`)
+			if doc.Components.Schemas[name] == nil {
+				t.Errorf("$ref to undefined schema %s", name)
+			}
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	if err := openapi.Handler()(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Errorf("expected an OpenAPI 3.0.3 document, got %v", doc["openapi"])
+	}
+}
+
+func TestSwaggerUI(t *testing.T) {
+	e := echo.New()
+	e.GET("/api/docs", openapi.SwaggerUI())
+	e.GET("/api/docs/*", openapi.SwaggerAssets())
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	page := get("/api/docs")
+	if page.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", page.Code)
+	}
+	if strings.Contains(page.Body.String(), "https://") {
+		t.Errorf("expected the page to load nothing from other hosts:\n%s", page.Body.String())
+	}
+	for path, ct := range map[string]string{
+		"/api/docs/swagger-ui-bundle.js": "javascript",
+		"/api/docs/swagger-ui.css":       "text/css",
+	} {
+		if !strings.Contains(page.Body.String(), path) {
+			t.Errorf("expected the page to load %s", path)
+		}
+		rec := get(path)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), ct) {
+			t.Errorf("GET %s: expected 200 with %s, got %d %q", path, ct, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+	if rec := get("/api/docs/LICENSE"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for files outside the bundle, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"net/http"
+
+	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/backup"
+	"winsbygroup.com/regserver/internal/bulk"
+	"winsbygroup.com/regserver/internal/bundle"
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/http/admin"
+	"winsbygroup.com/regserver/internal/http/client"
+	"winsbygroup.com/regserver/internal/inactivity"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/plan"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/reseller"
+)
+
+// route documents an endpoint registered by client.RegisterRoutes or
+// admin.RegisterRoutes. Paths use Echo's :param syntax.
+type route struct {
+	method   string
+	path     string
+	summary  string
+	request  any            // JSON request body (nil = none)
+	response any            // JSON response body (nil = none)
+	status   int            // success status (0 = 200 OK)
+	query    []Parameter    // query parameters
+	media    string         // response media type other than JSON, e.g. a download
+	auth     bool           // requires the group's API key (admin routes always do)
+	limited  bool           // rate limited per client IP and license key
+	errors   map[int]string // error responses besides the group's
+}
+
+// ErrorResponse is the body of error responses: handlers report "error",
+// middleware (authentication, rate limiting) reports "message"
+type ErrorResponse struct {
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ProductVersion is the response of GET /productver/:guid
+type ProductVersion struct {
+	ProductGUID   string
+	LatestVersion string
+	DownloadURL   string
+}
+
+// ExistsResponse is the response of GET /customers/:id/exists
+type ExistsResponse struct {
+	Exists bool `json:"exists"`
+}
+
+var productGUIDQuery = Parameter{Name: "productGuid", In: "query", Description: "The product of a bundle license key", Schema: &Schema{Type: "string"}}
+
+// clientRoutes are the client API endpoints under /api/v1
+var clientRoutes = []route{
+	{method: http.MethodPost, path: "/activate", summary: "Activate a product for a machine", auth: true, limited: true,
+		request: activation.Request{}, response: activation.Response{},
+		errors: map[int]string{400: "Invalid request or missing productGuid for a bundle key", 403: "License or key not active, product not licensed, or all seats in use"}},
+	{method: http.MethodPost, path: "/refresh", summary: "Re-issue the registration of an activated machine", auth: true, limited: true,
+		request: client.RefreshRequest{}, response: activation.Response{},
+		errors: map[int]string{403: "License or key not active", 404: "Machine not registered for this license"}},
+	{method: http.MethodGet, path: "/productver/:guid", summary: "Check for product updates",
+		response: ProductVersion{},
+		errors:   map[int]string{404: "Product not found"}},
+	{method: http.MethodGet, path: "/license/:license_key", summary: "Get license info", limited: true,
+		query: []Parameter{productGUIDQuery}, response: client.LicenseInfoResponse{},
+		errors: map[int]string{403: "License or key not active", 404: "License key not found"}},
+	{method: http.MethodPut, path: "/license/:license_key", summary: "Check in a machine and report its installed version", limited: true,
+		query: []Parameter{productGUIDQuery}, request: client.UpdateLicenseRequest{}, response: client.LicenseInfoResponse{},
+		errors: map[int]string{400: "Invalid request", 403: "License or key not active", 404: "License key not found or machine not registered"}},
+}
+
+// adminRoutes are the admin API endpoints under /api/admin
+var adminRoutes = []route{
+	// Customers
+	{method: http.MethodGet, path: "/customers", summary: "List customers", response: []customer.Customer{}},
+	{method: http.MethodGet, path: "/customers/:id", summary: "Get a customer", response: customer.Customer{}},
+	{method: http.MethodPost, path: "/customers", summary: "Create a customer", request: admin.CreateCustomerRequest{}, response: customer.Customer{}, status: http.StatusCreated},
+	{method: http.MethodPut, path: "/customers/:id", summary: "Update a customer", request: admin.UpdateCustomerRequest{}},
+	{method: http.MethodDelete, path: "/customers/:id", summary: "Delete a customer"},
+	{method: http.MethodGet, path: "/customers/:id/exists", summary: "Check whether a customer exists", response: ExistsResponse{}},
+	{method: http.MethodPut, path: "/customers/:id/reseller", summary: "Assign a customer to a reseller", request: admin.SetCustomerResellerRequest{}},
+
+	// Customer contacts
+	{method: http.MethodGet, path: "/customers/:customerId/contacts", summary: "List a customer's contacts", response: []customer.Contact{}},
+	{method: http.MethodPost, path: "/customers/:customerId/contacts", summary: "Create a contact", request: admin.CreateContactRequest{}, response: customer.Contact{}, status: http.StatusCreated},
+	{method: http.MethodPut, path: "/contacts/:id", summary: "Update a contact", request: admin.UpdateContactRequest{}},
+	{method: http.MethodDelete, path: "/contacts/:id", summary: "Delete a contact"},
+
+	// Products
+	{method: http.MethodGet, path: "/products", summary: "List products", response: []product.Product{}},
+	{method: http.MethodGet, path: "/products/:id", summary: "Get a product", response: product.Product{}},
+	{method: http.MethodPost, path: "/products", summary: "Create a product", request: admin.CreateProductRequest{}, response: product.Product{}, status: http.StatusCreated},
+	{method: http.MethodPut, path: "/products/:id", summary: "Update a product", request: admin.UpdateProductRequest{}},
+	{method: http.MethodDelete, path: "/products/:id", summary: "Delete a product"},
+
+	// Licenses
+	{method: http.MethodGet, path: "/customers/:customerId/licenses", summary: "List a customer's licenses", response: []license.License{}},
+	{method: http.MethodPost, path: "/customers/:customerId/licenses", summary: "Create a license", request: admin.CreateLicenseRequest{}, response: license.License{}, status: http.StatusCreated},
+	{method: http.MethodGet, path: "/licenses/:id", summary: "Get a license", response: license.License{}},
+	{method: http.MethodPut, path: "/licenses/:id", summary: "Update a license", request: admin.UpdateLicenseRequest{}},
+	{method: http.MethodDelete, path: "/licenses/:id", summary: "Delete a license"},
+	{method: http.MethodPut, path: "/licenses/:id/status", summary: "Suspend, cancel or reactivate a license", request: admin.SetLicenseStatusRequest{}},
+	{method: http.MethodPost, path: "/licenses/:id/rotate-key", summary: "Rotate a license key", request: admin.RotateKeyRequest{}, response: license.License{}},
+	{method: http.MethodPut, path: "/licenses/:id/key-status", summary: "Suspend, revoke or reactivate a license key", request: admin.SetKeyStatusRequest{}},
+	{method: http.MethodGet, path: "/licenses/:id/key-history", summary: "List a license's key rotations and status changes", response: []license.KeyHistory{}},
+
+	// Bundles
+	{method: http.MethodGet, path: "/bundles", summary: "List bundles", response: []bundle.Bundle{}},
+	{method: http.MethodGet, path: "/bundles/:id", summary: "Get a bundle", response: bundle.Bundle{}},
+	{method: http.MethodPost, path: "/bundles", summary: "Create a bundle", request: admin.CreateBundleRequest{}, response: bundle.Bundle{}, status: http.StatusCreated},
+	{method: http.MethodPut, path: "/bundles/:id", summary: "Update a bundle", request: admin.UpdateBundleRequest{}},
+	{method: http.MethodDelete, path: "/bundles/:id", summary: "Delete a bundle"},
+
+	// Customer bundles
+	{method: http.MethodGet, path: "/customers/:customerId/bundles", summary: "List a customer's bundles", response: []bundle.CustomerBundle{}},
+	{method: http.MethodPost, path: "/customers/:customerId/bundles", summary: "License a bundle to a customer", request: admin.CreateCustomerBundleRequest{}, response: bundle.CustomerBundle{}, status: http.StatusCreated},
+	{method: http.MethodPut, path: "/customers/:customerId/bundles/:bundleId", summary: "Update a customer's bundle licenses", request: admin.UpdateCustomerBundleRequest{}},
+	{method: http.MethodDelete, path: "/customers/:customerId/bundles/:bundleId", summary: "Delete a customer's bundle licenses"},
+	{method: http.MethodPost, path: "/customers/:customerId/bundles/:bundleId/rotate-key", summary: "Rotate a bundle license key", request: admin.RotateKeyRequest{}, response: bundle.CustomerBundle{}},
+	{method: http.MethodPut, path: "/customers/:customerId/bundles/:bundleId/key-status", summary: "Suspend, revoke or reactivate a bundle license key", request: admin.SetKeyStatusRequest{}},
+	{method: http.MethodGet, path: "/customers/:customerId/bundles/:bundleId/key-history", summary: "List a bundle license key's rotations and status changes", response: []bundle.KeyHistory{}},
+
+	// Feature definitions
+	{method: http.MethodGet, path: "/products/:productId/features", summary: "List a product's features", response: []feature.Feature{}},
+	{method: http.MethodPost, path: "/products/:productId/features", summary: "Create a feature", request: admin.CreateFeatureRequest{}, response: feature.Feature{}, status: http.StatusCreated},
+	{method: http.MethodPut, path: "/features/:id", summary: "Update a feature", request: admin.UpdateFeatureRequest{}},
+	{method: http.MethodDelete, path: "/features/:id", summary: "Delete a feature (hidden instead while registrations include it)",
+		errors: map[int]string{409: "Feature is hidden but still included in issued registrations"}},
+
+	// Plans
+	{method: http.MethodGet, path: "/products/:productId/plans", summary: "List a product's plans", response: []plan.Plan{}},
+	{method: http.MethodPost, path: "/products/:productId/plans", summary: "Create a plan", request: admin.CreatePlanRequest{}, response: plan.Plan{}, status: http.StatusCreated},
+	{method: http.MethodGet, path: "/plans/:id", summary: "Get a plan", response: plan.Plan{}},
+	{method: http.MethodPut, path: "/plans/:id", summary: "Update a plan", request: admin.UpdatePlanRequest{}},
+	{method: http.MethodDelete, path: "/plans/:id", summary: "Delete a plan"},
+	{method: http.MethodPut, path: "/plans/:id/features/:featureId", summary: "Set a plan's feature value", request: admin.SetPlanValueRequest{}},
+	{method: http.MethodGet, path: "/products/:productId/licenses", summary: "List a product's licenses with their plans", response: []plan.License{}},
+	{method: http.MethodPut, path: "/licenses/plan", summary: "Put licenses on a plan", request: admin.AssignPlanRequest{}},
+
+	// Bulk operations
+	{method: http.MethodPost, path: "/bulk/preview", summary: "Preview a bulk license update", request: admin.BulkRequest{}, response: bulk.Preview{}},
+	{method: http.MethodPost, path: "/bulk/apply", summary: "Apply a bulk license update", request: admin.BulkRequest{}, response: bulk.Operation{}, status: http.StatusCreated},
+	{method: http.MethodGet, path: "/bulk/operations", summary: "List applied bulk updates", response: []bulk.Operation{},
+		query: []Parameter{{Name: "productId", In: "query", Description: "Only updates of this product", Schema: &Schema{Type: "integer", Format: "int64"}}}},
+	{method: http.MethodGet, path: "/bulk/operations/:id", summary: "Get an applied bulk update", response: bulk.Operation{}},
+
+	// Inactivity policies
+	{method: http.MethodGet, path: "/products/:productId/inactivity-policy", summary: "Get a product's inactivity policy", response: inactivity.Policy{}},
+	{method: http.MethodPut, path: "/products/:productId/inactivity-policy", summary: "Set a product's inactivity policy", request: inactivity.Policy{}},
+	{method: http.MethodGet, path: "/licenses/:id/inactivity-policy", summary: "Get a license's inactivity policy override", response: inactivity.Policy{}},
+	{method: http.MethodPut, path: "/licenses/:id/inactivity-policy", summary: "Override the inactivity policy for a license", request: inactivity.Policy{}},
+	{method: http.MethodGet, path: "/inactivity/preview", summary: "Preview the next inactivity run", response: inactivity.Report{},
+		query: []Parameter{{Name: "productId", In: "query", Description: "Only registrations of this product", Schema: &Schema{Type: "integer", Format: "int64"}}}},
+	{method: http.MethodPost, path: "/inactivity/run", summary: "Run the inactivity policies now", response: inactivity.Result{}},
+
+	// License features
+	{method: http.MethodGet, path: "/licenses/:id/features", summary: "List a license's feature values", response: []featurevalue.FeatureValue{}},
+	{method: http.MethodPut, path: "/licenses/:id/features/:featureId", summary: "Set a license's feature value", request: admin.UpdateLicenseFeatureRequest{}},
+
+	// Machine registrations
+	{method: http.MethodGet, path: "/licenses/:id/registrations", summary: "List a license's machine registrations", response: []admin.MachineRegistration{},
+		query: []Parameter{{Name: "active", In: "query", Description: "true = only active (non-expired) registrations", Schema: &Schema{Type: "boolean"}}}},
+	{method: http.MethodGet, path: "/licenses/:id/registrations/export", summary: "Download the registration files of a license's active registrations", media: "application/zip"},
+	{method: http.MethodPost, path: "/registrations", summary: "Register a machine without the license key (offline registration)", request: admin.ManualRegistrationRequest{}, response: activation.Response{}, status: http.StatusCreated,
+		errors: map[int]string{400: "Missing machine code", 409: "No active license with a free seat"}},
+	{method: http.MethodGet, path: "/registrations/:machineId/:productId/export", summary: "Download a registration file", response: activation.Response{}},
+	{method: http.MethodDelete, path: "/registrations/:machineId/:productId", summary: "Delete a machine registration"},
+	{method: http.MethodPost, path: "/machines/:machineId/transfer", summary: "Transfer a machine to another customer", request: admin.TransferMachineRequest{}, response: machine.Transfer{},
+		errors: map[int]string{409: "The destination customer has no active license with a free seat"}},
+	{method: http.MethodGet, path: "/machines/:machineId/transfers", summary: "List a machine's transfers", response: []machine.Transfer{}},
+
+	// Expirations
+	{method: http.MethodGet, path: "/expirations", summary: "List licenses expiring before a date", response: []license.ExpiredLicense{},
+		query: []Parameter{{Name: "before", In: "query", Description: "Cutoff date (YYYY-MM-DD, default today)", Schema: &Schema{Type: "string", Format: "date"}}}},
+
+	// Resellers
+	{method: http.MethodGet, path: "/resellers", summary: "List resellers", response: []reseller.Reseller{}},
+	{method: http.MethodGet, path: "/resellers/:id", summary: "Get a reseller", response: reseller.Reseller{}},
+	{method: http.MethodPost, path: "/resellers", summary: "Create a reseller", request: admin.CreateResellerRequest{}, response: admin.CreateResellerResponse{}, status: http.StatusCreated},
+	{method: http.MethodPut, path: "/resellers/:id", summary: "Update a reseller", request: admin.UpdateResellerRequest{}},
+	{method: http.MethodDelete, path: "/resellers/:id", summary: "Delete a reseller"},
+	{method: http.MethodPost, path: "/resellers/:id/rotate-key", summary: "Rotate a reseller's API key", response: admin.RotateResellerKeyResponse{}},
+	{method: http.MethodGet, path: "/resellers/:id/usage", summary: "Get a reseller's seat usage", response: reseller.Usage{}},
+
+	// Backup
+	{method: http.MethodPost, path: "/backup", summary: "Back up the database", response: backup.BackupResult{}},
+}
@@ -0,0 +1,63 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validation errors
+var (
+	ErrNameRequired = errors.New("plan name is required")
+	ErrWrongProduct = errors.New("plan and license or feature belong to different products")
+)
+
+// Plan is a named set of feature values for a product ("Standard", "Pro", ...).
+// A license on a plan gets the plan's values in place of the feature defaults;
+// the license's own feature values still override both.
+type Plan struct {
+	PlanID    int64   `db:"plan_id" json:"planId"`
+	ProductID int64   `db:"product_id" json:"productId"`
+	PlanName  string  `db:"plan_name" json:"planName"`
+	Licenses  int     `db:"licenses" json:"licenses"` // number of licenses on the plan
+	Values    []Value `db:"-" json:"values"`
+}
+
+// Validate checks business rules for a plan
+func (p *Plan) Validate() error {
+	if strings.TrimSpace(p.PlanName) == "" {
+		return ErrNameRequired
+	}
+	return nil
+}
+
+// Value is a plan's value for one feature
+type Value struct {
+	FeatureID    int64  `db:"feature_id" json:"featureId"`
+	FeatureName  string `db:"feature_name" json:"featureName"`
+	FeatureValue string `db:"feature_value" json:"value"`
+}
+
+// ValueError is an invalid plan value for one feature. It wraps the
+// featurevalue.FieldError for the value.
+type ValueError struct {
+	FeatureID int64
+	Err       error
+}
+
+func (e *ValueError) Error() string { return fmt.Sprintf("feature %d: %v", e.FeatureID, e.Err) }
+
+func (e *ValueError) Unwrap() error { return e.Err }
+
+// License is a license of the plan's product with its customer and current
+// plan, as listed when assigning plans in bulk
+type License struct {
+	LicenseID    int64  `db:"license_id" json:"licenseId"`
+	CustomerID   int64  `db:"customer_id" json:"customerId"`
+	CustomerName string `db:"customer_name" json:"customerName"`
+	LicenseKey   string `db:"license_key" json:"licenseKey"`
+	LicenseCount int    `db:"license_count" json:"licenseCount"`
+	Status       string `db:"status" json:"status"`
+	PlanID       *int64 `db:"plan_id" json:"planId"`
+	PlanName     string `db:"plan_name" json:"planName"`
+}
@@ -0,0 +1,110 @@
+package plan
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	GetForProduct(ctx context.Context, productID int64) ([]Plan, error)
+	Get(ctx context.Context, id int64) (*Plan, error)
+	GetValues(ctx context.Context, id int64) ([]Value, error)
+	Create(ctx context.Context, tx *sqlx.Tx, p *Plan) (int64, error)
+	Update(ctx context.Context, tx *sqlx.Tx, p *Plan) error
+	Delete(ctx context.Context, tx *sqlx.Tx, id int64) error
+	SetValue(ctx context.Context, tx *sqlx.Tx, id, featureID int64, value string) error
+	DeleteValue(ctx context.Context, tx *sqlx.Tx, id, featureID int64) error
+	GetLicenses(ctx context.Context, productID int64) ([]License, error)
+}
+
+type repo struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) Repository {
+	return &repo{db: db}
+}
+
+func (r *repo) GetForProduct(ctx context.Context, productID int64) ([]Plan, error) {
+	out := []Plan{}
+	err := r.db.SelectContext(ctx, &out, getProductPlansSQL, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get product plans: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) Get(ctx context.Context, id int64) (*Plan, error) {
+	var out Plan
+	err := r.db.GetContext(ctx, &out, getPlanSQL, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("plan not found (%d)", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get plan: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *repo) GetValues(ctx context.Context, id int64) ([]Value, error) {
+	out := []Value{}
+	err := r.db.SelectContext(ctx, &out, getPlanValuesSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("get plan values: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, p *Plan) (int64, error) {
+	res, err := tx.ExecContext(ctx, createPlanSQL, p.ProductID, p.PlanName)
+	if err != nil {
+		return 0, fmt.Errorf("create plan: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, p *Plan) error {
+	_, err := tx.ExecContext(ctx, updatePlanSQL, p.PlanName, p.PlanID)
+	if err != nil {
+		return fmt.Errorf("update plan: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, deletePlanSQL, id)
+	if err != nil {
+		return fmt.Errorf("delete plan: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) SetValue(ctx context.Context, tx *sqlx.Tx, id, featureID int64, value string) error {
+	_, err := tx.ExecContext(ctx, setPlanValueSQL, id, featureID, value)
+	if err != nil {
+		return fmt.Errorf("set plan value: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) DeleteValue(ctx context.Context, tx *sqlx.Tx, id, featureID int64) error {
+	_, err := tx.ExecContext(ctx, deletePlanValueSQL, id, featureID)
+	if err != nil {
+		return fmt.Errorf("delete plan value: %w", err)
+	}
+	return nil
+}
+
+// GetLicenses returns every license of a product with its customer and plan
+func (r *repo) GetLicenses(ctx context.Context, productID int64) ([]License, error) {
+	out := []License{}
+	err := r.db.SelectContext(ctx, &out, getProductLicensesSQL, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get product licenses: %w", err)
+	}
+	return out, nil
+}
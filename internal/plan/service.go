@@ -0,0 +1,265 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/registration"
+)
+
+type Service struct {
+	repo       Repository
+	db         *sqlx.DB
+	licenseSvc *license.Service
+	featureSvc *feature.Service
+	reissuer   registration.Reissuer
+}
+
+func NewService(db *sqlx.DB, licenseSvc *license.Service, featureSvc *feature.Service) *Service {
+	return &Service{
+		db:         db,
+		repo:       New(db),
+		licenseSvc: licenseSvc,
+		featureSvc: featureSvc,
+	}
+}
+
+// SetReissuer sets what recomputes the registrations of a product when its
+// plans change (nil, the default, leaves registrations as they are). Licenses
+// put on another plan are re-issued by the license service.
+func (s *Service) SetReissuer(r registration.Reissuer) {
+	s.reissuer = r
+}
+
+func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// -------------------------
+// Plans
+// -------------------------
+
+// GetForProduct returns a product's plans with their feature values
+func (s *Service) GetForProduct(ctx context.Context, productID int64) ([]Plan, error) {
+	out, err := s.repo.GetForProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		if out[i].Values, err = s.repo.GetValues(ctx, out[i].PlanID); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (s *Service) Get(ctx context.Context, id int64) (*Plan, error) {
+	p, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Values, err = s.repo.GetValues(ctx, id); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Create creates a plan with its feature values (see SetValues)
+func (s *Service) Create(ctx context.Context, p *Plan) (*Plan, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	set, del, err := s.checkValues(ctx, p.ProductID, p.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		if id, err = s.repo.Create(ctx, tx, p); err != nil {
+			return err
+		}
+		return s.saveValues(ctx, tx, id, set, del)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, id)
+}
+
+// Update renames a plan and sets the given feature values (see SetValues).
+// Features not in p.Values keep their plan value.
+func (s *Service) Update(ctx context.Context, p *Plan) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	cur, err := s.repo.Get(ctx, p.PlanID)
+	if err != nil {
+		return err
+	}
+	set, del, err := s.checkValues(ctx, cur.ProductID, p.Values)
+	if err != nil {
+		return err
+	}
+
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.repo.Update(ctx, tx, p); err != nil {
+			return err
+		}
+		if err := s.saveValues(ctx, tx, p.PlanID, set, del); err != nil {
+			return err
+		}
+		return s.reissue(ctx, tx, cur.ProductID)
+	})
+}
+
+// Delete deletes a plan. Its licenses fall back to the feature defaults and
+// keep their own feature values.
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	cur, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.repo.Delete(ctx, tx, id); err != nil {
+			return err
+		}
+		return s.reissue(ctx, tx, cur.ProductID)
+	})
+}
+
+// SetValue sets a plan's value for one feature (see SetValues)
+func (s *Service) SetValue(ctx context.Context, id, featureID int64, value string) error {
+	err := s.SetValues(ctx, id, []Value{{FeatureID: featureID, FeatureValue: value}})
+	var ve *ValueError
+	if errors.As(err, &ve) {
+		return ve.Err
+	}
+	return err
+}
+
+// SetValues sets a plan's values for features of the plan's product after
+// validating them against the features' rules; either all values are saved or
+// none. An empty value removes the feature from the plan (for string features
+// too), so the feature's default applies.
+func (s *Service) SetValues(ctx context.Context, id int64, values []Value) error {
+	p, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	set, del, err := s.checkValues(ctx, p.ProductID, values)
+	if err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.saveValues(ctx, tx, id, set, del); err != nil {
+			return err
+		}
+		return s.reissue(ctx, tx, p.ProductID)
+	})
+}
+
+// reissue recomputes the registrations of a product whose plans changed in tx
+func (s *Service) reissue(ctx context.Context, tx *sqlx.Tx, productID int64) error {
+	if s.reissuer == nil {
+		return nil
+	}
+	return s.reissuer.ReissueProduct(ctx, tx, productID)
+}
+
+// checkValues validates plan values for a product's features. It returns the
+// values to store (in canonical form) and the IDs of the features to remove.
+func (s *Service) checkValues(ctx context.Context, productID int64, values []Value) ([]Value, []int64, error) {
+	var set []Value
+	var del []int64
+	for _, v := range values {
+		f, err := s.featureSvc.Get(ctx, v.FeatureID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if f.ProductID != productID {
+			return nil, nil, fmt.Errorf("%w: feature %d is not a feature of the plan's product", ErrWrongProduct, v.FeatureID)
+		}
+		if v.FeatureValue == "" {
+			del = append(del, v.FeatureID)
+			continue
+		}
+		canonical, err := f.Rules().Validate("feature_value", v.FeatureValue)
+		if err != nil {
+			return nil, nil, &ValueError{FeatureID: v.FeatureID, Err: err}
+		}
+		set = append(set, Value{FeatureID: v.FeatureID, FeatureValue: canonical})
+	}
+	return set, del, nil
+}
+
+func (s *Service) saveValues(ctx context.Context, tx *sqlx.Tx, id int64, set []Value, del []int64) error {
+	for _, v := range set {
+		if err := s.repo.SetValue(ctx, tx, id, v.FeatureID, v.FeatureValue); err != nil {
+			return err
+		}
+	}
+	for _, featureID := range del {
+		if err := s.repo.DeleteValue(ctx, tx, id, featureID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// -------------------------
+// Licenses
+// -------------------------
+
+// GetLicenses returns every license of a product with its customer and plan
+func (s *Service) GetLicenses(ctx context.Context, productID int64) ([]License, error) {
+	return s.repo.GetLicenses(ctx, productID)
+}
+
+// Assign puts licenses on a plan, or takes them off their plan if planID is
+// nil. Every license must be of the plan's product; either all licenses are
+// changed or none.
+func (s *Service) Assign(ctx context.Context, planID *int64, licenseIDs []int64) error {
+	var productID int64
+	if planID != nil {
+		p, err := s.repo.Get(ctx, *planID)
+		if err != nil {
+			return err
+		}
+		productID = p.ProductID
+	}
+
+	for _, id := range licenseIDs {
+		lic, err := s.licenseSvc.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if planID != nil && lic.ProductID != productID {
+			return fmt.Errorf("%w: license %d is not a license of the plan's product", ErrWrongProduct, id)
+		}
+	}
+
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		for _, id := range licenseIDs {
+			if err := s.licenseSvc.SetPlan(ctx, tx, id, planID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
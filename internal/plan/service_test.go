@@ -0,0 +1,188 @@
+package plan_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/feature"
+	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/plan"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestPlanService(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	licSvc := license.NewService(db)
+	featSvc := feature.NewService(db)
+	fvSvc := featurevalue.NewService(db)
+	svc := plan.NewService(db, licSvc, featSvc)
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+
+	cust, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Tiered Customer"})
+	prod, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Tiered", ProductGUID: "GUID-TIERED"})
+	other, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Other", ProductGUID: "GUID-OTHER"})
+
+	maxSeats := int64(100)
+	seats, _ := featSvc.Create(ctx, &feature.Feature{ProductID: prod.ProductID, FeatureName: "Seats", FeatureType: featurevalue.TypeInteger, DefaultValue: "1", MaxValue: &maxSeats})
+	reports, _ := featSvc.Create(ctx, &feature.Feature{ProductID: prod.ProductID, FeatureName: "Reports", FeatureType: featurevalue.TypeBoolean, DefaultValue: "false"})
+	edition, _ := featSvc.Create(ctx, &feature.Feature{ProductID: prod.ProductID, FeatureName: "Edition", FeatureType: featurevalue.TypeString, DefaultValue: "Basic"})
+	otherFeat, _ := featSvc.Create(ctx, &feature.Feature{ProductID: other.ProductID, FeatureName: "Seats", FeatureType: featurevalue.TypeInteger, DefaultValue: "1"})
+
+	newLicense := func(productID int64) *license.License {
+		lic, err := licSvc.Create(ctx, &license.License{
+			CustomerID:          cust.CustomerID,
+			ProductID:           productID,
+			LicenseKey:          uuid.New().String(),
+			LicenseCount:        1,
+			StartDate:           "2025-01-01",
+			ExpirationDate:      "2099-12-31",
+			MaintExpirationDate: "2099-12-31",
+		})
+		if err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+		return lic
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		if _, err := svc.Create(ctx, &plan.Plan{ProductID: prod.ProductID}); !errors.Is(err, plan.ErrNameRequired) {
+			t.Errorf("expected ErrNameRequired, got %v", err)
+		}
+		_, err := svc.Create(ctx, &plan.Plan{ProductID: prod.ProductID, PlanName: "Bad", Values: []plan.Value{{FeatureID: otherFeat.FeatureID, FeatureValue: "5"}}})
+		if !errors.Is(err, plan.ErrWrongProduct) {
+			t.Errorf("expected ErrWrongProduct for another product's feature, got %v", err)
+		}
+		_, err = svc.Create(ctx, &plan.Plan{ProductID: prod.ProductID, PlanName: "Bad", Values: []plan.Value{{FeatureID: seats.FeatureID, FeatureValue: "500"}}})
+		var ve *plan.ValueError
+		if !errors.As(err, &ve) || ve.FeatureID != seats.FeatureID || !errors.Is(err, featurevalue.ErrInvalidValue) {
+			t.Errorf("expected a ValueError for Seats, got %v", err)
+		}
+		plans, _ := svc.GetForProduct(ctx, prod.ProductID)
+		if len(plans) != 0 {
+			t.Errorf("expected no plans after failed creates, got %d", len(plans))
+		}
+	})
+
+	pro, err := svc.Create(ctx, &plan.Plan{ProductID: prod.ProductID, PlanName: "Pro", Values: []plan.Value{
+		{FeatureID: seats.FeatureID, FeatureValue: " 25 "},
+		{FeatureID: reports.FeatureID, FeatureValue: "1"},
+	}})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+
+	t.Run("create", func(t *testing.T) {
+		if len(pro.Values) != 2 {
+			t.Fatalf("expected 2 plan values, got %+v", pro.Values)
+		}
+		// Values are sorted by feature name and stored in canonical form
+		if pro.Values[0].FeatureName != "Reports" || pro.Values[0].FeatureValue != "true" {
+			t.Errorf("unexpected Reports value: %+v", pro.Values[0])
+		}
+		if pro.Values[1].FeatureName != "Seats" || pro.Values[1].FeatureValue != "25" {
+			t.Errorf("unexpected Seats value: %+v", pro.Values[1])
+		}
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		if _, err := svc.Create(ctx, &plan.Plan{ProductID: prod.ProductID, PlanName: "PRO"}); err == nil {
+			t.Error("expected an error for a duplicate plan name")
+		}
+		if _, err := svc.Create(ctx, &plan.Plan{ProductID: other.ProductID, PlanName: "Pro"}); err != nil {
+			t.Errorf("expected another product to allow the same plan name: %v", err)
+		}
+	})
+
+	lic := newLicense(prod.ProductID)
+	otherLic := newLicense(other.ProductID)
+
+	t.Run("merge order", func(t *testing.T) {
+		if err := svc.Assign(ctx, &pro.PlanID, []int64{lic.LicenseID}); err != nil {
+			t.Fatalf("assign plan: %v", err)
+		}
+		if err := fvSvc.Update(ctx, &featurevalue.FeatureValue{LicenseID: lic.LicenseID, FeatureID: seats.FeatureID, FeatureValue: "30"}); err != nil {
+			t.Fatalf("override Seats: %v", err)
+		}
+
+		defs, _ := featSvc.GetForProduct(ctx, prod.ProductID)
+		planVals, _ := fvSvc.GetPlanValues(ctx, lic.LicenseID)
+		vals, _ := fvSvc.GetFeatureValues(ctx, lic.LicenseID)
+		merged := feature.MergeWithOverrides(defs, planVals, vals)
+
+		want := map[string]string{"Seats": "30", "Reports": "true", "Edition": "Basic"}
+		for k, v := range want {
+			if merged[k] != v {
+				t.Errorf("merged[%s] = %q, want %q", k, merged[k], v)
+			}
+		}
+	})
+
+	t.Run("assign checks product", func(t *testing.T) {
+		err := svc.Assign(ctx, &pro.PlanID, []int64{lic.LicenseID, otherLic.LicenseID})
+		if !errors.Is(err, plan.ErrWrongProduct) {
+			t.Errorf("expected ErrWrongProduct, got %v", err)
+		}
+		if err := svc.Assign(ctx, &pro.PlanID, []int64{9999}); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("expected not found for an unknown license, got %v", err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		err := svc.Update(ctx, &plan.Plan{PlanID: pro.PlanID, PlanName: "Professional", Values: []plan.Value{
+			{FeatureID: reports.FeatureID, FeatureValue: ""},
+			{FeatureID: edition.FeatureID, FeatureValue: "Pro"},
+		}})
+		if err != nil {
+			t.Fatalf("update plan: %v", err)
+		}
+		got, _ := svc.Get(ctx, pro.PlanID)
+		if got.PlanName != "Professional" || got.Licenses != 1 {
+			t.Errorf("unexpected plan: %+v", got)
+		}
+		values := map[string]string{}
+		for _, v := range got.Values {
+			values[v.FeatureName] = v.FeatureValue
+		}
+		if len(values) != 2 || values["Seats"] != "25" || values["Edition"] != "Pro" {
+			t.Errorf("expected Seats kept, Reports removed and Edition set, got %v", values)
+		}
+	})
+
+	t.Run("licenses", func(t *testing.T) {
+		licenses, err := svc.GetLicenses(ctx, prod.ProductID)
+		if err != nil {
+			t.Fatalf("get licenses: %v", err)
+		}
+		if len(licenses) != 1 || licenses[0].PlanName != "Professional" || licenses[0].CustomerName != "Tiered Customer" {
+			t.Errorf("unexpected licenses: %+v", licenses)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := svc.Delete(ctx, pro.PlanID); err != nil {
+			t.Fatalf("delete plan: %v", err)
+		}
+		got, _ := licSvc.Get(ctx, lic.LicenseID)
+		if got.PlanID != nil {
+			t.Errorf("expected the license to be taken off the deleted plan, got plan %d", *got.PlanID)
+		}
+		planVals, _ := fvSvc.GetPlanValues(ctx, lic.LicenseID)
+		if len(planVals) != 0 {
+			t.Errorf("expected no plan values, got %+v", planVals)
+		}
+		if _, err := svc.Get(ctx, pro.PlanID); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("expected not found, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package plan
+
+const getProductPlansSQL = `
+SELECT
+    p.plan_id,
+    p.product_id,
+    p.plan_name,
+    (SELECT COUNT(*) FROM license l WHERE l.plan_id = p.plan_id) AS licenses
+FROM plan p
+WHERE p.product_id = ?
+ORDER BY p.plan_name
+`
+
+const getPlanSQL = `
+SELECT
+    p.plan_id,
+    p.product_id,
+    p.plan_name,
+    (SELECT COUNT(*) FROM license l WHERE l.plan_id = p.plan_id) AS licenses
+FROM plan p
+WHERE p.plan_id = ?
+`
+
+const createPlanSQL = `
+INSERT INTO plan (product_id, plan_name)
+VALUES (?, ?)
+`
+
+const updatePlanSQL = `
+UPDATE plan
+SET plan_name = ?
+WHERE plan_id = ?
+`
+
+const deletePlanSQL = `
+DELETE FROM plan
+WHERE plan_id = ?
+`
+
+const getPlanValuesSQL = `
+SELECT pf.feature_id, f.feature_name, pf.feature_value
+FROM plan_feature pf
+JOIN feature f ON f.feature_id = pf.feature_id
+WHERE pf.plan_id = ?
+ORDER BY f.feature_name
+`
+
+const setPlanValueSQL = `
+INSERT INTO plan_feature (plan_id, feature_id, feature_value)
+VALUES (?, ?, ?)
+ON CONFLICT (plan_id, feature_id) DO UPDATE SET feature_value = excluded.feature_value
+`
+
+const deletePlanValueSQL = `
+DELETE FROM plan_feature
+WHERE plan_id = ? AND feature_id = ?
+`
+
+const getProductLicensesSQL = `
+SELECT
+    l.license_id,
+    l.customer_id,
+    c.customer_name,
+    l.license_key,
+    l.license_count,
+    l.status,
+    l.plan_id,
+    COALESCE(p.plan_name, '') AS plan_name
+FROM license l
+JOIN customer c ON c.customer_id = l.customer_id
+LEFT JOIN plan p ON p.plan_id = l.plan_id
+WHERE l.product_id = ?
+ORDER BY c.customer_name, l.license_id
+`
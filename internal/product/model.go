@@ -1,5 +1,10 @@
 package product
 
+import "errors"
+
+// ErrNotFound is returned when a product does not exist
+var ErrNotFound = errors.New("product not found")
+
 type Product struct {
 	ProductID     int64  `db:"product_id"`
 	ProductName   string `db:"product_name"`
@@ -40,7 +40,7 @@ func (r *repo) Get(ctx context.Context, id int64) (*Product, error) {
 	var p Product
 	err := r.db.GetContext(ctx, &p, getProductSQL, id)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("product not found (%d)", id)
+		return nil, fmt.Errorf("%w (%d)", ErrNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get product: %w", err)
@@ -52,7 +52,7 @@ func (r *repo) GetByGUID(ctx context.Context, guid string) (*Product, error) {
 	var p Product
 	err := r.db.GetContext(ctx, &p, getProductByGUIDSQL, guid)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("product not found (%s)", guid)
+		return nil, fmt.Errorf("%w (%s)", ErrNotFound, guid)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get product by GUID: %w", err)
@@ -1,11 +1,71 @@
 package registration
 
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNotFound is returned when a registration does not exist
+var ErrNotFound = errors.New("registration not found")
+
 type Registration struct {
 	MachineID             int64  `db:"machine_id"`
 	ProductID             int64  `db:"product_id"`
+	LicenseID             *int64 `db:"license_id"` // nil only for registrations whose license was deleted before licenses had their own ID
 	ExpirationDate        string `db:"expiration_date"`
 	RegistrationHash      string `db:"registration_hash"`
 	FirstRegistrationDate string `db:"first_registration_date"`
 	LastRegistrationDate  string `db:"last_registration_date"`
 	InstalledVersion      string `db:"installed_version"`
+	LastCheckinDate       string `db:"last_checkin_date"` // empty until the machine checks in with PUT /license
+	IdleDate              string `db:"idle_date"`         // set while flagged idle by the inactivity policy
+	ReleasedDate          string `db:"released_date"`     // set once the inactivity policy released the seat
+	LastSeenAt            string `db:"last_seen_at"`      // last activation or check-in (UTC); empty if never seen since tracking began
+	ClientOS              string `db:"client_os"`
+	ClientHostname        string `db:"client_hostname"`
+	ClientIP              string `db:"client_ip"`
+}
+
+// TimeFormat is the format of last-seen times (UTC)
+const TimeFormat = "2006-01-02 15:04:05"
+
+// ClientInfo is what a machine reports about itself when it calls in. Empty
+// fields keep the values last reported.
+type ClientInfo struct {
+	OS       string
+	Hostname string
+	IP       string
+}
+
+// IsReleased reports whether the inactivity policy released the registration's
+// seat; the machine must activate again to use it
+func (r *Registration) IsReleased() bool {
+	return r.ReleasedDate != ""
+}
+
+// Issued is a registration with the code of the machine it is issued to, as
+// needed to recompute its hash
+type Issued struct {
+	Registration
+	MachineCode string `db:"machine_code"`
+}
+
+// Reissuer recomputes the expiration date and hash of registrations after the
+// terms they were issued under change, in the transaction that changed them.
+// Services that change license terms, feature values, feature definitions or
+// a machine's code call it so stored registrations never go stale.
+type Reissuer interface {
+	ReissueLicense(ctx context.Context, tx *sqlx.Tx, licenseID int64) error
+	ReissueProduct(ctx context.Context, tx *sqlx.Tx, productID int64) error
+	ReissueMachine(ctx context.Context, tx *sqlx.Tx, machineID int64) error
+}
+
+// ProductCount is the number of registrations for a product
+type ProductCount struct {
+	ProductID   int64  `db:"product_id"`
+	ProductName string `db:"product_name"`
+	Total       int    `db:"total"`
+	Active      int    `db:"active"`
 }
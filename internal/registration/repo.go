@@ -12,11 +12,18 @@ import (
 type Repository interface {
 	Get(ctx context.Context, machineID, productID int64) (*Registration, error)
 	GetForMachine(ctx context.Context, machineID int64) ([]Registration, error)
+	GetIssuedForLicense(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]Issued, error)
+	GetIssuedForProduct(ctx context.Context, tx *sqlx.Tx, productID int64) ([]Issued, error)
+	GetIssuedForMachine(ctx context.Context, tx *sqlx.Tx, machineID int64) ([]Issued, error)
 	Create(ctx context.Context, tx *sqlx.Tx, r *Registration) error
 	Update(ctx context.Context, tx *sqlx.Tx, r *Registration) error
 	Upsert(ctx context.Context, tx *sqlx.Tx, r *Registration) error
+	Reissue(ctx context.Context, tx *sqlx.Tx, r *Registration) error
 	Delete(ctx context.Context, tx *sqlx.Tx, machineID, productID int64) error
+	Exists(ctx context.Context, tx *sqlx.Tx, machineID, productID int64) (bool, error)
 	UpdateInstalledVersion(ctx context.Context, machineID, productID int64, version string) error
+	CheckIn(ctx context.Context, machineID, productID int64, seenAt string, info ClientInfo) error
+	CountByProduct(ctx context.Context) ([]ProductCount, error)
 }
 
 type repo struct {
@@ -31,7 +38,7 @@ func (r *repo) Get(ctx context.Context, machineID, productID int64) (*Registrati
 	var reg Registration
 	err := r.db.GetContext(ctx, &reg, getRegistrationSQL, machineID, productID)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("registration not found (%d/%d)", machineID, productID)
+		return nil, fmt.Errorf("%w (%d/%d)", ErrNotFound, machineID, productID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get registration: %w", err)
@@ -48,10 +55,38 @@ func (r *repo) GetForMachine(ctx context.Context, machineID int64) ([]Registrati
 	return out, nil
 }
 
+func (r *repo) GetIssuedForLicense(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]Issued, error) {
+	var out []Issued
+	err := tx.SelectContext(ctx, &out, getIssuedForLicenseSQL, licenseID)
+	if err != nil {
+		return nil, fmt.Errorf("get registrations for license: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetIssuedForProduct(ctx context.Context, tx *sqlx.Tx, productID int64) ([]Issued, error) {
+	var out []Issued
+	err := tx.SelectContext(ctx, &out, getIssuedForProductSQL, productID)
+	if err != nil {
+		return nil, fmt.Errorf("get registrations for product: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) GetIssuedForMachine(ctx context.Context, tx *sqlx.Tx, machineID int64) ([]Issued, error) {
+	var out []Issued
+	err := tx.SelectContext(ctx, &out, getIssuedForMachineSQL, machineID)
+	if err != nil {
+		return nil, fmt.Errorf("get registrations for machine: %w", err)
+	}
+	return out, nil
+}
+
 func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, reg *Registration) error {
 	_, err := tx.ExecContext(ctx, createRegistrationSQL,
 		reg.MachineID,
 		reg.ProductID,
+		reg.LicenseID,
 		reg.ExpirationDate,
 		reg.RegistrationHash,
 		reg.FirstRegistrationDate,
@@ -65,6 +100,7 @@ func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, reg *Registration) error
 
 func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, reg *Registration) error {
 	_, err := tx.ExecContext(ctx, updateRegistrationSQL,
+		reg.LicenseID,
 		reg.ExpirationDate,
 		reg.RegistrationHash,
 		reg.FirstRegistrationDate,
@@ -82,10 +118,15 @@ func (r *repo) Upsert(ctx context.Context, tx *sqlx.Tx, reg *Registration) error
 	_, err := tx.ExecContext(ctx, upsertRegistrationSQL,
 		reg.MachineID,
 		reg.ProductID,
+		reg.LicenseID,
 		reg.ExpirationDate,
 		reg.RegistrationHash,
 		reg.FirstRegistrationDate,
 		reg.LastRegistrationDate,
+		reg.LastSeenAt,
+		reg.ClientOS,
+		reg.ClientHostname,
+		reg.ClientIP,
 	)
 	if err != nil {
 		return fmt.Errorf("upsert registration: %w", err)
@@ -93,6 +134,19 @@ func (r *repo) Upsert(ctx context.Context, tx *sqlx.Tx, reg *Registration) error
 	return nil
 }
 
+func (r *repo) Reissue(ctx context.Context, tx *sqlx.Tx, reg *Registration) error {
+	_, err := tx.ExecContext(ctx, reissueRegistrationSQL,
+		reg.ExpirationDate,
+		reg.RegistrationHash,
+		reg.MachineID,
+		reg.ProductID,
+	)
+	if err != nil {
+		return fmt.Errorf("reissue registration: %w", err)
+	}
+	return nil
+}
+
 func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, machineID, productID int64) error {
 	_, err := tx.ExecContext(ctx, deleteRegistrationSQL, machineID, productID)
 	if err != nil {
@@ -101,6 +155,15 @@ func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, machineID, productID int
 	return nil
 }
 
+func (r *repo) Exists(ctx context.Context, tx *sqlx.Tx, machineID, productID int64) (bool, error) {
+	var exists bool
+	err := tx.GetContext(ctx, &exists, registrationExistsSQL, machineID, productID)
+	if err != nil {
+		return false, fmt.Errorf("registration exists: %w", err)
+	}
+	return exists, nil
+}
+
 func (r *repo) UpdateInstalledVersion(ctx context.Context, machineID, productID int64, version string) error {
 	result, err := r.db.ExecContext(ctx, updateInstalledVersionSQL, version, machineID, productID)
 	if err != nil {
@@ -108,7 +171,24 @@ func (r *repo) UpdateInstalledVersion(ctx context.Context, machineID, productID
 	}
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return fmt.Errorf("registration not found")
+		return ErrNotFound
 	}
 	return nil
 }
+
+func (r *repo) CheckIn(ctx context.Context, machineID, productID int64, seenAt string, info ClientInfo) error {
+	_, err := r.db.ExecContext(ctx, checkInSQL, seenAt, info.OS, info.Hostname, info.IP, machineID, productID)
+	if err != nil {
+		return fmt.Errorf("check in registration: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) CountByProduct(ctx context.Context) ([]ProductCount, error) {
+	var out []ProductCount
+	err := r.db.SelectContext(ctx, &out, countByProductSQL)
+	if err != nil {
+		return nil, fmt.Errorf("count registrations by product: %w", err)
+	}
+	return out, nil
+}
@@ -2,6 +2,7 @@ package registration
 
 import (
 	"context"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -38,6 +39,23 @@ func (s *Service) GetForMachine(ctx context.Context, machineID int64) ([]Registr
 	return s.repo.GetForMachine(ctx, machineID)
 }
 
+// GetIssuedForLicense returns the registrations of a license with their machine codes (within tx)
+func (s *Service) GetIssuedForLicense(ctx context.Context, tx *sqlx.Tx, licenseID int64) ([]Issued, error) {
+	return s.repo.GetIssuedForLicense(ctx, tx, licenseID)
+}
+
+// GetIssuedForProduct returns the registrations of a product that belong to a
+// license, with their machine codes (within tx)
+func (s *Service) GetIssuedForProduct(ctx context.Context, tx *sqlx.Tx, productID int64) ([]Issued, error) {
+	return s.repo.GetIssuedForProduct(ctx, tx, productID)
+}
+
+// GetIssuedForMachine returns the registrations of a machine that belong to a
+// license, with its machine code (within tx)
+func (s *Service) GetIssuedForMachine(ctx context.Context, tx *sqlx.Tx, machineID int64) ([]Issued, error) {
+	return s.repo.GetIssuedForMachine(ctx, tx, machineID)
+}
+
 func (s *Service) Create(ctx context.Context, r *Registration) (*Registration, error) {
 	err := s.WithTx(ctx, func(tx *sqlx.Tx) error {
 		return s.repo.Create(ctx, tx, r)
@@ -58,12 +76,35 @@ func (s *Service) Upsert(ctx context.Context, tx *sqlx.Tx, reg *Registration) er
 	return s.repo.Upsert(ctx, tx, reg)
 }
 
+// Reissue stores a registration's recomputed expiration date and hash in the
+// caller's transaction; its registration dates are kept
+func (s *Service) Reissue(ctx context.Context, tx *sqlx.Tx, reg *Registration) error {
+	return s.repo.Reissue(ctx, tx, reg)
+}
+
 func (s *Service) Delete(ctx context.Context, machineID, productID int64) error {
 	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
 		return s.repo.Delete(ctx, tx, machineID, productID)
 	})
 }
 
+// Exists reports whether the machine has ever been registered for the product (within tx)
+func (s *Service) Exists(ctx context.Context, tx *sqlx.Tx, machineID, productID int64) (bool, error) {
+	return s.repo.Exists(ctx, tx, machineID, productID)
+}
+
 func (s *Service) UpdateInstalledVersion(ctx context.Context, machineID, productID int64, version string) error {
 	return s.repo.UpdateInstalledVersion(ctx, machineID, productID, version)
 }
+
+// CheckIn records that the machine called in now with the details it reported,
+// and that it checked in today, clearing any idle flag. A released registration
+// is only marked as seen; unknown registrations are left unchanged.
+func (s *Service) CheckIn(ctx context.Context, machineID, productID int64, info ClientInfo) error {
+	return s.repo.CheckIn(ctx, machineID, productID, time.Now().UTC().Format(TimeFormat), info)
+}
+
+// CountByProduct returns total and active (non-expired) registrations for every product
+func (s *Service) CountByProduct(ctx context.Context) ([]ProductCount, error) {
+	return s.repo.CountByProduct(ctx)
+}
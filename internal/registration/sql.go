@@ -4,11 +4,19 @@ const getRegistrationSQL = `
 SELECT
     machine_id,
     product_id,
+    license_id,
     expiration_date,
     registration_hash,
     first_registration_date,
     last_registration_date,
-    installed_version
+    installed_version,
+    COALESCE(last_checkin_date, '') AS last_checkin_date,
+    COALESCE(idle_date, '') AS idle_date,
+    COALESCE(released_date, '') AS released_date,
+    COALESCE(last_seen_at, '') AS last_seen_at,
+    COALESCE(client_os, '') AS client_os,
+    COALESCE(client_hostname, '') AS client_hostname,
+    COALESCE(client_ip, '') AS client_ip
 FROM registration
 WHERE machine_id = ? AND product_id = ?
 `
@@ -17,30 +25,114 @@ const getRegistrationsForMachineSQL = `
 SELECT
     machine_id,
     product_id,
+    license_id,
     expiration_date,
     registration_hash,
     first_registration_date,
     last_registration_date,
-    installed_version
+    installed_version,
+    COALESCE(last_checkin_date, '') AS last_checkin_date,
+    COALESCE(idle_date, '') AS idle_date,
+    COALESCE(released_date, '') AS released_date,
+    COALESCE(last_seen_at, '') AS last_seen_at,
+    COALESCE(client_os, '') AS client_os,
+    COALESCE(client_hostname, '') AS client_hostname,
+    COALESCE(client_ip, '') AS client_ip
 FROM registration
 WHERE machine_id = ?
 ORDER BY product_id
 `
 
+// The issued registrations skip released ones, so re-issuing never extends a
+// seat the inactivity policy released
+const getIssuedForLicenseSQL = `
+SELECT
+    r.machine_id,
+    r.product_id,
+    r.license_id,
+    r.expiration_date,
+    r.registration_hash,
+    r.first_registration_date,
+    r.last_registration_date,
+    r.installed_version,
+    COALESCE(r.last_checkin_date, '') AS last_checkin_date,
+    COALESCE(r.idle_date, '') AS idle_date,
+    COALESCE(r.released_date, '') AS released_date,
+    COALESCE(r.last_seen_at, '') AS last_seen_at,
+    COALESCE(r.client_os, '') AS client_os,
+    COALESCE(r.client_hostname, '') AS client_hostname,
+    COALESCE(r.client_ip, '') AS client_ip,
+    m.machine_code
+FROM registration r
+JOIN machine m ON m.machine_id = r.machine_id
+WHERE r.license_id = ? AND r.released_date IS NULL
+ORDER BY r.machine_id
+`
+
+const getIssuedForProductSQL = `
+SELECT
+    r.machine_id,
+    r.product_id,
+    r.license_id,
+    r.expiration_date,
+    r.registration_hash,
+    r.first_registration_date,
+    r.last_registration_date,
+    r.installed_version,
+    COALESCE(r.last_checkin_date, '') AS last_checkin_date,
+    COALESCE(r.idle_date, '') AS idle_date,
+    COALESCE(r.released_date, '') AS released_date,
+    COALESCE(r.last_seen_at, '') AS last_seen_at,
+    COALESCE(r.client_os, '') AS client_os,
+    COALESCE(r.client_hostname, '') AS client_hostname,
+    COALESCE(r.client_ip, '') AS client_ip,
+    m.machine_code
+FROM registration r
+JOIN machine m ON m.machine_id = r.machine_id
+WHERE r.product_id = ? AND r.license_id IS NOT NULL AND r.released_date IS NULL
+ORDER BY r.license_id, r.machine_id
+`
+
+const getIssuedForMachineSQL = `
+SELECT
+    r.machine_id,
+    r.product_id,
+    r.license_id,
+    r.expiration_date,
+    r.registration_hash,
+    r.first_registration_date,
+    r.last_registration_date,
+    r.installed_version,
+    COALESCE(r.last_checkin_date, '') AS last_checkin_date,
+    COALESCE(r.idle_date, '') AS idle_date,
+    COALESCE(r.released_date, '') AS released_date,
+    COALESCE(r.last_seen_at, '') AS last_seen_at,
+    COALESCE(r.client_os, '') AS client_os,
+    COALESCE(r.client_hostname, '') AS client_hostname,
+    COALESCE(r.client_ip, '') AS client_ip,
+    m.machine_code
+FROM registration r
+JOIN machine m ON m.machine_id = r.machine_id
+WHERE r.machine_id = ? AND r.license_id IS NOT NULL AND r.released_date IS NULL
+ORDER BY r.product_id
+`
+
 const createRegistrationSQL = `
 INSERT INTO registration (
     machine_id,
     product_id,
+    license_id,
     expiration_date,
     registration_hash,
     first_registration_date,
     last_registration_date
-) VALUES (?, ?, ?, ?, ?, ?)
+) VALUES (?, ?, ?, ?, ?, ?, ?)
 `
 
 const updateRegistrationSQL = `
 UPDATE registration
 SET
+    license_id = ?,
     expiration_date = ?,
     registration_hash = ?,
     first_registration_date = ?,
@@ -50,23 +142,62 @@ WHERE machine_id = ? AND product_id = ?
 
 /*
 - first_registration_date is only set on insert
+- license_id moves to the license the machine last activated with
 - last_registration_date is always updated
 - expiration_date is refreshed from customer_product
 - registration_hash is updated (your original code used machineCode)
+- idle_date and released_date are cleared, as activating is activity
+- last_seen_at is updated; client details the client did not send are kept
 */
 const upsertRegistrationSQL = `
 INSERT INTO registration (
     machine_id,
     product_id,
+    license_id,
     expiration_date,
     registration_hash,
     first_registration_date,
-    last_registration_date
-) VALUES (?, ?, ?, ?, ?, ?)
+    last_registration_date,
+    last_seen_at,
+    client_os,
+    client_hostname,
+    client_ip
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))
 ON CONFLICT(machine_id, product_id) DO UPDATE SET
+    license_id = excluded.license_id,
     expiration_date = excluded.expiration_date,
     registration_hash = excluded.registration_hash,
-    last_registration_date = excluded.last_registration_date
+    last_registration_date = excluded.last_registration_date,
+    idle_date = NULL,
+    released_date = NULL,
+    last_seen_at = excluded.last_seen_at,
+    client_os = COALESCE(excluded.client_os, client_os),
+    client_hostname = COALESCE(excluded.client_hostname, client_hostname),
+    client_ip = COALESCE(excluded.client_ip, client_ip)
+`
+
+// reissueRegistrationSQL keeps the registration dates, so a re-issued
+// registration keeps the feature names it was activated with
+const reissueRegistrationSQL = `
+UPDATE registration
+SET
+    expiration_date = ?,
+    registration_hash = ?
+WHERE machine_id = ? AND product_id = ?
+`
+
+// checkInSQL records a check-in from the machine. A released registration is
+// still seen, but stays released until the machine activates again.
+const checkInSQL = `
+UPDATE registration
+SET
+    last_seen_at = ?,
+    client_os = COALESCE(NULLIF(?, ''), client_os),
+    client_hostname = COALESCE(NULLIF(?, ''), client_hostname),
+    client_ip = COALESCE(NULLIF(?, ''), client_ip),
+    last_checkin_date = CASE WHEN released_date IS NULL THEN DATE('now') ELSE last_checkin_date END,
+    idle_date = CASE WHEN released_date IS NULL THEN NULL ELSE idle_date END
+WHERE machine_id = ? AND product_id = ?
 `
 
 const deleteRegistrationSQL = `
@@ -74,8 +205,26 @@ DELETE FROM registration
 WHERE machine_id = ? AND product_id = ?
 `
 
+const registrationExistsSQL = `
+SELECT EXISTS(
+    SELECT 1 FROM registration WHERE machine_id = ? AND product_id = ?
+)
+`
+
 const updateInstalledVersionSQL = `
 UPDATE registration
 SET installed_version = ?
 WHERE machine_id = ? AND product_id = ?
 `
+
+const countByProductSQL = `
+SELECT
+    p.product_id,
+    p.product_name,
+    COUNT(r.machine_id) AS total,
+    COALESCE(SUM(CASE WHEN r.expiration_date >= DATE('now') THEN 1 ELSE 0 END), 0) AS active
+FROM product p
+LEFT JOIN registration r ON r.product_id = p.product_id
+GROUP BY p.product_id, p.product_name
+ORDER BY p.product_name
+`
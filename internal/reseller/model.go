@@ -0,0 +1,81 @@
+package reseller
+
+import "errors"
+
+var (
+	// ErrNameRequired is returned for a reseller without a name
+	ErrNameRequired = errors.New("reseller name is required")
+
+	// ErrNegativeAllocation is returned for a seat allocation below zero
+	ErrNegativeAllocation = errors.New("seat allocation cannot be negative")
+
+	// ErrInvalidKey is returned when an API key does not belong to any reseller
+	ErrInvalidKey = errors.New("invalid reseller API key")
+
+	// ErrAllocationExceeded is returned when a license would take a reseller
+	// past its seat allocation
+	ErrAllocationExceeded = errors.New("reseller seat allocation exceeded")
+
+	// ErrOutOfScope is returned when a reseller API key is used on a customer
+	// (or anything belonging to one) that the reseller does not manage
+	ErrOutOfScope = errors.New("not managed by this reseller")
+
+	// ErrAdminOnly is returned when a reseller API key is used for an
+	// operation reserved for the admin API key
+	ErrAdminOnly = errors.New("not available to reseller API keys")
+)
+
+// Reseller is a partner that sells licenses to its own customers. A reseller
+// authenticates to the admin API with its own key and may only manage its
+// customers' licenses, up to SeatAllocation seats in total.
+type Reseller struct {
+	ResellerID     int64  `db:"reseller_id" json:"resellerId"`
+	ResellerName   string `db:"reseller_name" json:"resellerName"`
+	Email          string `db:"email" json:"email"`
+	SeatAllocation int    `db:"seat_allocation" json:"seatAllocation"`
+	KeyHash        string `db:"api_key_hash" json:"-"`
+	KeyPrefix      string `db:"api_key_prefix" json:"keyPrefix"`
+	Notes          string `db:"notes" json:"notes"`
+	CreatedAt      string `db:"created_at" json:"createdAt"`
+}
+
+// Validate checks the reseller's fields
+func (r *Reseller) Validate() error {
+	if r.ResellerName == "" {
+		return ErrNameRequired
+	}
+	if r.SeatAllocation < 0 {
+		return ErrNegativeAllocation
+	}
+	return nil
+}
+
+// Usage reports a reseller's license seats against its allocation
+type Usage struct {
+	ResellerID     int64           `json:"resellerId"`
+	ResellerName   string          `json:"resellerName"`
+	SeatAllocation int             `json:"seatAllocation"`
+	SeatsIssued    int             `json:"seatsIssued"`    // sum of license counts
+	SeatsAvailable int             `json:"seatsAvailable"` // allocation not yet issued (never negative)
+	SeatsInUse     int             `json:"seatsInUse"`     // machines with an active registration
+	Customers      []CustomerUsage `json:"customers"`
+	Products       []ProductUsage  `json:"products"`
+}
+
+// CustomerUsage is one reseller customer's share of the reseller's seats
+type CustomerUsage struct {
+	CustomerID   int64  `db:"customer_id" json:"customerId"`
+	CustomerName string `db:"customer_name" json:"customerName"`
+	Licenses     int    `db:"licenses" json:"licenses"`
+	Seats        int    `db:"seats" json:"seats"`
+	SeatsInUse   int    `db:"seats_in_use" json:"seatsInUse"`
+}
+
+// ProductUsage is one product's share of the reseller's seats
+type ProductUsage struct {
+	ProductID   int64  `db:"product_id" json:"productId"`
+	ProductName string `db:"product_name" json:"productName"`
+	Licenses    int    `db:"licenses" json:"licenses"`
+	Seats       int    `db:"seats" json:"seats"`
+	SeatsInUse  int    `db:"seats_in_use" json:"seatsInUse"`
+}
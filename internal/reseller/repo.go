@@ -0,0 +1,144 @@
+package reseller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]Reseller, error)
+	Get(ctx context.Context, id int64) (*Reseller, error)
+	GetByKeyHash(ctx context.Context, hash string) (*Reseller, error)
+	Create(ctx context.Context, tx *sqlx.Tx, r *Reseller) (int64, error)
+	Update(ctx context.Context, tx *sqlx.Tx, r *Reseller) error
+	UpdateKey(ctx context.Context, tx *sqlx.Tx, id int64, hash, prefix string) error
+	Delete(ctx context.Context, tx *sqlx.Tx, id int64) error
+	SeatsIssued(ctx context.Context, id int64, exceptLicenseIDs []int64) (int, error)
+	CustomerUsage(ctx context.Context, id int64) ([]CustomerUsage, error)
+	ProductUsage(ctx context.Context, id int64) ([]ProductUsage, error)
+}
+
+type repo struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) Repository {
+	return &repo{db: db}
+}
+
+func (r *repo) GetAll(ctx context.Context) ([]Reseller, error) {
+	var out []Reseller
+	err := r.db.SelectContext(ctx, &out, getAllResellersSQL)
+	if err != nil {
+		return nil, fmt.Errorf("get all resellers: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) Get(ctx context.Context, id int64) (*Reseller, error) {
+	var out Reseller
+	err := r.db.GetContext(ctx, &out, getResellerSQL, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("reseller not found (%d)", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reseller: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *repo) GetByKeyHash(ctx context.Context, hash string) (*Reseller, error) {
+	var out Reseller
+	err := r.db.GetContext(ctx, &out, getResellerByKeyHashSQL, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reseller by key: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *repo) Create(ctx context.Context, tx *sqlx.Tx, rs *Reseller) (int64, error) {
+	res, err := tx.ExecContext(ctx, createResellerSQL,
+		rs.ResellerName,
+		rs.Email,
+		rs.SeatAllocation,
+		rs.KeyHash,
+		rs.KeyPrefix,
+		rs.Notes,
+		rs.CreatedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create reseller: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *repo) Update(ctx context.Context, tx *sqlx.Tx, rs *Reseller) error {
+	_, err := tx.ExecContext(ctx, updateResellerSQL,
+		rs.ResellerName,
+		rs.Email,
+		rs.SeatAllocation,
+		rs.Notes,
+		rs.ResellerID,
+	)
+	if err != nil {
+		return fmt.Errorf("update reseller: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) UpdateKey(ctx context.Context, tx *sqlx.Tx, id int64, hash, prefix string) error {
+	_, err := tx.ExecContext(ctx, updateKeySQL, hash, prefix, id)
+	if err != nil {
+		return fmt.Errorf("update reseller key: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) Delete(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, deleteResellerSQL, id)
+	if err != nil {
+		return fmt.Errorf("delete reseller: %w", err)
+	}
+	return nil
+}
+
+func (r *repo) SeatsIssued(ctx context.Context, id int64, exceptLicenseIDs []int64) (int, error) {
+	if len(exceptLicenseIDs) == 0 {
+		exceptLicenseIDs = []int64{0}
+	}
+	query, args, err := sqlx.In(seatsIssuedSQL, id, exceptLicenseIDs)
+	if err != nil {
+		return 0, fmt.Errorf("reseller seats issued: %w", err)
+	}
+	var n int
+	err = r.db.GetContext(ctx, &n, r.db.Rebind(query), args...)
+	if err != nil {
+		return 0, fmt.Errorf("reseller seats issued: %w", err)
+	}
+	return n, nil
+}
+
+func (r *repo) CustomerUsage(ctx context.Context, id int64) ([]CustomerUsage, error) {
+	out := []CustomerUsage{}
+	err := r.db.SelectContext(ctx, &out, customerUsageSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("reseller customer usage: %w", err)
+	}
+	return out, nil
+}
+
+func (r *repo) ProductUsage(ctx context.Context, id int64) ([]ProductUsage, error) {
+	out := []ProductUsage{}
+	err := r.db.SelectContext(ctx, &out, productUsageSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("reseller product usage: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,17 @@
+package reseller
+
+import "context"
+
+type scopeKey struct{}
+
+// WithScope returns a copy of ctx restricted to a reseller's customers
+func WithScope(ctx context.Context, resellerID int64) context.Context {
+	return context.WithValue(ctx, scopeKey{}, resellerID)
+}
+
+// ScopeFromContext returns the reseller a request is restricted to. It returns
+// false for unrestricted (admin) requests.
+func ScopeFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(scopeKey{}).(int64)
+	return id, ok
+}
@@ -0,0 +1,198 @@
+package reseller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// KeyPrefix starts every reseller API key, so reseller keys can be told apart
+// from the admin key without a database lookup
+const KeyPrefix = "rsk_"
+
+// keyPrefixLen is the number of key characters stored for display
+const keyPrefixLen = 12
+
+type Service struct {
+	repo Repository
+	db   *sqlx.DB
+}
+
+func NewService(db *sqlx.DB) *Service {
+	return &Service{
+		db:   db,
+		repo: New(db),
+	}
+}
+
+func (s *Service) WithTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]Reseller, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *Service) Get(ctx context.Context, id int64) (*Reseller, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Create creates a reseller and returns it with its API key. Only a hash of
+// the key is stored, so the key cannot be shown again.
+func (s *Service) Create(ctx context.Context, r *Reseller) (*Reseller, string, error) {
+	if err := r.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return nil, "", err
+	}
+	r.KeyHash = HashKey(key)
+	r.KeyPrefix = key[:keyPrefixLen]
+	r.CreatedAt = time.Now().UTC().Format("2006-01-02 15:04:05")
+
+	var id int64
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		id, err = s.repo.Create(ctx, tx, r)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	created, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, key, nil
+}
+
+// Update changes a reseller's details and seat allocation. Lowering the
+// allocation below the seats already issued is allowed; the reseller just
+// cannot issue more until it is back under.
+func (s *Service) Update(ctx context.Context, r *Reseller) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.repo.Get(ctx, r.ResellerID); err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.repo.Update(ctx, tx, r)
+	})
+}
+
+// Delete removes a reseller. Its customers become direct customers.
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	return s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.repo.Delete(ctx, tx, id)
+	})
+}
+
+// RotateKey replaces a reseller's API key. The old key stops working immediately.
+func (s *Service) RotateKey(ctx context.Context, id int64) (string, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return "", err
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return "", err
+	}
+	err = s.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return s.repo.UpdateKey(ctx, tx, id, HashKey(key), key[:keyPrefixLen])
+	})
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Authenticate returns the reseller owning an API key
+func (s *Service) Authenticate(ctx context.Context, key string) (*Reseller, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+	return s.repo.GetByKeyHash(ctx, HashKey(key))
+}
+
+// CheckAllocation returns ErrAllocationExceeded if issuing the requested
+// seats would take the reseller past its seat allocation. The seats of the
+// licenses being changed (licenseIDs, none for new licenses) are replaced by
+// the request rather than counted twice.
+func (s *Service) CheckAllocation(ctx context.Context, resellerID int64, licenseIDs []int64, requested int) error {
+	r, err := s.repo.Get(ctx, resellerID)
+	if err != nil {
+		return err
+	}
+	issued, err := s.repo.SeatsIssued(ctx, resellerID, licenseIDs)
+	if err != nil {
+		return err
+	}
+	if issued+requested > r.SeatAllocation {
+		return fmt.Errorf("%w: %d of %d seats issued, %d requested",
+			ErrAllocationExceeded, issued, r.SeatAllocation, requested)
+	}
+	return nil
+}
+
+// Usage reports a reseller's issued and used seats by customer and product
+func (s *Service) Usage(ctx context.Context, id int64) (*Usage, error) {
+	r, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	customers, err := s.repo.CustomerUsage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	products, err := s.repo.ProductUsage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &Usage{
+		ResellerID:     r.ResellerID,
+		ResellerName:   r.ResellerName,
+		SeatAllocation: r.SeatAllocation,
+		Customers:      customers,
+		Products:       products,
+	}
+	for _, c := range customers {
+		u.SeatsIssued += c.Seats
+		u.SeatsInUse += c.SeatsInUse
+	}
+	u.SeatsAvailable = max(r.SeatAllocation-u.SeatsIssued, 0)
+	return u, nil
+}
+
+// HashKey returns the stored form of a reseller API key (hex SHA-256)
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// newKey generates a random reseller API key
+func newKey() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate reseller key: %w", err)
+	}
+	return KeyPrefix + hex.EncodeToString(b), nil
+}
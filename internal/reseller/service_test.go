@@ -0,0 +1,177 @@
+package reseller_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"winsbygroup.com/regserver/internal/customer"
+	"winsbygroup.com/regserver/internal/license"
+	"winsbygroup.com/regserver/internal/product"
+	"winsbygroup.com/regserver/internal/reseller"
+	"winsbygroup.com/regserver/internal/testutil"
+)
+
+func TestResellerKeys(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	svc := reseller.NewService(db)
+
+	t.Run("validation", func(t *testing.T) {
+		if _, _, err := svc.Create(ctx, &reseller.Reseller{}); !errors.Is(err, reseller.ErrNameRequired) {
+			t.Errorf("expected ErrNameRequired, got %v", err)
+		}
+		_, _, err := svc.Create(ctx, &reseller.Reseller{ResellerName: "X", SeatAllocation: -1})
+		if !errors.Is(err, reseller.ErrNegativeAllocation) {
+			t.Errorf("expected ErrNegativeAllocation, got %v", err)
+		}
+	})
+
+	r, key, err := svc.Create(ctx, &reseller.Reseller{ResellerName: "Partner Ltd", SeatAllocation: 10})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	t.Run("key is stored hashed", func(t *testing.T) {
+		if !strings.HasPrefix(key, reseller.KeyPrefix) {
+			t.Errorf("expected key to start with %q, got %q", reseller.KeyPrefix, key)
+		}
+		if r.KeyHash == key || r.KeyHash != reseller.HashKey(key) {
+			t.Error("expected the stored key to be the hash of the key")
+		}
+		if !strings.HasPrefix(key, r.KeyPrefix) {
+			t.Errorf("expected key prefix %q to be the start of the key", r.KeyPrefix)
+		}
+	})
+
+	t.Run("authenticate", func(t *testing.T) {
+		got, err := svc.Authenticate(ctx, key)
+		if err != nil {
+			t.Fatalf("authenticate: %v", err)
+		}
+		if got.ResellerID != r.ResellerID {
+			t.Errorf("expected reseller %d, got %d", r.ResellerID, got.ResellerID)
+		}
+		if _, err := svc.Authenticate(ctx, key+"x"); !errors.Is(err, reseller.ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+		if _, err := svc.Authenticate(ctx, "not-a-reseller-key"); !errors.Is(err, reseller.ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+
+	t.Run("rotate key", func(t *testing.T) {
+		newKey, err := svc.RotateKey(ctx, r.ResellerID)
+		if err != nil {
+			t.Fatalf("rotate: %v", err)
+		}
+		if newKey == key {
+			t.Fatal("expected a new key")
+		}
+		if _, err := svc.Authenticate(ctx, key); !errors.Is(err, reseller.ErrInvalidKey) {
+			t.Errorf("expected old key to stop working, got %v", err)
+		}
+		if _, err := svc.Authenticate(ctx, newKey); err != nil {
+			t.Errorf("expected new key to work: %v", err)
+		}
+	})
+}
+
+func TestResellerAllocationAndUsage(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+
+	svc := reseller.NewService(db)
+	custSvc := customer.NewService(db)
+	prodSvc := product.NewService(db)
+	licSvc := license.NewService(db)
+
+	r, _, err := svc.Create(ctx, &reseller.Reseller{ResellerName: "Partner Ltd", SeatAllocation: 10})
+	if err != nil {
+		t.Fatalf("create reseller: %v", err)
+	}
+
+	c1, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "End Customer 1", ResellerID: &r.ResellerID})
+	c2, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "End Customer 2", ResellerID: &r.ResellerID})
+	direct, _ := custSvc.Create(ctx, &customer.Customer{CustomerName: "Direct Customer"})
+	p1, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Widget", ProductGUID: "GUID-RS-1"})
+	p2, _ := prodSvc.Create(ctx, &product.Product{ProductName: "Gadget", ProductGUID: "GUID-RS-2"})
+
+	newLicense := func(customerID, productID int64, key string, seats int) int64 {
+		lic, err := licSvc.Create(ctx, &license.License{
+			CustomerID:          customerID,
+			ProductID:           productID,
+			LicenseKey:          key,
+			LicenseCount:        seats,
+			StartDate:           "2024-01-01",
+			ExpirationDate:      "2099-12-31",
+			MaintExpirationDate: "2099-12-31",
+		})
+		if err != nil {
+			t.Fatalf("create license: %v", err)
+		}
+		return lic.LicenseID
+	}
+	lic1 := newLicense(c1.CustomerID, p1.ProductID, "RS-LIC-1", 4)
+	newLicense(c2.CustomerID, p1.ProductID, "RS-LIC-2", 3)
+	newLicense(direct.CustomerID, p1.ProductID, "RS-LIC-3", 50) // not the reseller's
+
+	t.Run("check allocation", func(t *testing.T) {
+		// 7 of 10 seats issued
+		if err := svc.CheckAllocation(ctx, r.ResellerID, nil, 3); err != nil {
+			t.Errorf("expected 3 more seats to fit: %v", err)
+		}
+		if err := svc.CheckAllocation(ctx, r.ResellerID, nil, 4); !errors.Is(err, reseller.ErrAllocationExceeded) {
+			t.Errorf("expected ErrAllocationExceeded, got %v", err)
+		}
+		// Changing an existing license only counts the difference
+		if err := svc.CheckAllocation(ctx, r.ResellerID, []int64{lic1}, 7); err != nil {
+			t.Errorf("expected raising 4 to 7 seats to fit: %v", err)
+		}
+		if err := svc.CheckAllocation(ctx, r.ResellerID, []int64{lic1}, 8); !errors.Is(err, reseller.ErrAllocationExceeded) {
+			t.Errorf("expected ErrAllocationExceeded, got %v", err)
+		}
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		newLicense(c2.CustomerID, p2.ProductID, "RS-LIC-4", 2)
+
+		u, err := svc.Usage(ctx, r.ResellerID)
+		if err != nil {
+			t.Fatalf("usage: %v", err)
+		}
+		if u.SeatAllocation != 10 || u.SeatsIssued != 9 || u.SeatsAvailable != 1 {
+			t.Errorf("unexpected seat totals: %+v", u)
+		}
+		if len(u.Customers) != 2 {
+			t.Fatalf("expected 2 customers, got %d", len(u.Customers))
+		}
+		if u.Customers[1].CustomerName != "End Customer 2" || u.Customers[1].Licenses != 2 || u.Customers[1].Seats != 5 {
+			t.Errorf("unexpected customer usage: %+v", u.Customers[1])
+		}
+		if len(u.Products) != 2 {
+			t.Fatalf("expected 2 products, got %d", len(u.Products))
+		}
+		// Ordered by product name: Gadget, Widget
+		if u.Products[1].ProductName != "Widget" || u.Products[1].Seats != 7 {
+			t.Errorf("unexpected product usage: %+v", u.Products[1])
+		}
+	})
+
+	t.Run("deleting a reseller keeps its customers", func(t *testing.T) {
+		if err := svc.Delete(ctx, r.ResellerID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		got, err := custSvc.Get(ctx, c1.CustomerID)
+		if err != nil {
+			t.Fatalf("get customer: %v", err)
+		}
+		if got.ResellerID != nil {
+			t.Errorf("expected a direct customer, got reseller %d", *got.ResellerID)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package reseller
+
+const resellerColumns = `
+    reseller_id, reseller_name, email, seat_allocation, api_key_hash, api_key_prefix, notes, created_at
+`
+
+const getAllResellersSQL = `
+SELECT` + resellerColumns + `FROM reseller
+ORDER BY reseller_name
+`
+
+const getResellerSQL = `
+SELECT` + resellerColumns + `FROM reseller
+WHERE reseller_id = ?
+`
+
+const getResellerByKeyHashSQL = `
+SELECT` + resellerColumns + `FROM reseller
+WHERE api_key_hash = ?
+`
+
+const createResellerSQL = `
+INSERT INTO reseller (
+    reseller_name, email, seat_allocation, api_key_hash, api_key_prefix, notes, created_at
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+const updateResellerSQL = `
+UPDATE reseller
+SET reseller_name = ?, email = ?, seat_allocation = ?, notes = ?
+WHERE reseller_id = ?
+`
+
+const updateKeySQL = `
+UPDATE reseller
+SET api_key_hash = ?, api_key_prefix = ?
+WHERE reseller_id = ?
+`
+
+const deleteResellerSQL = `
+DELETE FROM reseller
+WHERE reseller_id = ?
+`
+
+// seatsIssuedSQL sums the license counts of a reseller's customers, leaving
+// out the given licenses (those being changed). Expanded with sqlx.In.
+const seatsIssuedSQL = `
+SELECT COALESCE(SUM(l.license_count), 0)
+FROM license l
+JOIN customer c ON c.customer_id = l.customer_id
+WHERE c.reseller_id = ?
+  AND l.license_id NOT IN (?)
+`
+
+const customerUsageSQL = `
+SELECT
+    c.customer_id,
+    c.customer_name,
+    COUNT(l.license_id) AS licenses,
+    COALESCE(SUM(l.license_count), 0) AS seats,
+    (
+        SELECT COUNT(*)
+        FROM registration r
+        JOIN machine m ON m.machine_id = r.machine_id
+        WHERE m.customer_id = c.customer_id
+          AND r.expiration_date >= DATE('now')
+    ) AS seats_in_use
+FROM customer c
+LEFT JOIN license l ON l.customer_id = c.customer_id
+WHERE c.reseller_id = ?
+GROUP BY c.customer_id, c.customer_name
+ORDER BY c.customer_name
+`
+
+const productUsageSQL = `
+SELECT
+    p.product_id,
+    p.product_name,
+    COUNT(*) AS licenses,
+    SUM(l.license_count) AS seats,
+    (
+        SELECT COUNT(*)
+        FROM registration r
+        JOIN machine m ON m.machine_id = r.machine_id
+        JOIN customer c2 ON c2.customer_id = m.customer_id
+        WHERE c2.reseller_id = c.reseller_id
+          AND r.product_id = p.product_id
+          AND r.expiration_date >= DATE('now')
+    ) AS seats_in_use
+FROM license l
+JOIN customer c ON c.customer_id = l.customer_id
+JOIN product p ON p.product_id = l.product_id
+WHERE c.reseller_id = ?
+GROUP BY p.product_id, p.product_name
+ORDER BY p.product_name
+`
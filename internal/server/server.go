@@ -1,12 +1,16 @@
 package server
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
@@ -15,16 +19,25 @@ import (
 	mwsvc "winsbygroup.com/regserver/internal/middleware"
 
 	"winsbygroup.com/regserver/internal/activation"
+	"winsbygroup.com/regserver/internal/analytics"
 	"winsbygroup.com/regserver/internal/backup"
+	"winsbygroup.com/regserver/internal/bulk"
+	"winsbygroup.com/regserver/internal/bundle"
 	"winsbygroup.com/regserver/internal/config"
 	"winsbygroup.com/regserver/internal/customer"
 	"winsbygroup.com/regserver/internal/demodata"
 	"winsbygroup.com/regserver/internal/feature"
 	"winsbygroup.com/regserver/internal/featurevalue"
+	"winsbygroup.com/regserver/internal/inactivity"
 	"winsbygroup.com/regserver/internal/license"
 	"winsbygroup.com/regserver/internal/machine"
+	"winsbygroup.com/regserver/internal/mailer"
+	"winsbygroup.com/regserver/internal/metrics"
+	"winsbygroup.com/regserver/internal/openapi"
+	"winsbygroup.com/regserver/internal/plan"
 	"winsbygroup.com/regserver/internal/product"
 	"winsbygroup.com/regserver/internal/registration"
+	"winsbygroup.com/regserver/internal/reseller"
 	"winsbygroup.com/regserver/internal/sqlite"
 	"winsbygroup.com/regserver/static"
 
@@ -37,6 +50,17 @@ type Server struct {
 	Echo *echo.Echo
 	HTTP *http.Server
 	DB   *sqlx.DB
+
+	inactivitySvc      *inactivity.Service
+	inactivityInterval time.Duration
+}
+
+// StartJobs starts the background jobs, which stop when ctx is done
+func (s *Server) StartJobs(ctx context.Context) {
+	if s.inactivityInterval > 0 {
+		slog.Info("inactivity policy scheduled", "interval", s.inactivityInterval)
+		go s.inactivitySvc.Schedule(ctx, s.inactivityInterval)
+	}
 }
 
 func Build(cfg *config.Config) (*Server, error) {
@@ -49,6 +73,19 @@ func Build(cfg *config.Config) (*Server, error) {
 	if cfg.RegistrationSecret == "" {
 		return nil, errors.New("REGISTRATION_SECRET environment variable is required")
 	}
+	// Client IPs (rate limiting, lockouts, logs) come from X-Forwarded-For
+	// only when the request arrives through a trusted proxy
+	ipExtractor, err := mwsvc.IPExtractor(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	// Portal sign-in links are emailed, so their address must not come from
+	// the request's Host header
+	if cfg.Portal {
+		if err := validatePublicURL(cfg.PublicURL); err != nil {
+			return nil, err
+		}
+	}
 
 	//
 	// Database
@@ -56,11 +93,11 @@ func Build(cfg *config.Config) (*Server, error) {
 	isNewDB := false
 	if _, err := os.Stat(cfg.DBPath); os.IsNotExist(err) {
 		isNewDB = true
-		log.Printf("Creating database '%s' (from %s setting)", cfg.DBPath, cfg.DBPathSource)
+		slog.Info("creating database", "path", cfg.DBPath, "source", cfg.DBPathSource)
 	} else {
-		log.Printf("Opening database '%s' (from %s setting)", cfg.DBPath, cfg.DBPathSource)
+		slog.Info("opening database", "path", cfg.DBPath, "source", cfg.DBPathSource)
 	}
-	db, err := sqlx.Connect("sqlite3", cfg.DBPath)
+	db, err := sqlx.Connect("sqlite3", sqlite.DSN(cfg.DBPath))
 	if err != nil {
 		return nil, err
 	}
@@ -71,13 +108,8 @@ func Build(cfg *config.Config) (*Server, error) {
 		return nil, err
 	}
 
-	// Foreign key support is required each time the database is open and
-	// is required by the program for cascade deletes
-	if _, err := db.Exec(`PRAGMA foreign_keys=ON;`); err != nil {
-		return nil, err
-	}
-
-	// Verify foreign keys are supported and enabled
+	// Foreign key support is required by the program for cascade deletes; it
+	// is enabled on every connection by the DSN. Verify it is supported.
 	var fkEnabled int
 	if err := db.QueryRow(`PRAGMA foreign_keys;`).Scan(&fkEnabled); err != nil {
 		return nil, errors.New("SQLite foreign key support check failed: " + err.Error())
@@ -95,7 +127,7 @@ func Build(cfg *config.Config) (*Server, error) {
 		if err := demodata.Load(db.DB); err != nil {
 			return nil, errors.New("failed to load demo data: " + err.Error())
 		}
-		log.Print("Demo data loaded")
+		slog.Info("demo data loaded")
 	}
 
 	//
@@ -107,7 +139,15 @@ func Build(cfg *config.Config) (*Server, error) {
 	featureSvc := feature.NewService(db)
 	featureValueSvc := featurevalue.NewService(db)
 	machineSvc := machine.NewService(db)
+	machineSvc.SetFingerprintMatch(cfg.FingerprintMatch)
 	registrationSvc := registration.NewService(db)
+	analyticsSvc := analytics.NewService(db)
+	resellerSvc := reseller.NewService(db)
+	bundleSvc := bundle.NewService(db, licenseSvc)
+	planSvc := plan.NewService(db, licenseSvc, featureSvc)
+	bulkSvc := bulk.NewService(db, licenseSvc, featureSvc, featureValueSvc)
+	inactivitySvc := inactivity.NewService(db)
+	inactivitySvc.SetNoticeDays(cfg.Inactivity.NoticeDays)
 
 	activationSvc := activation.NewService(
 		db,
@@ -119,8 +159,17 @@ func Build(cfg *config.Config) (*Server, error) {
 		productSvc,
 		featureSvc,
 		featureValueSvc,
+		analyticsSvc,
 	)
 
+	// Changed license terms, feature values, features, plans and drifted
+	// machine codes re-issue the stored registrations they affect
+	licenseSvc.SetReissuer(activationSvc)
+	featureValueSvc.SetReissuer(activationSvc)
+	featureSvc.SetReissuer(activationSvc)
+	planSvc.SetReissuer(activationSvc)
+	machineSvc.SetReissuer(activationSvc)
+
 	//
 	// Handlers
 	//
@@ -133,6 +182,7 @@ func Build(cfg *config.Config) (*Server, error) {
 		featureSvc,
 		featureValueSvc,
 		customerSvc,
+		bundleSvc,
 	)
 
 	adminSvc := adminhttp.NewService(
@@ -143,6 +193,12 @@ func Build(cfg *config.Config) (*Server, error) {
 		featureValueSvc,
 		machineSvc,
 		registrationSvc,
+		activationSvc,
+		resellerSvc,
+		bundleSvc,
+		planSvc,
+		bulkSvc,
+		inactivitySvc,
 	)
 	backupSvc := backup.NewService(db, cfg.DBPath)
 	adminHandler := adminhttp.NewHandler(adminSvc, backupSvc)
@@ -156,6 +212,45 @@ func Build(cfg *config.Config) (*Server, error) {
 		registrationSvc,
 		activationSvc,
 		backupSvc,
+		analyticsSvc,
+	)
+
+	portalStore := mwsvc.NewPortalStore()
+	portalHandler := webhttp.NewPortalHandler(
+		customerSvc,
+		licenseSvc,
+		productSvc,
+		machineSvc,
+		registrationSvc,
+		activationSvc,
+		mailer.New(cfg.SMTP),
+		portalStore,
+		cfg.PublicURL,
+	)
+
+	//
+	// Metrics (values owned elsewhere are read on each scrape)
+	//
+	metricsHandler := metrics.Handler(
+		metrics.DBStats(db.Stats),
+		metrics.BackupStats(func() (time.Time, int64, bool, error) {
+			last, at, err := backupSvc.LastBackup()
+			if err != nil || last == nil {
+				return time.Time{}, 0, false, err
+			}
+			return at, last.Size, true, nil
+		}),
+		metrics.RegistrationCounts(func() ([]metrics.ProductRegistrations, error) {
+			counts, err := registrationSvc.CountByProduct(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			out := make([]metrics.ProductRegistrations, len(counts))
+			for i, c := range counts {
+				out[i] = metrics.ProductRegistrations{Product: c.ProductName, Total: c.Total, Active: c.Active}
+			}
+			return out, nil
+		}),
 	)
 
 	//
@@ -163,6 +258,8 @@ func Build(cfg *config.Config) (*Server, error) {
 	//
 	e := echo.New()
 	e.HideBanner = true
+	e.HidePort = true // startup is logged by main
+	e.IPExtractor = ipExtractor
 
 	// Health endpoints
 	e.GET("/livez", func(c echo.Context) error {
@@ -177,18 +274,33 @@ func Build(cfg *config.Config) (*Server, error) {
 	})
 
 	// Middleware
-	e.Use(mwecho.Logger())
+	e.Use(mwsvc.RequestID())     // Echo/generate X-Request-ID and add it to the request logger
+	e.Use(mwsvc.RequestLogger()) // One structured log line per request
 	e.Use(mwecho.Recover())
+	e.Use(mwsvc.Metrics())
+
+	// Prometheus metrics (optionally protected by a bearer token)
+	e.GET("/metrics", echo.WrapHandler(metricsHandler), mwsvc.MetricsAuth(cfg.MetricsToken))
+
+	// Rate limiting and brute-force lockout (client license key endpoints and web login)
+	limiter := mwsvc.NewRateLimiter(cfg.RateLimit)
 
 	// Client API
 	clientGroup := e.Group("/api/v1")
-	clienthttp.RegisterRoutes(clientGroup, clientHandler, mwsvc.LicenseKeyAuth(db))
+	clienthttp.RegisterRoutes(clientGroup, clientHandler, mwsvc.LicenseKeyAuth(db), limiter.Guard())
 
 	// Admin API
 	adminGroup := e.Group("/api/admin")
-	adminGroup.Use(mwsvc.AdminAPIKeyAuth())
+	adminGroup.Use(mwsvc.AdminAPIKeyAuth(db))
 	adminhttp.RegisterRoutes(adminGroup, adminHandler)
 
+	// OpenAPI spec of the client and admin APIs
+	e.GET("/api/openapi.json", openapi.Handler())
+	if cfg.SwaggerUI {
+		e.GET("/api/docs", openapi.SwaggerUI())
+		e.GET("/api/docs/*", openapi.SwaggerAssets())
+	}
+
 	// Web UI
 	webGroup := e.Group("/web")
 	webGroup.Use(mwsvc.Theme())                // Read theme cookie into context
@@ -208,7 +320,28 @@ func Build(cfg *config.Config) (*Server, error) {
 		},
 	}))
 	webGroup.Use(mwsvc.CSRF()) // Copy CSRF token to request context for templates
-	webhttp.RegisterRoutes(webGroup, webHandler)
+	webhttp.RegisterRoutes(webGroup, webHandler, limiter.Guard())
+
+	// Customer portal (license key + emailed sign-in link)
+	if cfg.Portal {
+		portalGroup := e.Group("/portal")
+		portalGroup.Use(mwsvc.Theme())
+		portalGroup.Use(mwsvc.PortalAuth(portalStore))
+		portalGroup.Use(mwecho.CSRFWithConfig(mwecho.CSRFConfig{
+			TokenLookup:    "header:X-CSRF-Token,form:_csrf",
+			CookieName:     "_csrf",
+			CookiePath:     "/",
+			CookieSecure:   true,
+			CookieHTTPOnly: true,
+			CookieSameSite: http.SameSiteStrictMode,
+			Skipper: func(c echo.Context) bool {
+				// Skip CSRF for sign-in (customer not authenticated yet)
+				return strings.HasPrefix(c.Path(), "/portal/login") || strings.HasPrefix(c.Path(), "/portal/auth/")
+			},
+		}))
+		portalGroup.Use(mwsvc.CSRF())
+		webhttp.RegisterPortalRoutes(portalGroup, portalHandler, limiter.Guard())
+	}
 
 	// Static files (embedded)
 	jsFS, _ := fs.Sub(static.Files, "js")
@@ -228,8 +361,22 @@ func Build(cfg *config.Config) (*Server, error) {
 	}
 
 	return &Server{
-		Echo: e,
-		HTTP: srv,
-		DB:   db,
+		Echo:               e,
+		HTTP:               srv,
+		DB:                 db,
+		inactivitySvc:      inactivitySvc,
+		inactivityInterval: cfg.Inactivity.CheckInterval,
 	}, nil
 }
+
+// validatePublicURL checks that the public URL is an absolute http(s) URL
+func validatePublicURL(publicURL string) error {
+	if publicURL == "" {
+		return errors.New("PUBLIC_URL (public_url in config.yaml) is required when the customer portal is enabled")
+	}
+	u, err := url.Parse(publicURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid public URL %q: expected e.g. https://license.example.com", publicURL)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"winsbygroup.com/regserver/internal/config"
+	"winsbygroup.com/regserver/internal/server"
+)
+
+func TestBuild_PortalRequiresPublicURL(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "test-admin-key")
+
+	tests := []struct {
+		publicURL string
+		wantErr   string
+	}{
+		{"", "PUBLIC_URL"},
+		{"license.example.com", "invalid public URL"},
+		{"ftp://license.example.com", "invalid public URL"},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{
+			DBPath:             filepath.Join(t.TempDir(), "test.db"),
+			RegistrationSecret: "test-secret",
+			Portal:             true,
+			PublicURL:          tt.publicURL,
+		}
+		_, err := server.Build(cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Build with public URL %q: expected error containing %q, got %v", tt.publicURL, tt.wantErr, err)
+		}
+	}
+}
+
+func TestBuild_PortalRoutes(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "test-admin-key")
+
+	for _, portal := range []bool{false, true} {
+		srv, err := server.Build(&config.Config{
+			DBPath:             filepath.Join(t.TempDir(), "test.db"),
+			RegistrationSecret: "test-secret",
+			Portal:             portal,
+			PublicURL:          "https://license.example.com",
+		})
+		if err != nil {
+			t.Fatalf("Build (portal %v): %v", portal, err)
+		}
+		defer srv.DB.Close()
+
+		rec := httptest.NewRecorder()
+		srv.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/portal/login", nil))
+		if got := rec.Code != http.StatusNotFound; got != portal {
+			t.Errorf("portal %v: GET /portal/login returned %d", portal, rec.Code)
+		}
+	}
+}
@@ -68,14 +68,14 @@ func TestCascadeDeleteCustomer(t *testing.T) {
 			(2, 1, 'MACHINE-1B', 'user2'),
 			(3, 2, 'MACHINE-2A', 'user3');
 
-		INSERT INTO license (customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
-			(1, 1, 'LIC-001', 5, 0, 0, '9999-12-31');
+		INSERT INTO license (license_id, customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
+			(1, 1, 1, 'LIC-001', 5, 0, 0, '9999-12-31');
 
 		INSERT INTO feature (feature_id, product_id, feature_name, feature_type, default_value) VALUES
 			(1, 1, 'MaxUsers', 0, '10');
 
-		INSERT INTO license_feature (customer_id, product_id, feature_id, feature_value) VALUES
-			(1, 1, 1, '100');
+		INSERT INTO license_feature (license_id, feature_id, feature_value) VALUES
+			(1, 1, '100');
 
 		INSERT INTO registration (machine_id, product_id, expiration_date, registration_hash, first_registration_date, last_registration_date) VALUES
 			(1, 1, '2030-01-01', 'hash1', '2024-01-01', '2024-01-01'),
@@ -89,7 +89,7 @@ func TestCascadeDeleteCustomer(t *testing.T) {
 	if got := countWhere(t, db, "SELECT COUNT(*) FROM license WHERE customer_id = 1"); got != 1 {
 		t.Fatalf("expected 1 license for customer 1, got %d", got)
 	}
-	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE customer_id = 1"); got != 1 {
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE license_id = 1"); got != 1 {
 		t.Fatalf("expected 1 feature value for customer 1, got %d", got)
 	}
 	if got := countWhere(t, db, "SELECT COUNT(*) FROM registration WHERE machine_id IN (1, 2)"); got != 2 {
@@ -109,7 +109,7 @@ func TestCascadeDeleteCustomer(t *testing.T) {
 	if got := countWhere(t, db, "SELECT COUNT(*) FROM license WHERE customer_id = 1"); got != 0 {
 		t.Errorf("expected 0 licenses after delete, got %d", got)
 	}
-	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE customer_id = 1"); got != 0 {
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE license_id = 1"); got != 0 {
 		t.Errorf("expected 0 feature values after delete, got %d", got)
 	}
 	if got := countWhere(t, db, "SELECT COUNT(*) FROM registration WHERE machine_id IN (1, 2)"); got != 0 {
@@ -146,16 +146,16 @@ func TestCascadeDeleteProduct(t *testing.T) {
 			(1, 1, 'MACHINE-1', 'user1'),
 			(2, 1, 'MACHINE-2', 'user2');
 
-		INSERT INTO license (customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
-			(1, 1, 'LIC-001', 5, 0, 0, '9999-12-31');
+		INSERT INTO license (license_id, customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
+			(1, 1, 1, 'LIC-001', 5, 0, 0, '9999-12-31');
 
 		INSERT INTO feature (feature_id, product_id, feature_name, feature_type, default_value) VALUES
 			(1, 1, 'Feature1A', 0, '10'),
 			(2, 1, 'Feature1B', 0, '20'),
 			(3, 2, 'Feature2A', 0, '30');
 
-		INSERT INTO license_feature (customer_id, product_id, feature_id, feature_value) VALUES
-			(1, 1, 1, '100');
+		INSERT INTO license_feature (license_id, feature_id, feature_value) VALUES
+			(1, 1, '100');
 
 		INSERT INTO registration (machine_id, product_id, expiration_date, registration_hash, first_registration_date, last_registration_date) VALUES
 			(1, 1, '2030-01-01', 'hash1', '2024-01-01', '2024-01-01'),
@@ -172,7 +172,7 @@ func TestCascadeDeleteProduct(t *testing.T) {
 	if got := countWhere(t, db, "SELECT COUNT(*) FROM registration WHERE product_id = 1"); got != 2 {
 		t.Fatalf("expected 2 registrations for product 1, got %d", got)
 	}
-	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE product_id = 1"); got != 1 {
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE license_id = 1"); got != 1 {
 		t.Fatalf("expected 1 feature value for product 1, got %d", got)
 	}
 
@@ -192,7 +192,7 @@ func TestCascadeDeleteProduct(t *testing.T) {
 	if got := countWhere(t, db, "SELECT COUNT(*) FROM registration WHERE product_id = 1"); got != 0 {
 		t.Errorf("expected 0 registrations after delete, got %d", got)
 	}
-	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE product_id = 1"); got != 0 {
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE license_id = 1"); got != 0 {
 		t.Errorf("expected 0 feature values after delete, got %d", got)
 	}
 
@@ -251,12 +251,13 @@ func TestCascadeDeleteMachine(t *testing.T) {
 }
 
 // TestCascadeDeleteLicense verifies that deleting a license cascades to:
-// - license_feature (direct FK on composite key)
+// - license_feature (direct FK)
+// - registrations counting against the license (direct FK)
 func TestCascadeDeleteLicense(t *testing.T) {
 	db := testutil.NewTestDB(t)
 	ctx := context.Background()
 
-	// Create test data: 2 customers, 1 product, each with a license and feature values
+	// Create test data: 2 customers, 1 product, each with a license, feature values and a registration
 	insertTestData(t, db, `
 		INSERT INTO customer (customer_id, customer_name) VALUES
 			(1, 'Customer One'),
@@ -265,40 +266,54 @@ func TestCascadeDeleteLicense(t *testing.T) {
 		INSERT INTO product (product_id, product_name, product_guid, latest_version, download_url) VALUES
 			(1, 'Product One', 'guid-1', '1.0', 'http://example.com');
 
-		INSERT INTO license (customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
-			(1, 1, 'LIC-001', 5, 0, 0, '9999-12-31'),
-			(2, 1, 'LIC-002', 5, 0, 0, '9999-12-31');
+		INSERT INTO machine (machine_id, customer_id, machine_code, user_name) VALUES
+			(1, 1, 'MACHINE-1', 'user1'),
+			(2, 2, 'MACHINE-2', 'user2');
+
+		INSERT INTO license (license_id, customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
+			(1, 1, 1, 'LIC-001', 5, 0, 0, '9999-12-31'),
+			(2, 2, 1, 'LIC-002', 5, 0, 0, '9999-12-31');
 
 		INSERT INTO feature (feature_id, product_id, feature_name, feature_type, default_value) VALUES
 			(1, 1, 'MaxUsers', 0, '10'),
 			(2, 1, 'MaxSessions', 0, '5');
 
-		INSERT INTO license_feature (customer_id, product_id, feature_id, feature_value) VALUES
-			(1, 1, 1, '100'),
-			(1, 1, 2, '50'),
-			(2, 1, 1, '200');
+		INSERT INTO license_feature (license_id, feature_id, feature_value) VALUES
+			(1, 1, '100'),
+			(1, 2, '50'),
+			(2, 1, '200');
+
+		INSERT INTO registration (machine_id, product_id, license_id, expiration_date, registration_hash, first_registration_date, last_registration_date) VALUES
+			(1, 1, 1, '2030-01-01', 'hash1', '2024-01-01', '2024-01-01'),
+			(2, 1, 2, '2030-01-01', 'hash2', '2024-01-01', '2024-01-01');
 	`)
 
 	// Verify initial state
-	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE customer_id = 1 AND product_id = 1"); got != 2 {
-		t.Fatalf("expected 2 feature values for license (1,1), got %d", got)
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE license_id = 1"); got != 2 {
+		t.Fatalf("expected 2 feature values for license 1, got %d", got)
 	}
 
 	// Delete license for customer 1
 	licSvc := license.NewService(db)
-	if err := licSvc.Delete(ctx, 1, 1); err != nil {
+	if err := licSvc.Delete(ctx, 1); err != nil {
 		t.Fatalf("delete license: %v", err)
 	}
 
 	// Verify cascade deletion
-	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE customer_id = 1 AND product_id = 1"); got != 0 {
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE license_id = 1"); got != 0 {
 		t.Errorf("expected 0 feature values after delete, got %d", got)
 	}
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM registration WHERE license_id = 1"); got != 0 {
+		t.Errorf("expected 0 registrations after delete, got %d", got)
+	}
 
-	// Verify customer 2's feature values are intact
-	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE customer_id = 2"); got != 1 {
+	// Verify customer 2's feature values and registration are intact
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM license_feature WHERE license_id = 2"); got != 1 {
 		t.Errorf("expected customer 2's feature value to remain, got %d", got)
 	}
+	if got := countWhere(t, db, "SELECT COUNT(*) FROM registration WHERE license_id = 2"); got != 1 {
+		t.Errorf("expected customer 2's registration to remain, got %d", got)
+	}
 }
 
 // TestCascadeDeleteFeature verifies that deleting a feature does NOT cascade
@@ -315,14 +330,14 @@ func TestCascadeDeleteFeature(t *testing.T) {
 		INSERT INTO product (product_id, product_name, product_guid, latest_version, download_url) VALUES
 			(1, 'Product One', 'guid-1', '1.0', 'http://example.com');
 
-		INSERT INTO license (customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
-			(1, 1, 'LIC-001', 5, 0, 0, '9999-12-31');
+		INSERT INTO license (license_id, customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
+			(1, 1, 1, 'LIC-001', 5, 0, 0, '9999-12-31');
 
 		INSERT INTO feature (feature_id, product_id, feature_name, feature_type, default_value) VALUES
 			(1, 1, 'MaxUsers', 0, '10');
 
-		INSERT INTO license_feature (customer_id, product_id, feature_id, feature_value) VALUES
-			(1, 1, 1, '100');
+		INSERT INTO license_feature (license_id, feature_id, feature_value) VALUES
+			(1, 1, '100');
 	`)
 
 	// Attempting to delete the feature should fail due to FK constraint
@@ -420,9 +435,9 @@ func TestCascadeDoesNotAffectUnrelatedData(t *testing.T) {
 			(1, 1, 'MACHINE-1', 'user1'),
 			(2, 2, 'MACHINE-2', 'user2');
 
-		INSERT INTO license (customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
-			(1, 1, 'LIC-1-1', 5, 0, 0, '9999-12-31'),
-			(2, 2, 'LIC-2-2', 5, 0, 0, '9999-12-31');
+		INSERT INTO license (license_id, customer_id, product_id, license_key, license_count, is_subscription, license_term, maint_expiration_date) VALUES
+			(1, 1, 1, 'LIC-1-1', 5, 0, 0, '9999-12-31'),
+			(2, 2, 2, 'LIC-2-2', 5, 0, 0, '9999-12-31');
 
 		INSERT INTO feature (feature_id, product_id, feature_name, feature_type, default_value) VALUES
 			(1, 1, 'Feature1', 0, '10'),
@@ -0,0 +1,24 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BusyTimeout is how long a connection waits for another connection's lock
+// before failing with "database is locked" (milliseconds)
+const BusyTimeout = 5000
+
+// DSN returns the data source name the database at path is opened with. The
+// options apply to every connection of the pool: foreign keys are enforced,
+// a locked database is retried for BusyTimeout, and transactions begin
+// IMMEDIATE so they take the write lock up front. Transactions that read
+// before they write (such as a seat check and the registration it allows)
+// are serialized instead of overlapping.
+func DSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_foreign_keys=on&_busy_timeout=%d&_txlock=immediate", path, sep, BusyTimeout)
+}
@@ -0,0 +1,19 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/GuiaBolso/darwin"
+)
+
+// MigrateTo applies the migrations up to and including version, leaving the
+// database as an older release created it (for tests of later migrations)
+func MigrateTo(db *sql.DB, version float64) error {
+	var migrations []darwin.Migration
+	for _, m := range minifiedMigrations() {
+		if m.Version <= version {
+			migrations = append(migrations, m)
+		}
+	}
+	return darwin.New(darwin.NewGenericDriver(db, darwin.SqliteDialect{}), migrations, nil).Migrate()
+}
@@ -4,7 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 
 	"github.com/GuiaBolso/darwin"
@@ -139,10 +139,496 @@ func defineMigrations() []darwin.Migration {
 		);`},
 
 		{Version: 1.16, Description: "Create Index 'idx_licfeat_feature_id'", Script: `
-		CREATE INDEX IF NOT EXISTS idx_licfeat_feature_id ON license_feature (feature_id ASC);`},
+		CREATE INDEX IF NOT EXISTS idx_license_feature_feature_id ON license_feature (feature_id ASC);`},
 
 		{Version: 1.17, Description: "Create Index 'idx_licfeat_custid_prodid'", Script: `
 		CREATE INDEX IF NOT EXISTS idx_licfeat_custid_prodid ON license_feature (customer_id ASC, product_id ASC);`},
+
+		// 2.xx: activation analytics (append-only history of activations)
+
+		{Version: 2.01, Description: "Create Table 'activation_event'", Script: `
+		CREATE TABLE IF NOT EXISTS activation_event (
+			event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			machine_id INTEGER NOT NULL,
+			event_type VARCHAR(20) NOT NULL CHECK (event_type IN ('new','reactivation')),
+			user_name VARCHAR(255) NOT NULL DEFAULT '',
+			client_ip VARCHAR(45) NOT NULL DEFAULT '',
+			client_version VARCHAR(20) NOT NULL DEFAULT '',
+			event_time VARCHAR(19) NOT NULL,
+			FOREIGN KEY (customer_id) REFERENCES customer (customer_id) ON DELETE CASCADE,
+			FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE,
+			FOREIGN KEY (machine_id) REFERENCES machine (machine_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 2.02, Description: "Create Index 'idx_actevent_time'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_actevent_time ON activation_event (event_time ASC);`},
+
+		{Version: 2.03, Description: "Create Index 'idx_actevent_custid_prodid'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_actevent_custid_prodid ON activation_event (customer_id ASC, product_id ASC);`},
+
+		// 3.xx: license key rotation and revocation
+
+		{Version: 3.01, Description: "Add Column 'license.key_status'", Script: `
+		ALTER TABLE license ADD COLUMN key_status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (key_status IN ('active','suspended','revoked'));`},
+
+		{Version: 3.02, Description: "Create Table 'license_key_history'", Script: `
+		CREATE TABLE IF NOT EXISTS license_key_history (
+			history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			license_key VARCHAR(36) NOT NULL COLLATE NOCASE,
+			reason VARCHAR(255) NOT NULL DEFAULT '',
+			replaced_at VARCHAR(19) NOT NULL,
+			grace_until VARCHAR(19) NOT NULL,
+			revoked_at VARCHAR(19) NOT NULL DEFAULT '',
+			FOREIGN KEY (customer_id, product_id) REFERENCES license (customer_id, product_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 3.03, Description: "Create Unique Index 'idx_keyhist_key'", Script: `
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_keyhist_key ON license_key_history (license_key);`},
+
+		{Version: 3.04, Description: "Create Index 'idx_keyhist_custid_prodid'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_keyhist_custid_prodid ON license_key_history (customer_id ASC, product_id ASC);`},
+
+		// 4.xx: license status (active, suspended, cancelled) with a reason and the
+		// time of the last change
+
+		{Version: 4.01, Description: "Add Column 'license.status'", Script: `
+		ALTER TABLE license ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active','suspended','cancelled'));`},
+
+		{Version: 4.02, Description: "Add Column 'license.status_reason'", Script: `
+		ALTER TABLE license ADD COLUMN status_reason VARCHAR(255) NOT NULL DEFAULT '';`},
+
+		{Version: 4.03, Description: "Add Column 'license.status_changed_at'", Script: `
+		ALTER TABLE license ADD COLUMN status_changed_at VARCHAR(19) NOT NULL DEFAULT '';`},
+
+		// 5.xx: audit trail of machines moved between customers
+
+		{Version: 5.01, Description: "Create Table 'machine_transfer'", Script: `
+		CREATE TABLE IF NOT EXISTS machine_transfer (
+			transfer_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			machine_id INTEGER NOT NULL,
+			from_customer_id INTEGER NOT NULL,
+			to_customer_id INTEGER NOT NULL,
+			reason VARCHAR(255) NOT NULL DEFAULT '',
+			transferred_at VARCHAR(19) NOT NULL,
+			FOREIGN KEY (machine_id) REFERENCES machine (machine_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 5.02, Description: "Create Index 'idx_transfer_machine_id'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_transfer_machine_id ON machine_transfer (machine_id ASC);`},
+
+		// 6.xx: several contacts per customer with roles; the customer's contact
+		// columns are copied in as its primary contact
+
+		{Version: 6.01, Description: "Create Table 'customer_contact'", Script: `
+		CREATE TABLE IF NOT EXISTS customer_contact (
+			contact_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id INTEGER NOT NULL,
+			contact_name VARCHAR(255) NOT NULL DEFAULT '',
+			phone VARCHAR(255) NOT NULL DEFAULT '',
+			email VARCHAR(255) NOT NULL DEFAULT '',
+			roles VARCHAR(255) NOT NULL DEFAULT '',
+			is_primary BOOLEAN NOT NULL DEFAULT 0,
+			notes TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (customer_id) REFERENCES customer (customer_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 6.02, Description: "Create Indexes on 'customer_contact'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_contact_customer_id ON customer_contact (customer_id ASC);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_contact_primary ON customer_contact (customer_id) WHERE is_primary = 1;`},
+
+		{Version: 6.03, Description: "Copy customer contacts to 'customer_contact'", Script: `
+		INSERT INTO customer_contact (customer_id, contact_name, phone, email, roles, is_primary)
+		SELECT customer_id, COALESCE(contact_name, ''), COALESCE(phone, ''), COALESCE(email, ''), 'billing,technical,purchasing', 1
+		FROM customer
+		WHERE COALESCE(contact_name, '') <> '' OR COALESCE(phone, '') <> '' OR COALESCE(email, '') <> '';`},
+
+		// 7.xx: resellers with scoped admin API keys
+
+		{Version: 7.01, Description: "Create Table 'reseller'", Script: `
+		CREATE TABLE IF NOT EXISTS reseller (
+			reseller_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reseller_name VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE,
+			email VARCHAR(255) NOT NULL DEFAULT '',
+			seat_allocation INTEGER NOT NULL DEFAULT 0 CHECK (seat_allocation >= 0),
+			api_key_hash CHAR(64) NOT NULL UNIQUE,
+			api_key_prefix VARCHAR(12) NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			created_at VARCHAR(19) NOT NULL
+		);`},
+
+		{Version: 7.02, Description: "Add Column 'customer.reseller_id'", Script: `
+		ALTER TABLE customer ADD COLUMN reseller_id INTEGER REFERENCES reseller (reseller_id) ON DELETE SET NULL;`},
+
+		{Version: 7.03, Description: "Create Index 'idx_customer_reseller_id'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_customer_reseller_id ON customer (reseller_id ASC);`},
+
+		// 8.xx: product bundles licensed as a single unit
+
+		{Version: 8.01, Description: "Create Table 'bundle'", Script: `
+		CREATE TABLE IF NOT EXISTS bundle (
+			bundle_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			bundle_name VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE,
+			description TEXT NOT NULL DEFAULT '',
+			created_at VARCHAR(19) NOT NULL
+		);`},
+
+		{Version: 8.02, Description: "Create Table 'bundle_product'", Script: `
+		CREATE TABLE IF NOT EXISTS bundle_product (
+			bundle_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			CONSTRAINT pk_bundle_product PRIMARY KEY (bundle_id, product_id),
+			FOREIGN KEY (bundle_id) REFERENCES bundle (bundle_id) ON DELETE CASCADE,
+			FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 8.03, Description: "Create Table 'customer_bundle'", Script: `
+		CREATE TABLE IF NOT EXISTS customer_bundle (
+			customer_id INTEGER NOT NULL,
+			bundle_id INTEGER NOT NULL,
+			license_key VARCHAR(36) NOT NULL UNIQUE COLLATE NOCASE,
+			created_at VARCHAR(19) NOT NULL,
+			CONSTRAINT pk_customer_bundle PRIMARY KEY (customer_id, bundle_id),
+			FOREIGN KEY (customer_id) REFERENCES customer (customer_id) ON DELETE CASCADE,
+			FOREIGN KEY (bundle_id) REFERENCES bundle (bundle_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 8.04, Description: "Add Column 'license.bundle_id'", Script: `
+		ALTER TABLE license ADD COLUMN bundle_id INTEGER REFERENCES bundle (bundle_id) ON DELETE SET NULL;`},
+
+		{Version: 8.05, Description: "Create Index 'idx_license_bundle_id'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_license_bundle_id ON license (customer_id, bundle_id);`},
+
+		// 9.xx: licenses get a surrogate key so a customer can hold several licenses
+		// of one product. license, license_feature and license_key_history are rebuilt
+		// (SQLite cannot change a primary key in place): the new tables are filled from
+		// the old ones, the old ones dropped and the new ones renamed, which also
+		// renames their foreign key references.
+
+		{Version: 9.01, Description: "Create Table 'license_new'", Script: `
+		CREATE TABLE IF NOT EXISTS license_new (
+			license_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			license_key VARCHAR(36) NOT NULL COLLATE NOCASE,
+			license_count INTEGER NOT NULL,
+			is_subscription INTEGER NOT NULL,
+			license_term INTEGER NOT NULL,
+			start_date VARCHAR(10),
+			expiration_date VARCHAR(10),
+			maint_expiration_date VARCHAR(10) NOT NULL DEFAULT '9999-12-31',
+			max_product_version VARCHAR(255),
+			key_status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (key_status IN ('active','suspended','revoked')),
+			status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active','suspended','cancelled')),
+			status_reason VARCHAR(255) NOT NULL DEFAULT '',
+			status_changed_at VARCHAR(19) NOT NULL DEFAULT '',
+			bundle_id INTEGER REFERENCES bundle (bundle_id) ON DELETE SET NULL,
+			FOREIGN KEY (customer_id) REFERENCES customer (customer_id) ON DELETE CASCADE,
+			FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 9.02, Description: "Copy licenses to 'license_new'", Script: `
+		INSERT INTO license_new (
+			customer_id, product_id, license_key, license_count, is_subscription, license_term,
+			start_date, expiration_date, maint_expiration_date, max_product_version,
+			key_status, status, status_reason, status_changed_at, bundle_id
+		)
+		SELECT
+			customer_id, product_id, license_key, license_count, is_subscription, license_term,
+			start_date, expiration_date, maint_expiration_date, max_product_version,
+			key_status, status, status_reason, status_changed_at, bundle_id
+		FROM license
+		ORDER BY customer_id, product_id;`},
+
+		{Version: 9.03, Description: "Create Table 'license_feature_new'", Script: `
+		CREATE TABLE IF NOT EXISTS license_feature_new (
+			license_id INTEGER NOT NULL,
+			feature_id INTEGER NOT NULL,
+			feature_value VARCHAR(255) NOT NULL,
+			CONSTRAINT pk_license_feature PRIMARY KEY (license_id, feature_id),
+			FOREIGN KEY (feature_id) REFERENCES feature (feature_id),
+			FOREIGN KEY (license_id) REFERENCES license_new (license_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 9.04, Description: "Copy feature values to 'license_feature_new'", Script: `
+		INSERT INTO license_feature_new (license_id, feature_id, feature_value)
+		SELECT l.license_id, lf.feature_id, lf.feature_value
+		FROM license_feature lf
+		JOIN license_new l ON l.customer_id = lf.customer_id AND l.product_id = lf.product_id;`},
+
+		{Version: 9.05, Description: "Create Table 'license_key_history_new'", Script: `
+		CREATE TABLE IF NOT EXISTS license_key_history_new (
+			history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			license_id INTEGER NOT NULL,
+			license_key VARCHAR(36) NOT NULL COLLATE NOCASE,
+			reason VARCHAR(255) NOT NULL DEFAULT '',
+			replaced_at VARCHAR(19) NOT NULL,
+			grace_until VARCHAR(19) NOT NULL,
+			revoked_at VARCHAR(19) NOT NULL DEFAULT '',
+			FOREIGN KEY (license_id) REFERENCES license_new (license_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 9.06, Description: "Copy key history to 'license_key_history_new'", Script: `
+		INSERT INTO license_key_history_new (history_id, license_id, license_key, reason, replaced_at, grace_until, revoked_at)
+		SELECT h.history_id, l.license_id, h.license_key, h.reason, h.replaced_at, h.grace_until, h.revoked_at
+		FROM license_key_history h
+		JOIN license_new l ON l.customer_id = h.customer_id AND l.product_id = h.product_id;`},
+
+		{Version: 9.07, Description: "Drop Tables 'license_key_history', 'license_feature' and 'license'", Script: `
+		DROP TABLE license_key_history;
+		DROP TABLE license_feature;
+		DROP TABLE license;`},
+
+		{Version: 9.08, Description: "Rename the rebuilt license tables", Script: `
+		ALTER TABLE license_new RENAME TO license;
+		ALTER TABLE license_feature_new RENAME TO license_feature;
+		ALTER TABLE license_key_history_new RENAME TO license_key_history;`},
+
+		{Version: 9.09, Description: "Create Indexes on the rebuilt license tables", Script: `
+		CREATE INDEX IF NOT EXISTS idx_license_customer_id ON license (customer_id ASC);
+		CREATE INDEX IF NOT EXISTS idx_license_product_id ON license (product_id ASC);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_license_key ON license (license_key);
+		CREATE INDEX IF NOT EXISTS idx_license_bundle_id ON license (customer_id, bundle_id);
+		CREATE INDEX IF NOT EXISTS idx_license_feature_feature_id ON license_feature (feature_id ASC);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_keyhist_key ON license_key_history (license_key);
+		CREATE INDEX IF NOT EXISTS idx_keyhist_license_id ON license_key_history (license_id ASC);`},
+
+		{Version: 9.10, Description: "Add Column 'registration.license_id'", Script: `
+		ALTER TABLE registration ADD COLUMN license_id INTEGER REFERENCES license (license_id) ON DELETE CASCADE;`},
+
+		{Version: 9.11, Description: "Attach registrations to their license", Script: `
+		UPDATE registration
+		SET license_id = (
+			SELECT l.license_id
+			FROM license l
+			JOIN machine m ON m.customer_id = l.customer_id
+			WHERE m.machine_id = registration.machine_id AND l.product_id = registration.product_id
+		);`},
+
+		{Version: 9.12, Description: "Create Index 'idx_registration_license_id'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_registration_license_id ON registration (license_id ASC);`},
+
+		// 10.xx: boolean (3) and date (4) feature types. feature and license_feature
+		// are rebuilt the same way as in 9.xx to widen the feature_type CHECK.
+
+		{Version: 10.01, Description: "Create Table 'feature_new'", Script: `
+		CREATE TABLE IF NOT EXISTS feature_new (
+			feature_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL,
+			feature_name VARCHAR(255) NOT NULL,
+			feature_type INTEGER NOT NULL CHECK (feature_type in (0,1,2,3,4)) DEFAULT 0,
+			allowed_values VARCHAR(255),
+			default_value VARCHAR(255),
+			FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 10.02, Description: "Copy features to 'feature_new'", Script: `
+		INSERT INTO feature_new (feature_id, product_id, feature_name, feature_type, allowed_values, default_value)
+		SELECT feature_id, product_id, feature_name, feature_type, allowed_values, default_value
+		FROM feature;`},
+
+		{Version: 10.03, Description: "Create Table 'license_feature_new'", Script: `
+		CREATE TABLE IF NOT EXISTS license_feature_new (
+			license_id INTEGER NOT NULL,
+			feature_id INTEGER NOT NULL,
+			feature_value VARCHAR(255) NOT NULL,
+			CONSTRAINT pk_license_feature PRIMARY KEY (license_id, feature_id),
+			FOREIGN KEY (feature_id) REFERENCES feature_new (feature_id),
+			FOREIGN KEY (license_id) REFERENCES license (license_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 10.04, Description: "Copy feature values to 'license_feature_new'", Script: `
+		INSERT INTO license_feature_new (license_id, feature_id, feature_value)
+		SELECT license_id, feature_id, feature_value
+		FROM license_feature;`},
+
+		{Version: 10.05, Description: "Drop Tables 'license_feature' and 'feature'", Script: `
+		DROP TABLE license_feature;
+		DROP TABLE feature;`},
+
+		{Version: 10.06, Description: "Rename the rebuilt feature tables", Script: `
+		ALTER TABLE feature_new RENAME TO feature;
+		ALTER TABLE license_feature_new RENAME TO license_feature;`},
+
+		{Version: 10.07, Description: "Create Indexes on the rebuilt feature tables", Script: `
+		CREATE INDEX IF NOT EXISTS idx_feature_product_id ON feature (product_id ASC);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_feature_product_name ON feature (product_id, feature_name COLLATE NOCASE);
+		CREATE INDEX IF NOT EXISTS idx_license_feature_feature_id ON license_feature (feature_id ASC);`},
+
+		// 11.xx: value constraints on feature definitions
+
+		{Version: 11.01, Description: "Add Column 'feature.min_value'", Script: `
+		ALTER TABLE feature ADD COLUMN min_value INTEGER;`},
+
+		{Version: 11.02, Description: "Add Column 'feature.max_value'", Script: `
+		ALTER TABLE feature ADD COLUMN max_value INTEGER;`},
+
+		{Version: 11.03, Description: "Add Column 'feature.max_length'", Script: `
+		ALTER TABLE feature ADD COLUMN max_length INTEGER NOT NULL DEFAULT 0;`},
+
+		// 12.xx: plans (tiers) - named sets of feature values per product that
+		// licenses reference; license overrides are applied on top of the plan
+
+		{Version: 12.01, Description: "Create Table 'plan'", Script: `
+		CREATE TABLE IF NOT EXISTS plan (
+			plan_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL,
+			plan_name VARCHAR(255) NOT NULL,
+			FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 12.02, Description: "Create Index 'idx_plan_product_name'", Script: `
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_plan_product_name ON plan (product_id, plan_name COLLATE NOCASE);`},
+
+		{Version: 12.03, Description: "Create Table 'plan_feature'", Script: `
+		CREATE TABLE IF NOT EXISTS plan_feature (
+			plan_id INTEGER NOT NULL,
+			feature_id INTEGER NOT NULL,
+			feature_value TEXT NOT NULL,
+			CONSTRAINT pk_plan_feature PRIMARY KEY (plan_id, feature_id),
+			FOREIGN KEY (plan_id) REFERENCES plan (plan_id) ON DELETE CASCADE,
+			FOREIGN KEY (feature_id) REFERENCES feature (feature_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 12.04, Description: "Create Index 'idx_plan_feature_feature_id'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_plan_feature_feature_id ON plan_feature (feature_id ASC);`},
+
+		{Version: 12.05, Description: "Add Column 'license.plan_id'", Script: `
+		ALTER TABLE license ADD COLUMN plan_id INTEGER REFERENCES plan (plan_id) ON DELETE SET NULL;`},
+
+		{Version: 12.06, Description: "Create Index 'idx_license_plan_id'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_license_plan_id ON license (plan_id ASC);`},
+
+		// 13.xx: feature lifecycle - a status (active, deprecated, hidden) and the
+		// former names of renamed features, so registrations activated before a
+		// change keep hashing the feature set they were issued with
+
+		{Version: 13.01, Description: "Add Column 'feature.feature_status'", Script: `
+		ALTER TABLE feature ADD COLUMN feature_status TEXT NOT NULL DEFAULT 'active'
+			CHECK (feature_status IN ('active', 'deprecated', 'hidden'));`},
+
+		{Version: 13.02, Description: "Add Column 'feature.status_date'", Script: `
+		ALTER TABLE feature ADD COLUMN status_date TEXT;`},
+
+		{Version: 13.03, Description: "Create Table 'feature_alias'", Script: `
+		CREATE TABLE IF NOT EXISTS feature_alias (
+			alias_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			feature_id INTEGER NOT NULL,
+			alias_name VARCHAR(255) NOT NULL,
+			cutoff_date TEXT NOT NULL,
+			FOREIGN KEY (feature_id) REFERENCES feature (feature_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 13.04, Description: "Create Index 'idx_feature_alias_feature_id'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_feature_alias_feature_id ON feature_alias (feature_id ASC);`},
+
+		// 14.xx: bulk license operations - an audit trail of changes applied to a
+		// selection of a product's licenses at once
+
+		{Version: 14.01, Description: "Create Table 'bulk_operation'", Script: `
+		CREATE TABLE IF NOT EXISTS bulk_operation (
+			operation_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL,
+			operation_time VARCHAR(19) NOT NULL,
+			selection TEXT NOT NULL,
+			changes TEXT NOT NULL,
+			license_count INTEGER NOT NULL,
+			FOREIGN KEY (product_id) REFERENCES product (product_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 14.02, Description: "Create Index 'idx_bulk_operation_product_id'", Script: `
+		CREATE INDEX IF NOT EXISTS idx_bulk_operation_product_id ON bulk_operation (product_id ASC);`},
+
+		{Version: 14.03, Description: "Create Table 'bulk_operation_license'", Script: `
+		CREATE TABLE IF NOT EXISTS bulk_operation_license (
+			operation_id INTEGER NOT NULL,
+			license_id INTEGER NOT NULL,
+			customer_name VARCHAR(255) NOT NULL,
+			changes TEXT NOT NULL,
+			CONSTRAINT pk_bulk_operation_license PRIMARY KEY (operation_id, license_id),
+			FOREIGN KEY (operation_id) REFERENCES bulk_operation (operation_id) ON DELETE CASCADE
+		);`},
+
+		// 15.xx: inactivity policies - registrations that stop checking in are
+		// flagged as idle and, after a notice period, released to free their seat
+
+		{Version: 15.01, Description: "Add Column 'product.inactivity_days'", Script: `
+		ALTER TABLE product ADD COLUMN inactivity_days INTEGER;`},
+
+		{Version: 15.02, Description: "Add Column 'product.inactivity_action'", Script: `
+		ALTER TABLE product ADD COLUMN inactivity_action VARCHAR(10) NOT NULL DEFAULT 'flag' CHECK (inactivity_action IN ('flag','release'));`},
+
+		{Version: 15.03, Description: "Add Column 'license.inactivity_days'", Script: `
+		ALTER TABLE license ADD COLUMN inactivity_days INTEGER;`},
+
+		{Version: 15.04, Description: "Add Column 'license.inactivity_action'", Script: `
+		ALTER TABLE license ADD COLUMN inactivity_action VARCHAR(10) CHECK (inactivity_action IN ('flag','release'));`},
+
+		{Version: 15.05, Description: "Add Column 'registration.last_checkin_date'", Script: `
+		ALTER TABLE registration ADD COLUMN last_checkin_date VARCHAR(10);`},
+
+		{Version: 15.06, Description: "Add Column 'registration.idle_date'", Script: `
+		ALTER TABLE registration ADD COLUMN idle_date VARCHAR(10);`},
+
+		{Version: 15.07, Description: "Add Column 'registration.released_date'", Script: `
+		ALTER TABLE registration ADD COLUMN released_date VARCHAR(10);`},
+
+		// 16.xx: last-seen tracking - when a machine last called in (activation or
+		// license check-in) and what it reported about itself
+
+		{Version: 16.01, Description: "Add Column 'registration.last_seen_at'", Script: `
+		ALTER TABLE registration ADD COLUMN last_seen_at VARCHAR(19);`},
+
+		{Version: 16.02, Description: "Add Column 'registration.client_os'", Script: `
+		ALTER TABLE registration ADD COLUMN client_os VARCHAR(255);`},
+
+		{Version: 16.03, Description: "Add Column 'registration.client_hostname'", Script: `
+		ALTER TABLE registration ADD COLUMN client_hostname VARCHAR(255);`},
+
+		{Version: 16.04, Description: "Add Column 'registration.client_ip'", Script: `
+		ALTER TABLE registration ADD COLUMN client_ip VARCHAR(45);`},
+
+		// 17.xx: key history records status changes (suspend, revoke, reactivate)
+		// as well as rotations, and bundle license keys get the same lifecycle.
+		// Only replaced keys must be unique in the history.
+
+		{Version: 17.01, Description: "Add Column 'license_key_history.event'", Script: `
+		ALTER TABLE license_key_history ADD COLUMN event VARCHAR(20) NOT NULL DEFAULT 'replaced';`},
+
+		{Version: 17.02, Description: "Limit Index 'idx_keyhist_key' to replaced keys", Script: `
+		DROP INDEX IF EXISTS idx_keyhist_key;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_keyhist_key ON license_key_history (license_key) WHERE event = 'replaced';`},
+
+		{Version: 17.03, Description: "Add Column 'customer_bundle.key_status'", Script: `
+		ALTER TABLE customer_bundle ADD COLUMN key_status VARCHAR(20) NOT NULL DEFAULT 'active';`},
+
+		{Version: 17.04, Description: "Create Table 'bundle_key_history'", Script: `
+		CREATE TABLE IF NOT EXISTS bundle_key_history (
+			history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id INTEGER NOT NULL,
+			bundle_id INTEGER NOT NULL,
+			license_key VARCHAR(36) NOT NULL COLLATE NOCASE,
+			event VARCHAR(20) NOT NULL DEFAULT 'replaced',
+			reason VARCHAR(255) NOT NULL DEFAULT '',
+			replaced_at VARCHAR(19) NOT NULL,
+			grace_until VARCHAR(19) NOT NULL DEFAULT '',
+			revoked_at VARCHAR(19) NOT NULL DEFAULT '',
+			FOREIGN KEY (customer_id, bundle_id) REFERENCES customer_bundle (customer_id, bundle_id) ON DELETE CASCADE
+		);`},
+
+		{Version: 17.05, Description: "Create Indexes on 'bundle_key_history'", Script: `
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_bundle_keyhist_key ON bundle_key_history (license_key) WHERE event = 'replaced';
+		CREATE INDEX IF NOT EXISTS idx_bundle_keyhist_customer_bundle ON bundle_key_history (customer_id, bundle_id);`},
+
+		// 18.xx: features take effect from a date, so a feature added to a product
+		// is not emitted to (or hashed for) registrations activated before it
+		// existed. Existing features have none and are emitted to every registration.
+
+		{Version: 18.01, Description: "Add Column 'feature.effective_date'", Script: `
+		ALTER TABLE feature ADD COLUMN effective_date TEXT;`},
 	}
 	return m
 }
@@ -269,7 +755,7 @@ func RunMigrations(db *sql.DB) error {
 
 	migrations := minifiedMigrations()
 	if count == len(migrations) && v1 == migrations[count-1].Version {
-		log.Printf("Database version %.2f is current, no migrations needed", v1)
+		slog.Info("database version is current, no migrations needed", "version", fmt.Sprintf("%.2f", v1))
 		return nil // already up to date
 	}
 
@@ -284,7 +770,12 @@ func RunMigrations(db *sql.DB) error {
 		close(infoChan)
 		_, v2, _ = currentVersion(db)
 		prog := progress(infoChan)
-		log.Printf("migration (was v%.2f now v%.2f): %v (%s)", v1, v2, err, prog)
+		slog.Error("migration failed",
+			"from_version", fmt.Sprintf("%.2f", v1),
+			"to_version", fmt.Sprintf("%.2f", v2),
+			"error", err,
+			"progress", prog,
+		)
 		return fmt.Errorf("migration error: %w\n%s", err, prog)
 	}
 	close(infoChan)
@@ -294,6 +785,6 @@ func RunMigrations(db *sql.DB) error {
 		return err
 	}
 
-	log.Print(changes(v1, v2))
+	slog.Info(changes(v1, v2))
 	return nil
 }
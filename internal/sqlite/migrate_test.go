@@ -3,6 +3,8 @@ package sqlite_test
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"path/filepath"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -28,6 +30,138 @@ func TestMigrationsApplyCleanly(t *testing.T) {
 	}
 }
 
+func TestMigrationsUpgradeBaselineData(t *testing.T) {
+	db, err := sql.Open("sqlite3", sqlite.DSN(filepath.Join(t.TempDir(), "test.db")))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	// A database from the first release, where a license is keyed by customer
+	// and product and feature values and registrations refer to it that way
+	if err := sqlite.MigrateTo(db, 1.17); err != nil {
+		t.Fatalf("baseline migrations failed: %v", err)
+	}
+	seed := []string{
+		`INSERT INTO customer (customer_id, customer_name, contact_name, email) VALUES
+			(1, 'Acme', 'John Doe', 'john@acme.com'), (2, 'Beta', NULL, NULL)`,
+		`INSERT INTO product (product_id, product_name, product_guid, latest_version, download_url) VALUES
+			(1, 'Widget', 'GUID-W', '1.0', ''), (2, 'Gadget', 'GUID-G', '1.0', '')`,
+		`INSERT INTO machine (machine_id, customer_id, machine_code, user_name) VALUES
+			(1, 1, 'M-A1', 'a1'), (2, 1, 'M-A2', 'a2'), (3, 2, 'M-B1', 'b1')`,
+		// Inserted out of order: license IDs follow customer and product
+		`INSERT INTO license (customer_id, product_id, license_key, license_count, is_subscription, license_term,
+			start_date, expiration_date) VALUES
+			(2, 1, 'KEY-B-W', 1, 0, 0, '2020-01-01', '2099-12-31'),
+			(1, 2, 'KEY-A-G', 1, 0, 0, '2020-01-01', '2099-12-31'),
+			(1, 1, 'KEY-A-W', 2, 0, 0, '2020-01-01', '2099-12-31')`,
+		`INSERT INTO feature (feature_id, product_id, feature_name, feature_type, allowed_values, default_value) VALUES
+			(1, 1, 'Seats', 0, '', '1'), (2, 2, 'Edition', 2, 'Std|Pro', 'Std')`,
+		`INSERT INTO license_feature (customer_id, product_id, feature_id, feature_value) VALUES
+			(1, 1, 1, '5'), (2, 1, 1, '10'), (1, 2, 2, 'Pro')`,
+		`INSERT INTO registration (machine_id, product_id, expiration_date, registration_hash) VALUES
+			(1, 1, '2099-12-31', 'H1'), (1, 2, '2099-12-31', 'H2'), (2, 1, '2099-12-31', 'H3'), (3, 1, '2099-12-31', 'H4')`,
+	}
+	for _, stmt := range seed {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v\n%s", err, stmt)
+		}
+	}
+
+	if err := sqlite.RunMigrations(db); err != nil {
+		t.Fatalf("migrations failed: %v", err)
+	}
+
+	t.Run("licenses get IDs by customer and product", func(t *testing.T) {
+		want := map[string]int64{"KEY-A-W": 1, "KEY-A-G": 2, "KEY-B-W": 3}
+		for key, id := range want {
+			var got int64
+			if err := db.QueryRow(`SELECT license_id FROM license WHERE license_key = ?`, key).Scan(&got); err != nil {
+				t.Fatalf("license %s: %v", key, err)
+			}
+			if got != id {
+				t.Errorf("license %s: expected ID %d, got %d", key, id, got)
+			}
+		}
+	})
+
+	t.Run("feature values keep their license", func(t *testing.T) {
+		rows, err := db.Query(`
+			SELECT l.license_key, lf.feature_id, lf.feature_value
+			FROM license_feature lf
+			JOIN license l ON l.license_id = lf.license_id
+			ORDER BY l.license_key, lf.feature_id`)
+		if err != nil {
+			t.Fatalf("query feature values: %v", err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var key, value string
+			var featureID int
+			if err := rows.Scan(&key, &featureID, &value); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			got = append(got, fmt.Sprintf("%s/%d=%s", key, featureID, value))
+		}
+		want := []string{"KEY-A-G/2=Pro", "KEY-A-W/1=5", "KEY-B-W/1=10"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("expected feature values %v, got %v", want, got)
+		}
+	})
+
+	t.Run("registrations are attached to their license", func(t *testing.T) {
+		var total, unattached, mismatched int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM registration`).Scan(&total); err != nil {
+			t.Fatalf("count registrations: %v", err)
+		}
+		if total != 4 {
+			t.Errorf("expected 4 registrations, got %d", total)
+		}
+		if err := db.QueryRow(`SELECT COUNT(*) FROM registration WHERE license_id IS NULL`).Scan(&unattached); err != nil {
+			t.Fatalf("count unattached: %v", err)
+		}
+		if err := db.QueryRow(`
+			SELECT COUNT(*)
+			FROM registration r
+			JOIN machine m ON m.machine_id = r.machine_id
+			JOIN license l ON l.license_id = r.license_id
+			WHERE l.customer_id <> m.customer_id OR l.product_id <> r.product_id`).Scan(&mismatched); err != nil {
+			t.Fatalf("count mismatched: %v", err)
+		}
+		if unattached != 0 || mismatched != 0 {
+			t.Errorf("expected every registration on its machine's license, got %d unattached and %d mismatched",
+				unattached, mismatched)
+		}
+	})
+
+	t.Run("contacts are copied", func(t *testing.T) {
+		var email string
+		if err := db.QueryRow(`SELECT email FROM customer_contact WHERE customer_id = 1 AND is_primary = 1`).Scan(&email); err != nil {
+			t.Fatalf("primary contact: %v", err)
+		}
+		if email != "john@acme.com" {
+			t.Errorf("expected the customer's email on its primary contact, got %q", email)
+		}
+	})
+
+	t.Run("foreign keys hold", func(t *testing.T) {
+		rows, err := db.Query(`PRAGMA foreign_key_check`)
+		if err != nil {
+			t.Fatalf("foreign_key_check: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var table, parent string
+			var rowID, fkID sql.NullInt64
+			if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			t.Errorf("foreign key violation: %s row %v references missing %s", table, rowID.Int64, parent)
+		}
+	})
+}
+
 func TestMigrationsSetsApplicationID(t *testing.T) {
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
@@ -16,7 +16,7 @@ func NewTestDB(t *testing.T) *sqlx.DB {
 func NewTestDBAt(t *testing.T, dbPath string) *sqlx.DB {
 	t.Helper()
 
-	db, err := sqlx.Open("sqlite3", dbPath)
+	db, err := sqlx.Open("sqlite3", sqlite.DSN(dbPath))
 	if err != nil {
 		t.Fatalf("open db: %v", err)
 	}
@@ -31,12 +31,7 @@ func NewTestDBAt(t *testing.T, dbPath string) *sqlx.DB {
 		t.Fatalf("set journal mode: %v", err)
 	}
 
-	// Enable foreign key enforcement
-	if _, err := db.Exec(`PRAGMA foreign_keys=ON;`); err != nil {
-		t.Fatalf("enable foreign keys: %v", err)
-	}
-
-	// Verify foreign keys are supported and enabled
+	// Verify foreign keys are supported and enabled (by the DSN)
 	var fkEnabled int
 	if err := db.QueryRow(`PRAGMA foreign_keys;`).Scan(&fkEnabled); err != nil {
 		t.Fatalf("foreign key support check failed: %v", err)
@@ -12,6 +12,8 @@ const (
 	FeatureTypeInteger FeatureType = 0
 	FeatureTypeString  FeatureType = 1
 	FeatureTypeValues  FeatureType = 2
+	FeatureTypeBoolean FeatureType = 3
+	FeatureTypeDate    FeatureType = 4
 )
 
 func (ft FeatureType) String() string {
@@ -22,6 +24,10 @@ func (ft FeatureType) String() string {
 		return "String"
 	case FeatureTypeValues:
 		return "Values"
+	case FeatureTypeBoolean:
+		return "Boolean"
+	case FeatureTypeDate:
+		return "Date"
 	default:
 		return "Unknown"
 	}
@@ -37,6 +43,28 @@ type Customer struct {
 	Notes        string
 }
 
+// Contact is a view model for a customer contact
+type Contact struct {
+	ContactID   int64
+	CustomerID  int64
+	ContactName string
+	Phone       string
+	Email       string
+	Roles       []string
+	IsPrimary   bool
+	Notes       string
+}
+
+// HasRole reports whether the contact has the role
+func (c Contact) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
 // Product is a view model for product display
 type Product struct {
 	ProductID     int64
@@ -48,6 +76,7 @@ type Product struct {
 
 // License is a view model for license display
 type License struct {
+	LicenseID           int64
 	CustomerID          int64
 	ProductID           int64
 	ProductName         string
@@ -59,6 +88,10 @@ type License struct {
 	ExpirationDate      string
 	MaintExpirationDate string
 	MaxProductVersion   string
+	KeyStatus           string
+	Status              string
+	StatusReason        string
+	StatusChangedAt     string
 }
 
 // SubscriptionText returns "Yes" or "No" for subscription status
@@ -93,6 +126,40 @@ func (lic License) IsMaintExpired() bool {
 	return t.Before(time.Now())
 }
 
+// IsActive reports whether the license is active (not suspended or cancelled)
+func (lic License) IsActive() bool {
+	return lic.Status == "" || lic.Status == "active"
+}
+
+// IsKeyActive reports whether the license key is accepted by client endpoints
+func (lic License) IsKeyActive() bool {
+	return lic.KeyStatus == "" || lic.KeyStatus == "active"
+}
+
+// StatusBadgeClass returns the badge class for a license or license key status
+func StatusBadgeClass(status string) string {
+	switch status {
+	case "suspended":
+		return "badge-warning"
+	case "cancelled", "revoked":
+		return "badge-error"
+	case "replaced":
+		return "badge-ghost"
+	}
+	return "badge-success"
+}
+
+// KeyHistory is a view model for a license key change (rotation or status change)
+type KeyHistory struct {
+	LicenseKey string
+	Event      string
+	Reason     string
+	ReplacedAt string
+	GraceUntil string
+	RevokedAt  string
+	InGrace    bool
+}
+
 // Feature is a view model for feature definition display
 type Feature struct {
 	FeatureID     int64
@@ -101,18 +168,60 @@ type Feature struct {
 	FeatureType   FeatureType
 	AllowedValues string
 	DefaultValue  string
+	MinValue      string   // empty = no minimum
+	MaxValue      string   // empty = no maximum
+	MaxLength     string   // empty = no limit
+	Status        string   // active, deprecated or hidden
+	Aliases       []string // former names, still emitted to older registrations
+}
+
+// AllowedValuesList returns the allowed values as a slice (pipe-delimited)
+func (f Feature) AllowedValuesList() []string {
+	if f.AllowedValues == "" {
+		return nil
+	}
+	return strings.Split(f.AllowedValues, "|")
 }
 
 // ProductFeature is a view model for customer-specific feature values
 type ProductFeature struct {
-	CustomerID    int64
+	LicenseID     int64
 	ProductID     int64
 	FeatureID     int64
 	FeatureName   string
 	FeatureType   FeatureType
 	FeatureValue  string
+	PlanValue     string // value of the license's plan; empty = none
 	AllowedValues string
 	DefaultValue  string
+	MinValue      string // empty = no minimum
+	MaxValue      string // empty = no maximum
+	MaxLength     string // empty = no limit
+}
+
+// EffectiveValue returns the license's value, or the inherited value when there is none
+func (pf ProductFeature) EffectiveValue() string {
+	if pf.FeatureValue == "" {
+		return pf.InheritedValue()
+	}
+	return pf.FeatureValue
+}
+
+// InheritedValue returns the value that applies without a license value: the
+// plan's value if the license's plan sets one, else the default
+func (pf ProductFeature) InheritedValue() string {
+	if pf.PlanValue != "" {
+		return pf.PlanValue
+	}
+	return pf.DefaultValue
+}
+
+// InheritedSource returns where InheritedValue comes from ("plan" or "default")
+func (pf ProductFeature) InheritedSource() string {
+	if pf.PlanValue != "" {
+		return "plan"
+	}
+	return "default"
 }
 
 // AllowedValuesList returns the allowed values as a slice (pipe-delimited)
@@ -123,9 +232,91 @@ func (pf ProductFeature) AllowedValuesList() []string {
 	return strings.Split(pf.AllowedValues, "|")
 }
 
+// Plan is a view model for a product plan and its feature values
+type Plan struct {
+	PlanID    int64
+	ProductID int64
+	PlanName  string
+	Licenses  int
+	Values    map[int64]string // feature ID -> plan value
+}
+
+// PlanLicense is a view model for a license listed when changing plans in bulk
+type PlanLicense struct {
+	LicenseID    int64
+	CustomerName string
+	LicenseKey   string
+	LicenseCount int
+	Status       string
+	PlanID       int64 // 0 = no plan
+	PlanName     string
+}
+
+// BulkItem is a view model for one license in a bulk operation preview
+type BulkItem struct {
+	LicenseID              int64
+	CustomerName           string
+	ProductName            string
+	LicenseKey             string
+	InBundle               bool
+	ExpirationDate         string
+	NewExpirationDate      string
+	MaintExpirationDate    string
+	NewMaintExpirationDate string
+	LicenseCount           int
+	NewLicenseCount        int
+	MaxProductVersion      string
+	NewMaxProductVersion   string
+	FeatureValue           string
+	NewFeatureValue        string
+	Error                  string
+}
+
+// BulkPreview is a view model for the preview of a bulk operation
+type BulkPreview struct {
+	Items          []BulkItem
+	Bundled        []BulkItem // other products' bundle licenses that change too
+	Errors         int
+	FeatureChanged bool
+}
+
+// BulkOperation is a view model for an applied bulk operation in the audit trail
+type BulkOperation struct {
+	OperationID   int64
+	OperationTime string
+	Selection     string
+	Changes       string
+	LicenseCount  int
+}
+
+// InactivityItem is a view model for a registration in the inactivity preview
+type InactivityItem struct {
+	CustomerName     string
+	LicenseKey       string
+	MachineCode      string
+	UserName         string
+	LastActivityDate string
+	IdleDate         string
+	ReleaseDate      string
+	Days             int
+	Action           string
+	Next             string
+}
+
+// InactivityReport is a view model for the preview of the next inactivity run
+type InactivityReport struct {
+	Items      []InactivityItem
+	Flag       int
+	Release    int
+	Wait       int
+	Clear      int
+	NoticeDays int
+}
+
 // MachineRegistration is a view model for machine registration display
 type MachineRegistration struct {
 	MachineID        int64
+	LicenseID        int64
 	CustomerID       int64
 	ProductID        int64
 	MachineCode      string
@@ -135,6 +326,10 @@ type MachineRegistration struct {
 	FirstRegDate     string
 	LastRegDate      string
 	InstalledVersion string
+	LastSeenAt       string // UTC
+	ClientOS         string
+	ClientHostname   string
+	ClientIP         string
 }
 
 // IsExpired checks if the machine registration has expired
@@ -149,6 +344,21 @@ func (mr MachineRegistration) IsExpired() bool {
 	return t.Before(time.Now())
 }
 
+// PortalLicense is a view model for a license in the customer portal
+type PortalLicense struct {
+	License
+	SeatsInUse int
+	Machines   []MachineRegistration
+}
+
+// SeatsAvailable returns the number of unused seats
+func (pl PortalLicense) SeatsAvailable() int {
+	if n := pl.LicenseCount - pl.SeatsInUse; n > 0 {
+		return n
+	}
+	return 0
+}
+
 // ExpiredLicense is a view model for expired license display
 type ExpiredLicense struct {
 	CustomerName        string
@@ -158,3 +368,63 @@ type ExpiredLicense struct {
 	ExpirationDate      string
 	MaintExpirationDate string
 }
+
+// ActivationPeriod is a view model for activation counts within a day, week or month
+type ActivationPeriod struct {
+	Period        string
+	New           int
+	Reactivations int
+}
+
+// Total returns all activations in the period
+func (ap ActivationPeriod) Total() int {
+	return ap.New + ap.Reactivations
+}
+
+// ActivationReport is a view model for the activations chart
+type ActivationReport struct {
+	Interval  string
+	ProductID int64
+	Periods   []ActivationPeriod
+}
+
+// MaxTotal returns the largest period total (used to scale the chart)
+func (ar ActivationReport) MaxTotal() int {
+	m := 0
+	for _, p := range ar.Periods {
+		if p.Total() > m {
+			m = p.Total()
+		}
+	}
+	return m
+}
+
+// TotalNew returns the number of new activations across all periods
+func (ar ActivationReport) TotalNew() int {
+	n := 0
+	for _, p := range ar.Periods {
+		n += p.New
+	}
+	return n
+}
+
+// TotalReactivations returns the number of reactivations across all periods
+func (ar ActivationReport) TotalReactivations() int {
+	n := 0
+	for _, p := range ar.Periods {
+		n += p.Reactivations
+	}
+	return n
+}
+
+// SeatUtilization is a view model for seats in use per license
+type SeatUtilization struct {
+	LicenseID    int64
+	CustomerID   int64
+	CustomerName string
+	ProductID    int64
+	ProductName  string
+	LicenseCount int
+	SeatsInUse   int
+	Percent      float64
+}
@@ -177,7 +177,7 @@ document.body.addEventListener('showToast', function(evt) {
 
 // Licenses page state
 var currentCustomerID = null;
-var currentProductID = null;
+var currentLicenseID = null;
 
 function loadCustomerLicenses(customerID) {
 	if (!customerID) {
@@ -185,12 +185,12 @@ function loadCustomerLicenses(customerID) {
 			'<div class="text-center py-12 text-base-content/60">Select a customer to view their licenses</div>';
 		document.getElementById('features-container').classList.add('hidden');
 		currentCustomerID = null;
-		currentProductID = null;
+		currentLicenseID = null;
 		return;
 	}
 
 	currentCustomerID = customerID;
-	currentProductID = null;
+	currentLicenseID = null;
 	document.getElementById('features-container').classList.add('hidden');
 
 	htmx.ajax('GET', '/web/licenses/' + customerID, {
@@ -199,10 +199,10 @@ function loadCustomerLicenses(customerID) {
 	});
 }
 
-function selectProduct(productID, licenseKey) {
+function selectLicense(licenseID, licenseKey) {
 	if (!currentCustomerID) return;
 
-	currentProductID = productID;
+	currentLicenseID = licenseID;
 
 	// Update license key display
 	var keyContainer = document.getElementById('license-key-container');
@@ -214,7 +214,7 @@ function selectProduct(productID, licenseKey) {
 
 	// Load features
 	document.getElementById('features-container').classList.remove('hidden');
-	htmx.ajax('GET', '/web/features/' + currentCustomerID + '/' + productID, {
+	htmx.ajax('GET', '/web/features/' + licenseID, {
 		target: '#features-container',
 		swap: 'innerHTML'
 	});
@@ -224,7 +224,7 @@ function selectProduct(productID, licenseKey) {
 	document.querySelectorAll('#licenses-table tbody tr').forEach(function(row) {
 		row.classList.remove('selected');
 	});
-	var selectedRow = document.querySelector('#licenses-table tbody tr[data-product-id="' + productID + '"]');
+	var selectedRow = document.querySelector('#licenses-table tbody tr[data-license-id="' + licenseID + '"]');
 	if (selectedRow) {
 		selectedRow.classList.add('selected');
 		// Get product name from first cell
@@ -241,8 +241,15 @@ function selectProduct(productID, licenseKey) {
 	}
 }
 
-function openMachinesModal(customerID, productID) {
-	htmx.ajax('GET', '/web/machines/' + customerID + '/' + productID, {
+// Reload the licenses table when a license changes from inside a modal
+document.body.addEventListener('licensesChanged', function(evt) {
+	if (currentCustomerID) {
+		loadCustomerLicenses(currentCustomerID);
+	}
+});
+
+function openMachinesModal(licenseID) {
+	htmx.ajax('GET', '/web/machines/' + licenseID, {
 		target: '#modal-content',
 		swap: 'innerHTML'
 	});
@@ -251,8 +258,8 @@ function openMachinesModal(customerID, productID) {
 // Re-initialize after HTMX swaps
 document.body.addEventListener('htmx:afterSwap', function(evt) {
 	if (evt.detail.target.id === 'licenses-container') {
-		// Reset product selection when licenses reload
-		currentProductID = null;
+		// Reset license selection when licenses reload
+		currentLicenseID = null;
 		var featuresContainer = document.getElementById('features-container');
 		if (featuresContainer) {
 			featuresContainer.classList.add('hidden');
@@ -260,7 +267,7 @@ document.body.addEventListener('htmx:afterSwap', function(evt) {
 	}
 });
 
-// Feature form: toggle Allowed Values field based on feature type
+// Feature form: toggle Allowed Values and the constraint fields based on feature type
 function toggleAllowedValues() {
 	var select = document.getElementById('feature-type-select');
 	var input = document.getElementById('allowed-values-input');
@@ -271,6 +278,28 @@ function toggleAllowedValues() {
 	if (!isValues) {
 		input.value = '';
 	}
+
+	// Constraint inputs only apply to their feature type
+	toggleFeatureInput('feature-min-input', select.value === 'integer');
+	toggleFeatureInput('feature-max-input', select.value === 'integer');
+	toggleFeatureInput('feature-maxlength-input', select.value === 'string');
+}
+
+function toggleFeatureInput(id, enabled) {
+	var input = document.getElementById(id);
+	if (!input) return;
+
+	input.disabled = !enabled;
+	if (!enabled) {
+		input.value = '';
+	}
+}
+
+// Plan change form: select or clear every license checkbox
+function toggleAllLicenses(checkbox) {
+	document.querySelectorAll('.license-checkbox').forEach(function(cb) {
+		cb.checked = checkbox.checked;
+	});
 }
 
 // License form: update UI based on license type (perpetual vs subscription)